@@ -61,6 +61,9 @@ var templateTestsRaw string
 //go:embed templates/templates.adoc.tmpl
 var templateTemplatesRaw string
 
+//go:embed templates/cli.adoc.tmpl
+var templateCLIRaw string
+
 var (
 	templateBloblFunctions *template.Template
 	templateBloblMethods   *template.Template
@@ -70,6 +73,7 @@ var (
 	templateRedpanda       *template.Template
 	templateTests          *template.Template
 	templateTemplates      *template.Template
+	templateCLI            *template.Template
 )
 
 func init() {
@@ -81,6 +85,7 @@ func init() {
 	templateRedpanda = template.Must(template.New("redpanda").Parse(templatePluginFieldsRaw + templateRedpandaRaw))
 	templateTests = template.Must(template.New("tests").Parse(templatePluginFieldsRaw + templateTestsRaw))
 	templateTemplates = template.Must(template.New("templates").Parse(templatePluginFieldsRaw + templateTemplatesRaw))
+	templateCLI = template.Must(template.New("cli").Parse(templateCLIRaw))
 }
 
 func create(t, path string, resBytes []byte) {
@@ -132,6 +137,9 @@ func main() {
 
 	// Template docs
 	doTemplates(docsDir)
+
+	// CLI docs
+	doCLI(docsDir)
 }
 
 func viewForDir(docsDir string) func(string, *service.ConfigView) {
@@ -374,3 +382,12 @@ func doTemplates(dir string) {
 
 	create("tests docs", filepath.Join(dir, "../..", "configuration", "pages", "templating.adoc"), buf.Bytes())
 }
+
+func doCLI(dir string) {
+	var buf bytes.Buffer
+	if err := templateCLI.Execute(&buf, nil); err != nil {
+		panic(fmt.Sprintf("Failed to generate cli docs: %v", err))
+	}
+
+	create("cli docs", filepath.Join(dir, "../..", "configuration", "pages", "cli.adoc"), buf.Bytes())
+}
@@ -0,0 +1,58 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed as a Redpanda Enterprise file under the Redpanda Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+// https://github.com/redpanda-data/connect/blob/main/licenses/rcl.md
+
+package cli
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdminAuth(t *testing.T) {
+	h := AdminAuth("secret", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	do := func(method, auth string) int {
+		t.Helper()
+		req := httptest.NewRequest(method, "/admin/pause/foo", http.NoBody)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusNoContent, do(http.MethodGet, ""))
+	assert.Equal(t, http.StatusUnauthorized, do(http.MethodPost, ""))
+	assert.Equal(t, http.StatusUnauthorized, do(http.MethodPost, "Bearer wrong"))
+	assert.Equal(t, http.StatusUnauthorized, do(http.MethodPost, "secret"))
+	assert.Equal(t, http.StatusNoContent, do(http.MethodPost, "Bearer secret"))
+}
+
+func TestAdminAuthNoToken(t *testing.T) {
+	h := AdminAuth("", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	for method, exp := range map[string]int{
+		http.MethodGet:  http.StatusNoContent,
+		http.MethodPut:  http.StatusUnauthorized,
+		http.MethodPost: http.StatusUnauthorized,
+	} {
+		req := httptest.NewRequest(method, "/admin/log_levels", http.NoBody)
+		req.Header.Set("Authorization", "Bearer ")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		assert.Equal(t, exp, rec.Code, method)
+	}
+}
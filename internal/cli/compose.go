@@ -0,0 +1,799 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed as a Redpanda Enterprise file under the Redpanda Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+// https://github.com/redpanda-data/connect/blob/main/licenses/rcl.md
+
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+// Top level sections that are replaced entirely when defined by a later config,
+// as merging the fields of two different component types is never intended.
+var composeReplacedSections = map[string]struct{}{
+	"input":    {},
+	"buffer":   {},
+	"pipeline": {},
+	"output":   {},
+	"metrics":  {},
+	"tracer":   {},
+}
+
+// Top level resource lists that are combined across configs, where each label
+// must be unique.
+var composeResourceSections = map[string]string{
+	"input_resources":      "input",
+	"processor_resources":  "processor",
+	"output_resources":     "output",
+	"cache_resources":      "cache",
+	"rate_limit_resources": "rate limit",
+}
+
+const composeDefaultHTTPAddress = "0.0.0.0:4195"
+
+// ProfileEnvVar is the environment variable used to select a config profile
+// when the profile flag is not provided.
+const ProfileEnvVar = "CONNECT_PROFILE"
+
+// Default endpoint paths of the http_server input and output, keyed by the
+// field that overrides them.
+var (
+	composeHTTPInputPaths = map[string]string{
+		"path":    "/post",
+		"ws_path": "/post/ws",
+	}
+	composeHTTPOutputPaths = map[string]string{
+		"path":        "/get",
+		"stream_path": "/get/stream",
+		"ws_path":     "/get/ws",
+	}
+)
+
+type composer struct {
+	// The file that each mapping node originates from.
+	origins map[*yaml.Node]string
+	// The file that each resource label was defined within.
+	resourceLabels map[string]string
+}
+
+// ExpandConfigPaths resolves a list of config paths into the files that should
+// be composed, where directories are expanded into the YAML files that they
+// directly contain in lexicographical order.
+func ExpandConfigPaths(paths []string) ([]string, error) {
+	var files []string
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config path: %w", err)
+		}
+		if !info.IsDir() {
+			files = append(files, p)
+			continue
+		}
+
+		entries, err := os.ReadDir(p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config directory: %w", err)
+		}
+		var dirFiles []string
+		for _, e := range entries {
+			if e.IsDir() {
+				continue
+			}
+			if ext := filepath.Ext(e.Name()); ext == ".yaml" || ext == ".yml" {
+				dirFiles = append(dirFiles, filepath.Join(p, e.Name()))
+			}
+		}
+		if len(dirFiles) == 0 {
+			return nil, fmt.Errorf("config directory '%v' does not contain any YAML files", p)
+		}
+		sort.Strings(dirFiles)
+		files = append(files, dirFiles...)
+	}
+	return files, nil
+}
+
+// ComposeConfigs reads and merges a list of config files into a single config,
+// where files later in the list take precedence over earlier ones.
+//
+// Fields of mappings are merged recursively with the exception of the input,
+// buffer, pipeline, output, metrics and tracer sections, which are replaced in
+// their entirety. Resource lists are combined, and an error is returned when a
+// resource label is defined more than once, or when multiple http_server
+// components of the composed config serve the same path on the same address.
+//
+// The profiles field of each file is removed, and when a profile is provided
+// its sections are applied over the file before it is composed. An error is
+// returned if a profile is provided that none of the files define.
+func ComposeConfigs(files []string, profile string) (*ComposedConfig, error) {
+	c := &composer{
+		origins:        map[*yaml.Node]string{},
+		resourceLabels: map[string]string{},
+	}
+
+	var profileDefined bool
+	root := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	for _, f := range files {
+		confBytes, err := os.ReadFile(f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config file: %w", err)
+		}
+
+		var doc yaml.Node
+		if err := yaml.Unmarshal(confBytes, &doc); err != nil {
+			return nil, fmt.Errorf("failed to parse config file '%v': %w", f, err)
+		}
+		if len(doc.Content) == 0 {
+			continue
+		}
+
+		fRoot := doc.Content[0]
+		if fRoot.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("config file '%v' must contain a mapping at the root", f)
+		}
+		c.setOrigin(fRoot, f)
+
+		defined, err := applyProfile(fRoot, profile, f)
+		if err != nil {
+			return nil, err
+		}
+		profileDefined = profileDefined || defined
+
+		if err := c.mergeRoot(root, fRoot, f); err != nil {
+			return nil, err
+		}
+	}
+	if profile != "" && !profileDefined {
+		return nil, fmt.Errorf("profile '%v' is not defined by any config", profile)
+	}
+
+	if err := c.checkHTTPPaths(root); err != nil {
+		return nil, err
+	}
+
+	composed, err := yaml.Marshal(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal composed config: %w", err)
+	}
+
+	header := "# Composed from: " + strings.Join(files, ", ") + "\n"
+	if profile != "" {
+		header += "# Profile: " + profile + "\n"
+	}
+	composed = append([]byte(header), composed...)
+
+	sources, err := c.sourceMap(root, composed)
+	if err != nil {
+		return nil, err
+	}
+	return &ComposedConfig{
+		Bytes:   composed,
+		files:   files,
+		sources: sources,
+	}, nil
+}
+
+func (c *composer) setOrigin(node *yaml.Node, file string) {
+	c.origins[node] = file
+	for _, child := range node.Content {
+		c.setOrigin(child, file)
+	}
+}
+
+// sourceMap maps each line of a composed config to the position of the first
+// node of the line within the file it originates from, which is obtained by
+// walking the merged nodes alongside those parsed from the composed config.
+func (c *composer) sourceMap(root *yaml.Node, composed []byte) (map[int]composedSource, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(composed, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse composed config: %w", err)
+	}
+
+	sources := map[int]composedSource{}
+	var walk func(src, dst *yaml.Node)
+	walk = func(src, dst *yaml.Node) {
+		// Mappings and sequences begin on the line of their first child,
+		// which may originate from a different file when merged.
+		if file, exists := c.origins[src]; exists && len(src.Content) == 0 {
+			if _, exists := sources[dst.Line]; !exists {
+				sources[dst.Line] = composedSource{
+					file:           file,
+					line:           src.Line,
+					column:         src.Column,
+					composedColumn: dst.Column,
+				}
+			}
+		}
+		if len(src.Content) != len(dst.Content) {
+			return
+		}
+		for i := range src.Content {
+			walk(src.Content[i], dst.Content[i])
+		}
+	}
+	if len(doc.Content) > 0 {
+		walk(root, doc.Content[0])
+	}
+	return sources, nil
+}
+
+type composedSource struct {
+	file           string
+	line           int
+	column         int
+	composedColumn int
+}
+
+// ComposedConfig is a config composed from one or more config files, which
+// tracks the file that each line of the composed config originates from.
+type ComposedConfig struct {
+	// The composed config in YAML format.
+	Bytes []byte
+
+	files   []string
+	sources map[int]composedSource
+}
+
+// Source returns the file and position within it that a position of the
+// composed config originates from. Lines that do not begin a node, such as
+// those of multiple line strings, are mapped relative to the nearest line
+// above them that does.
+func (c *ComposedConfig) Source(line, column int) (file string, srcLine, srcColumn int) {
+	for l := line; l > 0; l-- {
+		s, exists := c.sources[l]
+		if !exists {
+			continue
+		}
+		if l == line {
+			return s.file, s.line, max(1, s.column+column-s.composedColumn)
+		}
+		return s.file, s.line + line - l, column
+	}
+	if len(c.files) > 0 {
+		return c.files[0], 1, 1
+	}
+	return "", line, column
+}
+
+// Lint lints the composed config and returns the lints formatted with the file
+// and position of the config that each originates from.
+func (c *ComposedConfig) Lint(linter *service.StreamConfigLinter) ([]string, error) {
+	lints, err := linter.LintYAML(c.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	var formatted []string
+	seen := map[string]struct{}{}
+	for _, l := range lints {
+		file, line, column := c.Source(l.Line, l.Column)
+		lStr := fmt.Sprintf("%v(%v,%v) %v", file, line, column, l.What)
+		if _, exists := seen[lStr]; exists {
+			continue
+		}
+		seen[lStr] = struct{}{}
+		formatted = append(formatted, lStr)
+	}
+	return formatted, nil
+}
+
+// WriteRunConfig writes the composed config to a temporary file in order for
+// it to be read by the run command in place of the provided config, and
+// returns the path of the file. Linting of the file by the run command is
+// disabled, as the lints of the composed config are obtained with Lint.
+func (c *ComposedConfig) WriteRunConfig() (string, error) {
+	return writeComposed("", "connect-composed-*.yaml", append([]byte("# BENTHOS LINT DISABLE\n"), c.Bytes...))
+}
+
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func removeMappingValue(node *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			value := node.Content[i+1]
+			node.Content = append(node.Content[:i], node.Content[i+2:]...)
+			return value
+		}
+	}
+	return nil
+}
+
+// setMappingValue sets the value of a key within a mapping. When an existing
+// value is replaced the key is replaced as well, in order for the source of the
+// field to be that of the replacing value.
+func setMappingValue(node *yaml.Node, key, value *yaml.Node) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key.Value {
+			if node.Content[i+1] != value {
+				node.Content[i] = key
+			}
+			node.Content[i+1] = value
+			return
+		}
+	}
+	node.Content = append(node.Content, key, value)
+}
+
+func (c *composer) mergeRoot(dst, src *yaml.Node, file string) error {
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		key, value := src.Content[i], src.Content[i+1]
+
+		if kind, exists := composeResourceSections[key.Value]; exists {
+			if err := c.mergeResources(dst, key, value, kind, file); err != nil {
+				return err
+			}
+			continue
+		}
+
+		existing := mappingValue(dst, key.Value)
+		if _, replaced := composeReplacedSections[key.Value]; replaced || existing == nil {
+			setMappingValue(dst, key, value)
+			continue
+		}
+		setMappingValue(dst, key, mergeNodes(existing, value))
+	}
+	return nil
+}
+
+func (c *composer) mergeResources(dst, key, value *yaml.Node, kind, file string) error {
+	if value.Kind != yaml.SequenceNode {
+		return fmt.Errorf("config file '%v' field '%v' must be a list", file, key.Value)
+	}
+
+	for _, res := range value.Content {
+		label := resourceLabel(res)
+		if label == "" {
+			return fmt.Errorf("config file '%v' line %v: %v resource must have a label", file, res.Line, kind)
+		}
+
+		labelKey := kind + ":" + label
+		if prev, exists := c.resourceLabels[labelKey]; exists {
+			return fmt.Errorf("%v resource '%v' is defined in both '%v' and '%v'", kind, label, prev, file)
+		}
+		c.resourceLabels[labelKey] = file
+	}
+
+	existing := mappingValue(dst, key.Value)
+	if existing == nil {
+		existing = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		setMappingValue(dst, key, existing)
+	}
+	existing.Content = append(existing.Content, value.Content...)
+	return nil
+}
+
+// mergeNodes merges the fields of src into dst when both are mappings,
+// otherwise src replaces dst.
+func mergeNodes(dst, src *yaml.Node) *yaml.Node {
+	if dst.Kind != yaml.MappingNode || src.Kind != yaml.MappingNode {
+		return src
+	}
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		key, value := src.Content[i], src.Content[i+1]
+		if existing := mappingValue(dst, key.Value); existing != nil {
+			value = mergeNodes(existing, value)
+		}
+		setMappingValue(dst, key, value)
+	}
+	return dst
+}
+
+func readConfigRoot(path string) (*yaml.Node, error) {
+	confBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(confBytes, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse config file '%v': %w", path, err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	return doc.Content[0], nil
+}
+
+// ConfigProfiles returns the names of the profiles defined by a config file in
+// lexicographical order.
+func ConfigProfiles(path string) ([]string, error) {
+	root, err := readConfigRoot(path)
+	if err != nil || root == nil {
+		return nil, err
+	}
+
+	profiles := mappingValue(root, "profiles")
+	if profiles == nil {
+		return nil, nil
+	}
+	if profiles.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("config file '%v' field 'profiles' must be a mapping", path)
+	}
+
+	names := make([]string, 0, len(profiles.Content)/2)
+	for i := 0; i+1 < len(profiles.Content); i += 2 {
+		names = append(names, profiles.Content[i].Value)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// applyProfile removes the profiles field of a config and applies the sections
+// of the named profile over it, returning true if the profile is defined.
+//
+// Sections are merged following the same rules as composing multiple configs,
+// with the exception of resources, which replace any resource of the config
+// that shares the same label.
+func applyProfile(root *yaml.Node, profile, file string) (bool, error) {
+	profiles := removeMappingValue(root, "profiles")
+	if profiles == nil || profile == "" {
+		return false, nil
+	}
+	if profiles.Kind != yaml.MappingNode {
+		return false, fmt.Errorf("config file '%v' field 'profiles' must be a mapping", file)
+	}
+
+	overlay := mappingValue(profiles, profile)
+	if overlay == nil {
+		return false, nil
+	}
+	if overlay.Kind != yaml.MappingNode {
+		return false, fmt.Errorf("config file '%v' profile '%v' must be a mapping", file, profile)
+	}
+
+	for i := 0; i+1 < len(overlay.Content); i += 2 {
+		key, value := overlay.Content[i], overlay.Content[i+1]
+
+		if key.Value == "profiles" {
+			return false, fmt.Errorf("config file '%v' profile '%v' must not contain profiles", file, profile)
+		}
+
+		if _, exists := composeResourceSections[key.Value]; exists {
+			if value.Kind != yaml.SequenceNode {
+				return false, fmt.Errorf("config file '%v' profile '%v' field '%v' must be a list", file, profile, key.Value)
+			}
+			existing := mappingValue(root, key.Value)
+			if existing == nil || existing.Kind != yaml.SequenceNode {
+				setMappingValue(root, key, value)
+				continue
+			}
+			for _, res := range value.Content {
+				replaceResource(existing, res)
+			}
+			continue
+		}
+
+		existing := mappingValue(root, key.Value)
+		if _, replaced := composeReplacedSections[key.Value]; replaced || existing == nil {
+			setMappingValue(root, key, value)
+			continue
+		}
+		setMappingValue(root, key, mergeNodes(existing, value))
+	}
+	return true, nil
+}
+
+func resourceLabel(res *yaml.Node) string {
+	if res.Kind != yaml.MappingNode {
+		return ""
+	}
+	if l := mappingValue(res, "label"); l != nil {
+		return l.Value
+	}
+	return ""
+}
+
+// replaceResource replaces the resource of a list with the same label, or
+// appends it when the label is not present.
+func replaceResource(list, res *yaml.Node) {
+	if label := resourceLabel(res); label != "" {
+		for i, existing := range list.Content {
+			if resourceLabel(existing) == label {
+				list.Content[i] = res
+				return
+			}
+		}
+	}
+	list.Content = append(list.Content, res)
+}
+
+type httpEndpoint struct {
+	file string
+	line int
+}
+
+func (c *composer) checkHTTPPaths(root *yaml.Node) error {
+	globalAddress := composeDefaultHTTPAddress
+	if http := mappingValue(root, "http"); http != nil && http.Kind == yaml.MappingNode {
+		if addr := mappingValue(http, "address"); addr != nil && addr.Value != "" {
+			globalAddress = addr.Value
+		}
+	}
+
+	endpoints := map[string]httpEndpoint{}
+	var walk func(node *yaml.Node, output bool) error
+	walk = func(node *yaml.Node, output bool) error {
+		switch node.Kind {
+		case yaml.SequenceNode:
+			for _, child := range node.Content {
+				if err := walk(child, output); err != nil {
+					return err
+				}
+			}
+			return nil
+		case yaml.MappingNode:
+		default:
+			return nil
+		}
+
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i].Value, node.Content[i+1]
+
+			childOutput := output
+			switch key {
+			case "input", "inputs", "input_resources":
+				childOutput = false
+			case "output", "outputs", "output_resources":
+				childOutput = true
+			}
+
+			if key == "http_server" && value.Kind == yaml.MappingNode {
+				if err := c.registerHTTPServer(endpoints, globalAddress, value, childOutput); err != nil {
+					return err
+				}
+			}
+			if err := walk(value, childOutput); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return walk(root, false)
+}
+
+func (c *composer) registerHTTPServer(endpoints map[string]httpEndpoint, globalAddress string, conf *yaml.Node, output bool) error {
+	address := globalAddress
+	if addr := mappingValue(conf, "address"); addr != nil && addr.Value != "" {
+		address = addr.Value
+	}
+
+	paths := composeHTTPInputPaths
+	if output {
+		paths = composeHTTPOutputPaths
+	}
+
+	fields := make([]string, 0, len(paths))
+	for field := range paths {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	for _, field := range fields {
+		path := paths[field]
+		if v := mappingValue(conf, field); v != nil && v.Value != "" {
+			path = v.Value
+		}
+
+		this := httpEndpoint{file: c.origins[conf], line: conf.Line}
+		endpointKey := address + path
+		if prev, exists := endpoints[endpointKey]; exists {
+			return fmt.Errorf("http path '%v' on address '%v' is served by both '%v' line %v and '%v' line %v", path, address, prev.file, prev.line, this.file, this.line)
+		}
+		endpoints[endpointKey] = this
+	}
+	return nil
+}
+
+func writeComposed(dir, pattern string, composed []byte) (string, error) {
+	f, err := os.CreateTemp(dir, pattern)
+	if err != nil {
+		return "", fmt.Errorf("failed to create composed config file: %w", err)
+	}
+	if _, err := f.Write(composed); err != nil {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+		return "", fmt.Errorf("failed to write composed config file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		_ = os.Remove(f.Name())
+		return "", fmt.Errorf("failed to write composed config file: %w", err)
+	}
+	return f.Name(), nil
+}
+
+// ComposeRunConfig composes the config provided to the run command with any
+// overlays provided with it and the selected profile applied. The config and
+// overlays may be files or directories of files. Configs are only composed
+// when there are overlays, the config is a directory, or the config defines
+// profiles, otherwise nil is returned and the config is read as is.
+func ComposeRunConfig(config string, overlays []string, profile string) (*ComposedConfig, error) {
+	if config == "" {
+		if len(overlays) > 0 {
+			return nil, errors.New("overlays cannot be used without a config provided with --config")
+		}
+		return nil, nil
+	}
+	if len(overlays) == 0 && profile == "" {
+		info, err := os.Stat(config)
+		if err != nil {
+			// Left for the config reader to report.
+			return nil, nil
+		}
+		if !info.IsDir() {
+			if profiles, err := ConfigProfiles(config); err != nil || len(profiles) == 0 {
+				return nil, nil
+			}
+		}
+	}
+
+	files, err := ExpandConfigPaths(append([]string{config}, overlays...))
+	if err != nil {
+		return nil, err
+	}
+	return ComposeConfigs(files, profile)
+}
+
+// Flags of the lint subcommand that are followed by a value.
+var lintValueFlags = map[string]struct{}{
+	"-r": {}, "--resources": {},
+	"-e": {}, "--env-file": {},
+	"-t": {}, "--templates": {},
+}
+
+// lintProfileOptions are the options of the lint subcommand that apply to the
+// linting of configs that define profiles.
+type lintProfileOptions struct {
+	rejectDeprecated bool
+	requireLabels    bool
+	skipEnvVarCheck  bool
+	templates        []string
+}
+
+func (o *lintProfileOptions) parseFlag(arg string) {
+	name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+	enabled := true
+	if hasValue {
+		enabled, _ = strconv.ParseBool(value)
+	}
+	switch name {
+	case "deprecated":
+		o.rejectDeprecated = enabled
+	case "labels":
+		o.requireLabels = enabled
+	case "skip-env-var-check":
+		o.skipEnvVarCheck = o.skipEnvVarCheck || enabled
+	}
+}
+
+func (o *lintProfileOptions) linter(schema *service.ConfigSchema) (*service.StreamConfigLinter, error) {
+	if len(o.templates) > 0 {
+		env := schema.Environment().Clone()
+		for _, pattern := range o.templates {
+			paths, err := filepath.Glob(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("failed to resolve template glob pattern: %w", err)
+			}
+			for _, path := range paths {
+				templateBytes, err := os.ReadFile(path)
+				if err != nil {
+					return nil, fmt.Errorf("failed to read template file: %w", err)
+				}
+				if err := env.RegisterTemplateYAML(string(templateBytes)); err != nil {
+					return nil, fmt.Errorf("template file '%v': %w", path, err)
+				}
+			}
+		}
+		schema = env.FullConfigSchema("", "")
+	}
+	return schema.NewStreamConfigLinter().
+		SetRejectDeprecated(o.rejectDeprecated).
+		SetRequireLabels(o.requireLabels).
+		SetSkipEnvVarCheck(o.skipEnvVarCheck), nil
+}
+
+// LintProfileArgs checks the arguments of the lint subcommand for config files
+// that define profiles, which the linter does not recognise. Each is removed
+// from the arguments and linted with the schema instead, the config without a
+// profile along with each of its profiles, or only the selected profile when a
+// profile is selected. The returned lints are formatted the same as those of
+// the linter, with the file and position that each originates from.
+//
+// Environment files provided with the arguments are only read by the linter,
+// and therefore undefined environment variables are not reported for configs
+// that define profiles when any are provided.
+func LintProfileArgs(schema *service.ConfigSchema, args []string, selected string) (newArgs, lints []string, err error) {
+	cmdIndex := subcommandIndex(args, "lint")
+	if cmdIndex < 0 || isHelpRequested(args[cmdIndex+1:]) {
+		return args, nil, nil
+	}
+
+	var opts lintProfileOptions
+	var files []string
+	newArgs = make([]string, 0, len(args))
+	newArgs = append(newArgs, args[:cmdIndex+1]...)
+	for i := cmdIndex + 1; i < len(args); i++ {
+		arg := args[i]
+		if _, exists := lintValueFlags[arg]; exists && i+1 < len(args) {
+			switch arg {
+			case "-e", "--env-file":
+				opts.skipEnvVarCheck = true
+			case "-t", "--templates":
+				opts.templates = append(opts.templates, args[i+1])
+			}
+			newArgs = append(newArgs, arg, args[i+1])
+			i++
+			continue
+		}
+		if strings.HasPrefix(arg, "-") {
+			opts.parseFlag(arg)
+			newArgs = append(newArgs, arg)
+			continue
+		}
+
+		profiles, err := ConfigProfiles(arg)
+		if err != nil || len(profiles) == 0 {
+			// Paths that cannot be read are left for the linter to report.
+			newArgs = append(newArgs, arg)
+			continue
+		}
+		files = append(files, arg)
+	}
+	if len(files) == 0 {
+		return args, nil, nil
+	}
+
+	linter, err := opts.linter(schema)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	seen := map[string]struct{}{}
+	for _, file := range files {
+		profiles := []string{selected}
+		if selected == "" {
+			p, _ := ConfigProfiles(file)
+			profiles = append(profiles, p...)
+		}
+		for _, profile := range profiles {
+			composed, err := ComposeConfigs([]string{file}, profile)
+			if err != nil {
+				return nil, nil, err
+			}
+			composedLints, err := composed.Lint(linter)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to lint config file '%v': %w", file, err)
+			}
+			// Lints of the config without a profile are repeated for each
+			// profile that does not override the field.
+			for _, l := range composedLints {
+				if _, exists := seen[l]; exists {
+					continue
+				}
+				seen[l] = struct{}{}
+				lints = append(lints, l)
+			}
+		}
+	}
+	return newArgs, lints, nil
+}
@@ -0,0 +1,510 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed as a Redpanda Enterprise file under the Redpanda Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+// https://github.com/redpanda-data/connect/blob/main/licenses/rcl.md
+
+package cli_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"github.com/redpanda-data/connect/v4/internal/cli"
+)
+
+func writeComposeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+}
+
+func TestComposeConfigs(t *testing.T) {
+	dir := t.TempDir()
+	writeComposeFiles(t, dir, map[string]string{
+		"base.yaml": `
+http:
+  address: 0.0.0.0:4195
+  debug_endpoints: true
+logger:
+  level: INFO
+  format: json
+metrics:
+  prometheus: {}
+input:
+  stdin: {}
+cache_resources:
+  - label: shared
+    memory: {}
+`,
+		"pipeline.yaml": `
+logger:
+  level: DEBUG
+input:
+  generate:
+    mapping: 'root = "hello"'
+cache_resources:
+  - label: local
+    memory: {}
+output:
+  drop: {}
+`,
+	})
+
+	composed, err := cli.ComposeConfigs([]string{
+		filepath.Join(dir, "base.yaml"),
+		filepath.Join(dir, "pipeline.yaml"),
+	}, "")
+	require.NoError(t, err)
+
+	var conf map[string]any
+	require.NoError(t, yaml.Unmarshal(composed.Bytes, &conf))
+
+	assert.Equal(t, map[string]any{
+		"http": map[string]any{
+			"address":         "0.0.0.0:4195",
+			"debug_endpoints": true,
+		},
+		"logger": map[string]any{
+			"level":  "DEBUG",
+			"format": "json",
+		},
+		"metrics": map[string]any{
+			"prometheus": map[string]any{},
+		},
+		"input": map[string]any{
+			"generate": map[string]any{
+				"mapping": `root = "hello"`,
+			},
+		},
+		"cache_resources": []any{
+			map[string]any{"label": "shared", "memory": map[string]any{}},
+			map[string]any{"label": "local", "memory": map[string]any{}},
+		},
+		"output": map[string]any{
+			"drop": map[string]any{},
+		},
+	}, conf)
+}
+
+func TestComposeConfigsConflicts(t *testing.T) {
+	for _, testCase := range []struct {
+		name                string
+		files               []string
+		expectedErrContains string
+	}{
+		{
+			name: "duplicate resource label",
+			files: []string{`
+cache_resources:
+  - label: foo
+    memory: {}
+`, `
+cache_resources:
+  - label: foo
+    memory: {}
+`},
+			expectedErrContains: `cache resource 'foo' is defined in both`,
+		},
+		{
+			name: "same label different resource types",
+			files: []string{`
+cache_resources:
+  - label: foo
+    memory: {}
+`, `
+rate_limit_resources:
+  - label: foo
+    local: {}
+`},
+		},
+		{
+			name: "missing resource label",
+			files: []string{`
+processor_resources:
+  - mapping: 'root = this'
+`},
+			expectedErrContains: `processor resource must have a label`,
+		},
+		{
+			name: "http server input paths collide",
+			files: []string{`
+input:
+  http_server:
+    path: /foo
+`, `
+output_resources:
+  - label: bar
+    http_server:
+      path: /foo
+`},
+			expectedErrContains: `http path '/foo' on address '0.0.0.0:4195' is served by both`,
+		},
+		{
+			name: "http server default paths collide",
+			files: []string{`
+input_resources:
+  - label: a
+    http_server: {}
+`, `
+input_resources:
+  - label: b
+    http_server: {}
+`},
+			expectedErrContains: `http path '/post' on address '0.0.0.0:4195'`,
+		},
+		{
+			name: "http server separate addresses",
+			files: []string{`
+input_resources:
+  - label: a
+    http_server: {}
+`, `
+input_resources:
+  - label: b
+    http_server:
+      address: 0.0.0.0:8080
+`},
+		},
+		{
+			name: "http server input and output defaults",
+			files: []string{`
+input:
+  http_server: {}
+`, `
+output:
+  broker:
+    outputs:
+      - http_server: {}
+`},
+		},
+		{
+			name: "replaced input does not collide",
+			files: []string{`
+input:
+  http_server: {}
+`, `
+input:
+  http_server: {}
+`},
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			dir := t.TempDir()
+
+			var paths []string
+			for i, content := range testCase.files {
+				p := filepath.Join(dir, string(rune('a'+i))+".yaml")
+				require.NoError(t, os.WriteFile(p, []byte(content), 0o644))
+				paths = append(paths, p)
+			}
+
+			_, err := cli.ComposeConfigs(paths, "")
+			if testCase.expectedErrContains != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), testCase.expectedErrContains)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestExpandConfigPaths(t *testing.T) {
+	dir := t.TempDir()
+	writeComposeFiles(t, dir, map[string]string{
+		"b.yaml":     ``,
+		"a.yml":      ``,
+		"readme.txt": ``,
+	})
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "nested"), 0o755))
+
+	other := filepath.Join(t.TempDir(), "other.yaml")
+	require.NoError(t, os.WriteFile(other, nil, 0o644))
+
+	files, err := cli.ExpandConfigPaths([]string{other, dir})
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		other,
+		filepath.Join(dir, "a.yml"),
+		filepath.Join(dir, "b.yaml"),
+	}, files)
+
+	_, err = cli.ExpandConfigPaths([]string{filepath.Join(dir, "nested")})
+	require.Error(t, err)
+}
+
+func TestComposeRunConfig(t *testing.T) {
+	dir := t.TempDir()
+	writeComposeFiles(t, dir, map[string]string{
+		"a.yaml": "logger:\n  level: INFO\n",
+		"b.yaml": "logger:\n  level: DEBUG\n",
+	})
+	a, b := filepath.Join(dir, "a.yaml"), filepath.Join(dir, "b.yaml")
+
+	composed, err := cli.ComposeRunConfig(a, nil, "")
+	require.NoError(t, err)
+	assert.Nil(t, composed)
+
+	composed, err = cli.ComposeRunConfig("", nil, "")
+	require.NoError(t, err)
+	assert.Nil(t, composed)
+
+	composed, err = cli.ComposeRunConfig(a, []string{b}, "")
+	require.NoError(t, err)
+	require.NotNil(t, composed)
+	assert.Contains(t, string(composed.Bytes), "level: DEBUG")
+
+	composedPath, err := composed.WriteRunConfig()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.Remove(composedPath) })
+
+	written, err := os.ReadFile(composedPath)
+	require.NoError(t, err)
+	assert.Contains(t, string(written), "level: DEBUG")
+
+	composed, err = cli.ComposeRunConfig(dir, nil, "")
+	require.NoError(t, err)
+	require.NotNil(t, composed)
+	assert.Contains(t, string(composed.Bytes), "level: DEBUG")
+
+	_, err = cli.ComposeRunConfig("", []string{b}, "")
+	require.Error(t, err)
+}
+
+func TestComposedConfigLint(t *testing.T) {
+	dir := t.TempDir()
+	writeComposeFiles(t, dir, map[string]string{
+		"a.yaml": `
+input:
+  foo:
+    address: localhost:1234
+output:
+  inproc: {}
+`,
+		"b.yaml": `
+logger:
+  level: DEBUG
+
+input:
+  foo:
+    nope: true
+    password: foo
+`,
+	})
+	a, b := filepath.Join(dir, "a.yaml"), filepath.Join(dir, "b.yaml")
+
+	composed, err := cli.ComposeRunConfig(a, []string{b}, "")
+	require.NoError(t, err)
+	require.NotNil(t, composed)
+
+	lints, err := composed.Lint(docsTestSchema(t).NewStreamConfigLinter())
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		b + "(7,1) field nope not recognised",
+	}, lints)
+
+	composed, err = cli.ComposeRunConfig(a, nil, "missing")
+	require.Error(t, err)
+	assert.Nil(t, composed)
+}
+
+func TestComposeConfigsProfiles(t *testing.T) {
+	dir := t.TempDir()
+	writeComposeFiles(t, dir, map[string]string{
+		"a.yaml": `
+logger:
+  level: INFO
+  format: json
+cache_resources:
+  - label: dedupe
+    memory: {}
+  - label: other
+    memory: {}
+input:
+  stdin: {}
+output:
+  drop: {}
+profiles:
+  prod:
+    logger:
+      level: WARN
+    cache_resources:
+      - label: dedupe
+        redis:
+          url: redis://prod:6379
+    output:
+      kafka_franz:
+        seed_brokers: [ prod:9092 ]
+        topic: foo
+  dev: {}
+`,
+		"b.yaml": `
+http:
+  enabled: false
+profiles:
+  staging:
+    http:
+      enabled: true
+`,
+	})
+	files := []string{filepath.Join(dir, "a.yaml"), filepath.Join(dir, "b.yaml")}
+
+	composed, err := cli.ComposeConfigs(files, "prod")
+	require.NoError(t, err)
+
+	var conf map[string]any
+	require.NoError(t, yaml.Unmarshal(composed.Bytes, &conf))
+
+	assert.Equal(t, map[string]any{
+		"logger": map[string]any{
+			"level":  "WARN",
+			"format": "json",
+		},
+		"cache_resources": []any{
+			map[string]any{"label": "dedupe", "redis": map[string]any{"url": "redis://prod:6379"}},
+			map[string]any{"label": "other", "memory": map[string]any{}},
+		},
+		"input": map[string]any{
+			"stdin": map[string]any{},
+		},
+		"output": map[string]any{
+			"kafka_franz": map[string]any{
+				"seed_brokers": []any{"prod:9092"},
+				"topic":        "foo",
+			},
+		},
+		"http": map[string]any{
+			"enabled": false,
+		},
+	}, conf)
+
+	composed, err = cli.ComposeConfigs(files, "staging")
+	require.NoError(t, err)
+
+	conf = nil
+	require.NoError(t, yaml.Unmarshal(composed.Bytes, &conf))
+	assert.Equal(t, map[string]any{"enabled": true}, conf["http"])
+	assert.Equal(t, map[string]any{"drop": map[string]any{}}, conf["output"])
+	assert.NotContains(t, conf, "profiles")
+
+	_, err = cli.ComposeConfigs(files, "nope")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "profile 'nope' is not defined")
+
+	profiles, err := cli.ConfigProfiles(files[0])
+	require.NoError(t, err)
+	assert.Equal(t, []string{"dev", "prod"}, profiles)
+}
+
+func TestComposeRunConfigProfiles(t *testing.T) {
+	dir := t.TempDir()
+	writeComposeFiles(t, dir, map[string]string{
+		"a.yaml": `
+logger:
+  level: INFO
+profiles:
+  prod:
+    logger:
+      level: WARN
+`,
+		"plain.yaml": `
+logger:
+  level: INFO
+`,
+	})
+	a, plain := filepath.Join(dir, "a.yaml"), filepath.Join(dir, "plain.yaml")
+
+	// A config with profiles is composed even without a profile selected.
+	composed, err := cli.ComposeRunConfig(a, nil, "")
+	require.NoError(t, err)
+	require.NotNil(t, composed)
+	assert.Contains(t, string(composed.Bytes), "level: INFO")
+	assert.NotContains(t, string(composed.Bytes), "profiles")
+
+	composed, err = cli.ComposeRunConfig(a, nil, "prod")
+	require.NoError(t, err)
+	require.NotNil(t, composed)
+	assert.Contains(t, string(composed.Bytes), "level: WARN")
+
+	file, line, column := composed.Source(4, 12)
+	assert.Equal(t, a, file)
+	assert.Equal(t, 7, line)
+	assert.Equal(t, 14, column)
+
+	_, err = cli.ComposeRunConfig(plain, nil, "prod")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "profile 'prod' is not defined")
+}
+
+func TestLintProfileArgs(t *testing.T) {
+	dir := t.TempDir()
+	writeComposeFiles(t, dir, map[string]string{
+		"a.yaml": `
+input:
+  foo:
+    nope: true
+    password: foo
+output:
+  inproc: {}
+profiles:
+  prod:
+    input:
+      foo:
+        password: foo
+        also_nope: true
+  dev:
+    input:
+      foo:
+        password: '${DEV_PASSWORD}'
+`,
+		"plain.yaml": `
+input:
+  foo:
+    nope: true
+`,
+	})
+	a, plain := filepath.Join(dir, "a.yaml"), filepath.Join(dir, "plain.yaml")
+	schema := docsTestSchema(t)
+
+	args := []string{"connect", "lint", "-r", a, plain}
+	newArgs, lints, err := cli.LintProfileArgs(schema, args, "")
+	require.NoError(t, err)
+	assert.Empty(t, lints)
+	assert.Equal(t, args, newArgs)
+
+	newArgs, lints, err = cli.LintProfileArgs(schema, []string{"connect", "lint", "--deprecated", a, plain}, "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"connect", "lint", "--deprecated", plain}, newArgs)
+	assert.Equal(t, []string{
+		a + "(4,1) field nope not recognised",
+		a + "(1,1) required environment variables were not set: [DEV_PASSWORD]",
+		a + "(13,1) field also_nope not recognised",
+	}, lints)
+
+	newArgs, lints, err = cli.LintProfileArgs(schema, []string{"connect", "lint", "--skip-env-var-check", a}, "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"connect", "lint", "--skip-env-var-check"}, newArgs)
+	assert.Equal(t, []string{
+		a + "(4,1) field nope not recognised",
+		a + "(13,1) field also_nope not recognised",
+	}, lints)
+
+	newArgs, lints, err = cli.LintProfileArgs(schema, []string{"connect", "lint", a, plain}, "prod")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"connect", "lint", plain}, newArgs)
+	assert.Equal(t, []string{
+		a + "(13,1) field also_nope not recognised",
+	}, lints)
+
+	_, _, err = cli.LintProfileArgs(schema, []string{"connect", "lint", a}, "missing")
+	require.Error(t, err)
+}
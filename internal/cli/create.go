@@ -0,0 +1,385 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed as a Redpanda Enterprise file under the Redpanda Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+// https://github.com/redpanda-data/connect/blob/main/licenses/rcl.md
+
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// The comment attached to fields of example configs that must be set.
+const createRequiredComment = "No default (required)"
+
+const createDefaultMessage = `{"message":"hello world"}`
+
+// The flags added to the create subcommand.
+var createFlags = subcommandFlags{
+	command: "create",
+	flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:    "interactive",
+			Aliases: []string{"i"},
+			Usage:   "Ask for the components of the config and the values of their required fields, and write the config along with a unit test file for it.",
+		},
+	},
+}
+
+// IsInteractiveCreate parses the flags added to the create subcommand from the
+// provided CLI arguments, and returns true when the interactive flag is set.
+func IsInteractiveCreate(args []string) (bool, error) {
+	_, c, err := createFlags.parse(args)
+	if err != nil || c == nil {
+		return false, err
+	}
+	return c.Bool("interactive"), nil
+}
+
+type interactiveCreator struct {
+	in    *bufio.Scanner
+	out   io.Writer
+	views map[string]map[string]*service.ConfigView
+}
+
+// RunInteractiveCreate asks for the input, processors and output of a new
+// config, along with the values of their required fields, and writes the
+// config with the common fields of each component set to their defaults. A
+// unit test file for the config is written next to it, following the naming
+// convention of the test subcommand.
+//
+// The config is verified with env before being written, and an error is
+// returned without writing any files if either file already exists.
+func RunInteractiveCreate(env *service.Environment, in io.Reader, out io.Writer) error {
+	c := &interactiveCreator{
+		in:    bufio.NewScanner(in),
+		out:   out,
+		views: map[string]map[string]*service.ConfigView{},
+	}
+	walker := func(kind string) func(string, *service.ConfigView) {
+		c.views[kind] = map[string]*service.ConfigView{}
+		return func(name string, view *service.ConfigView) {
+			if !view.IsDeprecated() {
+				c.views[kind][name] = view
+			}
+		}
+	}
+	env.WalkInputs(walker("input"))
+	env.WalkProcessors(walker("processor"))
+	env.WalkOutputs(walker("output"))
+
+	fmt.Fprintln(out, "Enter ? at any component prompt to list the available components.")
+
+	inputName, err := c.askComponent("input", "stdin")
+	if err != nil {
+		return err
+	}
+	inputNode, err := c.componentNode("input", inputName)
+	if err != nil {
+		return err
+	}
+
+	var processors []*yaml.Node
+	for {
+		procName, err := c.askComponent("processor", "")
+		if err != nil {
+			return err
+		}
+		if procName == "" {
+			break
+		}
+		procNode, err := c.componentNode("processor", procName)
+		if err != nil {
+			return err
+		}
+		processors = append(processors, procNode)
+	}
+
+	outputName, err := c.askComponent("output", "stdout")
+	if err != nil {
+		return err
+	}
+	outputNode, err := c.componentNode("output", outputName)
+	if err != nil {
+		return err
+	}
+
+	confBytes, err := createConfigYAML(inputNode, processors, outputNode)
+	if err != nil {
+		return err
+	}
+	if err := env.NewStreamBuilder().SetYAML(string(confBytes)); err != nil {
+		return fmt.Errorf("generated config is invalid: %w", err)
+	}
+
+	sample, err := c.ask("Message to process within the unit test", createDefaultMessage)
+	if err != nil {
+		return err
+	}
+	testBytes, err := createTestYAML(sample, len(processors) > 0)
+	if err != nil {
+		return err
+	}
+
+	confPath, err := c.ask("Path of the config file", "connect.yaml")
+	if err != nil {
+		return err
+	}
+	testPath := strings.TrimSuffix(confPath, filepath.Ext(confPath)) + "_benthos_test.yaml"
+	for _, p := range []string{confPath, testPath} {
+		if _, err := os.Stat(p); err == nil {
+			return fmt.Errorf("file '%v' already exists", p)
+		}
+	}
+
+	if err := os.WriteFile(confPath, confBytes, 0o644); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	if err := os.WriteFile(testPath, testBytes, 0o644); err != nil {
+		return fmt.Errorf("failed to write unit test file: %w", err)
+	}
+	fmt.Fprintf(out, "Config written to %v and unit tests to %v\n", confPath, testPath)
+	return nil
+}
+
+func (c *interactiveCreator) ask(question, def string) (string, error) {
+	if def != "" {
+		fmt.Fprintf(c.out, "%v [%v]: ", question, def)
+	} else {
+		fmt.Fprintf(c.out, "%v: ", question)
+	}
+	if !c.in.Scan() {
+		if err := c.in.Err(); err != nil {
+			return "", err
+		}
+		return "", errors.New("unexpected end of input")
+	}
+	if answer := strings.TrimSpace(c.in.Text()); answer != "" {
+		return answer, nil
+	}
+	return def, nil
+}
+
+func (c *interactiveCreator) askComponent(kind, def string) (string, error) {
+	question := "Type of the " + kind
+	if kind == "processor" {
+		question = "Type of a processor to add, or empty to continue"
+	}
+	for {
+		name, err := c.ask(question, def)
+		if err != nil {
+			return "", err
+		}
+		if name == "" && kind == "processor" {
+			return "", nil
+		}
+		if name == "?" {
+			names := make([]string, 0, len(c.views[kind]))
+			for n := range c.views[kind] {
+				names = append(names, n)
+			}
+			sort.Strings(names)
+			fmt.Fprintln(c.out, strings.Join(names, ", "))
+			continue
+		}
+		if _, exists := c.views[kind][name]; exists {
+			return name, nil
+		}
+		fmt.Fprintf(c.out, "The %v '%v' is not recognised.\n", kind, name)
+	}
+}
+
+// componentNode returns the example config of a component containing its
+// common fields, where the values of required fields are asked for.
+func (c *interactiveCreator) componentNode(kind, name string) (*yaml.Node, error) {
+	data, err := c.views[kind][name].TemplateData()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate %v config: %w", kind, err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(data.CommonConfigYAML), &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse %v config: %w", kind, err)
+	}
+	if len(doc.Content) == 0 {
+		return nil, fmt.Errorf("%v '%v' has no example config", kind, name)
+	}
+
+	// The example configs of processors are not nested under the type.
+	root := doc.Content[0]
+	if kind != "processor" {
+		if root = mappingValue(root, kind); root == nil {
+			return nil, fmt.Errorf("%v '%v' has no example config", kind, name)
+		}
+	}
+	if label := mappingValue(root, "label"); label != nil && label.Value == "" {
+		_ = removeMappingValue(root, "label")
+	}
+
+	fields := make(map[string]service.TemplateDataPluginField, len(data.Fields))
+	for _, f := range data.Fields {
+		fields[f.FullName] = f
+	}
+
+	conf := mappingValue(root, name)
+	if conf == nil {
+		return root, nil
+	}
+	if strings.Contains(conf.LineComment, createRequiredComment) {
+		if err := c.askField(conf, name, name, service.TemplateDataPluginField{}); err != nil {
+			return nil, err
+		}
+	}
+	if err := c.askRequiredFields(conf, name, "", fields); err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+func (c *interactiveCreator) askRequiredFields(node *yaml.Node, name, prefix string, fields map[string]service.TemplateDataPluginField) error {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i].Value, node.Content[i+1]
+		path := prefix + key
+		if strings.Contains(value.LineComment, createRequiredComment) {
+			if err := c.askField(value, name, path, fields[path]); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := c.askRequiredFields(value, name, path+".", fields); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// askField asks for the value of a required field and replaces the node with
+// it, where values are parsed as YAML.
+func (c *interactiveCreator) askField(node *yaml.Node, name, path string, field service.TemplateDataPluginField) error {
+	var def string
+	if len(field.Examples) > 0 {
+		if exBytes, err := json.Marshal(field.Examples[0]); err == nil {
+			def = string(exBytes)
+		}
+	}
+
+	question := fmt.Sprintf("Value of %v field %v", name, path)
+	if name == path {
+		question = fmt.Sprintf("Value of %v", name)
+	}
+	for {
+		answer, err := c.ask(question, def)
+		if err != nil {
+			return err
+		}
+		if answer == "" {
+			fmt.Fprintln(c.out, "A value is required.")
+			continue
+		}
+
+		var doc yaml.Node
+		if err := yaml.Unmarshal([]byte(answer), &doc); err != nil || len(doc.Content) == 0 {
+			fmt.Fprintf(c.out, "The value is not valid YAML: %v\n", err)
+			continue
+		}
+		value := doc.Content[0]
+		if field.Type == "array" && value.Kind != yaml.SequenceNode {
+			value = &yaml.Node{
+				Kind:    yaml.SequenceNode,
+				Tag:     "!!seq",
+				Style:   yaml.FlowStyle,
+				Content: []*yaml.Node{value},
+			}
+		}
+		*node = *value
+		return nil
+	}
+}
+
+func marshalCreateYAML(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func createConfigYAML(input *yaml.Node, processors []*yaml.Node, output *yaml.Node) ([]byte, error) {
+	str := func(v string) *yaml.Node {
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: v}
+	}
+	procs := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Content: processors}
+	root := &yaml.Node{
+		Kind: yaml.MappingNode,
+		Tag:  "!!map",
+		Content: []*yaml.Node{
+			str("input"), input,
+			str("pipeline"), {
+				Kind:    yaml.MappingNode,
+				Tag:     "!!map",
+				Content: []*yaml.Node{str("processors"), procs},
+			},
+			str("output"), output,
+		},
+	}
+
+	confBytes, err := marshalCreateYAML(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+	return confBytes, nil
+}
+
+func createTestYAML(sample string, hasProcessors bool) ([]byte, error) {
+	condition := map[string]any{"content_equals": sample}
+	if hasProcessors {
+		condition = map[string]any{"bloblang": "!errored()"}
+	}
+	tests := map[string]any{
+		"tests": []any{
+			map[string]any{
+				"name":              "processes a message",
+				"target_processors": "/pipeline/processors",
+				"input_batch": []any{
+					map[string]any{"content": sample},
+				},
+				"output_batches": []any{
+					[]any{condition},
+				},
+			},
+		},
+	}
+
+	testBytes, err := marshalCreateYAML(tests)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal unit tests: %w", err)
+	}
+	if hasProcessors {
+		testBytes = append([]byte("# Replace the condition that the message is not errored with assertions of the\n# expected output.\n"), testBytes...)
+	}
+	return testBytes, nil
+}
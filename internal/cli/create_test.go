@@ -0,0 +1,128 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed as a Redpanda Enterprise file under the Redpanda Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+// https://github.com/redpanda-data/connect/blob/main/licenses/rcl.md
+
+package cli_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/connect/v4/internal/cli"
+)
+
+func createTestEnv(t testing.TB) *service.Environment {
+	t.Helper()
+	env := service.NewEmptyEnvironment()
+	require.NoError(t, env.RegisterInput("foo", service.NewConfigSpec().
+		Fields(
+			service.NewStringListField("addresses").Example([]string{"localhost:9092"}),
+			service.NewStringField("topic"),
+			service.NewIntField("retries").Default(3),
+		),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Input, error) {
+			return nil, nil
+		}))
+	require.NoError(t, env.RegisterProcessor("transform", service.NewConfigSpec().Field(service.NewStringField("")),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return nil, nil
+		}))
+	require.NoError(t, env.RegisterOutput("bar", service.NewConfigSpec().
+		Fields(service.NewStringField("url").Default("http://localhost")),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Output, int, error) {
+			return nil, 1, nil
+		}))
+
+	// The defaults of a stream config refer to these components.
+	require.NoError(t, env.RegisterBatchBuffer("none", service.NewConfigSpec(), nil))
+	require.NoError(t, env.RegisterMetricsExporter("none", service.NewConfigSpec(), nil))
+	require.NoError(t, env.RegisterOtelTracerProvider("none", service.NewConfigSpec(), nil))
+	return env
+}
+
+func TestIsInteractiveCreate(t *testing.T) {
+	for _, test := range []struct {
+		args []string
+		exp  bool
+	}{
+		{args: []string{"connect", "create", "-i"}, exp: true},
+		{args: []string{"connect", "create", "--interactive"}, exp: true},
+		{args: []string{"connect", "create", "--small", "-i=true"}, exp: true},
+		{args: []string{"connect", "create", "--interactive=false"}},
+		{args: []string{"connect", "create", "stdin//stdout"}},
+		{args: []string{"connect", "create", "--", "-i"}},
+		{args: []string{"connect", "--log.level", "warn", "--chilled", "create", "-i"}, exp: true},
+		{args: []string{"connect", "-c", "create", "lint", "-i"}},
+		{args: []string{"connect", "create", "-i", "--help"}},
+		{args: []string{"connect", "lint", "-i"}},
+	} {
+		interactive, err := cli.IsInteractiveCreate(test.args)
+		require.NoError(t, err, test.args)
+		assert.Equal(t, test.exp, interactive, test.args)
+	}
+
+	_, err := cli.IsInteractiveCreate([]string{"connect", "create", "--interactive=maybe"})
+	require.Error(t, err)
+}
+
+func TestRunInteractiveCreate(t *testing.T) {
+	dir := t.TempDir()
+	confPath := filepath.Join(dir, "pipeline.yaml")
+
+	answers := strings.Join([]string{
+		"nope",
+		"foo",
+		"",          // addresses, uses the example
+		"",          // topic, which is required
+		"orders",    // topic
+		"transform", // processor
+		"root = this.uppercase()",
+		"",    // no more processors
+		"bar", // output
+		"",    // sample message
+		confPath,
+	}, "\n") + "\n"
+
+	var out bytes.Buffer
+	require.NoError(t, cli.RunInteractiveCreate(createTestEnv(t), strings.NewReader(answers), &out))
+	assert.Contains(t, out.String(), "The input 'nope' is not recognised.")
+	assert.Contains(t, out.String(), "A value is required.")
+
+	confBytes, err := os.ReadFile(confPath)
+	require.NoError(t, err)
+	assert.Equal(t, `input:
+  foo:
+    addresses: ["localhost:9092"]
+    topic: orders
+    retries: 3
+pipeline:
+  processors:
+    - transform: root = this.uppercase()
+output:
+  bar:
+    url: http://localhost
+`, string(confBytes))
+
+	testBytes, err := os.ReadFile(filepath.Join(dir, "pipeline_benthos_test.yaml"))
+	require.NoError(t, err)
+	assert.Contains(t, string(testBytes), "target_processors: /pipeline/processors")
+	assert.Contains(t, string(testBytes), `content: '{"message":"hello world"}'`)
+	assert.Contains(t, string(testBytes), "bloblang: '!errored()'")
+
+	// Existing files are never overwritten.
+	answers = strings.Join([]string{"foo", "", "orders", "", "bar", "", confPath}, "\n") + "\n"
+	err = cli.RunInteractiveCreate(createTestEnv(t), strings.NewReader(answers), &out)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "already exists")
+}
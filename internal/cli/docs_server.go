@@ -0,0 +1,229 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed as a Redpanda Enterprise file under the Redpanda Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+// https://github.com/redpanda-data/connect/blob/main/licenses/rcl.md
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+	"gopkg.in/yaml.v3"
+)
+
+// DocsServer serves the documentation of the components registered within a
+// schema at the path `/docs/components/{type}/{name}`, along with the config of
+// every instance of the component within the loaded config. The documentation
+// is rendered from the running binary, and therefore always matches the
+// components and fields it supports.
+type DocsServer struct {
+	schema *service.ConfigSchema
+	mux    *http.ServeMux
+
+	mut    sync.RWMutex
+	config any
+}
+
+// NewDocsServer creates a documentation server for the components of a schema.
+func NewDocsServer(schema *service.ConfigSchema) *DocsServer {
+	d := &DocsServer{
+		schema: schema,
+		mux:    http.NewServeMux(),
+	}
+	d.mux.HandleFunc("GET /docs/components/{type}/{name}", d.handleComponent)
+	return d
+}
+
+// SetConfig sets the loaded config, where the values of fields marked as
+// secrets are scrubbed before the config is stored.
+func (d *DocsServer) SetConfig(v any) error {
+	scrubbed, err := d.schema.NewStreamConfigMarshaller().
+		SetScrubSecrets(true).
+		AnyToYAML(v)
+	if err != nil {
+		return fmt.Errorf("failed to scrub config: %w", err)
+	}
+
+	var config any
+	if err := yaml.Unmarshal([]byte(scrubbed), &config); err != nil {
+		return fmt.Errorf("failed to parse scrubbed config: %w", err)
+	}
+
+	d.mut.Lock()
+	d.config = config
+	d.mut.Unlock()
+	return nil
+}
+
+// ServeHTTP implements http.Handler.
+func (d *DocsServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	d.mux.ServeHTTP(w, r)
+}
+
+func (d *DocsServer) componentView(cType, name string) (*service.ConfigView, bool) {
+	env := d.schema.Environment()
+
+	var walkFn func(fn func(name string, config *service.ConfigView))
+	switch cType {
+	case "input":
+		walkFn = env.WalkInputs
+	case "buffer":
+		walkFn = env.WalkBuffers
+	case "processor":
+		walkFn = env.WalkProcessors
+	case "output":
+		walkFn = env.WalkOutputs
+	case "cache":
+		walkFn = env.WalkCaches
+	case "rate_limit":
+		walkFn = env.WalkRateLimits
+	case "metrics":
+		walkFn = env.WalkMetrics
+	case "tracer":
+		walkFn = env.WalkTracers
+	case "scanner":
+		walkFn = env.WalkScanners
+	default:
+		return nil, false
+	}
+
+	// The environment is walked rather than using the getters, since those
+	// ignore components registered only within this environment.
+	var view *service.ConfigView
+	walkFn(func(n string, c *service.ConfigView) {
+		if n == name {
+			view = c
+		}
+	})
+	return view, view != nil
+}
+
+type docsField struct {
+	Name         string   `json:"name"`
+	Type         string   `json:"type"`
+	Description  string   `json:"description"`
+	Default      string   `json:"default,omitempty"`
+	Options      []string `json:"options,omitempty"`
+	Examples     []any    `json:"examples,omitempty"`
+	Secret       bool     `json:"is_secret,omitempty"`
+	Interpolated bool     `json:"interpolated,omitempty"`
+	Version      string   `json:"version,omitempty"`
+}
+
+type docsExample struct {
+	Title   string `json:"title"`
+	Summary string `json:"summary"`
+	Config  string `json:"config"`
+}
+
+type docsInstance struct {
+	Path   string `json:"path"`
+	Label  string `json:"label,omitempty"`
+	Config any    `json:"config"`
+}
+
+type docsComponent struct {
+	Type        string         `json:"type"`
+	Name        string         `json:"name"`
+	Status      string         `json:"status"`
+	Version     string         `json:"version,omitempty"`
+	Summary     string         `json:"summary"`
+	Description string         `json:"description"`
+	Fields      []docsField    `json:"fields"`
+	Examples    []docsExample  `json:"examples"`
+	Instances   []docsInstance `json:"instances"`
+}
+
+func (d *DocsServer) handleComponent(w http.ResponseWriter, r *http.Request) {
+	cType, name := r.PathValue("type"), r.PathValue("name")
+
+	view, exists := d.componentView(cType, name)
+	if !exists {
+		http.Error(w, fmt.Sprintf("%v %v is not registered", cType, name), http.StatusNotFound)
+		return
+	}
+
+	if r.URL.Query().Get("format") == "asciidoc" {
+		b, err := view.RenderDocs()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/asciidoc; charset=utf-8")
+		_, _ = w.Write(b)
+		return
+	}
+
+	data, err := view.TemplateData()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	res := docsComponent{
+		Type:        cType,
+		Name:        name,
+		Status:      data.Status,
+		Version:     data.Version,
+		Summary:     data.Summary,
+		Description: data.Description,
+		Fields:      []docsField{},
+		Examples:    []docsExample{},
+		Instances:   []docsInstance{},
+	}
+	for _, f := range data.Fields {
+		res.Fields = append(res.Fields, docsField{
+			Name:         f.FullName,
+			Type:         f.Type,
+			Description:  f.Description,
+			Default:      f.DefaultMarshalled,
+			Options:      f.Options,
+			Examples:     f.Examples,
+			Secret:       f.IsSecret,
+			Interpolated: f.IsInterpolated,
+			Version:      f.Version,
+		})
+	}
+	for _, e := range data.Examples {
+		res.Examples = append(res.Examples, docsExample{
+			Title:   e.Title,
+			Summary: e.Summary,
+			Config:  e.Config,
+		})
+	}
+
+	d.mut.RLock()
+	config := d.config
+	d.mut.RUnlock()
+
+	if config != nil {
+		if err := d.schema.NewStreamConfigWalker().WalkComponentsAny(config, func(c *service.WalkedComponent) error {
+			if c.ComponentType != cType || c.Name != name {
+				return nil
+			}
+			v, err := c.ConfigAny()
+			if err != nil {
+				return err
+			}
+			res.Instances = append(res.Instances, docsInstance{
+				Path:   c.Path,
+				Label:  c.Label,
+				Config: v,
+			})
+			return nil
+		}); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
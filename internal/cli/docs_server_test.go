@@ -0,0 +1,131 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed as a Redpanda Enterprise file under the Redpanda Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+// https://github.com/redpanda-data/connect/blob/main/licenses/rcl.md
+
+package cli_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"github.com/redpanda-data/connect/v4/internal/cli"
+)
+
+func docsTestSchema(t testing.TB) *service.ConfigSchema {
+	t.Helper()
+	env := service.NewEmptyEnvironment()
+	require.NoError(t, env.RegisterInput("foo", service.NewConfigSpec().
+		Summary("Reads foo.").
+		Fields(
+			service.NewStringField("address").Description("The address of foo.").Default("localhost"),
+			service.NewStringField("password").Description("The password of foo.").Secret(),
+		).
+		Example("Basic", "Connect to foo.", "input:\n  foo:\n    password: bar\n"),
+		nil))
+	require.NoError(t, env.RegisterCache("bar", service.NewConfigSpec(), nil))
+
+	// The defaults of a stream config refer to these components.
+	require.NoError(t, env.RegisterBatchBuffer("none", service.NewConfigSpec(), nil))
+	require.NoError(t, env.RegisterOutput("inproc", service.NewConfigSpec(), nil))
+	require.NoError(t, env.RegisterMetricsExporter("none", service.NewConfigSpec(), nil))
+	require.NoError(t, env.RegisterOtelTracerProvider("none", service.NewConfigSpec(), nil))
+	return env.FullConfigSchema("", "")
+}
+
+func getDocs(t *testing.T, srv *cli.DocsServer, path string) (int, map[string]any) {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	if rec.Code != http.StatusOK {
+		return rec.Code, nil
+	}
+
+	var res map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+	return rec.Code, res
+}
+
+func TestDocsServer(t *testing.T) {
+	srv := cli.NewDocsServer(docsTestSchema(t))
+
+	var conf any
+	require.NoError(t, yaml.Unmarshal([]byte(`
+input:
+  label: first
+  foo:
+    address: a.example.com
+    password: hunter2
+input_resources:
+  - label: second
+    foo:
+      password: hunter3
+`), &conf))
+	require.NoError(t, srv.SetConfig(conf))
+
+	code, res := getDocs(t, srv, "/docs/components/input/foo")
+	require.Equal(t, http.StatusOK, code)
+
+	assert.Equal(t, "Reads foo.", res["summary"])
+	assert.Equal(t, []any{
+		map[string]any{
+			"name":        "address",
+			"type":        "string",
+			"description": "The address of foo.",
+			"default":     `"localhost"`,
+		},
+		map[string]any{
+			"name":        "password",
+			"type":        "string",
+			"description": "The password of foo.",
+			"is_secret":   true,
+		},
+	}, res["fields"])
+	require.Len(t, res["examples"], 1)
+
+	instances := res["instances"].([]any)
+	require.Len(t, instances, 2)
+
+	first := instances[0].(map[string]any)
+	assert.Equal(t, "input", first["path"])
+	assert.Equal(t, "first", first["label"])
+	assert.NotContains(t, mustJSON(t, instances), "hunter")
+	assert.Contains(t, mustJSON(t, first["config"]), "a.example.com")
+
+	code, res = getDocs(t, srv, "/docs/components/cache/bar")
+	require.Equal(t, http.StatusOK, code)
+	assert.Empty(t, res["instances"])
+
+	code, _ = getDocs(t, srv, "/docs/components/input/baz")
+	assert.Equal(t, http.StatusNotFound, code)
+
+	code, _ = getDocs(t, srv, "/docs/components/nope/foo")
+	assert.Equal(t, http.StatusNotFound, code)
+}
+
+func TestDocsServerAsciidoc(t *testing.T) {
+	srv := cli.NewDocsServer(docsTestSchema(t))
+
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/docs/components/input/foo?format=asciidoc", nil))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Contains(t, rec.Body.String(), "The address of foo.")
+}
+
+func mustJSON(t *testing.T, v any) string {
+	t.Helper()
+	b, err := json.Marshal(v)
+	require.NoError(t, err)
+	return string(b)
+}
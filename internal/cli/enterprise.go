@@ -10,9 +10,13 @@ package cli
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net"
+	"net/http"
 	"os"
+	"time"
 
 	"github.com/redpanda-data/benthos/v4/public/service"
 	"github.com/rs/xid"
@@ -20,6 +24,9 @@ import (
 
 	"github.com/redpanda-data/connect/v4/internal/impl/kafka/enterprise"
 	"github.com/redpanda-data/connect/v4/internal/license"
+	"github.com/redpanda-data/connect/v4/internal/loglevels"
+	"github.com/redpanda-data/connect/v4/internal/pausing"
+	"github.com/redpanda-data/connect/v4/internal/protohealth"
 	"github.com/redpanda-data/connect/v4/internal/secrets"
 	"github.com/redpanda-data/connect/v4/internal/telemetry"
 )
@@ -42,11 +49,113 @@ func InitEnterpriseCLI(binaryName, version, dateBuilt string, schema *service.Co
 		os.Exit(1)
 	}
 
+	// The interactive mode of the create subcommand is not supported by the
+	// CLI and is therefore handled before it parses the arguments.
+	interactiveCreate, err := IsInteractiveCreate(os.Args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+	if interactiveCreate {
+		if err := RunInteractiveCreate(schema.Environment(), os.Stdin, os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Flags added to the lint subcommand are parsed before the CLI parses the
+	// arguments, as the linter does not recognise them, and configs are
+	// checked against any organization policies they provide.
+	args, lintOpts, err := ParseLintFlags(os.Args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+	var lintProfile string
+	var lintFailed bool
+	if lintOpts != nil {
+		lintProfile = lintOpts.Profile
+	}
+	if lintOpts != nil && lintOpts.Policies != nil {
+		violations, err := lintOpts.Policies.LintArgs(schema.Environment(), args, lintProfile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+		for _, v := range violations {
+			fmt.Println(v.String())
+		}
+		lintFailed = len(violations) > 0
+	}
+
+	// Services required by tests are provisioned before the CLI parses the
+	// arguments, as the test runner does not recognise the flag, and are
+	// removed once it exits.
+	args, testServices, err := TestServicesArgs(args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+
+	// Config files that define profiles are linted before the CLI parses the
+	// arguments of the lint subcommand, as the linter does not recognise
+	// profiles, and are removed from the arguments.
+	args, profileLints, err := LintProfileArgs(schema, args, lintProfile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+	for _, lint := range profileLints {
+		fmt.Fprintln(os.Stderr, lint)
+		lintFailed = true
+	}
+	if len(args) != len(os.Args) {
+		opts = append([]service.CLIOptFunc{service.CLIOptSetArgs(args...)}, opts...)
+	}
+
+	var stopServices func()
+	if testServices != nil {
+		if stopServices, err = testServices.Start(context.Background()); err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+	}
+
 	secretLookupFn := func(ctx context.Context, key string) (string, bool) {
 		return "", false
 	}
 
 	var disableTelemetry bool
+
+	// The grpc health endpoint is optional and only started when a port is
+	// specified with the run flags, readiness is otherwise only tracked when
+	// the admin endpoints are enabled.
+	var readiness *protohealth.Readiness
+	readinessCtx, readinessDone := context.WithCancel(context.Background())
+	defer readinessDone()
+	// The documentation endpoint is also optional and only started when an
+	// address is specified with the run flags.
+	docsServer := NewDocsServer(schema)
+	var docsHTTP *http.Server
+
+	// Log levels of component paths are applied on top of the main logger,
+	// and can be changed at runtime via the optional admin endpoint.
+	logLevels := loglevels.New()
+	var logLevelFlag string
+	var adminHTTP *http.Server
+
+	// Configs composed by the run flags are written to a temporary file, and
+	// their lints are reported against the files they originate from once
+	// the logger is initialised.
+	var removeComposed func()
+	var composedLints []string
+	var composedStrict bool
+
+	// Components that can be paused obtain their gates from a registry that is
+	// shared with the admin endpoints.
+	pauses := pausing.NewRegistry()
+
 	licenseConfig := license.Config{
 		LicenseFilepath: os.Getenv("REDPANDA_LICENSE_FILEPATH"),
 	}
@@ -78,15 +187,54 @@ func InitEnterpriseCLI(binaryName, version, dateBuilt string, schema *service.Co
 		service.CLIOptSetEnvironment(schema.Environment()),
 		service.CLIOptOnLoggerInit(func(l *service.Logger) {
 			fbLogger = l
+			for _, lint := range composedLints {
+				if composedStrict {
+					fbLogger.With("lint", lint).Error("Config lint error")
+				} else {
+					fbLogger.With("lint", lint).Warn("Config lint error")
+				}
+			}
 			if cListApplied {
 				fbLogger.Infof("Successfully applied connectors allow/deny list from '%v'", connectorListPath)
 			}
 			rpLogger.SetFallbackLogger(l)
 		}),
-		service.CLIOptAddTeeLogger(slog.New(rpLogger)),
+		service.CLIOptAddTeeLogger(slog.New(logLevels.Handler(rpLogger))),
 		service.CLIOptOnConfigParse(func(pConf *service.ParsedConfig) error {
+			if composedStrict && len(composedLints) > 0 {
+				return errors.New("shutting down due to linter errors, to prevent shutdown run with --chilled")
+			}
+
 			// Kick off license service.
 			license.RegisterService(pConf.Resources(), licenseConfig)
+			pausing.SetRegistry(pConf.Resources(), pauses)
+
+			if err := logLevels.InitFromParsed(pConf); err != nil {
+				return err
+			}
+			if logLevelFlag != "" {
+				if err := logLevels.SetRootLevel(logLevelFlag); err != nil {
+					return err
+				}
+			}
+
+			if readiness != nil {
+				probes, err := protohealth.ProbesFromParsed(pConf)
+				if err != nil {
+					return err
+				}
+				for _, p := range probes {
+					readiness.AddProbe(p)
+				}
+			}
+
+			if docsHTTP != nil {
+				if v, err := pConf.FieldAny(); err == nil {
+					if err := docsServer.SetConfig(v); err != nil && fbLogger != nil {
+						fbLogger.Warnf("Failed to set config of documentation endpoint: %v", err)
+					}
+				}
+			}
 
 			// Kick off telemetry exporter.
 			if !disableTelemetry {
@@ -96,6 +244,11 @@ func InitEnterpriseCLI(binaryName, version, dateBuilt string, schema *service.Co
 		}),
 		service.CLIOptOnStreamStart(func(s *service.RunningStreamSummary) error {
 			rpLogger.SetStreamSummary(s)
+			if readiness != nil {
+				// Streams restarted after a config change replace their
+				// predecessor.
+				readiness.AddStream("main", protohealth.SummaryStatusFunc(s))
+			}
 			return nil
 		}),
 
@@ -103,13 +256,39 @@ func InitEnterpriseCLI(binaryName, version, dateBuilt string, schema *service.Co
 		service.CLIOptCustomRunFlags([]cli.Flag{
 			&cli.StringSliceFlag{
 				Name:  "secrets",
-				Usage: "Attempt to load secrets from a provided URN. If more than one entry is specified they will be attempted in order until a value is found. Environment variable lookups are specified with the URN `env:`, which by default is the only entry. In order to disable all secret lookups specify a single entry of `none:`.",
+				Usage: "Attempt to load secrets from a provided URN. If more than one entry is specified they will be attempted in order until a value is found. Environment variable lookups are specified with the URN `env:`, which by default is the only entry. Secrets can also be read from HashiCorp Vault with the URN `vault://host:port/{mount}/{prefix}`, where the token is read from the environment variable `VAULT_TOKEN`, and a field of a secret is selected with a key such as `${orders.password}`. In order to disable all secret lookups specify a single entry of `none:`.",
 				Value: cli.NewStringSlice("env:"),
 			},
 			&cli.BoolFlag{
 				Name:  "disable-telemetry",
 				Usage: "Disable anonymous telemetry from being emitted by this Connect instance.",
 			},
+			&cli.IntFlag{
+				Name:  "grpc-health-port",
+				Usage: "Serve the standard gRPC health checking protocol on a port, reflecting the connection status of each component of running streams. The overall status is reported with an empty service name, and each component is reported with its label as the service name. Disabled by default.",
+			},
+			&cli.StringFlag{
+				Name:  "docs-address",
+				Usage: "Serve the documentation of every registered component at `/docs/components/{type}/{name}` on an address, including the fields and examples of the component along with the config of each instance of it within the running config, where secrets are scrubbed. Append `?format=asciidoc` to obtain the full documentation page. Disabled by default.",
+			},
+			&cli.StringFlag{
+				Name:  "admin-address",
+				Usage: "Serve administrative endpoints on an address, including `/admin/ready` which details the connection state of each input and output, when it last changed and its last error, along with the results of dependency probes, `/admin/log_levels` where a GET request returns the log levels of component paths and a PUT request with a JSON object of paths to levels replaces them, and `/admin/pause/{name}` and `/admin/resume/{name}` which pause and resume `pausable` inputs and outputs. Requires `--admin-token`. Disabled by default.",
+			},
+			&cli.StringFlag{
+				Name:    "admin-token",
+				Usage:   "A token that requests to the admin endpoints which modify state must provide as a bearer token within the `Authorization` header. Required when `--admin-address` is set.",
+				EnvVars: []string{"CONNECT_ADMIN_TOKEN"},
+			},
+			&cli.StringSliceFlag{
+				Name:  "overlay",
+				Usage: "Compose a config at a `path`, which is either a file or a directory of config files, over the config provided with `--config`, where fields of each overlay take precedence over those before it. Resource labels must be unique across all configs, and `http_server` components of the configs must not serve the same path on the same address. Can be specified multiple times.",
+			},
+			&cli.StringFlag{
+				Name:    "profile",
+				Usage:   "Select a profile defined under the `profiles` field of the configs provided with `--config`, where the sections of the profile override those of the config.",
+				EnvVars: []string{ProfileEnvVar},
+			},
 			&cli.StringFlag{
 				Name:  "redpanda-license",
 				Usage: "Provide an explicit Redpanda License, which enables enterprise functionality. By default licenses found at the path `/etc/redpanda/redpanda.license` are applied.",
@@ -118,13 +297,104 @@ func InitEnterpriseCLI(binaryName, version, dateBuilt string, schema *service.Co
 			disableTelemetry = c.Bool("disable-telemetry")
 			licenseConfig.License = c.String("redpanda-license")
 
+			if port := c.Int("grpc-health-port"); port > 0 && readiness == nil {
+				lis, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+				if err != nil {
+					return fmt.Errorf("failed to listen on gRPC health port: %w", err)
+				}
+				readiness = protohealth.NewReadiness(lis.Addr().String(), time.Second)
+				go func() {
+					if err := readiness.Serve(readinessCtx, lis); err != nil && readinessCtx.Err() == nil {
+						if fbLogger != nil {
+							fbLogger.Errorf("gRPC health endpoint failed: %v", err)
+						} else {
+							fmt.Fprintf(os.Stderr, "gRPC health endpoint failed: %v\n", err)
+						}
+					}
+				}()
+			}
+
+			if addr := c.String("docs-address"); addr != "" && docsHTTP == nil {
+				docsHTTP = &http.Server{
+					Addr:              addr,
+					Handler:           docsServer,
+					ReadHeaderTimeout: 10 * time.Second,
+				}
+				go func() {
+					if err := docsHTTP.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+						fmt.Fprintf(os.Stderr, "Documentation endpoint failed: %v\n", err)
+					}
+				}()
+			}
+
+			logLevelFlag = c.String("log.level")
+			if addr := c.String("admin-address"); addr != "" && adminHTTP == nil {
+				adminToken := c.String("admin-token")
+				if adminToken == "" {
+					return errors.New("an admin token must be provided with --admin-token in order to serve the admin endpoints")
+				}
+				if readiness == nil {
+					readiness = protohealth.NewReadiness("", time.Second)
+					go func() {
+						if err := readiness.Poll(readinessCtx); err != nil && readinessCtx.Err() == nil {
+							fmt.Fprintf(os.Stderr, "Readiness polling failed: %v\n", err)
+						}
+					}()
+				}
+				adminMux := http.NewServeMux()
+				adminMux.Handle("/admin/log_levels", logLevels)
+				adminMux.Handle("/admin/ready", readiness)
+				adminMux.Handle("/admin/pause", pauses)
+				adminMux.Handle("/admin/pause/", pauses)
+				adminMux.Handle("/admin/resume/", pauses)
+				adminHTTP = &http.Server{
+					Addr:              addr,
+					Handler:           AdminAuth(adminToken, adminMux),
+					ReadHeaderTimeout: 10 * time.Second,
+				}
+				go func() {
+					if err := adminHTTP.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+						fmt.Fprintf(os.Stderr, "Admin endpoint failed: %v\n", err)
+					}
+				}()
+			}
+
 			if secretsURNs := c.StringSlice("secrets"); len(secretsURNs) > 0 {
 				var err error
 				if secretLookupFn, err = secrets.ParseLookupURNs(c.Context, slog.New(rpLogger), secretsURNs...); err != nil {
 					return err
 				}
 			}
-			return nil
+
+			// Overlays, a config directory or a config with profiles are
+			// composed into a single config that replaces the config flag.
+			config := flagContext(c, "config").String("config")
+			if config == "" && c.Command != nil && c.Command.Name == "run" {
+				config = c.Args().First()
+			}
+			composed, err := ComposeRunConfig(config, flagContext(c, "overlay").StringSlice("overlay"), flagContext(c, "profile").String("profile"))
+			if err != nil || composed == nil {
+				return err
+			}
+			if flagContext(c, "watcher").Bool("watcher") {
+				return errors.New("the config watcher cannot be used with overlays, a config directory or config profiles")
+			}
+			linter := schema.NewStreamConfigLinter().
+				SetSkipEnvVarCheck(true).
+				SetEnvVarLookupFunc(secretLookupFn)
+			if composedLints, err = composed.Lint(linter); err != nil {
+				return err
+			}
+			composedStrict = !flagContext(c, "chilled").Bool("chilled")
+
+			composedPath, err := composed.WriteRunConfig()
+			if err != nil {
+				return err
+			}
+			removeComposed = func() {
+				_ = os.Remove(composedPath)
+			}
+			return c.Set("config", composedPath)
 		}),
 		service.CLIOptSetEnvVarLookup(func(ctx context.Context, key string) (string, bool) {
 			return secretLookupFn(ctx, key)
@@ -139,10 +409,45 @@ func InitEnterpriseCLI(binaryName, version, dateBuilt string, schema *service.Co
 			fmt.Fprintln(os.Stderr, err.Error())
 		}
 	}
+	// The help text of subcommands written by the CLI does not list the flags
+	// added to them.
+	if exitCode == 0 {
+		_ = WriteSubcommandHelp(os.Stdout, os.Args)
+	}
 	rpLogger.TriggerEventStopped(err)
+	if readiness != nil {
+		readiness.Shutdown()
+	}
+	if docsHTTP != nil {
+		_ = docsHTTP.Close()
+	}
+	if adminHTTP != nil {
+		_ = adminHTTP.Close()
+	}
 
 	_ = rpLogger.Close(context.Background())
+	if removeComposed != nil {
+		removeComposed()
+	}
+	if stopServices != nil {
+		stopServices()
+	}
+	if exitCode == 0 && lintFailed {
+		exitCode = 1
+	}
 	if exitCode != 0 {
 		os.Exit(exitCode)
 	}
 }
+
+// flagContext returns the context of the command that a flag was set for,
+// which may be a parent of the provided context as the flags of the run
+// command are also accepted by the root command.
+func flagContext(c *cli.Context, name string) *cli.Context {
+	for _, l := range c.Lineage() {
+		if l.IsSet(name) {
+			return l
+		}
+	}
+	return c
+}
@@ -0,0 +1,498 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed as a Redpanda Enterprise file under the Redpanda Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+// https://github.com/redpanda-data/connect/blob/main/licenses/rcl.md
+
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/redpanda-data/benthos/v4/public/bloblang"
+	"github.com/redpanda-data/benthos/v4/public/service"
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Top level sections of a config that contain components, keyed by the type of
+// those components.
+var policyRootSlots = map[string]string{
+	"input":                "input",
+	"buffer":               "buffer",
+	"output":               "output",
+	"metrics":              "metrics",
+	"tracer":               "tracer",
+	"input_resources":      "input",
+	"processor_resources":  "processor",
+	"output_resources":     "output",
+	"cache_resources":      "cache",
+	"rate_limit_resources": "rate_limit",
+}
+
+// Fields within the config of a component that contain child components, keyed
+// by the type of those components.
+var policyNestedSlots = map[string]string{
+	"processors": "processor",
+	"input":      "input",
+	"inputs":     "input",
+	"output":     "output",
+	"outputs":    "output",
+}
+
+// LintPolicy is a rule of an organization that configs must satisfy, which is
+// checked by the lint subcommand.
+type LintPolicy struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	Component   string `yaml:"component"`
+	Check       string `yaml:"check"`
+
+	kind, component string
+	exec            *bloblang.Executor
+}
+
+// LintPolicyViolation describes a config that does not satisfy a policy.
+type LintPolicyViolation struct {
+	Path    string
+	Profile string
+	// The location of the component within the config, empty when the policy
+	// applies to the config as a whole.
+	Location string
+	Policy   string
+	Message  string
+}
+
+func (v LintPolicyViolation) String() string {
+	var b strings.Builder
+	b.WriteString(v.Path)
+	if v.Profile != "" {
+		fmt.Fprintf(&b, " (profile %v)", v.Profile)
+	}
+	if v.Location != "" {
+		b.WriteString(": " + v.Location)
+	}
+	fmt.Fprintf(&b, ": policy %v: %v", v.Policy, v.Message)
+	return b.String()
+}
+
+// LintPolicies is a set of policies loaded from policy files.
+type LintPolicies struct {
+	policies []*LintPolicy
+}
+
+// LoadLintPolicies reads policy files, which contain a `policies` list where
+// each policy has a name, a description, and a Bloblang query `check` that must
+// return true for configs that satisfy it.
+//
+// When a policy specifies a component, either a type such as `output` or a
+// type and name such as `output.kafka`, the check is executed against the
+// config of each matching component, otherwise it is executed against the
+// config as a whole, which is skipped for resource files. Metadata fields
+// `path` and `profile` are set for the config being linted, and `type`, `name`
+// and `label` for the component, where the profile and label are only set
+// when they are not empty.
+func LoadLintPolicies(paths ...string) (*LintPolicies, error) {
+	p := &LintPolicies{}
+	for _, path := range paths {
+		policyBytes, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read policy file: %w", err)
+		}
+
+		var file struct {
+			Policies []*LintPolicy `yaml:"policies"`
+		}
+		if err := yaml.Unmarshal(policyBytes, &file); err != nil {
+			return nil, fmt.Errorf("failed to parse policy file '%v': %w", path, err)
+		}
+		for i, policy := range file.Policies {
+			if err := policy.init(); err != nil {
+				return nil, fmt.Errorf("policy file '%v' policy %v: %w", path, i, err)
+			}
+		}
+		p.policies = append(p.policies, file.Policies...)
+	}
+	return p, nil
+}
+
+func (p *LintPolicy) init() (err error) {
+	if p.Name == "" {
+		return errors.New("a name must be specified")
+	}
+	if p.Check == "" {
+		return fmt.Errorf("policy '%v' must specify a check", p.Name)
+	}
+	if p.Component != "" {
+		p.kind, p.component, _ = strings.Cut(p.Component, ".")
+		if !isPolicyComponentType(p.kind) {
+			return fmt.Errorf("policy '%v' component type '%v' is not recognised", p.Name, p.kind)
+		}
+	}
+	if p.exec, err = bloblang.Parse(p.Check); err != nil {
+		return fmt.Errorf("policy '%v' check: %w", p.Name, err)
+	}
+	return nil
+}
+
+func isPolicyComponentType(kind string) bool {
+	switch kind {
+	case "input", "buffer", "processor", "output", "cache", "rate_limit", "metrics", "tracer":
+		return true
+	}
+	return false
+}
+
+type policyComponent struct {
+	kind, name, label, location string
+	conf                        any
+}
+
+type policyWalker struct {
+	// The names of registered components keyed by their type.
+	names      map[string]map[string]struct{}
+	components []policyComponent
+}
+
+func newPolicyWalker(env *service.Environment) *policyWalker {
+	w := &policyWalker{names: map[string]map[string]struct{}{}}
+	walker := func(kind string) func(string, *service.ConfigView) {
+		w.names[kind] = map[string]struct{}{}
+		return func(name string, _ *service.ConfigView) {
+			w.names[kind][name] = struct{}{}
+		}
+	}
+	env.WalkInputs(walker("input"))
+	env.WalkBuffers(walker("buffer"))
+	env.WalkProcessors(walker("processor"))
+	env.WalkOutputs(walker("output"))
+	env.WalkCaches(walker("cache"))
+	env.WalkRateLimits(walker("rate_limit"))
+	env.WalkMetrics(walker("metrics"))
+	env.WalkTracers(walker("tracer"))
+	return w
+}
+
+func (w *policyWalker) isComponent(kind, name string) bool {
+	_, exists := w.names[kind][name]
+	return exists
+}
+
+func (w *policyWalker) walkRoot(root *yaml.Node) {
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i].Value, root.Content[i+1]
+		if kind, exists := policyRootSlots[key]; exists {
+			w.walkSlot(value, kind, key)
+		} else {
+			w.walkNested(value, key)
+		}
+	}
+}
+
+func (w *policyWalker) walkSlot(node *yaml.Node, kind, location string) {
+	if node.Kind != yaml.SequenceNode {
+		w.walkComponent(node, kind, location)
+		return
+	}
+	for i, item := range node.Content {
+		w.walkComponent(item, kind, location+"."+strconv.Itoa(i))
+	}
+}
+
+func (w *policyWalker) walkComponent(node *yaml.Node, kind, location string) {
+	if node.Kind != yaml.MappingNode {
+		return
+	}
+	var label string
+	if l := mappingValue(node, "label"); l != nil {
+		label = l.Value
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i].Value, node.Content[i+1]
+		switch {
+		case key == "label":
+		case key == "processors":
+			w.walkSlot(value, "processor", location+".processors")
+		case w.isComponent(kind, key):
+			var conf any
+			_ = value.Decode(&conf)
+			w.components = append(w.components, policyComponent{
+				kind:     kind,
+				name:     key,
+				label:    label,
+				location: location,
+				conf:     conf,
+			})
+			// Components such as try and fallback are configured with a
+			// list of children of the same type.
+			if value.Kind == yaml.SequenceNode {
+				w.walkSlot(value, kind, location+"."+key)
+			} else {
+				w.walkNested(value, location+"."+key)
+			}
+		}
+	}
+}
+
+func (w *policyWalker) walkNested(node *yaml.Node, location string) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i].Value, node.Content[i+1]
+			if kind, exists := policyNestedSlots[key]; exists {
+				w.walkSlot(value, kind, location+"."+key)
+			} else {
+				w.walkNested(value, location+"."+key)
+			}
+		}
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			w.walkNested(item, location+"."+strconv.Itoa(i))
+		}
+	}
+}
+
+// lintConfig checks a config against the policies, where env is used in order
+// to identify the components of the config. Policies that do not specify a
+// component are skipped for resource files.
+func (p *LintPolicies) lintConfig(env *service.Environment, path, profile string, root *yaml.Node, resources bool) []LintPolicyViolation {
+	w := newPolicyWalker(env)
+	w.walkRoot(root)
+
+	var rootConf any
+	_ = root.Decode(&rootConf)
+
+	var violations []LintPolicyViolation
+	for _, policy := range p.policies {
+		if policy.kind == "" {
+			if resources {
+				continue
+			}
+			msg := service.NewMessage(nil)
+			msg.SetStructured(rootConf)
+			if v, failed := policy.checkMessage(msg, path, profile, ""); failed {
+				violations = append(violations, v)
+			}
+			continue
+		}
+		for _, c := range w.components {
+			if c.kind != policy.kind || (policy.component != "" && c.name != policy.component) {
+				continue
+			}
+			msg := service.NewMessage(nil)
+			msg.SetStructured(c.conf)
+			msg.MetaSet("type", c.kind)
+			msg.MetaSet("name", c.name)
+			if c.label != "" {
+				msg.MetaSet("label", c.label)
+			}
+			if v, failed := policy.checkMessage(msg, path, profile, c.location); failed {
+				violations = append(violations, v)
+			}
+		}
+	}
+	return violations
+}
+
+func (p *LintPolicy) checkMessage(msg *service.Message, path, profile, location string) (LintPolicyViolation, bool) {
+	msg.MetaSet("path", path)
+	if profile != "" {
+		msg.MetaSet("profile", profile)
+	}
+
+	v := LintPolicyViolation{
+		Path:     path,
+		Profile:  profile,
+		Location: location,
+		Policy:   p.Name,
+		Message:  p.Description,
+	}
+	if v.Message == "" {
+		v.Message = "check failed"
+	}
+
+	var res any
+	resMsg, err := msg.BloblangQuery(p.exec)
+	if err == nil && resMsg != nil {
+		res, err = resMsg.AsStructured()
+	}
+	if err != nil {
+		v.Message = fmt.Sprintf("failed to execute check: %v", err)
+		return v, true
+	}
+	passed, isBool := res.(bool)
+	if !isBool {
+		v.Message = fmt.Sprintf("check returned a non-boolean value of type %T", res)
+		return v, true
+	}
+	return v, !passed
+}
+
+// LintFile checks a config file against the policies. When the config defines
+// profiles the config without a profile is checked along with each profile.
+func (p *LintPolicies) LintFile(env *service.Environment, path string) ([]LintPolicyViolation, error) {
+	return p.lintFile(env, path, false, "")
+}
+
+func (p *LintPolicies) lintFile(env *service.Environment, path string, resources bool, selected string) ([]LintPolicyViolation, error) {
+	profiles, err := ConfigProfiles(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var violations []LintPolicyViolation
+	if selected == "" || len(profiles) == 0 {
+		root, err := readConfigRoot(path)
+		if err != nil || root == nil {
+			return nil, err
+		}
+		_ = removeMappingValue(root, "profiles")
+		violations = p.lintConfig(env, path, "", root, resources)
+	}
+	if selected != "" && len(profiles) > 0 {
+		profiles = []string{selected}
+	}
+
+	for _, profile := range profiles {
+		composed, err := ComposeConfigs([]string{path}, profile)
+		if err != nil {
+			return nil, err
+		}
+		var doc yaml.Node
+		if err := yaml.Unmarshal(composed.Bytes, &doc); err != nil {
+			return nil, fmt.Errorf("failed to parse config file '%v': %w", path, err)
+		}
+		if len(doc.Content) == 0 {
+			continue
+		}
+		violations = append(violations, p.lintConfig(env, path, profile, doc.Content[0], resources)...)
+	}
+	return violations, nil
+}
+
+// The flags added to the lint subcommand.
+var lintFlags = subcommandFlags{
+	command: "lint",
+	flags: []cli.Flag{
+		&cli.StringSliceFlag{
+			Name:  "policy",
+			Usage: "Check configs against the policies of a policy file at a `path`. Can be specified multiple times.",
+		},
+		&cli.StringFlag{
+			Name:  "profile",
+			Usage: "Lint only the selected profile of configs that define profiles, by default the config without a profile is linted along with each of its profiles.",
+		},
+	},
+}
+
+// LintFlags are the flags added to the lint subcommand.
+type LintFlags struct {
+	// Policies to check configs against, nil when no policy files are
+	// provided.
+	Policies *LintPolicies
+
+	// Profile to lint configs with, all profiles are linted when empty.
+	Profile string
+}
+
+// ParseLintFlags parses the flags added to the lint subcommand from its
+// arguments, loading the policy files they reference, and returns the
+// arguments without the flags. The returned flags are nil when the arguments
+// are unchanged.
+func ParseLintFlags(args []string) ([]string, *LintFlags, error) {
+	newArgs, c, err := lintFlags.parse(args)
+	if err != nil || c == nil {
+		return newArgs, nil, err
+	}
+
+	flags := &LintFlags{Profile: c.String("profile")}
+	if policyPaths := c.StringSlice("policy"); len(policyPaths) > 0 {
+		if flags.Policies, err = LoadLintPolicies(policyPaths...); err != nil {
+			return nil, nil, err
+		}
+	}
+	return newArgs, flags, nil
+}
+
+// LintArgs checks the config files referenced by the arguments of the lint
+// subcommand against the policies, including resource files. Paths may be
+// glob patterns, and paths ending with `/...` are walked for YAML files.
+// Files that cannot be read are skipped, as they are reported by the linter.
+// When a profile is selected only that profile of configs that define
+// profiles is checked.
+func (p *LintPolicies) LintArgs(env *service.Environment, args []string, profile string) ([]LintPolicyViolation, error) {
+	type lintPath struct {
+		pattern   string
+		resources bool
+	}
+	var paths []lintPath
+	for i := subcommandIndex(args, "lint") + 1; i > 0 && i < len(args); i++ {
+		arg := args[i]
+		if arg == "-r" || arg == "--resources" {
+			if i+1 < len(args) {
+				paths = append(paths, lintPath{pattern: args[i+1], resources: true})
+				i++
+			}
+			continue
+		}
+		if _, exists := lintValueFlags[arg]; exists {
+			i++
+			continue
+		}
+		if strings.HasPrefix(arg, "-") {
+			continue
+		}
+		paths = append(paths, lintPath{pattern: arg})
+	}
+
+	var violations []LintPolicyViolation
+	for _, lp := range paths {
+		files, err := expandLintPath(lp.pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range files {
+			fViolations, err := p.lintFile(env, f, lp.resources, profile)
+			if err != nil {
+				continue
+			}
+			violations = append(violations, fViolations...)
+		}
+	}
+	return violations, nil
+}
+
+func expandLintPath(pattern string) ([]string, error) {
+	if dir, isWalk := strings.CutSuffix(pattern, "..."); isWalk {
+		if dir == "" {
+			dir = "."
+		}
+		var files []string
+		err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if ext := filepath.Ext(path); !d.IsDir() && (ext == ".yaml" || ext == ".yml") {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to walk config directory: %w", err)
+		}
+		return files, nil
+	}
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expand config path: %w", err)
+	}
+	return files, nil
+}
@@ -0,0 +1,202 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed as a Redpanda Enterprise file under the Redpanda Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+// https://github.com/redpanda-data/connect/blob/main/licenses/rcl.md
+
+package cli_test
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/connect/v4/internal/cli"
+)
+
+func policyTestEnv(t testing.TB) *service.Environment {
+	t.Helper()
+	env := service.NewEmptyEnvironment()
+	for _, name := range []string{"kafka", "stdout", "broker", "fallback"} {
+		require.NoError(t, env.RegisterOutput(name, service.NewConfigSpec(), nil))
+	}
+	require.NoError(t, env.RegisterInput("generate", service.NewConfigSpec(), nil))
+	require.NoError(t, env.RegisterProcessor("mapping", service.NewConfigSpec(), nil))
+	return env
+}
+
+const testPolicies = `
+policies:
+  - name: kafka_tls
+    description: Kafka outputs must enable TLS.
+    component: output.kafka
+    check: this.tls.enabled == true
+  - name: no_stdout_in_prod
+    description: The stdout output must not be used in prod.
+    component: output.stdout
+    check: '@profile != "prod"'
+  - name: labelled_processors
+    description: Processors must be labelled.
+    component: processor
+    check: '@label != null'
+  - name: logger_json
+    description: Logs must be formatted as JSON.
+    check: this.logger.format == "json"
+`
+
+func TestParseLintFlags(t *testing.T) {
+	dir := t.TempDir()
+	writeComposeFiles(t, dir, map[string]string{
+		"policies.yaml": testPolicies,
+		"config.yaml": `
+logger:
+  format: json
+input:
+  generate:
+    mapping: 'root = {}'
+pipeline:
+  processors:
+    - label: foo
+      mapping: 'root = this'
+output:
+  broker:
+    outputs:
+      - kafka:
+          tls:
+            enabled: true
+      - fallback:
+          - kafka:
+              topic: foo
+          - stdout: {}
+profiles:
+  prod:
+    logger:
+      format: logfmt
+`,
+		"resources.yaml": `
+processor_resources:
+  - mapping: 'root = this'
+`,
+	})
+	policyPath := filepath.Join(dir, "policies.yaml")
+	confPath := filepath.Join(dir, "config.yaml")
+	resPath := filepath.Join(dir, "resources.yaml")
+
+	args := []string{"connect", "lint", confPath}
+	newArgs, flags, err := cli.ParseLintFlags(args)
+	require.NoError(t, err)
+	assert.Nil(t, flags)
+	assert.Equal(t, args, newArgs)
+
+	newArgs, flags, err = cli.ParseLintFlags([]string{"connect", "lint", "--policy", policyPath, "-r", resPath, confPath})
+	require.NoError(t, err)
+	require.NotNil(t, flags)
+	require.NotNil(t, flags.Policies)
+	assert.Empty(t, flags.Profile)
+	assert.Equal(t, []string{"connect", "lint", "-r", resPath, confPath}, newArgs)
+
+	lintArgs := func(profile string) []string {
+		t.Helper()
+		violations, err := flags.Policies.LintArgs(policyTestEnv(t), newArgs, profile)
+		require.NoError(t, err)
+
+		var results []string
+		for _, v := range violations {
+			results = append(results, v.String())
+		}
+		return results
+	}
+	assert.Equal(t, []string{
+		resPath + ": processor_resources.0: policy labelled_processors: Processors must be labelled.",
+		confPath + ": output.broker.outputs.1.fallback.0: policy kafka_tls: Kafka outputs must enable TLS.",
+		confPath + " (profile prod): output.broker.outputs.1.fallback.0: policy kafka_tls: Kafka outputs must enable TLS.",
+		confPath + " (profile prod): output.broker.outputs.1.fallback.1: policy no_stdout_in_prod: The stdout output must not be used in prod.",
+		confPath + " (profile prod): policy logger_json: Logs must be formatted as JSON.",
+	}, lintArgs(""))
+
+	newArgs, flags, err = cli.ParseLintFlags([]string{"connect", "lint", "--profile=prod", "--policy", policyPath, "--deprecated", "-r", resPath, confPath})
+	require.NoError(t, err)
+	require.NotNil(t, flags)
+	assert.Equal(t, "prod", flags.Profile)
+	assert.Equal(t, []string{"connect", "lint", "--deprecated", "-r", resPath, confPath}, newArgs)
+	assert.Equal(t, []string{
+		resPath + ": processor_resources.0: policy labelled_processors: Processors must be labelled.",
+		confPath + " (profile prod): output.broker.outputs.1.fallback.0: policy kafka_tls: Kafka outputs must enable TLS.",
+		confPath + " (profile prod): output.broker.outputs.1.fallback.1: policy no_stdout_in_prod: The stdout output must not be used in prod.",
+		confPath + " (profile prod): policy logger_json: Logs must be formatted as JSON.",
+	}, lintArgs(flags.Profile))
+
+	newArgs, flags, err = cli.ParseLintFlags([]string{"connect", "--log.level=warn", "-w", "lint", "--profile", "prod", confPath})
+	require.NoError(t, err)
+	require.NotNil(t, flags)
+	assert.Equal(t, "prod", flags.Profile)
+	assert.Equal(t, []string{"connect", "--log.level=warn", "-w", "lint", confPath}, newArgs)
+
+	_, _, err = cli.ParseLintFlags([]string{"connect", "lint", confPath, "--profile"})
+	require.Error(t, err)
+}
+
+func TestWriteSubcommandHelp(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, cli.WriteSubcommandHelp(&buf, []string{"connect", "lint", "./..."}))
+	assert.Empty(t, buf.String())
+
+	require.NoError(t, cli.WriteSubcommandHelp(&buf, []string{"connect", "--chilled", "lint", "--help"}))
+	assert.Contains(t, buf.String(), "ADDITIONAL OPTIONS:")
+	assert.Contains(t, buf.String(), "--policy path")
+	assert.Contains(t, buf.String(), "--profile value")
+
+	buf.Reset()
+	require.NoError(t, cli.WriteSubcommandHelp(&buf, []string{"connect", "test", "-h"}))
+	assert.Contains(t, buf.String(), "--services path")
+	assert.NotContains(t, buf.String(), "--policy")
+}
+
+func TestLintPolicyErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeComposeFiles(t, dir, map[string]string{
+		"unknown.yaml": `
+policies:
+  - name: foo
+    component: widget
+    check: 'true'
+`,
+		"nocheck.yaml": `
+policies:
+  - name: foo
+`,
+		"badcheck.yaml": `
+policies:
+  - name: foo
+    check: 'this.'
+`,
+		"nonbool.yaml": `
+policies:
+  - name: foo
+    check: 'this.logger'
+`,
+		"config.yaml": `
+logger:
+  level: INFO
+`,
+	})
+
+	for _, name := range []string{"unknown.yaml", "nocheck.yaml", "badcheck.yaml"} {
+		_, err := cli.LoadLintPolicies(filepath.Join(dir, name))
+		assert.Error(t, err, name)
+	}
+
+	policies, err := cli.LoadLintPolicies(filepath.Join(dir, "nonbool.yaml"))
+	require.NoError(t, err)
+
+	violations, err := policies.LintFile(policyTestEnv(t), filepath.Join(dir, "config.yaml"))
+	require.NoError(t, err)
+	require.Len(t, violations, 1)
+	assert.Contains(t, violations[0].Message, "non-boolean")
+}
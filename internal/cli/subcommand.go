@@ -0,0 +1,164 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed as a Redpanda Enterprise file under the Redpanda Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+// https://github.com/redpanda-data/connect/blob/main/licenses/rcl.md
+
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/urfave/cli/v2"
+)
+
+// Flags of the CLI that may precede a subcommand and are not followed by a
+// value, all other flags preceding a subcommand are followed by a value unless
+// it is provided with an equals sign.
+var rootBoolFlags = map[string]struct{}{
+	"version":           {},
+	"v":                 {},
+	"help":              {},
+	"h":                 {},
+	"help-autocomplete": {},
+	"chilled":           {},
+	"watcher":           {},
+	"w":                 {},
+	"disable-telemetry": {},
+}
+
+// subcommandIndex returns the index of the subcommand within the arguments,
+// skipping any flags of the CLI that precede it, or -1 when the arguments are
+// not for the subcommand.
+func subcommandIndex(args []string, command string) int {
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			return -1
+		}
+		if !strings.HasPrefix(arg, "-") {
+			if arg == command {
+				return i
+			}
+			return -1
+		}
+		name, _, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if _, isBool := rootBoolFlags[name]; !isBool && !hasValue {
+			i++
+		}
+	}
+	return -1
+}
+
+// isHelpRequested returns true when the arguments of a subcommand request its
+// help text, in which case the subcommand is not executed.
+func isHelpRequested(args []string) bool {
+	for _, arg := range args {
+		switch arg {
+		case "--":
+			return false
+		case "-h", "-help", "--help":
+			return true
+		}
+	}
+	return false
+}
+
+// subcommandFlags are flags added to a subcommand of the CLI. The subcommands
+// are defined by the CLI and do not recognise the flags, which are therefore
+// parsed from the arguments of the subcommand before the CLI parses them.
+type subcommandFlags struct {
+	command string
+	flags   []cli.Flag
+}
+
+// parse extracts the flags from the arguments when they are for the
+// subcommand, and returns the remaining arguments along with a context of the
+// parsed flags. The returned context is nil when the arguments are not for the
+// subcommand, none of the flags are set, or the help text of the subcommand is
+// requested.
+func (s subcommandFlags) parse(args []string) ([]string, *cli.Context, error) {
+	cmdIndex := subcommandIndex(args, s.command)
+	if cmdIndex < 0 || isHelpRequested(args[cmdIndex+1:]) {
+		return args, nil, nil
+	}
+
+	set := flag.NewFlagSet(s.command, flag.ContinueOnError)
+	set.SetOutput(io.Discard)
+	takesValue := map[string]bool{}
+	for _, f := range s.flags {
+		if err := f.Apply(set); err != nil {
+			return nil, nil, err
+		}
+		valueFlag := true
+		if df, ok := f.(cli.DocGenerationFlag); ok {
+			valueFlag = df.TakesValue()
+		}
+		for _, name := range f.Names() {
+			takesValue[name] = valueFlag
+		}
+	}
+
+	var flagArgs []string
+	newArgs := make([]string, 0, len(args))
+	newArgs = append(newArgs, args[:cmdIndex+1]...)
+	for i := cmdIndex + 1; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			newArgs = append(newArgs, args[i:]...)
+			break
+		}
+		if !strings.HasPrefix(arg, "-") {
+			newArgs = append(newArgs, arg)
+			continue
+		}
+		name, _, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		valueFlag, exists := takesValue[name]
+		if !exists {
+			newArgs = append(newArgs, arg)
+			continue
+		}
+		flagArgs = append(flagArgs, arg)
+		if valueFlag && !hasValue && i+1 < len(args) {
+			flagArgs = append(flagArgs, args[i+1])
+			i++
+		}
+	}
+	if len(flagArgs) == 0 {
+		return args, nil, nil
+	}
+
+	if err := set.Parse(flagArgs); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse flags of the %v subcommand: %w", s.command, err)
+	}
+	return newArgs, cli.NewContext(nil, set, nil), nil
+}
+
+// The subcommands of the CLI that flags are added to.
+var extendedSubcommands = []subcommandFlags{createFlags, lintFlags, testFlags}
+
+// WriteSubcommandHelp writes the flags added to a subcommand when the
+// arguments request its help text, which is written by the CLI and therefore
+// does not list them.
+func WriteSubcommandHelp(w io.Writer, args []string) error {
+	for _, s := range extendedSubcommands {
+		cmdIndex := subcommandIndex(args, s.command)
+		if cmdIndex < 0 || !isHelpRequested(args[cmdIndex+1:]) {
+			continue
+		}
+
+		tw := tabwriter.NewWriter(w, 1, 8, 2, ' ', 0)
+		fmt.Fprintln(tw, "\nADDITIONAL OPTIONS:")
+		for _, f := range s.flags {
+			fmt.Fprintf(tw, "   %v\n", f.String())
+		}
+		return tw.Flush()
+	}
+	return nil
+}
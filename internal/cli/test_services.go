@@ -0,0 +1,402 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed as a Redpanda Enterprise file under the Redpanda Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+// https://github.com/redpanda-data/connect/blob/main/licenses/rcl.md
+
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// TestService is a dependency of tests that is provisioned as a container for
+// the duration of the test subcommand.
+type TestService struct {
+	Type  string            `yaml:"type"`
+	Image string            `yaml:"image"`
+	Port  int               `yaml:"port"`
+	Env   map[string]string `yaml:"env"`
+	Cmd   []string          `yaml:"cmd"`
+	Ready []string          `yaml:"ready"`
+	Vars  map[string]string `yaml:"vars"`
+}
+
+// Defaults of the service types that can be provisioned without specifying an
+// image, where the vars are suffixes of the upper cased service name.
+var testServicePresets = map[string]TestService{
+	"kafka": {
+		Image: "redpandadata/redpanda:latest",
+		Port:  9092,
+		Cmd: []string{
+			"redpanda", "start",
+			"--node-id", "0",
+			"--mode", "dev-container",
+			"--kafka-addr", "0.0.0.0:9092",
+			"--advertise-kafka-addr", "{host}:{port}",
+		},
+		// Requests the metadata of the cluster.
+		Ready: []string{"rpk", "cluster", "info"},
+		Vars: map[string]string{
+			"_ADDRESS": "{host}:{port}",
+		},
+	},
+	"redis": {
+		Image: "redis:7",
+		Port:  6379,
+		Ready: []string{"redis-cli", "ping"},
+		Vars: map[string]string{
+			"_URL": "redis://{host}:{port}",
+		},
+	},
+	"postgres": {
+		Image: "postgres:16",
+		Port:  5432,
+		Env: map[string]string{
+			"POSTGRES_USER":     "connect",
+			"POSTGRES_PASSWORD": "connect",
+			"POSTGRES_DB":       "connect",
+		},
+		// The server started while the database is initialised only listens
+		// on a unix socket, and therefore readiness is checked over TCP.
+		Ready: []string{"pg_isready", "--host", "127.0.0.1", "--username", "connect", "--dbname", "connect"},
+		Vars: map[string]string{
+			"_DSN": "postgres://connect:connect@{host}:{port}/connect?sslmode=disable",
+		},
+	},
+}
+
+// TestServices is a set of services keyed by their names.
+type TestServices struct {
+	services map[string]*TestService
+
+	// Runs a docker command and returns its output, which is swapped in tests.
+	docker func(ctx context.Context, args ...string) (string, error)
+	// Waits for a provisioned service without a ready command to accept
+	// connections.
+	waitConn func(ctx context.Context, addr string) error
+	timeout  time.Duration
+}
+
+// LoadTestServices reads services files, which contain a `services` object of
+// service names to their definitions. A service either has a type of `kafka`,
+// `redis` or `postgres`, which provides defaults for the remaining fields, or
+// specifies an image and the port that it listens on.
+//
+// Environment variables are declared with `vars`, where `{host}` and `{port}`
+// are replaced with the address of the provisioned service. Services with a
+// type also declare variables prefixed with the upper cased service name, such
+// as `EVENTS_ADDRESS` for a kafka service named `events`.
+//
+// A service is ready once its `ready` command, which is executed within the
+// container, succeeds. Services with a type have a ready command that checks
+// the service itself, and services without one are ready once they accept
+// connections.
+func LoadTestServices(paths ...string) (*TestServices, error) {
+	s := &TestServices{
+		services: map[string]*TestService{},
+		docker:   runDocker,
+		waitConn: waitForConn,
+		timeout:  time.Minute,
+	}
+	for _, path := range paths {
+		servicesBytes, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read services file: %w", err)
+		}
+
+		var file struct {
+			Services map[string]*TestService `yaml:"services"`
+		}
+		if err := yaml.Unmarshal(servicesBytes, &file); err != nil {
+			return nil, fmt.Errorf("failed to parse services file '%v': %w", path, err)
+		}
+		for name, svc := range file.Services {
+			if _, exists := s.services[name]; exists {
+				return nil, fmt.Errorf("service '%v' is defined more than once", name)
+			}
+			if svc == nil {
+				svc = &TestService{}
+			}
+			if err := svc.init(name); err != nil {
+				return nil, fmt.Errorf("services file '%v' service '%v': %w", path, name, err)
+			}
+			s.services[name] = svc
+		}
+	}
+	return s, nil
+}
+
+func (s *TestService) init(name string) error {
+	if s.Type != "" {
+		preset, exists := testServicePresets[s.Type]
+		if !exists {
+			return fmt.Errorf("service type '%v' is not recognised", s.Type)
+		}
+		if s.Image == "" {
+			s.Image = preset.Image
+		}
+		if s.Port == 0 {
+			s.Port = preset.Port
+		}
+		if s.Cmd == nil {
+			s.Cmd = preset.Cmd
+		}
+		if s.Ready == nil {
+			s.Ready = preset.Ready
+		}
+		env := map[string]string{}
+		for k, v := range preset.Env {
+			env[k] = v
+		}
+		for k, v := range s.Env {
+			env[k] = v
+		}
+		s.Env = env
+
+		vars := map[string]string{}
+		prefix := strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(name))
+		for k, v := range preset.Vars {
+			vars[prefix+k] = v
+		}
+		for k, v := range s.Vars {
+			vars[k] = v
+		}
+		s.Vars = vars
+	}
+	if s.Image == "" {
+		return errors.New("an image or type must be specified")
+	}
+	if s.Port <= 0 {
+		return errors.New("a port must be specified")
+	}
+	return nil
+}
+
+// runArgs returns the docker run arguments of the service bound to a port of
+// the host, which is only published on the address of the host.
+func (s *TestService) runArgs(host string, hostPort int) []string {
+	r := testServiceReplacer(host, hostPort)
+	args := []string{"run", "--detach", "--rm", "--publish", fmt.Sprintf("%v:%v:%v", host, hostPort, s.Port)}
+	for _, k := range sortedKeys(s.Env) {
+		args = append(args, "--env", k+"="+r.Replace(s.Env[k]))
+	}
+	args = append(args, s.Image)
+	for _, c := range s.Cmd {
+		args = append(args, r.Replace(c))
+	}
+	return args
+}
+
+// vars returns the environment variables of the service bound to a port of the
+// host.
+func (s *TestService) vars(host string, hostPort int) map[string]string {
+	r := testServiceReplacer(host, hostPort)
+	vars := make(map[string]string, len(s.Vars))
+	for k, v := range s.Vars {
+		vars[k] = r.Replace(v)
+	}
+	return vars
+}
+
+func testServiceReplacer(host string, hostPort int) *strings.Replacer {
+	return strings.NewReplacer("{host}", host, "{port}", strconv.Itoa(hostPort))
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Start provisions a container for each service with docker and waits for them
+// to become ready, after which the environment variables of the services are
+// set. The returned func removes the containers, and is called
+// before an error is returned.
+func (s *TestServices) Start(ctx context.Context) (func(), error) {
+	var containers []string
+	stop := func() {
+		for _, id := range containers {
+			// The context of the run may already be cancelled, containers are
+			// removed regardless.
+			_, _ = s.docker(context.Background(), "rm", "--force", id)
+		}
+	}
+
+	// Published ports are bound to the loopback address only, which is used
+	// rather than localhost as it may resolve to an IPv6 address.
+	const host = "127.0.0.1"
+
+	names := make([]string, 0, len(s.services))
+	for name := range s.services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		svc := s.services[name]
+		hostPort, err := freePort()
+		if err != nil {
+			stop()
+			return nil, err
+		}
+
+		id, err := s.docker(ctx, svc.runArgs(host, hostPort)...)
+		if err != nil {
+			stop()
+			return nil, fmt.Errorf("failed to start service '%v': %w", name, err)
+		}
+		id = strings.TrimSpace(id)
+		containers = append(containers, id)
+
+		readyCtx, done := context.WithTimeout(ctx, s.timeout)
+		err = s.waitReady(readyCtx, id, net.JoinHostPort(host, strconv.Itoa(hostPort)), svc)
+		done()
+		if err != nil {
+			stop()
+			return nil, fmt.Errorf("service '%v' did not become ready: %w", name, err)
+		}
+
+		for k, v := range svc.vars(host, hostPort) {
+			if err := os.Setenv(k, v); err != nil {
+				stop()
+				return nil, fmt.Errorf("failed to set variable of service '%v': %w", name, err)
+			}
+		}
+	}
+	return stop, nil
+}
+
+func runDocker(ctx context.Context, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "docker", args...)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%w: %v", err, msg)
+		}
+		return "", err
+	}
+	return stdout.String(), nil
+}
+
+// The interval between checks of whether a service is ready.
+const testServiceRetryInterval = 500 * time.Millisecond
+
+// waitReady executes the ready command of a service within its container
+// until it succeeds, or waits for the service to accept connections when it
+// does not have one.
+func (s *TestServices) waitReady(ctx context.Context, id, addr string, svc *TestService) error {
+	if len(svc.Ready) == 0 {
+		return s.waitConn(ctx, addr)
+	}
+	args := append([]string{"exec", id}, svc.Ready...)
+	for {
+		_, err := s.docker(ctx, args...)
+		if err == nil {
+			return nil
+		}
+		select {
+		case <-time.After(testServiceRetryInterval):
+		case <-ctx.Done():
+			return err
+		}
+	}
+}
+
+// waitForConn waits for a connection to the address that is not closed
+// straight away. The proxy of a published port accepts connections before the
+// service within the container listens, and closes them when the service
+// refuses them.
+func waitForConn(ctx context.Context, addr string) error {
+	var d net.Dialer
+	for {
+		err := checkConn(ctx, &d, addr)
+		if err == nil {
+			return nil
+		}
+		select {
+		case <-time.After(testServiceRetryInterval):
+		case <-ctx.Done():
+			return err
+		}
+	}
+}
+
+func checkConn(ctx context.Context, d *net.Dialer, addr string) error {
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// Services either wait for the client to send a request or send a banner,
+	// either of which means the connection is held by the service.
+	if err := conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond)); err != nil {
+		return err
+	}
+	var b [1]byte
+	if _, err = conn.Read(b[:]); err != nil {
+		var nerr net.Error
+		if errors.As(err, &nerr) && nerr.Timeout() {
+			return nil
+		}
+		return fmt.Errorf("connection closed: %w", err)
+	}
+	return nil
+}
+
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, fmt.Errorf("failed to obtain a free port: %w", err)
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+// The flags added to the test subcommand.
+var testFlags = subcommandFlags{
+	command: "test",
+	flags: []cli.Flag{
+		&cli.StringSliceFlag{
+			Name:  "services",
+			Usage: "Provision the services defined by a services file at a `path` as containers before running tests, with the variables of each service set as environment variables. Can be specified multiple times.",
+		},
+	},
+}
+
+// TestServicesArgs parses the flags added to the test subcommand from its
+// arguments, loading the services files they reference, and returns the
+// arguments without the flags. The returned services are nil when the
+// arguments are unchanged.
+func TestServicesArgs(args []string) ([]string, *TestServices, error) {
+	newArgs, c, err := testFlags.parse(args)
+	if err != nil || c == nil {
+		return newArgs, nil, err
+	}
+
+	services, err := LoadTestServices(c.StringSlice("services")...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return newArgs, services, nil
+}
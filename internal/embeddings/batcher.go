@@ -0,0 +1,212 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed as a Redpanda Enterprise file under the Redpanda Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+// https://github.com/redpanda-data/connect/blob/main/licenses/rcl.md
+
+// Package embeddings contains utilities shared by processors that compute
+// vector embeddings with the APIs of model providers.
+package embeddings
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/cenkalti/backoff/v4"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+
+	"github.com/redpanda-data/connect/v4/internal/retries"
+)
+
+const (
+	ebFieldBatching    = "batching"
+	ebFieldMaxInputs   = "max_inputs"
+	ebFieldMaxTokens   = "max_tokens"
+	ebFieldMaxInFlight = "max_in_flight"
+)
+
+// BatchingFields returns the fields of a Batcher, where the defaults of the
+// maximum inputs and tokens of a request are the limits of the provider. A
+// maximum of tokens of zero disables the limit.
+func BatchingFields(defaultMaxInputs, defaultMaxTokens int) []*service.ConfigField {
+	return append([]*service.ConfigField{
+		service.NewObjectField(ebFieldBatching,
+			service.NewIntField(ebFieldMaxInputs).
+				Description("The maximum number of texts to send within a single request.").
+				Default(defaultMaxInputs),
+			service.NewIntField(ebFieldMaxTokens).
+				Description("The maximum number of tokens to send within a single request, where the tokens of each text are estimated as one token for every four bytes. Set to `0` in order to disable the limit.").
+				Default(defaultMaxTokens),
+			service.NewIntField(ebFieldMaxInFlight).
+				Description("The maximum number of requests to have in flight for each batch of messages.").
+				Default(4),
+		).
+			Description("Control how the messages of a batch are coalesced into requests. Requests are only combined for messages of the same batch, and therefore in order to benefit from this the messages should be batched, either at the input level or with a xref:components:processors/batched.adoc[`batched` processor]. When a request containing multiple texts fails after retries each text is sent individually, so that only the messages responsible for an error are failed.").
+			Advanced(),
+	}, retries.CommonRetryBackOffFields(3, "500ms", "10s", "1m")...)
+}
+
+// Batcher computes embeddings of texts by combining them into requests within
+// limits, sent concurrently and retried with a backoff.
+type Batcher struct {
+	maxInputs   int
+	maxTokens   int
+	maxInFlight int
+	backoffCtor func() backoff.BackOff
+}
+
+// BatcherFromParsed creates a Batcher from a config with the BatchingFields.
+func BatcherFromParsed(conf *service.ParsedConfig) (*Batcher, error) {
+	b := &Batcher{}
+
+	bConf := conf.Namespace(ebFieldBatching)
+	var err error
+	if b.maxInputs, err = bConf.FieldInt(ebFieldMaxInputs); err != nil {
+		return nil, err
+	}
+	if b.maxInputs < 1 {
+		return nil, fmt.Errorf("%s.%s must be at least 1", ebFieldBatching, ebFieldMaxInputs)
+	}
+	if b.maxTokens, err = bConf.FieldInt(ebFieldMaxTokens); err != nil {
+		return nil, err
+	}
+	if b.maxInFlight, err = bConf.FieldInt(ebFieldMaxInFlight); err != nil {
+		return nil, err
+	}
+	if b.maxInFlight < 1 {
+		return nil, fmt.Errorf("%s.%s must be at least 1", ebFieldBatching, ebFieldMaxInFlight)
+	}
+	if b.backoffCtor, err = retries.CommonRetryBackOffCtorFromParsed(conf); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// NewBatcher creates a Batcher with explicit limits that does not retry
+// failed requests.
+func NewBatcher(maxInputs, maxTokens, maxInFlight int) *Batcher {
+	return &Batcher{
+		maxInputs:   maxInputs,
+		maxTokens:   maxTokens,
+		maxInFlight: maxInFlight,
+		backoffCtor: func() backoff.BackOff {
+			return &backoff.StopBackOff{}
+		},
+	}
+}
+
+// WithMaxInputs returns a copy of the Batcher with a different maximum number
+// of texts within a request, for models that accept fewer texts than the
+// provider.
+func (b *Batcher) WithMaxInputs(n int) *Batcher {
+	c := *b
+	c.maxInputs = n
+	return &c
+}
+
+// EmbedFunc computes the embeddings of texts with a single request, and must
+// return an embedding for each text in order.
+type EmbedFunc[V any] func(ctx context.Context, texts []string) ([]V, error)
+
+func estimateTokens(text string) int {
+	return (len(text) + 3) / 4
+}
+
+// requests splits the indexes of texts into the texts of each request.
+func (b *Batcher) requests(texts []string) [][]int {
+	var reqs [][]int
+	var current []int
+	var tokens int
+	for i, t := range texts {
+		tTokens := estimateTokens(t)
+		if len(current) > 0 && (len(current) >= b.maxInputs || (b.maxTokens > 0 && tokens+tTokens > b.maxTokens)) {
+			reqs = append(reqs, current)
+			current, tokens = nil, 0
+		}
+		current = append(current, i)
+		tokens += tTokens
+	}
+	if len(current) > 0 {
+		reqs = append(reqs, current)
+	}
+	return reqs
+}
+
+// Embed computes the embeddings of texts with a Batcher, returning an embedding
+// or an error for each text in order.
+func Embed[V any](ctx context.Context, b *Batcher, texts []string, fn EmbedFunc[V]) ([]V, []error) {
+	results := make([]V, len(texts))
+	errs := make([]error, len(texts))
+
+	slots := make(chan struct{}, b.maxInFlight)
+	var wg sync.WaitGroup
+	var failedMut sync.Mutex
+	var failed []int
+	send := func(indexes []int) {
+		slots <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			if !embed(ctx, b, texts, indexes, fn, results, errs) && len(indexes) > 1 {
+				failedMut.Lock()
+				failed = append(failed, indexes...)
+				failedMut.Unlock()
+			}
+		}()
+	}
+
+	for _, indexes := range b.requests(texts) {
+		send(indexes)
+	}
+	wg.Wait()
+
+	// Texts of failed requests are sent individually in order to isolate the
+	// texts responsible.
+	for _, i := range failed {
+		send([]int{i})
+	}
+	wg.Wait()
+	return results, errs
+}
+
+// embed sends a single request with retries and stores its results, returning
+// false when the request failed.
+func embed[V any](ctx context.Context, b *Batcher, texts []string, indexes []int, fn EmbedFunc[V], results []V, errs []error) bool {
+	reqTexts := make([]string, len(indexes))
+	for i, index := range indexes {
+		reqTexts[i] = texts[index]
+	}
+
+	var embeddings []V
+	err := backoff.Retry(func() error {
+		var err error
+		if embeddings, err = fn(ctx, reqTexts); err != nil {
+			return err
+		}
+		if len(embeddings) != len(reqTexts) {
+			return backoff.Permanent(fmt.Errorf("expected %d embeddings in response, got: %d", len(reqTexts), len(embeddings)))
+		}
+		return nil
+	}, backoff.WithContext(b.backoffCtor(), ctx))
+
+	var permanent *backoff.PermanentError
+	if errors.As(err, &permanent) {
+		err = permanent.Err
+	}
+	for i, index := range indexes {
+		if err != nil {
+			errs[index] = err
+			continue
+		}
+		results[index], errs[index] = embeddings[i], nil
+	}
+	return err == nil
+}
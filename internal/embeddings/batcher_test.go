@@ -0,0 +1,115 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed as a Redpanda Enterprise file under the Redpanda Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+// https://github.com/redpanda-data/connect/blob/main/licenses/rcl.md
+
+package embeddings
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func TestBatcherRequests(t *testing.T) {
+	b := NewBatcher(3, 0, 1)
+	assert.Equal(t, [][]int{{0, 1, 2}, {3, 4}}, b.requests([]string{"a", "b", "c", "d", "e"}))
+	assert.Nil(t, b.requests(nil))
+
+	// Texts that exceed the maximum tokens by themselves are sent alone.
+	b = NewBatcher(10, 2, 1)
+	assert.Equal(t, [][]int{{0, 1}, {2}, {3}}, b.requests([]string{"foo", "bar", "foobarbaz", "baz"}))
+}
+
+func TestBatcherEmbed(t *testing.T) {
+	var mut sync.Mutex
+	var reqs [][]string
+	fn := func(ctx context.Context, texts []string) ([]int, error) {
+		mut.Lock()
+		reqs = append(reqs, texts)
+		mut.Unlock()
+
+		if slices.Contains(texts, "bad") {
+			return nil, errors.New("nope")
+		}
+		lengths := make([]int, len(texts))
+		for i, t := range texts {
+			lengths[i] = len(t)
+		}
+		return lengths, nil
+	}
+
+	results, errs := Embed(context.Background(), NewBatcher(2, 0, 2), []string{"a", "bb", "ccc", "bad", "eeeee"}, fn)
+	assert.Equal(t, []int{1, 2, 3, 0, 5}, results)
+	for i, err := range errs {
+		if i == 3 {
+			assert.EqualError(t, err, "nope")
+			continue
+		}
+		assert.NoError(t, err)
+	}
+
+	// The texts of the failed request are sent again individually.
+	var sent []string
+	for _, r := range reqs {
+		sent = append(sent, strings.Join(r, ","))
+	}
+	assert.ElementsMatch(t, []string{"a,bb", "ccc,bad", "eeeee", "ccc", "bad"}, sent)
+}
+
+func TestBatcherEmbedMismatch(t *testing.T) {
+	fn := func(ctx context.Context, texts []string) ([]int, error) {
+		return []int{}, nil
+	}
+
+	_, errs := Embed(context.Background(), NewBatcher(2, 0, 1), []string{"a", "b"}, fn)
+	for _, err := range errs {
+		assert.EqualError(t, err, "expected 1 embeddings in response, got: 0")
+	}
+}
+
+func TestBatcherFromParsed(t *testing.T) {
+	spec := service.NewConfigSpec().Fields(BatchingFields(96, 0)...)
+
+	conf, err := spec.ParseYAML(`
+batching:
+  max_inputs: 10
+  max_in_flight: 2
+max_retries: 1
+`, nil)
+	require.NoError(t, err)
+
+	b, err := BatcherFromParsed(conf)
+	require.NoError(t, err)
+	assert.Equal(t, 10, b.maxInputs)
+	assert.Equal(t, 0, b.maxTokens)
+	assert.Equal(t, 2, b.maxInFlight)
+
+	var calls int
+	_, errs := Embed(context.Background(), b, []string{"a"}, func(ctx context.Context, texts []string) ([]int, error) {
+		calls++
+		return nil, errors.New("nope")
+	})
+	assert.Error(t, errs[0])
+	assert.Equal(t, 2, calls)
+
+	conf, err = spec.ParseYAML(`
+batching:
+  max_inputs: 0
+`, nil)
+	require.NoError(t, err)
+
+	_, err = BatcherFromParsed(conf)
+	require.Error(t, err)
+}
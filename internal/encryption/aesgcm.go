@@ -0,0 +1,115 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package encryption provides an envelope for encrypting data that components
+// store at rest, such as the contents of buffers and caches.
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	fieldKey = "key"
+
+	envelopeVersion byte = 1
+)
+
+// AESGCMField returns a config field for an AES-GCM encryption envelope, which
+// is enabled when a key is provided.
+func AESGCMField(name string) *service.ConfigField {
+	return service.NewObjectField(name,
+		service.NewStringField(fieldKey).
+			Description("A hex or base64 encoded AES key of 16, 24 or 32 bytes, selecting AES-128, AES-192 or AES-256 respectively. When empty data is stored unencrypted.").
+			Example("${AT_REST_KEY}").
+			Secret().
+			Default(""),
+	).
+		Description("Encrypt data at rest with AES-GCM. The key should be supplied through an environment variable or a secrets manager configured with the `--secrets` flag rather than written into the config directly.").
+		Advanced().
+		Version("4.45.0")
+}
+
+// AESGCMFromParsed returns an envelope from a field defined by AESGCMField, or
+// nil if encryption is disabled.
+func AESGCMFromParsed(conf *service.ParsedConfig) (*Envelope, error) {
+	keyStr, err := conf.FieldString(fieldKey)
+	if err != nil || keyStr == "" {
+		return nil, err
+	}
+
+	key, err := hex.DecodeString(keyStr)
+	if err != nil {
+		if key, err = base64.StdEncoding.DecodeString(keyStr); err != nil {
+			return nil, errors.New("key must be hex or base64 encoded")
+		}
+	}
+	return NewEnvelope(key)
+}
+
+// Envelope encrypts and decrypts data with AES-GCM. Sealed data consists of a
+// version byte followed by a random nonce and the ciphertext.
+type Envelope struct {
+	aead cipher.AEAD
+}
+
+// NewEnvelope creates an envelope from an AES key of 16, 24 or 32 bytes.
+func NewEnvelope(key []byte) (*Envelope, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Envelope{aead: aead}, nil
+}
+
+// Seal encrypts plaintext, and additionalData is authenticated but not
+// encrypted and must be provided again in order to open the result.
+func (e *Envelope) Seal(plaintext, additionalData []byte) ([]byte, error) {
+	nonceSize := e.aead.NonceSize()
+
+	sealed := make([]byte, 1+nonceSize, 1+nonceSize+len(plaintext)+e.aead.Overhead())
+	sealed[0] = envelopeVersion
+	if _, err := rand.Read(sealed[1:]); err != nil {
+		return nil, err
+	}
+	return e.aead.Seal(sealed, sealed[1:], plaintext, additionalData), nil
+}
+
+// Open decrypts data that was encrypted with Seal.
+func (e *Envelope) Open(sealed, additionalData []byte) ([]byte, error) {
+	nonceSize := e.aead.NonceSize()
+	if len(sealed) < 1+nonceSize {
+		return nil, errors.New("encrypted data is too short")
+	}
+	if sealed[0] != envelopeVersion {
+		return nil, fmt.Errorf("unsupported encryption envelope version: %v", sealed[0])
+	}
+	plaintext, err := e.aead.Open(nil, sealed[1:1+nonceSize], sealed[1+nonceSize:], additionalData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt data: %w", err)
+	}
+	return plaintext, nil
+}
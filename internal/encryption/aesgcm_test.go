@@ -0,0 +1,78 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package encryption
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func TestEnvelopeRoundTrip(t *testing.T) {
+	e, err := NewEnvelope([]byte("0123456789abcdef0123456789abcdef"))
+	require.NoError(t, err)
+
+	sealed, err := e.Seal([]byte("hello world"), []byte("foo"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(sealed), "hello world")
+
+	plaintext, err := e.Open(sealed, []byte("foo"))
+	require.NoError(t, err)
+	assert.Equal(t, "hello world", string(plaintext))
+
+	_, err = e.Open(sealed, []byte("bar"))
+	require.Error(t, err)
+
+	sealed[len(sealed)-1] ^= 0xff
+	_, err = e.Open(sealed, []byte("foo"))
+	require.Error(t, err)
+}
+
+func TestAESGCMFromParsed(t *testing.T) {
+	spec := service.NewConfigSpec().Field(AESGCMField("encryption"))
+
+	tests := []struct {
+		name        string
+		key         string
+		enabled     bool
+		errContains string
+	}{
+		{name: "empty", key: `""`},
+		{name: "hex", key: "000102030405060708090a0b0c0d0e0f", enabled: true},
+		{name: "base64", key: "AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8=", enabled: true},
+		{name: "bad encoding", key: "not a key!", errContains: "hex or base64"},
+		{name: "bad length", key: "000102", errContains: "invalid key size"},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			conf, err := spec.ParseYAML("encryption:\n  key: "+test.key, nil)
+			require.NoError(t, err)
+
+			e, err := AESGCMFromParsed(conf.Namespace("encryption"))
+			if test.errContains != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.errContains)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.enabled, e != nil)
+		})
+	}
+}
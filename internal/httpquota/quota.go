@@ -0,0 +1,202 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package httpquota tracks the quotas that HTTP services report to clients
+// with response headers, allowing components to expose them as metrics and to
+// slow down before a quota is exhausted.
+package httpquota
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	qtFieldThrottle     = "throttle"
+	qtFieldMinRemaining = "min_remaining"
+	qtFieldMaxWait      = "max_wait"
+)
+
+// Field returns a config field for tracking quotas of an HTTP service.
+func Field(name string) *service.ConfigField {
+	return service.NewObjectField(name,
+		service.NewBoolField(qtFieldThrottle).
+			Description("Whether requests should be paused when the service reports that its quota is exhausted, or that requests should not be retried until later with a `Retry-After` header.").
+			Default(false),
+		service.NewIntField(qtFieldMinRemaining).
+			Description("When throttling, requests are paused until the quota resets once the remaining quota reported is at or below this value.").
+			Default(0),
+		service.NewDurationField(qtFieldMaxWait).
+			Description("The maximum period to pause a request for when throttling.").
+			Default("1m"),
+	).Description("Track the quota reported by the service with the response headers `X-RateLimit-Limit`, `X-RateLimit-Remaining`, `X-RateLimit-Reset` (or their `RateLimit-*` equivalents) and `Retry-After`. The values are exposed with the gauges `http_quota_limit`, `http_quota_remaining`, `http_quota_reset_seconds` and `http_retry_after_seconds`, and requests can optionally be throttled in order to avoid exceeding the quota.").
+		Advanced()
+}
+
+// Tracker records the quota reported by an HTTP service.
+type Tracker struct {
+	throttle     bool
+	minRemaining int64
+	maxWait      time.Duration
+
+	limitGauge      *service.MetricGauge
+	remainingGauge  *service.MetricGauge
+	resetGauge      *service.MetricGauge
+	retryAfterGauge *service.MetricGauge
+
+	nowFn func() time.Time
+
+	mut          sync.Mutex
+	hasRemaining bool
+	remaining    int64
+	resetAt      time.Time
+	retryAt      time.Time
+}
+
+// TrackerFromParsed creates a Tracker from a field created with Field.
+func TrackerFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*Tracker, error) {
+	t := &Tracker{
+		limitGauge:      mgr.Metrics().NewGauge("http_quota_limit"),
+		remainingGauge:  mgr.Metrics().NewGauge("http_quota_remaining"),
+		resetGauge:      mgr.Metrics().NewGauge("http_quota_reset_seconds"),
+		retryAfterGauge: mgr.Metrics().NewGauge("http_retry_after_seconds"),
+		nowFn:           time.Now,
+	}
+
+	var err error
+	if t.throttle, err = conf.FieldBool(qtFieldThrottle); err != nil {
+		return nil, err
+	}
+	var minRemaining int
+	if minRemaining, err = conf.FieldInt(qtFieldMinRemaining); err != nil {
+		return nil, err
+	}
+	t.minRemaining = int64(minRemaining)
+	if t.maxWait, err = conf.FieldDuration(qtFieldMaxWait); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// headerInt returns the leading integer of the first header present, which
+// allows for values such as `100, 100;w=60` found in draft standard headers.
+func headerInt(h http.Header, keys ...string) (int64, bool) {
+	for _, k := range keys {
+		v := strings.TrimSpace(h.Get(k))
+		if v == "" {
+			continue
+		}
+		if i := strings.IndexAny(v, ",;"); i >= 0 {
+			v = strings.TrimSpace(v[:i])
+		}
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return n, true
+		}
+	}
+	return 0, false
+}
+
+// RetryAfter parses a Retry-After header, which is either a number of seconds
+// or an HTTP date, into the time at which requests can be retried.
+func RetryAfter(h http.Header, now time.Time) (time.Time, bool) {
+	v := strings.TrimSpace(h.Get("Retry-After"))
+	if v == "" {
+		return time.Time{}, false
+	}
+	if secs, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return now.Add(time.Duration(secs) * time.Second), true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return t, true
+	}
+	return time.Time{}, false
+}
+
+// Observe records the quota reported by a response.
+func (t *Tracker) Observe(res *http.Response) {
+	now := t.nowFn()
+
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	if limit, ok := headerInt(res.Header, "X-RateLimit-Limit", "RateLimit-Limit", "X-Rate-Limit-Limit"); ok {
+		t.limitGauge.Set(limit)
+	}
+	if remaining, ok := headerInt(res.Header, "X-RateLimit-Remaining", "RateLimit-Remaining", "X-Rate-Limit-Remaining"); ok {
+		t.hasRemaining, t.remaining = true, remaining
+		t.remainingGauge.Set(remaining)
+	}
+	if reset, ok := headerInt(res.Header, "X-RateLimit-Reset", "RateLimit-Reset", "X-Rate-Limit-Reset"); ok {
+		// Values large enough to be a unix timestamp are treated as one,
+		// otherwise they are the number of seconds until the reset.
+		if reset > 1_000_000_000 {
+			t.resetAt = time.Unix(reset, 0)
+		} else {
+			t.resetAt = now.Add(time.Duration(reset) * time.Second)
+		}
+		t.resetGauge.Set(int64(max(t.resetAt.Sub(now), 0) / time.Second))
+	}
+	if retryAt, ok := RetryAfter(res.Header, now); ok {
+		t.retryAt = retryAt
+		t.retryAfterGauge.Set(int64(max(retryAt.Sub(now), 0) / time.Second))
+	} else {
+		t.retryAfterGauge.Set(0)
+	}
+}
+
+// Delay returns the period that the next request should be paused for in order
+// to respect the reported quota, which is zero unless throttling is enabled.
+func (t *Tracker) Delay() time.Duration {
+	if !t.throttle {
+		return 0
+	}
+
+	now := t.nowFn()
+
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	var until time.Time
+	if t.retryAt.After(now) {
+		until = t.retryAt
+	}
+	if t.hasRemaining && t.remaining <= t.minRemaining && t.resetAt.After(now) && t.resetAt.After(until) {
+		until = t.resetAt
+	}
+	if until.IsZero() {
+		return 0
+	}
+	return min(until.Sub(now), t.maxWait)
+}
+
+// Wait blocks for the period returned by Delay, or until the context is
+// cancelled.
+func (t *Tracker) Wait(ctx context.Context) error {
+	delay := t.Delay()
+	if delay <= 0 {
+		return nil
+	}
+	select {
+	case <-time.After(delay):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
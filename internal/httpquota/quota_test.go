@@ -0,0 +1,113 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpquota
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func testTracker(t *testing.T, yamlStr string, now *time.Time) *Tracker {
+	t.Helper()
+
+	spec := service.NewConfigSpec().Field(Field("quota"))
+	conf, err := spec.ParseYAML(yamlStr, nil)
+	require.NoError(t, err)
+
+	tracker, err := TrackerFromParsed(conf.Namespace("quota"), service.MockResources())
+	require.NoError(t, err)
+	tracker.nowFn = func() time.Time { return *now }
+	return tracker
+}
+
+func response(headers map[string]string) *http.Response {
+	res := &http.Response{Header: http.Header{}}
+	for k, v := range headers {
+		res.Header.Set(k, v)
+	}
+	return res
+}
+
+func TestTrackerRemaining(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	tracker := testTracker(t, `
+quota:
+  throttle: true
+  min_remaining: 5
+  max_wait: 30s
+`, &now)
+
+	tracker.Observe(response(map[string]string{
+		"X-RateLimit-Limit":     "100",
+		"X-RateLimit-Remaining": "50",
+		"X-RateLimit-Reset":     "10",
+	}))
+	assert.Equal(t, time.Duration(0), tracker.Delay())
+
+	tracker.Observe(response(map[string]string{
+		"RateLimit-Limit":     "100, 100;w=60",
+		"RateLimit-Remaining": "5",
+		"RateLimit-Reset":     "10",
+	}))
+	assert.Equal(t, 10*time.Second, tracker.Delay())
+
+	// Unix timestamps are capped by the maximum wait.
+	tracker.Observe(response(map[string]string{
+		"X-RateLimit-Remaining": "0",
+		"X-RateLimit-Reset":     "1700000060",
+	}))
+	assert.Equal(t, 30*time.Second, tracker.Delay())
+
+	now = now.Add(time.Minute)
+	assert.Equal(t, time.Duration(0), tracker.Delay())
+}
+
+func TestTrackerRetryAfter(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	tracker := testTracker(t, `
+quota:
+  throttle: true
+`, &now)
+
+	tracker.Observe(response(map[string]string{"Retry-After": "3"}))
+	assert.Equal(t, 3*time.Second, tracker.Delay())
+
+	tracker.Observe(response(map[string]string{"Retry-After": now.Add(7 * time.Second).UTC().Format(http.TimeFormat)}))
+	assert.Equal(t, 7*time.Second, tracker.Delay())
+
+	tracker.Observe(response(map[string]string{"Retry-After": "nope"}))
+	assert.Equal(t, 7*time.Second, tracker.Delay())
+
+	now = now.Add(10 * time.Second)
+	assert.Equal(t, time.Duration(0), tracker.Delay())
+}
+
+func TestTrackerNoThrottle(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	tracker := testTracker(t, `{}`, &now)
+
+	tracker.Observe(response(map[string]string{
+		"X-RateLimit-Remaining": "0",
+		"X-RateLimit-Reset":     "10",
+		"Retry-After":           "10",
+	}))
+	assert.Equal(t, time.Duration(0), tracker.Delay())
+}
@@ -25,6 +25,7 @@ const (
 	queueDeclareEnabledField     = "enabled"
 	queueDeclareDurableField     = "durable"
 	queueDeclareAutoDeleteField  = "auto_delete"
+	queueDeclareTypeField        = "type"
 	bindingsDeclareField         = "bindings_declare"
 	bindingsDeclareExchangeField = "exchange"
 	bindingsDeclareKeyField      = "key"
@@ -33,6 +34,9 @@ const (
 	nackRejectPattensField       = "nack_reject_patterns"
 	prefetchCountField           = "prefetch_count"
 	prefetchSizeField            = "prefetch_size"
+	prefetchGlobalField          = "prefetch_global"
+	consumerPriorityField        = "consumer_priority"
+	streamOffsetField            = "stream_offset"
 
 	// Output
 	exchangeField               = "exchange"
@@ -29,6 +29,8 @@ import (
 	amqp "github.com/rabbitmq/amqp091-go"
 
 	"github.com/redpanda-data/benthos/v4/public/service"
+
+	"github.com/redpanda-data/connect/v4/internal/tracing"
 )
 
 func amqp09InputSpec() *service.ConfigSpec {
@@ -81,6 +83,14 @@ You can access these metadata fields using xref:configuration:interpolation.adoc
 			service.NewBoolField(queueDeclareAutoDeleteField).
 				Description("Whether the declared queue will auto-delete.").
 				Default(false),
+			service.NewStringAnnotatedEnumField(queueDeclareTypeField, map[string]string{
+				"classic": "A classic queue.",
+				"quorum":  "A replicated quorum queue, which must be durable and cannot be auto-deleted.",
+				"stream":  "A stream queue, which must be durable and cannot be auto-deleted. Consumers of a stream can specify a `stream_offset` to start from.",
+			}).
+				Description("The type of queue to declare, which is set via the `x-queue-type` argument. When omitted the server default is used.").
+				Optional().
+				Version("4.45.0"),
 		).
 			Description(`Allows you to passively declare the target queue. If the queue already exists then the declaration passively verifies that they match the target fields.`).
 			Advanced().
@@ -122,13 +132,34 @@ You can access these metadata fields using xref:configuration:interpolation.adoc
 			Description("The maximum amount of pending messages measured in bytes to have consumed at a time.").
 			Default(0).
 			Advanced(),
+		service.NewBoolField(prefetchGlobalField).
+			Description("Whether the `prefetch_count` and `prefetch_size` limits apply to the channel as a whole rather than to each consumer of the channel. The interpretation of this flag varies between servers: RabbitMQ applies limits per consumer by default and shares them across all consumers of the channel when this is set.").
+			Default(false).
+			Advanced().
+			Version("4.45.0"),
+		service.NewIntField(consumerPriorityField).
+			Description("An optional priority for this consumer, set via the `x-priority` consumer argument. Servers that support consumer priorities deliver messages to higher priority consumers whilst they are able to receive them, and only fall back to lower priority consumers when they are blocked.").
+			Example(10).
+			Optional().
+			Advanced().
+			Version("4.45.0"),
+		service.NewStringField(streamOffsetField).
+			Description("When consuming from a stream queue, the offset to begin consuming from, set via the `x-stream-offset` consumer argument. Can be one of `first`, `last`, `next`, a numerical offset, or a relative interval supported by the server such as `1D` or `12h`. Stream queues require manual acknowledgements and a non-zero `prefetch_count`.").
+			Examples("first", "next", "5000", "1D").
+			Optional().
+			Advanced().
+			Version("4.45.0"),
 		service.NewTLSToggledField(tlsField),
-	)
+	).Fields(tracing.ExtractFields()...)
 }
 
 func init() {
 	err := service.RegisterInput("amqp_0_9", amqp09InputSpec(), func(conf *service.ParsedConfig, mgr *service.Resources) (service.Input, error) {
-		return amqp09ReaderFromParsed(conf, mgr)
+		r, err := amqp09ReaderFromParsed(conf, mgr)
+		if err != nil {
+			return nil, err
+		}
+		return tracing.WrapInput(conf, mgr, "amqp_0_9", r)
 	})
 	if err != nil {
 		panic(err)
@@ -154,16 +185,19 @@ type amqp09Reader struct {
 	tlsEnabled bool
 	tlsConf    *tls.Config
 
-	prefetchCount int
-	prefetchSize  int
-	consumerTag   string
-	autoAck       bool
+	prefetchCount  int
+	prefetchSize   int
+	prefetchGlobal bool
+	consumerTag    string
+	autoAck        bool
+	consumerArgs   amqp.Table
 
 	nackRejectPattens []*regexp.Regexp
 
 	queueDeclare    bool
 	queueDurable    bool
 	queueAutoDelete bool
+	queueArgs       amqp.Table
 
 	bindingDeclare []amqp09BindingDeclare
 
@@ -205,6 +239,9 @@ func amqp09ReaderFromParsed(conf *service.ParsedConfig, mgr *service.Resources)
 	if a.prefetchSize, err = conf.FieldInt(prefetchSizeField); err != nil {
 		return nil, err
 	}
+	if a.prefetchGlobal, err = conf.FieldBool(prefetchGlobalField); err != nil {
+		return nil, err
+	}
 	if a.consumerTag, err = conf.FieldString(consumerTagField); err != nil {
 		return nil, err
 	}
@@ -231,6 +268,41 @@ func amqp09ReaderFromParsed(conf *service.ParsedConfig, mgr *service.Resources)
 		a.queueDeclare, _ = qdConf.FieldBool(queueDeclareEnabledField)
 		a.queueDurable, _ = qdConf.FieldBool(queueDeclareDurableField)
 		a.queueAutoDelete, _ = qdConf.FieldBool(queueDeclareAutoDeleteField)
+		if a.queueDeclare && qdConf.Contains(queueDeclareTypeField) {
+			qType, err := qdConf.FieldString(queueDeclareTypeField)
+			if err != nil {
+				return nil, err
+			}
+			if qType != "classic" && (a.queueAutoDelete || !a.queueDurable) {
+				return nil, fmt.Errorf("%v queues must be durable and cannot be auto-deleted", qType)
+			}
+			a.queueArgs = amqp.Table{"x-queue-type": qType}
+		}
+	}
+
+	a.consumerArgs = amqp.Table{}
+	if conf.Contains(consumerPriorityField) {
+		priority, err := conf.FieldInt(consumerPriorityField)
+		if err != nil {
+			return nil, err
+		}
+		a.consumerArgs["x-priority"] = int32(priority)
+	}
+	if conf.Contains(streamOffsetField) {
+		offsetStr, err := conf.FieldString(streamOffsetField)
+		if err != nil {
+			return nil, err
+		}
+		if a.autoAck {
+			return nil, errors.New("auto_ack cannot be enabled when consuming from a stream offset")
+		}
+		if a.prefetchCount <= 0 {
+			return nil, errors.New("prefetch_count must be greater than zero when consuming from a stream offset")
+		}
+		a.consumerArgs["x-stream-offset"] = parseStreamOffset(offsetStr)
+	}
+	if len(a.consumerArgs) == 0 {
+		a.consumerArgs = nil
 	}
 
 	if conf.Contains(bindingsDeclareField) {
@@ -253,6 +325,16 @@ func amqp09ReaderFromParsed(conf *service.ParsedConfig, mgr *service.Resources)
 	return &a, nil
 }
 
+// parseStreamOffset converts a stream offset string into the type expected by
+// the server, where numerical offsets must be sent as integers and named or
+// interval based offsets as strings.
+func parseStreamOffset(s string) any {
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return i
+	}
+	return s
+}
+
 //------------------------------------------------------------------------------
 
 // Connect establishes a connection to an AMQP09 server.
@@ -284,7 +366,7 @@ func (a *amqp09Reader) Connect(ctx context.Context) (err error) {
 			a.queueAutoDelete, // delete when unused
 			false,             // exclusive
 			false,             // noWait
-			nil,               // arguments
+			a.queueArgs,       // arguments
 		); err != nil {
 			_ = amqpChan.Close()
 			_ = conn.Close()
@@ -307,7 +389,7 @@ func (a *amqp09Reader) Connect(ctx context.Context) (err error) {
 	}
 
 	if err = amqpChan.Qos(
-		a.prefetchCount, a.prefetchSize, false,
+		a.prefetchCount, a.prefetchSize, a.prefetchGlobal,
 	); err != nil {
 		_ = amqpChan.Close()
 		_ = conn.Close()
@@ -315,13 +397,13 @@ func (a *amqp09Reader) Connect(ctx context.Context) (err error) {
 	}
 
 	if consumerChan, err = amqpChan.Consume(
-		a.queue,       // name
-		a.consumerTag, // consumerTag,
-		a.autoAck,     // autoAck
-		false,         // exclusive
-		false,         // noLocal
-		false,         // noWait
-		nil,           // arguments
+		a.queue,        // name
+		a.consumerTag,  // consumerTag,
+		a.autoAck,      // autoAck
+		false,          // exclusive
+		false,          // noLocal
+		false,          // noWait
+		a.consumerArgs, // arguments
 	); err != nil {
 		_ = amqpChan.Close()
 		_ = conn.Close()
@@ -0,0 +1,133 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package amqp09
+
+import (
+	"testing"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func TestAMQP09InputConsumerArgs(t *testing.T) {
+	for _, test := range []struct {
+		name           string
+		config         string
+		queueArgs      amqp.Table
+		consumerArgs   amqp.Table
+		prefetchGlobal bool
+		errContains    string
+	}{
+		{
+			name: "defaults",
+			config: `
+urls: [ amqp://localhost:5672 ]
+queue: foo
+`,
+		},
+		{
+			name: "quorum queue with priority",
+			config: `
+urls: [ amqp://localhost:5672 ]
+queue: foo
+queue_declare:
+  enabled: true
+  type: quorum
+consumer_priority: 5
+`,
+			queueArgs:    amqp.Table{"x-queue-type": "quorum"},
+			consumerArgs: amqp.Table{"x-priority": int32(5)},
+		},
+		{
+			name: "quorum queue auto delete",
+			config: `
+urls: [ amqp://localhost:5672 ]
+queue: foo
+queue_declare:
+  enabled: true
+  type: quorum
+  auto_delete: true
+`,
+			errContains: "cannot be auto-deleted",
+		},
+		{
+			name: "quorum queue auto delete without declaration",
+			config: `
+urls: [ amqp://localhost:5672 ]
+queue: foo
+queue_declare:
+  enabled: false
+  type: quorum
+  auto_delete: true
+`,
+		},
+		{
+			name: "numeric stream offset",
+			config: `
+urls: [ amqp://localhost:5672 ]
+queue: foo
+stream_offset: "5000"
+`,
+			consumerArgs: amqp.Table{"x-stream-offset": int64(5000)},
+		},
+		{
+			name: "named stream offset",
+			config: `
+urls: [ amqp://localhost:5672 ]
+queue: foo
+stream_offset: first
+`,
+			consumerArgs: amqp.Table{"x-stream-offset": "first"},
+		},
+		{
+			name: "stream offset with auto ack",
+			config: `
+urls: [ amqp://localhost:5672 ]
+queue: foo
+auto_ack: true
+stream_offset: first
+`,
+			errContains: "auto_ack",
+		},
+		{
+			name: "global prefetch",
+			config: `
+urls: [ amqp://localhost:5672 ]
+queue: foo
+prefetch_global: true
+`,
+			prefetchGlobal: true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			conf, err := amqp09InputSpec().ParseYAML(test.config, nil)
+			require.NoError(t, err)
+
+			r, err := amqp09ReaderFromParsed(conf, service.MockResources())
+			if test.errContains != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.errContains)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.queueArgs, r.queueArgs)
+			assert.Equal(t, test.consumerArgs, r.consumerArgs)
+			assert.Equal(t, test.prefetchGlobal, r.prefetchGlobal)
+		})
+	}
+}
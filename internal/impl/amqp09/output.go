@@ -118,11 +118,11 @@ The fields 'key', 'exchange' and 'type' can be dynamically set using xref:config
 				Advanced().
 				Default(false),
 			service.NewBoolField(mandatoryField).
-				Description("Whether to set the mandatory flag on published messages. When set if a published message is routed to zero queues it is returned.").
+				Description("Whether to set the mandatory flag on published messages. When set if a published message is routed to zero queues it is returned, and the write fails. In order to attribute returned messages correctly publishes are not pipelined when this flag is set, which limits throughput regardless of `max_in_flight`.").
 				Advanced().
 				Default(false),
 			service.NewBoolField(immediateField).
-				Description("Whether to set the immediate flag on published messages. When set if there are no ready consumers of a queue then the message is dropped instead of waiting.").
+				Description("Whether to set the immediate flag on published messages. When set if there are no ready consumers of a queue then the message is returned instead of waiting, and the write fails. In order to attribute returned messages correctly publishes are not pipelined when this flag is set, which limits throughput regardless of `max_in_flight`.").
 				Advanced().
 				Default(false),
 			service.NewDurationField(timeoutField).
@@ -130,6 +130,7 @@ The fields 'key', 'exchange' and 'type' can be dynamically set using xref:config
 				Advanced().
 				Default(""),
 			service.NewTLSToggledField(tlsField),
+			service.NewInjectTracingSpanMappingField().Version("4.45.0"),
 		)
 }
 
@@ -140,7 +141,11 @@ func init() {
 			return nil, 0, err
 		}
 		w, err := amqp09WriterFromParsed(conf, mgr)
-		return w, maxInFlight, err
+		if err != nil {
+			return nil, 0, err
+		}
+		spanOutput, err := conf.WrapOutputExtractTracingSpanMapping("amqp_0_9", w)
+		return spanOutput, maxInFlight, err
 	})
 	if err != nil {
 		panic(err)
@@ -182,6 +187,7 @@ type amqp09Writer struct {
 	conn       *amqp.Connection
 	amqpChan   *amqp.Channel
 	returnChan <-chan amqp.Return
+	returnMut  sync.Mutex
 
 	connLock sync.RWMutex
 }
@@ -473,6 +479,14 @@ func (a *amqp09Writer) Write(ctx context.Context, msg *service.Message) error {
 		return fmt.Errorf("amqp failed to declare exchange: %w", err)
 	}
 
+	if returnChan != nil {
+		// Returned messages carry nothing that ties them to a publish, and so
+		// in order to attribute a return to the right message the publish and
+		// its confirmation must not overlap with those of other messages.
+		a.returnMut.Lock()
+		defer a.returnMut.Unlock()
+	}
+
 	conf, err := amqpChan.PublishWithDeferredConfirmWithContext(
 		ctx,
 		exchange,    // publish to an exchange
@@ -501,17 +515,23 @@ func (a *amqp09Writer) Write(ctx context.Context, msg *service.Message) error {
 		a.log.Errorf("Failed to send message: %w", err)
 		return service.ErrNotConnected
 	}
-	if !conf.Wait() {
+	acked, err := conf.WaitContext(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errNoAck, err)
+	}
+	if !acked {
 		a.log.Error("Failed to acknowledge message.")
-		return errNoAck
+		return fmt.Errorf("%w: message nacked by server", errNoAck)
 	}
 	if returnChan != nil {
+		// The server sends a returned message before the confirmation of its
+		// publish, and therefore once confirmed any return is already queued.
 		select {
-		case _, open := <-returnChan:
+		case ret, open := <-returnChan:
 			if !open {
 				return errors.New("acknowledgement not supported, ensure server supports immediate and mandatory flags")
 			}
-			return errNoAck
+			return fmt.Errorf("%w: message returned by server with code %v: %v", errNoAck, ret.ReplyCode, ret.ReplyText)
 		default:
 		}
 	}
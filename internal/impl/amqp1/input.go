@@ -29,6 +29,8 @@ import (
 	"github.com/Azure/go-amqp"
 
 	"github.com/redpanda-data/benthos/v4/public/service"
+
+	"github.com/redpanda-data/connect/v4/internal/tracing"
 )
 
 //go:embed input_description.adoc
@@ -76,7 +78,9 @@ func amqp1InputSpec() *service.ConfigSpec {
 				Advanced(),
 			service.NewTLSToggledField(tlsField),
 			saslFieldSpec(),
-		).LintRule(`
+		).
+		Fields(tracing.ExtractFields()...).
+		LintRule(`
 root = if this.url.or("") == "" && this.urls.or([]).length() == 0 {
   "field 'urls' must be set"
 }
@@ -86,7 +90,11 @@ root = if this.url.or("") == "" && this.urls.or([]).length() == 0 {
 func init() {
 	err := service.RegisterBatchInput("amqp_1", amqp1InputSpec(),
 		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchInput, error) {
-			return amqp1ReaderFromParsed(conf, mgr)
+			r, err := amqp1ReaderFromParsed(conf, mgr)
+			if err != nil {
+				return nil, err
+			}
+			return tracing.WrapBatchInput(conf, mgr, "amqp_1", r)
 		})
 	if err != nil {
 		panic(err)
@@ -68,6 +68,7 @@ This output benefits from sending multiple messages in flight in parallel for im
 			saslFieldSpec(),
 			service.NewMetadataExcludeFilterField(metaFilterField).
 				Description("Specify criteria for which metadata values are attached to messages as headers."),
+			service.NewInjectTracingSpanMappingField().Version("4.45.0"),
 		).LintRule(`
 root = if this.url.or("") == "" && this.urls.or([]).length() == 0 {
   "field 'urls' must be set"
@@ -88,7 +89,8 @@ func init() {
 				return nil, 0, err
 			}
 
-			return w, mIF, nil
+			spanOutput, err := conf.WrapOutputExtractTracingSpanMapping("amqp_1", w)
+			return spanOutput, mIF, err
 		})
 	if err != nil {
 		panic(err)
@@ -47,6 +47,8 @@ a binary value using the ` + "`data_key`" + ` field name.`).
 		Description(`A prefix can be specified to allow multiple cache types to share a single DynamoDB table. An optional TTL duration (` + "`ttl`" + `) and field
 (` + "`ttl_key`" + `) can be specified if the backing table has TTL enabled.
 
+When a ` + "`ttl_key`" + ` is specified it should be the attribute configured for https://docs.aws.amazon.com/amazondynamodb/latest/developerguide/TTL.html[DynamoDB TTL^], which stores the expiry of each item as a Unix timestamp in seconds. Since DynamoDB deletes expired items in the background, some time after they expire, items that have expired are treated as missing by Get commands, and Add commands are conditioned on either the key not existing or the item having expired, so that expired keys can be added again immediately. This makes the cache suitable for deduplication without relying on the timing of deletions.
+
 Strong read consistency can be enabled using the ` + "`consistent_read`" + ` configuration field.`).
 		Field(service.NewStringField("table").
 			Description("The table to store items in.")).
@@ -242,11 +244,28 @@ func (d *dynamodbCache) get(ctx context.Context, key string) ([]byte, error) {
 		return nil, err
 	}
 
-	val, ok := res.Item[d.dataKey].(*types.AttributeValueMemberB)
+	val, ok := d.itemValue(res.Item, time.Now())
 	if !ok {
 		return nil, service.ErrKeyNotFound
 	}
-	return val.Value, nil
+	return val, nil
+}
+
+// itemValue returns the value of an item, or false if the item has no value
+// or has expired but not yet been deleted by DynamoDB.
+func (d *dynamodbCache) itemValue(item map[string]types.AttributeValue, now time.Time) ([]byte, bool) {
+	val, ok := item[d.dataKey].(*types.AttributeValueMemberB)
+	if !ok {
+		return nil, false
+	}
+	if d.ttlKey != nil {
+		if expiry, ok := item[*d.ttlKey].(*types.AttributeValueMemberN); ok {
+			if ts, err := strconv.ParseInt(expiry.Value, 10, 64); err == nil && ts <= now.Unix() {
+				return nil, false
+			}
+		}
+	}
+	return val.Value, true
 }
 
 func (d *dynamodbCache) Set(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
@@ -348,13 +367,20 @@ func (d *dynamodbCache) Add(ctx context.Context, key string, value []byte, ttl *
 func (d *dynamodbCache) add(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
 	input := d.putItemInput(key, value, ttl)
 
+	cond := expression.AttributeNotExists(expression.Name(d.hashKey))
+	if d.ttlKey != nil {
+		// Expired items may not have been deleted yet, in which case they
+		// can be replaced.
+		cond = cond.Or(expression.Name(*d.ttlKey).LessThanEqual(expression.Value(time.Now().Unix())))
+	}
 	expr, err := expression.NewBuilder().
-		WithCondition(expression.AttributeNotExists(expression.Name(d.hashKey))).
+		WithCondition(cond).
 		Build()
 	if err != nil {
 		return err
 	}
 	input.ExpressionAttributeNames = expr.Names()
+	input.ExpressionAttributeValues = expr.Values()
 	input.ConditionExpression = expr.Condition()
 
 	if _, err = d.client.PutItem(ctx, input); err != nil {
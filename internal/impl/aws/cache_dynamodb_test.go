@@ -15,12 +15,19 @@
 package aws
 
 import (
+	"context"
+	"strconv"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/cenkalti/backoff/v4"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
 )
 
 func TestDynamoDBCacheConfig(t *testing.T) {
@@ -102,3 +109,86 @@ ttl_key: buz
 		})
 	}
 }
+
+type mockDynamoDBCache struct {
+	dynamoDBAPIV2
+	getFn func(*dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error)
+	putFn func(*dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error)
+}
+
+func (m *mockDynamoDBCache) GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	return m.getFn(params)
+}
+
+func (m *mockDynamoDBCache) PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	return m.putFn(params)
+}
+
+func testDynamoDBCache(client dynamoDBAPIV2) *dynamodbCache {
+	ttlKey := "ttl"
+	boff := backoff.NewExponentialBackOff()
+	boff.InitialInterval = time.Millisecond
+	boff.MaxElapsedTime = time.Second
+	return newDynamodbCache(client, "foo", "id", "data", false, &ttlKey, nil, boff)
+}
+
+func testDynamoDBItem(key, value string, expiry time.Time) map[string]types.AttributeValue {
+	item := map[string]types.AttributeValue{
+		"id":   &types.AttributeValueMemberS{Value: key},
+		"data": &types.AttributeValueMemberB{Value: []byte(value)},
+	}
+	if !expiry.IsZero() {
+		item["ttl"] = &types.AttributeValueMemberN{Value: strconv.FormatInt(expiry.Unix(), 10)}
+	}
+	return item
+}
+
+func TestDynamoDBCacheGetExpired(t *testing.T) {
+	items := map[string]map[string]types.AttributeValue{
+		"a": testDynamoDBItem("a", "foo", time.Now().Add(time.Hour)),
+		"b": testDynamoDBItem("b", "bar", time.Now().Add(-time.Hour)),
+		"c": testDynamoDBItem("c", "baz", time.Time{}),
+	}
+	c := testDynamoDBCache(&mockDynamoDBCache{
+		getFn: func(input *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+			key := input.Key["id"].(*types.AttributeValueMemberS).Value
+			return &dynamodb.GetItemOutput{Item: items[key]}, nil
+		},
+	})
+
+	v, err := c.Get(context.Background(), "a")
+	require.NoError(t, err)
+	assert.Equal(t, "foo", string(v))
+
+	_, err = c.Get(context.Background(), "b")
+	assert.Equal(t, service.ErrKeyNotFound, err)
+
+	v, err = c.Get(context.Background(), "c")
+	require.NoError(t, err)
+	assert.Equal(t, "baz", string(v))
+
+	_, err = c.Get(context.Background(), "d")
+	assert.Equal(t, service.ErrKeyNotFound, err)
+}
+
+func TestDynamoDBCacheAddCondition(t *testing.T) {
+	var input *dynamodb.PutItemInput
+	c := testDynamoDBCache(&mockDynamoDBCache{
+		putFn: func(i *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+			input = i
+			return nil, &types.ConditionalCheckFailedException{}
+		},
+	})
+
+	before := time.Now().Unix()
+	err := c.Add(context.Background(), "a", []byte("foo"), nil)
+	assert.Equal(t, service.ErrKeyAlreadyExists, err)
+
+	require.NotNil(t, input)
+	assert.Equal(t, "(attribute_not_exists (#0)) OR (#1 <= :0)", *input.ConditionExpression)
+	assert.Equal(t, map[string]string{"#0": "id", "#1": "ttl"}, input.ExpressionAttributeNames)
+
+	now, err := strconv.ParseInt(input.ExpressionAttributeValues[":0"].(*types.AttributeValueMemberN).Value, 10, 64)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, now, before)
+}
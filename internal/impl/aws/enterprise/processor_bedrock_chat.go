@@ -10,6 +10,7 @@ package enterprise
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"unicode/utf8"
@@ -31,6 +32,13 @@ const (
 	bedcpFieldStop         = "stop"
 	bedcpFieldTemp         = "temperature"
 	bedcpFieldTopP         = "top_p"
+
+	bedcpFieldGuardrail        = "guardrail"
+	bedcpFieldGuardrailID      = "id"
+	bedcpFieldGuardrailVersion = "version"
+	bedcpFieldGuardrailTrace   = "trace"
+
+	bedcpFieldFallbackRegions = "fallback_regions"
 )
 
 func init() {
@@ -43,14 +51,26 @@ func init() {
 func newBedrockChatConfigSpec() *service.ConfigSpec {
 	return service.NewConfigSpec().
 		Summary("Generates responses to messages in a chat conversation, using the AWS Bedrock API.").
-		Description(`This processor sends prompts to your chosen large language model (LLM) and generates text from the responses, using the AWS Bedrock API.
+		Description(`This processor sends prompts to your chosen large language model (LLM) and generates text from the responses, using the AWS Bedrock Converse API.
+
+The ` + "`model`" + ` can either be a model ID or the ID or ARN of an inference profile, where cross-region inference profiles such as ` + "`us.anthropic.claude-3-5-sonnet-20240620-v1:0`" + ` route requests across the regions of the profile. Alternatively, requests that fail due to throttling or the unavailability of the service within the region are sent again to each of the ` + "`fallback_regions`" + ` in order.
+
+== Metadata
+
+This processor adds the following metadata fields to each message:
+
+- stop_reason
+- guardrail_trace
+
+The field ` + "`guardrail_trace`" + ` contains the assessments of the guardrail as JSON, and is only added when tracing of the guardrail is enabled. When the guardrail intervenes the ` + "`stop_reason`" + ` is ` + "`guardrail_intervened`" + ` and the response is the blocked message configured for the guardrail.
+
 For more information, see the https://docs.aws.amazon.com/bedrock/latest/userguide[AWS Bedrock documentation^].`).
 		Categories("AI").
 		Version("4.34.0").
 		Fields(config.SessionFields()...).
 		Field(service.NewStringField(bedcpFieldModel).
-			Examples("amazon.titan-text-express-v1", "anthropic.claude-3-5-sonnet-20240620-v1:0", "cohere.command-text-v14", "meta.llama3-1-70b-instruct-v1:0", "mistral.mistral-large-2402-v1:0").
-			Description("The model ID to use. For a full list see the https://docs.aws.amazon.com/bedrock/latest/userguide/model-ids.html[AWS Bedrock documentation^].")).
+			Examples("amazon.titan-text-express-v1", "anthropic.claude-3-5-sonnet-20240620-v1:0", "cohere.command-text-v14", "meta.llama3-1-70b-instruct-v1:0", "mistral.mistral-large-2402-v1:0", "us.anthropic.claude-3-5-sonnet-20240620-v1:0").
+			Description("The model ID, or the ID or ARN of an inference profile, to use. For a full list see the https://docs.aws.amazon.com/bedrock/latest/userguide/model-ids.html[AWS Bedrock documentation^].")).
 		Field(service.NewStringField(bedcpFieldUserPrompt).
 			Description("The prompt you want to generate a response for. By default, the processor submits the entire payload as a string.").
 			Optional()).
@@ -73,7 +93,28 @@ For more information, see the https://docs.aws.amazon.com/bedrock/latest/usergui
 			Optional().
 			Advanced().
 			Description("The percentage of most-likely candidates that the model considers for the next token. For example, if you choose a value of 0.8, the model selects from the top 80% of the probability distribution of tokens that could be next in the sequence. ").
-			LintRule(`root = if this < 0 || this > 1 { ["field must be between 0.0-1.0"] }`))
+			LintRule(`root = if this < 0 || this > 1 { ["field must be between 0.0-1.0"] }`)).
+		Field(service.NewObjectField(bedcpFieldGuardrail,
+			service.NewStringField(bedcpFieldGuardrailID).
+				Description("The identifier or ARN of the guardrail. The guardrail is only applied when an identifier is specified.").
+				Default(""),
+			service.NewStringField(bedcpFieldGuardrailVersion).
+				Description("The version of the guardrail.").
+				Example("1").
+				Default("DRAFT"),
+			service.NewBoolField(bedcpFieldGuardrailTrace).
+				Description("Whether to trace the assessments of the guardrail, which are added to the metadata of messages.").
+				Default(false),
+		).
+			Description("A guardrail to apply to prompts and responses.").
+			Version("4.45.0").
+			Advanced()).
+		Field(service.NewStringListField(bedcpFieldFallbackRegions).
+			Description("Regions to send requests to, in order, when a request fails due to throttling or the unavailability of the service within the region of the processor.").
+			Example([]string{"us-west-2", "eu-west-1"}).
+			Default([]any{}).
+			Version("4.45.0").
+			Advanced())
 }
 
 func newBedrockChatProcessor(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
@@ -85,14 +126,51 @@ func newBedrockChatProcessor(conf *service.ParsedConfig, mgr *service.Resources)
 	if err != nil {
 		return nil, err
 	}
-	client := bedrockruntime.NewFromConfig(aconf)
 	model, err := conf.FieldString(bedcpFieldModel)
 	if err != nil {
 		return nil, err
 	}
 	p := &bedrockChatProcessor{
-		client: client,
-		model:  model,
+		clients: []bedrockRegionClient{
+			{region: aconf.Region, client: bedrockruntime.NewFromConfig(aconf)},
+		},
+		model: model,
+		log:   mgr.Logger(),
+	}
+	fallbackRegions, err := conf.FieldStringList(bedcpFieldFallbackRegions)
+	if err != nil {
+		return nil, err
+	}
+	for _, region := range fallbackRegions {
+		p.clients = append(p.clients, bedrockRegionClient{
+			region: region,
+			client: bedrockruntime.NewFromConfig(aconf, func(o *bedrockruntime.Options) {
+				o.Region = region
+			}),
+		})
+	}
+	gConf := conf.Namespace(bedcpFieldGuardrail)
+	guardrailID, err := gConf.FieldString(bedcpFieldGuardrailID)
+	if err != nil {
+		return nil, err
+	}
+	if guardrailID != "" {
+		version, err := gConf.FieldString(bedcpFieldGuardrailVersion)
+		if err != nil {
+			return nil, err
+		}
+		trace, err := gConf.FieldBool(bedcpFieldGuardrailTrace)
+		if err != nil {
+			return nil, err
+		}
+		p.guardrail = &bedrocktypes.GuardrailConfiguration{
+			GuardrailIdentifier: &guardrailID,
+			GuardrailVersion:    &version,
+			Trace:               bedrocktypes.GuardrailTraceDisabled,
+		}
+		if trace {
+			p.guardrail.Trace = bedrocktypes.GuardrailTraceEnabled
+		}
 	}
 	if conf.Contains(bedcpFieldUserPrompt) {
 		pf, err := conf.FieldInterpolatedString(bedcpFieldUserPrompt)
@@ -142,9 +220,19 @@ func newBedrockChatProcessor(conf *service.ParsedConfig, mgr *service.Resources)
 	return p, nil
 }
 
+type bedrockChatAPI interface {
+	Converse(context.Context, *bedrockruntime.ConverseInput, ...func(*bedrockruntime.Options)) (*bedrockruntime.ConverseOutput, error)
+}
+
+type bedrockRegionClient struct {
+	region string
+	client bedrockChatAPI
+}
+
 type bedrockChatProcessor struct {
-	client *bedrockruntime.Client
-	model  string
+	clients []bedrockRegionClient
+	model   string
+	log     *service.Logger
 
 	userPrompt   *service.InterpolatedString
 	systemPrompt *service.InterpolatedString
@@ -152,6 +240,7 @@ type bedrockChatProcessor struct {
 	stop         []string
 	temp         *float32
 	topP         *float32
+	guardrail    *bedrocktypes.GuardrailConfiguration
 }
 
 func (b *bedrockChatProcessor) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
@@ -177,6 +266,7 @@ func (b *bedrockChatProcessor) Process(ctx context.Context, msg *service.Message
 			Temperature:   b.temp,
 			TopP:          b.topP,
 		},
+		GuardrailConfig: b.guardrail,
 	}
 	if b.systemPrompt != nil {
 		prompt, err := b.systemPrompt.TryString(msg)
@@ -187,7 +277,7 @@ func (b *bedrockChatProcessor) Process(ctx context.Context, msg *service.Message
 			&bedrocktypes.SystemContentBlockMemberText{Value: prompt},
 		}
 	}
-	resp, err := b.client.Converse(ctx, input)
+	resp, err := b.converse(ctx, input)
 	if err != nil {
 		return nil, err
 	}
@@ -206,9 +296,50 @@ func (b *bedrockChatProcessor) Process(ctx context.Context, msg *service.Message
 	default:
 		return nil, fmt.Errorf("unsupported response content type: %T", content[0])
 	}
+	out.MetaSetMut("stop_reason", string(resp.StopReason))
+	if resp.Trace != nil && resp.Trace.Guardrail != nil {
+		trace, err := json.Marshal(resp.Trace.Guardrail)
+		if err != nil {
+			return nil, fmt.Errorf("unable to marshal guardrail trace: %w", err)
+		}
+		out.MetaSetMut("guardrail_trace", string(trace))
+	}
 	return service.MessageBatch{out}, nil
 }
 
+// converse sends the request to the region of the processor, followed by each
+// fallback region in order for as long as requests fail due to the region.
+func (b *bedrockChatProcessor) converse(ctx context.Context, input *bedrockruntime.ConverseInput) (*bedrockruntime.ConverseOutput, error) {
+	var err error
+	for i, c := range b.clients {
+		var resp *bedrockruntime.ConverseOutput
+		if resp, err = c.client.Converse(ctx, input); err == nil {
+			return resp, nil
+		}
+		if !isRegionalFailure(err) || ctx.Err() != nil {
+			return nil, err
+		}
+		if i < len(b.clients)-1 {
+			b.log.Warnf("Request to region %v failed, sending to region %v: %v", c.region, b.clients[i+1].region, err)
+		}
+	}
+	return nil, err
+}
+
+// isRegionalFailure returns whether an error is caused by the capacity or
+// availability of the service within a region, in which case the request may
+// succeed within another region.
+func isRegionalFailure(err error) bool {
+	var throttling *bedrocktypes.ThrottlingException
+	var unavailable *bedrocktypes.ServiceUnavailableException
+	var notReady *bedrocktypes.ModelNotReadyException
+	var internal *bedrocktypes.InternalServerException
+	return errors.As(err, &throttling) ||
+		errors.As(err, &unavailable) ||
+		errors.As(err, &notReady) ||
+		errors.As(err, &internal)
+}
+
 func (b *bedrockChatProcessor) computePrompt(msg *service.Message) (string, error) {
 	if b.userPrompt != nil {
 		return b.userPrompt.TryString(msg)
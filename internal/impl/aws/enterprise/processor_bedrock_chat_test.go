@@ -0,0 +1,258 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed as a Redpanda Enterprise file under the Redpanda Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+// https://github.com/redpanda-data/connect/blob/main/licenses/rcl.md
+
+package enterprise
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	bedrocktypes "github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"github.com/redpanda-data/benthos/v4/public/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/connect/v4/internal/license"
+)
+
+type mockBedrockChat struct {
+	fn     func(*bedrockruntime.ConverseInput) (*bedrockruntime.ConverseOutput, error)
+	inputs []*bedrockruntime.ConverseInput
+}
+
+func (m *mockBedrockChat) Converse(ctx context.Context, in *bedrockruntime.ConverseInput, opts ...func(*bedrockruntime.Options)) (*bedrockruntime.ConverseOutput, error) {
+	m.inputs = append(m.inputs, in)
+	return m.fn(in)
+}
+
+func bedrockTextOutput(text string, stopReason bedrocktypes.StopReason) *bedrockruntime.ConverseOutput {
+	return &bedrockruntime.ConverseOutput{
+		Output: &bedrocktypes.ConverseOutputMemberMessage{
+			Value: bedrocktypes.Message{
+				Role: bedrocktypes.ConversationRoleAssistant,
+				Content: []bedrocktypes.ContentBlock{
+					&bedrocktypes.ContentBlockMemberText{Value: text},
+				},
+			},
+		},
+		StopReason: stopReason,
+	}
+}
+
+func TestBedrockChatConfig(t *testing.T) {
+	conf, err := newBedrockChatConfigSpec().ParseYAML(`
+region: us-east-1
+credentials:
+  id: foo
+  secret: bar
+model: us.anthropic.claude-3-5-sonnet-20240620-v1:0
+system_prompt: 'You are a ${! meta("role") }.'
+max_tokens: 100
+temperature: 0.5
+stop: [ END ]
+guardrail:
+  id: abc123
+  version: "2"
+  trace: true
+fallback_regions: [ us-west-2, eu-west-1 ]
+`, nil)
+	require.NoError(t, err)
+
+	mgr := service.MockResources()
+	license.InjectTestService(mgr)
+
+	proc, err := newBedrockChatProcessor(conf, mgr)
+	require.NoError(t, err)
+
+	p := proc.(*bedrockChatProcessor)
+	assert.Equal(t, "us.anthropic.claude-3-5-sonnet-20240620-v1:0", p.model)
+
+	var regions []string
+	for _, c := range p.clients {
+		regions = append(regions, c.region)
+	}
+	assert.Equal(t, []string{"us-east-1", "us-west-2", "eu-west-1"}, regions)
+
+	require.NotNil(t, p.guardrail)
+	assert.Equal(t, "abc123", *p.guardrail.GuardrailIdentifier)
+	assert.Equal(t, "2", *p.guardrail.GuardrailVersion)
+	assert.Equal(t, bedrocktypes.GuardrailTraceEnabled, p.guardrail.Trace)
+
+	require.NotNil(t, p.maxTokens)
+	assert.Equal(t, int32(100), *p.maxTokens)
+	require.NotNil(t, p.temp)
+	assert.Equal(t, float32(0.5), *p.temp)
+	assert.Equal(t, []string{"END"}, p.stop)
+	assert.Nil(t, p.topP)
+	assert.Nil(t, p.userPrompt)
+	require.NotNil(t, p.systemPrompt)
+
+	conf, err = newBedrockChatConfigSpec().ParseYAML(`
+region: us-east-1
+model: amazon.titan-text-express-v1
+`, nil)
+	require.NoError(t, err)
+
+	proc, err = newBedrockChatProcessor(conf, mgr)
+	require.NoError(t, err)
+
+	p = proc.(*bedrockChatProcessor)
+	assert.Len(t, p.clients, 1)
+	assert.Nil(t, p.guardrail)
+	assert.Nil(t, p.maxTokens)
+}
+
+func TestBedrockChatGuardrail(t *testing.T) {
+	mock := &mockBedrockChat{
+		fn: func(in *bedrockruntime.ConverseInput) (*bedrockruntime.ConverseOutput, error) {
+			out := bedrockTextOutput("Sorry, I cannot answer that.", bedrocktypes.StopReasonGuardrailIntervened)
+			out.Trace = &bedrocktypes.ConverseTrace{
+				Guardrail: &bedrocktypes.GuardrailTraceAssessment{
+					ModelOutput: []string{"blocked"},
+				},
+			}
+			return out, nil
+		},
+	}
+
+	id, version := "abc123", "DRAFT"
+	p := &bedrockChatProcessor{
+		clients: []bedrockRegionClient{{region: "us-east-1", client: mock}},
+		model:   "anthropic.claude-3-5-sonnet-20240620-v1:0",
+		log:     service.MockResources().Logger(),
+		guardrail: &bedrocktypes.GuardrailConfiguration{
+			GuardrailIdentifier: &id,
+			GuardrailVersion:    &version,
+			Trace:               bedrocktypes.GuardrailTraceEnabled,
+		},
+	}
+
+	batch, err := p.Process(context.Background(), service.NewMessage([]byte("how do I pick a lock?")))
+	require.NoError(t, err)
+	require.Len(t, batch, 1)
+
+	require.Len(t, mock.inputs, 1)
+	assert.Equal(t, p.guardrail, mock.inputs[0].GuardrailConfig)
+
+	v, err := batch[0].AsStructured()
+	require.NoError(t, err)
+	assert.Equal(t, "Sorry, I cannot answer that.", v)
+
+	stopReason, _ := batch[0].MetaGetMut("stop_reason")
+	assert.Equal(t, "guardrail_intervened", stopReason)
+
+	trace, exists := batch[0].MetaGetMut("guardrail_trace")
+	require.True(t, exists)
+	assert.Contains(t, trace, "blocked")
+}
+
+func TestBedrockChatInferenceProfile(t *testing.T) {
+	mock := &mockBedrockChat{
+		fn: func(in *bedrockruntime.ConverseInput) (*bedrockruntime.ConverseOutput, error) {
+			return bedrockTextOutput("hello", bedrocktypes.StopReasonEndTurn), nil
+		},
+	}
+
+	profileARN := "arn:aws:bedrock:us-east-1:123456789012:inference-profile/us.anthropic.claude-3-5-sonnet-20240620-v1:0"
+	p := &bedrockChatProcessor{
+		clients: []bedrockRegionClient{{region: "us-east-1", client: mock}},
+		model:   profileARN,
+		log:     service.MockResources().Logger(),
+	}
+
+	batch, err := p.Process(context.Background(), service.NewMessage([]byte("hi")))
+	require.NoError(t, err)
+	require.Len(t, batch, 1)
+
+	require.Len(t, mock.inputs, 1)
+	assert.Equal(t, profileARN, *mock.inputs[0].ModelId)
+	assert.Nil(t, mock.inputs[0].GuardrailConfig)
+
+	stopReason, _ := batch[0].MetaGetMut("stop_reason")
+	assert.Equal(t, "end_turn", stopReason)
+	_, exists := batch[0].MetaGetMut("guardrail_trace")
+	assert.False(t, exists)
+}
+
+func TestBedrockChatFallbackRegions(t *testing.T) {
+	throttled := func(*bedrockruntime.ConverseInput) (*bedrockruntime.ConverseOutput, error) {
+		return nil, &bedrocktypes.ThrottlingException{Message: aws.String("slow down")}
+	}
+	unavailable := func(*bedrockruntime.ConverseInput) (*bedrockruntime.ConverseOutput, error) {
+		return nil, &bedrocktypes.ServiceUnavailableException{Message: aws.String("unavailable")}
+	}
+	invalid := func(*bedrockruntime.ConverseInput) (*bedrockruntime.ConverseOutput, error) {
+		return nil, &bedrocktypes.ValidationException{Message: aws.String("invalid")}
+	}
+	ok := func(*bedrockruntime.ConverseInput) (*bedrockruntime.ConverseOutput, error) {
+		return bedrockTextOutput("hello", bedrocktypes.StopReasonEndTurn), nil
+	}
+
+	for _, test := range []struct {
+		name      string
+		fns       []func(*bedrockruntime.ConverseInput) (*bedrockruntime.ConverseOutput, error)
+		calls     []int
+		errTarget any
+	}{
+		{
+			name:  "primary region succeeds",
+			fns:   []func(*bedrockruntime.ConverseInput) (*bedrockruntime.ConverseOutput, error){ok, ok},
+			calls: []int{1, 0},
+		},
+		{
+			name:  "fails over to the next region",
+			fns:   []func(*bedrockruntime.ConverseInput) (*bedrockruntime.ConverseOutput, error){throttled, unavailable, ok},
+			calls: []int{1, 1, 1},
+		},
+		{
+			name:      "non regional failures are not retried",
+			fns:       []func(*bedrockruntime.ConverseInput) (*bedrockruntime.ConverseOutput, error){invalid, ok},
+			calls:     []int{1, 0},
+			errTarget: new(*bedrocktypes.ValidationException),
+		},
+		{
+			name:      "all regions fail",
+			fns:       []func(*bedrockruntime.ConverseInput) (*bedrockruntime.ConverseOutput, error){throttled, unavailable},
+			calls:     []int{1, 1},
+			errTarget: new(*bedrocktypes.ServiceUnavailableException),
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			p := &bedrockChatProcessor{
+				model: "anthropic.claude-3-5-sonnet-20240620-v1:0",
+				log:   service.MockResources().Logger(),
+			}
+			regions := []string{"us-east-1", "us-west-2", "eu-west-1"}
+			var mocks []*mockBedrockChat
+			for i, fn := range test.fns {
+				m := &mockBedrockChat{fn: fn}
+				mocks = append(mocks, m)
+				p.clients = append(p.clients, bedrockRegionClient{region: regions[i], client: m})
+			}
+
+			batch, err := p.Process(context.Background(), service.NewMessage([]byte("hi")))
+			if test.errTarget != nil {
+				require.Error(t, err)
+				assert.True(t, errors.As(err, test.errTarget), err)
+			} else {
+				require.NoError(t, err)
+				require.Len(t, batch, 1)
+				v, err := batch[0].AsStructured()
+				require.NoError(t, err)
+				assert.Equal(t, "hello", v)
+			}
+
+			for i, m := range mocks {
+				assert.Len(t, m.inputs, test.calls[i], "region %v", p.clients[i].region)
+			}
+		})
+	}
+}
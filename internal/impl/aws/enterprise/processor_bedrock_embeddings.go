@@ -12,6 +12,8 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"strings"
 	"unicode/utf8"
 
 	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
@@ -19,6 +21,7 @@ import (
 
 	amzn "github.com/aws/aws-sdk-go-v2/aws"
 
+	"github.com/redpanda-data/connect/v4/internal/embeddings"
 	"github.com/redpanda-data/connect/v4/internal/impl/aws"
 	"github.com/redpanda-data/connect/v4/internal/impl/aws/config"
 	"github.com/redpanda-data/connect/v4/internal/license"
@@ -30,7 +33,7 @@ const (
 )
 
 func init() {
-	err := service.RegisterProcessor("aws_bedrock_embeddings", newBedrockEmbeddingsConfigSpec(), newBedrockEmbeddingsProcessor)
+	err := service.RegisterBatchProcessor("aws_bedrock_embeddings", newBedrockEmbeddingsConfigSpec(), newBedrockEmbeddingsProcessor)
 	if err != nil {
 		panic(err)
 	}
@@ -40,6 +43,9 @@ func newBedrockEmbeddingsConfigSpec() *service.ConfigSpec {
 	return service.NewConfigSpec().
 		Summary("Computes vector embeddings on text, using the AWS Bedrock API.").
 		Description(`This processor sends text to your chosen large language model (LLM) and computes vector embeddings, using the AWS Bedrock API.
+
+The texts of messages within a batch are combined into requests within the limits of the `+"`batching`"+` field, and these requests are sent concurrently. Cohere models accept multiple texts within a request, whereas each text is sent within its own request for other models. Messages that cannot be embedded are flagged as failed and can be handled with xref:configuration:error_handling.adoc[error handling patterns].
+
 For more information, see the https://docs.aws.amazon.com/bedrock/latest/userguide[AWS Bedrock documentation^].`).
 		Categories("AI").
 		Version("4.37.0").
@@ -50,6 +56,7 @@ For more information, see the https://docs.aws.amazon.com/bedrock/latest/usergui
 		Field(service.NewStringField(bedepFieldText).
 			Description("The prompt you want to generate a response for. By default, the processor submits the entire payload as a string.").
 			Optional()).
+		Fields(embeddings.BatchingFields(96, 0)...).
 		Example(
 			"Store embedding vectors in Clickhouse",
 			"Compute embeddings for some generated data and store it within https://clickhouse.com/[Clickhouse^]",
@@ -78,7 +85,7 @@ output:
 `)
 }
 
-func newBedrockEmbeddingsProcessor(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+func newBedrockEmbeddingsProcessor(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchProcessor, error) {
 	if err := license.CheckRunningEnterprise(mgr); err != nil {
 		return nil, err
 	}
@@ -92,9 +99,17 @@ func newBedrockEmbeddingsProcessor(conf *service.ParsedConfig, mgr *service.Reso
 	if err != nil {
 		return nil, err
 	}
+	batcher, err := embeddings.BatcherFromParsed(conf)
+	if err != nil {
+		return nil, err
+	}
 	p := &bedrockEmbeddingsProcessor{
-		client: client,
-		model:  model,
+		client:  client,
+		model:   model,
+		batcher: batcher,
+	}
+	if !bedrockAcceptsMultipleTexts(model) {
+		p.batcher = batcher.WithMaxInputs(1)
 	}
 	if conf.Contains(bedepFieldText) {
 		p.text, err = conf.FieldInterpolatedString(bedepFieldText)
@@ -106,12 +121,19 @@ func newBedrockEmbeddingsProcessor(conf *service.ParsedConfig, mgr *service.Reso
 }
 
 type bedrockEmbeddingsProcessor struct {
-	client *bedrockruntime.Client
-	model  string
+	client  *bedrockruntime.Client
+	model   string
+	batcher *embeddings.Batcher
 
 	text *service.InterpolatedString
 }
 
+// bedrockAcceptsMultipleTexts returns whether the request body of a model
+// accepts multiple texts, which is the case for Cohere models only.
+func bedrockAcceptsMultipleTexts(model string) bool {
+	return strings.Contains(model, "cohere.embed")
+}
+
 type embeddingsRequest struct {
 	InputText string `json:"inputText"`
 }
@@ -121,12 +143,52 @@ type embeddingsResponse struct {
 	InputTextTokenCount int       `json:"inputTextTokenCount"`
 }
 
-func (b *bedrockEmbeddingsProcessor) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
-	prompt, err := b.computeText(msg)
-	if err != nil {
-		return nil, err
+type cohereEmbeddingsRequest struct {
+	Texts     []string `json:"texts"`
+	InputType string   `json:"input_type"`
+}
+
+type cohereEmbeddingsResponse struct {
+	Embeddings [][]float64 `json:"embeddings"`
+}
+
+func (b *bedrockEmbeddingsProcessor) ProcessBatch(ctx context.Context, batch service.MessageBatch) ([]service.MessageBatch, error) {
+	batch = batch.Copy()
+
+	var texts []string
+	var indexes []int
+	for i, msg := range batch {
+		text, err := b.computeText(batch, i)
+		if err != nil {
+			msg.SetError(err)
+			continue
+		}
+		texts = append(texts, text)
+		indexes = append(indexes, i)
+	}
+
+	vectors, errs := embeddings.Embed(ctx, b.batcher, texts, b.embed)
+	for j, i := range indexes {
+		if errs[j] != nil {
+			batch[i].SetError(errs[j])
+			continue
+		}
+		vec := make([]any, len(vectors[j]))
+		for k, e := range vectors[j] {
+			vec[k] = e
+		}
+		batch[i].SetStructured(vec)
+	}
+	return []service.MessageBatch{batch}, nil
+}
+
+func (b *bedrockEmbeddingsProcessor) embed(ctx context.Context, texts []string) ([][]float64, error) {
+	var payload any = embeddingsRequest{texts[0]}
+	if bedrockAcceptsMultipleTexts(b.model) {
+		payload = cohereEmbeddingsRequest{Texts: texts, InputType: "search_document"}
+	} else if len(texts) != 1 {
+		return nil, fmt.Errorf("model %v only accepts a single text within a request", b.model)
 	}
-	payload := embeddingsRequest{prompt}
 	payloadBytes, err := json.Marshal(payload)
 	if err != nil {
 		return nil, err
@@ -139,6 +201,18 @@ func (b *bedrockEmbeddingsProcessor) Process(ctx context.Context, msg *service.M
 	if err != nil {
 		return nil, err
 	}
+
+	if bedrockAcceptsMultipleTexts(b.model) {
+		var resp cohereEmbeddingsResponse
+		if err = json.Unmarshal(output.Body, &resp); err != nil {
+			return nil, err
+		}
+		if resp.Embeddings == nil {
+			return nil, errors.New("response did not contain any embeddings")
+		}
+		return resp.Embeddings, nil
+	}
+
 	var resp embeddingsResponse
 	if err = json.Unmarshal(output.Body, &resp); err != nil {
 		return nil, err
@@ -146,20 +220,14 @@ func (b *bedrockEmbeddingsProcessor) Process(ctx context.Context, msg *service.M
 	if resp.Embedding == nil {
 		return nil, errors.New("response did not contain any embeddings")
 	}
-	vec := make([]any, len(resp.Embedding))
-	for i, e := range resp.Embedding {
-		vec[i] = e
-	}
-	out := msg.Copy()
-	out.SetStructured(vec)
-	return service.MessageBatch{out}, nil
+	return [][]float64{resp.Embedding}, nil
 }
 
-func (b *bedrockEmbeddingsProcessor) computeText(msg *service.Message) (string, error) {
+func (b *bedrockEmbeddingsProcessor) computeText(batch service.MessageBatch, i int) (string, error) {
 	if b.text != nil {
-		return b.text.TryString(msg)
+		return batch.TryInterpolatedString(i, b.text)
 	}
-	buf, err := msg.AsBytes()
+	buf, err := batch[i].AsBytes()
 	if err != nil {
 		return "", err
 	}
@@ -44,6 +44,7 @@ const (
 
 	// Kinesis Input Fields
 	kiFieldDynamoDB        = "dynamodb"
+	kiFieldEnhancedFanOut  = "enhanced_fan_out"
 	kiFieldStreams         = "streams"
 	kiFieldCheckpointLimit = "checkpoint_limit"
 	kiFieldCommitPeriod    = "commit_period"
@@ -56,6 +57,7 @@ const (
 type kiConfig struct {
 	Streams         []string
 	DynamoDB        kiddbConfig
+	EnhancedFanOut  kiefoConfig
 	CheckpointLimit int
 	CommitPeriod    string
 	LeasePeriod     string
@@ -72,6 +74,9 @@ func kinesisInputConfigFromParsed(pConf *service.ParsedConfig) (conf kiConfig, e
 			return
 		}
 	}
+	if conf.EnhancedFanOut, err = kinesisInputEFOConfigFromParsed(pConf.Namespace(kiFieldEnhancedFanOut)); err != nil {
+		return
+	}
 	if conf.CheckpointLimit, err = pConf.FieldInt(kiFieldCheckpointLimit); err != nil {
 		return
 	}
@@ -109,6 +114,12 @@ By default messages of a shard can be processed in parallel, up to a limit deter
 
 It's possible to configure Redpanda Connect to create the DynamoDB table required for coordination if it does not already exist. However, if you wish to create this yourself (recommended) then create a table with a string HASH key `+"`StreamID`"+` and a string RANGE key `+"`ShardID`"+`.
 
+== Enhanced fan out
+
+By default shards are consumed by polling, where the read throughput of each shard is shared by all consumers of the stream. When `+"`enhanced_fan_out.enabled`"+` is set the input instead registers a https://docs.aws.amazon.com/streams/latest/dev/enhanced-consumers.html[stream consumer^] with the name `+"`enhanced_fan_out.consumer_name`"+` and subscribes to shards with it, which gives it a dedicated read throughput and delivers records with lower latency.
+
+Shards are balanced and checkpointed with the same DynamoDB table in either mode, and therefore it's possible to switch an existing pipeline to enhanced fan out without losing its position.
+
 == Batching
 
 Use the `+"`batching`"+` fields to configure an optional xref:configuration:batching.adoc#batch-policy[batching policy]. Each stream shard will be batched separately in order to ensure that acknowledgements aren't contaminated.
@@ -137,6 +148,10 @@ Use the `+"`batching`"+` fields to configure an optional xref:configuration:batc
 				Advanced(),
 		).
 			Description("Determines the table used for storing and accessing the latest consumed sequence for shards, and for coordinating balanced consumers of streams."),
+		service.NewObjectField(kiFieldEnhancedFanOut, kinesisInputEFOFields()...).
+			Description("Configures consuming shards with enhanced fan out subscriptions rather than by polling.").
+			Version("4.45.0").
+			Advanced(),
 		service.NewIntField(kiFieldCheckpointLimit).
 			Description("The maximum gap between the in flight sequence versus the latest acknowledged sequence at a given time. Increasing this limit enables parallel processing and batching at the output level to work on individual shards. Any given sequence will not be committed unless all messages under that offset are delivered in order to preserve at least once delivery guarantees.").
 			Default(1024),
@@ -188,6 +203,7 @@ type streamInfo struct {
 	explicitShards []string
 	id             string // Either a name or arn, extracted from config and used for balancing shards
 	arn            string
+	consumerARN    string // Set when consuming with enhanced fan out
 }
 
 type kinesisReader struct {
@@ -452,7 +468,10 @@ func (k *kinesisReader) runConsumer(wg *sync.WaitGroup, info streamInfo, shardID
 	// Stores consumed records that have yet to be added to the batcher.
 	var pending []types.Record
 	var iter string
-	if iter, initErr = k.getIter(info, shardID, startingSequence); initErr != nil {
+	var efo *kinesisEFOSubscriber
+	if info.consumerARN != "" {
+		efo = k.newKinesisEFOSubscriber(info, shardID, startingSequence)
+	} else if iter, initErr = k.getIter(info, shardID, startingSequence); initErr != nil {
 		return initErr
 	}
 
@@ -481,6 +500,9 @@ func (k *kinesisReader) runConsumer(wg *sync.WaitGroup, info streamInfo, shardID
 	go func() {
 		defer func() {
 			commitCtxClose()
+			if efo != nil {
+				efo.Close()
+			}
 			recordBatcher.Close(context.Background(), state == awsKinesisConsumerFinished)
 			boff.Reset()
 			k.boffPool.Put(boff)
@@ -521,7 +543,22 @@ func (k *kinesisReader) runConsumer(wg *sync.WaitGroup, info streamInfo, shardID
 
 		for {
 			var err error
-			if state == awsKinesisConsumerConsuming && len(pending) == 0 && nextPullChan == unblockedChan {
+			if state == awsKinesisConsumerConsuming && len(pending) == 0 && nextPullChan == unblockedChan && efo != nil {
+				if pending, err = efo.Pull(k.ctx); err != nil {
+					if !awsErrIsTimeout(err) {
+						nextPullChan = time.After(boff.NextBackOff())
+						k.log.Errorf("Failed to receive Kinesis records from subscription: %v\n", err)
+					}
+				} else if len(pending) > 0 {
+					// Pulling from a subscription already waits for events,
+					// and therefore empty pulls are not backed off.
+					boff.Reset()
+					nextPullChan = blockedChan
+				}
+				if efo.Finished() {
+					state = awsKinesisConsumerFinished
+				}
+			} else if state == awsKinesisConsumerConsuming && len(pending) == 0 && nextPullChan == unblockedChan {
 				if pending, iter, err = k.getRecords(info, shardID, iter); err != nil {
 					if !awsErrIsTimeout(err) {
 						nextPullChan = time.After(boff.NextBackOff())
@@ -871,6 +908,12 @@ func (k *kinesisReader) Connect(ctx context.Context) error {
 		return err
 	}
 
+	if k.conf.EnhancedFanOut.Enabled {
+		if err = k.registerStreamConsumers(ctx); err != nil {
+			return err
+		}
+	}
+
 	if len(k.streams[0].explicitShards) > 0 {
 		go k.runExplicitShards()
 	} else {
@@ -0,0 +1,256 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/kinesis"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis/types"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	// Kinesis Input Enhanced Fan Out Fields
+	kiefoFieldEnabled      = "enabled"
+	kiefoFieldConsumerName = "consumer_name"
+)
+
+type kiefoConfig struct {
+	Enabled      bool
+	ConsumerName string
+}
+
+func kinesisInputEFOConfigFromParsed(pConf *service.ParsedConfig) (conf kiefoConfig, err error) {
+	if conf.Enabled, err = pConf.FieldBool(kiefoFieldEnabled); err != nil {
+		return
+	}
+	if conf.ConsumerName, err = pConf.FieldString(kiefoFieldConsumerName); err != nil {
+		return
+	}
+	if conf.Enabled && conf.ConsumerName == "" {
+		err = errors.New("a consumer_name must be specified when enhanced fan out is enabled")
+	}
+	return
+}
+
+func kinesisInputEFOFields() []*service.ConfigField {
+	return []*service.ConfigField{
+		service.NewBoolField(kiefoFieldEnabled).
+			Description("Whether to consume shards with enhanced fan out.").
+			Default(false),
+		service.NewStringField(kiefoFieldConsumerName).
+			Description("The name of the stream consumer to register and subscribe with, which must be unique for each distinct pipeline consuming a stream. The consumer is registered automatically when it does not already exist.").
+			Example("my-pipeline").
+			Default(""),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// registerStreamConsumers ensures that an enhanced fan out consumer exists and
+// is active for each stream, registering it when it does not exist.
+func (k *kinesisReader) registerStreamConsumers(ctx context.Context) error {
+	for _, info := range k.streams {
+		arn, err := k.registerStreamConsumer(ctx, info.arn)
+		if err != nil {
+			return fmt.Errorf("failed to register consumer for stream '%v': %w", info.id, err)
+		}
+		info.consumerARN = arn
+	}
+	return nil
+}
+
+func (k *kinesisReader) registerStreamConsumer(ctx context.Context, streamARN string) (string, error) {
+	consumerName := k.conf.EnhancedFanOut.ConsumerName
+
+	var consumer *types.ConsumerDescription
+	desc, err := k.svc.DescribeStreamConsumer(ctx, &kinesis.DescribeStreamConsumerInput{
+		StreamARN:    &streamARN,
+		ConsumerName: &consumerName,
+	})
+	if err == nil {
+		consumer = desc.ConsumerDescription
+	} else {
+		var rnfErr *types.ResourceNotFoundException
+		if !errors.As(err, &rnfErr) {
+			return "", err
+		}
+
+		k.log.Infof("Registering Kinesis stream consumer '%v'", consumerName)
+		res, err := k.svc.RegisterStreamConsumer(ctx, &kinesis.RegisterStreamConsumerInput{
+			StreamARN:    &streamARN,
+			ConsumerName: &consumerName,
+		})
+		if err != nil {
+			return "", err
+		}
+		consumer = &types.ConsumerDescription{
+			ConsumerARN:    res.Consumer.ConsumerARN,
+			ConsumerStatus: res.Consumer.ConsumerStatus,
+		}
+	}
+
+	for consumer.ConsumerStatus != types.ConsumerStatusActive {
+		if consumer.ConsumerStatus == types.ConsumerStatusDeleting {
+			return "", fmt.Errorf("consumer '%v' is being deleted", consumerName)
+		}
+		select {
+		case <-time.After(time.Second):
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+		if desc, err = k.svc.DescribeStreamConsumer(ctx, &kinesis.DescribeStreamConsumerInput{
+			ConsumerARN: consumer.ConsumerARN,
+		}); err != nil {
+			return "", err
+		}
+		consumer = desc.ConsumerDescription
+	}
+	return *consumer.ConsumerARN, nil
+}
+
+//------------------------------------------------------------------------------
+
+// The maximum period of time to wait for an event from a shard subscription
+// before yielding back to the consumer loop.
+var awsKinesisEFOPollWait = time.Millisecond * 100
+
+// kinesisEFOEventStream is the event stream of a shard subscription.
+type kinesisEFOEventStream interface {
+	Events() <-chan types.SubscribeToShardEventStream
+	Close() error
+	Err() error
+}
+
+// kinesisEFOSubscriber consumes records of a shard through an enhanced fan out
+// subscription, renewing the subscription whenever it expires (every five
+// minutes) or fails.
+type kinesisEFOSubscriber struct {
+	subscribeFn     func(context.Context, *kinesis.SubscribeToShardInput) (kinesisEFOEventStream, error)
+	consumerARN     string
+	shardID         string
+	startFromOldest bool
+
+	// The continuation sequence of the latest event received, subscriptions
+	// are renewed after this sequence.
+	sequence string
+	finished bool
+
+	stream      kinesisEFOEventStream
+	streamClose context.CancelFunc
+}
+
+func (k *kinesisReader) newKinesisEFOSubscriber(info streamInfo, shardID, startingSequence string) *kinesisEFOSubscriber {
+	svc := k.svc
+	return &kinesisEFOSubscriber{
+		subscribeFn: func(ctx context.Context, input *kinesis.SubscribeToShardInput) (kinesisEFOEventStream, error) {
+			res, err := svc.SubscribeToShard(ctx, input)
+			if err != nil {
+				return nil, err
+			}
+			return res.GetStream(), nil
+		},
+		consumerARN:     info.consumerARN,
+		shardID:         shardID,
+		startFromOldest: k.conf.StartFromOldest,
+		sequence:        startingSequence,
+	}
+}
+
+func (s *kinesisEFOSubscriber) subscribe(ctx context.Context) error {
+	pos := &types.StartingPosition{Type: types.ShardIteratorTypeTrimHorizon}
+	if !s.startFromOldest {
+		pos.Type = types.ShardIteratorTypeLatest
+	}
+	if s.sequence != "" {
+		sequence := s.sequence
+		pos.Type = types.ShardIteratorTypeAfterSequenceNumber
+		pos.SequenceNumber = &sequence
+	}
+
+	streamCtx, streamClose := context.WithCancel(ctx)
+	stream, err := s.subscribeFn(streamCtx, &kinesis.SubscribeToShardInput{
+		ConsumerARN:      &s.consumerARN,
+		ShardId:          &s.shardID,
+		StartingPosition: pos,
+	})
+	if err != nil {
+		streamClose()
+		return err
+	}
+	s.stream, s.streamClose = stream, streamClose
+	return nil
+}
+
+// Finished returns true once all records of a closed shard have been received.
+func (s *kinesisEFOSubscriber) Finished() bool {
+	return s.finished
+}
+
+// Pull returns any records received from the subscription within a short
+// period, and returns an empty slice when none were received.
+func (s *kinesisEFOSubscriber) Pull(ctx context.Context) ([]types.Record, error) {
+	if s.stream == nil {
+		if err := s.subscribe(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	select {
+	case event, open := <-s.stream.Events():
+		if !open {
+			err := s.stream.Err()
+			s.Close()
+			return nil, err
+		}
+		shardEvent, ok := event.(*types.SubscribeToShardEventStreamMemberSubscribeToShardEvent)
+		if !ok {
+			return nil, nil
+		}
+		// Events are sent even when a shard is idle, and the continuation
+		// sequence of each is tracked in order that a renewed subscription
+		// resumes from the position of the last event rather than the
+		// latest record of the shard.
+		records := shardEvent.Value.Records
+		if seq := shardEvent.Value.ContinuationSequenceNumber; seq != nil {
+			s.sequence = *seq
+		} else if len(records) > 0 && records[len(records)-1].SequenceNumber != nil {
+			s.sequence = *records[len(records)-1].SequenceNumber
+		}
+		if shardEvent.Value.ContinuationSequenceNumber == nil && len(shardEvent.Value.ChildShards) > 0 {
+			s.finished = true
+		}
+		return records, nil
+	case <-time.After(awsKinesisEFOPollWait):
+		return nil, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Close terminates the current subscription, if any.
+func (s *kinesisEFOSubscriber) Close() {
+	if s.stream == nil {
+		return
+	}
+	_ = s.stream.Close()
+	s.streamClose()
+	s.stream, s.streamClose = nil, nil
+}
@@ -15,8 +15,12 @@
 package aws
 
 import (
+	"context"
 	"testing"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -78,3 +82,116 @@ func TestStreamIDParser(t *testing.T) {
 		})
 	}
 }
+
+func TestKinesisInputEnhancedFanOutConfig(t *testing.T) {
+	pConf, err := kinesisInputSpec().ParseYAML(`
+streams: [ foo ]
+enhanced_fan_out:
+  enabled: true
+  consumer_name: bar
+`, nil)
+	require.NoError(t, err)
+
+	conf, err := kinesisInputConfigFromParsed(pConf)
+	require.NoError(t, err)
+	assert.True(t, conf.EnhancedFanOut.Enabled)
+	assert.Equal(t, "bar", conf.EnhancedFanOut.ConsumerName)
+
+	pConf, err = kinesisInputSpec().ParseYAML(`
+streams: [ foo ]
+enhanced_fan_out:
+  enabled: true
+`, nil)
+	require.NoError(t, err)
+
+	_, err = kinesisInputConfigFromParsed(pConf)
+	require.ErrorContains(t, err, "consumer_name")
+}
+
+type fakeEFOEventStream struct {
+	events chan types.SubscribeToShardEventStream
+	closed bool
+}
+
+func (f *fakeEFOEventStream) Events() <-chan types.SubscribeToShardEventStream {
+	return f.events
+}
+
+func (f *fakeEFOEventStream) Close() error {
+	f.closed = true
+	return nil
+}
+
+func (f *fakeEFOEventStream) Err() error {
+	return nil
+}
+
+func fakeEFOEvents(events ...types.SubscribeToShardEvent) *fakeEFOEventStream {
+	f := &fakeEFOEventStream{events: make(chan types.SubscribeToShardEventStream, len(events))}
+	for _, e := range events {
+		f.events <- &types.SubscribeToShardEventStreamMemberSubscribeToShardEvent{Value: e}
+	}
+	close(f.events)
+	return f
+}
+
+func TestKinesisEFOSubscriberResubscribe(t *testing.T) {
+	streams := []*fakeEFOEventStream{
+		fakeEFOEvents(
+			types.SubscribeToShardEvent{
+				Records: []types.Record{
+					{SequenceNumber: aws.String("10"), Data: []byte("foo")},
+					{SequenceNumber: aws.String("11"), Data: []byte("bar")},
+				},
+				ContinuationSequenceNumber: aws.String("11"),
+			},
+			// The shard is idle for the remainder of the subscription, yet
+			// the continuation sequence advances.
+			types.SubscribeToShardEvent{
+				ContinuationSequenceNumber: aws.String("15"),
+			},
+		),
+		fakeEFOEvents(
+			types.SubscribeToShardEvent{
+				Records: []types.Record{
+					{SequenceNumber: aws.String("16"), Data: []byte("baz")},
+				},
+				ChildShards: []types.ChildShard{{ShardId: aws.String("shard-2")}},
+			},
+		),
+	}
+
+	var inputs []*kinesis.SubscribeToShardInput
+	s := &kinesisEFOSubscriber{
+		subscribeFn: func(ctx context.Context, input *kinesis.SubscribeToShardInput) (kinesisEFOEventStream, error) {
+			require.Less(t, len(inputs), len(streams))
+			inputs = append(inputs, input)
+			return streams[len(inputs)-1], nil
+		},
+		consumerARN: "foo-consumer",
+		shardID:     "shard-1",
+	}
+
+	ctx := context.Background()
+	var data []string
+	for i := 0; i < 10 && !s.Finished(); i++ {
+		records, err := s.Pull(ctx)
+		require.NoError(t, err)
+		for _, r := range records {
+			data = append(data, string(r.Data))
+		}
+	}
+	s.Close()
+
+	assert.True(t, s.Finished())
+	assert.Equal(t, []string{"foo", "bar", "baz"}, data)
+	assert.True(t, streams[0].closed)
+
+	require.Len(t, inputs, 2)
+	assert.Equal(t, "shard-1", *inputs[0].ShardId)
+	assert.Equal(t, types.ShardIteratorTypeLatest, inputs[0].StartingPosition.Type)
+	assert.Nil(t, inputs[0].StartingPosition.SequenceNumber)
+
+	assert.Equal(t, types.ShardIteratorTypeAfterSequenceNumber, inputs[1].StartingPosition.Type)
+	assert.Equal(t, "15", *inputs[1].StartingPosition.SequenceNumber)
+}
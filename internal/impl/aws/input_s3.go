@@ -28,6 +28,7 @@ import (
 	"github.com/Jeffail/gabs/v2"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/aws/aws-sdk-go-v2/service/sqs"
 	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
 
@@ -53,6 +54,7 @@ const (
 	s3iFieldPrefix             = "prefix"
 	s3iFieldForcePathStyleURLs = "force_path_style_urls"
 	s3iFieldDeleteObjects      = "delete_objects"
+	s3iFieldValidateChecksums  = "validate_checksums"
 	s3iFieldSQS                = "sqs"
 )
 
@@ -100,6 +102,7 @@ type s3iConfig struct {
 	Prefix             string
 	ForcePathStyleURLs bool
 	DeleteObjects      bool
+	ValidateChecksums  bool
 	SQS                s3iSQSConfig
 	CodecCtor          codec.DeprecatedFallbackCodec
 }
@@ -120,6 +123,9 @@ func s3iConfigFromParsed(pConf *service.ParsedConfig) (conf s3iConfig, err error
 	if conf.DeleteObjects, err = pConf.FieldBool(s3iFieldDeleteObjects); err != nil {
 		return
 	}
+	if conf.ValidateChecksums, err = pConf.FieldBool(s3iFieldValidateChecksums); err != nil {
+		return
+	}
 	if pConf.Contains(s3iFieldSQS) {
 		if conf.SQS, err = s3iSQSConfigFromParsed(pConf.Namespace(s3iFieldSQS)); err != nil {
 			return
@@ -184,6 +190,11 @@ You can access these metadata fields using xref:configuration:interpolation.adoc
 				Description("Whether to delete downloaded objects from the bucket once they are processed.").
 				Default(false).
 				Advanced(),
+			service.NewBoolField(s3iFieldValidateChecksums).
+				Description("Whether to validate downloaded objects against the checksum stored with them, for objects that were uploaded with a checksum algorithm. Some S3-compatible object stores do not store checksums, in which case this field should be left disabled.").
+				Version("4.45.0").
+				Default(false).
+				Advanced(),
 		).
 		Fields(codec.DeprecatedCodecFields("to_the_end")...).
 		Fields(
@@ -772,10 +783,14 @@ func (a *awsS3Reader) getObjectTarget(ctx context.Context) (*s3PendingObject, er
 		}
 	}
 
-	obj, err := a.s3.GetObject(ctx, &s3.GetObjectInput{
+	getInput := &s3.GetObjectInput{
 		Bucket: aws.String(target.bucket),
 		Key:    aws.String(target.key),
-	})
+	}
+	if a.conf.ValidateChecksums {
+		getInput.ChecksumMode = s3types.ChecksumModeEnabled
+	}
+	obj, err := a.s3.GetObject(ctx, getInput)
 	if err != nil {
 		_ = target.ackFn(ctx, err)
 		return nil, err
@@ -17,16 +17,27 @@ package aws
 import (
 	"bytes"
 	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"hash"
+	"net/http"
 	"net/url"
 	"sort"
 	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
 	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
 
 	"github.com/redpanda-data/benthos/v4/public/bloblang"
 	"github.com/redpanda-data/benthos/v4/public/service"
@@ -53,9 +64,60 @@ const (
 	s3oFieldTimeout                 = "timeout"
 	s3oFieldKMSKeyID                = "kms_key_id"
 	s3oFieldServerSideEncryption    = "server_side_encryption"
+	s3oFieldSkipExisting            = "skip_existing"
+	s3oFieldObjectLockMode          = "object_lock_mode"
+	s3oFieldObjectLockRetainUntil   = "object_lock_retain_until"
+	s3oFieldObjectLockLegalHold     = "object_lock_legal_hold"
+	s3oFieldContentAddressed        = "content_addressed"
 	s3oFieldBatching                = "batching"
+
+	// Content Addressed Fields
+	s3oCAFieldEnabled       = "enabled"
+	s3oCAFieldAlgorithm     = "algorithm"
+	s3oCAFieldPrefix        = "prefix"
+	s3oCAFieldCheckExisting = "check_existing"
+	s3oCAFieldCache         = "cache"
+	s3oCAFieldLogicalID     = "logical_id"
+	s3oCAFieldManifest      = "manifest"
 )
 
+type s3oContentAddressedConfig struct {
+	Enabled       bool
+	Algorithm     string
+	Prefix        *service.InterpolatedString
+	CheckExisting bool
+	Cache         string
+	LogicalID     *service.InterpolatedString
+	Manifest      *service.OwnedOutput
+}
+
+func s3oContentAddressedConfigFromParsed(pConf *service.ParsedConfig) (conf s3oContentAddressedConfig, err error) {
+	if conf.Enabled, err = pConf.FieldBool(s3oCAFieldEnabled); err != nil || !conf.Enabled {
+		return
+	}
+	if conf.Algorithm, err = pConf.FieldString(s3oCAFieldAlgorithm); err != nil {
+		return
+	}
+	if conf.Prefix, err = pConf.FieldInterpolatedString(s3oCAFieldPrefix); err != nil {
+		return
+	}
+	if conf.CheckExisting, err = pConf.FieldBool(s3oCAFieldCheckExisting); err != nil {
+		return
+	}
+	if conf.Cache, err = pConf.FieldString(s3oCAFieldCache); err != nil {
+		return
+	}
+	if conf.LogicalID, err = pConf.FieldInterpolatedString(s3oCAFieldLogicalID); err != nil {
+		return
+	}
+	if pConf.Contains(s3oCAFieldManifest) {
+		if conf.Manifest, err = pConf.FieldOutput(s3oCAFieldManifest); err != nil {
+			return
+		}
+	}
+	return
+}
+
 type s3TagPair struct {
 	key   string
 	value *service.InterpolatedString
@@ -80,6 +142,11 @@ type s3oConfig struct {
 	KMSKeyID                string
 	ServerSideEncryption    string
 	UsePathStyle            bool
+	SkipExisting            bool
+	ObjectLockMode          string
+	ObjectLockRetainUntil   *service.InterpolatedString
+	ObjectLockLegalHold     bool
+	ContentAddressed        s3oContentAddressedConfig
 
 	aconf aws.Config
 }
@@ -149,6 +216,31 @@ func s3oConfigFromParsed(pConf *service.ParsedConfig) (conf s3oConfig, err error
 	if conf.ServerSideEncryption, err = pConf.FieldString(s3oFieldServerSideEncryption); err != nil {
 		return
 	}
+	if conf.SkipExisting, err = pConf.FieldBool(s3oFieldSkipExisting); err != nil {
+		return
+	}
+	if conf.ObjectLockMode, err = pConf.FieldString(s3oFieldObjectLockMode); err != nil {
+		return
+	}
+	if conf.ObjectLockRetainUntil, err = pConf.FieldInterpolatedString(s3oFieldObjectLockRetainUntil); err != nil {
+		return
+	}
+	if conf.ObjectLockMode != "" {
+		var retainUntilStr string
+		if retainUntilStr, err = pConf.FieldString(s3oFieldObjectLockRetainUntil); err != nil {
+			return
+		}
+		if retainUntilStr == "" {
+			err = fmt.Errorf("field %v must be set when %v is specified", s3oFieldObjectLockRetainUntil, s3oFieldObjectLockMode)
+			return
+		}
+	}
+	if conf.ObjectLockLegalHold, err = pConf.FieldBool(s3oFieldObjectLockLegalHold); err != nil {
+		return
+	}
+	if conf.ContentAddressed, err = s3oContentAddressedConfigFromParsed(pConf.Namespace(s3oFieldContentAddressed)); err != nil {
+		return
+	}
 	if conf.aconf, err = GetSession(context.TODO(), pConf); err != nil {
 		return
 	}
@@ -219,7 +311,33 @@ output:
       processors:
         - archive:
             format: json_array
-`+"```"+``+service.OutputPerformanceDocs(true, false)).
+`+"```"+`
+
+== S3-compatible object stores
+
+This output can write to object stores that implement the S3 API, such as MinIO, Cloudflare R2 and Ceph, by setting the `+"`endpoint`"+` field to the address of the store. Most of these stores, including MinIO and Ceph, expect `+"`force_path_style_urls`"+` to be enabled unless they have been configured with virtual-hosted buckets, and R2 expects the `+"`region`"+` to be set to `+"`auto`"+`.
+
+Support for the optional features of this output differs between stores and their versions, and it is worth checking the documentation of the target store before enabling the `+"`checksum_algorithm`"+`, `+"`skip_existing`"+` or object lock fields. Stores that do not support a feature will usually reject uploads that use it rather than silently ignoring it.
+
+== Conditional writes
+
+When `+"`skip_existing`"+` is enabled objects are uploaded with an `+"`If-None-Match: *`"+` header, and the store only writes the object if no object already exists at its path. Uploads rejected because an object already exists are treated as successful, which makes it possible to deduplicate writes by choosing a path that is derived from the message.
+
+== Content addressable storage
+
+When `+"`content_addressed.enabled`"+` is set the path of each object is derived from a hash of its contents rather than the `+"`path`"+` field, and therefore identical messages are always stored as the same object. Before uploading an object a HEAD request is made in order to check whether it already exists, in which case the upload is skipped. Keys that are known to exist can be stored in a `+"`content_addressed.cache`"+` resource in order to avoid repeating these requests.
+
+A manifest that maps the logical ID of each message to its content address can be produced by configuring a `+"`content_addressed.manifest`"+` output, which receives a JSON document for each object that was stored or skipped:
+
+`+"```json"+`
+{"id":"doc-123","bucket":"foo","key":"blobs/9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08","algorithm":"sha256","hash":"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08","size":4,"skipped":false}
+`+"```"+`
+
+Manifest documents are written once the objects of a batch have been uploaded, and if writing them fails the batch is attempted again, in which case the objects that were already uploaded are skipped.
+
+== Object lock
+
+The fields `+"`object_lock_mode`"+`, `+"`object_lock_retain_until`"+` and `+"`object_lock_legal_hold`"+` apply retention settings to each object, and require the bucket to have object lock enabled. Some stores require a checksum of each object when object lock is used, which can be satisfied by setting `+"`checksum_algorithm`"+`.`+service.OutputPerformanceDocs(true, false)).
 		Fields(
 			service.NewStringField(s3oFieldBucket).
 				Description("The bucket to upload messages to."),
@@ -290,6 +408,58 @@ output:
 				Description("Forces the client API to use path style URLs, which helps when connecting to custom endpoints.").
 				Advanced().
 				Default(false),
+			service.NewBoolField(s3oFieldSkipExisting).
+				Description("Only write objects that do not already exist at their path by uploading them with an `If-None-Match: *` condition, uploads that are rejected because the object exists are treated as successful.").
+				Version("4.45.0").
+				Advanced().
+				Default(false),
+			service.NewStringEnumField(s3oFieldObjectLockMode, "GOVERNANCE", "COMPLIANCE").
+				Description("An optional object lock retention mode to apply to each object, which requires `object_lock_retain_until` to be set.").
+				Version("4.45.0").
+				Advanced().
+				Default(""),
+			service.NewInterpolatedStringField(s3oFieldObjectLockRetainUntil).
+				Description("The date and time until which each object is retained under `object_lock_mode`, formatted as an RFC 3339 timestamp.").
+				Example(`${! now().ts_add_iso8601("P30D") }`).
+				Example("2030-01-01T00:00:00Z").
+				Version("4.45.0").
+				Advanced().
+				Default(""),
+			service.NewBoolField(s3oFieldObjectLockLegalHold).
+				Description("Whether to place a legal hold on each object.").
+				Version("4.45.0").
+				Advanced().
+				Default(false),
+			service.NewObjectField(s3oFieldContentAddressed,
+				service.NewBoolField(s3oCAFieldEnabled).
+					Description("Whether to derive the path of each object from a hash of its contents, in which case the `path` field is ignored.").
+					Default(false),
+				service.NewStringEnumField(s3oCAFieldAlgorithm, "sha256", "sha512", "sha1").
+					Description("The hashing algorithm used to derive content addresses.").
+					Default("sha256"),
+				service.NewInterpolatedStringField(s3oCAFieldPrefix).
+					Description("A prefix to prepend to the hex encoded hash of each object in order to form its path.").
+					Example("blobs/").
+					Example(`${! meta("tenant") }/`).
+					Default(""),
+				service.NewBoolField(s3oCAFieldCheckExisting).
+					Description("Whether to check if an object already exists with a HEAD request before uploading it, and skip the upload if so.").
+					Default(true),
+				service.NewStringField(s3oCAFieldCache).
+					Description("An optional xref:components:caches/about.adoc[cache resource] used to store the keys of objects that are known to exist, which avoids repeated HEAD requests for the same content.").
+					Default(""),
+				service.NewInterpolatedStringField(s3oCAFieldLogicalID).
+					Description("A logical identifier for each message that is included in manifest documents.").
+					Example(`${! meta("id") }`).
+					Example(`${! this.document.id }`).
+					Default(""),
+				service.NewOutputField(s3oCAFieldManifest).
+					Description("An optional output that receives a manifest document for each object, mapping its logical ID to its content address.").
+					Optional(),
+			).
+				Description("Store objects by the hash of their contents and skip uploads of content that already exists.").
+				Version("4.45.0").
+				Advanced(),
 			service.NewOutputMaxInFlightField(),
 			service.NewDurationField(s3oFieldTimeout).
 				Description("The maximum period to wait on an upload before abandoning it and reattempting.").
@@ -323,15 +493,21 @@ func init() {
 
 type amazonS3Writer struct {
 	conf     s3oConfig
+	client   *s3.Client
 	uploader *manager.Uploader
+	mgr      *service.Resources
 	log      *service.Logger
 }
 
 func newAmazonS3Writer(conf s3oConfig, mgr *service.Resources) (*amazonS3Writer, error) {
 	a := &amazonS3Writer{
 		conf: conf,
+		mgr:  mgr,
 		log:  mgr.Logger(),
 	}
+	if conf.ContentAddressed.Cache != "" && !mgr.HasCache(conf.ContentAddressed.Cache) {
+		return nil, fmt.Errorf("cache resource '%v' was not found", conf.ContentAddressed.Cache)
+	}
 	return a, nil
 }
 
@@ -340,13 +516,87 @@ func (a *amazonS3Writer) Connect(ctx context.Context) error {
 		return nil
 	}
 
+	if a.conf.ContentAddressed.Manifest != nil {
+		if err := a.conf.ContentAddressed.Manifest.Prime(); err != nil {
+			return err
+		}
+	}
+
 	client := s3.NewFromConfig(a.conf.aconf, func(o *s3.Options) {
 		o.UsePathStyle = a.conf.UsePathStyle
+		if a.conf.SkipExisting {
+			o.APIOptions = append(o.APIOptions, s3AddIfNoneMatchMiddleware)
+		}
 	})
+	a.client = client
 	a.uploader = manager.NewUploader(client)
 	return nil
 }
 
+func s3ContentHash(algorithm string, b []byte) string {
+	var h hash.Hash
+	switch algorithm {
+	case "sha512":
+		h = sha512.New()
+	case "sha1":
+		h = sha1.New()
+	default:
+		h = sha256.New()
+	}
+	_, _ = h.Write(b)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// objectExists checks whether an object exists, first consulting the cache of
+// known keys when one is configured.
+func (a *amazonS3Writer) objectExists(ctx context.Context, key string) (bool, error) {
+	cacheName := a.conf.ContentAddressed.Cache
+	if cacheName != "" {
+		var cErr error
+		if err := a.mgr.AccessCache(ctx, cacheName, func(c service.Cache) {
+			_, cErr = c.Get(ctx, key)
+		}); err != nil {
+			return false, err
+		}
+		if cErr == nil {
+			return true, nil
+		}
+		if !errors.Is(cErr, service.ErrKeyNotFound) {
+			a.log.Debugf("Failed to check cache for object '%v': %v", key, cErr)
+		}
+	}
+
+	if _, err := a.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: &a.conf.Bucket,
+		Key:    aws.String(key),
+	}); err != nil {
+		var respErr *awshttp.ResponseError
+		if errors.As(err, &respErr) && respErr.HTTPStatusCode() == http.StatusNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+
+	a.rememberObject(ctx, key)
+	return true, nil
+}
+
+func (a *amazonS3Writer) rememberObject(ctx context.Context, key string) {
+	cacheName := a.conf.ContentAddressed.Cache
+	if cacheName == "" {
+		return
+	}
+	var cErr error
+	if err := a.mgr.AccessCache(ctx, cacheName, func(c service.Cache) {
+		cErr = c.Set(ctx, key, []byte("1"), nil)
+	}); err != nil {
+		cErr = err
+	}
+	if cErr != nil {
+		a.log.Debugf("Failed to store object '%v' in cache: %v", key, cErr)
+	}
+}
+
 func (a *amazonS3Writer) WriteBatch(wctx context.Context, msg service.MessageBatch) error {
 	if a.uploader == nil {
 		return service.ErrNotConnected
@@ -355,7 +605,10 @@ func (a *amazonS3Writer) WriteBatch(wctx context.Context, msg service.MessageBat
 	ctx, cancel := context.WithTimeout(wctx, a.conf.Timeout)
 	defer cancel()
 
-	return msg.WalkWithBatchedErrors(func(i int, m *service.Message) error {
+	ca := a.conf.ContentAddressed
+	var manifestBatch service.MessageBatch
+
+	walkErr := msg.WalkWithBatchedErrors(func(i int, m *service.Message) error {
 		metadata := map[string]string{}
 		_ = a.conf.Metadata.WalkMut(m, func(k string, v any) error {
 			metadata[k] = bloblang.ValueToString(v)
@@ -406,11 +659,56 @@ func (a *amazonS3Writer) WriteBatch(wctx context.Context, msg service.MessageBat
 			websiteRedirectLocation = aws.String(ce)
 		}
 
-		key, err := msg.TryInterpolatedString(i, a.conf.Path)
+		mBytes, err := m.AsBytes()
 		if err != nil {
+			return err
+		}
+
+		var key, contentHash string
+		if ca.Enabled {
+			prefix, err := msg.TryInterpolatedString(i, ca.Prefix)
+			if err != nil {
+				return fmt.Errorf("content address prefix interpolation: %w", err)
+			}
+			contentHash = s3ContentHash(ca.Algorithm, mBytes)
+			key = prefix + contentHash
+		} else if key, err = msg.TryInterpolatedString(i, a.conf.Path); err != nil {
 			return fmt.Errorf("key interpolation: %w", err)
 		}
 
+		addManifest := func(skipped bool) error {
+			if ca.Manifest == nil {
+				return nil
+			}
+			logicalID, err := msg.TryInterpolatedString(i, ca.LogicalID)
+			if err != nil {
+				return fmt.Errorf("logical id interpolation: %w", err)
+			}
+			manifestMsg := m.Copy()
+			manifestMsg.SetStructuredMut(map[string]any{
+				"id":        logicalID,
+				"bucket":    a.conf.Bucket,
+				"key":       key,
+				"algorithm": ca.Algorithm,
+				"hash":      contentHash,
+				"size":      int64(len(mBytes)),
+				"skipped":   skipped,
+			})
+			manifestBatch = append(manifestBatch, manifestMsg)
+			return nil
+		}
+
+		if ca.Enabled && ca.CheckExisting {
+			exists, err := a.objectExists(ctx, key)
+			if err != nil {
+				return fmt.Errorf("failed to check whether object exists: %w", err)
+			}
+			if exists {
+				a.log.Tracef("Skipping upload of object '%v' as it already exists", key)
+				return addManifest(true)
+			}
+		}
+
 		contentType, err := msg.TryInterpolatedString(i, a.conf.ContentType)
 		if err != nil {
 			return fmt.Errorf("content type interpolation: %w", err)
@@ -421,11 +719,6 @@ func (a *amazonS3Writer) WriteBatch(wctx context.Context, msg service.MessageBat
 			return fmt.Errorf("storage class interpolation: %w", err)
 		}
 
-		mBytes, err := m.AsBytes()
-		if err != nil {
-			return err
-		}
-
 		uploadInput := &s3.PutObjectInput{
 			Bucket:                  &a.conf.Bucket,
 			Key:                     aws.String(key),
@@ -470,13 +763,69 @@ func (a *amazonS3Writer) WriteBatch(wctx context.Context, msg service.MessageBat
 			uploadInput.ServerSideEncryption = types.ServerSideEncryption(a.conf.ServerSideEncryption)
 		}
 
+		if a.conf.ObjectLockMode != "" {
+			retainUntilStr, err := msg.TryInterpolatedString(i, a.conf.ObjectLockRetainUntil)
+			if err != nil {
+				return fmt.Errorf("object lock retain until interpolation: %w", err)
+			}
+			retainUntil, err := time.Parse(time.RFC3339, retainUntilStr)
+			if err != nil {
+				return fmt.Errorf("failed to parse object lock retain until timestamp: %w", err)
+			}
+			uploadInput.ObjectLockMode = types.ObjectLockMode(a.conf.ObjectLockMode)
+			uploadInput.ObjectLockRetainUntilDate = &retainUntil
+		}
+
+		if a.conf.ObjectLockLegalHold {
+			uploadInput.ObjectLockLegalHoldStatus = types.ObjectLockLegalHoldStatusOn
+		}
+
 		if _, err := a.uploader.Upload(ctx, uploadInput); err != nil {
+			if a.conf.SkipExisting && s3IsPreconditionFailed(err) {
+				a.log.Debugf("Skipping upload of object '%v' as it already exists", key)
+				return addManifest(true)
+			}
 			return err
 		}
-		return nil
+		if ca.Enabled {
+			a.rememberObject(ctx, key)
+		}
+		return addManifest(false)
 	})
+
+	if len(manifestBatch) > 0 {
+		if err := ca.Manifest.WriteBatch(wctx, manifestBatch); err != nil {
+			return fmt.Errorf("failed to write manifest: %w", err)
+		}
+	}
+	return walkErr
 }
 
-func (a *amazonS3Writer) Close(context.Context) error {
+func (a *amazonS3Writer) Close(ctx context.Context) error {
+	if a.conf.ContentAddressed.Manifest != nil {
+		return a.conf.ContentAddressed.Manifest.Close(ctx)
+	}
 	return nil
 }
+
+// s3AddIfNoneMatchMiddleware adds an If-None-Match condition to the requests
+// that create an object, which are either a PutObject or the completion of a
+// multipart upload.
+func s3AddIfNoneMatchMiddleware(stack *middleware.Stack) error {
+	return stack.Build.Add(middleware.BuildMiddlewareFunc("IfNoneMatch", func(
+		ctx context.Context, in middleware.BuildInput, next middleware.BuildHandler,
+	) (middleware.BuildOutput, middleware.Metadata, error) {
+		switch awsmiddleware.GetOperationName(ctx) {
+		case "PutObject", "CompleteMultipartUpload":
+			if req, ok := in.Request.(*smithyhttp.Request); ok {
+				req.Header.Set("If-None-Match", "*")
+			}
+		}
+		return next.HandleBuild(ctx, in)
+	}), middleware.After)
+}
+
+func s3IsPreconditionFailed(err error) bool {
+	var respErr *awshttp.ResponseError
+	return errors.As(err, &respErr) && respErr.HTTPStatusCode() == http.StatusPreconditionFailed
+}
@@ -0,0 +1,233 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aws
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+type fakeS3Store struct {
+	mut     sync.Mutex
+	objects map[string]string
+	headers map[string]http.Header
+	puts    int
+	heads   int
+}
+
+func (f *fakeS3Store) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	if r.Method == http.MethodHead {
+		f.heads++
+		if _, exists := f.objects[r.URL.Path]; !exists {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodPut {
+		w.WriteHeader(http.StatusNotImplemented)
+		return
+	}
+	f.puts++
+	if _, exists := f.objects[r.URL.Path]; exists && r.Header.Get("If-None-Match") == "*" {
+		w.WriteHeader(http.StatusPreconditionFailed)
+		_, _ = w.Write([]byte(`<Error><Code>PreconditionFailed</Code><Message>At least one of the pre-conditions you specified did not hold</Message></Error>`))
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+	f.objects[r.URL.Path] = string(body)
+	f.headers[r.URL.Path] = r.Header.Clone()
+	w.WriteHeader(http.StatusOK)
+}
+
+func testS3Writer(t *testing.T, env *service.Environment, endpoint, conf string) *amazonS3Writer {
+	t.Helper()
+
+	pConf, err := s3oOutputSpec().ParseYAML(`
+bucket: foo
+endpoint: `+endpoint+`
+region: eu-west-1
+force_path_style_urls: true
+credentials:
+  id: xxxxx
+  secret: xxxxx
+`+conf, env)
+	require.NoError(t, err)
+
+	wConf, err := s3oConfigFromParsed(pConf)
+	require.NoError(t, err)
+
+	w, err := newAmazonS3Writer(wConf, service.MockResources())
+	require.NoError(t, err)
+	require.NoError(t, w.Connect(context.Background()))
+	return w
+}
+
+func TestS3OutputSkipExisting(t *testing.T) {
+	store := &fakeS3Store{objects: map[string]string{}, headers: map[string]http.Header{}}
+	server := httptest.NewServer(store)
+	t.Cleanup(server.Close)
+
+	w := testS3Writer(t, nil, server.URL, `
+path: ${! meta("id") }.txt
+skip_existing: true
+`)
+
+	write := func(id, content string) error {
+		msg := service.NewMessage([]byte(content))
+		msg.MetaSetMut("id", id)
+		return w.WriteBatch(context.Background(), service.MessageBatch{msg})
+	}
+
+	require.NoError(t, write("a", "first"))
+	require.NoError(t, write("a", "second"))
+	require.NoError(t, write("b", "third"))
+
+	assert.Equal(t, map[string]string{
+		"/foo/a.txt": "first",
+		"/foo/b.txt": "third",
+	}, store.objects)
+}
+
+func TestS3OutputObjectLock(t *testing.T) {
+	store := &fakeS3Store{objects: map[string]string{}, headers: map[string]http.Header{}}
+	server := httptest.NewServer(store)
+	t.Cleanup(server.Close)
+
+	w := testS3Writer(t, nil, server.URL, `
+path: locked.txt
+object_lock_mode: COMPLIANCE
+object_lock_retain_until: 2030-01-01T00:00:00Z
+object_lock_legal_hold: true
+`)
+	require.NoError(t, w.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte("hello world")),
+	}))
+
+	headers := store.headers["/foo/locked.txt"]
+	require.NotNil(t, headers)
+	assert.Equal(t, "COMPLIANCE", headers.Get("X-Amz-Object-Lock-Mode"))
+	assert.Equal(t, "2030-01-01T00:00:00Z", headers.Get("X-Amz-Object-Lock-Retain-Until-Date"))
+	assert.Equal(t, "ON", headers.Get("X-Amz-Object-Lock-Legal-Hold"))
+	assert.Empty(t, headers.Get("If-None-Match"))
+}
+
+func TestS3OutputObjectLockRequiresRetainUntil(t *testing.T) {
+	pConf, err := s3oOutputSpec().ParseYAML(`
+bucket: foo
+path: locked.txt
+object_lock_mode: GOVERNANCE
+`, nil)
+	require.NoError(t, err)
+
+	_, err = s3oConfigFromParsed(pConf)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "object_lock_retain_until")
+}
+
+type captureOutput struct {
+	mut     sync.Mutex
+	batches []service.MessageBatch
+}
+
+func (c *captureOutput) Connect(context.Context) error { return nil }
+
+func (c *captureOutput) WriteBatch(_ context.Context, b service.MessageBatch) error {
+	c.mut.Lock()
+	c.batches = append(c.batches, b)
+	c.mut.Unlock()
+	return nil
+}
+
+func (c *captureOutput) Close(context.Context) error { return nil }
+
+func TestS3OutputContentAddressed(t *testing.T) {
+	store := &fakeS3Store{objects: map[string]string{}, headers: map[string]http.Header{}}
+	server := httptest.NewServer(store)
+	t.Cleanup(server.Close)
+
+	manifest := &captureOutput{}
+	env := service.NewEnvironment()
+	require.NoError(t, env.RegisterBatchOutput("s3_test_manifest", service.NewConfigSpec(),
+		func(*service.ParsedConfig, *service.Resources) (service.BatchOutput, service.BatchPolicy, int, error) {
+			return manifest, service.BatchPolicy{}, 1, nil
+		}))
+
+	w := testS3Writer(t, env, server.URL, `
+path: ignored.txt
+content_addressed:
+  enabled: true
+  prefix: blobs/
+  logical_id: ${! meta("id") }
+  manifest:
+    s3_test_manifest: {}
+`)
+	t.Cleanup(func() {
+		require.NoError(t, w.Close(context.Background()))
+	})
+
+	newMsg := func(id, content string) *service.Message {
+		msg := service.NewMessage([]byte(content))
+		msg.MetaSetMut("id", id)
+		return msg
+	}
+
+	require.NoError(t, w.WriteBatch(context.Background(), service.MessageBatch{
+		newMsg("a", "test"),
+		newMsg("b", "other"),
+	}))
+	require.NoError(t, w.WriteBatch(context.Background(), service.MessageBatch{
+		newMsg("c", "test"),
+	}))
+
+	testHash := "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	otherHash := "d9298a10d1b0735837dc4bd85dac641b0f3cef27a47e5d53a54f2f3f5b2fcffa"
+	assert.Equal(t, map[string]string{
+		"/foo/blobs/" + testHash:  "test",
+		"/foo/blobs/" + otherHash: "other",
+	}, store.objects)
+	assert.Equal(t, 2, store.puts)
+	assert.Equal(t, 3, store.heads)
+
+	var docs []any
+	manifest.mut.Lock()
+	for _, b := range manifest.batches {
+		for _, m := range b {
+			v, err := m.AsStructured()
+			require.NoError(t, err)
+			docs = append(docs, v)
+		}
+	}
+	manifest.mut.Unlock()
+
+	assert.Equal(t, []any{
+		map[string]any{"id": "a", "bucket": "foo", "key": "blobs/" + testHash, "algorithm": "sha256", "hash": testHash, "size": int64(4), "skipped": false},
+		map[string]any{"id": "b", "bucket": "foo", "key": "blobs/" + otherHash, "algorithm": "sha256", "hash": otherHash, "size": int64(5), "skipped": false},
+		map[string]any{"id": "c", "bucket": "foo", "key": "blobs/" + testHash, "algorithm": "sha256", "hash": testHash, "size": int64(4), "skipped": true},
+	}, docs)
+}
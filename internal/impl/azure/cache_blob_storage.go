@@ -0,0 +1,159 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package azure
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/streaming"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blockblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	bscFieldContainer   = "container"
+	bscFieldContentType = "content_type"
+)
+
+func bsCacheSpec() *service.ConfigSpec {
+	return azureComponentSpec(true).
+		Beta().
+		Version("4.45.0").
+		Summary(`Stores each item in an Azure Blob Storage container as a block blob, where an item ID is the name of the blob within the container.`).
+		Description(`
+Supports multiple authentication methods but only one of the following is required:
+
+- `+"`storage_connection_string`"+`
+- `+"`storage_account` and `storage_access_key`"+`
+- `+"`storage_account` and `storage_sas_token`"+`
+- `+"`storage_account` to access via https://pkg.go.dev/github.com/Azure/azure-sdk-for-go/sdk/azidentity#DefaultAzureCredential[DefaultAzureCredential^]"+`
+
+If multiple are set then the `+"`storage_connection_string`"+` is given priority.
+
+Items are added exclusively with a conditional upload, and therefore this cache is suitable for deduplication. TTLs are not supported, and expiring items can instead be achieved with a lifecycle management policy on the container.`).
+		Fields(
+			service.NewStringField(bscFieldContainer).
+				Description("The container to store items in, which must already exist. This field is ignored when the `storage_sas_token` is a container SAS token."),
+			service.NewStringField(bscFieldContentType).
+				Description("The content type to set for each item.").
+				Default("application/octet-stream"),
+		)
+}
+
+func init() {
+	err := service.RegisterCache(
+		"azure_blob_storage", bsCacheSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Cache, error) {
+			return newBlobStorageCacheFromParsed(conf)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type blobStorageCache struct {
+	container   *container.Client
+	contentType string
+}
+
+func newBlobStorageCacheFromParsed(conf *service.ParsedConfig) (*blobStorageCache, error) {
+	containerName, err := conf.FieldString(bscFieldContainer)
+	if err != nil {
+		return nil, err
+	}
+	contentType, err := conf.FieldString(bscFieldContentType)
+	if err != nil {
+		return nil, err
+	}
+
+	containerInterp, err := service.NewInterpolatedString(containerName)
+	if err != nil {
+		return nil, err
+	}
+	client, containerSASToken, err := blobStorageClientFromParsed(conf, containerInterp)
+	if err != nil {
+		return nil, err
+	}
+	if containerSASToken {
+		// if using a container SAS token, the container is already implicit
+		containerName = ""
+	}
+
+	return &blobStorageCache{
+		container:   client.ServiceClient().NewContainerClient(containerName),
+		contentType: contentType,
+	}, nil
+}
+
+func (b *blobStorageCache) Get(ctx context.Context, key string) ([]byte, error) {
+	res, err := b.container.NewBlobClient(key).DownloadStream(ctx, nil)
+	if err != nil {
+		if isErrorCode(err, bloberror.BlobNotFound) {
+			return nil, service.ErrKeyNotFound
+		}
+		return nil, err
+	}
+	defer res.Body.Close()
+	return io.ReadAll(res.Body)
+}
+
+func (b *blobStorageCache) upload(ctx context.Context, key string, value []byte, conditions *blob.AccessConditions) error {
+	_, err := b.container.NewBlockBlobClient(key).Upload(ctx, streaming.NopCloser(bytes.NewReader(value)), &blockblob.UploadOptions{
+		HTTPHeaders: &blob.HTTPHeaders{
+			BlobContentType: &b.contentType,
+		},
+		AccessConditions: conditions,
+	})
+	return err
+}
+
+func (b *blobStorageCache) Set(ctx context.Context, key string, value []byte, _ *time.Duration) error {
+	return b.upload(ctx, key, value, nil)
+}
+
+func (b *blobStorageCache) Add(ctx context.Context, key string, value []byte, _ *time.Duration) error {
+	anyETag := azcore.ETagAny
+	err := b.upload(ctx, key, value, &blob.AccessConditions{
+		ModifiedAccessConditions: &blob.ModifiedAccessConditions{
+			IfNoneMatch: &anyETag,
+		},
+	})
+	if isErrorCode(err, bloberror.BlobAlreadyExists) || isErrorCode(err, bloberror.ConditionNotMet) {
+		return service.ErrKeyAlreadyExists
+	}
+	return err
+}
+
+func (b *blobStorageCache) Delete(ctx context.Context, key string) error {
+	_, err := b.container.NewBlobClient(key).Delete(ctx, nil)
+	if isErrorCode(err, bloberror.BlobNotFound) {
+		return nil
+	}
+	return err
+}
+
+func (b *blobStorageCache) Close(context.Context) error {
+	return nil
+}
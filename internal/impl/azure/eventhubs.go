@@ -0,0 +1,174 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package azure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/Azure/go-amqp"
+	"github.com/gofrs/uuid"
+)
+
+const (
+	// Common fields for event hubs components
+	ehFieldConnectionString = "connection_string"
+	ehFieldEventHub         = "event_hub"
+)
+
+// ehConnectionDetails are the parts of an event hubs connection string that
+// are required in order to dial a namespace.
+type ehConnectionDetails struct {
+	Host       string
+	KeyName    string
+	Key        string
+	EntityPath string
+}
+
+// parseEventHubsConnectionString parses a connection string of the form
+// `Endpoint=sb://<namespace>.servicebus.windows.net/;SharedAccessKeyName=<name>;SharedAccessKey=<key>[;EntityPath=<hub>]`.
+func parseEventHubsConnectionString(connStr string) (details ehConnectionDetails, err error) {
+	for _, part := range strings.Split(connStr, ";") {
+		key, value, found := strings.Cut(strings.TrimSpace(part), "=")
+		if !found {
+			continue
+		}
+		switch strings.ToLower(key) {
+		case "endpoint":
+			var u *url.URL
+			if u, err = url.Parse(value); err != nil {
+				return details, fmt.Errorf("failed to parse endpoint: %w", err)
+			}
+			details.Host = u.Host
+		case "sharedaccesskeyname":
+			details.KeyName = value
+		case "sharedaccesskey":
+			details.Key = value
+		case "entitypath":
+			details.EntityPath = value
+		case "sharedaccesssignature":
+			return details, errors.New("connection strings with a shared access signature are not supported, use a shared access key instead")
+		}
+	}
+	if details.Host == "" {
+		return details, errors.New("connection string is missing an endpoint")
+	}
+	if details.KeyName == "" || details.Key == "" {
+		return details, errors.New("connection string is missing a shared access key name and key")
+	}
+	return details, nil
+}
+
+// eventHubName returns the event hub explicitly configured, or otherwise the
+// entity path of the connection string.
+func (d ehConnectionDetails) eventHubName(configured string) (string, error) {
+	if configured != "" {
+		return configured, nil
+	}
+	if d.EntityPath != "" {
+		return d.EntityPath, nil
+	}
+	return "", errors.New("an event hub must be specified either with the event_hub field or within the EntityPath of the connection string")
+}
+
+func (d ehConnectionDetails) dial(ctx context.Context) (*amqp.Conn, error) {
+	return amqp.Dial(ctx, "amqps://"+d.Host, &amqp.ConnOptions{
+		SASLType: amqp.SASLTypePlain(d.KeyName, d.Key),
+		HostName: d.Host,
+	})
+}
+
+// ehPartitionIDs queries the management node of a namespace for the
+// partitions of an event hub.
+func ehPartitionIDs(ctx context.Context, session *amqp.Session, eventHub string) ([]string, error) {
+	replyID, err := uuid.NewV4()
+	if err != nil {
+		return nil, err
+	}
+	replyTo := "eh-mgmt-" + replyID.String()
+
+	sender, err := session.NewSender(ctx, "$management", &amqp.SenderOptions{
+		SourceAddress: replyTo,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open management sender: %w", err)
+	}
+	defer sender.Close(ctx)
+
+	receiver, err := session.NewReceiver(ctx, "$management", &amqp.ReceiverOptions{
+		TargetAddress: replyTo,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open management receiver: %w", err)
+	}
+	defer receiver.Close(ctx)
+
+	if err := sender.Send(ctx, &amqp.Message{
+		Properties: &amqp.MessageProperties{
+			MessageID: replyID.String(),
+			ReplyTo:   &replyTo,
+		},
+		ApplicationProperties: map[string]any{
+			"operation": "READ",
+			"name":      eventHub,
+			"type":      "com.microsoft:eventhub",
+		},
+	}, nil); err != nil {
+		return nil, fmt.Errorf("failed to send management request: %w", err)
+	}
+
+	res, err := receiver.Receive(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to receive management response: %w", err)
+	}
+	_ = receiver.AcceptMessage(ctx, res)
+
+	if code, ok := res.ApplicationProperties["status-code"].(int32); ok && code != 200 {
+		desc, _ := res.ApplicationProperties["status-description"].(string)
+		return nil, fmt.Errorf("management request failed with status %v: %v", code, desc)
+	}
+	return ehPartitionIDsFromValue(res.Value)
+}
+
+func ehPartitionIDsFromValue(v any) ([]string, error) {
+	var ids any
+	switch t := v.(type) {
+	case map[string]any:
+		ids = t["partition_ids"]
+	case map[any]any:
+		ids = t["partition_ids"]
+	default:
+		return nil, fmt.Errorf("unexpected management response type: %T", v)
+	}
+
+	switch t := ids.(type) {
+	case []string:
+		return t, nil
+	case []any:
+		idsList := make([]string, 0, len(t))
+		for _, id := range t {
+			idStr, ok := id.(string)
+			if !ok {
+				return nil, fmt.Errorf("unexpected partition id type: %T", id)
+			}
+			idsList = append(idsList, idStr)
+		}
+		return idsList, nil
+	}
+	return nil, fmt.Errorf("unexpected partition_ids type: %T", ids)
+}
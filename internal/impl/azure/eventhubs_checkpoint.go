@@ -0,0 +1,241 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package azure
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/streaming"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blockblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+)
+
+var errEHOwnershipNotClaimed = errors.New("partition ownership was claimed by another consumer")
+
+// ehOwnership describes the current owner of an event hub partition.
+type ehOwnership struct {
+	PartitionID  string
+	OwnerID      string
+	ETag         *azcore.ETag
+	LastModified time.Time
+}
+
+// ehCheckpoint describes the latest consumed position of a partition.
+type ehCheckpoint struct {
+	Offset         string
+	SequenceNumber int64
+}
+
+// ehCheckpointStore stores partition ownership and checkpoints within a blob
+// storage container, using the same layout as the Azure SDKs so that
+// consumers can be migrated between them.
+type ehCheckpointStore struct {
+	client *container.Client
+	prefix string
+}
+
+func newEHCheckpointStore(client *container.Client, namespace, eventHub, consumerGroup string) *ehCheckpointStore {
+	return &ehCheckpointStore{
+		client: client,
+		prefix: strings.ToLower(path.Join(namespace, eventHub, consumerGroup)),
+	}
+}
+
+func (s *ehCheckpointStore) list(ctx context.Context, kind string, fn func(partitionID string, item *container.BlobItem)) error {
+	prefix := s.prefix + "/" + kind + "/"
+	pager := s.client.NewListBlobsFlatPager(&container.ListBlobsFlatOptions{
+		Prefix:  &prefix,
+		Include: container.ListBlobsInclude{Metadata: true},
+	})
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+		for _, item := range page.Segment.BlobItems {
+			if item.Name == nil {
+				continue
+			}
+			fn(strings.TrimPrefix(*item.Name, prefix), item)
+		}
+	}
+	return nil
+}
+
+func blobMetadataValue(meta map[string]*string, key string) string {
+	for k, v := range meta {
+		if strings.EqualFold(k, key) && v != nil {
+			return *v
+		}
+	}
+	return ""
+}
+
+// ListOwnerships returns the current owners of all partitions that have been
+// claimed at least once.
+func (s *ehCheckpointStore) ListOwnerships(ctx context.Context) (ownerships []ehOwnership, err error) {
+	err = s.list(ctx, "ownership", func(partitionID string, item *container.BlobItem) {
+		o := ehOwnership{
+			PartitionID: partitionID,
+			OwnerID:     blobMetadataValue(item.Metadata, "ownerid"),
+		}
+		if item.Properties != nil {
+			o.ETag = item.Properties.ETag
+			if item.Properties.LastModified != nil {
+				o.LastModified = *item.Properties.LastModified
+			}
+		}
+		ownerships = append(ownerships, o)
+	})
+	return
+}
+
+// ClaimOwnership attempts to claim or renew the ownership of a partition,
+// which only succeeds when the ownership has not been modified since it was
+// listed.
+func (s *ehCheckpointStore) ClaimOwnership(ctx context.Context, o ehOwnership) (ehOwnership, error) {
+	conditions := &blob.ModifiedAccessConditions{}
+	if o.ETag != nil {
+		conditions.IfMatch = o.ETag
+	} else {
+		anyETag := azcore.ETagAny
+		conditions.IfNoneMatch = &anyETag
+	}
+
+	ownerID := o.OwnerID
+	res, err := s.client.NewBlockBlobClient(s.prefix+"/ownership/"+o.PartitionID).Upload(ctx, streaming.NopCloser(bytes.NewReader(nil)), &blockblob.UploadOptions{
+		Metadata: map[string]*string{"ownerid": &ownerID},
+		AccessConditions: &blob.AccessConditions{
+			ModifiedAccessConditions: conditions,
+		},
+	})
+	if err != nil {
+		if bloberror.HasCode(err, bloberror.ConditionNotMet, bloberror.BlobAlreadyExists) {
+			return o, errEHOwnershipNotClaimed
+		}
+		return o, err
+	}
+
+	o.ETag = res.ETag
+	if res.LastModified != nil {
+		o.LastModified = *res.LastModified
+	}
+	return o, nil
+}
+
+// ListCheckpoints returns the latest checkpoints of all partitions.
+func (s *ehCheckpointStore) ListCheckpoints(ctx context.Context) (map[string]ehCheckpoint, error) {
+	checkpoints := map[string]ehCheckpoint{}
+	err := s.list(ctx, "checkpoint", func(partitionID string, item *container.BlobItem) {
+		cp := ehCheckpoint{
+			Offset: blobMetadataValue(item.Metadata, "offset"),
+		}
+		cp.SequenceNumber, _ = strconv.ParseInt(blobMetadataValue(item.Metadata, "sequencenumber"), 10, 64)
+		if cp.Offset != "" {
+			checkpoints[partitionID] = cp
+		}
+	})
+	return checkpoints, err
+}
+
+// SetCheckpoint stores the latest consumed position of a partition.
+func (s *ehCheckpointStore) SetCheckpoint(ctx context.Context, partitionID string, cp ehCheckpoint) error {
+	seqStr := strconv.FormatInt(cp.SequenceNumber, 10)
+	_, err := s.client.NewBlockBlobClient(s.prefix+"/checkpoint/"+partitionID).Upload(ctx, streaming.NopCloser(bytes.NewReader(nil)), &blockblob.UploadOptions{
+		Metadata: map[string]*string{
+			"offset":         &cp.Offset,
+			"sequencenumber": &seqStr,
+		},
+	})
+	return err
+}
+
+//------------------------------------------------------------------------------
+
+// ehBalancePlan contains the ownership changes that a consumer should attempt
+// in order to balance partitions across all active consumers.
+type ehBalancePlan struct {
+	// Ownerships currently held by this consumer that should be renewed.
+	Renew []ehOwnership
+
+	// An ownership held by nobody, an inactive consumer or an overloaded
+	// consumer that should be claimed, if any.
+	Claim *ehOwnership
+}
+
+// ehPlanBalance determines which partition ownerships to renew and claim. At
+// most one new partition is claimed per round so that consumers converge on a
+// balanced distribution without contending over many partitions at once.
+func ehPlanBalance(partitionIDs []string, ownerships []ehOwnership, selfID string, now time.Time, expiry time.Duration) ehBalancePlan {
+	var plan ehBalancePlan
+
+	known := map[string]ehOwnership{}
+	for _, o := range ownerships {
+		known[o.PartitionID] = o
+	}
+
+	activeByOwner := map[string][]ehOwnership{selfID: nil}
+	var unowned []ehOwnership
+	for _, id := range partitionIDs {
+		o, exists := known[id]
+		if !exists {
+			unowned = append(unowned, ehOwnership{PartitionID: id})
+			continue
+		}
+		if o.OwnerID == "" || now.Sub(o.LastModified) > expiry {
+			unowned = append(unowned, o)
+			continue
+		}
+		activeByOwner[o.OwnerID] = append(activeByOwner[o.OwnerID], o)
+	}
+
+	plan.Renew = activeByOwner[selfID]
+	target := int(math.Ceil(float64(len(partitionIDs)) / float64(len(activeByOwner))))
+	if len(plan.Renew) >= target {
+		return plan
+	}
+
+	if len(unowned) > 0 {
+		claim := unowned[rand.Intn(len(unowned))]
+		plan.Claim = &claim
+		return plan
+	}
+
+	// Steal from the consumer with the most partitions, provided they have at
+	// least two more than we do so that a partition doesn't bounce between
+	// consumers with similar loads.
+	var victim []ehOwnership
+	for ownerID, owned := range activeByOwner {
+		if ownerID != selfID && len(owned) > len(victim) {
+			victim = owned
+		}
+	}
+	if len(victim) > len(plan.Renew)+1 {
+		claim := victim[rand.Intn(len(victim))]
+		plan.Claim = &claim
+	}
+	return plan
+}
@@ -0,0 +1,174 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package azure
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Azure/go-amqp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func TestEventHubsConnectionString(t *testing.T) {
+	d, err := parseEventHubsConnectionString("Endpoint=sb://foo.servicebus.windows.net/;SharedAccessKeyName=RootManageSharedAccessKey;SharedAccessKey=abc=;EntityPath=bar")
+	require.NoError(t, err)
+	assert.Equal(t, ehConnectionDetails{
+		Host:       "foo.servicebus.windows.net",
+		KeyName:    "RootManageSharedAccessKey",
+		Key:        "abc=",
+		EntityPath: "bar",
+	}, d)
+
+	hub, err := d.eventHubName("")
+	require.NoError(t, err)
+	assert.Equal(t, "bar", hub)
+
+	hub, err = d.eventHubName("baz")
+	require.NoError(t, err)
+	assert.Equal(t, "baz", hub)
+
+	_, err = parseEventHubsConnectionString("Endpoint=sb://foo.servicebus.windows.net/;SharedAccessSignature=SharedAccessSignature sr=foo")
+	require.Error(t, err)
+
+	_, err = parseEventHubsConnectionString("SharedAccessKeyName=foo;SharedAccessKey=bar")
+	require.ErrorContains(t, err, "endpoint")
+
+	d, err = parseEventHubsConnectionString("Endpoint=sb://foo.servicebus.windows.net/;SharedAccessKeyName=foo;SharedAccessKey=bar")
+	require.NoError(t, err)
+	_, err = d.eventHubName("")
+	require.Error(t, err)
+}
+
+func TestEventHubsPartitionIDsFromValue(t *testing.T) {
+	ids, err := ehPartitionIDsFromValue(map[string]any{"partition_ids": []string{"0", "1"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"0", "1"}, ids)
+
+	ids, err = ehPartitionIDsFromValue(map[any]any{"partition_ids": []any{"0", "1"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"0", "1"}, ids)
+
+	_, err = ehPartitionIDsFromValue("nope")
+	require.Error(t, err)
+}
+
+func TestEventHubsSelectorFilter(t *testing.T) {
+	assert.Equal(t, "amqp.annotation.x-opt-offset > '-1'", ehSelectorFilter(nil, true))
+	assert.Equal(t, "amqp.annotation.x-opt-offset > '@latest'", ehSelectorFilter(nil, false))
+	assert.Equal(t, "amqp.annotation.x-opt-offset > '1024'", ehSelectorFilter(&ehCheckpoint{Offset: "1024"}, true))
+}
+
+func TestEventHubsPlanBalance(t *testing.T) {
+	now := time.Now()
+	partitions := []string{"0", "1", "2", "3"}
+
+	// Nothing owned, claim something.
+	plan := ehPlanBalance(partitions, nil, "a", now, time.Minute)
+	assert.Empty(t, plan.Renew)
+	require.NotNil(t, plan.Claim)
+	assert.Contains(t, partitions, plan.Claim.PartitionID)
+
+	// Balanced, only renew.
+	ownerships := []ehOwnership{
+		{PartitionID: "0", OwnerID: "a", LastModified: now},
+		{PartitionID: "1", OwnerID: "a", LastModified: now},
+		{PartitionID: "2", OwnerID: "b", LastModified: now},
+		{PartitionID: "3", OwnerID: "b", LastModified: now},
+	}
+	plan = ehPlanBalance(partitions, ownerships, "a", now, time.Minute)
+	assert.Len(t, plan.Renew, 2)
+	assert.Nil(t, plan.Claim)
+
+	// An expired owner has its partitions claimed.
+	ownerships[2].LastModified = now.Add(-time.Hour)
+	ownerships[3].LastModified = now.Add(-time.Hour)
+	plan = ehPlanBalance(partitions, ownerships, "a", now, time.Minute)
+	assert.Len(t, plan.Renew, 2)
+	require.NotNil(t, plan.Claim)
+	assert.Contains(t, []string{"2", "3"}, plan.Claim.PartitionID)
+	assert.Equal(t, "b", plan.Claim.OwnerID)
+
+	// Steal from an overloaded owner.
+	ownerships = []ehOwnership{
+		{PartitionID: "0", OwnerID: "b", LastModified: now},
+		{PartitionID: "1", OwnerID: "b", LastModified: now},
+		{PartitionID: "2", OwnerID: "b", LastModified: now},
+		{PartitionID: "3", OwnerID: "b", LastModified: now},
+	}
+	plan = ehPlanBalance(partitions, ownerships, "a", now, time.Minute)
+	assert.Empty(t, plan.Renew)
+	require.NotNil(t, plan.Claim)
+	assert.Equal(t, "b", plan.Claim.OwnerID)
+
+	// Do not steal when the difference is only one.
+	ownerships = []ehOwnership{
+		{PartitionID: "0", OwnerID: "a", LastModified: now},
+		{PartitionID: "1", OwnerID: "b", LastModified: now},
+		{PartitionID: "2", OwnerID: "b", LastModified: now},
+	}
+	plan = ehPlanBalance(partitions[:3], ownerships, "a", now, time.Minute)
+	assert.Len(t, plan.Renew, 1)
+	assert.Nil(t, plan.Claim)
+}
+
+func TestEventHubsOutputGrouping(t *testing.T) {
+	pConf, err := ehoSpec().ParseYAML(`
+connection_string: Endpoint=sb://foo.servicebus.windows.net/;SharedAccessKeyName=foo;SharedAccessKey=bar
+event_hub: baz
+partition_key: ${! meta("key") }
+`, nil)
+	require.NoError(t, err)
+
+	conf, err := ehoConfigFromParsed(pConf)
+	require.NoError(t, err)
+
+	w := newEventHubsWriter(conf, service.MockResources())
+
+	var batch service.MessageBatch
+	for _, kv := range [][2]string{{"a", "1"}, {"b", "2"}, {"a", "3"}} {
+		msg := service.NewMessage([]byte(kv[1]))
+		msg.MetaSetMut("key", kv[0])
+		batch = append(batch, msg)
+	}
+
+	groups, err := w.groupBatch(batch)
+	require.NoError(t, err)
+	require.Len(t, groups, 2)
+
+	assert.Equal(t, "a", groups[0].partitionKey)
+	assert.Equal(t, []int{0, 2}, groups[0].indexes)
+	assert.Equal(t, "b", groups[1].partitionKey)
+	assert.Equal(t, []int{1}, groups[1].indexes)
+
+	env, err := groups[0].envelope()
+	require.NoError(t, err)
+	assert.Equal(t, ehBatchMessageFormat, env.Format)
+	assert.Equal(t, "a", env.Annotations[ehiAnnotationPartitionKy])
+	require.Len(t, env.Data, 2)
+
+	var inner amqp.Message
+	require.NoError(t, inner.UnmarshalBinary(env.Data[1]))
+	assert.Equal(t, []byte("3"), inner.GetData())
+	assert.Equal(t, "a", inner.ApplicationProperties["key"])
+
+	env, err = groups[1].envelope()
+	require.NoError(t, err)
+	assert.Equal(t, uint32(0), env.Format)
+	assert.Equal(t, []byte("2"), env.GetData())
+}
@@ -0,0 +1,658 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package azure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Azure/go-amqp"
+	"github.com/Jeffail/checkpoint"
+	"github.com/gofrs/uuid"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	// Event Hubs Input Fields
+	ehiFieldConsumerGroup    = "consumer_group"
+	ehiFieldStartFromOldest  = "start_from_oldest"
+	ehiFieldCheckpointStore  = "checkpoint_store"
+	ehiFieldCheckpointCont   = "container"
+	ehiFieldCheckpointLimit  = "checkpoint_limit"
+	ehiFieldCommitPeriod     = "commit_period"
+	ehiFieldRebalancePeriod  = "rebalance_period"
+	ehiFieldOwnershipExpiry  = "ownership_expiry"
+	ehiFieldCredit           = "credit"
+	ehiAnnotationOffset      = "x-opt-offset"
+	ehiAnnotationSequence    = "x-opt-sequence-number"
+	ehiAnnotationEnqueued    = "x-opt-enqueued-time"
+	ehiAnnotationPartitionKy = "x-opt-partition-key"
+)
+
+type ehiConfig struct {
+	Details         ehConnectionDetails
+	EventHub        string
+	ConsumerGroup   string
+	StartFromOldest bool
+	CheckpointLimit int
+	CommitPeriod    time.Duration
+	RebalancePeriod time.Duration
+	OwnershipExpiry time.Duration
+	Credit          int
+
+	store *ehCheckpointStore
+}
+
+func ehiConfigFromParsed(pConf *service.ParsedConfig) (conf ehiConfig, err error) {
+	var connStr string
+	if connStr, err = pConf.FieldString(ehFieldConnectionString); err != nil {
+		return
+	}
+	if conf.Details, err = parseEventHubsConnectionString(connStr); err != nil {
+		return
+	}
+	var hub string
+	if hub, err = pConf.FieldString(ehFieldEventHub); err != nil {
+		return
+	}
+	if conf.EventHub, err = conf.Details.eventHubName(hub); err != nil {
+		return
+	}
+	if conf.ConsumerGroup, err = pConf.FieldString(ehiFieldConsumerGroup); err != nil {
+		return
+	}
+	if conf.StartFromOldest, err = pConf.FieldBool(ehiFieldStartFromOldest); err != nil {
+		return
+	}
+	if conf.CheckpointLimit, err = pConf.FieldInt(ehiFieldCheckpointLimit); err != nil {
+		return
+	}
+	if conf.CommitPeriod, err = pConf.FieldDuration(ehiFieldCommitPeriod); err != nil {
+		return
+	}
+	if conf.RebalancePeriod, err = pConf.FieldDuration(ehiFieldRebalancePeriod); err != nil {
+		return
+	}
+	if conf.OwnershipExpiry, err = pConf.FieldDuration(ehiFieldOwnershipExpiry); err != nil {
+		return
+	}
+	if conf.Credit, err = pConf.FieldInt(ehiFieldCredit); err != nil {
+		return
+	}
+
+	storeConf := pConf.Namespace(ehiFieldCheckpointStore)
+	var containerName string
+	if containerName, err = storeConf.FieldString(ehiFieldCheckpointCont); err != nil {
+		return
+	}
+	if containerName != "" {
+		var containerQ *service.InterpolatedString
+		if containerQ, err = service.NewInterpolatedString(containerName); err != nil {
+			return
+		}
+		client, containerSASToken, cErr := blobStorageClientFromParsed(storeConf, containerQ)
+		if cErr != nil {
+			err = fmt.Errorf("failed to create checkpoint store client: %w", cErr)
+			return
+		}
+		if containerSASToken {
+			// When using a container SAS token the client is already scoped
+			// to the container.
+			containerName = ""
+		}
+		conf.store = newEHCheckpointStore(
+			client.ServiceClient().NewContainerClient(containerName),
+			conf.Details.Host, conf.EventHub, conf.ConsumerGroup,
+		)
+	}
+	return
+}
+
+func ehiSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Version("4.45.0").
+		Categories("Services", "Azure").
+		Summary("Consumes events from an Azure Event Hub, balancing partitions across consumers and checkpointing with Azure Blob Storage.").
+		Description(`
+Connects to an Event Hubs namespace with AMQP using a shared access key from the provided connection string.
+
+== Checkpointing and load balancing
+
+When `+"`checkpoint_store.container`"+` is set the partitions of the event hub are balanced across all consumers sharing the same consumer group and checkpoint store, and the latest acknowledged position of each partition is stored within the container at a regular interval defined by `+"`commit_period`"+`. Consumers resume from these checkpoints after restarts and when partitions move between consumers. Ownership and checkpoint blobs use the same layout as the Azure SDKs.
+
+When no checkpoint store is configured all partitions are consumed by this input, and consumption begins from either the oldest or latest event of each partition depending on `+"`start_from_oldest`"+` every time the input connects.
+
+== Ordering
+
+Messages of a partition can be processed in parallel, up to a limit determined by the field `+"`checkpoint_limit`"+`. If strict ordered processing is required then this value must be set to 1.
+
+== Metadata
+
+This input adds the following metadata fields to each message:
+
+`+"```text"+`
+- eventhub_partition_id
+- eventhub_offset
+- eventhub_sequence_number
+- eventhub_enqueued_time_unix
+- eventhub_partition_key
+- All application properties of the event
+`+"```"+`
+
+You can access these metadata fields using xref:configuration:interpolation.adoc#bloblang-queries[function interpolation].`).
+		Fields(
+			service.NewStringField(ehFieldConnectionString).
+				Description("The connection string of the Event Hubs namespace or event hub, which must contain a shared access key.").
+				Example("Endpoint=sb://example.servicebus.windows.net/;SharedAccessKeyName=RootManageSharedAccessKey;SharedAccessKey=...").
+				Secret(),
+			service.NewStringField(ehFieldEventHub).
+				Description("The event hub to consume from, which can be omitted when the connection string contains an `EntityPath`.").
+				Default(""),
+			service.NewStringField(ehiFieldConsumerGroup).
+				Description("The consumer group to consume as.").
+				Default("$Default"),
+			service.NewBoolField(ehiFieldStartFromOldest).
+				Description("Whether to consume from the oldest available event of a partition when it has no checkpoint, otherwise consumption begins from the latest event.").
+				Default(true),
+			service.NewObjectField(ehiFieldCheckpointStore,
+				service.NewStringField(ehiFieldCheckpointCont).
+					Description("The blob storage container to store partition ownership and checkpoints within. When empty checkpointing and load balancing are disabled.").
+					Default(""),
+				service.NewStringField(bscFieldStorageAccount).
+					Description("The storage account to access. This field is ignored if `"+bscFieldStorageConnectionString+"` is set.").
+					Default(""),
+				service.NewStringField(bscFieldStorageAccessKey).
+					Description("The storage account access key. This field is ignored if `"+bscFieldStorageConnectionString+"` is set.").
+					Default("").
+					Secret(),
+				service.NewStringField(bscFieldStorageConnectionString).
+					Description("A storage account connection string. This field is required if `"+bscFieldStorageAccount+"` and `"+bscFieldStorageAccessKey+"` / `"+bscFieldStorageSASToken+"` are not set.").
+					Default("").
+					Secret(),
+				service.NewStringField(bscFieldStorageSASToken).
+					Description("The storage account SAS token. This field is ignored if `"+bscFieldStorageConnectionString+"` or `"+bscFieldStorageAccessKey+"` are set.").
+					Default("").
+					Secret(),
+			).
+				Description("An Azure Blob Storage container used for checkpointing consumed positions and for balancing partitions across consumers."),
+			service.NewIntField(ehiFieldCheckpointLimit).
+				Description("The maximum number of messages of a partition that can be in flight at a given time. Any given position will not be committed unless all messages before it are acknowledged in order to preserve at least once delivery guarantees.").
+				Default(1024),
+			service.NewDurationField(ehiFieldCommitPeriod).
+				Description("The period of time between each checkpoint of acknowledged positions.").
+				Default("5s"),
+			service.NewDurationField(ehiFieldRebalancePeriod).
+				Description("The period of time between each renewal of partition ownerships and attempt to balance partitions across consumers.").
+				Default("10s").
+				Advanced(),
+			service.NewDurationField(ehiFieldOwnershipExpiry).
+				Description("The period of time after which a consumer that has failed to renew a partition ownership is assumed to be inactive and its partitions are claimed by others.").
+				Default("1m").
+				Advanced(),
+			service.NewIntField(ehiFieldCredit).
+				Description("The maximum number of events that can be prefetched from each partition.").
+				Default(300).
+				Advanced(),
+			service.NewAutoRetryNacksToggleField(),
+		)
+}
+
+func init() {
+	err := service.RegisterInput("azure_event_hubs", ehiSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Input, error) {
+			pConf, err := ehiConfigFromParsed(conf)
+			if err != nil {
+				return nil, err
+			}
+			r, err := newEventHubsReader(pConf, mgr)
+			if err != nil {
+				return nil, err
+			}
+			return service.AutoRetryNacksToggled(conf, r)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type ehAsyncMessage struct {
+	msg   *service.Message
+	ackFn service.AckFunc
+}
+
+type eventHubsReader struct {
+	conf    ehiConfig
+	ownerID string
+	log     *service.Logger
+
+	cMut     sync.Mutex
+	conn     *amqp.Conn
+	session  *amqp.Session
+	msgChan  chan ehAsyncMessage
+	stopFn   context.CancelFunc
+	doneChan chan struct{}
+}
+
+func newEventHubsReader(conf ehiConfig, mgr *service.Resources) (*eventHubsReader, error) {
+	u4, err := uuid.NewV4()
+	if err != nil {
+		return nil, err
+	}
+	return &eventHubsReader{
+		conf:    conf,
+		ownerID: u4.String(),
+		log:     mgr.Logger(),
+	}, nil
+}
+
+func (e *eventHubsReader) Connect(ctx context.Context) error {
+	e.cMut.Lock()
+	defer e.cMut.Unlock()
+
+	if e.msgChan != nil {
+		return nil
+	}
+
+	conn, err := e.conf.Details.dial(ctx)
+	if err != nil {
+		return err
+	}
+	session, err := conn.NewSession(ctx, nil)
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+
+	partitionIDs, err := ehPartitionIDs(ctx, session, e.conf.EventHub)
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+
+	runCtx, stopFn := context.WithCancel(context.Background())
+	msgChan, doneChan := make(chan ehAsyncMessage), make(chan struct{})
+	e.conn, e.session, e.stopFn = conn, session, stopFn
+	e.msgChan, e.doneChan = msgChan, doneChan
+
+	pc := &ehPartitionConsumer{
+		reader:   e,
+		session:  session,
+		msgChan:  msgChan,
+		connLost: stopFn,
+	}
+	go func() {
+		defer func() {
+			close(msgChan)
+			close(doneChan)
+		}()
+		if e.conf.store != nil {
+			e.runBalanced(runCtx, pc, partitionIDs)
+		} else {
+			e.runAll(runCtx, pc, partitionIDs)
+		}
+	}()
+	return nil
+}
+
+// ehPartitionConsumer contains the connection state shared by the consumers
+// of all partitions.
+type ehPartitionConsumer struct {
+	reader   *eventHubsReader
+	session  *amqp.Session
+	msgChan  chan<- ehAsyncMessage
+	connLost func()
+}
+
+func (e *eventHubsReader) runAll(ctx context.Context, pc *ehPartitionConsumer, partitionIDs []string) {
+	var wg sync.WaitGroup
+	for _, id := range partitionIDs {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			pc.run(ctx, id, nil)
+		}(id)
+	}
+	wg.Wait()
+}
+
+func (e *eventHubsReader) runBalanced(ctx context.Context, pc *ehPartitionConsumer, partitionIDs []string) {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	consumers := map[string]context.CancelFunc{}
+	defer func() {
+		for _, stop := range consumers {
+			stop()
+		}
+	}()
+
+	store := e.conf.store
+	for {
+		owned := map[string]struct{}{}
+		if ownerships, err := store.ListOwnerships(ctx); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			e.log.Errorf("Failed to list partition ownerships: %v", err)
+			// Without renewals our ownerships will expire, and therefore we
+			// retain consumers until the next successful round.
+			for id := range consumers {
+				owned[id] = struct{}{}
+			}
+		} else {
+			plan := ehPlanBalance(partitionIDs, ownerships, e.ownerID, time.Now(), e.conf.OwnershipExpiry)
+			claims := plan.Renew
+			if plan.Claim != nil {
+				claims = append(claims, *plan.Claim)
+			}
+			for _, o := range claims {
+				previousOwner := o.OwnerID
+				o.OwnerID = e.ownerID
+				if _, err := store.ClaimOwnership(ctx, o); err != nil {
+					if ctx.Err() != nil {
+						return
+					}
+					if !errors.Is(err, errEHOwnershipNotClaimed) {
+						e.log.Errorf("Failed to claim partition '%v': %v", o.PartitionID, err)
+					}
+					continue
+				}
+				if previousOwner != e.ownerID {
+					e.log.Debugf("Claimed partition '%v' as consumer '%v'", o.PartitionID, e.ownerID)
+				}
+				owned[o.PartitionID] = struct{}{}
+			}
+		}
+
+		for id, stop := range consumers {
+			if _, exists := owned[id]; !exists {
+				e.log.Debugf("Releasing partition '%v' as consumer '%v'", id, e.ownerID)
+				stop()
+				delete(consumers, id)
+			}
+		}
+
+		var checkpoints map[string]ehCheckpoint
+		for id := range owned {
+			if _, exists := consumers[id]; exists {
+				continue
+			}
+			if checkpoints == nil {
+				var err error
+				if checkpoints, err = store.ListCheckpoints(ctx); err != nil {
+					if ctx.Err() != nil {
+						return
+					}
+					e.log.Errorf("Failed to list partition checkpoints: %v", err)
+					break
+				}
+			}
+
+			var startFrom *ehCheckpoint
+			if cp, exists := checkpoints[id]; exists {
+				startFrom = &cp
+			}
+
+			partCtx, stop := context.WithCancel(ctx)
+			consumers[id] = stop
+			wg.Add(1)
+			go func(id string) {
+				defer wg.Done()
+				pc.run(partCtx, id, startFrom)
+			}(id)
+		}
+
+		select {
+		case <-time.After(e.conf.RebalancePeriod):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// ehSelectorFilter returns a filter expression that selects events following
+// a checkpoint, or from the start or end of a partition.
+func ehSelectorFilter(startFrom *ehCheckpoint, startFromOldest bool) string {
+	if startFrom != nil {
+		return fmt.Sprintf("amqp.annotation.%v > '%v'", ehiAnnotationOffset, startFrom.Offset)
+	}
+	if startFromOldest {
+		return "amqp.annotation." + ehiAnnotationOffset + " > '-1'"
+	}
+	return "amqp.annotation." + ehiAnnotationOffset + " > '@latest'"
+}
+
+func (pc *ehPartitionConsumer) run(ctx context.Context, partitionID string, startFrom *ehCheckpoint) {
+	e := pc.reader
+	e.log.Debugf("Consuming partition '%v' as consumer '%v'", partitionID, e.ownerID)
+
+	address := fmt.Sprintf("%v/ConsumerGroups/%v/Partitions/%v", e.conf.EventHub, e.conf.ConsumerGroup, partitionID)
+	checkpointer := checkpoint.NewCapped[ehCheckpoint](int64(e.conf.CheckpointLimit))
+
+	var committedMut sync.Mutex
+	var toCommit, lastCommitted *ehCheckpoint
+	commit := func(ctx context.Context) {
+		committedMut.Lock()
+		cp := toCommit
+		committedMut.Unlock()
+		if cp == nil || cp == lastCommitted {
+			return
+		}
+		if err := e.conf.store.SetCheckpoint(ctx, partitionID, *cp); err != nil {
+			e.log.Errorf("Failed to store checkpoint for partition '%v': %v", partitionID, err)
+			return
+		}
+		lastCommitted = cp
+	}
+	if e.conf.store != nil {
+		commitDone := make(chan struct{})
+		go func() {
+			defer close(commitDone)
+			commitTicker := time.NewTicker(e.conf.CommitPeriod)
+			defer commitTicker.Stop()
+			for {
+				select {
+				case <-commitTicker.C:
+					commit(ctx)
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+		defer func() {
+			<-commitDone
+			commitCtx, done := context.WithTimeout(context.Background(), time.Second*10)
+			commit(commitCtx)
+			done()
+		}()
+	}
+
+	settled := amqp.SenderSettleModeSettled
+	for {
+		receiver, err := pc.session.NewReceiver(ctx, address, &amqp.ReceiverOptions{
+			Credit:                    int32(e.conf.Credit),
+			Filters:                   []amqp.LinkFilter{amqp.NewSelectorFilter(ehSelectorFilter(startFrom, e.conf.StartFromOldest))},
+			RequestedSenderSettleMode: &settled,
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			if ehIsConnectionErr(err) {
+				e.log.Errorf("Lost connection due to: %v", err)
+				pc.connLost()
+				return
+			}
+			e.log.Errorf("Failed to open receiver for partition '%v': %v", partitionID, err)
+			select {
+			case <-time.After(time.Second):
+				continue
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		for {
+			amqpMsg, err := receiver.Receive(ctx, nil)
+			if err != nil {
+				if ctx.Err() == nil {
+					if ehIsConnectionErr(err) {
+						e.log.Errorf("Lost connection due to: %v", err)
+						pc.connLost()
+					} else {
+						e.log.Errorf("Failed to receive from partition '%v': %v", partitionID, err)
+					}
+				}
+				break
+			}
+
+			msg, cp := ehMessageFromAMQP(partitionID, amqpMsg)
+			startFrom = &cp
+
+			resolveFn, err := checkpointer.Track(ctx, cp, 1)
+			if err != nil {
+				break
+			}
+
+			select {
+			case pc.msgChan <- ehAsyncMessage{
+				msg: msg,
+				ackFn: func(ctx context.Context, res error) error {
+					if highest := resolveFn(); highest != nil {
+						committedMut.Lock()
+						toCommit = highest
+						committedMut.Unlock()
+					}
+					return nil
+				},
+			}:
+			case <-ctx.Done():
+			}
+			if ctx.Err() != nil {
+				break
+			}
+		}
+
+		closeCtx, done := context.WithTimeout(context.Background(), time.Second*5)
+		_ = receiver.Close(closeCtx)
+		done()
+
+		select {
+		case <-time.After(time.Second):
+		case <-ctx.Done():
+			e.log.Debugf("Closing partition '%v' as consumer '%v'", partitionID, e.ownerID)
+			return
+		}
+	}
+}
+
+func ehIsConnectionErr(err error) bool {
+	var connErr *amqp.ConnError
+	var sessErr *amqp.SessionError
+	return errors.As(err, &connErr) || errors.As(err, &sessErr)
+}
+
+func ehMessageFromAMQP(partitionID string, amqpMsg *amqp.Message) (*service.Message, ehCheckpoint) {
+	var part *service.Message
+	if data := amqpMsg.GetData(); data != nil {
+		part = service.NewMessage(data)
+	} else if value, ok := amqpMsg.Value.(string); ok {
+		part = service.NewMessage([]byte(value))
+	} else {
+		part = service.NewMessage(nil)
+	}
+
+	for k, v := range amqpMsg.ApplicationProperties {
+		part.MetaSetMut(k, v)
+	}
+
+	var cp ehCheckpoint
+	part.MetaSetMut("eventhub_partition_id", partitionID)
+	if offset, ok := amqpMsg.Annotations[ehiAnnotationOffset].(string); ok {
+		cp.Offset = offset
+		part.MetaSetMut("eventhub_offset", offset)
+	}
+	if seq, ok := amqpMsg.Annotations[ehiAnnotationSequence].(int64); ok {
+		cp.SequenceNumber = seq
+		part.MetaSetMut("eventhub_sequence_number", seq)
+	}
+	if enqueued, ok := amqpMsg.Annotations[ehiAnnotationEnqueued].(time.Time); ok {
+		part.MetaSetMut("eventhub_enqueued_time_unix", enqueued.Unix())
+	}
+	if key, ok := amqpMsg.Annotations[ehiAnnotationPartitionKy].(string); ok {
+		part.MetaSetMut("eventhub_partition_key", key)
+	}
+	return part, cp
+}
+
+func (e *eventHubsReader) Read(ctx context.Context) (*service.Message, service.AckFunc, error) {
+	e.cMut.Lock()
+	msgChan := e.msgChan
+	e.cMut.Unlock()
+
+	if msgChan == nil {
+		return nil, nil, service.ErrNotConnected
+	}
+
+	select {
+	case m, open := <-msgChan:
+		if !open {
+			e.cMut.Lock()
+			if e.msgChan == msgChan {
+				_ = e.disconnect(ctx)
+			}
+			e.cMut.Unlock()
+			return nil, nil, service.ErrNotConnected
+		}
+		return m.msg, m.ackFn, nil
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+}
+
+// disconnect closes the current connection once all partition consumers have
+// stopped, the connection mutex must be held by the caller.
+func (e *eventHubsReader) disconnect(ctx context.Context) error {
+	if e.stopFn == nil {
+		return nil
+	}
+	e.stopFn()
+
+	select {
+	case <-e.doneChan:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	if err := e.session.Close(ctx); err != nil {
+		e.log.Debugf("Failed to cleanly close session: %v", err)
+	}
+	err := e.conn.Close()
+	e.conn, e.session, e.stopFn, e.msgChan, e.doneChan = nil, nil, nil, nil, nil
+	return err
+}
+
+func (e *eventHubsReader) Close(ctx context.Context) error {
+	e.cMut.Lock()
+	defer e.cMut.Unlock()
+	return e.disconnect(ctx)
+}
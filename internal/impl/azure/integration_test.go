@@ -274,6 +274,33 @@ input:
 			integration.StreamTestOptVarSet("VAR2", "UseDevelopmentStorage=true;"),
 		)
 	})
+
+	t.Run("blob_storage_cache", func(t *testing.T) {
+		template := `
+cache_resources:
+  - label: testcache
+    azure_blob_storage:
+      container: $VAR1-$ID
+      storage_connection_string: $VAR2
+`
+		integration.CacheTests(
+			integration.CacheTestOpenClose(),
+			integration.CacheTestMissingKey(),
+			integration.CacheTestDoubleAdd(),
+			integration.CacheTestDelete(),
+			integration.CacheTestGetAndSet(1),
+		).Run(
+			t, template,
+			integration.CacheTestOptVarSet("VAR1", dummyContainer),
+			integration.CacheTestOptVarSet("VAR2", connString),
+			integration.CacheTestOptPreTest(func(t testing.TB, ctx context.Context, vars *integration.CacheTestConfigVars) {
+				client, err := azblob.NewClientFromConnectionString(connString, nil)
+				require.NoError(t, err)
+				_, err = client.CreateContainer(ctx, dummyContainer+"-"+vars.ID, nil)
+				require.NoError(t, err)
+			}),
+		)
+	})
 }
 
 func TestIntegrationCosmosDB(t *testing.T) {
@@ -0,0 +1,359 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package azure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/Azure/go-amqp"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	// Event Hubs Output Fields
+	ehoFieldPartitionKey = "partition_key"
+	ehoFieldPartitionID  = "partition_id"
+	ehoFieldMetadata     = "metadata"
+	ehoFieldBatching     = "batching"
+
+	// The message format used by event hubs for a batch of encoded messages.
+	ehBatchMessageFormat uint32 = 0x80013700
+)
+
+type ehoConfig struct {
+	Details      ehConnectionDetails
+	EventHub     string
+	PartitionKey *service.InterpolatedString
+	PartitionID  *service.InterpolatedString
+	MetaFilter   *service.MetadataExcludeFilter
+}
+
+func ehoConfigFromParsed(pConf *service.ParsedConfig) (conf ehoConfig, err error) {
+	var connStr string
+	if connStr, err = pConf.FieldString(ehFieldConnectionString); err != nil {
+		return
+	}
+	if conf.Details, err = parseEventHubsConnectionString(connStr); err != nil {
+		return
+	}
+	var hub string
+	if hub, err = pConf.FieldString(ehFieldEventHub); err != nil {
+		return
+	}
+	if conf.EventHub, err = conf.Details.eventHubName(hub); err != nil {
+		return
+	}
+	if pConf.Contains(ehoFieldPartitionKey) {
+		if conf.PartitionKey, err = pConf.FieldInterpolatedString(ehoFieldPartitionKey); err != nil {
+			return
+		}
+	}
+	if pConf.Contains(ehoFieldPartitionID) {
+		if conf.PartitionID, err = pConf.FieldInterpolatedString(ehoFieldPartitionID); err != nil {
+			return
+		}
+	}
+	if conf.MetaFilter, err = pConf.FieldMetadataExcludeFilter(ehoFieldMetadata); err != nil {
+		return
+	}
+	return
+}
+
+func ehoSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Version("4.45.0").
+		Categories("Services", "Azure").
+		Summary("Sends events to an Azure Event Hub.").
+		Description(`
+Connects to an Event Hubs namespace with AMQP using a shared access key from the provided connection string. xref:configuration:metadata.adoc[Metadata] from messages are sent as application properties of events.
+
+== Partitioning
+
+By default events are distributed across partitions by the event hub. When `+"`partition_key`"+` is set events sharing a key are sent to the same partition, and when `+"`partition_id`"+` is set events are sent directly to the given partition. Only one of these fields can be set.
+
+== Batching
+
+Messages of a batch that share the same partition key or partition ID are sent to the event hub as a single batched event, which greatly improves throughput. The total size of a batched event must not exceed the maximum event size of your Event Hubs tier (1MB for standard namespaces) and therefore it is recommended to use a `+"`byte_size`"+` when configuring a batching policy.`).
+		Fields(
+			service.NewStringField(ehFieldConnectionString).
+				Description("The connection string of the Event Hubs namespace or event hub, which must contain a shared access key.").
+				Example("Endpoint=sb://example.servicebus.windows.net/;SharedAccessKeyName=RootManageSharedAccessKey;SharedAccessKey=...").
+				Secret(),
+			service.NewStringField(ehFieldEventHub).
+				Description("The event hub to send to, which can be omitted when the connection string contains an `EntityPath`.").
+				Default(""),
+			service.NewInterpolatedStringField(ehoFieldPartitionKey).
+				Description("An optional key used to route events to partitions, where events sharing a key are sent to the same partition.").
+				Example(`${! meta("kafka_key") }`).
+				Optional(),
+			service.NewInterpolatedStringField(ehoFieldPartitionID).
+				Description("An optional partition to send events to directly.").
+				Example("0").
+				Optional().
+				Advanced(),
+			service.NewMetadataExcludeFilterField(ehoFieldMetadata).
+				Description("Specify criteria for which metadata values are sent as application properties, all are sent by default.").
+				Optional(),
+			service.NewOutputMaxInFlightField(),
+			service.NewBatchPolicyField(ehoFieldBatching),
+		).
+		LintRule(`root = if this.partition_key.or("") != "" && this.partition_id.or("") != "" { [ "only one of partition_key and partition_id can be set" ] }`)
+}
+
+func init() {
+	err := service.RegisterBatchOutput("azure_event_hubs", ehoSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPol service.BatchPolicy, maxInFlight int, err error) {
+			var pConf ehoConfig
+			if pConf, err = ehoConfigFromParsed(conf); err != nil {
+				return
+			}
+			if maxInFlight, err = conf.FieldMaxInFlight(); err != nil {
+				return
+			}
+			if batchPol, err = conf.FieldBatchPolicy(ehoFieldBatching); err != nil {
+				return
+			}
+			out = newEventHubsWriter(pConf, mgr)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type eventHubsWriter struct {
+	conf ehoConfig
+	log  *service.Logger
+
+	cMut    sync.RWMutex
+	conn    *amqp.Conn
+	session *amqp.Session
+	senders map[string]*amqp.Sender
+}
+
+func newEventHubsWriter(conf ehoConfig, mgr *service.Resources) *eventHubsWriter {
+	return &eventHubsWriter{
+		conf: conf,
+		log:  mgr.Logger(),
+	}
+}
+
+func (e *eventHubsWriter) Connect(ctx context.Context) error {
+	e.cMut.Lock()
+	defer e.cMut.Unlock()
+
+	if e.conn != nil {
+		return nil
+	}
+
+	conn, err := e.conf.Details.dial(ctx)
+	if err != nil {
+		return err
+	}
+	session, err := conn.NewSession(ctx, nil)
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+
+	e.conn, e.session = conn, session
+	e.senders = map[string]*amqp.Sender{}
+	return nil
+}
+
+func (e *eventHubsWriter) getSender(ctx context.Context, target string) (*amqp.Sender, error) {
+	e.cMut.RLock()
+	sender, exists := e.senders[target]
+	session := e.session
+	e.cMut.RUnlock()
+	if exists {
+		return sender, nil
+	}
+	if session == nil {
+		return nil, service.ErrNotConnected
+	}
+
+	e.cMut.Lock()
+	defer e.cMut.Unlock()
+	if sender, exists = e.senders[target]; exists {
+		return sender, nil
+	}
+
+	sender, err := session.NewSender(ctx, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	e.senders[target] = sender
+	return sender, nil
+}
+
+// ehSendGroup is a subset of a batch that shares a partition key or ID.
+type ehSendGroup struct {
+	partitionKey string
+	partitionID  string
+	indexes      []int
+	messages     []*amqp.Message
+}
+
+func (e *eventHubsWriter) groupBatch(batch service.MessageBatch) ([]*ehSendGroup, error) {
+	var keyExec, idExec *service.MessageBatchInterpolationExecutor
+	if e.conf.PartitionKey != nil {
+		keyExec = batch.InterpolationExecutor(e.conf.PartitionKey)
+	}
+	if e.conf.PartitionID != nil {
+		idExec = batch.InterpolationExecutor(e.conf.PartitionID)
+	}
+
+	var groups []*ehSendGroup
+	groupsByKey := map[[2]string]*ehSendGroup{}
+	for i, msg := range batch {
+		var key, id string
+		var err error
+		if keyExec != nil {
+			if key, err = keyExec.TryString(i); err != nil {
+				return nil, fmt.Errorf("partition key interpolation: %w", err)
+			}
+		}
+		if idExec != nil {
+			if id, err = idExec.TryString(i); err != nil {
+				return nil, fmt.Errorf("partition id interpolation: %w", err)
+			}
+		}
+
+		data, err := msg.AsBytes()
+		if err != nil {
+			return nil, err
+		}
+		amqpMsg := &amqp.Message{Data: [][]byte{data}}
+		_ = e.conf.MetaFilter.WalkMut(msg, func(k string, v any) error {
+			if amqpMsg.ApplicationProperties == nil {
+				amqpMsg.ApplicationProperties = map[string]any{}
+			}
+			amqpMsg.ApplicationProperties[k] = v
+			return nil
+		})
+		if key != "" {
+			amqpMsg.Annotations = amqp.Annotations{ehiAnnotationPartitionKy: key}
+		}
+
+		g, exists := groupsByKey[[2]string{key, id}]
+		if !exists {
+			g = &ehSendGroup{partitionKey: key, partitionID: id}
+			groupsByKey[[2]string{key, id}] = g
+			groups = append(groups, g)
+		}
+		g.indexes = append(g.indexes, i)
+		g.messages = append(g.messages, amqpMsg)
+	}
+	return groups, nil
+}
+
+// envelope returns the message to send for a group, which for groups of more
+// than one message is a batch of encoded messages.
+func (g *ehSendGroup) envelope() (*amqp.Message, error) {
+	if len(g.messages) == 1 {
+		return g.messages[0], nil
+	}
+	env := &amqp.Message{
+		Format: ehBatchMessageFormat,
+		Data:   make([][]byte, 0, len(g.messages)),
+	}
+	if g.partitionKey != "" {
+		env.Annotations = amqp.Annotations{ehiAnnotationPartitionKy: g.partitionKey}
+	}
+	for _, m := range g.messages {
+		b, err := m.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		env.Data = append(env.Data, b)
+	}
+	return env, nil
+}
+
+func (e *eventHubsWriter) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	groups, err := e.groupBatch(batch)
+	if err != nil {
+		return err
+	}
+
+	var batchErr *service.BatchError
+	for _, g := range groups {
+		target := e.conf.EventHub
+		if g.partitionID != "" {
+			target += "/Partitions/" + g.partitionID
+		}
+
+		env, err := g.envelope()
+		if err == nil {
+			var sender *amqp.Sender
+			if sender, err = e.getSender(ctx, target); err == nil {
+				err = sender.Send(ctx, env, nil)
+			}
+		}
+		if err != nil {
+			if errors.Is(err, service.ErrNotConnected) {
+				return err
+			}
+			if ehIsConnectionErr(err) {
+				e.log.Errorf("Lost connection due to: %v", err)
+				e.disconnect(ctx)
+				return service.ErrNotConnected
+			}
+			if batchErr == nil {
+				batchErr = service.NewBatchError(batch, err)
+			}
+			for _, i := range g.indexes {
+				batchErr.Failed(i, err)
+			}
+		}
+	}
+	if batchErr != nil {
+		return batchErr
+	}
+	return nil
+}
+
+func (e *eventHubsWriter) disconnect(ctx context.Context) {
+	e.cMut.Lock()
+	defer e.cMut.Unlock()
+
+	for _, s := range e.senders {
+		_ = s.Close(ctx)
+	}
+	e.senders = nil
+	if e.session != nil {
+		_ = e.session.Close(ctx)
+		e.session = nil
+	}
+	if e.conn != nil {
+		if err := e.conn.Close(); err != nil {
+			e.log.Debugf("Failed to cleanly close connection: %v", err)
+		}
+		e.conn = nil
+	}
+}
+
+func (e *eventHubsWriter) Close(ctx context.Context) error {
+	e.disconnect(ctx)
+	return nil
+}
@@ -17,6 +17,7 @@ import (
 	"github.com/redpanda-data/benthos/v4/public/bloblang"
 	"github.com/redpanda-data/benthos/v4/public/service"
 
+	"github.com/redpanda-data/connect/v4/internal/embeddings"
 	"github.com/redpanda-data/connect/v4/internal/license"
 )
 
@@ -26,7 +27,7 @@ const (
 )
 
 func init() {
-	err := service.RegisterProcessor(
+	err := service.RegisterBatchProcessor(
 		"cohere_embeddings",
 		embeddingProcessorConfig(),
 		makeEmbeddingsProcessor,
@@ -43,6 +44,8 @@ func embeddingProcessorConfig() *service.ConfigSpec {
 		Description(`
 This processor sends text strings to the Cohere API, which generates vector embeddings. By default, the processor submits the entire payload of each message as a string, unless you use the `+"`"+oepFieldTextMapping+"`"+` configuration field to customize it.
 
+The texts of messages within a batch are combined into requests within the limits of the `+"`batching`"+` field, and these requests are sent concurrently. Messages that cannot be embedded are flagged as failed and can be handled with xref:configuration:error_handling.adoc[error handling patterns].
+
 To learn more about vector embeddings, see the https://docs.cohere.com/docs/embeddings[Cohere API documentation^].`).
 		Version("4.37.0").
 		Fields(
@@ -66,6 +69,7 @@ To learn more about vector embeddings, see the https://docs.cohere.com/docs/embe
 				Description("Specifies the type of input passed to the model.").
 				Default("search_document"),
 		).
+		Fields(embeddings.BatchingFields(96, 0)...).
 		Example(
 			"Store embedding vectors in Qdrant",
 			"Compute embeddings for some generated data and store it within xrefs:component:outputs/qdrant.adoc[Qdrant]",
@@ -88,7 +92,7 @@ output:
     vector_mapping: "root = this"`)
 }
 
-func makeEmbeddingsProcessor(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+func makeEmbeddingsProcessor(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchProcessor, error) {
 	if err := license.CheckRunningEnterprise(mgr); err != nil {
 		return nil, err
 	}
@@ -116,7 +120,11 @@ func makeEmbeddingsProcessor(conf *service.ParsedConfig, mgr *service.Resources)
 		}
 		et = t
 	}
-	return &embeddingsProcessor{b, t, et}, nil
+	batcher, err := embeddings.BatcherFromParsed(conf)
+	if err != nil {
+		return nil, err
+	}
+	return &embeddingsProcessor{b, t, et, batcher}, nil
 }
 
 type embeddingsProcessor struct {
@@ -124,29 +132,63 @@ type embeddingsProcessor struct {
 
 	text      *bloblang.Executor
 	inputType cohere.EmbedInputType
+	batcher   *embeddings.Batcher
 }
 
-func (p *embeddingsProcessor) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
-	var body cohere.EmbedRequest
-	body.Model = &p.model
-	body.InputType = &p.inputType
-	if p.text != nil {
-		s, err := msg.BloblangQuery(p.text)
+func (p *embeddingsProcessor) ProcessBatch(ctx context.Context, batch service.MessageBatch) ([]service.MessageBatch, error) {
+	batch = batch.Copy()
+
+	var texts []string
+	var indexes []int
+	for i, msg := range batch {
+		text, err := p.computeText(batch, i)
 		if err != nil {
-			return nil, fmt.Errorf("%s execution error: %w", oepFieldTextMapping, err)
+			msg.SetError(err)
+			continue
 		}
-		r, err := s.AsBytes()
-		if err != nil {
-			return nil, fmt.Errorf("%s extraction error: %w", oepFieldTextMapping, err)
+		texts = append(texts, text)
+		indexes = append(indexes, i)
+	}
+
+	vectors, errs := embeddings.Embed(ctx, p.batcher, texts, p.embed)
+	for j, i := range indexes {
+		if errs[j] != nil {
+			batch[i].SetError(errs[j])
+			continue
 		}
-		body.Texts = append(body.Texts, string(r))
-	} else {
-		b, err := msg.AsBytes()
+		data := make([]any, len(vectors[j]))
+		for k, f := range vectors[j] {
+			data[k] = f
+		}
+		batch[i].SetStructuredMut(data)
+	}
+	return []service.MessageBatch{batch}, nil
+}
+
+func (p *embeddingsProcessor) computeText(batch service.MessageBatch, i int) (string, error) {
+	if p.text == nil {
+		b, err := batch[i].AsBytes()
 		if err != nil {
-			return nil, err
+			return "", err
 		}
-		body.Texts = append(body.Texts, string(b))
+		return string(b), nil
+	}
+	s, err := batch.BloblangQuery(i, p.text)
+	if err != nil {
+		return "", fmt.Errorf("%s execution error: %w", oepFieldTextMapping, err)
 	}
+	r, err := s.AsBytes()
+	if err != nil {
+		return "", fmt.Errorf("%s extraction error: %w", oepFieldTextMapping, err)
+	}
+	return string(r), nil
+}
+
+func (p *embeddingsProcessor) embed(ctx context.Context, texts []string) ([][]float64, error) {
+	var body cohere.EmbedRequest
+	body.Model = &p.model
+	body.InputType = &p.inputType
+	body.Texts = texts
 	resp, err := p.client.Embed(ctx, &body)
 	if err != nil {
 		return nil, err
@@ -154,15 +196,5 @@ func (p *embeddingsProcessor) Process(ctx context.Context, msg *service.Message)
 	if resp.EmbeddingsFloats == nil {
 		return nil, errors.New("expected embeddings output")
 	}
-	if len(resp.EmbeddingsFloats.Embeddings) != 1 {
-		return nil, fmt.Errorf("expected a single embeddings response, got: %d", len(resp.EmbeddingsFloats.Embeddings))
-	}
-	embd := resp.EmbeddingsFloats.Embeddings[0]
-	data := make([]any, len(embd))
-	for i, f := range embd {
-		data[i] = f
-	}
-	msg = msg.Copy()
-	msg.SetStructuredMut(data)
-	return service.MessageBatch{msg}, nil
+	return resp.EmbeddingsFloats.Embeddings, nil
 }
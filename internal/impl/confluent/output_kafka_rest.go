@@ -0,0 +1,364 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package confluent
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	krpFieldURL       = "url"
+	krpFieldClusterID = "cluster_id"
+	krpFieldTopic     = "topic"
+	krpFieldKey       = "key"
+	krpFieldPartition = "partition"
+	krpFieldMetadata  = "metadata"
+	krpFieldTLS       = "tls"
+	krpFieldBatching  = "batching"
+)
+
+func kafkaRESTOutputConfig() *service.ConfigSpec {
+	spec := service.NewConfigSpec().
+		Beta().
+		Categories("Services").
+		Version("4.45.0").
+		Summary("Produces messages to Kafka topics through the v3 API of a Confluent REST Proxy or Confluent Cloud.").
+		Description(`
+This output is useful in environments where the ports of the Kafka protocol are blocked and only HTTPS is permitted. Records are produced with the streaming mode of the v3 records API, where the records of a batch that share a topic are sent within a single request, and the result of each record is reported individually, so that only the records that failed are retried.
+
+The `+"`"+krpFieldURL+"`"+` is the base URL of the v3 API excluding the `+"`/v3`"+` path segment, which for a self-hosted REST Proxy is typically its root, e.g. `+"`http://localhost:8082`"+`, and for Confluent Cloud is the REST endpoint of the cluster followed by `+"`/kafka`"+`, e.g. `+"`https://pkc-00000.us-east-1.aws.confluent.cloud:443/kafka`"+`. Confluent Cloud requires an API key and secret for the cluster, which are provided with `+"`basic_auth`"+`.
+
+Message contents and keys are sent as binary data, and therefore messages are written to topics unchanged. In order to produce records encoded with a schema the `+"`schema_registry_encode`"+` processor can be used beforehand.
+
+`+service.OutputPerformanceDocs(true, true)).
+		Fields(
+			service.NewURLField(krpFieldURL).
+				Description("The base URL of the v3 API.").
+				Example("http://localhost:8082").
+				Example("https://pkc-00000.us-east-1.aws.confluent.cloud:443/kafka"),
+			service.NewStringField(krpFieldClusterID).
+				Description("The ID of the Kafka cluster to produce to.").
+				Example("lkc-00000"),
+			service.NewInterpolatedStringField(krpFieldTopic).
+				Description("The topic to produce each message to."),
+			service.NewInterpolatedStringField(krpFieldKey).
+				Description("An optional key to set for each message.").
+				Optional(),
+			service.NewInterpolatedStringField(krpFieldPartition).
+				Description("An optional explicit partition to produce each message to, which must resolve to an integer. When omitted, records are partitioned by the cluster.").
+				Example(`${! meta("partition") }`).
+				Optional().
+				Advanced(),
+			service.NewMetadataFilterField(krpFieldMetadata).
+				Description("Determine which (if any) metadata values should be added to records as headers.").
+				Optional(),
+		)
+
+	for _, f := range service.NewHTTPRequestAuthSignerFields() {
+		spec = spec.Field(f)
+	}
+	return spec.Fields(
+		service.NewTLSToggledField(krpFieldTLS),
+		service.NewOutputMaxInFlightField(),
+		service.NewBatchPolicyField(krpFieldBatching),
+	).Example("Confluent Cloud", "Produce to a topic of a Confluent Cloud cluster over HTTPS.", `
+output:
+  kafka_rest:
+    url: https://pkc-00000.us-east-1.aws.confluent.cloud:443/kafka
+    cluster_id: lkc-00000
+    topic: orders
+    key: ${! this.order_id }
+    basic_auth:
+      enabled: true
+      username: ${CONFLUENT_API_KEY}
+      password: ${CONFLUENT_API_SECRET}
+    batching:
+      count: 100
+      period: 100ms
+`)
+}
+
+func init() {
+	err := service.RegisterBatchOutput("kafka_rest", kafkaRESTOutputConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPolicy service.BatchPolicy, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldMaxInFlight(); err != nil {
+				return
+			}
+			if batchPolicy, err = conf.FieldBatchPolicy(krpFieldBatching); err != nil {
+				return
+			}
+			out, err = newKafkaRESTOutputFromConfig(conf, mgr)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type kafkaRESTOutput struct {
+	baseURL    string
+	topic      *service.InterpolatedString
+	key        *service.InterpolatedString
+	partition  *service.InterpolatedString
+	metaFilter *service.MetadataFilter
+	reqSigner  func(f fs.FS, req *http.Request) error
+
+	client http.Client
+	mgr    *service.Resources
+}
+
+func newKafkaRESTOutputFromConfig(conf *service.ParsedConfig, mgr *service.Resources) (*kafkaRESTOutput, error) {
+	o := &kafkaRESTOutput{mgr: mgr}
+
+	urlStr, err := conf.FieldString(krpFieldURL)
+	if err != nil {
+		return nil, err
+	}
+	clusterID, err := conf.FieldString(krpFieldClusterID)
+	if err != nil {
+		return nil, err
+	}
+	if clusterID == "" {
+		return nil, errors.New("a cluster_id must be specified")
+	}
+	o.baseURL = strings.TrimSuffix(urlStr, "/") + "/v3/clusters/" + url.PathEscape(clusterID) + "/topics/"
+
+	if o.topic, err = conf.FieldInterpolatedString(krpFieldTopic); err != nil {
+		return nil, err
+	}
+	if conf.Contains(krpFieldKey) {
+		if o.key, err = conf.FieldInterpolatedString(krpFieldKey); err != nil {
+			return nil, err
+		}
+	}
+	if conf.Contains(krpFieldPartition) {
+		if o.partition, err = conf.FieldInterpolatedString(krpFieldPartition); err != nil {
+			return nil, err
+		}
+	}
+	if conf.Contains(krpFieldMetadata) {
+		if o.metaFilter, err = conf.FieldMetadataFilter(krpFieldMetadata); err != nil {
+			return nil, err
+		}
+	}
+	if o.reqSigner, err = conf.HTTPRequestAuthSignerFromParsed(); err != nil {
+		return nil, err
+	}
+
+	var tlsConf *tls.Config
+	var tlsEnabled bool
+	if tlsConf, tlsEnabled, err = conf.FieldTLSToggled(krpFieldTLS); err != nil {
+		return nil, err
+	}
+	if tlsEnabled && tlsConf != nil {
+		if c, ok := http.DefaultTransport.(*http.Transport); ok {
+			cloned := c.Clone()
+			cloned.TLSClientConfig = tlsConf
+			o.client.Transport = cloned
+		} else {
+			o.client.Transport = &http.Transport{
+				TLSClientConfig: tlsConf,
+			}
+		}
+	}
+	return o, nil
+}
+
+type kafkaRESTData struct {
+	Type string `json:"type"`
+	Data string `json:"data"`
+}
+
+type kafkaRESTHeader struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type kafkaRESTRecord struct {
+	PartitionID *int              `json:"partition_id,omitempty"`
+	Headers     []kafkaRESTHeader `json:"headers,omitempty"`
+	Key         *kafkaRESTData    `json:"key,omitempty"`
+	Value       *kafkaRESTData    `json:"value"`
+}
+
+type kafkaRESTResult struct {
+	ErrorCode int    `json:"error_code"`
+	Message   string `json:"message"`
+}
+
+func binaryData(b []byte) *kafkaRESTData {
+	return &kafkaRESTData{Type: "BINARY", Data: base64.StdEncoding.EncodeToString(b)}
+}
+
+func (o *kafkaRESTOutput) Connect(ctx context.Context) error {
+	return nil
+}
+
+func (o *kafkaRESTOutput) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	topicExec := batch.InterpolationExecutor(o.topic)
+	var keyExec, partitionExec *service.MessageBatchInterpolationExecutor
+	if o.key != nil {
+		keyExec = batch.InterpolationExecutor(o.key)
+	}
+	if o.partition != nil {
+		partitionExec = batch.InterpolationExecutor(o.partition)
+	}
+
+	// Records are grouped by topic as each request produces to a single
+	// topic, and the order of records within a topic is preserved.
+	var topics []string
+	indexes := map[string][]int{}
+	bodies := map[string]*bytes.Buffer{}
+	for i, msg := range batch {
+		topic, err := topicExec.TryString(i)
+		if err != nil {
+			return fmt.Errorf("topic interpolation error: %w", err)
+		}
+
+		content, err := msg.AsBytes()
+		if err != nil {
+			return err
+		}
+		record := kafkaRESTRecord{Value: binaryData(content)}
+		if keyExec != nil {
+			key, err := keyExec.TryBytes(i)
+			if err != nil {
+				return fmt.Errorf("key interpolation error: %w", err)
+			}
+			record.Key = binaryData(key)
+		}
+		if partitionExec != nil {
+			pStr, err := partitionExec.TryString(i)
+			if err != nil {
+				return fmt.Errorf("partition interpolation error: %w", err)
+			}
+			partition, err := strconv.Atoi(pStr)
+			if err != nil {
+				return fmt.Errorf("failed to parse partition %q: %w", pStr, err)
+			}
+			record.PartitionID = &partition
+		}
+		_ = o.metaFilter.Walk(msg, func(key, value string) error {
+			record.Headers = append(record.Headers, kafkaRESTHeader{
+				Name:  key,
+				Value: base64.StdEncoding.EncodeToString([]byte(value)),
+			})
+			return nil
+		})
+
+		body, exists := bodies[topic]
+		if !exists {
+			body = &bytes.Buffer{}
+			bodies[topic] = body
+			topics = append(topics, topic)
+		}
+		if err := json.NewEncoder(body).Encode(record); err != nil {
+			return err
+		}
+		indexes[topic] = append(indexes[topic], i)
+	}
+
+	var batchErr *service.BatchError
+	for _, topic := range topics {
+		results, err := o.produce(ctx, topic, bodies[topic])
+		for j, index := range indexes[topic] {
+			recordErr := err
+			if recordErr == nil {
+				if j >= len(results) {
+					recordErr = errors.New("no result was returned for the record")
+				} else if r := results[j]; r.ErrorCode < 200 || r.ErrorCode > 299 {
+					recordErr = fmt.Errorf("failed to produce record to topic %v: %v (%v)", topic, r.Message, r.ErrorCode)
+				}
+			}
+			if recordErr == nil {
+				continue
+			}
+			if batchErr == nil {
+				batchErr = service.NewBatchError(batch, recordErr)
+			}
+			batchErr.Failed(index, recordErr)
+		}
+	}
+	if batchErr != nil {
+		return batchErr
+	}
+	return nil
+}
+
+// produce sends the records of a body to a topic, and returns the result of
+// each record in the order they were sent.
+func (o *kafkaRESTOutput) produce(ctx context.Context, topic string, body *bytes.Buffer) ([]kafkaRESTResult, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.baseURL+url.PathEscape(topic)+"/records", body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	// Streaming mode, where each record has its own result, requires a
+	// chunked request.
+	req.TransferEncoding = []string{"chunked"}
+	if err := o.reqSigner(o.mgr.FS(), req); err != nil {
+		return nil, err
+	}
+
+	res, err := o.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		var result kafkaRESTResult
+		resBytes, _ := io.ReadAll(res.Body)
+		if err := json.Unmarshal(resBytes, &result); err != nil || result.Message == "" {
+			return nil, fmt.Errorf("request to produce to topic %v returned status %v: %s", topic, res.StatusCode, bytes.TrimSpace(resBytes))
+		}
+		return nil, fmt.Errorf("request to produce to topic %v returned status %v: %v (%v)", topic, res.StatusCode, result.Message, result.ErrorCode)
+	}
+
+	var results []kafkaRESTResult
+	dec := json.NewDecoder(res.Body)
+	for {
+		var result kafkaRESTResult
+		if err := dec.Decode(&result); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return results, fmt.Errorf("failed to parse produce response: %w", err)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func (o *kafkaRESTOutput) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,202 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package confluent
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+type testRESTProduced struct {
+	topic     string
+	key       string
+	value     string
+	partition *int
+	headers   map[string]string
+}
+
+// testRESTProxy emulates the streaming mode of the v3 records API, failing
+// records with the value "fail".
+func testRESTProxy(t *testing.T) (*httptest.Server, func() []testRESTProduced) {
+	t.Helper()
+
+	var mut sync.Mutex
+	var produced []testRESTProduced
+
+	decode := func(s string) string {
+		b, err := base64.StdEncoding.DecodeString(s)
+		require.NoError(t, err)
+		return string(b)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, _ := r.BasicAuth()
+		if user != "key" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error_code":40101,"message":"Unauthorized"}`))
+			return
+		}
+		assert.Equal(t, []string{"chunked"}, r.TransferEncoding)
+
+		topic, found := strings.CutPrefix(r.URL.Path, "/kafka/v3/clusters/lkc-1/topics/")
+		require.True(t, found, r.URL.Path)
+		topic = strings.TrimSuffix(topic, "/records")
+
+		dec := json.NewDecoder(r.Body)
+		enc := json.NewEncoder(w)
+		for dec.More() {
+			var rec kafkaRESTRecord
+			require.NoError(t, dec.Decode(&rec))
+
+			p := testRESTProduced{topic: topic, value: decode(rec.Value.Data), partition: rec.PartitionID}
+			if rec.Key != nil {
+				p.key = decode(rec.Key.Data)
+			}
+			for _, h := range rec.Headers {
+				if p.headers == nil {
+					p.headers = map[string]string{}
+				}
+				p.headers[h.Name] = decode(h.Value)
+			}
+
+			if p.value == "fail" {
+				require.NoError(t, enc.Encode(map[string]any{"error_code": 400, "message": "bad record"}))
+				continue
+			}
+			mut.Lock()
+			produced = append(produced, p)
+			mut.Unlock()
+			require.NoError(t, enc.Encode(map[string]any{"error_code": 200, "topic_name": topic, "offset": len(produced)}))
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, func() []testRESTProduced {
+		mut.Lock()
+		defer mut.Unlock()
+		return produced
+	}
+}
+
+func testKafkaRESTOutput(t *testing.T, conf string) *kafkaRESTOutput {
+	t.Helper()
+
+	pConf, err := kafkaRESTOutputConfig().ParseYAML(conf, nil)
+	require.NoError(t, err)
+
+	out, err := newKafkaRESTOutputFromConfig(pConf, service.MockResources())
+	require.NoError(t, err)
+	require.NoError(t, out.Connect(context.Background()))
+	return out
+}
+
+func TestKafkaRESTOutputProduce(t *testing.T) {
+	srv, produced := testRESTProxy(t)
+
+	out := testKafkaRESTOutput(t, fmt.Sprintf(`
+url: %v/kafka
+cluster_id: lkc-1
+topic: ${! meta("topic") }
+key: ${! json("id") }
+partition: ${! json("id") }
+metadata:
+  include_prefixes: [ h_ ]
+basic_auth:
+  enabled: true
+  username: key
+  password: secret
+`, srv.URL))
+
+	var batch service.MessageBatch
+	for i, topic := range []string{"foo", "bar", "foo"} {
+		msg := service.NewMessage([]byte(fmt.Sprintf(`{"id":%v}`, i)))
+		msg.MetaSetMut("topic", topic)
+		msg.MetaSetMut("h_source", "test")
+		msg.MetaSetMut("ignored", "nope")
+		batch = append(batch, msg)
+	}
+	require.NoError(t, out.WriteBatch(context.Background(), batch))
+
+	p0, p1, p2 := 0, 1, 2
+	headers := map[string]string{"h_source": "test"}
+	assert.Equal(t, []testRESTProduced{
+		{topic: "foo", key: "0", value: `{"id":0}`, partition: &p0, headers: headers},
+		{topic: "foo", key: "2", value: `{"id":2}`, partition: &p2, headers: headers},
+		{topic: "bar", key: "1", value: `{"id":1}`, partition: &p1, headers: headers},
+	}, produced())
+}
+
+func TestKafkaRESTOutputPartialFailure(t *testing.T) {
+	srv, produced := testRESTProxy(t)
+
+	out := testKafkaRESTOutput(t, fmt.Sprintf(`
+url: %v/kafka
+cluster_id: lkc-1
+topic: foo
+basic_auth:
+  enabled: true
+  username: key
+  password: secret
+`, srv.URL))
+
+	batch := service.MessageBatch{
+		service.NewMessage([]byte("first")),
+		service.NewMessage([]byte("fail")),
+		service.NewMessage([]byte("third")),
+	}
+	err := out.WriteBatch(context.Background(), batch)
+	require.Error(t, err)
+
+	var batchErr *service.BatchError
+	require.True(t, errors.As(err, &batchErr))
+
+	var failed []int
+	batchErr.WalkMessages(func(i int, _ *service.Message, err error) bool {
+		if err != nil {
+			failed = append(failed, i)
+			assert.Contains(t, err.Error(), "bad record")
+		}
+		return true
+	})
+	assert.Equal(t, []int{1}, failed)
+	assert.Len(t, produced(), 2)
+}
+
+func TestKafkaRESTOutputUnauthorized(t *testing.T) {
+	srv, _ := testRESTProxy(t)
+
+	out := testKafkaRESTOutput(t, fmt.Sprintf(`
+url: %v/kafka
+cluster_id: lkc-1
+topic: foo
+`, srv.URL))
+
+	err := out.WriteBatch(context.Background(), service.MessageBatch{service.NewMessage([]byte("hello"))})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "returned status 401: Unauthorized (40101)")
+}
@@ -0,0 +1,471 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package confluent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/linkedin/goavro/v2"
+	franz_sr "github.com/twmb/franz-go/pkg/sr"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+
+	"github.com/redpanda-data/connect/v4/internal/impl/confluent/sr"
+)
+
+const (
+	sraeFieldURL            = "url"
+	sraeFieldSubject        = "subject"
+	sraeFieldRefreshPeriod  = "refresh_period"
+	sraeFieldEvolution      = "evolution"
+	sraeFieldPolicy         = "policy"
+	sraeFieldMaxNewFields   = "max_new_fields"
+	sraeFieldTLS            = "tls"
+	sraePolicyReject        = "reject"
+	sraePolicyDrop          = "drop"
+	sraePolicyRegister      = "register"
+	sraeRegisterTimeout     = 10 * time.Second
+	sraeRequestTimeout      = 5 * time.Second
+	sraeMetricRegistrations = "schema_registry_avro_encode_registrations"
+)
+
+func schemaRegistryAvroEncoderConfig() *service.ConfigSpec {
+	spec := service.NewConfigSpec().
+		Beta().
+		Version("4.45.0").
+		Categories("Parsing", "Integration").
+		Summary("Converts JSON documents to Avro using the latest Avro schema of a subject from a Confluent Schema Registry service, optionally evolving and registering the schema when documents contain new fields.").
+		Description(`
+Documents are expected to be standard JSON objects, rather than Avro JSON, where union values are not wrapped with their type. Each document is encoded with the latest schema of the subject, which must be an Avro record, and is prefixed with the identifier of the schema in the format expected by Confluent serialisers.
+
+Top level fields of a document that are not present within the schema are handled according to the evolution ` + "`policy`" + `. With the ` + "`register`" + ` policy a new version of the schema is created that adds each new field as an optional field, with a type of a union of ` + "`null`" + ` and a type inferred from the value, and a default of ` + "`null`" + `. The evolved schema is checked for compatibility with the latest version of the subject by the schema registry, according to the compatibility level of the subject, and is only registered when it is compatible. Changes to existing fields and new fields within nested records are not detected, and documents that do not otherwise match the schema fail to encode.
+
+Messages that fail to encode remain unchanged, and the error can be caught using xref:configuration:error_handling.adoc[error handling methods].`).
+		Field(service.NewURLField(sraeFieldURL).Description("The base URL of the schema registry service.")).
+		Field(service.NewInterpolatedStringField(sraeFieldSubject).Description("The schema subject to derive schemas from.").
+			Example("foo").
+			Example(`${! meta("kafka_topic") }-value`)).
+		Field(service.NewDurationField(sraeFieldRefreshPeriod).
+			Description("The period after which the latest schema of a subject is obtained again from the schema registry.").
+			Default("10m")).
+		Field(service.NewObjectField(sraeFieldEvolution,
+			service.NewStringAnnotatedEnumField(sraeFieldPolicy, map[string]string{
+				sraePolicyReject:   "Documents containing fields that are not within the schema fail to encode.",
+				sraePolicyDrop:     "Fields that are not within the schema are removed before encoding.",
+				sraePolicyRegister: "A new version of the schema containing the fields is registered when it is compatible with the subject.",
+			}).
+				Description("The policy for fields that are not within the schema.").
+				Default(sraePolicyReject),
+			service.NewIntField(sraeFieldMaxNewFields).
+				Description("The maximum number of new fields that a single document may add to a schema with the `register` policy, documents adding more fail to encode. Set to `0` for no limit.").
+				Default(10),
+		).Description("Controls how documents containing fields that are not within the schema are handled."))
+
+	for _, f := range service.NewHTTPRequestAuthSignerFields() {
+		spec = spec.Field(f)
+	}
+
+	return spec.Field(service.NewTLSField(sraeFieldTLS)).
+		Example("Schema on Write", "Encode JSON documents as Avro for a Kafka topic, registering new versions of the schema as documents gain fields.", `
+pipeline:
+  processors:
+    - schema_registry_avro_encode:
+        url: http://localhost:8081
+        subject: events-value
+        evolution:
+          policy: register
+
+output:
+  kafka_franz:
+    seed_brokers: [ localhost:9092 ]
+    topic: events
+`)
+}
+
+func init() {
+	err := service.RegisterBatchProcessor(
+		"schema_registry_avro_encode", schemaRegistryAvroEncoderConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchProcessor, error) {
+			return newSchemaRegistryAvroEncoderFromConfig(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type avroSubjectSchema struct {
+	id        int
+	schema    franz_sr.Schema
+	record    map[string]any
+	fields    map[string]struct{}
+	codec     *goavro.Codec
+	updatedAt time.Time
+}
+
+type schemaRegistryAvroEncoder struct {
+	client        *sr.Client
+	subject       *service.InterpolatedString
+	refreshPeriod time.Duration
+	policy        string
+	maxNewFields  int
+
+	mRegistrations *service.MetricCounter
+
+	mut     sync.Mutex
+	schemas map[string]*avroSubjectSchema
+
+	logger *service.Logger
+	nowFn  func() time.Time
+}
+
+func newSchemaRegistryAvroEncoderFromConfig(conf *service.ParsedConfig, mgr *service.Resources) (*schemaRegistryAvroEncoder, error) {
+	s := &schemaRegistryAvroEncoder{
+		mRegistrations: mgr.Metrics().NewCounter(sraeMetricRegistrations),
+		schemas:        map[string]*avroSubjectSchema{},
+		logger:         mgr.Logger(),
+		nowFn:          time.Now,
+	}
+
+	urlStr, err := conf.FieldString(sraeFieldURL)
+	if err != nil {
+		return nil, err
+	}
+	if s.subject, err = conf.FieldInterpolatedString(sraeFieldSubject); err != nil {
+		return nil, err
+	}
+	if s.refreshPeriod, err = conf.FieldDuration(sraeFieldRefreshPeriod); err != nil {
+		return nil, err
+	}
+	if s.policy, err = conf.FieldString(sraeFieldEvolution, sraeFieldPolicy); err != nil {
+		return nil, err
+	}
+	if s.maxNewFields, err = conf.FieldInt(sraeFieldEvolution, sraeFieldMaxNewFields); err != nil {
+		return nil, err
+	}
+	authSigner, err := conf.HTTPRequestAuthSignerFromParsed()
+	if err != nil {
+		return nil, err
+	}
+	tlsConf, err := conf.FieldTLS(sraeFieldTLS)
+	if err != nil {
+		return nil, err
+	}
+	if s.client, err = sr.NewClient(urlStr, authSigner, tlsConf, mgr); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *schemaRegistryAvroEncoder) ProcessBatch(ctx context.Context, batch service.MessageBatch) ([]service.MessageBatch, error) {
+	batch = batch.Copy()
+	for i, msg := range batch {
+		subject, err := batch.TryInterpolatedString(i, s.subject)
+		if err != nil {
+			msg.SetError(fmt.Errorf("subject interpolation error: %w", err))
+			continue
+		}
+		if err := s.encode(ctx, subject, msg); err != nil {
+			msg.SetError(err)
+		}
+	}
+	return []service.MessageBatch{batch}, nil
+}
+
+func (s *schemaRegistryAvroEncoder) Close(ctx context.Context) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+func (s *schemaRegistryAvroEncoder) encode(ctx context.Context, subject string, msg *service.Message) error {
+	v, err := msg.AsStructuredMut()
+	if err != nil {
+		return fmt.Errorf("failed to parse document: %w", err)
+	}
+	doc, ok := v.(map[string]any)
+	if !ok {
+		return fmt.Errorf("expected document to be an object, got %T", v)
+	}
+
+	schema, err := s.getSchema(ctx, subject)
+	if err != nil {
+		return err
+	}
+
+	if unknown := unknownFields(schema, doc); len(unknown) > 0 {
+		switch s.policy {
+		case sraePolicyReject:
+			return fmt.Errorf("document contains fields that are not within the schema of subject %q: %v", subject, strings.Join(unknown, ", "))
+		case sraePolicyDrop:
+			for _, k := range unknown {
+				delete(doc, k)
+			}
+		case sraePolicyRegister:
+			if s.maxNewFields > 0 && len(unknown) > s.maxNewFields {
+				return fmt.Errorf("document adds %v fields to the schema of subject %q, exceeding the maximum of %v", len(unknown), subject, s.maxNewFields)
+			}
+			if schema, err = s.evolve(ctx, subject, doc); err != nil {
+				return err
+			}
+		}
+	}
+
+	docBytes, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+
+	native, _, err := schema.codec.NativeFromTextual(docBytes)
+	if err != nil {
+		return fmt.Errorf("failed to convert document to the schema of subject %q: %w", subject, err)
+	}
+	binary, err := schema.codec.BinaryFromNative(nil, native)
+	if err != nil {
+		return fmt.Errorf("failed to encode document with the schema of subject %q: %w", subject, err)
+	}
+
+	if binary, err = insertID(schema.id, binary); err != nil {
+		return err
+	}
+	msg.SetBytes(binary)
+	return nil
+}
+
+func unknownFields(schema *avroSubjectSchema, doc map[string]any) []string {
+	var unknown []string
+	for k := range doc {
+		if _, exists := schema.fields[k]; !exists {
+			unknown = append(unknown, k)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+func (s *schemaRegistryAvroEncoder) getSchema(ctx context.Context, subject string) (*avroSubjectSchema, error) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	if schema, exists := s.schemas[subject]; exists && s.nowFn().Sub(schema.updatedAt) < s.refreshPeriod {
+		return schema, nil
+	}
+	return s.fetchLocked(ctx, subject)
+}
+
+func (s *schemaRegistryAvroEncoder) fetchLocked(ctx context.Context, subject string) (*avroSubjectSchema, error) {
+	ctx, done := context.WithTimeout(ctx, sraeRequestTimeout)
+	defer done()
+
+	latest, err := s.client.GetSchemaBySubjectAndVersion(ctx, subject, nil, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to obtain latest schema of subject %q: %w", subject, err)
+	}
+	if latest.Type != franz_sr.TypeAvro {
+		return nil, fmt.Errorf("latest schema of subject %q is of type %v, expected avro", subject, latest.Type)
+	}
+
+	schema, err := s.newSubjectSchema(ctx, latest.ID, latest.Schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse latest schema of subject %q: %w", subject, err)
+	}
+	s.schemas[subject] = schema
+	return schema, nil
+}
+
+func (s *schemaRegistryAvroEncoder) newSubjectSchema(ctx context.Context, id int, schema franz_sr.Schema) (*avroSubjectSchema, error) {
+	var record map[string]any
+	if err := json.Unmarshal([]byte(schema.Schema), &record); err != nil {
+		return nil, err
+	}
+	if record["type"] != "record" {
+		return nil, errors.New("schema must be a record")
+	}
+	fieldsArr, _ := record["fields"].([]any)
+
+	fields := make(map[string]struct{}, len(fieldsArr))
+	for _, f := range fieldsArr {
+		if fObj, ok := f.(map[string]any); ok {
+			if name, ok := fObj["name"].(string); ok {
+				fields[name] = struct{}{}
+			}
+		}
+	}
+
+	schemaSpec, err := resolveAvroReferences(ctx, s.client, schema)
+	if err != nil {
+		return nil, err
+	}
+	codec, err := goavro.NewCodecForStandardJSONFull(schemaSpec)
+	if err != nil {
+		return nil, err
+	}
+
+	return &avroSubjectSchema{
+		id:        id,
+		schema:    schema,
+		record:    record,
+		fields:    fields,
+		codec:     codec,
+		updatedAt: s.nowFn(),
+	}, nil
+}
+
+var avroNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// evolve registers a new version of the schema of a subject containing the
+// fields of a document that it lacks.
+func (s *schemaRegistryAvroEncoder) evolve(ctx context.Context, subject string, doc map[string]any) (*avroSubjectSchema, error) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	ctx, done := context.WithTimeout(ctx, sraeRegisterTimeout)
+	defer done()
+
+	// Another processor may have already evolved the schema.
+	current, err := s.fetchLocked(ctx, subject)
+	if err != nil {
+		return nil, err
+	}
+	unknown := unknownFields(current, doc)
+	if len(unknown) == 0 {
+		return current, nil
+	}
+
+	recordName, _ := current.record["name"].(string)
+
+	evolved := make(map[string]any, len(current.record))
+	for k, v := range current.record {
+		evolved[k] = v
+	}
+	fields, _ := current.record["fields"].([]any)
+	fields = append([]any{}, fields...)
+	for _, k := range unknown {
+		if !avroNameRegexp.MatchString(k) {
+			return nil, fmt.Errorf("field %q is not a valid avro name", k)
+		}
+		t, err := inferAvroType(recordName+"_"+k, doc[k])
+		if err != nil {
+			return nil, fmt.Errorf("failed to infer type of field %q: %w", k, err)
+		}
+		fields = append(fields, map[string]any{
+			"name":    k,
+			"type":    []any{"null", t},
+			"default": nil,
+		})
+	}
+	evolved["fields"] = fields
+
+	evolvedBytes, err := json.Marshal(evolved)
+	if err != nil {
+		return nil, err
+	}
+	evolvedSchema := franz_sr.Schema{
+		Schema:     string(evolvedBytes),
+		Type:       franz_sr.TypeAvro,
+		References: current.schema.References,
+	}
+
+	compatible, reasons, err := s.client.CheckCompatibility(ctx, subject, evolvedSchema)
+	if err != nil {
+		return nil, err
+	}
+	if !compatible {
+		if len(reasons) == 0 {
+			reasons = []string{"no reason given"}
+		}
+		return nil, fmt.Errorf("schema evolved with fields %v is not compatible with subject %q: %v", strings.Join(unknown, ", "), subject, strings.Join(reasons, "; "))
+	}
+
+	id, err := s.client.CreateSchema(ctx, subject, evolvedSchema)
+	if err != nil {
+		return nil, err
+	}
+	s.mRegistrations.Incr(1)
+	s.logger.Infof("Registered new schema %v for subject %q with fields: %v", id, subject, strings.Join(unknown, ", "))
+
+	schema, err := s.newSubjectSchema(ctx, id, evolvedSchema)
+	if err != nil {
+		return nil, err
+	}
+	s.schemas[subject] = schema
+	return schema, nil
+}
+
+// inferAvroType returns an avro type for a JSON value, where records are named
+// after the path of the field that contains them.
+func inferAvroType(name string, v any) (any, error) {
+	switch t := v.(type) {
+	case nil, string:
+		return "string", nil
+	case bool:
+		return "boolean", nil
+	case json.Number:
+		if _, err := t.Int64(); err == nil {
+			return "long", nil
+		}
+		return "double", nil
+	case int, int32, int64, uint, uint32, uint64:
+		return "long", nil
+	case float32, float64:
+		return "double", nil
+	case []any:
+		var items any = "string"
+		for _, e := range t {
+			if e == nil {
+				continue
+			}
+			var err error
+			if items, err = inferAvroType(name+"_item", e); err != nil {
+				return nil, err
+			}
+			break
+		}
+		return map[string]any{"type": "array", "items": items}, nil
+	case map[string]any:
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		fields := make([]any, 0, len(keys))
+		for _, k := range keys {
+			if !avroNameRegexp.MatchString(k) {
+				return nil, fmt.Errorf("field %q is not a valid avro name", k)
+			}
+			ft, err := inferAvroType(name+"_"+k, t[k])
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, map[string]any{
+				"name":    k,
+				"type":    []any{"null", ft},
+				"default": nil,
+			})
+		}
+		return map[string]any{"type": "record", "name": name, "fields": fields}, nil
+	}
+	return nil, fmt.Errorf("unsupported value type %T", v)
+}
@@ -0,0 +1,282 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package confluent
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/linkedin/goavro/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+type avroEvolutionRegistry struct {
+	mut          sync.Mutex
+	schemas      []string
+	incompatible []string
+	checked      []string
+}
+
+func runAvroEvolutionRegistry(t testing.TB, initial string) (*avroEvolutionRegistry, string) {
+	t.Helper()
+
+	reg := &avroEvolutionRegistry{schemas: []string{initial}}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reg.mut.Lock()
+		defer reg.mut.Unlock()
+
+		var body struct {
+			Schema string `json:"schema"`
+		}
+		if r.Method == http.MethodPost {
+			b, _ := io.ReadAll(r.Body)
+			if err := json.Unmarshal(b, &body); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		writeJSON := func(v any) {
+			b, _ := json.Marshal(v)
+			_, _ = w.Write(b)
+		}
+		subjectSchema := func(version int) map[string]any {
+			return map[string]any{
+				"subject": "events",
+				"version": version,
+				"id":      version,
+				"schema":  reg.schemas[version-1],
+			}
+		}
+
+		path := r.URL.EscapedPath()
+		switch {
+		case r.Method == http.MethodGet && path == "/subjects/events/versions/latest":
+			writeJSON(subjectSchema(len(reg.schemas)))
+		case r.Method == http.MethodPost && path == "/compatibility/subjects/events/versions/latest":
+			reg.checked = append(reg.checked, body.Schema)
+			if len(reg.incompatible) > 0 {
+				writeJSON(map[string]any{"is_compatible": false, "messages": reg.incompatible})
+				return
+			}
+			writeJSON(map[string]any{"is_compatible": true})
+		case r.Method == http.MethodPost && path == "/subjects/events/versions":
+			reg.schemas = append(reg.schemas, body.Schema)
+			writeJSON(map[string]any{"id": len(reg.schemas)})
+		case r.Method == http.MethodGet && strings.HasPrefix(path, "/schemas/ids/"):
+			var id int
+			if _, err := fmt.Sscanf(path, "/schemas/ids/%d/versions", &id); err != nil || id > len(reg.schemas) {
+				http.Error(w, "not found", http.StatusNotFound)
+				return
+			}
+			writeJSON([]map[string]any{{"subject": "events", "version": id}})
+		case r.Method == http.MethodGet && strings.HasPrefix(path, "/subjects/events/versions/"):
+			var version int
+			if _, err := fmt.Sscanf(path, "/subjects/events/versions/%d", &version); err != nil || version > len(reg.schemas) {
+				http.Error(w, "not found", http.StatusNotFound)
+				return
+			}
+			writeJSON(subjectSchema(version))
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(ts.Close)
+
+	return reg, ts.URL
+}
+
+const testEvolutionSchema = `{"type":"record","name":"event","fields":[{"name":"id","type":"string"},{"name":"count","type":"long"}]}`
+
+func testAvroEncoder(t testing.TB, urlStr, policy string) *schemaRegistryAvroEncoder {
+	t.Helper()
+
+	conf, err := schemaRegistryAvroEncoderConfig().ParseYAML(fmt.Sprintf(`
+url: %v
+subject: events
+evolution:
+  policy: %v
+  max_new_fields: 2
+`, urlStr, policy), nil)
+	require.NoError(t, err)
+
+	enc, err := newSchemaRegistryAvroEncoderFromConfig(conf, service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = enc.Close(context.Background())
+	})
+	return enc
+}
+
+func avroEncodeDocs(t testing.TB, enc *schemaRegistryAvroEncoder, docs ...string) service.MessageBatch {
+	t.Helper()
+
+	var batch service.MessageBatch
+	for _, d := range docs {
+		batch = append(batch, service.NewMessage([]byte(d)))
+	}
+	out, err := enc.ProcessBatch(context.Background(), batch)
+	require.NoError(t, err)
+	require.Len(t, out, 1)
+	return out[0]
+}
+
+func avroDecodeWith(t testing.TB, schema string, msg *service.Message) (int, string) {
+	t.Helper()
+
+	require.NoError(t, msg.GetError())
+	b, err := msg.AsBytes()
+	require.NoError(t, err)
+
+	require.Greater(t, len(b), 5)
+	require.Equal(t, byte(0), b[0])
+
+	codec, err := goavro.NewCodecForStandardJSONFull(schema)
+	require.NoError(t, err)
+	native, _, err := codec.NativeFromBinary(b[5:])
+	require.NoError(t, err)
+	textual, err := codec.TextualFromNative(nil, native)
+	require.NoError(t, err)
+	return int(binary.BigEndian.Uint32(b[1:5])), string(textual)
+}
+
+func TestSchemaRegistryAvroEncodeKnownFields(t *testing.T) {
+	_, urlStr := runAvroEvolutionRegistry(t, testEvolutionSchema)
+	enc := testAvroEncoder(t, urlStr, sraePolicyReject)
+
+	out := avroEncodeDocs(t, enc, `{"id":"foo","count":3}`, `{"id":"bar"}`, `[1,2]`)
+
+	id, doc := avroDecodeWith(t, testEvolutionSchema, out[0])
+	assert.Equal(t, 1, id)
+	assert.JSONEq(t, `{"id":"foo","count":3}`, doc)
+
+	require.Error(t, out[1].GetError())
+	assert.Contains(t, out[2].GetError().Error(), "expected document to be an object")
+}
+
+func TestSchemaRegistryAvroEncodeReject(t *testing.T) {
+	reg, urlStr := runAvroEvolutionRegistry(t, testEvolutionSchema)
+	enc := testAvroEncoder(t, urlStr, sraePolicyReject)
+
+	out := avroEncodeDocs(t, enc, `{"id":"foo","count":3,"new":true}`)
+	require.Error(t, out[0].GetError())
+	assert.Contains(t, out[0].GetError().Error(), "not within the schema of subject \"events\": new")
+
+	b, err := out[0].AsBytes()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"id":"foo","count":3,"new":true}`, string(b))
+	assert.Len(t, reg.schemas, 1)
+}
+
+func TestSchemaRegistryAvroEncodeDrop(t *testing.T) {
+	reg, urlStr := runAvroEvolutionRegistry(t, testEvolutionSchema)
+	enc := testAvroEncoder(t, urlStr, sraePolicyDrop)
+
+	out := avroEncodeDocs(t, enc, `{"id":"foo","count":3,"new":true}`)
+	_, doc := avroDecodeWith(t, testEvolutionSchema, out[0])
+	assert.JSONEq(t, `{"id":"foo","count":3}`, doc)
+	assert.Len(t, reg.schemas, 1)
+}
+
+func TestSchemaRegistryAvroEncodeRegister(t *testing.T) {
+	reg, urlStr := runAvroEvolutionRegistry(t, testEvolutionSchema)
+	enc := testAvroEncoder(t, urlStr, sraePolicyRegister)
+
+	out := avroEncodeDocs(t, enc,
+		`{"id":"foo","count":3,"tags":["a","b"],"origin":{"host":"h","port":80}}`,
+		`{"id":"bar","count":4}`,
+		`{"id":"baz","count":5,"tags":["c"]}`,
+	)
+
+	require.Len(t, reg.schemas, 2)
+	assert.Len(t, reg.checked, 1)
+	assert.JSONEq(t, `{"type":"record","name":"event","fields":[
+  {"name":"id","type":"string"},
+  {"name":"count","type":"long"},
+  {"name":"origin","type":["null",{"type":"record","name":"event_origin","fields":[
+    {"name":"host","type":["null","string"],"default":null},
+    {"name":"port","type":["null","long"],"default":null}
+  ]}],"default":null},
+  {"name":"tags","type":["null",{"type":"array","items":"string"}],"default":null}
+]}`, reg.schemas[1])
+
+	id, doc := avroDecodeWith(t, reg.schemas[1], out[0])
+	assert.Equal(t, 2, id)
+	assert.JSONEq(t, `{"id":"foo","count":3,"tags":["a","b"],"origin":{"host":"h","port":80}}`, doc)
+
+	for _, msg := range out[1:] {
+		id, _ := avroDecodeWith(t, reg.schemas[1], msg)
+		assert.Equal(t, 2, id)
+	}
+}
+
+func TestSchemaRegistryAvroEncodeRegisterIncompatible(t *testing.T) {
+	reg, urlStr := runAvroEvolutionRegistry(t, testEvolutionSchema)
+	reg.incompatible = []string{"READER_FIELD_MISSING_DEFAULT_VALUE"}
+	enc := testAvroEncoder(t, urlStr, sraePolicyRegister)
+
+	out := avroEncodeDocs(t, enc, `{"id":"foo","count":3,"new":1.5}`)
+	require.Error(t, out[0].GetError())
+	assert.Contains(t, out[0].GetError().Error(), "is not compatible with subject \"events\": READER_FIELD_MISSING_DEFAULT_VALUE")
+	assert.Len(t, reg.checked, 1)
+	assert.Len(t, reg.schemas, 1)
+}
+
+func TestSchemaRegistryAvroEncodeRegisterLimits(t *testing.T) {
+	reg, urlStr := runAvroEvolutionRegistry(t, testEvolutionSchema)
+	enc := testAvroEncoder(t, urlStr, sraePolicyRegister)
+
+	out := avroEncodeDocs(t, enc,
+		`{"id":"foo","count":3,"a":1,"b":2,"c":3}`,
+		`{"id":"foo","count":3,"not-valid":1}`,
+	)
+	assert.Contains(t, out[0].GetError().Error(), "exceeding the maximum of 2")
+	assert.Contains(t, out[1].GetError().Error(), "is not a valid avro name")
+	assert.Empty(t, reg.checked)
+	assert.Len(t, reg.schemas, 1)
+}
+
+func TestInferAvroType(t *testing.T) {
+	tests := []struct {
+		value    any
+		expected any
+	}{
+		{value: nil, expected: "string"},
+		{value: "foo", expected: "string"},
+		{value: true, expected: "boolean"},
+		{value: json.Number("10"), expected: "long"},
+		{value: json.Number("1.5"), expected: "double"},
+		{value: int64(10), expected: "long"},
+		{value: 1.5, expected: "double"},
+		{value: []any{}, expected: map[string]any{"type": "array", "items": "string"}},
+		{value: []any{nil, true}, expected: map[string]any{"type": "array", "items": "boolean"}},
+	}
+	for _, test := range tests {
+		actual, err := inferAvroType("field", test.value)
+		require.NoError(t, err)
+		assert.Equal(t, test.expected, actual, "%#v", test.value)
+	}
+}
@@ -217,6 +217,17 @@ func (c *Client) CreateSchema(ctx context.Context, subject string, schema sr.Sch
 	return ss.ID, nil
 }
 
+// CheckCompatibility checks whether a schema is compatible with the latest
+// version of the given subject, according to the compatibility level of the
+// subject, returning the reasons when it is not.
+func (c *Client) CheckCompatibility(ctx context.Context, subject string, schema sr.Schema) (bool, []string, error) {
+	res, err := c.clientSR.CheckCompatibility(sr.WithParams(ctx, sr.Verbose), subject, -1, schema)
+	if err != nil {
+		return false, nil, fmt.Errorf("failed to check compatibility of schema for subject %q: %s", subject, err)
+	}
+	return res.Is, res.Messages, nil
+}
+
 type refWalkFn func(ctx context.Context, name string, info sr.Schema) error
 
 // WalkReferences goes through the provided schema info and for each reference
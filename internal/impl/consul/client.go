@@ -0,0 +1,76 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package consul
+
+import (
+	"net/http"
+
+	"github.com/hashicorp/consul/api"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	cFieldAddress    = "address"
+	cFieldToken      = "token"
+	cFieldDatacenter = "datacenter"
+	cFieldTLS        = "tls"
+)
+
+func clientFields() []*service.ConfigField {
+	return []*service.ConfigField{
+		service.NewURLField(cFieldAddress).
+			Description("The address of the Consul HTTP API.").
+			Default("http://127.0.0.1:8500"),
+		service.NewStringField(cFieldToken).
+			Description("An optional ACL token to authenticate requests with.").
+			Default("").
+			Secret(),
+		service.NewStringField(cFieldDatacenter).
+			Description("An optional datacenter to query, defaulting to the datacenter of the agent.").
+			Default("").
+			Advanced(),
+		service.NewTLSToggledField(cFieldTLS),
+	}
+}
+
+func clientFromParsed(conf *service.ParsedConfig) (*api.Client, error) {
+	c := &api.Config{}
+
+	var err error
+	if c.Address, err = conf.FieldString(cFieldAddress); err != nil {
+		return nil, err
+	}
+	if c.Token, err = conf.FieldString(cFieldToken); err != nil {
+		return nil, err
+	}
+	if c.Datacenter, err = conf.FieldString(cFieldDatacenter); err != nil {
+		return nil, err
+	}
+
+	tlsConf, tlsEnabled, err := conf.FieldTLSToggled(cFieldTLS)
+	if err != nil {
+		return nil, err
+	}
+	if tlsEnabled && tlsConf != nil {
+		if t, ok := http.DefaultTransport.(*http.Transport); ok {
+			c.Transport = t.Clone()
+		} else {
+			c.Transport = &http.Transport{}
+		}
+		c.Transport.TLSClientConfig = tlsConf
+	}
+	return api.NewClient(c)
+}
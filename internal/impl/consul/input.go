@@ -0,0 +1,233 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package consul
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	kiFieldPrefix      = "prefix"
+	kiFieldWaitTime    = "wait_time"
+	kiFieldEmitInitial = "emit_initial"
+)
+
+func kvInputSpec() *service.ConfigSpec {
+	spec := service.NewConfigSpec().
+		Beta().
+		Categories("Services").
+		Version("4.45.0").
+		Summary("Watches the keys of a Consul KV store under a prefix, emitting a message for each key that is created, modified or deleted.").
+		Description(`
+Changes are detected with https://developer.hashicorp.com/consul/api-docs/features/blocking[blocking queries^], where each query waits for the index of the prefix to change for up to `+"`"+kiFieldWaitTime+"`"+`, and the keys returned are compared with those of the previous query. When the input starts every existing key is emitted as a `+"`put`"+`, unless `+"`"+kiFieldEmitInitial+"`"+` is disabled.
+
+Each message contains the value of the key, and deleted keys result in an empty message with the operation `+"`delete`"+`. Since Consul only reports the current state of the prefix, multiple changes to a key between two queries are observed as a single change.
+
+== Metadata
+
+This input adds the following metadata fields to each message:
+
+- consul_key
+- consul_operation (`+"`put`"+` or `+"`delete`"+`)
+- consul_create_index
+- consul_modify_index
+- consul_flags
+- consul_index
+
+You can access these metadata fields using xref:configuration:interpolation.adoc#bloblang-queries[function interpolation].`).
+		Fields(clientFields()...).
+		Fields(
+			service.NewStringField(kiFieldPrefix).
+				Description("The prefix of the keys to watch, where an empty prefix watches all keys.").
+				Example("config/my-service/"),
+			service.NewDurationField(kiFieldWaitTime).
+				Description("The maximum duration of each blocking query.").
+				Default("5m").
+				Advanced(),
+			service.NewBoolField(kiFieldEmitInitial).
+				Description("Whether to emit the keys that exist when the input starts.").
+				Default(true),
+			service.NewAutoRetryNacksToggleField(),
+		).
+		Example("Propagate Config", "Write changes to the config of a service to a Kafka topic, keyed by the name of each key.", `
+input:
+  consul_kv:
+    address: http://consul.service:8500
+    token: ${CONSUL_TOKEN}
+    prefix: config/my-service/
+
+output:
+  kafka_franz:
+    seed_brokers: [ localhost:9092 ]
+    topic: config_changes
+    key: ${! @consul_key }
+`)
+	return spec
+}
+
+func init() {
+	err := service.RegisterInput("consul_kv", kvInputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Input, error) {
+			i, err := newKVInputFromParsed(conf, mgr)
+			if err != nil {
+				return nil, err
+			}
+			return service.AutoRetryNacksToggled(conf, i)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type kvEvent struct {
+	entry     *api.KVPair
+	operation string
+	index     uint64
+}
+
+type kvInput struct {
+	client      *api.Client
+	prefix      string
+	waitTime    time.Duration
+	emitInitial bool
+
+	mut         sync.Mutex
+	index       uint64
+	seen        map[string]uint64
+	initialised bool
+	pending     []kvEvent
+}
+
+func newKVInputFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*kvInput, error) {
+	i := &kvInput{seen: map[string]uint64{}}
+
+	var err error
+	if i.client, err = clientFromParsed(conf); err != nil {
+		return nil, err
+	}
+	if i.prefix, err = conf.FieldString(kiFieldPrefix); err != nil {
+		return nil, err
+	}
+	if i.waitTime, err = conf.FieldDuration(kiFieldWaitTime); err != nil {
+		return nil, err
+	}
+	if i.emitInitial, err = conf.FieldBool(kiFieldEmitInitial); err != nil {
+		return nil, err
+	}
+	return i, nil
+}
+
+func (i *kvInput) Connect(ctx context.Context) error {
+	return nil
+}
+
+// poll performs a blocking query for the prefix, and queues an event for
+// each key that has changed since the previous query.
+func (i *kvInput) poll(ctx context.Context) error {
+	opts := &api.QueryOptions{}
+	if i.index > 0 {
+		opts.WaitIndex = i.index
+		opts.WaitTime = i.waitTime
+	}
+
+	// Entries are nil when there are no keys with the prefix.
+	entries, meta, err := i.client.KV().List(i.prefix, opts.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+
+	index := meta.LastIndex
+	if i.initialised && index == i.index {
+		// The query timed out without any changes.
+		return nil
+	}
+
+	var events []kvEvent
+	current := make(map[string]uint64, len(entries))
+	for _, e := range entries {
+		current[e.Key] = e.ModifyIndex
+		if prev, exists := i.seen[e.Key]; exists && prev == e.ModifyIndex {
+			continue
+		}
+		if i.initialised || i.emitInitial {
+			events = append(events, kvEvent{entry: e, operation: "put", index: index})
+		}
+	}
+	slices.SortStableFunc(events, func(a, b kvEvent) int {
+		return cmp.Compare(a.entry.ModifyIndex, b.entry.ModifyIndex)
+	})
+
+	var deleted []string
+	for key := range i.seen {
+		if _, exists := current[key]; !exists {
+			deleted = append(deleted, key)
+		}
+	}
+	slices.Sort(deleted)
+	for _, key := range deleted {
+		events = append(events, kvEvent{entry: &api.KVPair{Key: key}, operation: "delete", index: index})
+	}
+
+	// Indexes that go backwards, such as after a restore of a snapshot,
+	// must reset blocking queries, and an index of zero would never block.
+	if index < i.index {
+		index = 0
+	} else if index == 0 {
+		index = 1
+	}
+	i.index = index
+	i.seen = current
+	i.initialised = true
+	i.pending = append(i.pending, events...)
+	return nil
+}
+
+func (i *kvInput) Read(ctx context.Context) (*service.Message, service.AckFunc, error) {
+	i.mut.Lock()
+	defer i.mut.Unlock()
+
+	for len(i.pending) == 0 {
+		if err := i.poll(ctx); err != nil {
+			if ctx.Err() != nil {
+				return nil, nil, ctx.Err()
+			}
+			return nil, nil, err
+		}
+	}
+
+	e := i.pending[0]
+	i.pending = i.pending[1:]
+
+	msg := service.NewMessage(e.entry.Value)
+	msg.MetaSetMut("consul_key", e.entry.Key)
+	msg.MetaSetMut("consul_operation", e.operation)
+	msg.MetaSetMut("consul_create_index", int64(e.entry.CreateIndex))
+	msg.MetaSetMut("consul_modify_index", int64(e.entry.ModifyIndex))
+	msg.MetaSetMut("consul_flags", int64(e.entry.Flags))
+	msg.MetaSetMut("consul_index", int64(e.index))
+	return msg, func(context.Context, error) error { return nil }, nil
+}
+
+func (i *kvInput) Close(ctx context.Context) error {
+	return nil
+}
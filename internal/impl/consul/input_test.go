@@ -0,0 +1,287 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package consul
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+// fakeConsul implements the subset of the KV and transaction endpoints of the
+// Consul HTTP API used by the components.
+type fakeConsul struct {
+	mut     sync.Mutex
+	cond    *sync.Cond
+	index   uint64
+	entries map[string]api.KVPair
+	tokens  []string
+}
+
+func newFakeConsul(t *testing.T) (*fakeConsul, *httptest.Server) {
+	f := &fakeConsul{index: 1, entries: map[string]api.KVPair{}}
+	f.cond = sync.NewCond(&f.mut)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/kv/", f.handleKV)
+	mux.HandleFunc("PUT /v1/txn", f.handleTxn)
+	srv := httptest.NewServer(mux)
+	t.Cleanup(func() {
+		f.mut.Lock()
+		f.cond.Broadcast()
+		f.mut.Unlock()
+		srv.Close()
+	})
+	return f, srv
+}
+
+func (f *fakeConsul) put(key, value string) {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	f.index++
+	e, exists := f.entries[key]
+	if !exists {
+		e = api.KVPair{Key: key, CreateIndex: f.index}
+	}
+	e.Value = []byte(value)
+	e.ModifyIndex = f.index
+	f.entries[key] = e
+	f.cond.Broadcast()
+}
+
+func (f *fakeConsul) delete(key string) {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	f.index++
+	delete(f.entries, key)
+	f.cond.Broadcast()
+}
+
+func (f *fakeConsul) handleKV(w http.ResponseWriter, r *http.Request) {
+	prefix := strings.TrimPrefix(r.URL.Path, "/v1/kv/")
+
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	f.tokens = append(f.tokens, r.Header.Get("X-Consul-Token"))
+
+	if index, _ := strconv.ParseUint(r.URL.Query().Get("index"), 10, 64); index > 0 {
+		deadline := time.Now().Add(time.Second)
+		for f.index <= index && time.Now().Before(deadline) && r.Context().Err() == nil {
+			go func() {
+				time.Sleep(10 * time.Millisecond)
+				f.mut.Lock()
+				f.cond.Broadcast()
+				f.mut.Unlock()
+			}()
+			f.cond.Wait()
+		}
+	}
+
+	var matched []api.KVPair
+	for k, e := range f.entries {
+		if strings.HasPrefix(k, prefix) {
+			matched = append(matched, e)
+		}
+	}
+	w.Header().Set("X-Consul-Index", strconv.FormatUint(f.index, 10))
+	if len(matched) == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(matched)
+}
+
+func (f *fakeConsul) handleTxn(w http.ResponseWriter, r *http.Request) {
+	var ops api.TxnOps
+	if err := json.NewDecoder(r.Body).Decode(&ops); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	f.tokens = append(f.tokens, r.Header.Get("X-Consul-Token"))
+
+	for i, op := range ops {
+		if strings.HasPrefix(op.KV.Key, "locked/") {
+			w.WriteHeader(http.StatusConflict)
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"Errors": api.TxnErrors{{OpIndex: i, What: "key is locked"}},
+			})
+			return
+		}
+	}
+
+	f.index++
+	for _, op := range ops {
+		switch op.KV.Verb {
+		case api.KVSet:
+			e, exists := f.entries[op.KV.Key]
+			if !exists {
+				e = api.KVPair{Key: op.KV.Key, CreateIndex: f.index}
+			}
+			e.Value = op.KV.Value
+			e.ModifyIndex = f.index
+			f.entries[op.KV.Key] = e
+		case api.KVDelete:
+			delete(f.entries, op.KV.Key)
+		case api.KVDeleteTree:
+			for k := range f.entries {
+				if strings.HasPrefix(k, op.KV.Key) {
+					delete(f.entries, k)
+				}
+			}
+		}
+	}
+	f.cond.Broadcast()
+	_, _ = w.Write([]byte(`{"Results":[],"Errors":null}`))
+}
+
+func readEvent(t *testing.T, i *kvInput) (key, op, value string) {
+	t.Helper()
+
+	ctx, done := context.WithTimeout(context.Background(), 5*time.Second)
+	defer done()
+
+	msg, ackFn, err := i.Read(ctx)
+	require.NoError(t, err)
+	require.NoError(t, ackFn(ctx, nil))
+
+	b, err := msg.AsBytes()
+	require.NoError(t, err)
+	key, _ = msg.MetaGet("consul_key")
+	op, _ = msg.MetaGet("consul_operation")
+	return key, op, string(b)
+}
+
+func TestKVInputWatch(t *testing.T) {
+	f, srv := newFakeConsul(t)
+	f.put("app/a", "1")
+	f.put("app/b", "2")
+	f.put("other/c", "3")
+
+	pConf, err := kvInputSpec().ParseYAML(`
+address: `+srv.URL+`
+prefix: app/
+token: foo
+wait_time: 1s
+`, nil)
+	require.NoError(t, err)
+
+	i, err := newKVInputFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+	require.NoError(t, i.Connect(context.Background()))
+	t.Cleanup(func() {
+		_ = i.Close(context.Background())
+	})
+
+	key, op, value := readEvent(t, i)
+	assert.Equal(t, []string{"app/a", "put", "1"}, []string{key, op, value})
+	key, op, value = readEvent(t, i)
+	assert.Equal(t, []string{"app/b", "put", "2"}, []string{key, op, value})
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		f.put("other/c", "4")
+		f.put("app/a", "5")
+		f.delete("app/b")
+	}()
+
+	key, op, value = readEvent(t, i)
+	assert.Equal(t, []string{"app/a", "put", "5"}, []string{key, op, value})
+	key, op, value = readEvent(t, i)
+	if key == "app/a" {
+		// The changes may be observed by separate queries.
+		key, op, value = readEvent(t, i)
+	}
+	assert.Equal(t, []string{"app/b", "delete", ""}, []string{key, op, value})
+
+	f.mut.Lock()
+	assert.Contains(t, f.tokens, "foo")
+	f.mut.Unlock()
+}
+
+func TestKVInputSkipInitial(t *testing.T) {
+	f, srv := newFakeConsul(t)
+	f.put("app/a", "1")
+
+	pConf, err := kvInputSpec().ParseYAML(`
+address: `+srv.URL+`
+prefix: app/
+emit_initial: false
+`, nil)
+	require.NoError(t, err)
+
+	i, err := newKVInputFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+	require.NoError(t, i.Connect(context.Background()))
+	t.Cleanup(func() {
+		_ = i.Close(context.Background())
+	})
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		f.put("app/b", "2")
+	}()
+
+	key, op, value := readEvent(t, i)
+	assert.Equal(t, []string{"app/b", "put", "2"}, []string{key, op, value})
+}
+
+func TestKVInputIndexReset(t *testing.T) {
+	f, srv := newFakeConsul(t)
+	f.put("app/a", "1")
+
+	pConf, err := kvInputSpec().ParseYAML(`
+address: `+srv.URL+`
+prefix: app/
+`, nil)
+	require.NoError(t, err)
+
+	i, err := newKVInputFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+	require.NoError(t, i.Connect(context.Background()))
+	t.Cleanup(func() {
+		_ = i.Close(context.Background())
+	})
+
+	key, _, _ := readEvent(t, i)
+	assert.Equal(t, "app/a", key)
+
+	// Simulate a restore of a snapshot, where the index goes backwards.
+	f.mut.Lock()
+	f.index = 0
+	f.entries = map[string]api.KVPair{}
+	f.mut.Unlock()
+	f.put("app/z", "2")
+
+	key, op, value := readEvent(t, i)
+	assert.Equal(t, []string{"app/z", "put", "2"}, []string{key, op, value})
+	key, op, _ = readEvent(t, i)
+	assert.Equal(t, []string{"app/a", "delete"}, []string{key, op})
+	assert.Equal(t, uint64(0), i.index)
+}
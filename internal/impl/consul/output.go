@@ -0,0 +1,176 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package consul
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/consul/api"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	koFieldKey       = "key"
+	koFieldOperation = "operation"
+	koFieldBatching  = "batching"
+
+	// The maximum number of operations Consul accepts within a transaction.
+	maxTxnOps = 64
+)
+
+func kvOutputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Services").
+		Version("4.45.0").
+		Summary("Writes messages as keys of a Consul KV store.").
+		Description(`
+The messages of each batch are written within a single https://developer.hashicorp.com/consul/api-docs/txn[transaction^], so that either all keys of a batch are written or none of them are. Consul limits transactions to `+"`64`"+` operations, and therefore batches larger than this are rejected.
+
+When `+"`"+koFieldOperation+"`"+` resolves to `+"`delete`"+` the key is deleted and the contents of the message are ignored, and `+"`delete-tree`"+` deletes every key with the key as a prefix.`+service.OutputPerformanceDocs(true, true)).
+		Fields(clientFields()...).
+		Fields(
+			service.NewInterpolatedStringField(koFieldKey).
+				Description("The key to write each message to.").
+				Example(`config/${! @kafka_key }`),
+			service.NewInterpolatedStringField(koFieldOperation).
+				Description("The operation to perform with each message, which must resolve to either `set`, `delete` or `delete-tree`.").
+				Example(`${! if @consul_operation == "delete" { "delete" } else { "set" } }`).
+				Default("set"),
+			service.NewOutputMaxInFlightField(),
+			service.NewBatchPolicyField(koFieldBatching),
+		).
+		Example("Mirror Keys", "Mirror the keys under a prefix of one Consul cluster into another, including deletions.", `
+input:
+  consul_kv:
+    address: http://consul-a:8500
+    prefix: config/
+
+output:
+  consul_kv:
+    address: http://consul-b:8500
+    key: ${! @consul_key }
+    operation: ${! if @consul_operation == "delete" { "delete" } else { "set" } }
+    batching:
+      count: 64
+      period: 100ms
+`)
+}
+
+func init() {
+	err := service.RegisterBatchOutput("consul_kv", kvOutputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPolicy service.BatchPolicy, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldMaxInFlight(); err != nil {
+				return
+			}
+			if batchPolicy, err = conf.FieldBatchPolicy(koFieldBatching); err != nil {
+				return
+			}
+			out, err = newKVOutputFromParsed(conf, mgr)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type kvOutput struct {
+	client    *api.Client
+	key       *service.InterpolatedString
+	operation *service.InterpolatedString
+}
+
+func newKVOutputFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*kvOutput, error) {
+	o := &kvOutput{}
+
+	var err error
+	if o.client, err = clientFromParsed(conf); err != nil {
+		return nil, err
+	}
+	if o.key, err = conf.FieldInterpolatedString(koFieldKey); err != nil {
+		return nil, err
+	}
+	if o.operation, err = conf.FieldInterpolatedString(koFieldOperation); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+func (o *kvOutput) Connect(ctx context.Context) error {
+	return nil
+}
+
+func (o *kvOutput) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	if len(batch) > maxTxnOps {
+		return fmt.Errorf("batch of %v messages exceeds the limit of %v operations per transaction", len(batch), maxTxnOps)
+	}
+
+	keyExec := batch.InterpolationExecutor(o.key)
+	opExec := batch.InterpolationExecutor(o.operation)
+
+	ops := make(api.TxnOps, 0, len(batch))
+	for i, msg := range batch {
+		key, err := keyExec.TryString(i)
+		if err != nil {
+			return fmt.Errorf("key interpolation error: %w", err)
+		}
+		if key == "" {
+			return errors.New("key interpolation resulted in an empty key")
+		}
+		verb, err := opExec.TryString(i)
+		if err != nil {
+			return fmt.Errorf("operation interpolation error: %w", err)
+		}
+
+		op := &api.KVTxnOp{Verb: api.KVOp(verb), Key: key}
+		switch op.Verb {
+		case api.KVSet:
+			if op.Value, err = msg.AsBytes(); err != nil {
+				return err
+			}
+		case api.KVDelete, api.KVDeleteTree:
+		default:
+			return fmt.Errorf("operation %q of message %v is not supported", verb, i)
+		}
+		ops = append(ops, &api.TxnOp{KV: op})
+	}
+
+	ok, res, _, err := o.client.Txn().Txn(ops, (&api.QueryOptions{}).WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	if ok {
+		return nil
+	}
+
+	// The transaction was rolled back, and the response lists the operations
+	// that caused it.
+	if res == nil || len(res.Errors) == 0 {
+		return errors.New("transaction was rolled back")
+	}
+	var reasons []string
+	for _, e := range res.Errors {
+		reasons = append(reasons, fmt.Sprintf("operation %v: %v", e.OpIndex, e.What))
+	}
+	return fmt.Errorf("transaction was rolled back: %v", strings.Join(reasons, ", "))
+}
+
+func (o *kvOutput) Close(ctx context.Context) error {
+	return nil
+}
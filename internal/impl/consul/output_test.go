@@ -0,0 +1,118 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package consul
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func TestKVOutputTxn(t *testing.T) {
+	f, srv := newFakeConsul(t)
+	f.put("app/old/a", "1")
+	f.put("app/old/b", "2")
+	f.put("app/c", "3")
+
+	pConf, err := kvOutputSpec().ParseYAML(`
+address: `+srv.URL+`
+token: bar
+key: app/${! @name }
+operation: ${! @op | "set" }
+`, nil)
+	require.NoError(t, err)
+
+	o, err := newKVOutputFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+	require.NoError(t, o.Connect(context.Background()))
+	t.Cleanup(func() {
+		_ = o.Close(context.Background())
+	})
+
+	batch := service.MessageBatch{
+		service.NewMessage([]byte("foo")),
+		service.NewMessage([]byte("bar")),
+		service.NewMessage(nil),
+		service.NewMessage(nil),
+	}
+	batch[0].MetaSetMut("name", "x")
+	batch[1].MetaSetMut("name", "y")
+	batch[2].MetaSetMut("name", "c")
+	batch[2].MetaSetMut("op", "delete")
+	batch[3].MetaSetMut("name", "old/")
+	batch[3].MetaSetMut("op", "delete-tree")
+	require.NoError(t, o.WriteBatch(context.Background(), batch))
+
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	values := map[string]string{}
+	for k, e := range f.entries {
+		values[k] = string(e.Value)
+	}
+	assert.Equal(t, map[string]string{
+		"app/x": "foo",
+		"app/y": "bar",
+	}, values)
+	assert.Contains(t, f.tokens, "bar")
+}
+
+func TestKVOutputErrors(t *testing.T) {
+	f, srv := newFakeConsul(t)
+
+	pConf, err := kvOutputSpec().ParseYAML(`
+address: `+srv.URL+`
+key: ${! @key }
+operation: ${! @op | "set" }
+`, nil)
+	require.NoError(t, err)
+
+	o, err := newKVOutputFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+	require.NoError(t, o.Connect(context.Background()))
+	t.Cleanup(func() {
+		_ = o.Close(context.Background())
+	})
+
+	batch := service.MessageBatch{
+		service.NewMessage([]byte("foo")),
+		service.NewMessage([]byte("bar")),
+	}
+	batch[0].MetaSetMut("key", "app/a")
+	batch[1].MetaSetMut("key", "locked/b")
+	err = o.WriteBatch(context.Background(), batch)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "operation 1: key is locked")
+
+	f.mut.Lock()
+	assert.Empty(t, f.entries)
+	f.mut.Unlock()
+
+	batch = service.MessageBatch{service.NewMessage([]byte("foo"))}
+	batch[0].MetaSetMut("key", "app/a")
+	batch[0].MetaSetMut("op", "cas")
+	require.ErrorContains(t, o.WriteBatch(context.Background(), batch), `operation "cas" of message 0 is not supported`)
+
+	batch = make(service.MessageBatch, maxTxnOps+1)
+	for i := range batch {
+		batch[i] = service.NewMessage([]byte("foo"))
+		batch[i].MetaSetMut("key", "app/a")
+	}
+	require.ErrorContains(t, o.WriteBatch(context.Background(), batch), "exceeds the limit")
+}
@@ -0,0 +1,260 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crypto
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/redpanda-data/benthos/v4/public/bloblang"
+)
+
+// appendCanonicalJSON serialises a value following the JSON Canonicalization
+// Scheme (RFC 8785), where object keys are sorted by their UTF-16 code units,
+// strings use the minimal escaping of ECMAScript and numbers are serialised
+// as IEEE 754 doubles in their shortest ECMAScript form.
+func appendCanonicalJSON(b []byte, v any) ([]byte, error) {
+	switch t := v.(type) {
+	case nil:
+		return append(b, "null"...), nil
+	case bool:
+		return strconv.AppendBool(b, t), nil
+	case string:
+		return appendCanonicalString(b, t), nil
+	case []byte:
+		return appendCanonicalString(b, string(t)), nil
+	case time.Time:
+		return appendCanonicalString(b, t.Format(time.RFC3339Nano)), nil
+	case float64:
+		return appendCanonicalNumber(b, t)
+	case float32:
+		return appendCanonicalNumber(b, float64(t))
+	case int:
+		return appendCanonicalNumber(b, float64(t))
+	case int32:
+		return appendCanonicalNumber(b, float64(t))
+	case int64:
+		return appendCanonicalNumber(b, float64(t))
+	case uint32:
+		return appendCanonicalNumber(b, float64(t))
+	case uint64:
+		return appendCanonicalNumber(b, float64(t))
+	case json.Number:
+		f, err := t.Float64()
+		if err != nil {
+			return nil, err
+		}
+		return appendCanonicalNumber(b, f)
+	case []any:
+		b = append(b, '[')
+		for i, e := range t {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			var err error
+			if b, err = appendCanonicalJSON(b, e); err != nil {
+				return nil, err
+			}
+		}
+		return append(b, ']'), nil
+	case map[string]any:
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		slices.SortFunc(keys, compareUTF16)
+
+		b = append(b, '{')
+		for i, k := range keys {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			b = appendCanonicalString(b, k)
+			b = append(b, ':')
+			var err error
+			if b, err = appendCanonicalJSON(b, t[k]); err != nil {
+				return nil, err
+			}
+		}
+		return append(b, '}'), nil
+	}
+	return nil, fmt.Errorf("value of type %T cannot be serialised as JSON", v)
+}
+
+func compareUTF16(a, b string) int {
+	return slices.Compare(utf16.Encode([]rune(a)), utf16.Encode([]rune(b)))
+}
+
+func appendCanonicalString(b []byte, s string) []byte {
+	const hexDigits = "0123456789abcdef"
+
+	b = append(b, '"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b = append(b, `\"`...)
+		case '\\':
+			b = append(b, `\\`...)
+		case '\b':
+			b = append(b, `\b`...)
+		case '\f':
+			b = append(b, `\f`...)
+		case '\n':
+			b = append(b, `\n`...)
+		case '\r':
+			b = append(b, `\r`...)
+		case '\t':
+			b = append(b, `\t`...)
+		default:
+			if r < 0x20 {
+				b = append(b, '\\', 'u', '0', '0', hexDigits[r>>4], hexDigits[r&0xf])
+			} else {
+				b = utf8.AppendRune(b, r)
+			}
+		}
+	}
+	return append(b, '"')
+}
+
+func appendCanonicalNumber(b []byte, f float64) ([]byte, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil, errors.New("NaN and infinite numbers cannot be serialised as JSON")
+	}
+	if f == 0 {
+		return append(b, '0'), nil
+	}
+	if f < 0 {
+		b = append(b, '-')
+		f = -f
+	}
+
+	// Obtain the shortest round-trip digits and the exponent, then lay them
+	// out following the ECMAScript Number.prototype.toString rules.
+	sci := strconv.FormatFloat(f, 'e', -1, 64)
+	mantissa, expStr, _ := strings.Cut(sci, "e")
+	digits := strings.Replace(mantissa, ".", "", 1)
+	exp, err := strconv.Atoi(expStr)
+	if err != nil {
+		return nil, err
+	}
+
+	k, n := len(digits), exp+1
+	switch {
+	case k <= n && n <= 21:
+		b = append(b, digits...)
+		b = append(b, strings.Repeat("0", n-k)...)
+	case 0 < n && n <= 21:
+		b = append(b, digits[:n]...)
+		b = append(b, '.')
+		b = append(b, digits[n:]...)
+	case -6 < n && n <= 0:
+		b = append(b, "0."...)
+		b = append(b, strings.Repeat("0", -n)...)
+		b = append(b, digits...)
+	default:
+		b = append(b, digits[0])
+		if k > 1 {
+			b = append(b, '.')
+			b = append(b, digits[1:]...)
+		}
+		b = append(b, 'e')
+		if n-1 >= 0 {
+			b = append(b, '+')
+		}
+		b = strconv.AppendInt(b, int64(n-1), 10)
+	}
+	return b, nil
+}
+
+var canonicalHashers = map[string]func() hash.Hash{
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+func registerCanonicalJSONMethods() error {
+	canonicalSpec := bloblang.NewPluginSpec().
+		Category("Parsing").
+		Version("4.45.0").
+		Description("Serialises a value into a JSON string following the JSON Canonicalization Scheme (RFC 8785). Object keys are sorted, whitespace is removed and numbers are serialised in a consistent form, which means structurally equal values always result in the same string regardless of the key ordering or formatting of their source.").
+		Example("", `root.canonical = this.doc.canonical_json()`, [2]string{
+			`{"doc":{"b":[1.50,true,null],"a":"é"}}`,
+			`{"canonical":"{\"a\":\"é\",\"b\":[1.5,true,null]}"}`,
+		})
+
+	if err := bloblang.RegisterMethodV2("canonical_json", canonicalSpec, func(args *bloblang.ParsedParams) (bloblang.Method, error) {
+		return func(v any) (any, error) {
+			b, err := appendCanonicalJSON(nil, v)
+			if err != nil {
+				return nil, err
+			}
+			return string(b), nil
+		}, nil
+	}); err != nil {
+		return err
+	}
+
+	hashSpec := bloblang.NewPluginSpec().
+		Category("Encoding and Encryption").
+		Version("4.45.0").
+		Description("Hashes the canonical JSON form (RFC 8785) of a value, resulting in a hash that is stable for structurally equal values regardless of key ordering and formatting. This is useful for deduplication keys and signatures of structured data. The result is a byte array, in order to obtain a string use the `encode` method.").
+		Param(bloblang.NewStringParam("algorithm").Description("The hashing algorithm to use, one of `sha1`, `sha256` or `sha512`.").Default("sha256")).
+		Example("", `root.key = this.doc.canonical_hash().encode("hex")`, [2]string{
+			`{"doc":{"b":2,"a":1}}`,
+			`{"key":"43258cff783fe7036d8a43033f830adfc60ec037382473548ac742b888292777"}`,
+		}, [2]string{
+			`{"doc":{"a":1.0,"b":2}}`,
+			`{"key":"43258cff783fe7036d8a43033f830adfc60ec037382473548ac742b888292777"}`,
+		})
+
+	return bloblang.RegisterMethodV2("canonical_hash", hashSpec, func(args *bloblang.ParsedParams) (bloblang.Method, error) {
+		algorithm, err := args.GetString("algorithm")
+		if err != nil {
+			return nil, err
+		}
+		newHash, exists := canonicalHashers[algorithm]
+		if !exists {
+			return nil, fmt.Errorf("unsupported hashing algorithm: %v", algorithm)
+		}
+
+		return func(v any) (any, error) {
+			b, err := appendCanonicalJSON(nil, v)
+			if err != nil {
+				return nil, err
+			}
+			h := newHash()
+			_, _ = h.Write(b)
+			return h.Sum(nil), nil
+		}, nil
+	})
+}
+
+func init() {
+	if err := registerCanonicalJSONMethods(); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crypto
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/bloblang"
+)
+
+func TestCanonicalJSONNumbers(t *testing.T) {
+	for _, c := range []struct {
+		input    float64
+		expected string
+	}{
+		{input: 0, expected: "0"},
+		{input: math.Copysign(0, -1), expected: "0"},
+		{input: 5e-324, expected: "5e-324"},
+		{input: -5e-324, expected: "-5e-324"},
+		{input: 1.7976931348623157e308, expected: "1.7976931348623157e+308"},
+		{input: 9007199254740992, expected: "9007199254740992"},
+		{input: -9007199254740992, expected: "-9007199254740992"},
+		{input: 295147905179352830000, expected: "295147905179352830000"},
+		{input: 1e21, expected: "1e+21"},
+		{input: 1e23, expected: "1e+23"},
+		{input: 1e-6, expected: "0.000001"},
+		{input: 1e-7, expected: "1e-7"},
+		{input: 4.5, expected: "4.5"},
+		{input: 0.002, expected: "0.002"},
+		{input: 333333333.33333329, expected: "333333333.3333333"},
+	} {
+		b, err := appendCanonicalNumber(nil, c.input)
+		require.NoError(t, err)
+		assert.Equal(t, c.expected, string(b), c.input)
+	}
+
+	_, err := appendCanonicalNumber(nil, math.NaN())
+	require.Error(t, err)
+	_, err = appendCanonicalNumber(nil, math.Inf(1))
+	require.Error(t, err)
+}
+
+func TestCanonicalJSON(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "rfc 8785 example",
+			input:    `{"numbers":[333333333.33333329,1E30,4.50,2e-3,0.000000000000000000000000001],"string":"\u20ac$\u000F\u000aA'\u0042\u0022\u005c\\\"\/","literals":[null,true,false]}`,
+			expected: `{"literals":[null,true,false],"numbers":[333333333.3333333,1e+30,4.5,0.002,1e-27],"string":"€$\u000f\nA'B\"\\\\\"/"}`,
+		},
+		{
+			name:     "utf-16 key ordering",
+			input:    `{"\u20ac":"Euro Sign","\r":"Carriage Return","\ufb33":"Hebrew Letter Dalet With Dagesh","1":"One","\ud83d\ude00":"Emoji: Grinning Face","\u0080":"Control","\u00f6":"Latin Small Letter O With Diaeresis"}`,
+			expected: "{\"\\r\":\"Carriage Return\",\"1\":\"One\",\"\u0080\":\"Control\",\"\u00f6\":\"Latin Small Letter O With Diaeresis\",\"\u20ac\":\"Euro Sign\",\"\U0001f600\":\"Emoji: Grinning Face\",\"\ufb33\":\"Hebrew Letter Dalet With Dagesh\"}",
+		},
+		{
+			name:     "nested whitespace",
+			input:    "{ \"b\" : { \"d\" : [ ], \"c\" : { } } ,\n\"a\" : \"<&>\" }",
+			expected: `{"a":"<&>","b":{"c":{},"d":[]}}`,
+		},
+	} {
+		var v any
+		require.NoError(t, json.Unmarshal([]byte(c.input), &v), c.name)
+
+		b, err := appendCanonicalJSON(nil, v)
+		require.NoError(t, err, c.name)
+		assert.Equal(t, c.expected, string(b), c.name)
+	}
+}
+
+func TestCanonicalHashMethod(t *testing.T) {
+	e, err := bloblang.Parse(`root = this.canonical_hash("sha256").encode("hex")`)
+	require.NoError(t, err)
+
+	var hashes []string
+	for _, input := range []string{
+		`{"a":1,"b":[1,2,{"c":true}]}`,
+		`{ "b" : [1.0, 2, {"c": true}], "a" : 1 }`,
+		`{"b":[1,2,{"c":true}],"a":1e0}`,
+	} {
+		var v any
+		require.NoError(t, json.Unmarshal([]byte(input), &v))
+
+		res, err := e.Query(v)
+		require.NoError(t, err)
+		hashes = append(hashes, res.(string))
+	}
+	assert.Equal(t, hashes[0], hashes[1])
+	assert.Equal(t, hashes[0], hashes[2])
+
+	canonical, err := appendCanonicalJSON(nil, map[string]any{"a": 1, "b": []any{1, 2, map[string]any{"c": true}}})
+	require.NoError(t, err)
+	assert.Equal(t, `{"a":1,"b":[1,2,{"c":true}]}`, string(canonical))
+
+	sum, err := hex.DecodeString(hashes[0])
+	require.NoError(t, err)
+	assert.Len(t, sum, 32)
+
+	_, err = bloblang.Parse(`root = this.canonical_hash("md5")`)
+	require.Error(t, err)
+}
@@ -0,0 +1,130 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crypto
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	spFieldKey   = "key"
+	spFieldKeyID = "key_id"
+)
+
+func signProcessorSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Categories("Utility").
+		Version("4.45.0").
+		Summary("Signs the payload of messages so that their integrity can be verified downstream with the `verify` processor.").
+		Description(`
+By default signatures are detached from the payload, and a base64 encoded signature is added to each message as metadata along with the identifier of the signing key. This allows the payload to pass through other systems unchanged, as long as metadata is also retained. Alternatively, setting `+"`detached` to `false`"+` wraps each payload within a JSON envelope that contains both the payload and the signature.
+
+The key identifier allows keys to be rotated without disrupting downstream consumers, as a `+"xref:components:processors/verify.adoc[`verify` processor]"+` can be configured with both the old and new keys during the rotation.`).
+		Fields(
+			signatureAlgorithmField(),
+			service.NewStringField(spFieldKey).
+				Description("The private key used for signing, or the shared secret for HMAC algorithms.").
+				Secret(),
+			service.NewStringField(spFieldKeyID).
+				Description("An identifier of the signing key, which is stored alongside the signature and used to select the verification key downstream.").
+				Default(""),
+		).
+		Fields(signatureEncodingFields()...).
+		Example("Ed25519 detached signatures", "Sign messages before sending them to another pipeline.", `
+pipeline:
+  processors:
+    - sign:
+        algorithm: ed25519
+        key: ${SIGNING_KEY}
+        key_id: 2024-10
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor("sign", signProcessorSpec(), func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+		return newSignProcessorFromParsed(conf)
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type signProcessor struct {
+	sign  signFunc
+	keyID string
+	enc   signatureEncoding
+}
+
+func newSignProcessorFromParsed(conf *service.ParsedConfig) (*signProcessor, error) {
+	algorithm, err := conf.FieldString(sigFieldAlgorithm)
+	if err != nil {
+		return nil, err
+	}
+	key, err := conf.FieldString(spFieldKey)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &signProcessor{}
+	if p.sign, err = signerFromKey(algorithm, key); err != nil {
+		return nil, err
+	}
+	if p.keyID, err = conf.FieldString(spFieldKeyID); err != nil {
+		return nil, err
+	}
+	if p.enc, err = signatureEncodingFromParsed(conf); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *signProcessor) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	payload, err := msg.AsBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := p.sign(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.enc.detached {
+		msg.MetaSetMut(p.enc.signatureMeta, base64.StdEncoding.EncodeToString(signature))
+		if p.keyID != "" {
+			msg.MetaSetMut(p.enc.keyIDMeta, p.keyID)
+		}
+		return service.MessageBatch{msg}, nil
+	}
+
+	envBytes, err := json.Marshal(signatureEnvelope{
+		Payload:   payload,
+		Signature: signature,
+		KeyID:     p.keyID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	msg.SetBytes(envBytes)
+	return service.MessageBatch{msg}, nil
+}
+
+func (p *signProcessor) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,170 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crypto
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func testPEMKeys(t *testing.T, algorithm string) (private, public string) {
+	t.Helper()
+
+	var privKey, pubKey any
+	switch algorithm {
+	case sigAlgEd25519:
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+		privKey, pubKey = priv, pub
+	default:
+		priv, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+		privKey, pubKey = priv, &priv.PublicKey
+	}
+
+	privDER, err := x509.MarshalPKCS8PrivateKey(privKey)
+	require.NoError(t, err)
+	pubDER, err := x509.MarshalPKIXPublicKey(pubKey)
+	require.NoError(t, err)
+
+	private = string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privDER}))
+	public = string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}))
+	return
+}
+
+func indentKey(key string) string {
+	return strings.ReplaceAll(strings.TrimSpace(key), "\n", "\n    ")
+}
+
+func testSignProcessor(t *testing.T, yamlStr string) *signProcessor {
+	t.Helper()
+
+	conf, err := signProcessorSpec().ParseYAML(yamlStr, nil)
+	require.NoError(t, err)
+
+	p, err := newSignProcessorFromParsed(conf)
+	require.NoError(t, err)
+	return p
+}
+
+func testVerifyProcessor(t *testing.T, yamlStr string) *verifyProcessor {
+	t.Helper()
+
+	conf, err := verifyProcessorSpec().ParseYAML(yamlStr, nil)
+	require.NoError(t, err)
+
+	p, err := newVerifyProcessorFromParsed(conf)
+	require.NoError(t, err)
+	return p
+}
+
+func TestSignVerifyAlgorithms(t *testing.T) {
+	for _, algorithm := range []string{sigAlgEd25519, sigAlgRSAPSS, sigAlgRSAPKCS1v15, sigAlgHMACSHA256} {
+		for _, detached := range []bool{true, false} {
+			t.Run(fmt.Sprintf("%v detached %v", algorithm, detached), func(t *testing.T) {
+				privKey, pubKey := "dont-tell-anyone", "dont-tell-anyone"
+				if algorithm != sigAlgHMACSHA256 {
+					privKey, pubKey = testPEMKeys(t, algorithm)
+				}
+
+				signer := testSignProcessor(t, fmt.Sprintf(`
+algorithm: %v
+detached: %v
+key_id: foo
+key: |
+    %v
+`, algorithm, detached, indentKey(privKey)))
+
+				verifier := testVerifyProcessor(t, fmt.Sprintf(`
+algorithm: %v
+detached: %v
+keys:
+  - id: foo
+    key: |
+        %v
+`, algorithm, detached, indentKey(indentKey(pubKey))))
+
+				batch, err := signer.Process(context.Background(), service.NewMessage([]byte("hello world")))
+				require.NoError(t, err)
+				require.Len(t, batch, 1)
+
+				signed := batch[0]
+				if detached {
+					b, err := signed.AsBytes()
+					require.NoError(t, err)
+					assert.Equal(t, "hello world", string(b))
+
+					kid, _ := signed.MetaGetMut("signature_key_id")
+					assert.Equal(t, "foo", kid)
+				}
+
+				batch, err = verifier.Process(context.Background(), signed.Copy())
+				require.NoError(t, err)
+				require.Len(t, batch, 1)
+
+				b, err := batch[0].AsBytes()
+				require.NoError(t, err)
+				assert.Equal(t, "hello world", string(b))
+
+				tampered := signed.Copy()
+				if detached {
+					tampered.SetBytes([]byte("hello world!"))
+				} else {
+					sb, err := signed.AsBytes()
+					require.NoError(t, err)
+					tampered.SetBytes([]byte(strings.Replace(string(sb), `"payload":"`, `"payload":"A`, 1)))
+				}
+				_, err = verifier.Process(context.Background(), tampered)
+				require.Error(t, err)
+			})
+		}
+	}
+}
+
+func TestSignKeyErrors(t *testing.T) {
+	_, rsaPub := testPEMKeys(t, sigAlgRSAPSS)
+	edPriv, _ := testPEMKeys(t, sigAlgEd25519)
+
+	for _, c := range []struct {
+		algorithm string
+		key       string
+	}{
+		{algorithm: sigAlgEd25519, key: "not a pem"},
+		{algorithm: sigAlgEd25519, key: rsaPub},
+		{algorithm: sigAlgRSAPSS, key: edPriv},
+	} {
+		conf, err := signProcessorSpec().ParseYAML(fmt.Sprintf(`
+algorithm: %v
+key: |
+    %v
+`, c.algorithm, indentKey(c.key)), nil)
+		require.NoError(t, err)
+
+		_, err = newSignProcessorFromParsed(conf)
+		require.Error(t, err, c.algorithm)
+	}
+}
@@ -0,0 +1,199 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crypto
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	vpFieldKeys       = "keys"
+	vpFieldKeysID     = "id"
+	vpFieldKeysKey    = "key"
+	vpFieldRequireKID = "require_key_id"
+)
+
+func verifyProcessorSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Categories("Utility").
+		Version("4.45.0").
+		Summary("Verifies signatures added to messages by the `sign` processor.").
+		Description(`
+Messages that fail verification are flagged as failed and can be handled using xref:configuration:error_handling.adoc[error handling patterns]. When signatures are attached (`+"`detached` set to `false`"+`) the envelope is removed and the message payload is restored to its original contents once verified.
+
+== Key rotation
+
+Multiple keys can be specified, and when a signature carries a key identifier only the key with a matching `+"`id`"+` is used. Signatures without a key identifier are checked against every key unless `+"`require_key_id`"+` is set. In order to rotate keys add the new key to this processor first, then switch the signing key upstream, and remove the old key once it is no longer in use.`).
+		Fields(
+			signatureAlgorithmField(),
+			service.NewObjectListField(vpFieldKeys,
+				service.NewStringField(vpFieldKeysID).
+					Description("The identifier of the key, matching the `key_id` of the signing processor.").
+					Default(""),
+				service.NewStringField(vpFieldKeysKey).
+					Description("The public key used for verification, or the shared secret for HMAC algorithms.").
+					Secret(),
+			).Description("The set of keys that signatures can be verified with."),
+			service.NewBoolField(vpFieldRequireKID).
+				Description("Whether signatures without a key identifier, or with an identifier that does not match a key, should be rejected rather than checked against every key.").
+				Default(false).
+				Advanced(),
+		).
+		Fields(signatureEncodingFields()...).
+		Example("Ed25519 with key rotation", "Verify messages signed with either of two keys, and drop any that fail.", `
+pipeline:
+  processors:
+    - verify:
+        algorithm: ed25519
+        keys:
+          - id: 2024-10
+            key: ${VERIFY_KEY_NEW}
+          - id: 2024-07
+            key: ${VERIFY_KEY_OLD}
+    - mapping: 'root = if errored() { deleted() }'
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor("verify", verifyProcessorSpec(), func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+		return newVerifyProcessorFromParsed(conf)
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type verifyKey struct {
+	id     string
+	verify verifyFunc
+}
+
+type verifyProcessor struct {
+	keys         []verifyKey
+	requireKeyID bool
+	enc          signatureEncoding
+}
+
+func newVerifyProcessorFromParsed(conf *service.ParsedConfig) (*verifyProcessor, error) {
+	algorithm, err := conf.FieldString(sigFieldAlgorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	keyConfs, err := conf.FieldObjectList(vpFieldKeys)
+	if err != nil {
+		return nil, err
+	}
+	if len(keyConfs) == 0 {
+		return nil, errors.New("at least one key must be specified")
+	}
+
+	p := &verifyProcessor{}
+	for i, kConf := range keyConfs {
+		var k verifyKey
+		if k.id, err = kConf.FieldString(vpFieldKeysID); err != nil {
+			return nil, err
+		}
+		key, err := kConf.FieldString(vpFieldKeysKey)
+		if err != nil {
+			return nil, err
+		}
+		if k.verify, err = verifierFromKey(algorithm, key); err != nil {
+			return nil, fmt.Errorf("key %v: %w", i, err)
+		}
+		p.keys = append(p.keys, k)
+	}
+
+	if p.requireKeyID, err = conf.FieldBool(vpFieldRequireKID); err != nil {
+		return nil, err
+	}
+	if p.enc, err = signatureEncodingFromParsed(conf); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *verifyProcessor) check(keyID string, payload, signature []byte) error {
+	matched := false
+	for _, k := range p.keys {
+		if keyID != "" && k.id != keyID {
+			continue
+		}
+		matched = true
+		if k.verify(payload, signature) {
+			return nil
+		}
+	}
+	if !matched {
+		if p.requireKeyID || keyID == "" {
+			return fmt.Errorf("no verification key matches key id '%v'", keyID)
+		}
+		// The key id is unknown, fall back to checking every key.
+		return p.check("", payload, signature)
+	}
+	return errors.New("signature verification failed")
+}
+
+func (p *verifyProcessor) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	rawBytes, err := msg.AsBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	var keyID string
+	var payload, signature []byte
+	if p.enc.detached {
+		sigStr, exists := msg.MetaGetMut(p.enc.signatureMeta)
+		if !exists {
+			return nil, fmt.Errorf("signature metadata key '%v' not found", p.enc.signatureMeta)
+		}
+		sigB64, _ := sigStr.(string)
+		if signature, err = base64.StdEncoding.DecodeString(sigB64); err != nil {
+			return nil, fmt.Errorf("failed to decode signature: %w", err)
+		}
+		if kid, exists := msg.MetaGetMut(p.enc.keyIDMeta); exists {
+			keyID, _ = kid.(string)
+		}
+		payload = rawBytes
+	} else {
+		var env signatureEnvelope
+		if err := json.Unmarshal(rawBytes, &env); err != nil {
+			return nil, fmt.Errorf("failed to parse signature envelope: %w", err)
+		}
+		payload, signature, keyID = env.Payload, env.Signature, env.KeyID
+	}
+
+	if keyID == "" && p.requireKeyID {
+		return nil, errors.New("signature does not have a key id")
+	}
+	if err := p.check(keyID, payload, signature); err != nil {
+		return nil, err
+	}
+
+	if !p.enc.detached {
+		msg.SetBytes(payload)
+	}
+	return service.MessageBatch{msg}, nil
+}
+
+func (p *verifyProcessor) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,83 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crypto
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func TestVerifyKeyRotation(t *testing.T) {
+	oldPriv, oldPub := testPEMKeys(t, sigAlgEd25519)
+	newPriv, newPub := testPEMKeys(t, sigAlgEd25519)
+	otherPriv, _ := testPEMKeys(t, sigAlgEd25519)
+
+	signWith := func(key, keyID string) *service.Message {
+		t.Helper()
+
+		p := testSignProcessor(t, `
+algorithm: ed25519
+key_id: "`+keyID+`"
+key: |
+    `+indentKey(key)+`
+`)
+		batch, err := p.Process(context.Background(), service.NewMessage([]byte("hello world")))
+		require.NoError(t, err)
+		require.Len(t, batch, 1)
+		return batch[0]
+	}
+
+	verifierConf := `
+algorithm: ed25519
+keys:
+  - id: new
+    key: |
+        ` + indentKey(indentKey(newPub)) + `
+  - id: old
+    key: |
+        ` + indentKey(indentKey(oldPub)) + `
+`
+
+	verifier := testVerifyProcessor(t, verifierConf)
+	strictVerifier := testVerifyProcessor(t, verifierConf+"require_key_id: true\n")
+
+	for _, c := range []struct {
+		name      string
+		msg       *service.Message
+		errLoose  bool
+		errStrict bool
+	}{
+		{name: "new key", msg: signWith(newPriv, "new")},
+		{name: "old key", msg: signWith(oldPriv, "old")},
+		{name: "no key id", msg: signWith(oldPriv, ""), errStrict: true},
+		{name: "unknown key id", msg: signWith(newPriv, "nope"), errStrict: true},
+		{name: "mismatched key id", msg: signWith(newPriv, "old"), errLoose: true, errStrict: true},
+		{name: "unknown key", msg: signWith(otherPriv, "new"), errLoose: true, errStrict: true},
+	} {
+		_, err := verifier.Process(context.Background(), c.msg.Copy())
+		assert.Equal(t, c.errLoose, err != nil, c.name)
+
+		_, err = strictVerifier.Process(context.Background(), c.msg.Copy())
+		assert.Equal(t, c.errStrict, err != nil, c.name)
+	}
+
+	_, err := verifier.Process(context.Background(), service.NewMessage([]byte("hello world")))
+	require.Error(t, err)
+}
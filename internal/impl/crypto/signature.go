@@ -0,0 +1,201 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crypto
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	sigFieldAlgorithm     = "algorithm"
+	sigFieldDetached      = "detached"
+	sigFieldSignatureMeta = "signature_metadata"
+	sigFieldKeyIDMeta     = "key_id_metadata"
+)
+
+const (
+	sigAlgEd25519     = "ed25519"
+	sigAlgRSAPSS      = "rsa_pss_sha256"
+	sigAlgRSAPKCS1v15 = "rsa_pkcs1v15_sha256"
+	sigAlgHMACSHA256  = "hmac_sha256"
+)
+
+func signatureAlgorithmField() *service.ConfigField {
+	return service.NewStringAnnotatedEnumField(sigFieldAlgorithm, map[string]string{
+		sigAlgEd25519:     "Ed25519 signatures, keys must be PEM encoded, with private keys in PKCS #8 form and public keys in PKIX form.",
+		sigAlgRSAPSS:      "RSASSA-PSS signatures over a SHA-256 digest, keys must be PEM encoded in PKCS #1, PKCS #8 or PKIX form.",
+		sigAlgRSAPKCS1v15: "RSASSA-PKCS1-v1_5 signatures over a SHA-256 digest, keys must be PEM encoded in PKCS #1, PKCS #8 or PKIX form.",
+		sigAlgHMACSHA256:  "HMAC-SHA256 message authentication codes, the key is a shared secret used both for signing and verification.",
+	}).Description("The signature algorithm to use.")
+}
+
+func signatureEncodingFields() []*service.ConfigField {
+	return []*service.ConfigField{
+		service.NewBoolField(sigFieldDetached).
+			Description("Whether signatures are detached from the payload and stored in metadata. When `false` the payload is wrapped in a JSON envelope of the form `{\"payload\":\"<base64>\",\"signature\":\"<base64>\",\"key_id\":\"<id>\"}`.").
+			Default(true),
+		service.NewStringField(sigFieldSignatureMeta).
+			Description("The metadata key that holds the base64 encoded signature of detached signatures.").
+			Default("signature").
+			Advanced(),
+		service.NewStringField(sigFieldKeyIDMeta).
+			Description("The metadata key that holds the identifier of the key used for detached signatures.").
+			Default("signature_key_id").
+			Advanced(),
+	}
+}
+
+// signatureEnvelope is the JSON form of a message with an attached signature.
+type signatureEnvelope struct {
+	Payload   []byte `json:"payload"`
+	Signature []byte `json:"signature"`
+	KeyID     string `json:"key_id,omitempty"`
+}
+
+type signatureEncoding struct {
+	detached      bool
+	signatureMeta string
+	keyIDMeta     string
+}
+
+func signatureEncodingFromParsed(conf *service.ParsedConfig) (e signatureEncoding, err error) {
+	if e.detached, err = conf.FieldBool(sigFieldDetached); err != nil {
+		return
+	}
+	if e.signatureMeta, err = conf.FieldString(sigFieldSignatureMeta); err != nil {
+		return
+	}
+	e.keyIDMeta, err = conf.FieldString(sigFieldKeyIDMeta)
+	return
+}
+
+type signFunc func(payload []byte) ([]byte, error)
+
+type verifyFunc func(payload, signature []byte) bool
+
+func decodePEM(key string) ([]byte, error) {
+	block, _ := pem.Decode([]byte(key))
+	if block == nil {
+		return nil, errors.New("key is not PEM encoded")
+	}
+	return block.Bytes, nil
+}
+
+func signerFromKey(algorithm, key string) (signFunc, error) {
+	if algorithm == sigAlgHMACSHA256 {
+		secret := []byte(key)
+		return func(payload []byte) ([]byte, error) {
+			mac := hmac.New(sha256.New, secret)
+			_, _ = mac.Write(payload)
+			return mac.Sum(nil), nil
+		}, nil
+	}
+
+	der, err := decodePEM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	var priv any
+	if priv, err = x509.ParsePKCS8PrivateKey(der); err != nil {
+		var pkcs1Err error
+		if priv, pkcs1Err = x509.ParsePKCS1PrivateKey(der); pkcs1Err != nil {
+			return nil, fmt.Errorf("failed to parse private key: %w", err)
+		}
+	}
+
+	switch algorithm {
+	case sigAlgEd25519:
+		edKey, ok := priv.(ed25519.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("expected an ed25519 private key, got %T", priv)
+		}
+		return func(payload []byte) ([]byte, error) {
+			return ed25519.Sign(edKey, payload), nil
+		}, nil
+	case sigAlgRSAPSS, sigAlgRSAPKCS1v15:
+		rsaKey, ok := priv.(*rsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("expected an RSA private key, got %T", priv)
+		}
+		return func(payload []byte) ([]byte, error) {
+			digest := sha256.Sum256(payload)
+			if algorithm == sigAlgRSAPSS {
+				return rsa.SignPSS(rand.Reader, rsaKey, crypto.SHA256, digest[:], nil)
+			}
+			return rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest[:])
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported signature algorithm: %v", algorithm)
+}
+
+func verifierFromKey(algorithm, key string) (verifyFunc, error) {
+	if algorithm == sigAlgHMACSHA256 {
+		secret := []byte(key)
+		return func(payload, signature []byte) bool {
+			mac := hmac.New(sha256.New, secret)
+			_, _ = mac.Write(payload)
+			return hmac.Equal(mac.Sum(nil), signature)
+		}, nil
+	}
+
+	der, err := decodePEM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	var pub any
+	if pub, err = x509.ParsePKIXPublicKey(der); err != nil {
+		var pkcs1Err error
+		if pub, pkcs1Err = x509.ParsePKCS1PublicKey(der); pkcs1Err != nil {
+			return nil, fmt.Errorf("failed to parse public key: %w", err)
+		}
+	}
+
+	switch algorithm {
+	case sigAlgEd25519:
+		edKey, ok := pub.(ed25519.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("expected an ed25519 public key, got %T", pub)
+		}
+		return func(payload, signature []byte) bool {
+			return ed25519.Verify(edKey, payload, signature)
+		}, nil
+	case sigAlgRSAPSS, sigAlgRSAPKCS1v15:
+		rsaKey, ok := pub.(*rsa.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("expected an RSA public key, got %T", pub)
+		}
+		return func(payload, signature []byte) bool {
+			digest := sha256.Sum256(payload)
+			if algorithm == sigAlgRSAPSS {
+				return rsa.VerifyPSS(rsaKey, crypto.SHA256, digest[:], signature, nil) == nil
+			}
+			return rsa.VerifyPKCS1v15(rsaKey, crypto.SHA256, digest[:], signature) == nil
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported signature algorithm: %v", algorithm)
+}
@@ -0,0 +1,569 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package disk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/dustin/go-humanize"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	dbFieldPath        = "path"
+	dbFieldSegmentSize = "segment_size"
+	dbFieldSyncWrites  = "sync_writes"
+	dbFieldRetention   = "retention"
+	dbFieldMaxSize     = "max_size"
+	dbFieldMaxAge      = "max_age"
+)
+
+// BufferConfig returns a config spec for a disk buffer.
+func BufferConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Version("4.45.0").
+		Summary("Stores messages in a write-ahead log on disk, consuming them in the order that they were written and replaying any that were not delivered after a restart or crash.").
+		Description(`
+Batches written to the buffer are appended as individual records to a log made up of segment files within the directory `+"`"+dbFieldPath+"`"+`. Each record is protected by a CRC, and when the buffer is opened every segment is validated so that a record that was only partially written at the time of a crash is discarded, along with anything after it within the segment.
+
+Batches are consumed in the order that they were written, and a batch that fails to be delivered is consumed again before any that follow it. The position up to which all batches have been delivered is persisted to a checkpoint file, and segments for which every batch has been delivered are deleted.
+
+== Delivery guarantees
+
+Batches are acknowledged at the input level once they are written to the log, which includes an fsync when `+"`"+dbFieldSyncWrites+"`"+` is enabled. When the service restarts every batch from the oldest one that had not yet been delivered onwards is consumed again, including any that were in flight at the time, and therefore delivery is at-least-once as long as the directory is not lost.
+
+Disabling `+"`"+dbFieldSyncWrites+"`"+` improves throughput considerably and still protects against the service crashing, but batches that have not yet been flushed by the operating system may be lost if the machine itself fails.
+
+== Retention
+
+By default segments are only deleted once all of their batches have been delivered, and the log grows for as long as the output is unable to keep up. The fields within `+"`"+dbFieldRetention+"`"+` limit the size and age of the log, and when either is exceeded the oldest segment is deleted regardless of whether its batches have been delivered. The segment being written to is never deleted, and so limits are enforced at the granularity of `+"`"+dbFieldSegmentSize+"`"+`.
+`).
+		Fields(
+			service.NewStringField(dbFieldPath).
+				Description("The path of a directory in which to store the log, which will be created if it does not already exist. Only one buffer may use a directory at a time."),
+			service.NewStringField(dbFieldSegmentSize).
+				Description("The size at which a segment is closed and a new one is started. A single batch larger than this size is written to a segment of its own.").
+				Default("64MB").
+				Advanced(),
+			service.NewBoolField(dbFieldSyncWrites).
+				Description("Whether to fsync each write to the log before acknowledging it.").
+				Default(true),
+			service.NewObjectField(dbFieldRetention,
+				service.NewStringField(dbFieldMaxSize).
+					Description("The maximum total size of the log, beyond which the oldest segments are deleted. Set to `0` for no limit.").
+					Example("10GB").
+					Default("0"),
+				service.NewDurationField(dbFieldMaxAge).
+					Description("The maximum time since a segment was last written to, beyond which it is deleted. Set to `0s` for no limit.").
+					Example("72h").
+					Default("0s"),
+			).
+				Description("Limits on the size and age of the log, which are enforced even when doing so discards batches that have not been delivered.").
+				Advanced(),
+		).
+		Example("Durable HTTP Ingestion", "Acknowledge HTTP requests once their data is safely on disk, and deliver it to Kafka even after the broker or this service has been down.", `
+input:
+  http_server:
+    path: /events
+
+buffer:
+  disk:
+    path: ./data/events
+    retention:
+      max_size: 20GB
+
+output:
+  kafka_franz:
+    seed_brokers: [ localhost:9092 ]
+    topic: events
+`)
+}
+
+func init() {
+	err := service.RegisterBatchBuffer(
+		"disk", BufferConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchBuffer, error) {
+			return NewBufferFromConfig(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+func fieldBytes(conf *service.ParsedConfig, path ...string) (int64, error) {
+	s, err := conf.FieldString(path...)
+	if err != nil {
+		return 0, err
+	}
+	b, err := humanize.ParseBytes(s)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse %v bytes: %w", path[len(path)-1], err)
+	}
+	if b > math.MaxInt64 {
+		return 0, fmt.Errorf("%v is too large", path[len(path)-1])
+	}
+	return int64(b), nil
+}
+
+// NewBufferFromConfig creates a new disk buffer from a parsed config.
+func NewBufferFromConfig(conf *service.ParsedConfig, res *service.Resources) (*Buffer, error) {
+	path, err := conf.FieldString(dbFieldPath)
+	if err != nil {
+		return nil, err
+	}
+	segmentSize, err := fieldBytes(conf, dbFieldSegmentSize)
+	if err != nil {
+		return nil, err
+	}
+	syncWrites, err := conf.FieldBool(dbFieldSyncWrites)
+	if err != nil {
+		return nil, err
+	}
+	maxSize, err := fieldBytes(conf, dbFieldRetention, dbFieldMaxSize)
+	if err != nil {
+		return nil, err
+	}
+	maxAge, err := conf.FieldDuration(dbFieldRetention, dbFieldMaxAge)
+	if err != nil {
+		return nil, err
+	}
+	if segmentSize <= 0 {
+		return nil, fmt.Errorf("%v must be greater than zero", dbFieldSegmentSize)
+	}
+	if maxSize > 0 && maxSize < segmentSize {
+		return nil, fmt.Errorf("%v.%v must be at least %v", dbFieldRetention, dbFieldMaxSize, dbFieldSegmentSize)
+	}
+	return newBuffer(path, segmentSize, syncWrites, maxSize, maxAge, res.Logger())
+}
+
+//------------------------------------------------------------------------------
+
+type recordRef struct {
+	seq    uint64
+	seg    *segment
+	offset int64
+}
+
+// Buffer stores batches within a segmented write-ahead log on disk.
+type Buffer struct {
+	dir         string
+	segmentSize int64
+	syncWrites  bool
+	maxSize     int64
+	maxAge      time.Duration
+	log         *service.Logger
+
+	cond *sync.Cond
+
+	// The last segment is the one being written to.
+	segments []*segment
+	nextSeq  uint64
+
+	// The position of the next record that has not yet been read, and the
+	// lowest sequence number it may have.
+	readSeg    *segment
+	readOffset int64
+	readSeq    uint64
+
+	inFlight map[uint64]recordRef
+	retries  []recordRef
+
+	// All records below this sequence number have been delivered.
+	checkpoint uint64
+
+	endOfInput bool
+	closed     bool
+}
+
+func newBuffer(dir string, segmentSize int64, syncWrites bool, maxSize int64, maxAge time.Duration, log *service.Logger) (*Buffer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	checkpoint, err := readCheckpoint(dir)
+	if err != nil {
+		if !errors.Is(err, errCorruptRecord) {
+			return nil, err
+		}
+		log.Warn("Checkpoint of disk buffer is corrupt, all stored batches will be consumed again")
+	}
+
+	d := &Buffer{
+		dir:         dir,
+		segmentSize: segmentSize,
+		syncWrites:  syncWrites,
+		maxSize:     maxSize,
+		maxAge:      maxAge,
+		log:         log,
+		cond:        sync.NewCond(&sync.Mutex{}),
+		nextSeq:     checkpoint,
+		readSeq:     checkpoint,
+		inFlight:    map[uint64]recordRef{},
+		checkpoint:  checkpoint,
+	}
+
+	seqs, err := listSegments(dir)
+	if err != nil {
+		return nil, err
+	}
+	for i, firstSeq := range seqs {
+		seg, lastSeq, discarded, err := openSegment(dir, firstSeq)
+		if err != nil {
+			d.closeSegments()
+			return nil, err
+		}
+		if discarded > 0 {
+			log.Warnf("Discarded %v bytes of incomplete or corrupt data from disk buffer segment %v", discarded, seg.path)
+		}
+		d.nextSeq = max(d.nextSeq, firstSeq)
+		if seg.size > 0 {
+			d.nextSeq = max(d.nextSeq, lastSeq+1)
+		}
+
+		// Segments entirely below the checkpoint were delivered but not yet
+		// deleted, and empty segments are left behind by a crash immediately
+		// after rolling.
+		delivered := i < len(seqs)-1 && seqs[i+1] <= checkpoint
+		if delivered || (seg.size == 0 && i < len(seqs)-1) {
+			if err := seg.remove(); err != nil {
+				d.closeSegments()
+				return nil, err
+			}
+			continue
+		}
+		d.segments = append(d.segments, seg)
+	}
+
+	if len(d.segments) == 0 {
+		seg, err := createSegment(dir, d.nextSeq, syncWrites)
+		if err != nil {
+			return nil, err
+		}
+		d.segments = append(d.segments, seg)
+	}
+	d.readSeg = d.segments[0]
+
+	if err := d.enforceRetention(time.Now()); err != nil {
+		d.closeSegments()
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *Buffer) closeSegments() {
+	for _, s := range d.segments {
+		if s.f != nil {
+			_ = s.f.Close()
+		}
+	}
+}
+
+// broadcast wakes pending reads, and holds the lock so that the wake up cannot
+// be missed by a read that is about to wait.
+func (d *Buffer) broadcast() {
+	d.cond.L.Lock()
+	d.cond.Broadcast()
+	d.cond.L.Unlock()
+}
+
+func (d *Buffer) activeSegment() *segment {
+	return d.segments[len(d.segments)-1]
+}
+
+func (d *Buffer) totalSize() (size int64) {
+	for _, s := range d.segments {
+		size += s.size
+	}
+	return
+}
+
+// dropOldestSegment deletes the oldest segment, discarding any records within
+// it that have not yet been delivered.
+func (d *Buffer) dropOldestSegment() error {
+	seg, next := d.segments[0], d.segments[1]
+	if err := seg.remove(); err != nil {
+		return err
+	}
+	d.segments = d.segments[1:]
+
+	if d.readSeg == seg {
+		d.readSeg, d.readOffset = next, 0
+	}
+	d.readSeq = max(d.readSeq, next.firstSeq)
+	for seq, ref := range d.inFlight {
+		if ref.seg == seg {
+			delete(d.inFlight, seq)
+		}
+	}
+	d.retries = slices.DeleteFunc(d.retries, func(ref recordRef) bool {
+		return ref.seg == seg
+	})
+	return nil
+}
+
+// enforceRetention deletes the oldest segments until the limits on the size
+// and age of the log are satisfied.
+func (d *Buffer) enforceRetention(now time.Time) error {
+	if d.maxSize <= 0 && d.maxAge <= 0 {
+		return nil
+	}
+
+	var dropped bool
+	for len(d.segments) > 1 {
+		oldest := d.segments[0]
+		expired := d.maxAge > 0 && now.Sub(oldest.modTime) > d.maxAge
+		oversized := d.maxSize > 0 && d.totalSize() > d.maxSize
+		if !expired && !oversized {
+			break
+		}
+		if d.segments[1].firstSeq > d.checkpoint {
+			d.log.Warnf("Deleting disk buffer segment %v containing batches that have not been delivered as it exceeds the retention limits", oldest.path)
+		}
+		if err := d.dropOldestSegment(); err != nil {
+			return err
+		}
+		dropped = true
+	}
+	if dropped {
+		return d.advanceCheckpoint()
+	}
+	return nil
+}
+
+// advanceCheckpoint moves the checkpoint up to the lowest sequence number that
+// has not yet been delivered, and deletes any segments that are entirely below
+// it.
+func (d *Buffer) advanceCheckpoint() error {
+	lowest := d.readSeq
+	for seq := range d.inFlight {
+		lowest = min(lowest, seq)
+	}
+	if len(d.retries) > 0 {
+		lowest = min(lowest, d.retries[0].seq)
+	}
+	if lowest <= d.checkpoint {
+		return nil
+	}
+
+	if err := writeCheckpoint(d.dir, lowest); err != nil {
+		return err
+	}
+	d.checkpoint = lowest
+
+	for len(d.segments) > 1 && d.segments[1].firstSeq <= d.checkpoint {
+		if err := d.dropOldestSegment(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// nextRecord returns the next record to be delivered, or false if there are
+// none remaining.
+func (d *Buffer) nextRecord() (recordRef, []byte, bool) {
+	for len(d.retries) > 0 {
+		ref := d.retries[0]
+		d.retries = d.retries[1:]
+
+		_, payload, _, err := ref.seg.readRecord(ref.offset)
+		if err != nil {
+			d.log.Errorf("Failed to read batch %v from disk buffer segment %v, it will be skipped: %v", ref.seq, ref.seg.path, err)
+			continue
+		}
+		return ref, payload, true
+	}
+
+	for {
+		if d.readOffset >= d.readSeg.size {
+			i := slices.Index(d.segments, d.readSeg)
+			if i == len(d.segments)-1 {
+				return recordRef{}, nil, false
+			}
+			d.readSeg, d.readOffset = d.segments[i+1], 0
+			continue
+		}
+
+		ref := recordRef{seg: d.readSeg, offset: d.readOffset}
+		seq, payload, n, err := d.readSeg.readRecord(d.readOffset)
+		if err != nil {
+			d.log.Errorf("Failed to read from disk buffer segment %v, the rest of the segment will be skipped: %v", d.readSeg.path, err)
+			d.readOffset = d.readSeg.size
+			continue
+		}
+		d.readOffset += n
+		d.readSeq = seq + 1
+
+		// Records below the checkpoint were delivered before a restart.
+		if seq < d.checkpoint {
+			continue
+		}
+		ref.seq = seq
+		return ref, payload, true
+	}
+}
+
+func (d *Buffer) ackFn(ref recordRef) service.AckFunc {
+	return func(ctx context.Context, err error) error {
+		d.cond.L.Lock()
+		defer d.cond.L.Unlock()
+
+		if d.closed {
+			return errors.New("buffer closed")
+		}
+
+		// Records that were deleted by retention are no longer tracked.
+		if _, exists := d.inFlight[ref.seq]; !exists {
+			return nil
+		}
+		delete(d.inFlight, ref.seq)
+		d.cond.Broadcast()
+
+		if err != nil {
+			i, _ := slices.BinarySearchFunc(d.retries, ref.seq, func(r recordRef, seq uint64) int {
+				switch {
+				case r.seq < seq:
+					return -1
+				case r.seq > seq:
+					return 1
+				}
+				return 0
+			})
+			d.retries = slices.Insert(d.retries, i, ref)
+			return nil
+		}
+		return d.advanceCheckpoint()
+	}
+}
+
+// ReadBatch waits for the next batch in the log and returns it.
+func (d *Buffer) ReadBatch(ctx context.Context) (service.MessageBatch, service.AckFunc, error) {
+	ctx, done := context.WithCancel(ctx)
+	defer done()
+
+	go func() {
+		<-ctx.Done()
+		d.broadcast()
+	}()
+
+	d.cond.L.Lock()
+	defer d.cond.L.Unlock()
+
+	for {
+		if d.closed {
+			return nil, nil, service.ErrEndOfBuffer
+		}
+		if ctx.Err() != nil {
+			return nil, nil, ctx.Err()
+		}
+		if err := d.enforceRetention(time.Now()); err != nil {
+			return nil, nil, err
+		}
+
+		if ref, payload, ok := d.nextRecord(); ok {
+			batch, err := decodeBatch(payload)
+			if err != nil {
+				d.log.Errorf("Failed to decode batch %v from disk buffer segment %v, it will be skipped: %v", ref.seq, ref.seg.path, err)
+				if err := d.advanceCheckpoint(); err != nil {
+					return nil, nil, err
+				}
+				continue
+			}
+			d.inFlight[ref.seq] = ref
+			return batch, d.ackFn(ref), nil
+		}
+
+		if d.endOfInput && len(d.inFlight) == 0 {
+			return nil, nil, service.ErrEndOfBuffer
+		}
+		d.cond.Wait()
+	}
+}
+
+// WriteBatch appends a batch to the log.
+func (d *Buffer) WriteBatch(ctx context.Context, msgBatch service.MessageBatch, aFn service.AckFunc) error {
+	payload, err := encodeBatch(msgBatch)
+	if err != nil {
+		return err
+	}
+	if len(payload) > math.MaxUint32 {
+		return errors.New("batch is too large to be written to the buffer")
+	}
+
+	d.cond.L.Lock()
+	defer d.cond.L.Unlock()
+
+	if d.closed {
+		return service.ErrEndOfBuffer
+	}
+
+	record := encodeRecord(d.nextSeq, payload)
+	if active := d.activeSegment(); active.size > 0 && active.size+int64(len(record)) > d.segmentSize {
+		seg, err := createSegment(d.dir, d.nextSeq, d.syncWrites)
+		if err != nil {
+			return err
+		}
+		d.segments = append(d.segments, seg)
+	}
+	if err := d.activeSegment().appendRecord(record, d.syncWrites); err != nil {
+		return err
+	}
+	d.nextSeq++
+
+	if err := d.enforceRetention(time.Now()); err != nil {
+		return err
+	}
+	if err := aFn(ctx, nil); err != nil {
+		return err
+	}
+
+	d.cond.Broadcast()
+	return nil
+}
+
+// EndOfInput signals to the buffer that the input is finished and therefore
+// once all batches have been delivered it should close.
+func (d *Buffer) EndOfInput() {
+	go func() {
+		d.cond.L.Lock()
+		defer d.cond.L.Unlock()
+
+		d.endOfInput = true
+		d.cond.Broadcast()
+	}()
+}
+
+// Close the segment files of the log.
+func (d *Buffer) Close(ctx context.Context) error {
+	d.cond.L.Lock()
+	defer d.cond.L.Unlock()
+
+	if d.closed {
+		return nil
+	}
+	d.closed = true
+	d.cond.Broadcast()
+	d.closeSegments()
+	return nil
+}
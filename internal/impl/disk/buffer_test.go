@@ -0,0 +1,343 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package disk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func diskBufFromConf(t testing.TB, conf string) *Buffer {
+	t.Helper()
+
+	parsedConf, err := BufferConfig().ParseYAML(conf, nil)
+	require.NoError(t, err)
+
+	buf, err := NewBufferFromConfig(parsedConf, service.MockResources())
+	require.NoError(t, err)
+	return buf
+}
+
+func noopAck(context.Context, error) error { return nil }
+
+func writeMessages(t testing.TB, buf *Buffer, contents ...string) {
+	t.Helper()
+	for _, c := range contents {
+		msg := service.NewMessage([]byte(c))
+		msg.MetaSetMut("content", c)
+		require.NoError(t, buf.WriteBatch(context.Background(), service.MessageBatch{msg}, noopAck))
+	}
+}
+
+func readMessage(t testing.TB, buf *Buffer) (string, service.AckFunc) {
+	t.Helper()
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+
+	batch, aFn, err := buf.ReadBatch(ctx)
+	require.NoError(t, err)
+	require.Len(t, batch, 1)
+
+	b, err := batch[0].AsBytes()
+	require.NoError(t, err)
+	meta, _ := batch[0].MetaGetMut("content")
+	assert.Equal(t, string(b), meta)
+	return string(b), aFn
+}
+
+func segmentFiles(t testing.TB, dir string) []string {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join(dir, "*"+segmentExt))
+	require.NoError(t, err)
+	return files
+}
+
+func TestDiskBufferOrdering(t *testing.T) {
+	tCtx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	dir := t.TempDir()
+	buf := diskBufFromConf(t, fmt.Sprintf(`path: %v`, dir))
+	defer buf.Close(tCtx)
+
+	writeMessages(t, buf, "foo", "bar", "baz")
+
+	for _, exp := range []string{"foo", "bar", "baz"} {
+		content, aFn := readMessage(t, buf)
+		assert.Equal(t, exp, content)
+		require.NoError(t, aFn(tCtx, nil))
+	}
+
+	buf.EndOfInput()
+	_, _, err := buf.ReadBatch(tCtx)
+	require.ErrorIs(t, err, service.ErrEndOfBuffer)
+}
+
+func TestDiskBufferNackRedelivered(t *testing.T) {
+	tCtx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	buf := diskBufFromConf(t, fmt.Sprintf(`path: %v`, t.TempDir()))
+	defer buf.Close(tCtx)
+
+	writeMessages(t, buf, "foo", "bar", "baz")
+
+	_, fooAck := readMessage(t, buf)
+	_, barAck := readMessage(t, buf)
+	require.NoError(t, barAck(tCtx, errors.New("nope")))
+	require.NoError(t, fooAck(tCtx, errors.New("nope")))
+
+	var contents []string
+	for range 3 {
+		content, aFn := readMessage(t, buf)
+		contents = append(contents, content)
+		require.NoError(t, aFn(tCtx, nil))
+	}
+	assert.Equal(t, []string{"foo", "bar", "baz"}, contents)
+}
+
+func TestDiskBufferEndOfInputWaitsForAcks(t *testing.T) {
+	tCtx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	buf := diskBufFromConf(t, fmt.Sprintf(`path: %v`, t.TempDir()))
+	defer buf.Close(tCtx)
+
+	writeMessages(t, buf, "foo")
+	_, aFn := readMessage(t, buf)
+	buf.EndOfInput()
+
+	go func() {
+		time.Sleep(time.Millisecond * 50)
+		_ = aFn(tCtx, errors.New("nope"))
+	}()
+
+	content, aFn := readMessage(t, buf)
+	assert.Equal(t, "foo", content)
+	require.NoError(t, aFn(tCtx, nil))
+
+	_, _, err := buf.ReadBatch(tCtx)
+	require.ErrorIs(t, err, service.ErrEndOfBuffer)
+}
+
+func TestDiskBufferReplayAfterRestart(t *testing.T) {
+	tCtx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	dir := t.TempDir()
+	conf := fmt.Sprintf(`
+path: %v
+segment_size: 100B
+`, dir)
+
+	buf := diskBufFromConf(t, conf)
+	writeMessages(t, buf, "first", "second", "third", "fourth", "fifth")
+
+	_, aFn := readMessage(t, buf)
+	require.NoError(t, aFn(tCtx, nil))
+	_, aFn = readMessage(t, buf)
+	require.NoError(t, aFn(tCtx, nil))
+
+	// Third is in flight when the buffer closes, and fourth is delivered after
+	// it and will therefore be replayed.
+	_, _ = readMessage(t, buf)
+	_, aFn = readMessage(t, buf)
+	require.NoError(t, aFn(tCtx, nil))
+	require.NoError(t, buf.Close(tCtx))
+
+	buf = diskBufFromConf(t, conf)
+	defer buf.Close(tCtx)
+
+	writeMessages(t, buf, "sixth")
+
+	var contents []string
+	for range 4 {
+		content, aFn := readMessage(t, buf)
+		contents = append(contents, content)
+		require.NoError(t, aFn(tCtx, nil))
+	}
+	assert.Equal(t, []string{"third", "fourth", "fifth", "sixth"}, contents)
+
+	// Only the segment being written to remains once everything is delivered.
+	assert.Len(t, segmentFiles(t, dir), 1)
+}
+
+func TestDiskBufferTornWrite(t *testing.T) {
+	tCtx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	dir := t.TempDir()
+	conf := fmt.Sprintf(`path: %v`, dir)
+
+	buf := diskBufFromConf(t, conf)
+	writeMessages(t, buf, "foo", "bar")
+	require.NoError(t, buf.Close(tCtx))
+
+	files := segmentFiles(t, dir)
+	require.Len(t, files, 1)
+	info, err := os.Stat(files[0])
+	require.NoError(t, err)
+
+	// Simulate a crash part way through writing the last record.
+	require.NoError(t, os.Truncate(files[0], info.Size()-2))
+
+	buf = diskBufFromConf(t, conf)
+	defer buf.Close(tCtx)
+
+	writeMessages(t, buf, "baz")
+
+	var contents []string
+	for range 2 {
+		content, aFn := readMessage(t, buf)
+		contents = append(contents, content)
+		require.NoError(t, aFn(tCtx, nil))
+	}
+	assert.Equal(t, []string{"foo", "baz"}, contents)
+}
+
+func TestDiskBufferCorruptRecord(t *testing.T) {
+	tCtx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	dir := t.TempDir()
+	conf := fmt.Sprintf(`path: %v`, dir)
+
+	buf := diskBufFromConf(t, conf)
+	writeMessages(t, buf, "foo", "bar", "baz")
+	require.NoError(t, buf.Close(tCtx))
+
+	files := segmentFiles(t, dir)
+	require.Len(t, files, 1)
+	b, err := os.ReadFile(files[0])
+	require.NoError(t, err)
+
+	// Flip the content of the second record, which discards it along with
+	// everything after it.
+	i := strings.Index(string(b), "bar")
+	require.Positive(t, i)
+	b[i] = 'c'
+	require.NoError(t, os.WriteFile(files[0], b, 0o644))
+
+	buf = diskBufFromConf(t, conf)
+	defer buf.Close(tCtx)
+
+	content, aFn := readMessage(t, buf)
+	assert.Equal(t, "foo", content)
+	require.NoError(t, aFn(tCtx, nil))
+
+	buf.EndOfInput()
+	_, _, err = buf.ReadBatch(tCtx)
+	require.ErrorIs(t, err, service.ErrEndOfBuffer)
+}
+
+func TestDiskBufferRetentionMaxSize(t *testing.T) {
+	tCtx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	dir := t.TempDir()
+	buf := diskBufFromConf(t, fmt.Sprintf(`
+path: %v
+segment_size: 100B
+retention:
+  max_size: 200B
+`, dir))
+	defer buf.Close(tCtx)
+
+	var contents []string
+	for i := range 10 {
+		contents = append(contents, fmt.Sprintf("message %v", i))
+	}
+	writeMessages(t, buf, contents...)
+
+	var total int64
+	for _, f := range segmentFiles(t, dir) {
+		info, err := os.Stat(f)
+		require.NoError(t, err)
+		total += info.Size()
+	}
+	assert.LessOrEqual(t, total, int64(200))
+
+	// The oldest messages were dropped, but the newest remain in order.
+	buf.EndOfInput()
+
+	var read []string
+	for {
+		batch, aFn, err := buf.ReadBatch(tCtx)
+		if errors.Is(err, service.ErrEndOfBuffer) {
+			break
+		}
+		require.NoError(t, err)
+		b, err := batch[0].AsBytes()
+		require.NoError(t, err)
+		read = append(read, string(b))
+		require.NoError(t, aFn(tCtx, nil))
+	}
+	require.NotEmpty(t, read)
+	assert.Less(t, len(read), len(contents))
+	assert.Equal(t, contents[len(contents)-len(read):], read)
+}
+
+func TestDiskBufferRetentionMaxAge(t *testing.T) {
+	tCtx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	dir := t.TempDir()
+	buf := diskBufFromConf(t, fmt.Sprintf(`
+path: %v
+segment_size: 50B
+retention:
+  max_age: 1h
+`, dir))
+	defer buf.Close(tCtx)
+
+	writeMessages(t, buf, "old", "new")
+	require.Len(t, segmentFiles(t, dir), 2)
+
+	buf.cond.L.Lock()
+	require.NoError(t, buf.enforceRetention(time.Now().Add(time.Hour*2)))
+	buf.cond.L.Unlock()
+
+	assert.Len(t, segmentFiles(t, dir), 1)
+
+	content, aFn := readMessage(t, buf)
+	assert.Equal(t, "new", content)
+	require.NoError(t, aFn(tCtx, nil))
+}
+
+func TestDiskBufferConfigErrors(t *testing.T) {
+	for _, conf := range []string{
+		`{ path: ./foo, segment_size: 0B }`,
+		`{ path: ./foo, segment_size: nope }`,
+		`{ path: ./foo, segment_size: 10MB, retention: { max_size: 1MB } }`,
+	} {
+		parsedConf, err := BufferConfig().ParseYAML(conf, nil)
+		require.NoError(t, err)
+
+		_, err = NewBufferFromConfig(parsedConf, service.MockResources())
+		require.Error(t, err, conf)
+	}
+}
@@ -0,0 +1,337 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package disk
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+// Each record within a segment is framed by a header consisting of the length
+// of the payload, a CRC of the sequence number and payload, and the sequence
+// number of the record.
+const (
+	recordHeaderLen = 16
+	segmentExt      = ".seg"
+	checkpointFile  = "checkpoint"
+)
+
+var (
+	crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+	errCorruptRecord = errors.New("record is corrupt")
+)
+
+type segment struct {
+	firstSeq uint64
+	path     string
+	f        *os.File
+	size     int64
+	modTime  time.Time
+}
+
+func segmentName(firstSeq uint64) string {
+	return fmt.Sprintf("%020d%v", firstSeq, segmentExt)
+}
+
+func createSegment(dir string, firstSeq uint64, syncWrites bool) (*segment, error) {
+	path := filepath.Join(dir, segmentName(firstSeq))
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	if syncWrites {
+		if err := syncDir(dir); err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+	}
+	return &segment{
+		firstSeq: firstSeq,
+		path:     path,
+		f:        f,
+		modTime:  time.Now(),
+	}, nil
+}
+
+// listSegments returns the sequence numbers that the segment files within a
+// directory start from, in ascending order.
+func listSegments(dir string) ([]uint64, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var seqs []uint64
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), segmentExt)
+		if !ok || e.IsDir() {
+			continue
+		}
+		seq, err := strconv.ParseUint(name, 10, 64)
+		if err != nil {
+			continue
+		}
+		seqs = append(seqs, seq)
+	}
+	// Names are zero padded and therefore ReadDir has already sorted them.
+	return seqs, nil
+}
+
+// openSegment opens an existing segment file and validates each of its
+// records, truncating the file at the first record that is incomplete or
+// fails its CRC check. Returns the sequence number of the last valid record
+// and the number of bytes that were discarded.
+func openSegment(dir string, firstSeq uint64) (seg *segment, lastSeq uint64, discarded int64, err error) {
+	path := filepath.Join(dir, segmentName(firstSeq))
+	f, err := os.OpenFile(path, os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, 0, 0, err
+	}
+	seg = &segment{
+		firstSeq: firstSeq,
+		path:     path,
+		f:        f,
+		size:     info.Size(),
+		modTime:  info.ModTime(),
+	}
+
+	var offset int64
+	for offset < seg.size {
+		seq, _, n, err := seg.readRecord(offset)
+		if err != nil || seq < firstSeq || (offset > 0 && seq <= lastSeq) {
+			break
+		}
+		lastSeq = seq
+		offset += n
+	}
+	if offset < seg.size {
+		if err := f.Truncate(offset); err != nil {
+			_ = f.Close()
+			return nil, 0, 0, err
+		}
+		discarded = seg.size - offset
+		seg.size = offset
+	}
+	return seg, lastSeq, discarded, nil
+}
+
+// readRecord reads the record at an offset of the segment, returning its
+// sequence number, payload and total length.
+func (s *segment) readRecord(offset int64) (seq uint64, payload []byte, n int64, err error) {
+	var header [recordHeaderLen]byte
+	if _, err := s.f.ReadAt(header[:], offset); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = errCorruptRecord
+		}
+		return 0, nil, 0, err
+	}
+	payloadLen := int64(binary.BigEndian.Uint32(header[0:4]))
+	crc := binary.BigEndian.Uint32(header[4:8])
+	seq = binary.BigEndian.Uint64(header[8:16])
+
+	if offset+recordHeaderLen+payloadLen > s.size {
+		return 0, nil, 0, errCorruptRecord
+	}
+	payload = make([]byte, payloadLen)
+	if _, err := s.f.ReadAt(payload, offset+recordHeaderLen); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = errCorruptRecord
+		}
+		return 0, nil, 0, err
+	}
+	if crc32.Update(crc32.Checksum(header[8:16], crcTable), crcTable, payload) != crc {
+		return 0, nil, 0, errCorruptRecord
+	}
+	return seq, payload, recordHeaderLen + payloadLen, nil
+}
+
+// appendRecord writes a record to the end of the segment. A failed write is
+// truncated so that the segment never contains a partial record that is
+// followed by later ones.
+func (s *segment) appendRecord(record []byte, syncWrites bool) error {
+	if _, err := s.f.WriteAt(record, s.size); err != nil {
+		_ = s.f.Truncate(s.size)
+		return err
+	}
+	if syncWrites {
+		if err := s.f.Sync(); err != nil {
+			_ = s.f.Truncate(s.size)
+			return err
+		}
+	}
+	s.size += int64(len(record))
+	s.modTime = time.Now()
+	return nil
+}
+
+func (s *segment) remove() error {
+	_ = s.f.Close()
+	s.f = nil
+	return os.Remove(s.path)
+}
+
+func encodeRecord(seq uint64, payload []byte) []byte {
+	record := make([]byte, recordHeaderLen, recordHeaderLen+len(payload))
+	binary.BigEndian.PutUint32(record[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint64(record[8:16], seq)
+	crc := crc32.Update(crc32.Checksum(record[8:16], crcTable), crcTable, payload)
+	binary.BigEndian.PutUint32(record[4:8], crc)
+	return append(record, payload...)
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+//------------------------------------------------------------------------------
+
+// readCheckpoint returns the sequence number below which all records have
+// been acknowledged, or zero when no valid checkpoint exists.
+func readCheckpoint(dir string) (uint64, error) {
+	b, err := os.ReadFile(filepath.Join(dir, checkpointFile))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	if len(b) != 12 || crc32.Checksum(b[:8], crcTable) != binary.BigEndian.Uint32(b[8:]) {
+		return 0, errCorruptRecord
+	}
+	return binary.BigEndian.Uint64(b[:8]), nil
+}
+
+// writeCheckpoint atomically replaces the checkpoint by writing it to a
+// temporary file and renaming it. The checkpoint is not synced, as losing it
+// only results in batches being delivered again.
+func writeCheckpoint(dir string, seq uint64) error {
+	var b [12]byte
+	binary.BigEndian.PutUint64(b[:8], seq)
+	binary.BigEndian.PutUint32(b[8:], crc32.Checksum(b[:8], crcTable))
+
+	tmpPath := filepath.Join(dir, checkpointFile+".tmp")
+	f, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(b[:])
+	if cErr := f.Close(); err == nil {
+		err = cErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, filepath.Join(dir, checkpointFile))
+}
+
+//------------------------------------------------------------------------------
+
+func appendUint32(buffer []byte, i uint32) []byte {
+	return binary.BigEndian.AppendUint32(buffer, i)
+}
+
+func readUint32(b []byte) (uint32, []byte, error) {
+	if len(b) < 4 {
+		return 0, nil, errCorruptRecord
+	}
+	return binary.BigEndian.Uint32(b), b[4:], nil
+}
+
+func readBytes(b []byte) ([]byte, []byte, error) {
+	l, b, err := readUint32(b)
+	if err != nil {
+		return nil, nil, err
+	}
+	if uint32(len(b)) < l {
+		return nil, nil, errCorruptRecord
+	}
+	return b[:l], b[l:], nil
+}
+
+// encodeBatch serialises the contents and metadata of a batch of messages.
+func encodeBatch(batch service.MessageBatch) ([]byte, error) {
+	buffer := appendUint32(nil, uint32(len(batch)))
+	for _, msg := range batch {
+		metaObj := map[string]any{}
+		_ = msg.MetaWalkMut(func(key string, value any) error {
+			metaObj[key] = value
+			return nil
+		})
+		metaBytes, err := msgpack.Marshal(metaObj)
+		if err != nil {
+			return nil, err
+		}
+		msgBytes, err := msg.AsBytes()
+		if err != nil {
+			return nil, err
+		}
+
+		buffer = appendUint32(buffer, uint32(len(metaBytes)))
+		buffer = append(buffer, metaBytes...)
+		buffer = appendUint32(buffer, uint32(len(msgBytes)))
+		buffer = append(buffer, msgBytes...)
+	}
+	return buffer, nil
+}
+
+func decodeBatch(b []byte) (service.MessageBatch, error) {
+	parts, b, err := readUint32(b)
+	if err != nil {
+		return nil, err
+	}
+	batch := make(service.MessageBatch, 0, parts)
+	for i := uint32(0); i < parts; i++ {
+		var metaBytes, msgBytes []byte
+		if metaBytes, b, err = readBytes(b); err != nil {
+			return nil, err
+		}
+		if msgBytes, b, err = readBytes(b); err != nil {
+			return nil, err
+		}
+
+		metaObj := map[string]any{}
+		if err := msgpack.Unmarshal(metaBytes, &metaObj); err != nil {
+			return nil, err
+		}
+		msg := service.NewMessage(msgBytes)
+		for k, v := range metaObj {
+			msg.MetaSetMut(k, v)
+		}
+		batch = append(batch, msg)
+	}
+	return batch, nil
+}
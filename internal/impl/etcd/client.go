@@ -0,0 +1,70 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcd
+
+import (
+	clientv3 "go.etcd.io/etcd/client/v3"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	cFieldEndpoints = "endpoints"
+	cFieldUsername  = "username"
+	cFieldPassword  = "password"
+	cFieldTLS       = "tls"
+)
+
+func clientFields() []*service.ConfigField {
+	return []*service.ConfigField{
+		service.NewStringListField(cFieldEndpoints).
+			Description("A list of endpoints of the etcd cluster to connect to.").
+			Example([]string{"127.0.0.1:2379"}).
+			Example([]string{"etcd-0:2379", "etcd-1:2379", "etcd-2:2379"}).
+			Default([]string{"127.0.0.1:2379"}),
+		service.NewStringField(cFieldUsername).
+			Description("An optional username to authenticate with.").
+			Default(""),
+		service.NewStringField(cFieldPassword).
+			Description("The password of the user to authenticate with.").
+			Default("").
+			Secret(),
+		service.NewTLSToggledField(cFieldTLS),
+	}
+}
+
+func clientConfigFromParsed(conf *service.ParsedConfig) (clientv3.Config, error) {
+	var c clientv3.Config
+
+	var err error
+	if c.Endpoints, err = conf.FieldStringList(cFieldEndpoints); err != nil {
+		return c, err
+	}
+	if c.Username, err = conf.FieldString(cFieldUsername); err != nil {
+		return c, err
+	}
+	if c.Password, err = conf.FieldString(cFieldPassword); err != nil {
+		return c, err
+	}
+
+	tlsConf, tlsEnabled, err := conf.FieldTLSToggled(cFieldTLS)
+	if err != nil {
+		return c, err
+	}
+	if tlsEnabled {
+		c.TLS = tlsConf
+	}
+	return c, nil
+}
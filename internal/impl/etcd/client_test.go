@@ -0,0 +1,52 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientConfig(t *testing.T) {
+	pConf, err := watchInputSpec().ParseYAML(`
+endpoints: [ etcd-0:2379, etcd-1:2379 ]
+username: root
+password: hunter2
+prefix: /app/
+tls:
+  enabled: true
+`, nil)
+	require.NoError(t, err)
+
+	conf, err := clientConfigFromParsed(pConf)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"etcd-0:2379", "etcd-1:2379"}, conf.Endpoints)
+	assert.Equal(t, "root", conf.Username)
+	assert.Equal(t, "hunter2", conf.Password)
+	assert.NotNil(t, conf.TLS)
+
+	pConf, err = kvOutputSpec().ParseYAML(`
+key: /app/a
+`, nil)
+	require.NoError(t, err)
+
+	conf, err = clientConfigFromParsed(pConf)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"127.0.0.1:2379"}, conf.Endpoints)
+	assert.Empty(t, conf.Username)
+	assert.Nil(t, conf.TLS)
+}
@@ -0,0 +1,242 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcd
+
+import (
+	"context"
+	"sync"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	wiFieldPrefix      = "prefix"
+	wiFieldEmitInitial = "emit_initial"
+)
+
+func watchInputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Services").
+		Version("4.45.0").
+		Summary("Watches the keys of an etcd cluster under a prefix, emitting a message for each key that is created, modified or deleted.").
+		Description(`
+When the input starts the keys under the prefix are read at the current revision, and every key is emitted as a `+"`put`"+` unless `+"`"+wiFieldEmitInitial+"`"+` is disabled. Changes made after that revision are then consumed with a watch, which resumes from the last revision received when the connection to a member is lost, so that no changes are missed.
+
+If the revision to resume from has been compacted the keys under the prefix are read and emitted again, and keys deleted in the meantime are not observed.
+
+Each message contains the value of the key, and deleted keys result in an empty message with the operation `+"`delete`"+`.
+
+== Metadata
+
+This input adds the following metadata fields to each message:
+
+- etcd_key
+- etcd_operation (`+"`put`"+` or `+"`delete`"+`)
+- etcd_create_revision
+- etcd_mod_revision
+- etcd_version
+- etcd_lease
+
+You can access these metadata fields using xref:configuration:interpolation.adoc#bloblang-queries[function interpolation].`).
+		Fields(clientFields()...).
+		Fields(
+			service.NewStringField(wiFieldPrefix).
+				Description("The prefix of the keys to watch, where an empty prefix watches all keys.").
+				Example("/config/my-service/"),
+			service.NewBoolField(wiFieldEmitInitial).
+				Description("Whether to emit the keys that exist when the input starts.").
+				Default(true),
+			service.NewAutoRetryNacksToggleField(),
+		)
+}
+
+func init() {
+	err := service.RegisterInput("etcd_watch", watchInputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Input, error) {
+			i, err := newWatchInputFromParsed(conf, mgr)
+			if err != nil {
+				return nil, err
+			}
+			return service.AutoRetryNacksToggled(conf, i)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type watchEvent struct {
+	kv        *mvccpb.KeyValue
+	operation string
+}
+
+type watchInput struct {
+	conf        clientv3.Config
+	prefix      string
+	emitInitial bool
+	log         *service.Logger
+
+	// Only accessed by Connect and Read, which aren't called concurrently.
+	revision int64
+	resync   bool
+	pending  []watchEvent
+
+	mut    sync.Mutex
+	client *clientv3.Client
+	watch  clientv3.WatchChan
+	cancel context.CancelFunc
+}
+
+func newWatchInputFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*watchInput, error) {
+	i := &watchInput{log: mgr.Logger(), resync: true}
+
+	var err error
+	if i.conf, err = clientConfigFromParsed(conf); err != nil {
+		return nil, err
+	}
+	if i.prefix, err = conf.FieldString(wiFieldPrefix); err != nil {
+		return nil, err
+	}
+	if i.emitInitial, err = conf.FieldBool(wiFieldEmitInitial); err != nil {
+		return nil, err
+	}
+	return i, nil
+}
+
+func (i *watchInput) Connect(ctx context.Context) error {
+	i.mut.Lock()
+	defer i.mut.Unlock()
+
+	if i.watch != nil {
+		return nil
+	}
+	if i.client == nil {
+		conf := i.conf
+		conf.Context = context.Background()
+		client, err := clientv3.New(conf)
+		if err != nil {
+			return err
+		}
+		i.client = client
+	}
+
+	if i.resync {
+		res, err := i.client.Get(ctx, i.prefix, clientv3.WithPrefix())
+		if err != nil {
+			return err
+		}
+		// Keys are emitted on the first connect only when enabled, but
+		// always after a compaction since changes may have been missed.
+		if i.revision > 0 || i.emitInitial {
+			for _, kv := range res.Kvs {
+				i.pending = append(i.pending, watchEvent{kv: kv, operation: "put"})
+			}
+		}
+		i.revision = res.Header.Revision
+		i.resync = false
+	}
+
+	// The client resumes the watch from the last revision received when the
+	// connection is lost, and requiring a leader prevents the watch from
+	// stalling on a member that is partitioned from the cluster.
+	watchCtx, cancel := context.WithCancel(clientv3.WithRequireLeader(context.Background()))
+	i.watch = i.client.Watch(watchCtx, i.prefix, clientv3.WithPrefix(), clientv3.WithRev(i.revision+1))
+	i.cancel = cancel
+	return nil
+}
+
+// disconnect cancels a watch, such that the next call to Connect creates a new
+// one from the last revision read.
+func (i *watchInput) disconnect(watch clientv3.WatchChan) {
+	i.mut.Lock()
+	defer i.mut.Unlock()
+	if i.watch == watch {
+		i.cancel()
+		i.watch = nil
+	}
+}
+
+func (i *watchInput) Read(ctx context.Context) (*service.Message, service.AckFunc, error) {
+	for len(i.pending) == 0 {
+		i.mut.Lock()
+		watch := i.watch
+		i.mut.Unlock()
+		if watch == nil {
+			return nil, nil, service.ErrNotConnected
+		}
+
+		var res clientv3.WatchResponse
+		var open bool
+		select {
+		case res, open = <-watch:
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		}
+
+		switch {
+		case !open:
+			i.disconnect(watch)
+			return nil, nil, service.ErrNotConnected
+		case res.CompactRevision > 0:
+			i.log.Warnf("Revision %v has been compacted, reading all keys again", i.revision+1)
+			i.resync = true
+			i.disconnect(watch)
+			return nil, nil, service.ErrNotConnected
+		case res.Err() != nil:
+			i.log.Warnf("Watch was closed: %v", res.Err())
+			i.disconnect(watch)
+			return nil, nil, service.ErrNotConnected
+		}
+
+		for _, e := range res.Events {
+			op := "put"
+			if e.Type == mvccpb.DELETE {
+				op = "delete"
+			}
+			i.pending = append(i.pending, watchEvent{kv: e.Kv, operation: op})
+			i.revision = max(i.revision, e.Kv.ModRevision)
+		}
+	}
+
+	e := i.pending[0]
+	i.pending = i.pending[1:]
+
+	msg := service.NewMessage(e.kv.Value)
+	msg.MetaSetMut("etcd_key", string(e.kv.Key))
+	msg.MetaSetMut("etcd_operation", e.operation)
+	msg.MetaSetMut("etcd_create_revision", e.kv.CreateRevision)
+	msg.MetaSetMut("etcd_mod_revision", e.kv.ModRevision)
+	msg.MetaSetMut("etcd_version", e.kv.Version)
+	msg.MetaSetMut("etcd_lease", e.kv.Lease)
+	return msg, func(context.Context, error) error { return nil }, nil
+}
+
+func (i *watchInput) Close(ctx context.Context) error {
+	i.mut.Lock()
+	defer i.mut.Unlock()
+	if i.cancel != nil {
+		i.cancel()
+		i.watch = nil
+	}
+	if i.client != nil {
+		err := i.client.Close()
+		i.client = nil
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,277 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcd
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ory/dockertest/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	clientv3 "go.etcd.io/etcd/client/v3"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+	"github.com/redpanda-data/benthos/v4/public/service/integration"
+)
+
+// setupEtcd runs an etcd server, returning its endpoint along with a client
+// connected to it.
+func setupEtcd(t *testing.T) (string, *clientv3.Client) {
+	t.Helper()
+
+	pool, err := dockertest.NewPool("")
+	require.NoError(t, err)
+
+	pool.MaxWait = time.Second * 30
+	resource, err := pool.RunWithOptions(&dockertest.RunOptions{
+		Repository: "quay.io/coreos/etcd",
+		Tag:        "v3.5.17",
+		Cmd: []string{
+			"etcd",
+			"--listen-client-urls", "http://0.0.0.0:2379",
+			"--advertise-client-urls", "http://0.0.0.0:2379",
+		},
+		ExposedPorts: []string{"2379/tcp"},
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, pool.Purge(resource))
+	})
+	_ = resource.Expire(900)
+
+	endpoint := "localhost:" + resource.GetPort("2379/tcp")
+	client, err := clientv3.New(clientv3.Config{
+		Endpoints:   []string{endpoint},
+		DialTimeout: time.Second * 5,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = client.Close()
+	})
+
+	require.NoError(t, pool.Retry(func() error {
+		ctx, done := context.WithTimeout(context.Background(), time.Second)
+		defer done()
+		_, err := client.Get(ctx, "health")
+		return err
+	}))
+	return endpoint, client
+}
+
+func put(t *testing.T, client *clientv3.Client, key, value string) {
+	t.Helper()
+	_, err := client.Put(context.Background(), key, value)
+	require.NoError(t, err)
+}
+
+func readEvent(t *testing.T, i *watchInput) (key, op, value string) {
+	t.Helper()
+
+	ctx, done := context.WithTimeout(context.Background(), 10*time.Second)
+	defer done()
+
+	for {
+		msg, ackFn, err := i.Read(ctx)
+		if err == service.ErrNotConnected {
+			require.NoError(t, i.Connect(ctx))
+			continue
+		}
+		require.NoError(t, err)
+		require.NoError(t, ackFn(ctx, nil))
+
+		b, err := msg.AsBytes()
+		require.NoError(t, err)
+		key, _ = msg.MetaGet("etcd_key")
+		op, _ = msg.MetaGet("etcd_operation")
+		return key, op, string(b)
+	}
+}
+
+func TestIntegrationEtcdWatch(t *testing.T) {
+	integration.CheckSkip(t)
+	t.Parallel()
+
+	endpoint, client := setupEtcd(t)
+	put(t, client, "/app/a", "1")
+	put(t, client, "/app/b", "2")
+	put(t, client, "/other/c", "3")
+
+	pConf, err := watchInputSpec().ParseYAML(`
+endpoints: [ `+endpoint+` ]
+prefix: /app/
+`, nil)
+	require.NoError(t, err)
+
+	i, err := newWatchInputFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = i.Close(context.Background())
+	})
+	require.NoError(t, i.Connect(context.Background()))
+
+	key, op, value := readEvent(t, i)
+	assert.Equal(t, []string{"/app/a", "put", "1"}, []string{key, op, value})
+	key, op, value = readEvent(t, i)
+	assert.Equal(t, []string{"/app/b", "put", "2"}, []string{key, op, value})
+
+	put(t, client, "/other/c", "4")
+	put(t, client, "/app/a", "5")
+	_, err = client.Delete(context.Background(), "/app/b")
+	require.NoError(t, err)
+
+	key, op, value = readEvent(t, i)
+	assert.Equal(t, []string{"/app/a", "put", "5"}, []string{key, op, value})
+	key, op, value = readEvent(t, i)
+	assert.Equal(t, []string{"/app/b", "delete", ""}, []string{key, op, value})
+}
+
+func TestIntegrationEtcdWatchCompacted(t *testing.T) {
+	integration.CheckSkip(t)
+	t.Parallel()
+
+	endpoint, client := setupEtcd(t)
+	put(t, client, "/app/a", "1")
+
+	pConf, err := watchInputSpec().ParseYAML(`
+endpoints: [ `+endpoint+` ]
+prefix: /app/
+emit_initial: false
+`, nil)
+	require.NoError(t, err)
+
+	i, err := newWatchInputFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = i.Close(context.Background())
+	})
+	require.NoError(t, i.Connect(context.Background()))
+
+	// Simulate the input falling behind a compaction by closing the watch.
+	i.mut.Lock()
+	i.cancel()
+	i.mut.Unlock()
+
+	put(t, client, "/app/b", "2")
+	put(t, client, "/app/b", "3")
+	res, err := client.Get(context.Background(), "/app/b")
+	require.NoError(t, err)
+	_, err = client.Compact(context.Background(), res.Header.Revision)
+	require.NoError(t, err)
+
+	key, op, value := readEvent(t, i)
+	assert.Equal(t, []string{"/app/a", "put", "1"}, []string{key, op, value})
+	key, op, value = readEvent(t, i)
+	assert.Equal(t, []string{"/app/b", "put", "3"}, []string{key, op, value})
+}
+
+func TestIntegrationEtcdKVOutput(t *testing.T) {
+	integration.CheckSkip(t)
+	t.Parallel()
+
+	endpoint, client := setupEtcd(t)
+	put(t, client, "/app/old/a", "1")
+	put(t, client, "/app/old/b", "2")
+	put(t, client, "/app/c", "3")
+
+	pConf, err := kvOutputSpec().ParseYAML(`
+endpoints: [ `+endpoint+` ]
+key: /app/${! @name }
+operation: ${! @op | "put" }
+`, nil)
+	require.NoError(t, err)
+
+	o, err := newKVOutputFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = o.Close(context.Background())
+	})
+	require.NoError(t, o.Connect(context.Background()))
+
+	batch := service.MessageBatch{
+		service.NewMessage([]byte("foo")),
+		service.NewMessage([]byte("bar")),
+		service.NewMessage(nil),
+		service.NewMessage(nil),
+	}
+	batch[0].MetaSetMut("name", "x")
+	batch[1].MetaSetMut("name", "y")
+	batch[2].MetaSetMut("name", "c")
+	batch[2].MetaSetMut("op", "delete")
+	batch[3].MetaSetMut("name", "old/")
+	batch[3].MetaSetMut("op", "delete_prefix")
+	require.NoError(t, o.WriteBatch(context.Background(), batch))
+
+	res, err := client.Get(context.Background(), "/app/", clientv3.WithPrefix())
+	require.NoError(t, err)
+
+	values := map[string]string{}
+	for _, kv := range res.Kvs {
+		values[string(kv.Key)] = string(kv.Value)
+	}
+	assert.Equal(t, map[string]string{
+		"/app/x": "foo",
+		"/app/y": "bar",
+	}, values)
+
+	batch = service.MessageBatch{service.NewMessage([]byte("foo"))}
+	batch[0].MetaSetMut("name", "z")
+	batch[0].MetaSetMut("op", "cas")
+	require.ErrorContains(t, o.WriteBatch(context.Background(), batch), `operation "cas" of message 0 is not supported`)
+}
+
+func TestIntegrationEtcdAuth(t *testing.T) {
+	integration.CheckSkip(t)
+	t.Parallel()
+
+	endpoint, client := setupEtcd(t)
+
+	ctx := context.Background()
+	_, err := client.UserAdd(ctx, "root", "hunter2")
+	require.NoError(t, err)
+	_, err = client.UserGrantRole(ctx, "root", "root")
+	require.NoError(t, err)
+	_, err = client.AuthEnable(ctx)
+	require.NoError(t, err)
+
+	pConf, err := kvOutputSpec().ParseYAML(`
+endpoints: [ `+endpoint+` ]
+username: root
+password: wrong
+key: /app/a
+`, nil)
+	require.NoError(t, err)
+
+	o, err := newKVOutputFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+	require.Error(t, o.Connect(ctx))
+
+	pConf, err = kvOutputSpec().ParseYAML(`
+endpoints: [ `+endpoint+` ]
+username: root
+password: hunter2
+key: /app/a
+`, nil)
+	require.NoError(t, err)
+
+	o, err = newKVOutputFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = o.Close(context.Background())
+	})
+	require.NoError(t, o.Connect(ctx))
+	require.NoError(t, o.WriteBatch(ctx, service.MessageBatch{service.NewMessage([]byte("foo"))}))
+}
@@ -0,0 +1,194 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	koFieldKey       = "key"
+	koFieldOperation = "operation"
+	koFieldBatching  = "batching"
+)
+
+func kvOutputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Services").
+		Version("4.45.0").
+		Summary("Writes messages as keys of an etcd cluster.").
+		Description(`
+The messages of each batch are written within a single transaction, so that either all keys of a batch are written or none of them are. By default etcd limits transactions to `+"`128`"+` operations, which is configured with the `+"`--max-txn-ops`"+` flag of the server, and therefore batches should not exceed this limit.
+
+When `+"`"+koFieldOperation+"`"+` resolves to `+"`delete`"+` the key is deleted and the contents of the message are ignored, and `+"`delete_prefix`"+` deletes every key with the key as a prefix.`+service.OutputPerformanceDocs(true, true)).
+		Fields(clientFields()...).
+		Fields(
+			service.NewInterpolatedStringField(koFieldKey).
+				Description("The key to write each message to.").
+				Example(`/config/${! @kafka_key }`),
+			service.NewInterpolatedStringField(koFieldOperation).
+				Description("The operation to perform with each message, which must resolve to either `put`, `delete` or `delete_prefix`.").
+				Example(`${! @etcd_operation }`).
+				Default("put"),
+			service.NewOutputMaxInFlightField(),
+			service.NewBatchPolicyField(koFieldBatching),
+		).
+		Example("Mirror Keys", "Mirror the keys under a prefix of one etcd cluster into another, including deletions.", `
+input:
+  etcd_watch:
+    endpoints: [ etcd-a:2379 ]
+    prefix: /config/
+
+output:
+  etcd_kv:
+    endpoints: [ etcd-b:2379 ]
+    key: ${! @etcd_key }
+    operation: ${! @etcd_operation }
+    batching:
+      count: 100
+      period: 100ms
+`)
+}
+
+func init() {
+	err := service.RegisterBatchOutput("etcd_kv", kvOutputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPolicy service.BatchPolicy, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldMaxInFlight(); err != nil {
+				return
+			}
+			if batchPolicy, err = conf.FieldBatchPolicy(koFieldBatching); err != nil {
+				return
+			}
+			out, err = newKVOutputFromParsed(conf, mgr)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type kvOutput struct {
+	conf      clientv3.Config
+	key       *service.InterpolatedString
+	operation *service.InterpolatedString
+
+	mut    sync.RWMutex
+	client *clientv3.Client
+}
+
+func newKVOutputFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*kvOutput, error) {
+	o := &kvOutput{}
+
+	var err error
+	if o.conf, err = clientConfigFromParsed(conf); err != nil {
+		return nil, err
+	}
+	if o.key, err = conf.FieldInterpolatedString(koFieldKey); err != nil {
+		return nil, err
+	}
+	if o.operation, err = conf.FieldInterpolatedString(koFieldOperation); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+func (o *kvOutput) Connect(ctx context.Context) error {
+	o.mut.Lock()
+	defer o.mut.Unlock()
+
+	if o.client != nil {
+		return nil
+	}
+
+	conf := o.conf
+	conf.Context = context.Background()
+	client, err := clientv3.New(conf)
+	if err != nil {
+		return err
+	}
+	o.client = client
+	return nil
+}
+
+func (o *kvOutput) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	o.mut.RLock()
+	client := o.client
+	o.mut.RUnlock()
+	if client == nil {
+		return service.ErrNotConnected
+	}
+
+	keyExec := batch.InterpolationExecutor(o.key)
+	opExec := batch.InterpolationExecutor(o.operation)
+
+	ops := make([]clientv3.Op, 0, len(batch))
+	for i, msg := range batch {
+		key, err := keyExec.TryString(i)
+		if err != nil {
+			return fmt.Errorf("key interpolation error: %w", err)
+		}
+		if key == "" {
+			return errors.New("key interpolation resulted in an empty key")
+		}
+		verb, err := opExec.TryString(i)
+		if err != nil {
+			return fmt.Errorf("operation interpolation error: %w", err)
+		}
+
+		switch verb {
+		case "put":
+			value, err := msg.AsBytes()
+			if err != nil {
+				return err
+			}
+			ops = append(ops, clientv3.OpPut(key, string(value)))
+		case "delete":
+			ops = append(ops, clientv3.OpDelete(key))
+		case "delete_prefix":
+			ops = append(ops, clientv3.OpDelete(key, clientv3.WithPrefix()))
+		default:
+			return fmt.Errorf("operation %q of message %v is not supported", verb, i)
+		}
+	}
+
+	res, err := client.Txn(ctx).Then(ops...).Commit()
+	if err != nil {
+		return err
+	}
+	if !res.Succeeded {
+		return errors.New("transaction did not succeed")
+	}
+	return nil
+}
+
+func (o *kvOutput) Close(ctx context.Context) error {
+	o.mut.Lock()
+	defer o.mut.Unlock()
+
+	if o.client == nil {
+		return nil
+	}
+	err := o.client.Close()
+	o.client = nil
+	return err
+}
@@ -17,8 +17,10 @@ package gcp
 import (
 	"context"
 	"errors"
+	"fmt"
 	"strings"
 	"sync"
+	"time"
 
 	"cloud.google.com/go/pubsub"
 	"google.golang.org/api/option"
@@ -35,6 +37,11 @@ const (
 	pbiFieldMaxOutstandingMessages = "max_outstanding_messages"
 	pbiFieldMaxOutstandingBytes    = "max_outstanding_bytes"
 	pbiFieldSync                   = "sync"
+	pbiFieldExactlyOnce            = "exactly_once_delivery"
+	pbiFieldAckDeadline            = "ack_deadline"
+	pbiFieldAckMaxExtension        = "max_extension"
+	pbiFieldAckMaxExtensionPeriod  = "max_extension_period"
+	pbiFieldAckMinExtensionPeriod  = "min_extension_period"
 	pbiFieldCreateSub              = "create_subscription"
 	pbiFieldCreateSubEnabled       = "enabled"
 	pbiFieldCreateSubTopicID       = "topic"
@@ -48,6 +55,10 @@ type pbiConfig struct {
 	MaxOutstandingMessages int
 	MaxOutstandingBytes    int
 	Sync                   bool
+	ExactlyOnce            bool
+	MaxExtension           time.Duration
+	MaxExtensionPeriod     time.Duration
+	MinExtensionPeriod     time.Duration
 	CreateEnabled          bool
 	CreateTopicID          string
 }
@@ -74,6 +85,26 @@ func pbiConfigFromParsed(pConf *service.ParsedConfig) (conf pbiConfig, err error
 	if conf.Sync, err = pConf.FieldBool(pbiFieldSync); err != nil {
 		return
 	}
+	if conf.ExactlyOnce, err = pConf.FieldBool(pbiFieldExactlyOnce); err != nil {
+		return
+	}
+
+	ackConf := pConf.Namespace(pbiFieldAckDeadline)
+	if conf.MaxExtension, err = ackConf.FieldDuration(pbiFieldAckMaxExtension); err != nil {
+		return
+	}
+	if conf.MaxExtensionPeriod, err = ackConf.FieldDuration(pbiFieldAckMaxExtensionPeriod); err != nil {
+		return
+	}
+	if err = validateExtensionPeriod(pbiFieldAckMaxExtensionPeriod, conf.MaxExtensionPeriod); err != nil {
+		return
+	}
+	if conf.MinExtensionPeriod, err = ackConf.FieldDuration(pbiFieldAckMinExtensionPeriod); err != nil {
+		return
+	}
+	if err = validateExtensionPeriod(pbiFieldAckMinExtensionPeriod, conf.MinExtensionPeriod); err != nil {
+		return
+	}
 	if pConf.Contains(pbiFieldCreateSub) {
 		createConf := pConf.Namespace(pbiFieldCreateSub)
 		if conf.CreateEnabled, err = createConf.FieldBool(pbiFieldCreateSubEnabled); err != nil {
@@ -86,6 +117,15 @@ func pbiConfigFromParsed(pConf *service.ParsedConfig) (conf pbiConfig, err error
 	return
 }
 
+// validateExtensionPeriod checks that an ack extension period is either
+// disabled or within the bounds accepted by the Pub/Sub client.
+func validateExtensionPeriod(field string, d time.Duration) error {
+	if d > 0 && (d < 10*time.Second || d > 600*time.Second) {
+		return fmt.Errorf("field %v must be zero or between 10s and 600s, got %v", field, d)
+	}
+	return nil
+}
+
 func pbiSpec() *service.ConfigSpec {
 	return service.NewConfigSpec().
 		Stable().
@@ -103,6 +143,12 @@ This input adds the following metadata fields to each message:
 - All message attributes
 
 You can access these metadata fields using xref:configuration:interpolation.adoc#bloblang-queries[function interpolation].
+
+== Exactly-once delivery
+
+When consuming from a subscription with https://cloud.google.com/pubsub/docs/exactly-once-delivery[exactly-once delivery^] enabled, set `+"`exactly_once_delivery`"+` to `+"`true`"+` so that acknowledgements are confirmed by the server before messages are considered delivered. Acknowledgements that the server rejects, for example because the ack deadline has expired, are then reported as errors and the message will be redelivered. When combined with `+"`create_subscription`"+` the created subscription has exactly-once delivery enabled.
+
+With exactly-once delivery the ack deadline of a message cannot be extended after it expires, and therefore it can be beneficial to tune the `+"`ack_deadline`"+` fields so that extensions are requested well ahead of expiry.
 `).
 		Fields(
 			service.NewStringField(pbiFieldProjectID).
@@ -127,6 +173,25 @@ You can access these metadata fields using xref:configuration:interpolation.adoc
 			service.NewIntField(pbiFieldMaxOutstandingBytes).
 				Description("The maximum number of outstanding pending messages to be consumed measured in bytes.").
 				Default(1e9), // pubsub.DefaultReceiveSettings.MaxOutstandingBytes (1G)
+			service.NewBoolField(pbiFieldExactlyOnce).
+				Description("Whether to wait for the server to confirm each acknowledgement, which is required in order to benefit from the guarantees of subscriptions with exactly-once delivery enabled. When this is set and a subscription is created it will have exactly-once delivery enabled.").
+				Version("4.45.0").
+				Default(false),
+			service.NewObjectField(pbiFieldAckDeadline,
+				service.NewDurationField(pbiFieldAckMaxExtension).
+					Description("The maximum period for which the ack deadline of each message is automatically extended. Automatic extension beyond the initial receipt can be disabled with a negative duration.").
+					Default("60m"), // pubsub.DefaultReceiveSettings.MaxExtension
+				service.NewDurationField(pbiFieldAckMaxExtensionPeriod).
+					Description("The maximum duration by which to extend the ack deadline at a time, which bounds the amount of time before a message is redelivered should the consumer fail to extend it. Must be between 10s and 600s, or zero to disable.").
+					Default("0s"),
+				service.NewDurationField(pbiFieldAckMinExtensionPeriod).
+					Description("The minimum duration by which to extend the ack deadline at a time, which can reduce the number of extension requests made. Must be between 10s and 600s, or zero to disable.").
+					Example("60s").
+					Default("0s"),
+			).
+				Description("Tune the automatic extension of ack deadlines for messages that are being processed.").
+				Version("4.45.0").
+				Advanced(),
 			service.NewObjectField(pbiFieldCreateSub,
 				service.NewBoolField(pbiFieldCreateSubEnabled).
 					Description("Whether to configure subscription or not.").Default(false),
@@ -171,7 +236,10 @@ func createSubscription(conf pbiConfig, client *pubsub.Client, log *service.Logg
 	}
 
 	log.Infof("Creating subscription '%v' on topic '%v'\n", conf.SubscriptionID, conf.CreateTopicID)
-	_, err = client.CreateSubscription(context.Background(), conf.SubscriptionID, pubsub.SubscriptionConfig{Topic: client.Topic(conf.CreateTopicID)})
+	_, err = client.CreateSubscription(context.Background(), conf.SubscriptionID, pubsub.SubscriptionConfig{
+		Topic:                     client.Topic(conf.CreateTopicID),
+		EnableExactlyOnceDelivery: conf.ExactlyOnce,
+	})
 	if err != nil {
 		log.Errorf("Error creating subscription %v", err)
 	}
@@ -233,6 +301,9 @@ func (c *gcpPubSubReader) Connect(ignored context.Context) error {
 	sub.ReceiveSettings.MaxOutstandingMessages = c.conf.MaxOutstandingMessages
 	sub.ReceiveSettings.MaxOutstandingBytes = c.conf.MaxOutstandingBytes
 	sub.ReceiveSettings.Synchronous = c.conf.Sync
+	sub.ReceiveSettings.MaxExtension = c.conf.MaxExtension
+	sub.ReceiveSettings.MaxExtensionPeriod = c.conf.MaxExtensionPeriod
+	sub.ReceiveSettings.MinExtensionPeriod = c.conf.MinExtensionPeriod
 
 	subCtx, cancel := context.WithCancel(context.Background())
 	msgsChan := make(chan *pubsub.Message, 1)
@@ -293,6 +364,22 @@ func (c *gcpPubSubReader) Read(ctx context.Context) (*service.Message, service.A
 		part.MetaSetMut("gcp_pubsub_delivery_attempt", *gmsg.DeliveryAttempt)
 	}
 
+	if c.conf.ExactlyOnce {
+		return part, func(ctx context.Context, res error) error {
+			var ackRes *pubsub.AckResult
+			if res != nil {
+				ackRes = gmsg.NackWithResult()
+			} else {
+				ackRes = gmsg.AckWithResult()
+			}
+			status, err := ackRes.Get(ctx)
+			if err != nil {
+				return fmt.Errorf("acknowledgement failed with status %v: %w", status, err)
+			}
+			return nil
+		}, nil
+	}
+
 	return part, func(ctx context.Context, res error) error {
 		if res != nil {
 			gmsg.Nack()
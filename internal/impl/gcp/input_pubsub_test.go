@@ -0,0 +1,56 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gcp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPubSubInputAckDeadlineConfig(t *testing.T) {
+	pConf, err := pbiSpec().ParseYAML(`
+project: sample-project
+subscription: test
+exactly_once_delivery: true
+ack_deadline:
+  max_extension: 10m
+  min_extension_period: 60s
+`, nil)
+	require.NoError(t, err)
+
+	conf, err := pbiConfigFromParsed(pConf)
+	require.NoError(t, err)
+
+	assert.True(t, conf.ExactlyOnce)
+	assert.Equal(t, 10*time.Minute, conf.MaxExtension)
+	assert.Equal(t, time.Duration(0), conf.MaxExtensionPeriod)
+	assert.Equal(t, time.Minute, conf.MinExtensionPeriod)
+}
+
+func TestPubSubInputAckDeadlineConfigBadPeriod(t *testing.T) {
+	pConf, err := pbiSpec().ParseYAML(`
+project: sample-project
+subscription: test
+ack_deadline:
+  max_extension_period: 5s
+`, nil)
+	require.NoError(t, err)
+
+	_, err = pbiConfigFromParsed(pConf)
+	require.ErrorContains(t, err, "max_extension_period")
+}
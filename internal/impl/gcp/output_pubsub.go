@@ -71,7 +71,7 @@ pipeline:
 				Description("An optional endpoint to override the default of `pubsub.googleapis.com:443`. This can be used to connect to a region specific pubsub endpoint. For a list of valid values, see https://cloud.google.com/pubsub/docs/reference/service_apis_overview#list_of_regional_endpoints[this document^]."),
 			service.NewInterpolatedStringField("ordering_key").
 				Optional().
-				Description("The ordering key to use for publishing messages.").
+				Description("The ordering key to use for publishing messages. When set, message ordering is enabled on the topic and messages sharing a key are delivered in order to subscriptions with message ordering enabled. If a message fails to publish then publishing for its key is resumed so that the message can be retried.").
 				Advanced(),
 			service.NewIntField("max_in_flight").Default(64).Description("The maximum number of messages to have in flight at a given time. Increasing this may improve throughput."),
 			service.NewIntField("count_threshold").
@@ -353,11 +353,31 @@ func (out *pubsubOutput) writeMessage(ctx context.Context, cachedTopics map[stri
 		return nil, fmt.Errorf("failed to get bytes from message: %w", err)
 	}
 
-	return topic.Publish(ctx, &pubsub.Message{
+	res := topic.Publish(ctx, &pubsub.Message{
 		Data:        data,
 		Attributes:  attr,
 		OrderingKey: orderingKey,
-	}), nil
+	})
+	if orderingKey != "" {
+		res = &orderedPublishResult{publishResult: res, topic: topic, orderingKey: orderingKey}
+	}
+	return res, nil
+}
+
+// orderedPublishResult resumes publishing for an ordering key after a failed
+// publish, as the client otherwise rejects all further messages of that key.
+type orderedPublishResult struct {
+	publishResult
+	topic       pubsubTopic
+	orderingKey string
+}
+
+func (o *orderedPublishResult) Get(ctx context.Context) (string, error) {
+	serverID, err := o.publishResult.Get(ctx)
+	if err != nil {
+		o.topic.ResumePublish(o.orderingKey)
+	}
+	return serverID, err
 }
 
 func (out *pubsubOutput) getTopic(ctx context.Context, name string) (pubsubTopic, error) {
@@ -150,6 +150,50 @@ func TestPubSubOutput_MessageAttr(t *testing.T) {
 	require.Equal(t, "foo_1", psmsg.OrderingKey)
 }
 
+func TestPubSubOutput_ResumeOrderingKey(t *testing.T) {
+	ctx := context.Background()
+
+	conf, err := newPubSubOutputConfig().ParseYAML(`
+    project: sample-project
+    topic: test
+    ordering_key: '${! content().string() }'
+    `,
+		nil,
+	)
+	require.NoError(t, err, "bad output config")
+
+	client := &mockPubSubClient{}
+
+	fooTopic := &mockTopic{}
+	fooTopic.On("Exists").Return(true, nil).Once()
+	fooTopic.On("EnableOrdering").Return().Once()
+	fooTopic.On("ResumePublish", "foo").Return().Once()
+	fooTopic.On("Stop").Return().Once()
+
+	fooMsg := &mockPublishResult{}
+	fooMsg.On("Get").Return("", errors.New("simulated error")).Once()
+	fooTopic.On("Publish", "foo", mock.AnythingOfType("*pubsub.Message")).Return(fooMsg).Once()
+
+	client.On("Topic", "test").Return(fooTopic).Once()
+	client.On("Close").Return(nil).Once()
+
+	out, err := newPubSubOutput(conf)
+	require.NoError(t, err, "failed to create output")
+	out.client = client
+	t.Cleanup(func() {
+		err = out.Close(ctx)
+		require.NoError(t, err, "closing output failed")
+
+		mock.AssertExpectationsForObjects(t, client, fooTopic, fooMsg)
+	})
+
+	err = out.Connect(ctx)
+	require.NoError(t, err, "connect failed")
+
+	err = out.WriteBatch(ctx, service.MessageBatch{service.NewMessage([]byte("foo"))})
+	require.ErrorContains(t, err, "simulated error")
+}
+
 func TestPubSubOutput_MissingTopic(t *testing.T) {
 	ctx := context.Background()
 
@@ -0,0 +1,347 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/redpanda-data/benthos/v4/public/bloblang"
+	"github.com/redpanda-data/benthos/v4/public/service"
+
+	"github.com/redpanda-data/connect/v4/internal/httpquota"
+)
+
+const (
+	gpFieldURL              = "url"
+	gpFieldQuery            = "query"
+	gpFieldOperationName    = "operation_name"
+	gpFieldVariables        = "variables"
+	gpFieldHeaders          = "headers"
+	gpFieldPersistedQueries = "persisted_queries"
+	gpFieldTimeout          = "timeout"
+	gpFieldQuota            = "quota"
+	gpFieldTLS              = "tls"
+)
+
+func processorSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Categories("Integration").
+		Version("4.45.0").
+		Summary("Executes a GraphQL query or mutation for each message and replaces the message with the resulting data.").
+		Description(`
+Variables of the operation are created for each message with a `+"`variables`"+` mapping, and the `+"`data`"+` of each response replaces the contents of the message.
+
+== Errors
+
+When a response contains GraphQL errors the message is flagged as failed, with an error that summarises the messages of each error, and can be handled using xref:configuration:error_handling.adoc[error handling patterns]. Any partial `+"`data`"+` of the response still replaces the contents of the message. The full list of errors is added to the metadata field `+"`graphql_errors`"+` as a JSON array, and the distinct `+"`extensions.code`"+` values of the errors are added to the metadata field `+"`graphql_error_codes`"+` as a comma separated list, which is useful for routing messages based on the type of failure.
+
+Requests that fail without a GraphQL response, such as connection errors or responses with an unexpected status code, result in the message being flagged as failed with its contents unchanged.
+
+== Persisted queries
+
+When `+"`persisted_queries`"+` is enabled requests are sent without the query document, and instead contain its SHA-256 hash following the https://www.apollographql.com/docs/apollo-server/performance/apq[automatic persisted queries^] protocol. If the server responds with a `+"`PersistedQueryNotFound`"+` error the request is repeated with the full query, which registers it with the server for subsequent requests.`).
+		Fields(
+			service.NewURLField(gpFieldURL).
+				Description("The URL of the GraphQL endpoint.").
+				Example("https://api.example.com/graphql"),
+			service.NewStringField(gpFieldQuery).
+				Description("The GraphQL document containing the query or mutation to execute.").
+				Example(`query GetUser($id: ID!) { user(id: $id) { name email } }`),
+			service.NewStringField(gpFieldOperationName).
+				Description("The name of the operation to execute, which is required when the document contains multiple operations.").
+				Default("").
+				Advanced(),
+			service.NewBloblangField(gpFieldVariables).
+				Description("An optional xref:guides:bloblang/about.adoc[Bloblang mapping] that creates the variables of the operation from each message, which must result in an object.").
+				Example(`root.id = this.user_id`).
+				Optional(),
+			service.NewInterpolatedStringMapField(gpFieldHeaders).
+				Description("A map of headers to add to each request.").
+				Example(map[string]any{"Authorization": "Bearer ${! env(\"API_TOKEN\") }"}).
+				Default(map[string]any{}),
+			service.NewBoolField(gpFieldPersistedQueries).
+				Description("Whether to send the hash of the query instead of the full document following the automatic persisted queries protocol.").
+				Default(false).
+				Advanced(),
+			service.NewDurationField(gpFieldTimeout).
+				Description("The maximum period to wait for each request to complete.").
+				Default("10s"),
+			httpquota.Field(gpFieldQuota),
+			service.NewTLSToggledField(gpFieldTLS),
+		).
+		Example("Enrich with a query", "Look up a user for each message and add the result to a new field.", `
+pipeline:
+  processors:
+    - branch:
+        processors:
+          - graphql:
+              url: https://api.example.com/graphql
+              query: 'query GetUser($id: ID!) { user(id: $id) { name email } }'
+              variables: 'root.id = this.user_id'
+        result_map: 'root.user = this.user'
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor("graphql", processorSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return newProcessorFromParsed(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type processor struct {
+	url           string
+	query         string
+	queryHash     string
+	operationName string
+	variables     *bloblang.Executor
+	headers       map[string]*service.InterpolatedString
+	persisted     bool
+	timeout       time.Duration
+	quota         *httpquota.Tracker
+	client        *http.Client
+	log           *service.Logger
+}
+
+func newProcessorFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*processor, error) {
+	p := &processor{log: mgr.Logger()}
+
+	var err error
+	if p.url, err = conf.FieldString(gpFieldURL); err != nil {
+		return nil, err
+	}
+	if p.query, err = conf.FieldString(gpFieldQuery); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(p.query) == "" {
+		return nil, errors.New("query must not be empty")
+	}
+	queryHash := sha256.Sum256([]byte(p.query))
+	p.queryHash = hex.EncodeToString(queryHash[:])
+
+	if p.operationName, err = conf.FieldString(gpFieldOperationName); err != nil {
+		return nil, err
+	}
+	if conf.Contains(gpFieldVariables) {
+		if p.variables, err = conf.FieldBloblang(gpFieldVariables); err != nil {
+			return nil, err
+		}
+	}
+	if p.headers, err = conf.FieldInterpolatedStringMap(gpFieldHeaders); err != nil {
+		return nil, err
+	}
+	if p.persisted, err = conf.FieldBool(gpFieldPersistedQueries); err != nil {
+		return nil, err
+	}
+	if p.timeout, err = conf.FieldDuration(gpFieldTimeout); err != nil {
+		return nil, err
+	}
+
+	if p.quota, err = httpquota.TrackerFromParsed(conf.Namespace(gpFieldQuota), mgr); err != nil {
+		return nil, err
+	}
+
+	tlsConf, tlsEnabled, err := conf.FieldTLSToggled(gpFieldTLS)
+	if err != nil {
+		return nil, err
+	}
+	p.client = &http.Client{Timeout: p.timeout}
+	if tlsEnabled && tlsConf != nil {
+		if c, ok := http.DefaultTransport.(*http.Transport); ok {
+			cloned := c.Clone()
+			cloned.TLSClientConfig = tlsConf
+			p.client.Transport = cloned
+		} else {
+			p.client.Transport = &http.Transport{
+				TLSClientConfig: tlsConf,
+			}
+		}
+	}
+	return p, nil
+}
+
+type gqlRequest struct {
+	Query         string         `json:"query,omitempty"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+	Extensions    map[string]any `json:"extensions,omitempty"`
+}
+
+type gqlError struct {
+	Message    string         `json:"message"`
+	Extensions map[string]any `json:"extensions,omitempty"`
+}
+
+type gqlResponse struct {
+	Data   json.RawMessage   `json:"data"`
+	Errors []json.RawMessage `json:"errors"`
+}
+
+func (p *processor) send(ctx context.Context, msg *service.Message, req gqlRequest) (*gqlResponse, error) {
+	reqBytes, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	hReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(reqBytes))
+	if err != nil {
+		return nil, err
+	}
+	hReq.Header.Set("Content-Type", "application/json")
+	hReq.Header.Set("Accept", "application/graphql-response+json, application/json")
+	for k, v := range p.headers {
+		vStr, err := v.TryString(msg)
+		if err != nil {
+			return nil, fmt.Errorf("header %v interpolation: %w", k, err)
+		}
+		hReq.Header.Set(k, vStr)
+	}
+
+	if err := p.quota.Wait(ctx); err != nil {
+		return nil, err
+	}
+
+	res, err := p.client.Do(hReq)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	p.quota.Observe(res)
+
+	resBytes, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var gRes gqlResponse
+	if err := json.Unmarshal(resBytes, &gRes); err != nil || (gRes.Data == nil && len(gRes.Errors) == 0) {
+		if res.StatusCode < 200 || res.StatusCode > 299 {
+			return nil, fmt.Errorf("request returned unexpected response code: %v", res.StatusCode)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse response: %w", err)
+		}
+		return nil, errors.New("response does not contain data or errors")
+	}
+	return &gRes, nil
+}
+
+func persistedQueryNotFound(errs []gqlError) bool {
+	for _, e := range errs {
+		if e.Message == "PersistedQueryNotFound" || e.Extensions["code"] == "PERSISTED_QUERY_NOT_FOUND" {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *processor) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	req := gqlRequest{
+		Query:         p.query,
+		OperationName: p.operationName,
+	}
+
+	if p.variables != nil {
+		varsMsg, err := msg.BloblangQuery(p.variables)
+		if err != nil {
+			return nil, fmt.Errorf("variables mapping failed: %w", err)
+		}
+		if varsMsg != nil {
+			vars, err := varsMsg.AsStructured()
+			if err != nil {
+				return nil, fmt.Errorf("variables mapping failed: %w", err)
+			}
+			var ok bool
+			if req.Variables, ok = vars.(map[string]any); !ok {
+				return nil, fmt.Errorf("variables mapping must result in an object, got %T", vars)
+			}
+		}
+	}
+
+	if p.persisted {
+		req.Query = ""
+		req.Extensions = map[string]any{
+			"persistedQuery": map[string]any{
+				"version":    1,
+				"sha256Hash": p.queryHash,
+			},
+		}
+	}
+
+	res, err := p.send(ctx, msg, req)
+	if err != nil {
+		return nil, err
+	}
+
+	errs := make([]gqlError, len(res.Errors))
+	for i, raw := range res.Errors {
+		_ = json.Unmarshal(raw, &errs[i])
+	}
+
+	if p.persisted && persistedQueryNotFound(errs) {
+		p.log.Debug("Persisted query not found, retrying with the full query")
+		req.Query = p.query
+		if res, err = p.send(ctx, msg, req); err != nil {
+			return nil, err
+		}
+		errs = make([]gqlError, len(res.Errors))
+		for i, raw := range res.Errors {
+			_ = json.Unmarshal(raw, &errs[i])
+		}
+	}
+
+	if len(res.Data) > 0 && string(res.Data) != "null" {
+		msg.SetBytes(res.Data)
+	}
+
+	if len(errs) > 0 {
+		errsBytes, _ := json.Marshal(res.Errors)
+		msg.MetaSetMut("graphql_errors", string(errsBytes))
+
+		var codes, messages []string
+		seenCodes := map[string]struct{}{}
+		for _, e := range errs {
+			messages = append(messages, e.Message)
+			code, _ := e.Extensions["code"].(string)
+			if _, seen := seenCodes[code]; code != "" && !seen {
+				seenCodes[code] = struct{}{}
+				codes = append(codes, code)
+			}
+		}
+		if len(codes) > 0 {
+			msg.MetaSetMut("graphql_error_codes", strings.Join(codes, ","))
+		}
+		msg.SetError(fmt.Errorf("graphql errors: %v", strings.Join(messages, "; ")))
+	}
+	return service.MessageBatch{msg}, nil
+}
+
+func (p *processor) Close(ctx context.Context) error {
+	p.client.CloseIdleConnections()
+	return nil
+}
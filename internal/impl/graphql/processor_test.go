@@ -0,0 +1,232 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+type testGraphQLServer struct {
+	mut        sync.Mutex
+	requests   []gqlRequest
+	persisted  map[string]string
+	respond    func(req gqlRequest) (int, string)
+	authHeader string
+}
+
+func newTestGraphQLServer(t *testing.T, respond func(req gqlRequest) (int, string)) (*testGraphQLServer, string) {
+	t.Helper()
+
+	s := &testGraphQLServer{persisted: map[string]string{}, respond: respond}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req gqlRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+		s.mut.Lock()
+		s.requests = append(s.requests, req)
+		s.authHeader = r.Header.Get("Authorization")
+		if pq, ok := req.Extensions["persistedQuery"].(map[string]any); ok {
+			hash, _ := pq["sha256Hash"].(string)
+			if req.Query == "" {
+				if req.Query = s.persisted[hash]; req.Query == "" {
+					s.mut.Unlock()
+					_, _ = w.Write([]byte(`{"errors":[{"message":"PersistedQueryNotFound","extensions":{"code":"PERSISTED_QUERY_NOT_FOUND"}}]}`))
+					return
+				}
+			} else {
+				s.persisted[hash] = req.Query
+			}
+		}
+		s.mut.Unlock()
+
+		code, body := s.respond(req)
+		w.WriteHeader(code)
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return s, srv.URL
+}
+
+func testProcessor(t *testing.T, yamlStr string) *processor {
+	t.Helper()
+
+	conf, err := processorSpec().ParseYAML(yamlStr, nil)
+	require.NoError(t, err)
+
+	p, err := newProcessorFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = p.Close(context.Background())
+	})
+	return p
+}
+
+func TestProcessorQuery(t *testing.T) {
+	s, url := newTestGraphQLServer(t, func(req gqlRequest) (int, string) {
+		return http.StatusOK, `{"data":{"user":{"id":"` + req.Variables["id"].(string) + `","name":"foo"}}}`
+	})
+
+	p := testProcessor(t, `
+url: `+url+`
+query: 'query GetUser($id: ID!) { user(id: $id) { id name } }'
+operation_name: GetUser
+variables: 'root.id = this.user_id'
+headers:
+  Authorization: Bearer ${! @token }
+`)
+
+	inMsg := service.NewMessage([]byte(`{"user_id":"abc"}`))
+	inMsg.MetaSetMut("token", "meow")
+
+	batch, err := p.Process(context.Background(), inMsg)
+	require.NoError(t, err)
+	require.Len(t, batch, 1)
+	require.NoError(t, batch[0].GetError())
+
+	b, err := batch[0].AsBytes()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"user":{"id":"abc","name":"foo"}}`, string(b))
+
+	require.Len(t, s.requests, 1)
+	assert.Equal(t, "GetUser", s.requests[0].OperationName)
+	assert.Equal(t, map[string]any{"id": "abc"}, s.requests[0].Variables)
+	assert.Equal(t, "Bearer meow", s.authHeader)
+
+	_, err = p.Process(context.Background(), service.NewMessage([]byte(`{"user_id":"abc"}`)))
+	require.NoError(t, err)
+}
+
+func TestProcessorErrors(t *testing.T) {
+	_, url := newTestGraphQLServer(t, func(req gqlRequest) (int, string) {
+		switch req.Variables["case"] {
+		case "partial":
+			return http.StatusOK, `{"data":{"user":null},"errors":[{"message":"not found","path":["user"],"extensions":{"code":"NOT_FOUND"}},{"message":"also not found","extensions":{"code":"NOT_FOUND"}}]}`
+		case "validation":
+			return http.StatusBadRequest, `{"errors":[{"message":"unknown field","extensions":{"code":"GRAPHQL_VALIDATION_FAILED"}}]}`
+		}
+		return http.StatusBadGateway, `nope`
+	})
+
+	p := testProcessor(t, `
+url: `+url+`
+query: '{ user { id } }'
+variables: 'root.case = content().string()'
+`)
+
+	batch, err := p.Process(context.Background(), service.NewMessage([]byte(`partial`)))
+	require.NoError(t, err)
+	require.Len(t, batch, 1)
+	require.EqualError(t, batch[0].GetError(), "graphql errors: not found; also not found")
+
+	b, err := batch[0].AsBytes()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"user":null}`, string(b))
+
+	v, _ := batch[0].MetaGetMut("graphql_error_codes")
+	assert.Equal(t, "NOT_FOUND", v)
+
+	v, _ = batch[0].MetaGetMut("graphql_errors")
+	var errs []any
+	require.NoError(t, json.Unmarshal([]byte(v.(string)), &errs))
+	assert.Len(t, errs, 2)
+
+	batch, err = p.Process(context.Background(), service.NewMessage([]byte(`validation`)))
+	require.NoError(t, err)
+	require.Len(t, batch, 1)
+	require.EqualError(t, batch[0].GetError(), "graphql errors: unknown field")
+
+	b, err = batch[0].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, "validation", string(b))
+
+	v, _ = batch[0].MetaGetMut("graphql_error_codes")
+	assert.Equal(t, "GRAPHQL_VALIDATION_FAILED", v)
+
+	_, err = p.Process(context.Background(), service.NewMessage([]byte(`other`)))
+	require.EqualError(t, err, "request returned unexpected response code: 502")
+}
+
+func TestProcessorPersistedQueries(t *testing.T) {
+	s, url := newTestGraphQLServer(t, func(req gqlRequest) (int, string) {
+		return http.StatusOK, `{"data":{"query":"` + req.Query + `"}}`
+	})
+
+	p := testProcessor(t, `
+url: `+url+`
+query: '{ hello }'
+persisted_queries: true
+`)
+
+	for i := 0; i < 2; i++ {
+		batch, err := p.Process(context.Background(), service.NewMessage(nil))
+		require.NoError(t, err)
+		require.Len(t, batch, 1)
+		require.NoError(t, batch[0].GetError())
+
+		b, err := batch[0].AsBytes()
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"query":"{ hello }"}`, string(b))
+	}
+
+	// The first attempt is a miss, followed by a registration with the full
+	// query, and then a hit without the query.
+	require.Len(t, s.requests, 3)
+	assert.Empty(t, s.requests[0].Query)
+	assert.Equal(t, "{ hello }", s.requests[1].Query)
+	assert.Empty(t, s.requests[2].Query)
+}
+
+func TestProcessorQuotaThrottle(t *testing.T) {
+	var calls []time.Time
+	var mut sync.Mutex
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mut.Lock()
+		calls = append(calls, time.Now())
+		mut.Unlock()
+
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("Retry-After", "1")
+		_, _ = w.Write([]byte(`{"data":{"ok":true}}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	p := testProcessor(t, `
+url: `+srv.URL+`
+query: '{ ok }'
+quota:
+  throttle: true
+  max_wait: 200ms
+`)
+
+	for i := 0; i < 2; i++ {
+		batch, err := p.Process(context.Background(), service.NewMessage(nil))
+		require.NoError(t, err)
+		require.NoError(t, batch[0].GetError())
+	}
+
+	require.Len(t, calls, 2)
+	assert.GreaterOrEqual(t, calls[1].Sub(calls[0]), 200*time.Millisecond)
+}
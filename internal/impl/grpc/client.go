@@ -0,0 +1,421 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jhump/protoreflect/grpcreflect"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/redpanda-data/benthos/v4/public/bloblang"
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	gcFieldAddress        = "address"
+	gcFieldMethod         = "method"
+	gcFieldImportPaths    = "import_paths"
+	gcFieldRequestMapping = "request_mapping"
+	gcFieldMetadata       = "metadata"
+	gcFieldTimeout        = "timeout"
+	gcFieldUseProtoNames  = "use_proto_names"
+	gcFieldTLS            = "tls"
+)
+
+// clientFields returns the fields of components that call a method, where
+// consumers of streams have no timeout as streams are consumed for as long as
+// they remain open.
+func clientFields(consumer bool) []*service.ConfigField {
+	fields := []*service.ConfigField{
+		service.NewStringField(gcFieldAddress).
+			Description("The address of the gRPC server to connect to, which must be set unless a `sidecar` is configured.").
+			Default("").
+			Example("localhost:50051").
+			Example("dns:///api.example.com:443"),
+		service.NewStringField(gcFieldMethod).
+			Description("The fully qualified name of the method to call, in the form `package.Service/Method`.").
+			Example("helloworld.Greeter/SayHello"),
+		service.NewStringListField(gcFieldImportPaths).
+			Description("A list of directories containing .proto files, including all definitions required for the target method. When empty the method is resolved from the server using gRPC server reflection, which must be enabled on the server.").
+			Default([]any{}),
+		service.NewBloblangField(gcFieldRequestMapping).
+			Description("An optional xref:guides:bloblang/about.adoc[Bloblang mapping] used to create the request from each message, the result of which is converted into the request message using the protobuf JSON mapping. When omitted the contents of each message are used as the JSON request.").
+			Example(`root.name = this.user.name`).
+			Optional(),
+		service.NewInterpolatedStringMapField(gcFieldMetadata).
+			Description("A map of metadata to send with each call.").
+			Example(map[string]any{"authorization": "Bearer ${! env(\"API_TOKEN\") }"}).
+			Default(map[string]any{}).
+			Advanced(),
+	}
+	if !consumer {
+		fields = append(fields, service.NewDurationField(gcFieldTimeout).
+			Description("The maximum period to wait for each call to complete, including all responses of a server-streaming call.").
+			Default("5s"))
+	}
+	return append(fields,
+		service.NewBoolField(gcFieldUseProtoNames).
+			Description("Whether responses should use the field names from the protobuf definition rather than lowerCamelCase JSON names.").
+			Default(false).
+			Advanced(),
+		service.NewTLSToggledField(gcFieldTLS),
+		sidecarField(),
+	)
+}
+
+// rpcClient calls a single method of a gRPC server using dynamic messages.
+type rpcClient struct {
+	address     string
+	fullMethod  string
+	serviceName string
+	methodName  string
+	importPaths []string
+	reqMapping  *bloblang.Executor
+	metadata    map[string]*service.InterpolatedString
+	timeout     time.Duration
+	protoNames  bool
+	creds       credentials.TransportCredentials
+	sidecar     *sidecar
+
+	mgr *service.Resources
+
+	connMut sync.Mutex
+	conn    *grpc.ClientConn
+	method  protoreflect.MethodDescriptor
+	types   *protoregistry.Types
+}
+
+func rpcClientFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*rpcClient, error) {
+	c := &rpcClient{mgr: mgr}
+
+	var err error
+	if c.address, err = conf.FieldString(gcFieldAddress); err != nil {
+		return nil, err
+	}
+	if c.sidecar, err = sidecarFromParsed(conf.Namespace(gcFieldSidecar), mgr); err != nil {
+		return nil, err
+	}
+	if c.sidecar == nil && c.address == "" {
+		return nil, errors.New("an address must be specified unless a sidecar is configured")
+	}
+
+	var method string
+	if method, err = conf.FieldString(gcFieldMethod); err != nil {
+		return nil, err
+	}
+	method = strings.TrimPrefix(method, "/")
+	sepIndex := strings.LastIndex(method, "/")
+	if sepIndex <= 0 || sepIndex == len(method)-1 {
+		return nil, fmt.Errorf("method '%v' must be in the form package.Service/Method", method)
+	}
+	c.serviceName, c.methodName = method[:sepIndex], method[sepIndex+1:]
+	c.fullMethod = "/" + method
+
+	if c.importPaths, err = conf.FieldStringList(gcFieldImportPaths); err != nil {
+		return nil, err
+	}
+	if conf.Contains(gcFieldRequestMapping) {
+		if c.reqMapping, err = conf.FieldBloblang(gcFieldRequestMapping); err != nil {
+			return nil, err
+		}
+	}
+	if c.metadata, err = conf.FieldInterpolatedStringMap(gcFieldMetadata); err != nil {
+		return nil, err
+	}
+	if conf.Contains(gcFieldTimeout) {
+		if c.timeout, err = conf.FieldDuration(gcFieldTimeout); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.protoNames, err = conf.FieldBool(gcFieldUseProtoNames); err != nil {
+		return nil, err
+	}
+
+	tlsConf, tlsEnabled, err := conf.FieldTLSToggled(gcFieldTLS)
+	if err != nil {
+		return nil, err
+	}
+	if tlsEnabled {
+		c.creds = credentials.NewTLS(tlsConf)
+	} else {
+		c.creds = insecure.NewCredentials()
+	}
+	return c, nil
+}
+
+// connect creates the connection and resolves the method descriptor, this is
+// safe to call repeatedly and only has an effect until it first succeeds, or
+// until the sidecar process exits.
+func (c *rpcClient) connect(ctx context.Context) error {
+	_, _, err := c.connection(ctx)
+	return err
+}
+
+// connection connects when necessary and returns the connection and method
+// descriptor, which remain usable by the caller after a reconnect.
+func (c *rpcClient) connection(ctx context.Context) (*grpc.ClientConn, protoreflect.MethodDescriptor, error) {
+	c.connMut.Lock()
+	defer c.connMut.Unlock()
+
+	if c.sidecar != nil && c.conn != nil && !c.sidecar.running() {
+		c.mgr.Logger().Warn("Plugin process is not running, launching it again")
+		_ = c.conn.Close()
+		c.conn, c.method = nil, nil
+	}
+
+	if c.method != nil {
+		return c.conn, c.method, nil
+	}
+
+	if c.conn == nil {
+		address := c.address
+		if c.sidecar != nil {
+			var err error
+			if address, err = c.sidecar.start(); err != nil {
+				return nil, nil, err
+			}
+		}
+		conn, err := grpc.NewClient(address, grpc.WithTransportCredentials(c.creds))
+		if err != nil {
+			return nil, nil, err
+		}
+		c.conn = conn
+		if c.sidecar != nil {
+			go c.sidecar.checkHealth(conn)
+		}
+	}
+
+	var err error
+	if len(c.importPaths) > 0 {
+		c.method, c.types, err = c.methodFromFiles()
+	} else {
+		c.method, err = c.methodFromReflection(ctx)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if c.method.IsStreamingClient() {
+		c.method = nil
+		return nil, nil, fmt.Errorf("method '%v' is client streaming, which is not supported", c.fullMethod)
+	}
+	return c.conn, c.method, nil
+}
+
+func (c *rpcClient) methodFromFiles() (protoreflect.MethodDescriptor, *protoregistry.Types, error) {
+	sd, types, err := serviceFromFiles(c.mgr.FS(), c.importPaths, c.serviceName)
+	if err != nil {
+		return nil, nil, err
+	}
+	md := sd.Methods().ByName(protoreflect.Name(c.methodName))
+	if md == nil {
+		return nil, nil, fmt.Errorf("service '%v' does not have a method '%v'", c.serviceName, c.methodName)
+	}
+	return md, types, nil
+}
+
+func (c *rpcClient) methodFromReflection(ctx context.Context) (protoreflect.MethodDescriptor, error) {
+	refClient := grpcreflect.NewClientAuto(ctx, c.conn)
+	defer refClient.Reset()
+
+	sd, err := refClient.ResolveService(c.serviceName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve service '%v' with server reflection: %w", c.serviceName, err)
+	}
+	md := sd.FindMethodByName(c.methodName)
+	if md == nil {
+		return nil, fmt.Errorf("service '%v' does not have a method '%v'", c.serviceName, c.methodName)
+	}
+	return md.UnwrapMethod(), nil
+}
+
+func (c *rpcClient) request(method protoreflect.MethodDescriptor, msg *service.Message) (*dynamicpb.Message, error) {
+	if c.reqMapping != nil {
+		var err error
+		if msg, err = msg.BloblangQuery(c.reqMapping); err != nil {
+			return nil, fmt.Errorf("request mapping failed: %w", err)
+		}
+		if msg == nil {
+			return nil, errors.New("request mapping resulted in a deleted message")
+		}
+	}
+
+	reqBytes, err := msg.AsBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	req := dynamicpb.NewMessage(method.Input())
+	opts := protojson.UnmarshalOptions{Resolver: c.resolver()}
+	if err := opts.Unmarshal(reqBytes, req); err != nil {
+		return nil, fmt.Errorf("failed to convert message into request '%v': %w", method.Input().FullName(), err)
+	}
+	return req, nil
+}
+
+type typeResolver interface {
+	protoregistry.MessageTypeResolver
+	protoregistry.ExtensionTypeResolver
+}
+
+func (c *rpcClient) resolver() typeResolver {
+	if c.types != nil {
+		return c.types
+	}
+	return protoregistry.GlobalTypes
+}
+
+// marshal a response into its JSON form.
+func (c *rpcClient) marshal(res *dynamicpb.Message) ([]byte, error) {
+	opts := protojson.MarshalOptions{
+		Resolver:      c.resolver(),
+		UseProtoNames: c.protoNames,
+	}
+	return opts.Marshal(res)
+}
+
+// call the method with a message, and return each response received.
+func (c *rpcClient) call(ctx context.Context, msg *service.Message) ([]*dynamicpb.Message, error) {
+	conn, method, err := c.connection(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, done := context.WithTimeout(ctx, c.timeout)
+	defer done()
+
+	req, ctx, err := c.prepare(ctx, method, msg)
+	if err != nil {
+		return nil, err
+	}
+
+	if !method.IsStreamingServer() {
+		res := dynamicpb.NewMessage(method.Output())
+		if err := conn.Invoke(ctx, c.fullMethod, req, res); err != nil {
+			return nil, err
+		}
+		return []*dynamicpb.Message{res}, nil
+	}
+
+	stream, err := c.sendStream(ctx, conn, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var responses []*dynamicpb.Message
+	for {
+		res := dynamicpb.NewMessage(method.Output())
+		if err := stream.RecvMsg(res); err != nil {
+			if errors.Is(err, io.EOF) {
+				return responses, nil
+			}
+			return nil, err
+		}
+		responses = append(responses, res)
+	}
+}
+
+// stream calls a server-streaming method with a message and returns the
+// stream of responses, which is not subject to the timeout and ends when the
+// context is cancelled.
+func (c *rpcClient) stream(ctx context.Context, msg *service.Message) (grpc.ClientStream, protoreflect.MethodDescriptor, error) {
+	conn, method, err := c.connection(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !method.IsStreamingServer() {
+		return nil, nil, fmt.Errorf("method '%v' is not server streaming", c.fullMethod)
+	}
+
+	req, ctx, err := c.prepare(ctx, method, msg)
+	if err != nil {
+		return nil, nil, err
+	}
+	stream, err := c.sendStream(ctx, conn, req)
+	if err != nil {
+		return nil, nil, err
+	}
+	return stream, method, nil
+}
+
+// prepare creates the request of a call from a message, along with a context
+// carrying the metadata of the call.
+func (c *rpcClient) prepare(ctx context.Context, method protoreflect.MethodDescriptor, msg *service.Message) (*dynamicpb.Message, context.Context, error) {
+	if c.sidecar != nil && !c.sidecar.healthy.Load() {
+		return nil, nil, errors.New("plugin is not serving")
+	}
+
+	req, err := c.request(method, msg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(c.metadata) > 0 {
+		md := metadata.MD{}
+		for k, v := range c.metadata {
+			vStr, err := v.TryString(msg)
+			if err != nil {
+				return nil, nil, fmt.Errorf("metadata %v interpolation: %w", k, err)
+			}
+			md.Append(k, vStr)
+		}
+		ctx = metadata.NewOutgoingContext(ctx, md)
+	}
+	return req, ctx, nil
+}
+
+func (c *rpcClient) sendStream(ctx context.Context, conn *grpc.ClientConn, req *dynamicpb.Message) (grpc.ClientStream, error) {
+	stream, err := conn.NewStream(ctx, &grpc.StreamDesc{ServerStreams: true}, c.fullMethod)
+	if err != nil {
+		return nil, err
+	}
+	if err := stream.SendMsg(req); err != nil {
+		return nil, err
+	}
+	if err := stream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return stream, nil
+}
+
+func (c *rpcClient) close() error {
+	c.connMut.Lock()
+	defer c.connMut.Unlock()
+
+	if c.sidecar != nil {
+		defer c.sidecar.stop()
+	}
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	c.method = nil
+	return err
+}
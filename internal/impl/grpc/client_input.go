@@ -0,0 +1,146 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func clientInputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Categories("Network").
+		Version("4.45.0").
+		Summary("Consumes the responses of a server-streaming gRPC method as messages.").
+		Description(`
+The method is resolved either from .proto files within `+"`import_paths`"+`, or from the server itself using gRPC server reflection when no import paths are specified. The request of the call is the result of `+"`request_mapping`"+` executed on an empty message, or an empty request when omitted, and each response is converted into JSON and consumed as a message. The method is called again whenever the stream ends.
+
+Responses are only received as fast as they are consumed by the pipeline, and therefore gRPC flow control applies backpressure to the server. The method has no means of acknowledging responses, and so responses that were received but not yet delivered are lost when the stream breaks or the input shuts down.
+
+== Plugins
+
+Combined with the `+"`sidecar`"+` field this input allows inputs to be implemented as plugin processes in any language with gRPC support.`).
+		Fields(clientFields(true)...).
+		Field(service.NewAutoRetryNacksToggleField()).
+		Example("Consume a plugin", "Launch a plugin process and consume the responses of a method it serves.", `
+input:
+  grpc:
+    method: acme.plugin.v1.Source/Read
+    request_mapping: 'root.topic = "orders"'
+    sidecar:
+      command: [ ./plugins/source ]
+`)
+}
+
+func init() {
+	err := service.RegisterInput("grpc", clientInputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Input, error) {
+			i, err := newClientInputFromParsed(conf, mgr)
+			if err != nil {
+				return nil, err
+			}
+			return service.AutoRetryNacksToggled(conf, i)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type clientInput struct {
+	client *rpcClient
+	log    *service.Logger
+
+	mut      sync.Mutex
+	stream   grpc.ClientStream
+	method   protoreflect.MethodDescriptor
+	cancelFn context.CancelFunc
+}
+
+func newClientInputFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*clientInput, error) {
+	c, err := rpcClientFromParsed(conf, mgr)
+	if err != nil {
+		return nil, err
+	}
+	return &clientInput{client: c, log: mgr.Logger()}, nil
+}
+
+func (i *clientInput) Connect(ctx context.Context) error {
+	i.mut.Lock()
+	defer i.mut.Unlock()
+
+	if i.stream != nil {
+		return nil
+	}
+
+	// The stream outlives the context of the connect call.
+	streamCtx, cancel := context.WithCancel(context.Background())
+	stream, method, err := i.client.stream(streamCtx, service.NewMessage([]byte(`{}`)))
+	if err != nil {
+		cancel()
+		return err
+	}
+	i.stream, i.method, i.cancelFn = stream, method, cancel
+	return nil
+}
+
+func (i *clientInput) Read(ctx context.Context) (*service.Message, service.AckFunc, error) {
+	i.mut.Lock()
+	stream, method := i.stream, i.method
+	i.mut.Unlock()
+
+	if stream == nil {
+		return nil, nil, service.ErrNotConnected
+	}
+
+	res := dynamicpb.NewMessage(method.Output())
+	if err := stream.RecvMsg(res); err != nil {
+		if !errors.Is(err, io.EOF) {
+			i.log.Errorf("Stream of method '%v' failed: %v", i.client.fullMethod, err)
+		}
+		i.resetStream()
+		return nil, nil, service.ErrNotConnected
+	}
+
+	resBytes, err := i.client.marshal(res)
+	if err != nil {
+		return nil, nil, err
+	}
+	return service.NewMessage(resBytes), func(ctx context.Context, err error) error {
+		return nil
+	}, nil
+}
+
+func (i *clientInput) resetStream() {
+	i.mut.Lock()
+	defer i.mut.Unlock()
+
+	if i.cancelFn != nil {
+		i.cancelFn()
+	}
+	i.stream, i.method, i.cancelFn = nil, nil, nil
+}
+
+func (i *clientInput) Close(ctx context.Context) error {
+	i.resetStream()
+	return i.client.close()
+}
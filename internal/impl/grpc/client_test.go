@@ -0,0 +1,272 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpc
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	testpb "google.golang.org/grpc/interop/grpc_testing"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+type testService struct {
+	testpb.UnimplementedTestServiceServer
+}
+
+func (testService) StreamingOutputCall(req *testpb.StreamingOutputCallRequest, stream testpb.TestService_StreamingOutputCallServer) error {
+	var prefix string
+	if md, ok := metadata.FromIncomingContext(stream.Context()); ok {
+		if v := md.Get("x-prefix"); len(v) > 0 {
+			prefix = v[0]
+		}
+	}
+	for _, p := range req.GetResponseParameters() {
+		body := []byte(prefix)
+		for i := int32(0); i < p.GetSize(); i++ {
+			body = append(body, 'x')
+		}
+		if err := stream.Send(&testpb.StreamingOutputCallResponse{
+			Payload: &testpb.Payload{Body: body},
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func startTestServer(t *testing.T) string {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	healthSrv := health.NewServer()
+	healthSrv.SetServingStatus("foo", healthpb.HealthCheckResponse_SERVING)
+	healthSrv.SetServingStatus("bar", healthpb.HealthCheckResponse_NOT_SERVING)
+
+	srv := grpc.NewServer()
+	healthpb.RegisterHealthServer(srv, healthSrv)
+	testpb.RegisterTestServiceServer(srv, testService{})
+	reflection.Register(srv)
+
+	go func() {
+		_ = srv.Serve(lis)
+	}()
+	t.Cleanup(srv.Stop)
+
+	return lis.Addr().String()
+}
+
+func testProcessor(t *testing.T, yamlStr string) *processor {
+	t.Helper()
+
+	conf, err := processorSpec().ParseYAML(yamlStr, nil)
+	require.NoError(t, err)
+
+	p, err := newProcessorFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = p.Close(context.Background())
+	})
+	return p
+}
+
+func TestProcessorUnaryReflection(t *testing.T) {
+	addr := startTestServer(t)
+
+	p := testProcessor(t, `
+address: `+addr+`
+method: /grpc.health.v1.Health/Check
+request_mapping: 'root.service = this.name'
+`)
+
+	inMsg := service.NewMessage([]byte(`{"name":"foo"}`))
+	inMsg.MetaSetMut("keep", "me")
+
+	batch, err := p.Process(context.Background(), inMsg)
+	require.NoError(t, err)
+	require.Len(t, batch, 1)
+
+	b, err := batch[0].AsBytes()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"status":"SERVING"}`, string(b))
+
+	v, _ := batch[0].MetaGetMut("keep")
+	assert.Equal(t, "me", v)
+
+	batch, err = p.Process(context.Background(), service.NewMessage([]byte(`{"name":"bar"}`)))
+	require.NoError(t, err)
+	require.Len(t, batch, 1)
+
+	b, err = batch[0].AsBytes()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"status":"NOT_SERVING"}`, string(b))
+
+	_, err = p.Process(context.Background(), service.NewMessage([]byte(`{"name":"baz"}`)))
+	require.Error(t, err)
+}
+
+func TestProcessorServerStreaming(t *testing.T) {
+	addr := startTestServer(t)
+
+	p := testProcessor(t, `
+address: `+addr+`
+method: grpc.testing.TestService/StreamingOutputCall
+metadata:
+  x-prefix: ${! @prefix }
+`)
+
+	inMsg := service.NewMessage([]byte(`{"responseParameters":[{"size":1},{"size":2},{"size":3}]}`))
+	inMsg.MetaSetMut("prefix", "ab")
+
+	batch, err := p.Process(context.Background(), inMsg)
+	require.NoError(t, err)
+	require.Len(t, batch, 3)
+
+	for i, exp := range []string{`{"payload":{"body":"YWJ4"}}`, `{"payload":{"body":"YWJ4eA=="}}`, `{"payload":{"body":"YWJ4eHg="}}`} {
+		b, err := batch[i].AsBytes()
+		require.NoError(t, err)
+		assert.JSONEq(t, exp, string(b), i)
+	}
+
+	batch, err = p.Process(context.Background(), service.NewMessage([]byte(`{}`)))
+	require.NoError(t, err)
+	assert.Empty(t, batch)
+}
+
+func TestProcessorImportPaths(t *testing.T) {
+	addr := startTestServer(t)
+
+	protoDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(protoDir, "health.proto"), []byte(`
+syntax = "proto3";
+
+package grpc.health.v1;
+
+message HealthCheckRequest {
+  string service = 1;
+}
+
+message HealthCheckResponse {
+  enum ServingStatus {
+    UNKNOWN = 0;
+    SERVING = 1;
+    NOT_SERVING = 2;
+    SERVICE_UNKNOWN = 3;
+  }
+  ServingStatus status = 1;
+}
+
+service Health {
+  rpc Check(HealthCheckRequest) returns (HealthCheckResponse);
+}
+`), 0o644))
+
+	p := testProcessor(t, `
+address: `+addr+`
+method: grpc.health.v1.Health/Check
+import_paths: [ `+protoDir+` ]
+`)
+
+	batch, err := p.Process(context.Background(), service.NewMessage([]byte(`{"service":"foo"}`)))
+	require.NoError(t, err)
+	require.Len(t, batch, 1)
+
+	b, err := batch[0].AsBytes()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"status":"SERVING"}`, string(b))
+
+	_, err = p.Process(context.Background(), service.NewMessage([]byte(`{"nope":"foo"}`)))
+	require.Error(t, err)
+}
+
+func TestClientConfigErrors(t *testing.T) {
+	for _, method := range []string{"Check", "grpc.health.v1.Health/", "/Check"} {
+		conf, err := processorSpec().ParseYAML(`
+address: localhost:1234
+method: `+method+`
+`, nil)
+		require.NoError(t, err)
+
+		_, err = newProcessorFromParsed(conf, service.MockResources())
+		require.Error(t, err, method)
+	}
+}
+
+func TestOutputUnary(t *testing.T) {
+	addr := startTestServer(t)
+
+	conf, err := outputSpec().ParseYAML(`
+address: `+addr+`
+method: grpc.health.v1.Health/Check
+`, nil)
+	require.NoError(t, err)
+
+	out, err := newOutputFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = out.Close(context.Background())
+	})
+
+	require.NoError(t, out.Connect(context.Background()))
+	require.NoError(t, out.Write(context.Background(), service.NewMessage([]byte(`{"service":"foo"}`))))
+	require.Error(t, out.Write(context.Background(), service.NewMessage([]byte(`{"service":"baz"}`))))
+}
+
+func TestClientInputServerStreaming(t *testing.T) {
+	addr := startTestServer(t)
+
+	conf, err := clientInputSpec().ParseYAML(`
+address: `+addr+`
+method: grpc.testing.TestService/StreamingOutputCall
+request_mapping: 'root.response_parameters = [{"size":1},{"size":2}]'
+`, nil)
+	require.NoError(t, err)
+
+	in, err := newClientInputFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = in.Close(context.Background())
+	})
+
+	for i := 0; i < 2; i++ {
+		require.NoError(t, in.Connect(context.Background()))
+		for _, exp := range []string{`{"payload":{"body":"eA=="}}`, `{"payload":{"body":"eHg="}}`} {
+			msg, ackFn, err := in.Read(context.Background())
+			require.NoError(t, err)
+			require.NoError(t, ackFn(context.Background(), nil))
+
+			b, err := msg.AsBytes()
+			require.NoError(t, err)
+			assert.JSONEq(t, exp, string(b))
+		}
+
+		// The method is called again once the stream ends.
+		_, _, err = in.Read(context.Background())
+		require.ErrorIs(t, err, service.ErrNotConnected)
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpc
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+
+	"github.com/redpanda-data/connect/v4/internal/impl/protobuf"
+)
+
+// serviceFromFiles parses all .proto files found within a list of import
+// paths and returns the descriptor of a service defined within them.
+func serviceFromFiles(fsys fs.FS, importPaths []string, serviceName string) (protoreflect.ServiceDescriptor, *protoregistry.Types, error) {
+	files := map[string]string{}
+	for _, importPath := range importPaths {
+		if err := fs.WalkDir(fsys, importPath, func(path string, info fs.DirEntry, ferr error) error {
+			if ferr != nil || info.IsDir() || filepath.Ext(info.Name()) != ".proto" {
+				return ferr
+			}
+			rPath, ferr := filepath.Rel(importPath, path)
+			if ferr != nil {
+				return fmt.Errorf("failed to get relative path: %w", ferr)
+			}
+			content, ferr := service.ReadFile(fsys, path)
+			if ferr != nil {
+				return fmt.Errorf("failed to read import %v: %w", path, ferr)
+			}
+			files[rPath] = string(content)
+			return nil
+		}); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	descriptors, types, err := protobuf.RegistriesFromMap(files)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	d, err := descriptors.FindDescriptorByName(protoreflect.FullName(serviceName))
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to find service '%v' definition within '%v'", serviceName, importPaths)
+	}
+	sd, ok := d.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, nil, fmt.Errorf("descriptor %v was unexpected type %T", serviceName, d)
+	}
+	return sd, types, nil
+}
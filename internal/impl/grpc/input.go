@@ -0,0 +1,391 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/redpanda-data/benthos/v4/public/bloblang"
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	gsiFieldAddress         = "address"
+	gsiFieldService         = "service"
+	gsiFieldImportPaths     = "import_paths"
+	gsiFieldResponseMapping = "response_mapping"
+	gsiFieldTLS             = "tls"
+	gsiFieldTLSEnabled      = "enabled"
+	gsiFieldTLSCertFile     = "cert_file"
+	gsiFieldTLSKeyFile      = "key_file"
+)
+
+func serverInputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Categories("Network").
+		Version("4.45.0").
+		Summary("Receive messages over gRPC by serving a service defined in .proto files.").
+		Description(`
+Every method of the configured service is served, and each request received is converted into JSON using the protobuf JSON mapping and consumed as a message. A response is only sent once the message has been delivered (acknowledged) by the pipeline, and an error status is returned to the caller when delivery fails.
+
+Unary and server-streaming methods send a single response for each call, bidirectional streaming methods send a response for each request received, and client streaming methods send a single response once the client closes the stream and all requests have been delivered. Responses are empty unless a `+"`response_mapping`"+` is specified.
+
+== Deadlines
+
+When a caller specifies a deadline it is attached to the context of each message, and therefore honoured by processors and outputs that support cancellation. A response is sent with the status `+"`DEADLINE_EXCEEDED`"+` if a message is not delivered before the deadline.
+
+== Metadata
+
+This input adds the following metadata fields to each message:
+
+- grpc_method
+- grpc_peer
+- grpc_deadline
+- All metadata of the call, where keys with multiple values are joined with a comma
+
+You can access these metadata fields using xref:configuration:interpolation.adoc#bloblang-queries[function interpolation].`).
+		Fields(
+			service.NewStringField(gsiFieldAddress).
+				Description("The address to listen on.").
+				Default("0.0.0.0:50051"),
+			service.NewStringField(gsiFieldService).
+				Description("The fully qualified name of the service to serve.").
+				Example("acme.ingest.v1.IngestService"),
+			service.NewStringListField(gsiFieldImportPaths).
+				Description("A list of directories containing .proto files, including all definitions required for the service."),
+			service.NewBloblangField(gsiFieldResponseMapping).
+				Description("An optional xref:guides:bloblang/about.adoc[Bloblang mapping] executed on a message once delivered, the result of which is converted into the response of the method using the protobuf JSON mapping. For client streaming methods the mapping is executed on the last message of the stream. The metadata field `grpc_method` can be used in order to differentiate between methods.").
+				Example(`root.id = this.id`).
+				Optional(),
+			service.NewObjectField(gsiFieldTLS,
+				service.NewBoolField(gsiFieldTLSEnabled).
+					Description("Whether to serve with TLS.").
+					Default(false),
+				service.NewStringField(gsiFieldTLSCertFile).
+					Description("The path of a certificate file to use.").
+					Default(""),
+				service.NewStringField(gsiFieldTLSKeyFile).
+					Description("The path of the key file of the certificate.").
+					Default(""),
+			).Description("TLS settings for the server.").Advanced(),
+		)
+}
+
+func init() {
+	err := service.RegisterInput("grpc_server", serverInputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Input, error) {
+			return newServerInputFromParsed(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type serverMessage struct {
+	msg   *service.Message
+	ackFn service.AckFunc
+}
+
+type serverInput struct {
+	address     string
+	serviceName string
+	importPaths []string
+	respMapping *bloblang.Executor
+	creds       credentials.TransportCredentials
+
+	mgr *service.Resources
+	log *service.Logger
+
+	methods map[string]protoreflect.MethodDescriptor
+	types   *protoregistry.Types
+
+	msgs    chan serverMessage
+	closing chan struct{}
+
+	mut      sync.Mutex
+	server   *grpc.Server
+	listener net.Listener
+}
+
+func newServerInputFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*serverInput, error) {
+	i := &serverInput{
+		mgr:     mgr,
+		log:     mgr.Logger(),
+		msgs:    make(chan serverMessage),
+		closing: make(chan struct{}),
+	}
+
+	var err error
+	if i.address, err = conf.FieldString(gsiFieldAddress); err != nil {
+		return nil, err
+	}
+	if i.serviceName, err = conf.FieldString(gsiFieldService); err != nil {
+		return nil, err
+	}
+	if i.importPaths, err = conf.FieldStringList(gsiFieldImportPaths); err != nil {
+		return nil, err
+	}
+	if len(i.importPaths) == 0 {
+		return nil, errors.New("at least one import path must be specified")
+	}
+	if conf.Contains(gsiFieldResponseMapping) {
+		if i.respMapping, err = conf.FieldBloblang(gsiFieldResponseMapping); err != nil {
+			return nil, err
+		}
+	}
+
+	tlsConf := conf.Namespace(gsiFieldTLS)
+	tlsEnabled, err := tlsConf.FieldBool(gsiFieldTLSEnabled)
+	if err != nil {
+		return nil, err
+	}
+	if tlsEnabled {
+		certFile, err := tlsConf.FieldString(gsiFieldTLSCertFile)
+		if err != nil {
+			return nil, err
+		}
+		keyFile, err := tlsConf.FieldString(gsiFieldTLSKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		if i.creds, err = credentials.NewServerTLSFromFile(certFile, keyFile); err != nil {
+			return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+		}
+	}
+
+	sd, types, err := serviceFromFiles(mgr.FS(), i.importPaths, i.serviceName)
+	if err != nil {
+		return nil, err
+	}
+	i.types = types
+	i.methods = map[string]protoreflect.MethodDescriptor{}
+	methods := sd.Methods()
+	for j := 0; j < methods.Len(); j++ {
+		md := methods.Get(j)
+		i.methods["/"+string(sd.FullName())+"/"+string(md.Name())] = md
+	}
+	return i, nil
+}
+
+func (i *serverInput) Connect(ctx context.Context) error {
+	i.mut.Lock()
+	defer i.mut.Unlock()
+
+	if i.server != nil {
+		return nil
+	}
+
+	listener, err := net.Listen("tcp", i.address)
+	if err != nil {
+		return err
+	}
+
+	var opts []grpc.ServerOption
+	if i.creds != nil {
+		opts = append(opts, grpc.Creds(i.creds))
+	}
+	opts = append(opts, grpc.UnknownServiceHandler(i.handle))
+
+	i.server = grpc.NewServer(opts...)
+	i.listener = listener
+
+	go func(server *grpc.Server) {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+			i.log.Errorf("gRPC server error: %v", err)
+		}
+	}(i.server)
+
+	i.log.Infof("Receiving gRPC calls for service %v at: %v", i.serviceName, listener.Addr())
+	return nil
+}
+
+func (i *serverInput) Read(ctx context.Context) (*service.Message, service.AckFunc, error) {
+	select {
+	case m := <-i.msgs:
+		return m.msg, m.ackFn, nil
+	case <-i.closing:
+		return nil, nil, service.ErrEndOfInput
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+}
+
+func (i *serverInput) Close(ctx context.Context) error {
+	i.mut.Lock()
+	server := i.server
+	i.server = nil
+	i.mut.Unlock()
+
+	select {
+	case <-i.closing:
+	default:
+		close(i.closing)
+	}
+
+	if server == nil {
+		return nil
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		server.Stop()
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+func (i *serverInput) handle(_ any, stream grpc.ServerStream) error {
+	fullMethod, _ := grpc.MethodFromServerStream(stream)
+	md, exists := i.methods[fullMethod]
+	if !exists {
+		return status.Errorf(codes.Unimplemented, "method %v is not implemented", fullMethod)
+	}
+
+	var last *service.Message
+	for {
+		req := dynamicpb.NewMessage(md.Input())
+		if err := stream.RecvMsg(req); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return err
+		}
+
+		msg, err := i.messageFromRequest(stream.Context(), fullMethod, req)
+		if err != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+		if err := i.deliver(stream.Context(), msg); err != nil {
+			return err
+		}
+		last = msg
+
+		if md.IsStreamingClient() && !md.IsStreamingServer() {
+			continue
+		}
+		if err := i.sendResponse(stream, md, msg); err != nil {
+			return err
+		}
+		if !md.IsStreamingClient() {
+			return nil
+		}
+	}
+
+	if md.IsStreamingClient() && !md.IsStreamingServer() {
+		if last == nil {
+			last = service.NewMessage(nil).WithContext(stream.Context())
+			last.MetaSetMut("grpc_method", fullMethod)
+		}
+		return i.sendResponse(stream, md, last)
+	}
+	return nil
+}
+
+func (i *serverInput) messageFromRequest(ctx context.Context, fullMethod string, req *dynamicpb.Message) (*service.Message, error) {
+	reqBytes, err := protojson.MarshalOptions{Resolver: i.types}.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := service.NewMessage(reqBytes).WithContext(ctx)
+	msg.MetaSetMut("grpc_method", fullMethod)
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		msg.MetaSetMut("grpc_peer", p.Addr.String())
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		msg.MetaSetMut("grpc_deadline", deadline.Format(time.RFC3339Nano))
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for k, v := range md {
+			msg.MetaSetMut(k, strings.Join(v, ","))
+		}
+	}
+	return msg, nil
+}
+
+func (i *serverInput) deliver(ctx context.Context, msg *service.Message) error {
+	resChan := make(chan error, 1)
+	select {
+	case i.msgs <- serverMessage{
+		msg: msg,
+		ackFn: func(ctx context.Context, err error) error {
+			select {
+			case resChan <- err:
+			default:
+			}
+			return nil
+		},
+	}:
+	case <-i.closing:
+		return status.Error(codes.Unavailable, "server is shutting down")
+	case <-ctx.Done():
+		return status.FromContextError(ctx.Err()).Err()
+	}
+
+	select {
+	case err := <-resChan:
+		if err != nil {
+			return status.Errorf(codes.Unavailable, "failed to deliver message: %v", err)
+		}
+	case <-ctx.Done():
+		return status.FromContextError(ctx.Err()).Err()
+	}
+	return nil
+}
+
+func (i *serverInput) sendResponse(stream grpc.ServerStream, md protoreflect.MethodDescriptor, msg *service.Message) error {
+	res := dynamicpb.NewMessage(md.Output())
+	if i.respMapping != nil {
+		resMsg, err := msg.BloblangQuery(i.respMapping)
+		if err != nil {
+			return status.Errorf(codes.Internal, "response mapping failed: %v", err)
+		}
+		if resMsg != nil {
+			resBytes, err := resMsg.AsBytes()
+			if err != nil {
+				return status.Error(codes.Internal, err.Error())
+			}
+			if err := (protojson.UnmarshalOptions{Resolver: i.types}).Unmarshal(resBytes, res); err != nil {
+				return status.Errorf(codes.Internal, "failed to convert response mapping result into '%v': %v", md.Output().FullName(), err)
+			}
+		}
+	}
+	return stream.SendMsg(res)
+}
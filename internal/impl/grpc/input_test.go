@@ -0,0 +1,246 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const testGreeterProto = `
+syntax = "proto3";
+
+package test.v1;
+
+message HelloRequest {
+  string name = 1;
+}
+
+message HelloResponse {
+  string greeting = 1;
+}
+
+service Greeter {
+  rpc Hello(HelloRequest) returns (HelloResponse);
+  rpc Collect(stream HelloRequest) returns (HelloResponse);
+  rpc Chat(stream HelloRequest) returns (stream HelloResponse);
+}
+`
+
+func testServerInput(t *testing.T) (*serverInput, string) {
+	t.Helper()
+
+	protoDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(protoDir, "greeter.proto"), []byte(testGreeterProto), 0o644))
+
+	conf, err := serverInputSpec().ParseYAML(`
+address: 127.0.0.1:0
+service: test.v1.Greeter
+import_paths: [ `+protoDir+` ]
+response_mapping: 'root.greeting = "hello " + this.name.or("nobody")'
+`, nil)
+	require.NoError(t, err)
+
+	i, err := newServerInputFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+	require.NoError(t, i.Connect(context.Background()))
+	t.Cleanup(func() {
+		ctx, done := context.WithTimeout(context.Background(), time.Second)
+		defer done()
+		_ = i.Close(ctx)
+	})
+	return i, protoDir
+}
+
+// consume messages from the input, acknowledging each one with the result of
+// a function and sending it to a channel.
+func consume(t *testing.T, i *serverInput, ackErr func(*service.Message) error) <-chan *service.Message {
+	t.Helper()
+
+	msgs := make(chan *service.Message, 10)
+	go func() {
+		for {
+			msg, ackFn, err := i.Read(context.Background())
+			if err != nil {
+				return
+			}
+			msgs <- msg
+			_ = ackFn(context.Background(), ackErr(msg))
+		}
+	}()
+	return msgs
+}
+
+func noAckErr(*service.Message) error {
+	return nil
+}
+
+func TestServerInputUnary(t *testing.T) {
+	i, protoDir := testServerInput(t)
+	msgs := consume(t, i, func(m *service.Message) error {
+		if b, _ := m.AsBytes(); string(b) == `{"name":"fail"}` {
+			return errors.New("nope")
+		}
+		return nil
+	})
+
+	p := testProcessor(t, `
+address: `+i.listener.Addr().String()+`
+method: test.v1.Greeter/Hello
+import_paths: [ `+protoDir+` ]
+metadata:
+  x-tenant: acme
+`)
+
+	batch, err := p.Process(context.Background(), service.NewMessage([]byte(`{"name":"foo"}`)))
+	require.NoError(t, err)
+	require.Len(t, batch, 1)
+
+	b, err := batch[0].AsBytes()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"greeting":"hello foo"}`, string(b))
+
+	msg := <-msgs
+	b, err = msg.AsBytes()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"name":"foo"}`, string(b))
+
+	v, _ := msg.MetaGetMut("grpc_method")
+	assert.Equal(t, "/test.v1.Greeter/Hello", v)
+	v, _ = msg.MetaGetMut("x-tenant")
+	assert.Equal(t, "acme", v)
+	_, exists := msg.MetaGetMut("grpc_deadline")
+	assert.True(t, exists)
+	_, exists = msg.MetaGetMut("grpc_peer")
+	assert.True(t, exists)
+
+	_, err = p.Process(context.Background(), service.NewMessage([]byte(`{"name":"fail"}`)))
+	require.Error(t, err)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	<-msgs
+}
+
+func TestServerInputStreaming(t *testing.T) {
+	i, _ := testServerInput(t)
+	msgs := consume(t, i, noAckErr)
+
+	conn, err := grpc.NewClient(i.listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+
+	sd := i.methods["/test.v1.Greeter/Chat"].Parent().(protoreflect.ServiceDescriptor)
+	collect, chat := sd.Methods().ByName("Collect"), sd.Methods().ByName("Chat")
+
+	newReq := func(md protoreflect.MethodDescriptor, name string) *dynamicpb.Message {
+		req := dynamicpb.NewMessage(md.Input())
+		require.NoError(t, protojson.Unmarshal([]byte(`{"name":"`+name+`"}`), req))
+		return req
+	}
+	resGreeting := func(res *dynamicpb.Message) string {
+		b, err := protojson.Marshal(res)
+		require.NoError(t, err)
+		return string(b)
+	}
+
+	ctx, done := context.WithTimeout(context.Background(), 5*time.Second)
+	defer done()
+
+	// Client streaming results in a single response for the last message.
+	stream, err := conn.NewStream(ctx, &grpc.StreamDesc{ClientStreams: true}, "/test.v1.Greeter/Collect")
+	require.NoError(t, err)
+	for _, name := range []string{"a", "b", "c"} {
+		require.NoError(t, stream.SendMsg(newReq(collect, name)))
+	}
+	require.NoError(t, stream.CloseSend())
+
+	res := dynamicpb.NewMessage(collect.Output())
+	require.NoError(t, stream.RecvMsg(res))
+	assert.JSONEq(t, `{"greeting":"hello c"}`, resGreeting(res))
+
+	for _, name := range []string{"a", "b", "c"} {
+		b, err := (<-msgs).AsBytes()
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"name":"`+name+`"}`, string(b))
+	}
+
+	// Bidirectional streaming results in a response for each message.
+	stream, err = conn.NewStream(ctx, &grpc.StreamDesc{ClientStreams: true, ServerStreams: true}, "/test.v1.Greeter/Chat")
+	require.NoError(t, err)
+	for _, name := range []string{"d", "e"} {
+		require.NoError(t, stream.SendMsg(newReq(chat, name)))
+
+		res := dynamicpb.NewMessage(chat.Output())
+		require.NoError(t, stream.RecvMsg(res))
+		assert.JSONEq(t, `{"greeting":"hello `+name+`"}`, resGreeting(res))
+		<-msgs
+	}
+	require.NoError(t, stream.CloseSend())
+	require.ErrorIs(t, stream.RecvMsg(dynamicpb.NewMessage(chat.Output())), io.EOF)
+
+	// Unknown methods are rejected.
+	err = conn.Invoke(ctx, "/test.v1.Greeter/Nope", newReq(chat, "f"), dynamicpb.NewMessage(chat.Output()))
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+}
+
+func TestServerInputDeadline(t *testing.T) {
+	i, _ := testServerInput(t)
+
+	conn, err := grpc.NewClient(i.listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+
+	hello := i.methods["/test.v1.Greeter/Hello"]
+
+	ctx, done := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer done()
+
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- conn.Invoke(ctx, "/test.v1.Greeter/Hello", dynamicpb.NewMessage(hello.Input()), dynamicpb.NewMessage(hello.Output()))
+	}()
+
+	// Read the message without acknowledging it until after the deadline.
+	msg, ackFn, err := i.Read(context.Background())
+	require.NoError(t, err)
+
+	deadline, ok := msg.Context().Deadline()
+	require.True(t, ok)
+	assert.WithinDuration(t, time.Now().Add(500*time.Millisecond), deadline, 500*time.Millisecond)
+
+	err = <-errChan
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+	require.NoError(t, ackFn(context.Background(), nil))
+}
@@ -0,0 +1,75 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpc
+
+import (
+	"context"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func outputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Categories("Network").
+		Version("4.45.0").
+		Summary("Calls a unary or server-streaming gRPC method for each message.").
+		Description(`
+The method is resolved either from .proto files within ` + "`import_paths`" + `, or from the server itself using gRPC server reflection when no import paths are specified. Requests are created by converting the message contents (or the result of ` + "`request_mapping`" + `) from JSON into the request type.
+
+Responses are discarded, and a message is only acknowledged once the call succeeds, for server-streaming methods this means all responses have been received. In order to make use of the responses use the ` + "xref:components:processors/grpc.adoc[`grpc` processor]" + ` instead.
+
+Client-streaming and bidirectional-streaming methods are not supported.`).
+		Fields(clientFields(false)...).
+		Field(service.NewOutputMaxInFlightField())
+}
+
+func init() {
+	err := service.RegisterOutput("grpc", outputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.Output, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldMaxInFlight(); err != nil {
+				return
+			}
+			out, err = newOutputFromParsed(conf, mgr)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type output struct {
+	client *rpcClient
+}
+
+func newOutputFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*output, error) {
+	c, err := rpcClientFromParsed(conf, mgr)
+	if err != nil {
+		return nil, err
+	}
+	return &output{client: c}, nil
+}
+
+func (o *output) Connect(ctx context.Context) error {
+	return o.client.connect(ctx)
+}
+
+func (o *output) Write(ctx context.Context, msg *service.Message) error {
+	_, err := o.client.call(ctx, msg)
+	return err
+}
+
+func (o *output) Close(ctx context.Context) error {
+	return o.client.close()
+}
@@ -0,0 +1,96 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpc
+
+import (
+	"context"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func processorSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Categories("Integration").
+		Version("4.45.0").
+		Summary("Calls a unary or server-streaming gRPC method for each message and replaces the message with the response.").
+		Description(`
+The method is resolved either from .proto files within `+"`import_paths`"+`, or from the server itself using gRPC server reflection when no import paths are specified. Requests are created by converting the message contents (or the result of `+"`request_mapping`"+`) from JSON into the request type, and responses are converted back into JSON.
+
+For unary methods each message is replaced with the response. For server-streaming methods each message is expanded into one message per response received, and a call that yields no responses results in the message being removed.
+
+Client-streaming and bidirectional-streaming methods are not supported.
+
+== Metadata
+
+Metadata of the original message is retained on every response message.
+`).
+		Fields(clientFields(false)...).
+		Example("Enrich with a unary call", "Call a service with fields from each message and store the response under a new field.", `
+pipeline:
+  processors:
+    - branch:
+        request_map: 'root.id = this.user_id'
+        processors:
+          - grpc:
+              address: localhost:50051
+              method: users.v1.UserService/GetUser
+        result_map: 'root.user = this'
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor("grpc", processorSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return newProcessorFromParsed(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type processor struct {
+	client *rpcClient
+}
+
+func newProcessorFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*processor, error) {
+	c, err := rpcClientFromParsed(conf, mgr)
+	if err != nil {
+		return nil, err
+	}
+	return &processor{client: c}, nil
+}
+
+func (p *processor) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	responses, err := p.client.call(ctx, msg)
+	if err != nil {
+		return nil, err
+	}
+
+	batch := make(service.MessageBatch, 0, len(responses))
+	for _, res := range responses {
+		resBytes, err := p.client.marshal(res)
+		if err != nil {
+			return nil, err
+		}
+		resMsg := msg.Copy()
+		resMsg.SetBytes(resBytes)
+		batch = append(batch, resMsg)
+	}
+	return batch, nil
+}
+
+func (p *processor) Close(ctx context.Context) error {
+	return p.client.close()
+}
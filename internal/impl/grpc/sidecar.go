@@ -0,0 +1,312 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpc
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	gcFieldSidecar                    = "sidecar"
+	gcFieldSidecarCommand             = "command"
+	gcFieldSidecarEnv                 = "env"
+	gcFieldSidecarProtocolVersion     = "protocol_version"
+	gcFieldSidecarHandshakeTimeout    = "handshake_timeout"
+	gcFieldSidecarHealthCheckInterval = "health_check_interval"
+
+	// sidecarCoreProtocolVersion is the version of the handshake itself, which
+	// is the first field of the handshake line.
+	sidecarCoreProtocolVersion = 1
+
+	// sidecarEnvProtocolVersion is set within the environment of plugin
+	// processes to the expected protocol version of the plugin.
+	sidecarEnvProtocolVersion = "CONNECT_PLUGIN_PROTOCOL_VERSION"
+)
+
+func sidecarField() *service.ConfigField {
+	return service.NewObjectField(gcFieldSidecar,
+		service.NewStringListField(gcFieldSidecarCommand).
+			Description("The command used to launch the plugin, where the first element is the executable and the remaining elements are its arguments. The plugin is only launched when a command is specified.").
+			Default([]any{}).
+			Example([]string{"./plugins/enrich", "--verbose"}).
+			Example([]string{"python3", "plugin.py"}),
+		service.NewStringMapField(gcFieldSidecarEnv).
+			Description("Environment variables set for the plugin process in addition to those of this process.").
+			Default(map[string]any{}),
+		service.NewIntField(gcFieldSidecarProtocolVersion).
+			Description("The version of the application protocol that the plugin must report in its handshake, the plugin is stopped when the versions differ. The expected version is also set as the `"+sidecarEnvProtocolVersion+"` environment variable of the plugin process.").
+			Default(1).
+			Advanced(),
+		service.NewDurationField(gcFieldSidecarHandshakeTimeout).
+			Description("The maximum period to wait for the plugin to write its handshake after launching.").
+			Default("10s").
+			Advanced(),
+		service.NewDurationField(gcFieldSidecarHealthCheckInterval).
+			Description("The period between checks of the plugin with the standard gRPC health checking protocol, calls are rejected while the plugin is not serving. Plugins that do not implement the health service are considered healthy. Set to `0s` in order to disable health checks.").
+			Default("5s").
+			Advanced(),
+	).
+		Description(`Launch the gRPC server as a plugin process managed by this component, in which case ` + "`address`" + ` is ignored. The plugin must write a handshake line of the form ` + "`1|<protocol_version>|<network>|<address>|grpc`" + ` to stdout once it is listening, where the network is ` + "`tcp` or `unix`" + `. The remaining output of the plugin is logged, and the plugin is launched again when it exits.`).
+		Advanced()
+}
+
+// sidecar manages a plugin process that serves gRPC on an address reported
+// by its handshake.
+type sidecar struct {
+	command          []string
+	env              map[string]string
+	protocolVersion  int
+	handshakeTimeout time.Duration
+	healthInterval   time.Duration
+
+	log *service.Logger
+
+	mut     sync.Mutex
+	cmd     *exec.Cmd
+	address string
+	exited  chan struct{}
+	healthy atomic.Bool
+}
+
+// sidecarFromParsed returns nil when no sidecar command is configured.
+func sidecarFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*sidecar, error) {
+	s := &sidecar{log: mgr.Logger()}
+
+	var err error
+	if s.command, err = conf.FieldStringList(gcFieldSidecarCommand); err != nil {
+		return nil, err
+	}
+	if len(s.command) == 0 {
+		return nil, nil
+	}
+	if s.env, err = conf.FieldStringMap(gcFieldSidecarEnv); err != nil {
+		return nil, err
+	}
+	if s.protocolVersion, err = conf.FieldInt(gcFieldSidecarProtocolVersion); err != nil {
+		return nil, err
+	}
+	if s.handshakeTimeout, err = conf.FieldDuration(gcFieldSidecarHandshakeTimeout); err != nil {
+		return nil, err
+	}
+	if s.healthInterval, err = conf.FieldDuration(gcFieldSidecarHealthCheckInterval); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// running returns whether the plugin process has been launched and has not yet
+// exited.
+func (s *sidecar) running() bool {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	if s.exited == nil {
+		return false
+	}
+	select {
+	case <-s.exited:
+		return false
+	default:
+		return true
+	}
+}
+
+// start launches the plugin process unless it is already running, and returns
+// the gRPC target of the address reported by its handshake.
+func (s *sidecar) start() (string, error) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	if s.exited != nil {
+		select {
+		case <-s.exited:
+		default:
+			return s.address, nil
+		}
+	}
+
+	cmd := exec.Command(s.command[0], s.command[1:]...)
+	cmd.Env = append(os.Environ(), sidecarEnvProtocolVersion+"="+strconv.Itoa(s.protocolVersion))
+	for k, v := range s.env {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+
+	// Pipes are closed once the process exits, which ends the readers of the
+	// output.
+	stdoutR, stdoutW := io.Pipe()
+	stderrR, stderrW := io.Pipe()
+	cmd.Stdout, cmd.Stderr = stdoutW, stderrW
+	if err := cmd.Start(); err != nil {
+		return "", fmt.Errorf("failed to launch plugin: %w", err)
+	}
+
+	exited := make(chan struct{})
+	go s.logLines(bufio.NewScanner(stderrR))
+	go func() {
+		err := cmd.Wait()
+		_ = stdoutW.Close()
+		_ = stderrW.Close()
+		if err != nil {
+			s.log.Warnf("Plugin process exited: %v", err)
+		} else {
+			s.log.Info("Plugin process exited")
+		}
+		close(exited)
+	}()
+
+	handshake := make(chan string, 1)
+	go func() {
+		lines := bufio.NewScanner(stdoutR)
+		if lines.Scan() {
+			handshake <- lines.Text()
+			s.logLines(lines)
+		}
+		close(handshake)
+		_, _ = io.Copy(io.Discard, stdoutR)
+	}()
+
+	var line string
+	var ok bool
+	select {
+	case line, ok = <-handshake:
+	case <-time.After(s.handshakeTimeout):
+		_ = cmd.Process.Kill()
+		<-exited
+		return "", fmt.Errorf("plugin did not write a handshake within %v", s.handshakeTimeout)
+	}
+	if !ok {
+		<-exited
+		return "", errors.New("plugin exited before writing a handshake")
+	}
+
+	address, err := s.parseHandshake(line)
+	if err != nil {
+		_ = cmd.Process.Kill()
+		<-exited
+		return "", err
+	}
+	s.cmd, s.address, s.exited = cmd, address, exited
+	s.healthy.Store(true)
+	return address, nil
+}
+
+// parseHandshake checks a handshake line of the form
+// CORE-VERSION|APP-VERSION|NETWORK|ADDRESS|PROTOCOL and returns the address as
+// a gRPC target.
+func (s *sidecar) parseHandshake(line string) (string, error) {
+	parts := strings.Split(strings.TrimSpace(line), "|")
+	if len(parts) != 5 {
+		return "", fmt.Errorf("plugin wrote an invalid handshake: %q", line)
+	}
+	if parts[0] != strconv.Itoa(sidecarCoreProtocolVersion) {
+		return "", fmt.Errorf("plugin handshake version %v is not supported, expected %v", parts[0], sidecarCoreProtocolVersion)
+	}
+	if parts[1] != strconv.Itoa(s.protocolVersion) {
+		return "", fmt.Errorf("plugin protocol version %v does not match the expected version %v", parts[1], s.protocolVersion)
+	}
+	if parts[4] != "grpc" {
+		return "", fmt.Errorf("plugin protocol %v is not supported, expected grpc", parts[4])
+	}
+	switch parts[2] {
+	case "tcp":
+		return parts[3], nil
+	case "unix":
+		return "unix://" + parts[3], nil
+	}
+	return "", fmt.Errorf("plugin network %v is not supported, expected tcp or unix", parts[2])
+}
+
+func (s *sidecar) logLines(lines *bufio.Scanner) {
+	for lines.Scan() {
+		s.log.Infof("Plugin: %s", lines.Text())
+	}
+}
+
+// checkHealth polls the health service of the plugin over a connection until
+// the plugin exits.
+func (s *sidecar) checkHealth(conn *grpc.ClientConn) {
+	if s.healthInterval <= 0 {
+		return
+	}
+
+	s.mut.Lock()
+	exited := s.exited
+	s.mut.Unlock()
+
+	client := healthpb.NewHealthClient(conn)
+	ticker := time.NewTicker(s.healthInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-exited:
+			return
+		}
+
+		ctx, done := context.WithTimeout(context.Background(), s.healthInterval)
+		res, err := client.Check(ctx, &healthpb.HealthCheckRequest{})
+		done()
+
+		healthy := err == nil && res.GetStatus() == healthpb.HealthCheckResponse_SERVING
+		if status.Code(err) == codes.Unimplemented {
+			healthy = true
+		}
+		if was := s.healthy.Swap(healthy); was != healthy {
+			if healthy {
+				s.log.Info("Plugin is serving")
+			} else {
+				s.log.Warnf("Plugin is not serving: %v", healthErr(res, err))
+			}
+		}
+	}
+}
+
+func healthErr(res *healthpb.HealthCheckResponse, err error) error {
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("status %v", res.GetStatus())
+}
+
+// stop kills the plugin process and waits for it to exit.
+func (s *sidecar) stop() {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	if s.cmd == nil {
+		return
+	}
+	_ = s.cmd.Process.Kill()
+	<-s.exited
+	s.cmd = nil
+}
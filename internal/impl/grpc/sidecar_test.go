@@ -0,0 +1,151 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+// testPluginEnv is set when the test binary is launched as a plugin.
+const testPluginEnv = "GRPC_TEST_PLUGIN"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(testPluginEnv) != "" {
+		runTestPlugin()
+		return
+	}
+	os.Exit(m.Run())
+}
+
+func runTestPlugin() {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	healthSrv := health.NewServer()
+	healthSrv.SetServingStatus("foo", healthpb.HealthCheckResponse_SERVING)
+
+	srv := grpc.NewServer()
+	healthpb.RegisterHealthServer(srv, healthSrv)
+	reflection.Register(srv)
+
+	fmt.Printf("1|%v|tcp|%v|grpc\n", os.Getenv(sidecarEnvProtocolVersion), lis.Addr())
+	fmt.Println("plugin started")
+	_ = srv.Serve(lis)
+}
+
+func TestSidecarProcessor(t *testing.T) {
+	p := testProcessor(t, `
+method: /grpc.health.v1.Health/Check
+request_mapping: 'root.service = this.name'
+sidecar:
+  command: [ `+strconv.Quote(os.Args[0])+` ]
+  env:
+    `+testPluginEnv+`: "true"
+  health_check_interval: 10ms
+`)
+
+	check := func() {
+		t.Helper()
+		batch, err := p.Process(context.Background(), service.NewMessage([]byte(`{"name":"foo"}`)))
+		require.NoError(t, err)
+		require.Len(t, batch, 1)
+
+		b, err := batch[0].AsBytes()
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"status":"SERVING"}`, string(b))
+	}
+	check()
+	require.True(t, p.client.sidecar.running())
+
+	// The plugin is launched again once it exits.
+	p.client.sidecar.mut.Lock()
+	require.NoError(t, p.client.sidecar.cmd.Process.Kill())
+	p.client.sidecar.mut.Unlock()
+	assert.Eventually(t, func() bool {
+		return !p.client.sidecar.running()
+	}, time.Second*5, time.Millisecond*10)
+	check()
+
+	require.NoError(t, p.Close(context.Background()))
+	assert.False(t, p.client.sidecar.running())
+}
+
+func TestSidecarHandshakeErrors(t *testing.T) {
+	s := &sidecar{protocolVersion: 2}
+
+	address, err := s.parseHandshake("1|2|tcp|127.0.0.1:1234|grpc\n")
+	require.NoError(t, err)
+	assert.Equal(t, "127.0.0.1:1234", address)
+
+	address, err = s.parseHandshake("1|2|unix|/tmp/plugin.sock|grpc")
+	require.NoError(t, err)
+	assert.Equal(t, "unix:///tmp/plugin.sock", address)
+
+	for _, line := range []string{
+		"",
+		"hello world",
+		"2|2|tcp|127.0.0.1:1234|grpc",
+		"1|1|tcp|127.0.0.1:1234|grpc",
+		"1|2|udp|127.0.0.1:1234|grpc",
+		"1|2|tcp|127.0.0.1:1234|netrpc",
+	} {
+		_, err := s.parseHandshake(line)
+		assert.Error(t, err, line)
+	}
+}
+
+func TestSidecarNoHandshake(t *testing.T) {
+	conf, err := processorSpec().ParseYAML(`
+method: /grpc.health.v1.Health/Check
+sidecar:
+  command: [ "true" ]
+`, nil)
+	require.NoError(t, err)
+
+	p, err := newProcessorFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+
+	_, err = p.Process(context.Background(), service.NewMessage([]byte(`{}`)))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "exited before writing a handshake")
+}
+
+func TestClientAddressRequired(t *testing.T) {
+	conf, err := processorSpec().ParseYAML(`
+method: /grpc.health.v1.Health/Check
+`, nil)
+	require.NoError(t, err)
+
+	_, err = newProcessorFromParsed(conf, service.MockResources())
+	require.Error(t, err)
+}
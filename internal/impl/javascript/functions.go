@@ -15,10 +15,9 @@
 package javascript
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"io"
-	"net/http"
 	"strings"
 
 	"github.com/dop251/goja"
@@ -123,38 +122,51 @@ benthos.v0_msg_set_structured(result);
 				return nil, err
 			}
 
-			var payloadReader io.Reader
-			if payload != "" {
-				payloadReader = strings.NewReader(payload)
-			}
-
-			req, err := http.NewRequest(method, url, payloadReader)
-			if err != nil {
-				return nil, err
-			}
-
-			// Parse HTTP headers
-			for k, v := range httpHeaders {
-				vStr, _ := v.(string)
-				req.Header.Add(k, vStr)
-			}
-
-			// Do request
-			resp, err := http.DefaultClient.Do(req)
-			if err != nil {
-				return nil, err
-			}
-			defer resp.Body.Close()
+			return r.fetch(r.ctx, url, httpHeaders, method, payload)
+		}
+	})
 
-			respBody, err := io.ReadAll(resp.Body)
-			if err != nil {
+var _ = registerVMRunnerFunction(
+	"v0_fetch_async",
+	`Executes an HTTP request asynchronously and returns a promise that resolves with the result as an object of the form `+"`"+`{"status":200,"body":"foo"}`+"`"+`. The processing of a message completes once all promises created for it have settled, and a rejected promise that is not handled fails the message.`,
+).
+	Param("url", "string", "The URL to fetch").
+	Param("headers", "object(string,string)", "An object of string/string key/value pairs to add the request as headers.").
+	Param("method", "string", "The method of the request.").
+	Param("body", "(optional) string", "A body to send.").
+	Example(`
+(async () => {
+  let [user, orders] = await Promise.all([
+    benthos.v0_fetch_async("http://example.com/user", {}, "GET", ""),
+    benthos.v0_fetch_async("http://example.com/orders", {}, "GET", ""),
+  ]);
+  benthos.v0_msg_set_structured({ user: user.body, orders: orders.body });
+})();
+`).
+	FnCtor(func(r *vmRunner) jsFunction {
+		return func(call goja.FunctionCall, rt *goja.Runtime, l *service.Logger) (interface{}, error) {
+			var (
+				url         string
+				httpHeaders map[string]any
+				method      = "GET"
+				payload     = ""
+			)
+			if err := parseArgs(call, &url, &httpHeaders, &method, &payload); err != nil {
 				return nil, err
 			}
 
-			return map[string]any{
-				"status": resp.StatusCode,
-				"body":   string(respBody),
-			}, nil
+			promise, resolve, reject := rt.NewPromise()
+			r.runAsync(func(ctx context.Context) func() {
+				res, err := r.fetch(ctx, url, httpHeaders, method, payload)
+				return func() {
+					if err != nil {
+						reject(err.Error())
+						return
+					}
+					resolve(res)
+				}
+			})
+			return promise, nil
 		}
 	})
 
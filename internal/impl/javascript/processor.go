@@ -38,6 +38,7 @@ const (
 	codeField    = "code"
 	fileField    = "file"
 	includeField = "global_folders"
+	rlField      = "fetch_rate_limit"
 )
 
 func javascriptProcessorConfig() *service.ConfigSpec {
@@ -70,6 +71,10 @@ In order to optimize code execution JS runtimes are created on demand (in order
 
 Although technically possible, it is recommended that you do not rely on the global state for maintaining state across invocations as the pooling nature of the runtimes will prevent deterministic behavior. We aim to support deterministic strategies for mutating global state in the future.
 
+== Promises
+
+Promises and async functions are supported. Once a program has executed for a message the processor waits for all asynchronous functions called for it, such as `+"`benthos.v0_fetch_async`"+`, to settle before moving on to the next message, and therefore mutations within their callbacks apply to the message. A rejected promise that is not handled results in an error.
+
 == Functions
 `+description.String()+`
 `).
@@ -82,6 +87,11 @@ Although technically possible, it is recommended that you do not rely on the glo
 		Field(service.NewStringListField(includeField).
 			Description("List of folders that will be used to load modules from if the requested JS module is not found elsewhere.").
 			Default([]string{})).
+		Field(service.NewStringField(rlField).
+			Description("An optional xref:components:rate_limits/about.adoc[rate limit resource] to throttle HTTP requests made with `benthos.v0_fetch` and `benthos.v0_fetch_async`.").
+			Optional().
+			Advanced().
+			Version("4.45.0")).
 		LintRule(fmt.Sprintf(`
 let codeLen = (this.%v | "").length()
 let fileLen = (this.%v | "").length()
@@ -135,6 +145,8 @@ type javascriptProcessor struct {
 	program         *goja.Program
 	requireRegistry *require.Registry
 	logger          *service.Logger
+	mgr             *service.Resources
+	fetchRateLimit  string
 	vmPool          sync.Pool
 }
 
@@ -204,10 +216,22 @@ func newJavascriptProcessorFromConfig(conf *service.ParsedConfig, mgr *service.R
 	)
 	requireRegistry.RegisterNativeModule("console", console.RequireWithPrinter(&Logger{logger}))
 
+	var fetchRateLimit string
+	if conf.Contains(rlField) {
+		if fetchRateLimit, err = conf.FieldString(rlField); err != nil {
+			return nil, err
+		}
+		if !mgr.HasRateLimit(fetchRateLimit) {
+			return nil, fmt.Errorf("rate limit resource '%v' was not found", fetchRateLimit)
+		}
+	}
+
 	return &javascriptProcessor{
 		program:         program,
 		requireRegistry: requireRegistry,
 		logger:          logger,
+		mgr:             mgr,
+		fetchRateLimit:  fetchRateLimit,
 		vmPool:          sync.Pool{},
 	}, nil
 }
@@ -23,6 +23,7 @@ import (
 	"net/http/httptest"
 	"os"
 	"path"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -393,3 +394,96 @@ code: |
 
 	require.NoError(t, proc.Close(bCtx))
 }
+
+func TestProcessorHTTPFetchAsync(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			http.Error(w, "nah", http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write([]byte("echo: " + r.URL.Path))
+	}))
+	t.Cleanup(testServer.Close)
+
+	var accesses atomic.Int64
+	res := service.MockResources(service.MockResourcesOptAddRateLimit("fetches", func(context.Context) (time.Duration, error) {
+		accesses.Add(1)
+		return 0, nil
+	}))
+
+	conf, err := javascriptProcessorConfig().ParseYAML(fmt.Sprintf(`
+fetch_rate_limit: fetches
+code: |
+  (async () => {
+    let [a, b] = await Promise.all([
+      benthos.v0_fetch_async("%[1]v/a", {}, "GET", ""),
+      benthos.v0_fetch_async("%[1]v/" + benthos.v0_msg_as_string(), {}, "GET", ""),
+    ]);
+    let c = await benthos.v0_fetch_async("%[1]v/fail", {}, "GET", "");
+    benthos.v0_msg_set_string([a.body, b.body, c.status].join(", "));
+  })();
+`, testServer.URL), nil)
+	require.NoError(t, err)
+
+	proc, err := newJavascriptProcessorFromConfig(conf, res)
+	require.NoError(t, err)
+
+	bCtx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	resBatches, err := proc.ProcessBatch(bCtx, service.MessageBatch{
+		service.NewMessage([]byte("first")),
+		service.NewMessage([]byte("second")),
+	})
+	require.NoError(t, err)
+	require.Len(t, resBatches, 1)
+	require.Len(t, resBatches[0], 2)
+
+	resBytes, err := resBatches[0][0].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, "echo: /a, echo: /first, 502", string(resBytes))
+
+	resBytes, err = resBatches[0][1].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, "echo: /a, echo: /second, 502", string(resBytes))
+
+	assert.Equal(t, int64(6), accesses.Load())
+
+	require.NoError(t, proc.Close(bCtx))
+}
+
+func TestProcessorHTTPFetchAsyncRejection(t *testing.T) {
+	conf, err := javascriptProcessorConfig().ParseYAML(`
+code: |
+  (() => {
+    benthos.v0_fetch_async("not a valid url", {}, "GET", "").then((res) => {
+      benthos.v0_msg_set_string(res.body);
+    });
+  })();
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := newJavascriptProcessorFromConfig(conf, service.MockResources())
+	require.NoError(t, err)
+
+	bCtx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	_, err = proc.ProcessBatch(bCtx, service.MessageBatch{
+		service.NewMessage([]byte("first")),
+	})
+	require.ErrorContains(t, err, "unhandled promise rejection")
+
+	require.NoError(t, proc.Close(bCtx))
+}
+
+func TestProcessorFetchRateLimitMissing(t *testing.T) {
+	conf, err := javascriptProcessorConfig().ParseYAML(`
+code: 'benthos.v0_msg_set_string("hello")'
+fetch_rate_limit: nope
+`, nil)
+	require.NoError(t, err)
+
+	_, err = newJavascriptProcessorFromConfig(conf, service.MockResources())
+	require.EqualError(t, err, "rate limit resource 'nope' was not found")
+}
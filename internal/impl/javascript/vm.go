@@ -17,6 +17,10 @@ package javascript
 import (
 	"context"
 	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
 
 	"github.com/dop251/goja"
 	"github.com/dop251/goja_nodejs/console"
@@ -28,11 +32,22 @@ type vmRunner struct {
 	vm *goja.Runtime
 	p  *goja.Program
 
-	logger *service.Logger
+	logger         *service.Logger
+	mgr            *service.Resources
+	fetchRateLimit string
 
+	ctx           context.Context
 	runBatch      service.MessageBatch
 	targetMessage *service.Message
 	targetIndex   int
+
+	// Asynchronous operations of the current message, which settle on the VM
+	// goroutine via the settled channel, or are abandoned once the message has
+	// been processed.
+	pending   int
+	settled   chan func()
+	abandoned chan struct{}
+	rejected  map[*goja.Promise]struct{}
 }
 
 func (j *javascriptProcessor) newVM() (*vmRunner, error) {
@@ -42,11 +57,22 @@ func (j *javascriptProcessor) newVM() (*vmRunner, error) {
 	console.Enable(vm)
 
 	vr := &vmRunner{
-		vm:     vm,
-		logger: j.logger,
-		p:      j.program,
+		vm:             vm,
+		logger:         j.logger,
+		mgr:            j.mgr,
+		fetchRateLimit: j.fetchRateLimit,
+		p:              j.program,
+		rejected:       map[*goja.Promise]struct{}{},
 	}
 
+	vm.SetPromiseRejectionTracker(func(p *goja.Promise, op goja.PromiseRejectionOperation) {
+		if op == goja.PromiseRejectionReject {
+			vr.rejected[p] = struct{}{}
+		} else {
+			delete(vr.rejected, p)
+		}
+	})
+
 	for name, fc := range vmRunnerFunctionCtors {
 		if err := setFunction(vr, name, fc.ctor(vr)); err != nil {
 			return nil, err
@@ -129,9 +155,114 @@ func parseArgs(call goja.FunctionCall, ptrs ...any) error {
 }
 
 func (r *vmRunner) reset() {
+	r.ctx = nil
 	r.runBatch = nil
 	r.targetMessage = nil
 	r.targetIndex = 0
+	r.pending = 0
+	r.settled = nil
+	if r.abandoned != nil {
+		close(r.abandoned)
+		r.abandoned = nil
+	}
+	clear(r.rejected)
+}
+
+// runAsync executes fn in the background and then the function it returns on
+// the VM goroutine once the program has yielded, which is where promises must
+// be settled.
+func (r *vmRunner) runAsync(fn func(ctx context.Context) func()) {
+	ctx, settled, abandoned := r.ctx, r.settled, r.abandoned
+	r.pending++
+	go func() {
+		settle := fn(ctx)
+		select {
+		case settled <- settle:
+		case <-abandoned:
+		}
+	}()
+}
+
+// awaitAsync waits for all asynchronous operations of the current message to
+// settle, including those started by the settling of others.
+func (r *vmRunner) awaitAsync() error {
+	for r.pending > 0 {
+		select {
+		case settle := <-r.settled:
+			r.pending--
+			settle()
+		case <-r.ctx.Done():
+			return r.ctx.Err()
+		}
+	}
+	for p := range r.rejected {
+		return fmt.Errorf("unhandled promise rejection: %v", p.Result())
+	}
+	return nil
+}
+
+func (r *vmRunner) fetch(ctx context.Context, url string, headers map[string]any, method, payload string) (map[string]any, error) {
+	if r.fetchRateLimit != "" {
+		if err := r.waitForRateLimit(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	var payloadReader io.Reader
+	if payload != "" {
+		payloadReader = strings.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, payloadReader)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse HTTP headers
+	for k, v := range headers {
+		vStr, _ := v.(string)
+		req.Header.Add(k, vStr)
+	}
+
+	// Do request
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]any{
+		"status": resp.StatusCode,
+		"body":   string(respBody),
+	}, nil
+}
+
+func (r *vmRunner) waitForRateLimit(ctx context.Context) error {
+	for {
+		var period time.Duration
+		var rlErr error
+		if err := r.mgr.AccessRateLimit(ctx, r.fetchRateLimit, func(rl service.RateLimit) {
+			period, rlErr = rl.Access(ctx)
+		}); err != nil {
+			return err
+		}
+		if rlErr != nil {
+			return rlErr
+		}
+		if period <= 0 {
+			return nil
+		}
+		select {
+		case <-time.After(period):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
 }
 
 func (r *vmRunner) Run(ctx context.Context, batch service.MessageBatch) (service.MessageBatch, error) {
@@ -140,11 +271,17 @@ func (r *vmRunner) Run(ctx context.Context, batch service.MessageBatch) (service
 	var newBatch service.MessageBatch
 	for i := range batch {
 		r.reset()
+		r.ctx = ctx
+		r.settled = make(chan func())
+		r.abandoned = make(chan struct{})
 		r.runBatch = batch
 		r.targetIndex = i
 		r.targetMessage = batch[i]
 
 		_, err := r.vm.RunProgram(r.p)
+		if err == nil {
+			err = r.awaitAsync()
+		}
 		if err != nil {
 			// TODO: Make this more granular, error could be message specific
 			return nil, err
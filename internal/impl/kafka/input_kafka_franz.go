@@ -19,6 +19,8 @@ import (
 
 	"github.com/redpanda-data/benthos/v4/public/service"
 	"github.com/twmb/franz-go/pkg/kgo"
+
+	"github.com/redpanda-data/connect/v4/internal/tracing"
 )
 
 func franzKafkaInputConfig() *service.ConfigSpec {
@@ -74,6 +76,7 @@ func FranzKafkaInputConfigFields() []*service.ConfigField {
 		[]*service.ConfigField{
 			service.NewAutoRetryNacksToggleField(),
 		},
+		tracing.ExtractFields(),
 	)
 }
 
@@ -96,7 +99,11 @@ func init() {
 				return nil, err
 			}
 
-			return service.AutoRetryNacksBatchedToggled(conf, rdr)
+			r, err := service.AutoRetryNacksBatchedToggled(conf, rdr)
+			if err != nil {
+				return nil, err
+			}
+			return tracing.WrapBatchInput(conf, mgr, "kafka_franz", r)
 		})
 	if err != nil {
 		panic(err)
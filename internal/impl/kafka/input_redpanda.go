@@ -19,6 +19,8 @@ import (
 
 	"github.com/redpanda-data/benthos/v4/public/service"
 	"github.com/twmb/franz-go/pkg/kgo"
+
+	"github.com/redpanda-data/connect/v4/internal/tracing"
 )
 
 func redpandaInputConfig() *service.ConfigSpec {
@@ -100,6 +102,7 @@ func redpandaInputConfigFields() []*service.ConfigField {
 		[]*service.ConfigField{
 			service.NewAutoRetryNacksToggleField(),
 		},
+		tracing.ExtractFields(),
 	)
 }
 
@@ -124,7 +127,11 @@ func init() {
 				return nil, err
 			}
 
-			return service.AutoRetryNacksBatchedToggled(conf, rdr)
+			r, err := service.AutoRetryNacksBatchedToggled(conf, rdr)
+			if err != nil {
+				return nil, err
+			}
+			return tracing.WrapBatchInput(conf, mgr, "redpanda", r)
 		})
 	if err != nil {
 		panic(err)
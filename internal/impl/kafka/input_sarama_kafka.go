@@ -26,6 +26,8 @@ import (
 	"github.com/Jeffail/checkpoint"
 
 	"github.com/redpanda-data/benthos/v4/public/service"
+
+	"github.com/redpanda-data/connect/v4/internal/tracing"
 )
 
 const (
@@ -138,7 +140,6 @@ Unfortunately this error message will appear for a wide range of connection prob
 			service.NewDurationField(iskFieldMaxProcessingPeriod).
 				Description("A maximum estimate for the time taken to process a message, this is used for tuning consumer group synchronization.").
 				Advanced().Default("100ms"),
-			service.NewExtractTracingSpanMappingField(),
 			service.NewObjectField(iskFieldGroup,
 				service.NewDurationField(iskFieldGroupSessionTimeout).
 					Description("A period after which a consumer of the group is kicked after no heartbeats.").
@@ -159,7 +160,8 @@ Unfortunately this error message will appear for a wide range of connection prob
 				Description("Decode headers into lists to allow handling of multiple values with the same key").
 				Advanced().Default(false),
 			service.NewBatchPolicyField(iskFieldBatching).Advanced(),
-		)
+		).
+		Fields(tracing.ExtractFields()...)
 }
 
 func init() {
@@ -174,7 +176,7 @@ func init() {
 			return nil, err
 		}
 
-		return conf.WrapBatchInputExtractTracingSpanMapping("kafka", r)
+		return tracing.WrapBatchInput(conf, mgr, "kafka", r)
 	})
 	if err != nil {
 		panic(err)
@@ -57,6 +57,7 @@ func FranzKafkaOutputConfigFields() []*service.ConfigField {
 				Description("The maximum number of batches to be sending in parallel at any given time.").
 				Default(10),
 			service.NewBatchPolicyField(kfoFieldBatching),
+			service.NewInjectTracingSpanMappingField().Version("4.45.0"),
 
 			// Deprecated
 			service.NewStringField(kfoFieldRackID).Deprecated(),
@@ -116,6 +117,10 @@ func init() {
 				client = nil
 				return nil
 			})
+			if err != nil {
+				return
+			}
+			output, err = conf.WrapBatchOutputExtractTracingSpanMapping("kafka_franz", output)
 			return
 		})
 	if err != nil {
@@ -48,6 +48,7 @@ func redpandaOutputConfigFields() []*service.ConfigField {
 			service.NewIntField(roFieldMaxInFlight).
 				Description("The maximum number of batches to be sending in parallel at any given time.").
 				Default(256),
+			service.NewInjectTracingSpanMappingField().Version("4.45.0"),
 		},
 		FranzProducerFields(),
 	)
@@ -108,6 +109,10 @@ func init() {
 				client = nil
 				return nil
 			})
+			if err != nil {
+				return
+			}
+			output, err = conf.WrapBatchOutputExtractTracingSpanMapping("redpanda", output)
 			return
 		})
 	if err != nil {
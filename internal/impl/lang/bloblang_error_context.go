@@ -0,0 +1,97 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lang
+
+import (
+	"regexp"
+	"strconv"
+
+	"github.com/redpanda-data/benthos/v4/public/bloblang"
+)
+
+var (
+	errCtxAssignmentRegexp  = regexp.MustCompile(`^failed assignment \(line (\d+)\): `)
+	errCtxFieldPrefixRegexp = regexp.MustCompile("^field `([^`]+)`: ")
+	errCtxFieldRegexp       = regexp.MustCompile("field `([^`]+)`")
+)
+
+// errorContext breaks a mapping error string down into the line numbers of the
+// assignments that failed, the path of the field involved, and the remaining
+// message. Strings that are not mapping errors are returned as the message.
+func errorContext(s string) map[string]any {
+	msg := s
+	lines := []any{}
+	for {
+		m := errCtxAssignmentRegexp.FindStringSubmatch(msg)
+		if m == nil {
+			break
+		}
+		n, _ := strconv.ParseInt(m[1], 10, 64)
+		lines = append(lines, n)
+		msg = msg[len(m[0]):]
+	}
+
+	var path any
+	if m := errCtxFieldPrefixRegexp.FindStringSubmatch(msg); m != nil {
+		path = m[1]
+		msg = msg[len(m[0]):]
+	} else if m := errCtxFieldRegexp.FindStringSubmatch(msg); m != nil {
+		path = m[1]
+	}
+
+	var line any
+	if len(lines) > 0 {
+		line = lines[0]
+	}
+	return map[string]any{
+		"message": msg,
+		"line":    line,
+		"lines":   lines,
+		"path":    path,
+	}
+}
+
+func init() {
+	errorContextSpec := bloblang.NewPluginSpec().
+		Beta().
+		Version("4.45.0").
+		Category("Parsing").
+		Description("Parses an error string, such as the result of the `error` function, into an object describing where within a mapping the error occurred. The field `line` contains the line number of the assignment that failed, and `lines` contains the line numbers of each nested assignment when the error occurred within a named map, starting with the outermost. The field `path` contains the path of the field that the error relates to, and `message` contains the remainder of the error. Fields that cannot be determined from the error are `null`, and errors that did not originate from a mapping are returned entirely as the `message`. This makes it possible to route messages that failed a mapping by the field responsible, with a condition such as `error().error_context().path == \"this.price\"`.").
+		Example("",
+			`root = this.error.error_context()`,
+			[2]string{
+				`{"error":"failed assignment (line 2): field ` + "`this.price`" + `: strconv.ParseFloat: parsing \"free\": invalid syntax"}`,
+				`{"line":2,"lines":[2],"message":"strconv.ParseFloat: parsing \"free\": invalid syntax","path":"this.price"}`,
+			},
+			[2]string{
+				`{"error":"failed assignment (line 4): failed assignment (line 2): expected number value, got null from field ` + "`this.count`" + `"}`,
+				`{"line":4,"lines":[4,2],"message":"expected number value, got null from field ` + "`this.count`" + `","path":"this.count"}`,
+			},
+			[2]string{
+				`{"error":"connection refused"}`,
+				`{"line":null,"lines":[],"message":"connection refused","path":null}`,
+			})
+
+	if err := bloblang.RegisterMethodV2(
+		"error_context", errorContextSpec,
+		func(args *bloblang.ParsedParams) (bloblang.Method, error) {
+			return bloblang.StringMethod(func(s string) (any, error) {
+				return errorContext(s), nil
+			}), nil
+		},
+	); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,463 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lang
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redpanda-data/benthos/v4/public/bloblang"
+)
+
+// The number of elements or characters generated beyond the minimum when a
+// schema does not specify a maximum.
+const fakeSchemaDefaultSpread = 5
+
+// The maximum depth of nested schemas, which prevents recursive references
+// from generating values indefinitely.
+const fakeSchemaMaxDepth = 32
+
+const fakeSchemaAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789 _-"
+
+func init() {
+	fakeSchemaSpec := bloblang.NewPluginSpec().
+		Beta().
+		Category("Fake Data Generation").
+		Description("Generates a random value that conforms to a https://json-schema.org/[JSON Schema^], which is useful for testing mappings against a variety of documents. The keywords `type`, `enum`, `const`, `properties`, `required`, `additionalProperties`, `items`, `minItems`, `maxItems`, `minLength`, `maxLength`, `format`, `minimum`, `maximum`, `exclusiveMinimum`, `exclusiveMaximum`, `multipleOf`, `oneOf`, `anyOf` and local `$ref` references are supported, and values at the boundaries of ranges are generated more often than others. An error is returned when a schema contains the keywords `pattern`, `allOf` or `not`.").
+		Param(bloblang.NewStringParam("schema").Description("The JSON Schema document to generate values for.")).
+		Param(bloblang.NewInt64Param("seed").Description("A seed for the random number generator, which makes the sequence of generated values reproducible. By default values are not reproducible.").Optional()).
+		ExampleNotTested("Generate documents from a schema read from a file, with a seed in order that a failing document can be reproduced:",
+			`root = fake_json_schema(file("./schema.json").string(), 42)`).
+		Example("Generate documents from an inline schema:",
+			`root = fake_json_schema("""{"type":"object","properties":{"id":{"type":"integer","minimum":1}},"required":["id"]}""")`)
+
+	if err := bloblang.RegisterFunctionV2(
+		"fake_json_schema", fakeSchemaSpec,
+		func(args *bloblang.ParsedParams) (bloblang.Function, error) {
+			schemaStr, err := args.GetString("schema")
+			if err != nil {
+				return nil, err
+			}
+			seedPtr, err := args.GetOptionalInt64("seed")
+			if err != nil {
+				return nil, err
+			}
+
+			g, err := newFakeSchemaGenerator(schemaStr, seedPtr)
+			if err != nil {
+				return nil, err
+			}
+			return g.generate, nil
+		},
+	); err != nil {
+		panic(err)
+	}
+}
+
+type fakeSchemaGenerator struct {
+	root any
+
+	mut sync.Mutex
+	rnd *rand.Rand
+}
+
+func newFakeSchemaGenerator(schemaStr string, seed *int64) (*fakeSchemaGenerator, error) {
+	var root any
+	if err := json.Unmarshal([]byte(schemaStr), &root); err != nil {
+		return nil, fmt.Errorf("failed to parse schema: %w", err)
+	}
+	s := uint64(time.Now().UnixNano())
+	if seed != nil {
+		s = uint64(*seed)
+	}
+	return &fakeSchemaGenerator{
+		root: root,
+		rnd:  rand.New(rand.NewPCG(s, s)),
+	}, nil
+}
+
+func (g *fakeSchemaGenerator) generate() (any, error) {
+	g.mut.Lock()
+	defer g.mut.Unlock()
+	return g.value(g.root, 0)
+}
+
+func (g *fakeSchemaGenerator) resolveRef(ref string) (any, error) {
+	if !strings.HasPrefix(ref, "#") {
+		return nil, fmt.Errorf("reference '%v' is not supported, only local references are", ref)
+	}
+	current := g.root
+	for _, seg := range strings.Split(strings.TrimPrefix(strings.TrimPrefix(ref, "#"), "/"), "/") {
+		if seg == "" {
+			continue
+		}
+		seg = strings.ReplaceAll(strings.ReplaceAll(seg, "~1", "/"), "~0", "~")
+		obj, ok := current.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("reference '%v' not found", ref)
+		}
+		if current, ok = obj[seg]; !ok {
+			return nil, fmt.Errorf("reference '%v' not found", ref)
+		}
+	}
+	return current, nil
+}
+
+func (g *fakeSchemaGenerator) value(schema any, depth int) (any, error) {
+	if depth > fakeSchemaMaxDepth {
+		return nil, errors.New("schema is nested too deeply")
+	}
+
+	switch t := schema.(type) {
+	case bool:
+		if !t {
+			return nil, errors.New("schema false does not allow any value")
+		}
+		return g.anyScalar(), nil
+	case map[string]any:
+	default:
+		return nil, fmt.Errorf("expected schema to be an object, got %T", schema)
+	}
+	obj := schema.(map[string]any)
+
+	for _, k := range []string{"pattern", "allOf", "not"} {
+		if _, exists := obj[k]; exists {
+			return nil, fmt.Errorf("schema keyword '%v' is not supported", k)
+		}
+	}
+
+	if ref, ok := obj["$ref"].(string); ok {
+		resolved, err := g.resolveRef(ref)
+		if err != nil {
+			return nil, err
+		}
+		return g.value(resolved, depth+1)
+	}
+	if c, exists := obj["const"]; exists {
+		return c, nil
+	}
+	if enum, ok := obj["enum"].([]any); ok {
+		if len(enum) == 0 {
+			return nil, errors.New("schema enum is empty")
+		}
+		return enum[g.rnd.IntN(len(enum))], nil
+	}
+	for _, k := range []string{"oneOf", "anyOf"} {
+		if options, ok := obj[k].([]any); ok {
+			if len(options) == 0 {
+				return nil, fmt.Errorf("schema %v is empty", k)
+			}
+			return g.value(options[g.rnd.IntN(len(options))], depth+1)
+		}
+	}
+
+	switch g.schemaType(obj) {
+	case "null":
+		return nil, nil
+	case "boolean":
+		return g.rnd.IntN(2) == 0, nil
+	case "integer":
+		return g.integer(obj)
+	case "number":
+		return g.number(obj)
+	case "string":
+		return g.stringValue(obj)
+	case "array":
+		return g.array(obj, depth)
+	case "object":
+		return g.object(obj, depth)
+	}
+	return g.anyScalar(), nil
+}
+
+func (g *fakeSchemaGenerator) schemaType(obj map[string]any) string {
+	switch t := obj["type"].(type) {
+	case string:
+		return t
+	case []any:
+		if len(t) > 0 {
+			if s, ok := t[g.rnd.IntN(len(t))].(string); ok {
+				return s
+			}
+		}
+	}
+	if _, exists := obj["properties"]; exists {
+		return "object"
+	}
+	if _, exists := obj["items"]; exists {
+		return "array"
+	}
+	return ""
+}
+
+func (g *fakeSchemaGenerator) anyScalar() any {
+	switch g.rnd.IntN(3) {
+	case 0:
+		return g.rnd.IntN(2) == 0
+	case 1:
+		return int64(g.rnd.IntN(1000))
+	}
+	return g.randomString(g.rnd.IntN(fakeSchemaDefaultSpread + 1))
+}
+
+func numberKeyword(obj map[string]any, key string) (float64, bool) {
+	f, ok := obj[key].(float64)
+	return f, ok
+}
+
+// numericBounds returns the inclusive range of a numeric schema, where step is
+// the smallest difference between two values. A range of a million is used in
+// place of a bound that is not specified.
+func numericBounds(obj map[string]any, step float64) (minV, maxV float64) {
+	var hasMin, hasMax bool
+	if v, ok := numberKeyword(obj, "minimum"); ok {
+		minV, hasMin = v, true
+	}
+	if v, ok := numberKeyword(obj, "exclusiveMinimum"); ok && (!hasMin || v+step > minV) {
+		minV, hasMin = v+step, true
+	}
+	if v, ok := numberKeyword(obj, "maximum"); ok {
+		maxV, hasMax = v, true
+	}
+	if v, ok := numberKeyword(obj, "exclusiveMaximum"); ok && (!hasMax || v-step < maxV) {
+		maxV, hasMax = v-step, true
+	}
+	switch {
+	case !hasMin && !hasMax:
+		minV, maxV = -1e6, 1e6
+	case !hasMin:
+		minV = maxV - 1e6
+	case !hasMax:
+		maxV = minV + 1e6
+	}
+	return
+}
+
+func (g *fakeSchemaGenerator) integer(obj map[string]any) (any, error) {
+	minF, maxF := numericBounds(obj, 1)
+	minV, maxV := int64(math.Ceil(minF)), int64(math.Floor(maxF))
+
+	multiple := int64(1)
+	if m, ok := numberKeyword(obj, "multipleOf"); ok {
+		if m <= 0 || m != math.Trunc(m) {
+			return nil, errors.New("schema multipleOf of an integer must be a positive integer")
+		}
+		multiple = int64(m)
+	}
+
+	// Values are generated as a multiple within the range.
+	lowM := int64(math.Ceil(float64(minV) / float64(multiple)))
+	highM := int64(math.Floor(float64(maxV) / float64(multiple)))
+	if highM < lowM {
+		return nil, errors.New("schema does not allow any integer")
+	}
+
+	var n int64
+	switch g.rnd.IntN(4) {
+	case 0:
+		n = lowM
+	case 1:
+		n = highM
+	default:
+		n = lowM + g.rnd.Int64N(highM-lowM+1)
+	}
+	return n * multiple, nil
+}
+
+func (g *fakeSchemaGenerator) number(obj map[string]any) (any, error) {
+	if _, ok := obj["multipleOf"]; ok {
+		return g.integerMultipleNumber(obj)
+	}
+	minV, maxV := numericBounds(obj, 1e-9)
+	if maxV < minV {
+		return nil, errors.New("schema does not allow any number")
+	}
+	switch g.rnd.IntN(4) {
+	case 0:
+		return minV, nil
+	case 1:
+		return maxV, nil
+	}
+	return minV + g.rnd.Float64()*(maxV-minV), nil
+}
+
+func (g *fakeSchemaGenerator) integerMultipleNumber(obj map[string]any) (any, error) {
+	m, _ := numberKeyword(obj, "multipleOf")
+	if m <= 0 {
+		return nil, errors.New("schema multipleOf must be positive")
+	}
+	minV, maxV := numericBounds(obj, 1e-9)
+	lowM, highM := int64(math.Ceil(minV/m)), int64(math.Floor(maxV/m))
+	if highM < lowM {
+		return nil, errors.New("schema does not allow any number")
+	}
+	return float64(lowM+g.rnd.Int64N(highM-lowM+1)) * m, nil
+}
+
+// length returns a random length within the range of two keywords, where the
+// bounds are generated more often than other lengths.
+func (g *fakeSchemaGenerator) length(obj map[string]any, minKey, maxKey string) (int, error) {
+	minL, maxL := 0, -1
+	if v, ok := numberKeyword(obj, minKey); ok {
+		minL = int(v)
+	}
+	if v, ok := numberKeyword(obj, maxKey); ok {
+		maxL = int(v)
+	}
+	if maxL < 0 {
+		maxL = minL + fakeSchemaDefaultSpread
+	}
+	if maxL < minL {
+		return 0, fmt.Errorf("schema %v is less than %v", maxKey, minKey)
+	}
+	switch g.rnd.IntN(4) {
+	case 0:
+		return minL, nil
+	case 1:
+		return maxL, nil
+	}
+	return minL + g.rnd.IntN(maxL-minL+1), nil
+}
+
+func (g *fakeSchemaGenerator) randomString(n int) string {
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		b.WriteByte(fakeSchemaAlphabet[g.rnd.IntN(len(fakeSchemaAlphabet))])
+	}
+	return b.String()
+}
+
+func (g *fakeSchemaGenerator) stringValue(obj map[string]any) (any, error) {
+	if format, ok := obj["format"].(string); ok {
+		ts := time.Unix(g.rnd.Int64N(4102444800), 0).UTC()
+		switch format {
+		case "date-time":
+			return ts.Format(time.RFC3339), nil
+		case "date":
+			return ts.Format(time.DateOnly), nil
+		case "time":
+			return ts.Format(time.TimeOnly), nil
+		case "email":
+			return strings.ToLower(g.randomAlnum(8)) + "@example.com", nil
+		case "uuid":
+			b := make([]byte, 16)
+			for i := range b {
+				b[i] = byte(g.rnd.IntN(256))
+			}
+			b[6] = (b[6] & 0x0f) | 0x40
+			b[8] = (b[8] & 0x3f) | 0x80
+			return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+		case "uri":
+			return "https://example.com/" + strings.ToLower(g.randomAlnum(8)), nil
+		case "ipv4":
+			return fmt.Sprintf("%d.%d.%d.%d", g.rnd.IntN(256), g.rnd.IntN(256), g.rnd.IntN(256), g.rnd.IntN(256)), nil
+		}
+	}
+	n, err := g.length(obj, "minLength", "maxLength")
+	if err != nil {
+		return nil, err
+	}
+	return g.randomString(n), nil
+}
+
+func (g *fakeSchemaGenerator) randomAlnum(n int) string {
+	const alnum = "abcdefghijklmnopqrstuvwxyz0123456789"
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		b.WriteByte(alnum[g.rnd.IntN(len(alnum))])
+	}
+	return b.String()
+}
+
+func (g *fakeSchemaGenerator) array(obj map[string]any, depth int) (any, error) {
+	n, err := g.length(obj, "minItems", "maxItems")
+	if err != nil {
+		return nil, err
+	}
+	items, exists := obj["items"]
+	if !exists {
+		items = true
+	}
+	arr := make([]any, 0, n)
+	for i := 0; i < n; i++ {
+		v, err := g.value(items, depth+1)
+		if err != nil {
+			return nil, fmt.Errorf("items: %w", err)
+		}
+		arr = append(arr, v)
+	}
+	return arr, nil
+}
+
+func (g *fakeSchemaGenerator) object(obj map[string]any, depth int) (any, error) {
+	props, _ := obj["properties"].(map[string]any)
+
+	required := map[string]struct{}{}
+	if req, ok := obj["required"].([]any); ok {
+		for _, r := range req {
+			if s, ok := r.(string); ok {
+				required[s] = struct{}{}
+			}
+		}
+	}
+
+	// Properties are walked in a sorted order so that seeded sequences are
+	// reproducible.
+	keys := make([]string, 0, len(props))
+	for k := range props {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	res := map[string]any{}
+	for _, k := range keys {
+		if _, isRequired := required[k]; !isRequired && g.rnd.IntN(2) == 0 {
+			continue
+		}
+		v, err := g.value(props[k], depth+1)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %w", k, err)
+		}
+		res[k] = v
+	}
+
+	// Required properties without a schema are generated as any value.
+	var unknown []string
+	for k := range required {
+		if _, hasSchema := props[k]; !hasSchema {
+			unknown = append(unknown, k)
+		}
+	}
+	sort.Strings(unknown)
+	for _, k := range unknown {
+		res[k] = g.anyScalar()
+	}
+
+	if additional, ok := obj["additionalProperties"].(map[string]any); ok && g.rnd.IntN(2) == 0 {
+		v, err := g.value(additional, depth+1)
+		if err != nil {
+			return nil, fmt.Errorf("additionalProperties: %w", err)
+		}
+		res[g.randomAlnum(6)] = v
+	}
+	return res, nil
+}
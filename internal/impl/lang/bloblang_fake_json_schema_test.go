@@ -0,0 +1,128 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lang
+
+import (
+	"encoding/json"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xeipuuv/gojsonschema"
+
+	"github.com/redpanda-data/benthos/v4/public/bloblang"
+)
+
+const fakeTestSchema = `{
+  "type": "object",
+  "properties": {
+    "id": { "type": "integer", "minimum": 1, "maximum": 100 },
+    "price": { "type": "number", "exclusiveMinimum": 0, "maximum": 10 },
+    "quantity": { "type": "integer", "multipleOf": 5, "minimum": 0, "maximum": 50 },
+    "name": { "type": "string", "minLength": 2, "maxLength": 8 },
+    "status": { "enum": ["active", "inactive"] },
+    "created_at": { "type": "string", "format": "date-time" },
+    "email": { "type": "string", "format": "email" },
+    "tags": {
+      "type": "array",
+      "items": { "type": "string", "maxLength": 3 },
+      "minItems": 1,
+      "maxItems": 4
+    },
+    "owner": { "$ref": "#/$defs/person" },
+    "note": { "type": ["string", "null"] },
+    "shape": {
+      "oneOf": [
+        { "type": "object", "properties": { "radius": { "type": "number", "minimum": 0 } }, "required": ["radius"] },
+        { "type": "boolean" }
+      ]
+    }
+  },
+  "required": ["id", "name", "tags", "owner"],
+  "additionalProperties": { "type": "integer" },
+  "$defs": {
+    "person": {
+      "type": "object",
+      "properties": { "first_name": { "type": "string", "minLength": 1 } },
+      "required": ["first_name"]
+    }
+  }
+}`
+
+func TestFakeJSONSchemaConforms(t *testing.T) {
+	schema, err := gojsonschema.NewSchema(gojsonschema.NewStringLoader(fakeTestSchema))
+	require.NoError(t, err)
+
+	e, err := bloblang.Parse(`root = fake_json_schema(` + strconv.Quote(fakeTestSchema) + `, 7)`)
+	require.NoError(t, err)
+
+	for i := 0; i < 500; i++ {
+		v, err := e.Query(nil)
+		require.NoError(t, err)
+
+		vBytes, err := json.Marshal(v)
+		require.NoError(t, err)
+
+		res, err := schema.Validate(gojsonschema.NewBytesLoader(vBytes))
+		require.NoError(t, err)
+		assert.True(t, res.Valid(), "%s: %v", vBytes, res.Errors())
+	}
+}
+
+func TestFakeJSONSchemaSeed(t *testing.T) {
+	generate := func(mapping string) []string {
+		e, err := bloblang.Parse(mapping)
+		require.NoError(t, err)
+
+		var results []string
+		for i := 0; i < 10; i++ {
+			v, err := e.Query(nil)
+			require.NoError(t, err)
+
+			vBytes, err := json.Marshal(v)
+			require.NoError(t, err)
+			results = append(results, string(vBytes))
+		}
+		return results
+	}
+
+	seeded := `root = fake_json_schema(` + strconv.Quote(fakeTestSchema) + `, 42)`
+	assert.Equal(t, generate(seeded), generate(seeded))
+	assert.NotEqual(t, generate(seeded), generate(`root = fake_json_schema(`+strconv.Quote(fakeTestSchema)+`, 43)`))
+}
+
+func TestFakeJSONSchemaErrors(t *testing.T) {
+	tests := map[string]string{
+		"invalid json":     `{"type":`,
+		"pattern":          `{"type":"string","pattern":"^a+$"}`,
+		"empty enum":       `{"enum":[]}`,
+		"missing ref":      `{"$ref":"#/$defs/nope"}`,
+		"remote ref":       `{"$ref":"https://example.com/schema.json"}`,
+		"empty range":      `{"type":"integer","minimum":5,"maximum":1}`,
+		"recursive ref":    `{"$ref":"#"}`,
+		"inverted lengths": `{"type":"string","minLength":5,"maxLength":1}`,
+	}
+	for name, schema := range tests {
+		t.Run(name, func(t *testing.T) {
+			e, err := bloblang.Parse(`root = fake_json_schema(` + strconv.Quote(schema) + `)`)
+			if err != nil {
+				return
+			}
+			_, err = e.Query(nil)
+			assert.Error(t, err)
+		})
+	}
+}
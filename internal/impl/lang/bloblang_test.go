@@ -111,3 +111,77 @@ func TestULID_BadRandom(t *testing.T) {
 	require.ErrorContains(t, err, "invalid randomness source: not-very-random")
 	require.Nil(t, ex, "did not expect an executable mapping")
 }
+
+func TestErrorContext(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected map[string]any
+	}{
+		{
+			input: "failed assignment (line 1): field `this.foo`: strconv.ParseFloat: parsing \"x\": invalid syntax",
+			expected: map[string]any{
+				"line":    int64(1),
+				"lines":   []any{int64(1)},
+				"path":    "this.foo",
+				"message": `strconv.ParseFloat: parsing "x": invalid syntax`,
+			},
+		},
+		{
+			input: "failed assignment (line 2): expected string value, got null from field `this.foo.bar`",
+			expected: map[string]any{
+				"line":    int64(2),
+				"lines":   []any{int64(2)},
+				"path":    "this.foo.bar",
+				"message": "expected string value, got null from field `this.foo.bar`",
+			},
+		},
+		{
+			input: "failed assignment (line 4): failed assignment (line 2): expected number value, got null from field `this.q`",
+			expected: map[string]any{
+				"line":    int64(4),
+				"lines":   []any{int64(4), int64(2)},
+				"path":    "this.q",
+				"message": "expected number value, got null from field `this.q`",
+			},
+		},
+		{
+			input: "failed assignment (line 1): boom",
+			expected: map[string]any{
+				"line":    int64(1),
+				"lines":   []any{int64(1)},
+				"path":    nil,
+				"message": "boom",
+			},
+		},
+		{
+			input: "connection refused",
+			expected: map[string]any{
+				"line":    nil,
+				"lines":   []any{},
+				"path":    nil,
+				"message": "connection refused",
+			},
+		},
+	}
+
+	exec, err := bloblang.Parse(`root = this.error_context()`)
+	require.NoError(t, err)
+
+	for _, test := range tests {
+		res, err := exec.Query(test.input)
+		require.NoError(t, err)
+		assert.Equal(t, test.expected, res, test.input)
+	}
+}
+
+func TestErrorContextFromMapping(t *testing.T) {
+	exec, err := bloblang.Parse("root.a = 5\nroot.b = this.foo.bar.uppercase()")
+	require.NoError(t, err)
+
+	_, queryErr := exec.Query(map[string]any{"foo": "x"})
+	require.Error(t, queryErr)
+
+	res := errorContext(queryErr.Error())
+	assert.Equal(t, int64(2), res["line"])
+	assert.Equal(t, "this.foo.bar", res["path"])
+}
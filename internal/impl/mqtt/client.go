@@ -42,6 +42,12 @@ const (
 	msFieldClientPassword          = "password"
 	msFieldClientKeepAlive         = "keepalive"
 	msFieldClientTLS               = "tls"
+	msFieldClientProtocolVersion   = "protocol_version"
+)
+
+const (
+	protocolVersion311 = "3.1.1"
+	protocolVersion5   = "5"
 )
 
 func clientFields() []*service.ConfigField {
@@ -97,6 +103,14 @@ func clientFields() []*service.ConfigField {
 			Default(30).
 			Advanced(),
 		service.NewTLSToggledField(msFieldClientTLS),
+		service.NewStringAnnotatedEnumField(msFieldClientProtocolVersion, map[string]string{
+			protocolVersion311: "MQTT 3.1.1.",
+			protocolVersion5:   "MQTT 5, which adds support for user properties, message expiry intervals and session expiry intervals.",
+		}).
+			Description("The version of the MQTT protocol to connect with.").
+			Default(protocolVersion311).
+			Advanced().
+			Version("4.45.0"),
 	}
 }
 
@@ -110,6 +124,8 @@ type clientOptsBuilder struct {
 	tlsEnabled     bool
 	tlsConf        *tls.Config
 	will           willOpt
+
+	protocolVersion string
 }
 
 func clientOptsFromParsed(conf *service.ParsedConfig) (opts clientOptsBuilder, err error) {
@@ -156,9 +172,26 @@ func clientOptsFromParsed(conf *service.ParsedConfig) (opts clientOptsBuilder, e
 	if opts.tlsConf, opts.tlsEnabled, err = conf.FieldTLSToggled(msFieldClientTLS); err != nil {
 		return
 	}
+	if opts.protocolVersion, err = conf.FieldString(msFieldClientProtocolVersion); err != nil {
+		return
+	}
 	return
 }
 
+// requireProtocolV5 returns an error when any of the given fields, which are
+// only supported by MQTT 5, are set while connecting with an older version.
+func (b *clientOptsBuilder) requireProtocolV5(conf *service.ParsedConfig, fields ...string) error {
+	if b.protocolVersion == protocolVersion5 {
+		return nil
+	}
+	for _, f := range fields {
+		if conf.Contains(f) {
+			return fmt.Errorf("field %v requires %v to be %v", f, msFieldClientProtocolVersion, protocolVersion5)
+		}
+	}
+	return nil
+}
+
 func (b *clientOptsBuilder) apply(opts *mqtt.ClientOptions) *mqtt.ClientOptions {
 	opts = opts.SetAutoReconnect(false).
 		SetClientID(b.clientID).
@@ -0,0 +1,136 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mqtt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/eclipse/paho.golang/autopaho"
+	"github.com/eclipse/paho.golang/paho"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+// autopahoConfig returns the configuration of an MQTT 5 connection manager.
+// Unlike the MQTT 3.1.1 client the connection manager reconnects by itself,
+// and resumes the session of the client after reconnecting so long as the
+// session has not expired.
+func (b *clientOptsBuilder) autopahoConfig(log *service.Logger) autopaho.ClientConfig {
+	conf := autopaho.ClientConfig{
+		ServerUrls:     b.urls,
+		KeepAlive:      uint16(b.keepAlive),
+		ConnectTimeout: b.connectTimeout,
+		Errors:         &pahoLogger{log: log},
+		PahoErrors:     &pahoLogger{log: log},
+		OnConnectError: func(err error) {
+			log.Errorf("Failed to connect: %v", err)
+		},
+		ConnectPacketBuilder: func(cp *paho.Connect, _ *url.URL) (*paho.Connect, error) {
+			// Some brokers omit the user properties of messages unless the
+			// client requests problem information, which is otherwise
+			// disabled by the connection manager.
+			if cp.Properties == nil {
+				cp.Properties = &paho.ConnectProperties{}
+			}
+			cp.Properties.RequestProblemInfo = true
+			return cp, nil
+		},
+		ClientConfig: paho.ClientConfig{
+			ClientID: b.clientID,
+			OnClientError: func(err error) {
+				log.Errorf("Connection lost due to: %v", err)
+			},
+			OnServerDisconnect: func(d *paho.Disconnect) {
+				log.Errorf("Disconnected by the server with reason code %v", d.ReasonCode)
+			},
+		},
+	}
+	if b.tlsEnabled {
+		conf.TlsCfg = b.tlsConf
+	}
+	if b.username != "" || b.password != "" {
+		conf.SetUsernamePassword(b.username, []byte(b.password))
+	}
+	if b.will.Enabled {
+		conf.SetWillMessage(b.will.Topic, []byte(b.will.Payload), b.will.QoS, b.will.Retained)
+	}
+	return conf
+}
+
+// connectV5 starts a connection manager and waits for its first connection
+// to be established, shutting the manager down if the connection could not be
+// established within the connect timeout.
+func (b *clientOptsBuilder) connectV5(ctx context.Context, conf autopaho.ClientConfig) (*autopaho.ConnectionManager, error) {
+	var errMut sync.Mutex
+	var connErr error
+	onConnectError := conf.OnConnectError
+	conf.OnConnectError = func(err error) {
+		errMut.Lock()
+		connErr = err
+		errMut.Unlock()
+		onConnectError(err)
+	}
+
+	// The connection manager runs until it is disconnected, and therefore it
+	// must not inherit the context of the connection attempt.
+	cm, err := autopaho.NewConnection(context.Background(), conf)
+	if err != nil {
+		return nil, err
+	}
+
+	awaitCtx, done := context.WithTimeout(ctx, b.connectTimeout)
+	defer done()
+	if err := cm.AwaitConnection(awaitCtx); err != nil {
+		disconnectCtx, dDone := context.WithTimeout(context.Background(), time.Second)
+		_ = cm.Disconnect(disconnectCtx)
+		dDone()
+
+		errMut.Lock()
+		defer errMut.Unlock()
+		if connErr != nil {
+			return nil, connErr
+		}
+		return nil, err
+	}
+	return cm, nil
+}
+
+func disconnectV5(cm *autopaho.ConnectionManager) {
+	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+	_ = cm.Disconnect(ctx)
+}
+
+// pahoLogger adapts a service logger to the logger interface of paho.
+type pahoLogger struct {
+	log *service.Logger
+}
+
+func (l *pahoLogger) Println(v ...any) {
+	l.log.Error(fmt.Sprint(v...))
+}
+
+func (l *pahoLogger) Printf(format string, v ...any) {
+	l.log.Errorf(format, v...)
+}
+
+func isConnectionDown(err error) bool {
+	return errors.Is(err, autopaho.ConnectionDownError)
+}
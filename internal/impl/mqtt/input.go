@@ -16,6 +16,8 @@ package mqtt
 
 import (
 	"context"
+	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -25,9 +27,11 @@ import (
 )
 
 const (
-	miFieldTopics       = "topics"
-	miFieldQoS          = "qos"
-	miFieldCleanSession = "clean_session"
+	miFieldTopics        = "topics"
+	miFieldQoS           = "qos"
+	miFieldCleanSession  = "clean_session"
+	miFieldShareGroup    = "shared_subscription_group"
+	miFieldSessionExpiry = "session_expiry_interval"
 )
 
 func inputConfigSpec() *service.ConfigSpec {
@@ -46,6 +50,12 @@ This input adds the following metadata fields to each message:
 - mqtt_topic
 - mqtt_message_id
 
+When connecting with MQTT 5 the following metadata fields are also added when the message has them set, and the user properties of the message are added as metadata fields of the same name:
+
+- mqtt_message_expiry_interval
+- mqtt_content_type
+- mqtt_response_topic
+
 You can access these metadata fields using xref:configuration:interpolation.adoc#bloblang-queries[function interpolation].`).
 		Fields(clientFields()...).
 		Fields(
@@ -56,16 +66,28 @@ You can access these metadata fields using xref:configuration:interpolation.adoc
 				Advanced().
 				Default(1),
 			service.NewBoolField(miFieldCleanSession).
-				Description("Set whether the connection is non-persistent.").
+				Description("Set whether the connection is non-persistent. When connecting with MQTT 5 this sets the clean start flag of the first connection only, so that the session is resumed after reconnecting.").
 				Default(true).
 				Advanced(),
+			service.NewStringField(miFieldShareGroup).
+				Description("An optional shared subscription group. When set each topic is subscribed to as `$share/<group>/<topic>`, which distributes messages across all clients subscribing with the same group rather than each client receiving a copy of every message. This allows consumers to be scaled horizontally, and requires a broker that supports shared subscriptions.").
+				Example("connect_consumers").
+				Optional().
+				Advanced().
+				Version("4.45.0"),
+			service.NewDurationField(miFieldSessionExpiry).
+				Description("The period of time that the broker retains the session of the client after it disconnects, including its subscriptions and messages that have not been acknowledged, which are delivered once the client reconnects. Requires the `"+msFieldClientProtocolVersion+"` to be `"+protocolVersion5+"` and `"+miFieldCleanSession+"` to be `false` in order to resume a session between runs.").
+				Example("5m").
+				Optional().
+				Advanced().
+				Version("4.45.0"),
 			service.NewAutoRetryNacksToggleField(),
 		)
 }
 
 func init() {
 	err := service.RegisterInput("mqtt", inputConfigSpec(), func(conf *service.ParsedConfig, mgr *service.Resources) (service.Input, error) {
-		rdr, err := newMQTTReaderFromParsed(conf, mgr)
+		rdr, err := newReaderFromParsed(conf, mgr)
 		if err != nil {
 			return nil, err
 		}
@@ -76,11 +98,55 @@ func init() {
 	}
 }
 
-type mqttReader struct {
+func newReaderFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (service.Input, error) {
+	version, err := conf.FieldString(msFieldClientProtocolVersion)
+	if err != nil {
+		return nil, err
+	}
+	if version == protocolVersion5 {
+		return newMQTT5ReaderFromParsed(conf, mgr)
+	}
+	return newMQTTReaderFromParsed(conf, mgr)
+}
+
+// readerOpts are the options common to the readers of each protocol version.
+type readerOpts struct {
 	clientBuilder clientOptsBuilder
 	topics        []string
 	qos           uint8
 	cleanSession  bool
+	shareGroup    string
+}
+
+func readerOptsFromParsed(conf *service.ParsedConfig) (opts readerOpts, err error) {
+	if opts.clientBuilder, err = clientOptsFromParsed(conf); err != nil {
+		return
+	}
+	if opts.topics, err = conf.FieldStringList(miFieldTopics); err != nil {
+		return
+	}
+	var tmpQoS int
+	if tmpQoS, err = conf.FieldInt(miFieldQoS); err != nil {
+		return
+	}
+	opts.qos = uint8(tmpQoS)
+	if opts.cleanSession, err = conf.FieldBool(miFieldCleanSession); err != nil {
+		return
+	}
+	if conf.Contains(miFieldShareGroup) {
+		if opts.shareGroup, err = conf.FieldString(miFieldShareGroup); err != nil {
+			return
+		}
+		if strings.ContainsAny(opts.shareGroup, "/+#") {
+			err = fmt.Errorf("shared subscription group '%v' must not contain the characters '/', '+' or '#'", opts.shareGroup)
+			return
+		}
+	}
+	return
+}
+
+type mqttReader struct {
+	readerOpts
 
 	client  mqtt.Client
 	msgChan chan mqtt.Message
@@ -98,25 +164,24 @@ func newMQTTReaderFromParsed(conf *service.ParsedConfig, mgr *service.Resources)
 	}
 
 	var err error
-	if m.clientBuilder, err = clientOptsFromParsed(conf); err != nil {
-		return nil, err
-	}
-
-	if m.topics, err = conf.FieldStringList(miFieldTopics); err != nil {
-		return nil, err
-	}
-	var tmpQoS int
-	if tmpQoS, err = conf.FieldInt(miFieldQoS); err != nil {
+	if m.readerOpts, err = readerOptsFromParsed(conf); err != nil {
 		return nil, err
 	}
-	m.qos = uint8(tmpQoS)
-	if m.cleanSession, err = conf.FieldBool(miFieldCleanSession); err != nil {
+	if err = m.clientBuilder.requireProtocolV5(conf, miFieldSessionExpiry); err != nil {
 		return nil, err
 	}
-
 	return m, nil
 }
 
+// subscriptionTopic returns the topic filter to subscribe with, which is
+// prefixed with the shared subscription group when one is configured.
+func subscriptionTopic(shareGroup, topic string) string {
+	if shareGroup == "" {
+		return topic
+	}
+	return "$share/" + shareGroup + "/" + topic
+}
+
 func (m *mqttReader) Connect(ctx context.Context) error {
 	m.cMut.Lock()
 	defer m.cMut.Unlock()
@@ -149,7 +214,7 @@ func (m *mqttReader) Connect(ctx context.Context) error {
 		SetOnConnectHandler(func(c mqtt.Client) {
 			topics := make(map[string]byte)
 			for _, topic := range m.topics {
-				topics[topic] = m.qos
+				topics[subscriptionTopic(m.shareGroup, topic)] = m.qos
 			}
 
 			tok := c.SubscribeMultiple(topics, func(c mqtt.Client, msg mqtt.Message) {
@@ -0,0 +1,149 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mqtt
+
+import (
+	"testing"
+
+	"github.com/eclipse/paho.golang/paho"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func TestInputSharedSubscriptionConfig(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		config   string
+		topic    string
+		errContn string
+	}{
+		{
+			name: "no group",
+			config: `
+urls: [ tcp://localhost:1883 ]
+topics: [ foo ]
+`,
+			topic: "foo",
+		},
+		{
+			name: "with group",
+			config: `
+urls: [ tcp://localhost:1883 ]
+topics: [ foo/+/bar ]
+shared_subscription_group: meow
+`,
+			topic: "$share/meow/foo/+/bar",
+		},
+		{
+			name: "invalid group",
+			config: `
+urls: [ tcp://localhost:1883 ]
+topics: [ foo ]
+shared_subscription_group: me/ow
+`,
+			errContn: "must not contain",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			conf, err := inputConfigSpec().ParseYAML(test.config, nil)
+			require.NoError(t, err)
+
+			r, err := newMQTTReaderFromParsed(conf, service.MockResources())
+			if test.errContn != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.errContn)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.topic, subscriptionTopic(r.shareGroup, r.topics[0]))
+		})
+	}
+}
+
+func TestInputProtocolVersionConfig(t *testing.T) {
+	conf, err := inputConfigSpec().ParseYAML(`
+urls: [ tcp://localhost:1883 ]
+topics: [ foo ]
+session_expiry_interval: 5m
+`, nil)
+	require.NoError(t, err)
+
+	_, err = newReaderFromParsed(conf, service.MockResources())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "requires protocol_version to be 5")
+
+	conf, err = inputConfigSpec().ParseYAML(`
+urls: [ tcp://localhost:1883 ]
+topics: [ foo ]
+protocol_version: "5"
+clean_session: false
+session_expiry_interval: 5m
+shared_subscription_group: meow
+`, nil)
+	require.NoError(t, err)
+
+	rdr, err := newReaderFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+
+	r, ok := rdr.(*mqtt5Reader)
+	require.True(t, ok)
+	assert.Equal(t, "$share/meow/foo", subscriptionTopic(r.shareGroup, r.topics[0]))
+	assert.Equal(t, 300.0, r.sessionExpiry.Seconds())
+	assert.False(t, r.cleanSession)
+}
+
+func TestInputMessageFromPublish(t *testing.T) {
+	expiry := uint32(60)
+	p := &paho.Publish{
+		PacketID: 12,
+		QoS:      1,
+		Retain:   true,
+		Topic:    "foo/bar",
+		Payload:  []byte("hello world"),
+		Properties: &paho.PublishProperties{
+			MessageExpiry: &expiry,
+			ContentType:   "text/plain",
+			User: paho.UserProperties{
+				{Key: "tenant", Value: "acme"},
+			},
+		},
+	}
+
+	msg := messageFromPublish(p)
+
+	b, err := msg.AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, "hello world", string(b))
+
+	for k, v := range map[string]any{
+		"mqtt_duplicate":               false,
+		"mqtt_qos":                     1,
+		"mqtt_retained":                true,
+		"mqtt_topic":                   "foo/bar",
+		"mqtt_message_id":              12,
+		"mqtt_message_expiry_interval": 60,
+		"mqtt_content_type":            "text/plain",
+		"tenant":                       "acme",
+	} {
+		actual, exists := msg.MetaGetMut(k)
+		require.True(t, exists, k)
+		assert.Equal(t, v, actual, k)
+	}
+
+	_, exists := msg.MetaGetMut("mqtt_response_topic")
+	assert.False(t, exists)
+}
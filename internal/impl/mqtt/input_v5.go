@@ -0,0 +1,205 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mqtt
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/eclipse/paho.golang/autopaho"
+	"github.com/eclipse/paho.golang/paho"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+type mqtt5Reader struct {
+	readerOpts
+	sessionExpiry time.Duration
+
+	cm      *autopaho.ConnectionManager
+	msgChan chan paho.PublishReceived
+	cMut    sync.Mutex
+
+	interruptChan chan struct{}
+
+	log *service.Logger
+}
+
+func newMQTT5ReaderFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*mqtt5Reader, error) {
+	m := &mqtt5Reader{
+		interruptChan: make(chan struct{}),
+		log:           mgr.Logger(),
+	}
+
+	var err error
+	if m.readerOpts, err = readerOptsFromParsed(conf); err != nil {
+		return nil, err
+	}
+	if conf.Contains(miFieldSessionExpiry) {
+		if m.sessionExpiry, err = conf.FieldDuration(miFieldSessionExpiry); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func (m *mqtt5Reader) Connect(ctx context.Context) error {
+	m.cMut.Lock()
+	defer m.cMut.Unlock()
+
+	if m.cm != nil {
+		return nil
+	}
+
+	var msgMut sync.Mutex
+	msgChan := make(chan paho.PublishReceived)
+
+	closeMsgChan := func() bool {
+		msgMut.Lock()
+		chanOpen := msgChan != nil
+		if chanOpen {
+			close(msgChan)
+			msgChan = nil
+		}
+		msgMut.Unlock()
+		return chanOpen
+	}
+
+	subs := make([]paho.SubscribeOptions, 0, len(m.topics))
+	for _, topic := range m.topics {
+		subs = append(subs, paho.SubscribeOptions{
+			Topic: subscriptionTopic(m.shareGroup, topic),
+			QoS:   m.qos,
+		})
+	}
+
+	conf := m.clientBuilder.autopahoConfig(m.log)
+	conf.CleanStartOnInitialConnection = m.cleanSession
+	conf.SessionExpiryInterval = uint32(m.sessionExpiry.Seconds())
+	conf.EnableManualAcknowledgment = true
+	conf.OnConnectionUp = func(cm *autopaho.ConnectionManager, ca *paho.Connack) {
+		// Subscriptions are part of the session, and are therefore retained
+		// by the broker when a session is resumed.
+		if ca.SessionPresent {
+			return
+		}
+		if _, err := cm.Subscribe(context.Background(), &paho.Subscribe{Subscriptions: subs}); err != nil {
+			m.log.Errorf("Failed to subscribe to topics '%v': %v", m.topics, err)
+			m.log.Error("Shutting connection down.")
+			closeMsgChan()
+		}
+	}
+	conf.OnPublishReceived = []func(paho.PublishReceived) (bool, error){
+		func(pr paho.PublishReceived) (bool, error) {
+			msgMut.Lock()
+			defer msgMut.Unlock()
+			if msgChan != nil {
+				select {
+				case msgChan <- pr:
+				case <-m.interruptChan:
+				}
+			}
+			return true, nil
+		},
+	}
+
+	cm, err := m.clientBuilder.connectV5(ctx, conf)
+	if err != nil {
+		return err
+	}
+
+	m.cm = cm
+	m.msgChan = msgChan
+	return nil
+}
+
+// messageFromPublish converts a received publish packet into a message, with
+// the properties of the packet added as metadata.
+func messageFromPublish(p *paho.Publish) *service.Message {
+	message := service.NewMessage(p.Payload)
+
+	message.MetaSetMut("mqtt_duplicate", p.Duplicate())
+	message.MetaSetMut("mqtt_qos", int(p.QoS))
+	message.MetaSetMut("mqtt_retained", p.Retain)
+	message.MetaSetMut("mqtt_topic", p.Topic)
+	message.MetaSetMut("mqtt_message_id", int(p.PacketID))
+
+	if props := p.Properties; props != nil {
+		if props.MessageExpiry != nil {
+			message.MetaSetMut("mqtt_message_expiry_interval", int(*props.MessageExpiry))
+		}
+		if props.ContentType != "" {
+			message.MetaSetMut("mqtt_content_type", props.ContentType)
+		}
+		if props.ResponseTopic != "" {
+			message.MetaSetMut("mqtt_response_topic", props.ResponseTopic)
+		}
+		for _, prop := range props.User {
+			message.MetaSetMut(prop.Key, prop.Value)
+		}
+	}
+	return message
+}
+
+func (m *mqtt5Reader) Read(ctx context.Context) (*service.Message, service.AckFunc, error) {
+	m.cMut.Lock()
+	msgChan := m.msgChan
+	m.cMut.Unlock()
+
+	if msgChan == nil {
+		return nil, nil, service.ErrNotConnected
+	}
+
+	select {
+	case pr, open := <-msgChan:
+		if !open {
+			m.cMut.Lock()
+			if m.cm != nil {
+				go disconnectV5(m.cm)
+			}
+			m.msgChan = nil
+			m.cm = nil
+			m.cMut.Unlock()
+			return nil, nil, service.ErrNotConnected
+		}
+
+		return messageFromPublish(pr.Packet), func(ctx context.Context, res error) error {
+			if res == nil {
+				// Acknowledgements of a connection that has since been lost
+				// are dropped, and the message is redelivered by the broker
+				// when the session is resumed.
+				return pr.Client.Ack(pr.Packet)
+			}
+			return nil
+		}, nil
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	case <-m.interruptChan:
+		return nil, nil, service.ErrEndOfInput
+	}
+}
+
+func (m *mqtt5Reader) Close(ctx context.Context) (err error) {
+	m.cMut.Lock()
+	defer m.cMut.Unlock()
+
+	if m.cm != nil {
+		close(m.interruptChan)
+		disconnectV5(m.cm)
+		m.cm = nil
+	}
+	return
+}
@@ -97,6 +97,36 @@ input:
 			integration.StreamTestOptMaxInFlight(10),
 		)
 	})
+	t.Run("with protocol version 5", func(t *testing.T) {
+		t.Parallel()
+		templateV5 := `
+output:
+  mqtt:
+    urls: [ tcp://localhost:$PORT ]
+    protocol_version: "5"
+    qos: 1
+    topic: topic-$ID
+    client_id: client-output-$ID
+    max_in_flight: $MAX_IN_FLIGHT
+    user_properties: {}
+
+input:
+  mqtt:
+    urls: [ tcp://localhost:$PORT ]
+    protocol_version: "5"
+    topics: [ topic-$ID ]
+    client_id: client-input-$ID
+    clean_session: false
+    session_expiry_interval: 1m
+`
+		suite.Run(
+			t, templateV5,
+			integration.StreamTestOptSleepAfterInput(100*time.Millisecond),
+			integration.StreamTestOptSleepAfterOutput(100*time.Millisecond),
+			integration.StreamTestOptPort(resource.GetPort("1883/tcp")),
+			integration.StreamTestOptMaxInFlight(10),
+		)
+	})
 	t.Run("with generated suffix", func(t *testing.T) {
 		t.Parallel()
 		suite.Run(
@@ -32,6 +32,8 @@ const (
 	moFieldWriteTimeout         = "write_timeout"
 	moFieldRetained             = "retained"
 	moFieldRetainedInterpolated = "retained_interpolated"
+	moFieldUserProperties       = "user_properties"
+	moFieldMessageExpiry        = "message_expiry_interval"
 )
 
 func outputConfigSpec() *service.ConfigSpec {
@@ -61,6 +63,17 @@ The `+"`topic`"+` field can be dynamically set using function interpolations des
 				Advanced().
 				Optional().
 				Version("3.59.0"),
+			service.NewMetadataExcludeFilterField(moFieldUserProperties).
+				Description("Send the metadata fields of messages as user properties, excluding any that match the given criteria. Requires the `"+msFieldClientProtocolVersion+"` to be `"+protocolVersion5+"`.").
+				Optional().
+				Advanced().
+				Version("4.45.0"),
+			service.NewDurationField(moFieldMessageExpiry).
+				Description("The period of time after which the broker discards messages that have not yet been delivered to subscribers. Requires the `"+msFieldClientProtocolVersion+"` to be `"+protocolVersion5+"`.").
+				Example("1h").
+				Optional().
+				Advanced().
+				Version("4.45.0"),
 			service.NewOutputMaxInFlightField(),
 		)
 }
@@ -70,7 +83,7 @@ func init() {
 		if maxInFlight, err = conf.FieldMaxInFlight(); err != nil {
 			return
 		}
-		out, err = newMQTTWriterFromParsed(conf, mgr)
+		out, err = newWriterFromParsed(conf, mgr)
 		return
 	})
 	if err != nil {
@@ -78,9 +91,19 @@ func init() {
 	}
 }
 
-type mqttWriter struct {
-	log *service.Logger
+func newWriterFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (service.Output, error) {
+	version, err := conf.FieldString(msFieldClientProtocolVersion)
+	if err != nil {
+		return nil, err
+	}
+	if version == protocolVersion5 {
+		return newMQTT5WriterFromParsed(conf, mgr)
+	}
+	return newMQTTWriterFromParsed(conf, mgr)
+}
 
+// writerOpts are the options common to the writers of each protocol version.
+type writerOpts struct {
 	clientBuilder clientOptsBuilder
 
 	writeTimeout   time.Duration
@@ -88,6 +111,52 @@ type mqttWriter struct {
 	retained       bool
 	retainedInterp *service.InterpolatedString
 	qos            uint8
+}
+
+func writerOptsFromParsed(conf *service.ParsedConfig) (opts writerOpts, err error) {
+	if opts.clientBuilder, err = clientOptsFromParsed(conf); err != nil {
+		return
+	}
+	if opts.writeTimeout, err = conf.FieldDuration(moFieldWriteTimeout); err != nil {
+		return
+	}
+	if opts.topic, err = conf.FieldInterpolatedString(moFieldTopic); err != nil {
+		return
+	}
+	if opts.retained, err = conf.FieldBool(moFieldRetained); err != nil {
+		return
+	}
+	if iStrp, _ := conf.FieldString(moFieldRetainedInterpolated); iStrp != "" {
+		if opts.retainedInterp, err = conf.FieldInterpolatedString(moFieldRetainedInterpolated); err != nil {
+			return
+		}
+	}
+	var tmpQoS int
+	if tmpQoS, err = conf.FieldInt(moFieldQoS); err != nil {
+		return
+	}
+	opts.qos = uint8(tmpQoS)
+	return
+}
+
+// isRetained returns whether a message should be published as retained.
+func (o *writerOpts) isRetained(log *service.Logger, msg *service.Message) bool {
+	retained := o.retained
+	if o.retainedInterp != nil {
+		retainedStr, parseErr := o.retainedInterp.TryString(msg)
+		if parseErr != nil {
+			log.Errorf("Retained interpolation error: %v", parseErr)
+		} else if retained, parseErr = strconv.ParseBool(retainedStr); parseErr != nil {
+			log.Errorf("Error parsing boolean value from retained flag: %v \n", parseErr)
+		}
+	}
+	return retained
+}
+
+type mqttWriter struct {
+	log *service.Logger
+
+	writerOpts
 
 	client  mqtt.Client
 	connMut sync.RWMutex
@@ -99,29 +168,12 @@ func newMQTTWriterFromParsed(conf *service.ParsedConfig, mgr *service.Resources)
 	}
 
 	var err error
-	if m.clientBuilder, err = clientOptsFromParsed(conf); err != nil {
-		return nil, err
-	}
-
-	if m.writeTimeout, err = conf.FieldDuration(moFieldWriteTimeout); err != nil {
-		return nil, err
-	}
-	if m.topic, err = conf.FieldInterpolatedString(moFieldTopic); err != nil {
+	if m.writerOpts, err = writerOptsFromParsed(conf); err != nil {
 		return nil, err
 	}
-	if m.retained, err = conf.FieldBool(moFieldRetained); err != nil {
-		return nil, err
-	}
-	if iStrp, _ := conf.FieldString(moFieldRetainedInterpolated); iStrp != "" {
-		if m.retainedInterp, err = conf.FieldInterpolatedString(moFieldRetainedInterpolated); err != nil {
-			return nil, err
-		}
-	}
-	var tmpQoS int
-	if tmpQoS, err = conf.FieldInt(moFieldQoS); err != nil {
+	if err = m.clientBuilder.requireProtocolV5(conf, moFieldUserProperties, moFieldMessageExpiry); err != nil {
 		return nil, err
 	}
-	m.qos = uint8(tmpQoS)
 	return m, nil
 }
 
@@ -161,15 +213,7 @@ func (m *mqttWriter) Write(ctx context.Context, msg *service.Message) error {
 		return service.ErrNotConnected
 	}
 
-	retained := m.retained
-	if m.retainedInterp != nil {
-		retainedStr, parseErr := m.retainedInterp.TryString(msg)
-		if parseErr != nil {
-			m.log.Errorf("Retained interpolation error: %v", parseErr)
-		} else if retained, parseErr = strconv.ParseBool(retainedStr); parseErr != nil {
-			m.log.Errorf("Error parsing boolean value from retained flag: %v \n", parseErr)
-		}
-	}
+	retained := m.isRetained(m.log, msg)
 
 	topicStr, err := m.topic.TryString(msg)
 	if err != nil {
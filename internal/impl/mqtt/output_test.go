@@ -0,0 +1,73 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mqtt
+
+import (
+	"testing"
+
+	"github.com/eclipse/paho.golang/paho"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func TestOutputProtocolVersionConfig(t *testing.T) {
+	conf, err := outputConfigSpec().ParseYAML(`
+urls: [ tcp://localhost:1883 ]
+topic: foo
+message_expiry_interval: 1h
+`, nil)
+	require.NoError(t, err)
+
+	_, err = newWriterFromParsed(conf, service.MockResources())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "requires protocol_version to be 5")
+}
+
+func TestOutputPublishFromMessage(t *testing.T) {
+	conf, err := outputConfigSpec().ParseYAML(`
+urls: [ tcp://localhost:1883 ]
+protocol_version: "5"
+topic: 'foo/${! meta("tenant") }'
+qos: 2
+retained_interpolated: '${! meta("retain") }'
+message_expiry_interval: 1h
+user_properties:
+  exclude_prefixes: [ retain ]
+`, nil)
+	require.NoError(t, err)
+
+	out, err := newWriterFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+
+	w, ok := out.(*mqtt5Writer)
+	require.True(t, ok)
+
+	msg := service.NewMessage([]byte("hello world"))
+	msg.MetaSetMut("tenant", "acme")
+	msg.MetaSetMut("retain", "true")
+
+	p, err := w.publishFromMessage(msg)
+	require.NoError(t, err)
+
+	assert.Equal(t, "foo/acme", p.Topic)
+	assert.Equal(t, byte(2), p.QoS)
+	assert.True(t, p.Retain)
+	assert.Equal(t, "hello world", string(p.Payload))
+	require.NotNil(t, p.Properties.MessageExpiry)
+	assert.Equal(t, uint32(3600), *p.Properties.MessageExpiry)
+	assert.Equal(t, paho.UserProperties{{Key: "tenant", Value: "acme"}}, p.Properties.User)
+}
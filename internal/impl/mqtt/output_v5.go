@@ -0,0 +1,151 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mqtt
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/eclipse/paho.golang/autopaho"
+	"github.com/eclipse/paho.golang/paho"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+type mqtt5Writer struct {
+	log *service.Logger
+
+	writerOpts
+	userProps     *service.MetadataExcludeFilter
+	messageExpiry time.Duration
+
+	cm      *autopaho.ConnectionManager
+	connMut sync.RWMutex
+}
+
+func newMQTT5WriterFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*mqtt5Writer, error) {
+	m := &mqtt5Writer{
+		log: mgr.Logger(),
+	}
+
+	var err error
+	if m.writerOpts, err = writerOptsFromParsed(conf); err != nil {
+		return nil, err
+	}
+	if conf.Contains(moFieldUserProperties) {
+		if m.userProps, err = conf.FieldMetadataExcludeFilter(moFieldUserProperties); err != nil {
+			return nil, err
+		}
+	}
+	if conf.Contains(moFieldMessageExpiry) {
+		if m.messageExpiry, err = conf.FieldDuration(moFieldMessageExpiry); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func (m *mqtt5Writer) Connect(ctx context.Context) error {
+	m.connMut.Lock()
+	defer m.connMut.Unlock()
+
+	if m.cm != nil {
+		return nil
+	}
+
+	conf := m.clientBuilder.autopahoConfig(m.log)
+	conf.CleanStartOnInitialConnection = true
+
+	cm, err := m.clientBuilder.connectV5(ctx, conf)
+	if err != nil {
+		return err
+	}
+
+	m.cm = cm
+	return nil
+}
+
+// publishFromMessage converts a message into a publish packet.
+func (m *mqtt5Writer) publishFromMessage(msg *service.Message) (*paho.Publish, error) {
+	topicStr, err := m.topic.TryString(msg)
+	if err != nil {
+		return nil, fmt.Errorf("topic interpolation error: %w", err)
+	}
+
+	mBytes, err := msg.AsBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	p := &paho.Publish{
+		QoS:        m.qos,
+		Retain:     m.isRetained(m.log, msg),
+		Topic:      topicStr,
+		Payload:    mBytes,
+		Properties: &paho.PublishProperties{},
+	}
+	if m.messageExpiry > 0 {
+		expiry := uint32(m.messageExpiry.Seconds())
+		p.Properties.MessageExpiry = &expiry
+	}
+	if m.userProps != nil {
+		_ = m.userProps.WalkMut(msg, func(key string, value any) error {
+			p.Properties.User.Add(key, fmt.Sprint(value))
+			return nil
+		})
+	}
+	return p, nil
+}
+
+func (m *mqtt5Writer) Write(ctx context.Context, msg *service.Message) error {
+	m.connMut.RLock()
+	cm := m.cm
+	m.connMut.RUnlock()
+
+	if cm == nil {
+		return service.ErrNotConnected
+	}
+
+	p, err := m.publishFromMessage(msg)
+	if err != nil {
+		return err
+	}
+
+	ctx, done := context.WithTimeout(ctx, m.writeTimeout)
+	defer done()
+
+	// The connection manager reconnects by itself, and therefore a publish
+	// attempted while the connection is down is simply retried.
+	if _, err := cm.Publish(ctx, p); err != nil {
+		if isConnectionDown(err) {
+			return service.ErrNotConnected
+		}
+		return err
+	}
+	return nil
+}
+
+func (m *mqtt5Writer) Close(context.Context) error {
+	m.connMut.Lock()
+	defer m.connMut.Unlock()
+
+	if m.cm != nil {
+		disconnectV5(m.cm)
+		m.cm = nil
+	}
+	return nil
+}
@@ -16,6 +16,8 @@ package nats
 
 import (
 	"github.com/redpanda-data/benthos/v4/public/service"
+
+	"github.com/redpanda-data/connect/v4/internal/tracing"
 )
 
 const (
@@ -38,8 +40,11 @@ NATS component, so that monitoring tools between NATS and Redpanda Connect can s
 `
 }
 
-func inputTracingDocs() *service.ConfigField {
-	return service.NewExtractTracingSpanMappingField().Version(tracingVersion)
+func inputTracingDocs() []*service.ConfigField {
+	return []*service.ConfigField{
+		service.NewExtractTracingSpanMappingField().Version(tracingVersion),
+		tracing.ExtractModeField(),
+	}
 }
 func outputTracingDocs() *service.ConfigField {
 	return service.NewInjectTracingSpanMappingField().Version(tracingVersion)
@@ -23,6 +23,8 @@ import (
 	"github.com/nats-io/nats.go"
 
 	"github.com/redpanda-data/benthos/v4/public/service"
+
+	"github.com/redpanda-data/connect/v4/internal/tracing"
 )
 
 func natsInputConfig() *service.ConfigSpec {
@@ -66,7 +68,7 @@ You can access these metadata fields using xref:configuration:interpolation.adoc
 			Default(nats.DefaultSubPendingMsgsLimit).
 			LintRule(`root = if this < 0 { ["prefetch count must be greater than or equal to zero"] }`)).
 		Fields(connectionTailFields()...).
-		Field(inputTracingDocs())
+		Fields(inputTracingDocs()...)
 }
 
 func init() {
@@ -82,7 +84,7 @@ func init() {
 			if err != nil {
 				return nil, err
 			}
-			return conf.WrapInputExtractTracingSpanMapping("nats", r)
+			return tracing.WrapInput(conf, mgr, "nats", r)
 		},
 	)
 	if err != nil {
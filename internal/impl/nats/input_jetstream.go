@@ -27,6 +27,8 @@ import (
 	"github.com/Jeffail/shutdown"
 
 	"github.com/redpanda-data/benthos/v4/public/service"
+
+	"github.com/redpanda-data/connect/v4/internal/tracing"
 )
 
 func natsJetStreamInputConfig() *service.ConfigSpec {
@@ -97,7 +99,7 @@ xref:configuration:interpolation.adoc#bloblang-queries[function interpolation].
 			Advanced().
 			Default(1024)).
 		Fields(connectionTailFields()...).
-		Field(inputTracingDocs())
+		Fields(inputTracingDocs()...)
 }
 
 func init() {
@@ -108,7 +110,7 @@ func init() {
 			if err != nil {
 				return nil, err
 			}
-			return conf.WrapInputExtractTracingSpanMapping("nats_jetstream", input)
+			return tracing.WrapInput(conf, mgr, "nats_jetstream", input)
 		})
 	if err != nil {
 		panic(err)
@@ -25,6 +25,8 @@ import (
 	"github.com/nats-io/stan.go"
 
 	"github.com/redpanda-data/benthos/v4/public/service"
+
+	"github.com/redpanda-data/connect/v4/internal/tracing"
 )
 
 const (
@@ -149,7 +151,7 @@ You can access these metadata fields using xref:configuration:interpolation.adoc
 				Default("30s"),
 		).
 		Fields(connectionTailFields()...).
-		Field(inputTracingDocs())
+		Fields(inputTracingDocs()...)
 }
 
 func init() {
@@ -164,7 +166,7 @@ func init() {
 			if err != nil {
 				return nil, err
 			}
-			return conf.WrapInputExtractTracingSpanMapping("nats_stream", input)
+			return tracing.WrapInput(conf, mgr, "nats_stream", input)
 		})
 	if err != nil {
 		panic(err)
@@ -24,6 +24,7 @@ import (
 
 	"github.com/dustin/go-humanize"
 	"github.com/ollama/ollama/api"
+	"github.com/ollama/ollama/envconfig"
 	"github.com/redpanda-data/benthos/v4/public/service"
 
 	"github.com/redpanda-data/connect/v4/internal/singleton"
@@ -34,6 +35,9 @@ const (
 	bopFieldModel          = "model"
 	bopFieldCacheDirectory = "cache_directory"
 	bopFieldDownloadURL    = "download_url"
+	bopFieldKeepAlive      = "keep_alive"
+	bopFieldPreload        = "preload"
+	bopFieldMaxIdleConns   = "max_idle_connections"
 
 	bopFieldRunner = "runner"
 	// Runner fields
@@ -86,9 +90,32 @@ func commonFields() []*service.ConfigField {
 			Description("If `" + bopFieldServerAddress + "` is not set - the URL to download the ollama binary from. Defaults to the offical Ollama GitHub release for this platform.").
 			Advanced().
 			Optional(),
+		service.NewDurationField(bopFieldKeepAlive).
+			Description("How long the model stays loaded in memory following a request. Set to a negative duration such as `-1s` in order to keep the model loaded indefinitely, or to `0s` in order to unload the model immediately after each request. By default the server decides, which is five minutes unless configured otherwise.").
+			Examples("30m", "-1s").
+			Version("4.45.0").
+			Optional(),
+		service.NewBoolField(bopFieldPreload).
+			Description("Load the model into memory when the processor starts, rather than with the first message processed, in order to avoid a latency spike on the first message.").
+			Default(true).
+			Version("4.45.0").
+			Advanced(),
+		service.NewIntField(bopFieldMaxIdleConns).
+			Description("The maximum number of idle connections to the Ollama server kept open for reuse by subsequent requests.").
+			Default(16).
+			Version("4.45.0").
+			Advanced(),
 	}
 }
 
+// modelKind determines the request used in order to load a model.
+type modelKind int
+
+const (
+	modelKindChat modelKind = iota
+	modelKindEmbeddings
+)
+
 func extractOptions(conf *service.ParsedConfig) (map[string]any, error) {
 	opts := api.Options{}
 	if conf.Contains(ocpFieldMaxTokens) {
@@ -245,11 +272,13 @@ func (co *commandOutput) Write(b []byte) (int, error) {
 }
 
 type baseOllamaProcessor struct {
-	model  string
-	opts   map[string]any
-	ticket singleton.Ticket
-	client *api.Client
-	logger *service.Logger
+	model     string
+	kind      modelKind
+	opts      map[string]any
+	keepAlive *api.Duration
+	ticket    singleton.Ticket
+	client    *api.Client
+	logger    *service.Logger
 }
 
 type key int
@@ -265,8 +294,8 @@ type runOllamaConfig struct {
 	downloadURL string
 }
 
-func newBaseProcessor(conf *service.ParsedConfig, mgr *service.Resources) (p *baseOllamaProcessor, err error) {
-	p = &baseOllamaProcessor{}
+func newBaseProcessor(conf *service.ParsedConfig, mgr *service.Resources, kind modelKind) (p *baseOllamaProcessor, err error) {
+	p = &baseOllamaProcessor{kind: kind}
 	p.logger = mgr.Logger()
 	p.model, err = conf.FieldString(bopFieldModel)
 	if err != nil {
@@ -276,6 +305,25 @@ func newBaseProcessor(conf *service.ParsedConfig, mgr *service.Resources) (p *ba
 	if err != nil {
 		return
 	}
+	if conf.Contains(bopFieldKeepAlive) {
+		var d time.Duration
+		d, err = conf.FieldDuration(bopFieldKeepAlive)
+		if err != nil {
+			return
+		}
+		p.keepAlive = &api.Duration{Duration: d}
+	}
+	var preload bool
+	preload, err = conf.FieldBool(bopFieldPreload)
+	if err != nil {
+		return
+	}
+	var maxIdleConns int
+	maxIdleConns, err = conf.FieldInt(bopFieldMaxIdleConns)
+	if err != nil {
+		return
+	}
+	httpClient := newPooledHTTPClient(maxIdleConns)
 	if conf.Contains(bopFieldServerAddress) {
 		var a string
 		a, err = conf.FieldString(bopFieldServerAddress)
@@ -287,7 +335,7 @@ func newBaseProcessor(conf *service.ParsedConfig, mgr *service.Resources) (p *ba
 		if err != nil {
 			return
 		}
-		p.client = api.NewClient(u, http.DefaultClient)
+		p.client = api.NewClient(u, httpClient)
 	} else {
 		var cacheDir string
 		if conf.Contains(bopFieldCacheDirectory) {
@@ -329,10 +377,7 @@ func newBaseProcessor(conf *service.ParsedConfig, mgr *service.Resources) (p *ba
 				_ = p.Close(context.Background())
 			}
 		}()
-		p.client, err = api.ClientFromEnvironment()
-		if err != nil {
-			return
-		}
+		p.client = api.NewClient(envconfig.Host(), httpClient)
 	}
 	if err = p.waitForServer(context.Background()); err != nil {
 		return
@@ -342,9 +387,28 @@ func newBaseProcessor(conf *service.ParsedConfig, mgr *service.Resources) (p *ba
 		return
 	}
 	p.logger.Infof("Finished pulling %q", p.model)
+	if preload {
+		p.logger.Infof("Loading %q", p.model)
+		if err = p.loadModel(context.Background()); err != nil {
+			err = fmt.Errorf("failed to load model %q: %w", p.model, err)
+			return
+		}
+		p.logger.Infof("Finished loading %q", p.model)
+	}
 	return
 }
 
+// newPooledHTTPClient returns a client that keeps idle connections to the
+// server open for reuse, the default transport only keeps two connections for
+// each host which results in new connections for most requests when messages
+// are processed in parallel.
+func newPooledHTTPClient(maxIdleConns int) *http.Client {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.MaxIdleConns = maxIdleConns
+	t.MaxIdleConnsPerHost = maxIdleConns
+	return &http.Client{Transport: t}
+}
+
 func (o *baseOllamaProcessor) waitForServer(ctx context.Context) error {
 	timeout := time.After(5 * time.Second)
 	tick := time.NewTicker(500 * time.Millisecond)
@@ -370,6 +434,28 @@ func (o *baseOllamaProcessor) pullModel(ctx context.Context) error {
 	})
 }
 
+// loadModel loads the model into memory with a request that does not contain
+// any input.
+func (o *baseOllamaProcessor) loadModel(ctx context.Context) error {
+	if o.kind == modelKindEmbeddings {
+		_, err := o.client.Embeddings(ctx, &api.EmbeddingRequest{
+			Model:     o.model,
+			KeepAlive: o.keepAlive,
+			Options:   o.opts,
+		})
+		return err
+	}
+	shouldStream := false
+	return o.client.Chat(ctx, &api.ChatRequest{
+		Model:     o.model,
+		Stream:    &shouldStream,
+		KeepAlive: o.keepAlive,
+		Options:   o.opts,
+	}, func(resp api.ChatResponse) error {
+		return nil
+	})
+}
+
 func (o *baseOllamaProcessor) Close(ctx context.Context) error {
 	if ollamaProcess == nil {
 		return nil
@@ -0,0 +1,141 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed as a Redpanda Enterprise file under the Redpanda Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+// https://github.com/redpanda-data/connect/blob/main/licenses/rcl.md
+
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/connect/v4/internal/license"
+)
+
+type fakeOllamaServer struct {
+	mut      sync.Mutex
+	requests []map[string]any
+}
+
+func (f *fakeOllamaServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/" {
+		return
+	}
+	var req map[string]any
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req["path"] = r.URL.Path
+
+	f.mut.Lock()
+	f.requests = append(f.requests, req)
+	f.mut.Unlock()
+
+	switch r.URL.Path {
+	case "/api/pull":
+		_, _ = w.Write([]byte(`{"status":"success"}`))
+	case "/api/embeddings":
+		if req["prompt"] == "" {
+			_, _ = w.Write([]byte(`{"embedding":[]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"embedding":[0.1,0.2]}`))
+	case "/api/chat":
+		_, _ = w.Write([]byte(`{"message":{"role":"assistant","content":"hello"},"done":true}`))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (f *fakeOllamaServer) paths() []string {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	var paths []string
+	for _, r := range f.requests {
+		paths = append(paths, r["path"].(string))
+	}
+	return paths
+}
+
+func TestOllamaPreloadKeepAlive(t *testing.T) {
+	fake := &fakeOllamaServer{}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	conf, err := ollamaEmbeddingProcessorConfig().ParseYAML(`
+model: all-minilm
+server_address: `+srv.URL+`
+keep_alive: -1s
+`, nil)
+	require.NoError(t, err)
+
+	mgr := service.MockResources()
+	license.InjectTestService(mgr)
+
+	proc, err := makeOllamaEmbeddingProcessor(conf, mgr)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = proc.Close(context.Background())
+	})
+
+	// The model is loaded before any messages are processed.
+	assert.Equal(t, []string{"/api/pull", "/api/embeddings"}, fake.paths())
+	assert.Equal(t, "", fake.requests[1]["prompt"])
+	assert.Equal(t, float64(-1), fake.requests[1]["keep_alive"])
+
+	batch, err := proc.Process(context.Background(), service.NewMessage([]byte("hello world")))
+	require.NoError(t, err)
+	require.Len(t, batch, 1)
+
+	v, err := batch[0].AsStructured()
+	require.NoError(t, err)
+	assert.Equal(t, []any{0.1, 0.2}, v)
+	assert.Equal(t, float64(-1), fake.requests[2]["keep_alive"])
+}
+
+func TestOllamaNoPreload(t *testing.T) {
+	fake := &fakeOllamaServer{}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	conf, err := ollamaChatProcessorConfig().ParseYAML(`
+model: tinyllama
+server_address: `+srv.URL+`
+keep_alive: 30m
+preload: false
+`, nil)
+	require.NoError(t, err)
+
+	mgr := service.MockResources()
+	license.InjectTestService(mgr)
+
+	proc, err := makeOllamaCompletionProcessor(conf, mgr)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = proc.Close(context.Background())
+	})
+	assert.Equal(t, []string{"/api/pull"}, fake.paths())
+
+	batch, err := proc.Process(context.Background(), service.NewMessage([]byte("hi")))
+	require.NoError(t, err)
+	require.Len(t, batch, 1)
+
+	b, err := batch[0].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(b))
+	assert.Equal(t, []string{"/api/pull", "/api/chat"}, fake.paths())
+	assert.Equal(t, "30m0s", fake.requests[1]["keep_alive"])
+}
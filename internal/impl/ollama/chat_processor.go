@@ -194,7 +194,7 @@ func makeOllamaCompletionProcessor(conf *service.ParsedConfig, mgr *service.Reso
 	if err != nil {
 		return nil, err
 	}
-	b, err := newBaseProcessor(conf, mgr)
+	b, err := newBaseProcessor(conf, mgr, modelKindChat)
 	if err != nil {
 		return nil, err
 	}
@@ -269,6 +269,7 @@ func (o *ollamaCompletionProcessor) generateCompletion(ctx context.Context, syst
 	var req api.ChatRequest
 	req.Model = o.model
 	req.Options = o.opts
+	req.KeepAlive = o.keepAlive
 	req.Format = o.format
 	if systemPrompt != "" {
 		req.Messages = append(req.Messages, api.Message{
@@ -112,7 +112,7 @@ func makeOllamaEmbeddingProcessor(conf *service.ParsedConfig, mgr *service.Resou
 		}
 		p.text = pf
 	}
-	b, err := newBaseProcessor(conf, mgr)
+	b, err := newBaseProcessor(conf, mgr, modelKindEmbeddings)
 	if err != nil {
 		return nil, err
 	}
@@ -163,6 +163,7 @@ func (o *ollamaEmbeddingProcessor) generateEmbedding(ctx context.Context, text s
 	req.Model = o.model
 	req.Prompt = text
 	req.Options = o.opts
+	req.KeepAlive = o.keepAlive
 	resp, err := o.client.Embeddings(ctx, &req)
 	if err != nil {
 		return nil, err
@@ -93,7 +93,7 @@ func makeOllamaModerationProcessor(conf *service.ParsedConfig, mgr *service.Reso
 	if err != nil {
 		return nil, err
 	}
-	b, err := newBaseProcessor(conf, mgr)
+	b, err := newBaseProcessor(conf, mgr, modelKindChat)
 	if err != nil {
 		return nil, err
 	}
@@ -146,6 +146,7 @@ func (o *ollamaModerationProcessor) generateCompletion(ctx context.Context, prom
 	var req api.ChatRequest
 	req.Model = o.model
 	req.Options = o.opts
+	req.KeepAlive = o.keepAlive
 	req.Messages = append(req.Messages, api.Message{
 		Role:    "user",
 		Content: prompt,
@@ -11,7 +11,10 @@ package openai
 import (
 	"context"
 	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"math"
 	"net/http"
 	"slices"
@@ -21,6 +24,8 @@ import (
 	"github.com/redpanda-data/benthos/v4/public/bloblang"
 	"github.com/redpanda-data/benthos/v4/public/service"
 	oai "github.com/sashabaranov/go-openai"
+	"github.com/sashabaranov/go-openai/jsonschema"
+	"github.com/xeipuuv/gojsonschema"
 
 	"github.com/redpanda-data/connect/v4/internal/impl/confluent/sr"
 	"github.com/redpanda-data/connect/v4/internal/license"
@@ -51,6 +56,20 @@ const (
 	ocpFieldSchemaRegistryNamePrefix      = "name_prefix"
 	ocpFieldSchemaRegistryURL             = "url"
 	ocpFieldSchemaRegistryTLS             = "tls"
+	// Tool fields
+	ocpFieldTools                = "tools"
+	ocpFieldToolName             = "name"
+	ocpFieldToolDesc             = "description"
+	ocpFieldToolParams           = "parameters"
+	ocpFieldToolParamsRequired   = "required"
+	ocpFieldToolParamsProperties = "properties"
+	ocpFieldToolParamsPropType   = "type"
+	ocpFieldToolParamsPropDesc   = "description"
+	ocpFieldToolParamsPropEnum   = "enum"
+	ocpFieldToolProcessors       = "processors"
+	ocpFieldMaxToolCalls         = "max_tool_calls"
+	ocpFieldStream               = "stream"
+	ocpFieldValidateResponse     = "validate_response"
 )
 
 func init() {
@@ -136,6 +155,10 @@ We generally recommend altering this or top_p but not both.`).
 				Description("The schema registry to dynamically load schemas from when responding in `json_schema` format. Schemas themselves must be in JSON format. To learn more about what JSON schema is supported see the https://platform.openai.com/docs/guides/structured-outputs/supported-schemas[OpenAI documentation^].").
 				Optional().
 				Advanced(),
+			service.NewBoolField(ocpFieldValidateResponse).
+				Description("Whether to check that responses in `json` format are valid JSON, and that responses in `json_schema` format conform to the schema, failing the message when they do not.").
+				Version("4.45.0").
+				Default(false),
 			service.NewFloatField(ocpFieldTopP).
 				Optional().
 				Advanced().
@@ -161,10 +184,42 @@ We generally recommend altering this or temperature but not both.`).
 				Optional().
 				Advanced().
 				Description("Up to 4 sequences where the API will stop generating further tokens."),
+			service.NewBoolField(ocpFieldStream).
+				Description("Whether to receive the response as a stream of tokens, in which case the message is replaced with a batch of messages, one for each chunk of content received in order. The metadata field `openai_chunk_index` is set to the index of each chunk. Streaming cannot be combined with `tools`.").
+				Version("4.45.0").
+				Default(false).
+				Advanced(),
+			service.NewObjectListField(ocpFieldTools,
+				service.NewStringField(ocpFieldToolName).Description("The name of the tool."),
+				service.NewStringField(ocpFieldToolDesc).Description("A description of what the tool does, which the model uses to decide when and how to call it."),
+				service.NewObjectField(ocpFieldToolParams,
+					service.NewStringListField(ocpFieldToolParamsRequired).
+						Description("The names of the properties that must be included in calls.").
+						Default([]any{}),
+					service.NewObjectMapField(ocpFieldToolParamsProperties,
+						service.NewStringField(ocpFieldToolParamsPropType).Description("The JSON type of the property."),
+						service.NewStringField(ocpFieldToolParamsPropDesc).Description("A description of the property for the model."),
+						service.NewStringListField(ocpFieldToolParamsPropEnum).
+							Description("The possible values of the property, when restricted to a set of values.").
+							Default([]any{}),
+					).Description("The properties of the arguments of the tool, keyed by name."),
+				).Description("The arguments of the tool."),
+				service.NewProcessorListField(ocpFieldToolProcessors).
+					Description("The processors executed when the model calls the tool, with a copy of the message where the contents are replaced with the JSON arguments of the call. The contents of the resulting messages are sent back to the model as the result of the call."),
+			).
+				Description("Tools that the model may call while generating the response. Calls are executed with the processors of each tool and their results sent back to the model, until the model responds without calling a tool.").
+				Version("4.45.0").
+				Default([]any{}),
+			service.NewIntField(ocpFieldMaxToolCalls).
+				Description("The maximum number of tool calls while generating a single response, after which the message fails.").
+				Version("4.45.0").
+				Default(10).
+				Advanced(),
 		).LintRule(`
       root = match {
         this.exists("`+ocpFieldJSONSchema+`") && this.exists("`+ocpFieldSchemaRegistry+`") => ["cannot set both `+"`"+ocpFieldJSONSchema+"`"+` and `+"`"+ocpFieldSchemaRegistry+"`"+`"]
         this.response_format == "json_schema" && !this.exists("`+ocpFieldJSONSchema+`") && !this.exists("`+ocpFieldSchemaRegistry+`") => ["schema must be specified using either `+"`"+ocpFieldJSONSchema+"`"+` or `+"`"+ocpFieldSchemaRegistry+"`"+`"]
+        this.`+ocpFieldStream+`.or(false) && this.`+ocpFieldTools+`.or([]).length() > 0 => ["cannot combine `+"`"+ocpFieldStream+"`"+` with `+"`"+ocpFieldTools+"`"+`"]
       }
     `).
 		Example(
@@ -188,6 +243,30 @@ pipeline:
 output:
   stdout:
     codec: lines
+`).
+		Example(
+			"Call tools while generating a response",
+			"This example gives the model a tool for looking up the weather of a city, which is implemented with an HTTP request.",
+			`
+pipeline:
+  processors:
+    - openai_chat_completion:
+        model: gpt-4o
+        api_key: TODO
+        prompt: "${! content() }"
+        tools:
+          - name: get_weather
+            description: Get the current weather of a city.
+            parameters:
+              required: [ city ]
+              properties:
+                city:
+                  type: string
+                  description: The name of the city.
+            processors:
+              - http:
+                  verb: GET
+                  url: 'https://wttr.in/${! this.city }?format=j1'
 `)
 }
 
@@ -318,6 +397,25 @@ func makeChatProcessor(conf *service.ParsedConfig, mgr *service.Resources) (serv
 	default:
 		return nil, fmt.Errorf("unknown %s: %q", ocpFieldResponseFormat, v)
 	}
+	validateResponse, err := conf.FieldBool(ocpFieldValidateResponse)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := conf.FieldBool(ocpFieldStream)
+	if err != nil {
+		return nil, err
+	}
+	tools, err := newChatTools(conf)
+	if err != nil {
+		return nil, err
+	}
+	if stream && len(tools) > 0 {
+		return nil, fmt.Errorf("cannot combine %s with %s", ocpFieldStream, ocpFieldTools)
+	}
+	maxToolCalls, err := conf.FieldInt(ocpFieldMaxToolCalls)
+	if err != nil {
+		return nil, err
+	}
 	return &chatProcessor{
 		b,
 		up,
@@ -333,9 +431,94 @@ func makeChatProcessor(conf *service.ParsedConfig, mgr *service.Resources) (serv
 		stop,
 		responseFormat,
 		schemaProvider,
+		validateResponse,
+		stream,
+		tools,
+		maxToolCalls,
 	}, nil
 }
 
+type chatTool struct {
+	definition oai.Tool
+	processors []*service.OwnedProcessor
+}
+
+func newChatTools(conf *service.ParsedConfig) ([]*chatTool, error) {
+	toolConfs, err := conf.FieldObjectList(ocpFieldTools)
+	if err != nil {
+		return nil, err
+	}
+	var tools []*chatTool
+	names := map[string]struct{}{}
+	for _, tc := range toolConfs {
+		name, err := tc.FieldString(ocpFieldToolName)
+		if err != nil {
+			return nil, err
+		}
+		if _, exists := names[name]; exists {
+			return nil, fmt.Errorf("tool %q is defined more than once", name)
+		}
+		names[name] = struct{}{}
+		desc, err := tc.FieldString(ocpFieldToolDesc)
+		if err != nil {
+			return nil, err
+		}
+		required, err := tc.FieldStringList(ocpFieldToolParams, ocpFieldToolParamsRequired)
+		if err != nil {
+			return nil, err
+		}
+		propConfs, err := tc.FieldObjectMap(ocpFieldToolParams, ocpFieldToolParamsProperties)
+		if err != nil {
+			return nil, err
+		}
+		props := map[string]jsonschema.Definition{}
+		for propName, pc := range propConfs {
+			t, err := pc.FieldString(ocpFieldToolParamsPropType)
+			if err != nil {
+				return nil, err
+			}
+			d, err := pc.FieldString(ocpFieldToolParamsPropDesc)
+			if err != nil {
+				return nil, err
+			}
+			enum, err := pc.FieldStringList(ocpFieldToolParamsPropEnum)
+			if err != nil {
+				return nil, err
+			}
+			props[propName] = jsonschema.Definition{
+				Type:        jsonschema.DataType(t),
+				Description: d,
+				Enum:        enum,
+			}
+		}
+		for _, r := range required {
+			if _, exists := props[r]; !exists {
+				return nil, fmt.Errorf("tool %q requires property %q, which is not defined", name, r)
+			}
+		}
+		procs, err := tc.FieldProcessorList(ocpFieldToolProcessors)
+		if err != nil {
+			return nil, err
+		}
+		tools = append(tools, &chatTool{
+			definition: oai.Tool{
+				Type: oai.ToolTypeFunction,
+				Function: &oai.FunctionDefinition{
+					Name:        name,
+					Description: desc,
+					Parameters: jsonschema.Definition{
+						Type:       jsonschema.Object,
+						Properties: props,
+						Required:   required,
+					},
+				},
+			},
+			processors: procs,
+		})
+	}
+	return tools, nil
+}
+
 func newFixedSchemaProvider(conf *service.ParsedConfig) (jsonSchemaProvider, error) {
 	name, err := conf.FieldString(ocpFieldJSONSchemaName)
 	if err != nil {
@@ -406,6 +589,10 @@ type chatProcessor struct {
 	stop             []string
 	responseFormat   oai.ChatCompletionResponseFormatType
 	schemaProvider   jsonSchemaProvider
+	validateResponse bool
+	stream           bool
+	tools            []*chatTool
+	maxToolCalls     int
 }
 
 func (p *chatProcessor) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
@@ -427,16 +614,20 @@ func (p *chatProcessor) Process(ctx context.Context, msg *service.Message) (serv
 	if p.presencePenalty != nil {
 		body.PresencePenalty = *p.presencePenalty
 	}
+	var schema *oai.ChatCompletionResponseFormatJSONSchema
 	if p.responseFormat != oai.ChatCompletionResponseFormatTypeText {
 		body.ResponseFormat = &oai.ChatCompletionResponseFormat{Type: p.responseFormat}
 		if p.schemaProvider != nil {
-			s, err := p.schemaProvider.GetJSONSchema(ctx)
-			if err != nil {
+			var err error
+			if schema, err = p.schemaProvider.GetJSONSchema(ctx); err != nil {
 				return nil, err
 			}
-			body.ResponseFormat.JSONSchema = s
+			body.ResponseFormat.JSONSchema = schema
 		}
 	}
+	for _, tool := range p.tools {
+		body.Tools = append(body.Tools, tool.definition)
+	}
 	body.Stop = p.stop
 	if p.user != nil {
 		u, err := p.user.TryString(msg)
@@ -495,14 +686,155 @@ func (p *chatProcessor) Process(ctx context.Context, msg *service.Message) (serv
 			}},
 		})
 	}
-	resp, err := p.client.CreateChatCompletion(ctx, body)
-	if err != nil {
-		return nil, err
+	if p.stream {
+		return p.processStream(ctx, msg, body, schema)
+	}
+
+	var content string
+	for toolCalls := 0; ; {
+		resp, err := p.client.CreateChatCompletion(ctx, body)
+		if err != nil {
+			return nil, err
+		}
+		if len(resp.Choices) != 1 {
+			return nil, fmt.Errorf("invalid number of choices in response: %d", len(resp.Choices))
+		}
+		respMsg := resp.Choices[0].Message
+		if len(respMsg.ToolCalls) == 0 {
+			content = respMsg.Content
+			break
+		}
+		if toolCalls += len(respMsg.ToolCalls); toolCalls > p.maxToolCalls {
+			return nil, fmt.Errorf("exceeded the maximum of %d tool calls", p.maxToolCalls)
+		}
+		body.Messages = append(body.Messages, respMsg)
+		for _, call := range respMsg.ToolCalls {
+			result, err := p.callTool(ctx, msg, call)
+			if err != nil {
+				return nil, err
+			}
+			body.Messages = append(body.Messages, oai.ChatCompletionMessage{
+				Role:       "tool",
+				Content:    result,
+				ToolCallID: call.ID,
+			})
+		}
 	}
-	if len(resp.Choices) != 1 {
-		return nil, fmt.Errorf("invalid number of choices in response: %d", len(resp.Choices))
+	if err := p.validate(content, schema); err != nil {
+		return nil, err
 	}
 	msg = msg.Copy()
-	msg.SetBytes([]byte(resp.Choices[0].Message.Content))
+	msg.SetBytes([]byte(content))
 	return service.MessageBatch{msg}, nil
 }
+
+// callTool executes the processors of a tool with the arguments of a call, and
+// returns the contents of the resulting messages.
+func (p *chatProcessor) callTool(ctx context.Context, msg *service.Message, call oai.ToolCall) (string, error) {
+	i := slices.IndexFunc(p.tools, func(t *chatTool) bool {
+		return t.definition.Function.Name == call.Function.Name
+	})
+	if i == -1 {
+		return "", fmt.Errorf("model called unknown tool %q", call.Function.Name)
+	}
+	tool := p.tools[i]
+	toolMsg := msg.Copy()
+	toolMsg.SetBytes([]byte(call.Function.Arguments))
+	batches, err := service.ExecuteProcessors(ctx, tool.processors, service.MessageBatch{toolMsg})
+	if err != nil {
+		return "", fmt.Errorf("tool %q failed: %w", call.Function.Name, err)
+	}
+	var results []string
+	for _, batch := range batches {
+		for _, m := range batch {
+			if err := m.GetError(); err != nil {
+				return "", fmt.Errorf("tool %q failed: %w", call.Function.Name, err)
+			}
+			b, err := m.AsBytes()
+			if err != nil {
+				return "", err
+			}
+			results = append(results, string(b))
+		}
+	}
+	return strings.Join(results, "\n"), nil
+}
+
+func (p *chatProcessor) processStream(ctx context.Context, msg *service.Message, body oai.ChatCompletionRequest, schema *oai.ChatCompletionResponseFormatJSONSchema) (service.MessageBatch, error) {
+	body.Stream = true
+	stream, err := p.client.CreateChatCompletionStream(ctx, body)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+
+	var chunks []string
+	for {
+		resp, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(resp.Choices) == 0 {
+			continue
+		}
+		if c := resp.Choices[0].Delta.Content; c != "" {
+			chunks = append(chunks, c)
+		}
+	}
+	if err := p.validate(strings.Join(chunks, ""), schema); err != nil {
+		return nil, err
+	}
+
+	batch := make(service.MessageBatch, 0, len(chunks))
+	for i, c := range chunks {
+		chunkMsg := msg.Copy()
+		chunkMsg.SetBytes([]byte(c))
+		chunkMsg.MetaSetMut("openai_chunk_index", i)
+		batch = append(batch, chunkMsg)
+	}
+	return batch, nil
+}
+
+// validate checks the content of a response against its response format when
+// validation is enabled.
+func (p *chatProcessor) validate(content string, schema *oai.ChatCompletionResponseFormatJSONSchema) error {
+	if !p.validateResponse || p.responseFormat == oai.ChatCompletionResponseFormatTypeText {
+		return nil
+	}
+	if !json.Valid([]byte(content)) {
+		return errors.New("response is not valid JSON")
+	}
+	if schema == nil {
+		return nil
+	}
+	schemaBytes, err := json.Marshal(schema.Schema)
+	if err != nil {
+		return err
+	}
+	result, err := gojsonschema.Validate(gojsonschema.NewBytesLoader(schemaBytes), gojsonschema.NewStringLoader(content))
+	if err != nil {
+		return fmt.Errorf("failed to validate response: %w", err)
+	}
+	if !result.Valid() {
+		errs := make([]string, 0, len(result.Errors()))
+		for _, e := range result.Errors() {
+			errs = append(errs, e.String())
+		}
+		return fmt.Errorf("response does not conform to schema %v: %v", schema.Name, strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+func (p *chatProcessor) Close(ctx context.Context) error {
+	for _, tool := range p.tools {
+		for _, proc := range tool.processors {
+			if err := proc.Close(ctx); err != nil {
+				return err
+			}
+		}
+	}
+	return p.baseProcessor.Close(ctx)
+}
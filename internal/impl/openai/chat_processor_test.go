@@ -10,6 +10,10 @@ package openai
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/go-faker/faker/v4"
@@ -17,6 +21,8 @@ import (
 	oai "github.com/sashabaranov/go-openai"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	_ "github.com/redpanda-data/benthos/v4/public/components/pure"
 )
 
 type mockChatClient struct {
@@ -66,3 +72,173 @@ func TestChatInterpolationError(t *testing.T) {
 	_, err = p.Process(context.Background(), input)
 	assert.Error(t, err)
 }
+
+type mockToolChatClient struct {
+	stubClient
+	requests []oai.ChatCompletionRequest
+}
+
+func (m *mockToolChatClient) CreateChatCompletion(ctx context.Context, body oai.ChatCompletionRequest) (resp oai.ChatCompletionResponse, err error) {
+	m.requests = append(m.requests, body)
+	last := body.Messages[len(body.Messages)-1]
+	if last.Role == "tool" {
+		resp.Choices = []oai.ChatCompletionChoice{{
+			Message: oai.ChatCompletionMessage{Role: "assistant", Content: "It is sunny in " + last.Content},
+		}}
+		return
+	}
+	resp.Choices = []oai.ChatCompletionChoice{{
+		Message: oai.ChatCompletionMessage{
+			Role: "assistant",
+			ToolCalls: []oai.ToolCall{{
+				ID:   "call_1",
+				Type: oai.ToolTypeFunction,
+				Function: oai.FunctionCall{
+					Name:      "get_weather",
+					Arguments: `{"city":"london"}`,
+				},
+			}},
+		},
+	}}
+	return
+}
+
+func TestChatTools(t *testing.T) {
+	conf, err := chatProcessorConfig().ParseYAML(`
+model: gpt-4o
+api_key: foo
+tools:
+  - name: get_weather
+    description: Get the current weather of a city.
+    parameters:
+      required: [ city ]
+      properties:
+        city:
+          type: string
+          description: The name of the city.
+    processors:
+      - mapping: 'root = this.city.uppercase()'
+`, nil)
+	require.NoError(t, err)
+
+	tools, err := newChatTools(conf)
+	require.NoError(t, err)
+	require.Len(t, tools, 1)
+
+	client := &mockToolChatClient{}
+	p := chatProcessor{
+		baseProcessor: &baseProcessor{
+			client: client,
+			model:  "gpt-4o",
+		},
+		tools:        tools,
+		maxToolCalls: 10,
+	}
+	t.Cleanup(func() {
+		_ = p.Close(context.Background())
+	})
+
+	output, err := p.Process(context.Background(), service.NewMessage([]byte("What is the weather in London?")))
+	require.NoError(t, err)
+	require.Len(t, output, 1)
+
+	b, err := output[0].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, "It is sunny in LONDON", string(b))
+
+	require.Len(t, client.requests, 2)
+	require.Len(t, client.requests[0].Tools, 1)
+	assert.Equal(t, "get_weather", client.requests[0].Tools[0].Function.Name)
+	require.Len(t, client.requests[1].Messages, 3)
+	assert.Equal(t, "call_1", client.requests[1].Messages[2].ToolCallID)
+
+	// Models that keep calling tools are cut off.
+	p.maxToolCalls = 0
+	_, err = p.Process(context.Background(), service.NewMessage([]byte("What is the weather in London?")))
+	require.Error(t, err)
+}
+
+type mockContentChatClient struct {
+	stubClient
+	content string
+}
+
+func (m *mockContentChatClient) CreateChatCompletion(ctx context.Context, body oai.ChatCompletionRequest) (resp oai.ChatCompletionResponse, err error) {
+	resp.Choices = []oai.ChatCompletionChoice{{
+		Message: oai.ChatCompletionMessage{Role: "assistant", Content: m.content},
+	}}
+	return
+}
+
+func TestChatValidateResponse(t *testing.T) {
+	schema, err := newFixedSchema("person", "", `{"type":"object","properties":{"name":{"type":"string"}},"required":["name"]}`)
+	require.NoError(t, err)
+
+	client := &mockContentChatClient{}
+	p := chatProcessor{
+		baseProcessor: &baseProcessor{
+			client: client,
+			model:  "gpt-4o",
+		},
+		responseFormat:   oai.ChatCompletionResponseFormatTypeJSONSchema,
+		schemaProvider:   schema,
+		validateResponse: true,
+	}
+
+	for content, valid := range map[string]bool{
+		`{"name":"alice"}`: true,
+		`{"age":30}`:       false,
+		`not json`:         false,
+	} {
+		client.content = content
+		_, err := p.Process(context.Background(), service.NewMessage([]byte("hello")))
+		if valid {
+			assert.NoError(t, err, content)
+		} else {
+			assert.Error(t, err, content)
+		}
+	}
+}
+
+func TestChatStream(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body oai.ChatCompletionRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.True(t, body.Stream)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, c := range []string{"Hello", " world", "!"} {
+			chunk, _ := json.Marshal(oai.ChatCompletionStreamResponse{
+				Choices: []oai.ChatCompletionStreamChoice{{
+					Delta: oai.ChatCompletionStreamChoiceDelta{Content: c},
+				}},
+			})
+			fmt.Fprintf(w, "data: %s\n\n", chunk)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	t.Cleanup(srv.Close)
+
+	cfg := oai.DefaultConfig("foo")
+	cfg.BaseURL = srv.URL
+	p := chatProcessor{
+		baseProcessor: &baseProcessor{
+			client: oai.NewClientWithConfig(cfg),
+			model:  "gpt-4o",
+		},
+		stream: true,
+	}
+
+	output, err := p.Process(context.Background(), service.NewMessage([]byte("hello")))
+	require.NoError(t, err)
+	require.Len(t, output, 3)
+	for i, exp := range []string{"Hello", " world", "!"} {
+		b, err := output[i].AsBytes()
+		require.NoError(t, err)
+		assert.Equal(t, exp, string(b))
+
+		v, ok := output[i].MetaGetMut("openai_chunk_index")
+		require.True(t, ok)
+		assert.Equal(t, i, v)
+	}
+}
@@ -17,6 +17,7 @@ import (
 // A mockable client for unit testing
 type client interface {
 	CreateChatCompletion(ctx context.Context, body oai.ChatCompletionRequest) (oai.ChatCompletionResponse, error)
+	CreateChatCompletionStream(ctx context.Context, body oai.ChatCompletionRequest) (*oai.ChatCompletionStream, error)
 	CreateEmbeddings(ctx context.Context, body oai.EmbeddingRequestConverter) (oai.EmbeddingResponse, error)
 	CreateSpeech(ctx context.Context, body oai.CreateSpeechRequest) (oai.RawResponse, error)
 	CreateTranscription(ctx context.Context, body oai.AudioRequest) (oai.AudioResponse, error)
@@ -27,6 +27,11 @@ func (*stubClient) CreateChatCompletion(ctx context.Context, body oai.ChatComple
 	return
 }
 
+func (*stubClient) CreateChatCompletionStream(ctx context.Context, body oai.ChatCompletionRequest) (r *oai.ChatCompletionStream, err error) {
+	err = errors.New("unimplemented")
+	return
+}
+
 func (*stubClient) CreateSpeech(ctx context.Context, body oai.CreateSpeechRequest) (r oai.RawResponse, err error) {
 	err = errors.New("unimplemented")
 	return
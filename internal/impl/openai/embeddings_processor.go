@@ -11,11 +11,13 @@ package openai
 import (
 	"context"
 	"fmt"
+	"sort"
 
 	"github.com/redpanda-data/benthos/v4/public/bloblang"
 	"github.com/redpanda-data/benthos/v4/public/service"
 	oai "github.com/sashabaranov/go-openai"
 
+	"github.com/redpanda-data/connect/v4/internal/embeddings"
 	"github.com/redpanda-data/connect/v4/internal/license"
 )
 
@@ -25,7 +27,7 @@ const (
 )
 
 func init() {
-	err := service.RegisterProcessor(
+	err := service.RegisterBatchProcessor(
 		"openai_embeddings",
 		embeddingProcessorConfig(),
 		makeEmbeddingsProcessor,
@@ -42,6 +44,8 @@ func embeddingProcessorConfig() *service.ConfigSpec {
 		Description(`
 This processor sends text strings to the OpenAI API, which generates vector embeddings. By default, the processor submits the entire payload of each message as a string, unless you use the `+"`"+oepFieldTextMapping+"`"+` configuration field to customize it.
 
+The texts of messages within a batch are combined into requests within the limits of the `+"`batching`"+` field, and these requests are sent concurrently. Messages that cannot be embedded are flagged as failed and can be handled with xref:configuration:error_handling.adoc[error handling patterns].
+
 To learn more about vector embeddings, see the https://platform.openai.com/docs/guides/embeddings[OpenAI API documentation^].`).
 		Version("4.32.0").
 		Fields(
@@ -59,6 +63,7 @@ To learn more about vector embeddings, see the https://platform.openai.com/docs/
 				Description("The number of dimensions the resulting output embeddings should have. Only supported in `text-embedding-3` and later models.").
 				Optional(),
 		).
+		Fields(embeddings.BatchingFields(2048, 300000)...).
 		Example(
 			"Store embedding vectors in Pinecone",
 			"Compute embeddings for some generated data and store it within xrefs:component:outputs/pinecone.adoc[Pinecone]",
@@ -81,7 +86,7 @@ output:
     vector_mapping: "root = this"`)
 }
 
-func makeEmbeddingsProcessor(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+func makeEmbeddingsProcessor(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchProcessor, error) {
 	if err := license.CheckRunningEnterprise(mgr); err != nil {
 		return nil, err
 	}
@@ -105,7 +110,11 @@ func makeEmbeddingsProcessor(conf *service.ParsedConfig, mgr *service.Resources)
 		}
 		dims = &v
 	}
-	return &embeddingsProcessor{b, t, dims}, nil
+	batcher, err := embeddings.BatcherFromParsed(conf)
+	if err != nil {
+		return nil, err
+	}
+	return &embeddingsProcessor{b, t, dims, batcher}, nil
 }
 
 type embeddingsProcessor struct {
@@ -113,44 +122,75 @@ type embeddingsProcessor struct {
 
 	text       *bloblang.Executor
 	dimensions *int
+	batcher    *embeddings.Batcher
 }
 
-func (p *embeddingsProcessor) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
-	var body oai.EmbeddingRequestStrings
-	body.Model = oai.EmbeddingModel(p.model)
-	if p.dimensions != nil {
-		body.Dimensions = *p.dimensions
-	}
-	if p.text != nil {
-		s, err := msg.BloblangQuery(p.text)
+func (p *embeddingsProcessor) ProcessBatch(ctx context.Context, batch service.MessageBatch) ([]service.MessageBatch, error) {
+	batch = batch.Copy()
+
+	var texts []string
+	var indexes []int
+	for i, msg := range batch {
+		text, err := p.computeText(batch, i)
 		if err != nil {
-			return nil, fmt.Errorf("%s execution error: %w", oepFieldTextMapping, err)
+			msg.SetError(err)
+			continue
 		}
-		r, err := s.AsBytes()
-		if err != nil {
-			return nil, fmt.Errorf("%s extraction error: %w", oepFieldTextMapping, err)
+		texts = append(texts, text)
+		indexes = append(indexes, i)
+	}
+
+	vectors, errs := embeddings.Embed(ctx, p.batcher, texts, p.embed)
+	for j, i := range indexes {
+		if errs[j] != nil {
+			batch[i].SetError(errs[j])
+			continue
+		}
+		data := make([]any, len(vectors[j]))
+		for k, f := range vectors[j] {
+			data[k] = f
 		}
-		body.Input = append(body.Input, string(r))
-	} else {
-		b, err := msg.AsBytes()
+		batch[i].SetStructuredMut(data)
+	}
+	return []service.MessageBatch{batch}, nil
+}
+
+func (p *embeddingsProcessor) computeText(batch service.MessageBatch, i int) (string, error) {
+	if p.text == nil {
+		b, err := batch[i].AsBytes()
 		if err != nil {
-			return nil, err
+			return "", err
 		}
-		body.Input = append(body.Input, string(b))
+		return string(b), nil
+	}
+	s, err := batch.BloblangQuery(i, p.text)
+	if err != nil {
+		return "", fmt.Errorf("%s execution error: %w", oepFieldTextMapping, err)
+	}
+	r, err := s.AsBytes()
+	if err != nil {
+		return "", fmt.Errorf("%s extraction error: %w", oepFieldTextMapping, err)
 	}
+	return string(r), nil
+}
+
+func (p *embeddingsProcessor) embed(ctx context.Context, texts []string) ([][]float32, error) {
+	var body oai.EmbeddingRequestStrings
+	body.Model = oai.EmbeddingModel(p.model)
+	if p.dimensions != nil {
+		body.Dimensions = *p.dimensions
+	}
+	body.Input = texts
 	resp, err := p.client.CreateEmbeddings(ctx, body)
 	if err != nil {
 		return nil, err
 	}
-	if len(resp.Data) != 1 {
-		return nil, fmt.Errorf("expected a single embeddings response, got: %d", len(resp.Data))
-	}
-	embd := resp.Data[0]
-	data := make([]any, len(embd.Embedding))
-	for i, f := range embd.Embedding {
-		data[i] = f
+	sort.Slice(resp.Data, func(i, j int) bool {
+		return resp.Data[i].Index < resp.Data[j].Index
+	})
+	vectors := make([][]float32, len(resp.Data))
+	for i, embd := range resp.Data {
+		vectors[i] = embd.Embedding
 	}
-	msg = msg.Copy()
-	msg.SetStructuredMut(data)
-	return service.MessageBatch{msg}, nil
+	return vectors, nil
 }
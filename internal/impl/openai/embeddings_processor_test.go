@@ -19,6 +19,8 @@ import (
 	oai "github.com/sashabaranov/go-openai"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/connect/v4/internal/embeddings"
 )
 
 type mockEmbeddingsClient struct {
@@ -52,14 +54,25 @@ func TestEmbedding(t *testing.T) {
 			client: &mockEmbeddingsClient{},
 			model:  "text-embedding-ada-002",
 		},
-		text: text,
+		text:    text,
+		batcher: embeddings.NewBatcher(2, 0, 2),
 	}
-	input := service.NewMessage([]byte(faker.Paragraph(options.WithGenerateUniqueValues(true))))
-	output, err := p.Process(context.Background(), input)
+	var input service.MessageBatch
+	for i := 0; i < 5; i++ {
+		input = append(input, service.NewMessage([]byte(faker.Paragraph(options.WithGenerateUniqueValues(true)))))
+	}
+	output, err := p.ProcessBatch(context.Background(), input)
 	assert.NoError(t, err)
-	assert.Len(t, output, 1)
-	msg := output[0]
-	require.NoError(t, msg.GetError())
+	require.Len(t, output, 1)
+	require.Len(t, output[0], 5)
+	for i, msg := range output[0] {
+		require.NoError(t, msg.GetError())
+		in, err := input[i].AsBytes()
+		require.NoError(t, err)
+		v, err := msg.AsStructured()
+		require.NoError(t, err)
+		assert.Len(t, v, len(in))
+	}
 }
 
 func TestEmbeddingInterpolationError(t *testing.T) {
@@ -70,9 +83,13 @@ func TestEmbeddingInterpolationError(t *testing.T) {
 			client: &mockEmbeddingsClient{},
 			model:  "text-embedding-ada-002",
 		},
-		text: text,
+		text:    text,
+		batcher: embeddings.NewBatcher(2, 0, 2),
 	}
 	input := service.NewMessage([]byte(faker.Paragraph(options.WithGenerateUniqueValues(true))))
-	_, err = p.Process(context.Background(), input)
-	assert.Error(t, err)
+	output, err := p.ProcessBatch(context.Background(), service.MessageBatch{input})
+	require.NoError(t, err)
+	require.Len(t, output, 1)
+	require.Len(t, output[0], 1)
+	assert.Error(t, output[0][0].GetError())
 }
@@ -0,0 +1,573 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otlp
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	omFieldAddress            = "address"
+	omFieldSecure             = "secure"
+	omFieldHeaders            = "headers"
+	omFieldResourceAttributes = "resource_attributes"
+	omFieldPushInterval       = "push_interval"
+	omFieldTimeout            = "timeout"
+	omFieldTemporality        = "temporality"
+	omFieldHistogramBuckets   = "histogram_buckets"
+
+	omTemporalityCumulative = "cumulative"
+	omTemporalityDelta      = "delta"
+)
+
+// The default timing histogram buckets in seconds, which match the defaults of
+// the prometheus exporter.
+var omDefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+func otlpMetricsSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Version("4.45.0").
+		Summary("Pushes metrics to an https://opentelemetry.io/docs/collector/[Open Telemetry collector^] over OTLP/gRPC.").
+		Description(`
+Counters are exported as monotonic sums, gauges as gauges and timings as explicit bucket histograms where the values are converted from nanoseconds into seconds.
+
+The temporality of sums and histograms is set with the field `+"`"+omFieldTemporality+"`"+`. Cumulative sums and histograms report totals since the exporter started, whereas delta sums and histograms report the change since the previous push, and series that have not changed since the previous push are omitted.`).
+		Fields(
+			service.NewStringField(omFieldAddress).
+				Description("The address of a collector to push metrics to.").
+				Default("localhost:4317"),
+			service.NewBoolField(omFieldSecure).
+				Description("Connect to the collector with client transport security.").
+				Default(false),
+			service.NewStringMapField(omFieldHeaders).
+				Description("A map of headers to add to each push request, which is commonly used for authentication.").
+				Default(map[string]any{}).
+				Example(map[string]any{"authorization": "Bearer ${OTLP_TOKEN}"}).
+				Advanced(),
+			service.NewStringMapField(omFieldResourceAttributes).
+				Description("A map of attributes describing the resource that metrics are pushed for. The attributes `"+string(semconv.ServiceNameKey)+"` and `"+string(semconv.ServiceVersionKey)+"` are added with default values when `"+string(semconv.ServiceNameKey)+"` is not set.").
+				Default(map[string]any{}).
+				Example(map[string]any{"service.name": "orders_pipeline", "deployment.environment": "production"}),
+			service.NewDurationField(omFieldPushInterval).
+				Description("The period of time between each push of metrics to the collector.").
+				Default("10s"),
+			service.NewDurationField(omFieldTimeout).
+				Description("The maximum period of time to wait for a push to complete.").
+				Default("10s").
+				Advanced(),
+			service.NewStringAnnotatedEnumField(omFieldTemporality, map[string]string{
+				omTemporalityCumulative: "Sums and histograms report totals since the exporter started.",
+				omTemporalityDelta:      "Sums and histograms report the change since the previous push.",
+			}).
+				Description("The aggregation temporality of sums and histograms.").
+				Default(omTemporalityCumulative),
+			service.NewFloatListField(omFieldHistogramBuckets).
+				Description("The bucket boundaries (in seconds) of timing histograms. If left empty defaults to `"+fmt.Sprintf("%v", omDefaultBuckets)+"`.").
+				Default([]any{}).
+				Advanced(),
+		).
+		Example("Delta Temporality", "Push metrics every thirty seconds with delta temporality, which is expected by some vendors.", `
+metrics:
+  otlp:
+    address: otlp.example.com:4317
+    secure: true
+    headers:
+      api-key: ${OTLP_API_KEY}
+    resource_attributes:
+      service.name: orders_pipeline
+    push_interval: 30s
+    temporality: delta
+`)
+}
+
+func init() {
+	err := service.RegisterMetricsExporter("otlp", otlpMetricsSpec(), func(conf *service.ParsedConfig, log *service.Logger) (service.MetricsExporter, error) {
+		return newOtlpMetricsFromParsed(conf, log)
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type otlpCounter struct {
+	attrs    []*commonpb.KeyValue
+	value    atomic.Int64
+	exported int64
+}
+
+func (c *otlpCounter) Incr(count int64) {
+	c.value.Add(count)
+}
+
+type otlpGauge struct {
+	attrs []*commonpb.KeyValue
+	value atomic.Int64
+}
+
+func (g *otlpGauge) Set(value int64) {
+	g.value.Store(value)
+}
+
+type otlpTimer struct {
+	attrs  []*commonpb.KeyValue
+	bounds []float64
+
+	mut      sync.Mutex
+	count    uint64
+	sum      float64
+	min, max float64
+	buckets  []uint64
+}
+
+func (t *otlpTimer) Timing(delta int64) {
+	v := float64(delta) / float64(time.Second)
+
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	if t.count == 0 || v < t.min {
+		t.min = v
+	}
+	if t.count == 0 || v > t.max {
+		t.max = v
+	}
+	t.count++
+	t.sum += v
+	t.buckets[sort.SearchFloat64s(t.bounds, v)]++
+}
+
+// snapshot returns a copy of the current state of the histogram, and resets it
+// when reset is true.
+func (t *otlpTimer) snapshot(reset bool) (count uint64, sum, minV, maxV float64, buckets []uint64) {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	count, sum, minV, maxV = t.count, t.sum, t.min, t.max
+	buckets = slices.Clone(t.buckets)
+	if reset {
+		t.count, t.sum, t.min, t.max = 0, 0, 0, 0
+		clear(t.buckets)
+	}
+	return
+}
+
+// otlpSeriesSet holds each series of a metric keyed by its label values.
+type otlpSeriesSet[T any] struct {
+	labels []string
+	series map[string]T
+}
+
+func otlpAttributes(labels, values []string) []*commonpb.KeyValue {
+	if len(labels) != len(values) {
+		return nil
+	}
+	attrs := make([]*commonpb.KeyValue, len(labels))
+	for i := range labels {
+		attrs[i] = otlpStringKeyValue(labels[i], values[i])
+	}
+	return attrs
+}
+
+func otlpStringKeyValue(k, v string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{
+		Key:   k,
+		Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v}},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type otlpMetrics struct {
+	log          *service.Logger
+	conn         *grpc.ClientConn
+	client       colmetricspb.MetricsServiceClient
+	headers      metadata.MD
+	resource     *resourcepb.Resource
+	pushInterval time.Duration
+	timeout      time.Duration
+	delta        bool
+	bounds       []float64
+	nowFn        func() time.Time
+
+	mut      sync.Mutex
+	counters map[string]*otlpSeriesSet[*otlpCounter]
+	gauges   map[string]*otlpSeriesSet[*otlpGauge]
+	timers   map[string]*otlpSeriesSet[*otlpTimer]
+
+	// Only accessed by exports, which are serialised by exportMut.
+	exportMut  sync.Mutex
+	startTime  time.Time
+	lastExport time.Time
+
+	shutSig   chan struct{}
+	closeOnce sync.Once
+	loopDone  chan struct{}
+}
+
+func newOtlpMetricsFromParsed(conf *service.ParsedConfig, log *service.Logger) (*otlpMetrics, error) {
+	o := &otlpMetrics{
+		log:      log,
+		nowFn:    time.Now,
+		counters: map[string]*otlpSeriesSet[*otlpCounter]{},
+		gauges:   map[string]*otlpSeriesSet[*otlpGauge]{},
+		timers:   map[string]*otlpSeriesSet[*otlpTimer]{},
+		shutSig:  make(chan struct{}),
+		loopDone: make(chan struct{}),
+	}
+
+	address, err := conf.FieldString(omFieldAddress)
+	if err != nil {
+		return nil, err
+	}
+	secure, err := conf.FieldBool(omFieldSecure)
+	if err != nil {
+		return nil, err
+	}
+	headers, err := conf.FieldStringMap(omFieldHeaders)
+	if err != nil {
+		return nil, err
+	}
+	o.headers = metadata.New(headers)
+
+	attrs, err := conf.FieldStringMap(omFieldResourceAttributes)
+	if err != nil {
+		return nil, err
+	}
+	o.resource = otlpResource(attrs, conf.EngineVersion())
+
+	if o.pushInterval, err = conf.FieldDuration(omFieldPushInterval); err != nil {
+		return nil, err
+	}
+	if o.pushInterval <= 0 {
+		return nil, fmt.Errorf("%v must be greater than zero", omFieldPushInterval)
+	}
+	if o.timeout, err = conf.FieldDuration(omFieldTimeout); err != nil {
+		return nil, err
+	}
+
+	temporality, err := conf.FieldString(omFieldTemporality)
+	if err != nil {
+		return nil, err
+	}
+	o.delta = temporality == omTemporalityDelta
+
+	if o.bounds, err = conf.FieldFloatList(omFieldHistogramBuckets); err != nil {
+		return nil, err
+	}
+	if len(o.bounds) == 0 {
+		o.bounds = omDefaultBuckets
+	}
+	if !slices.IsSorted(o.bounds) {
+		return nil, fmt.Errorf("%v must be in ascending order", omFieldHistogramBuckets)
+	}
+
+	creds := insecure.NewCredentials()
+	if secure {
+		creds = credentials.NewTLS(&tls.Config{})
+	}
+	if o.conn, err = grpc.NewClient(address, grpc.WithTransportCredentials(creds)); err != nil {
+		return nil, err
+	}
+	o.client = colmetricspb.NewMetricsServiceClient(o.conn)
+
+	o.startTime = o.nowFn()
+	o.lastExport = o.startTime
+	go o.loop()
+	return o, nil
+}
+
+func otlpResource(attrs map[string]string, engineVersion string) *resourcepb.Resource {
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	res := &resourcepb.Resource{}
+	for _, k := range keys {
+		res.Attributes = append(res.Attributes, otlpStringKeyValue(k, attrs[k]))
+	}
+
+	if _, ok := attrs[string(semconv.ServiceNameKey)]; !ok {
+		res.Attributes = append(res.Attributes, otlpStringKeyValue(string(semconv.ServiceNameKey), "benthos"))
+
+		// Only set the default service version attribute if the user doesn't
+		// provide a custom service name attribute.
+		if _, ok := attrs[string(semconv.ServiceVersionKey)]; !ok {
+			res.Attributes = append(res.Attributes, otlpStringKeyValue(string(semconv.ServiceVersionKey), engineVersion))
+		}
+	}
+	return res
+}
+
+func (o *otlpMetrics) loop() {
+	defer close(o.loopDone)
+
+	ticker := time.NewTicker(o.pushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-o.shutSig:
+			return
+		}
+		ctx, done := context.WithTimeout(context.Background(), o.timeout)
+		if err := o.export(ctx); err != nil {
+			o.log.Errorf("Failed to push metrics: %v", err)
+		}
+		done()
+	}
+}
+
+// export pushes the current state of all metrics to the collector.
+func (o *otlpMetrics) export(ctx context.Context) error {
+	o.exportMut.Lock()
+	defer o.exportMut.Unlock()
+
+	now := o.nowFn()
+	start := o.startTime
+	if o.delta {
+		start = o.lastExport
+	}
+
+	metrics := o.collect(uint64(start.UnixNano()), uint64(now.UnixNano()))
+	if len(metrics) == 0 {
+		o.lastExport = now
+		return nil
+	}
+
+	req := &colmetricspb.ExportMetricsServiceRequest{
+		ResourceMetrics: []*metricspb.ResourceMetrics{{
+			Resource: o.resource,
+			ScopeMetrics: []*metricspb.ScopeMetrics{{
+				Scope:   &commonpb.InstrumentationScope{Name: "github.com/redpanda-data/connect/v4"},
+				Metrics: metrics,
+			}},
+		}},
+	}
+
+	if len(o.headers) > 0 {
+		ctx = metadata.NewOutgoingContext(ctx, o.headers)
+	}
+	res, err := o.client.Export(ctx, req)
+	o.lastExport = now
+	if err != nil {
+		return err
+	}
+	if ps := res.GetPartialSuccess(); ps != nil && ps.GetRejectedDataPoints() > 0 {
+		return fmt.Errorf("collector rejected %v data points: %v", ps.GetRejectedDataPoints(), ps.GetErrorMessage())
+	}
+	return nil
+}
+
+func (o *otlpMetrics) temporality() metricspb.AggregationTemporality {
+	if o.delta {
+		return metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA
+	}
+	return metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE
+}
+
+// collect builds the data points of all metrics, and must be called with the
+// export lock held.
+func (o *otlpMetrics) collect(startNano, nowNano uint64) (metrics []*metricspb.Metric) {
+	o.mut.Lock()
+	defer o.mut.Unlock()
+
+	for _, name := range sortedKeys(o.counters) {
+		var points []*metricspb.NumberDataPoint
+		for _, key := range sortedKeys(o.counters[name].series) {
+			c := o.counters[name].series[key]
+			v := c.value.Load()
+			if o.delta {
+				v, c.exported = v-c.exported, v
+				if v == 0 {
+					continue
+				}
+			}
+			points = append(points, &metricspb.NumberDataPoint{
+				Attributes:        c.attrs,
+				StartTimeUnixNano: startNano,
+				TimeUnixNano:      nowNano,
+				Value:             &metricspb.NumberDataPoint_AsInt{AsInt: v},
+			})
+		}
+		if len(points) == 0 {
+			continue
+		}
+		metrics = append(metrics, &metricspb.Metric{
+			Name: name,
+			Data: &metricspb.Metric_Sum{Sum: &metricspb.Sum{
+				DataPoints:             points,
+				AggregationTemporality: o.temporality(),
+				IsMonotonic:            true,
+			}},
+		})
+	}
+
+	for _, name := range sortedKeys(o.gauges) {
+		var points []*metricspb.NumberDataPoint
+		for _, key := range sortedKeys(o.gauges[name].series) {
+			g := o.gauges[name].series[key]
+			points = append(points, &metricspb.NumberDataPoint{
+				Attributes:   g.attrs,
+				TimeUnixNano: nowNano,
+				Value:        &metricspb.NumberDataPoint_AsInt{AsInt: g.value.Load()},
+			})
+		}
+		if len(points) == 0 {
+			continue
+		}
+		metrics = append(metrics, &metricspb.Metric{
+			Name: name,
+			Data: &metricspb.Metric_Gauge{Gauge: &metricspb.Gauge{DataPoints: points}},
+		})
+	}
+
+	for _, name := range sortedKeys(o.timers) {
+		var points []*metricspb.HistogramDataPoint
+		for _, key := range sortedKeys(o.timers[name].series) {
+			t := o.timers[name].series[key]
+			count, sum, minV, maxV, buckets := t.snapshot(o.delta)
+			if o.delta && count == 0 {
+				continue
+			}
+			p := &metricspb.HistogramDataPoint{
+				Attributes:        t.attrs,
+				StartTimeUnixNano: startNano,
+				TimeUnixNano:      nowNano,
+				Count:             count,
+				Sum:               &sum,
+				BucketCounts:      buckets,
+				ExplicitBounds:    t.bounds,
+			}
+			if count > 0 {
+				p.Min, p.Max = &minV, &maxV
+			}
+			points = append(points, p)
+		}
+		if len(points) == 0 {
+			continue
+		}
+		metrics = append(metrics, &metricspb.Metric{
+			Name: name,
+			Unit: "s",
+			Data: &metricspb.Metric_Histogram{Histogram: &metricspb.Histogram{
+				DataPoints:             points,
+				AggregationTemporality: o.temporality(),
+			}},
+		})
+	}
+	return
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+//------------------------------------------------------------------------------
+
+func otlpSeries[T any](o *otlpMetrics, sets map[string]*otlpSeriesSet[T], name string, labels, values []string, newFn func(attrs []*commonpb.KeyValue) T) T {
+	o.mut.Lock()
+	defer o.mut.Unlock()
+
+	set, exists := sets[name]
+	if !exists {
+		set = &otlpSeriesSet[T]{labels: labels, series: map[string]T{}}
+		sets[name] = set
+	}
+
+	key := strings.Join(values, "\x00")
+	s, exists := set.series[key]
+	if !exists {
+		s = newFn(otlpAttributes(set.labels, values))
+		set.series[key] = s
+	}
+	return s
+}
+
+func (o *otlpMetrics) NewCounterCtor(name string, labelKeys ...string) service.MetricsExporterCounterCtor {
+	return func(labelValues ...string) service.MetricsExporterCounter {
+		return otlpSeries(o, o.counters, name, labelKeys, labelValues, func(attrs []*commonpb.KeyValue) *otlpCounter {
+			return &otlpCounter{attrs: attrs}
+		})
+	}
+}
+
+func (o *otlpMetrics) NewTimerCtor(name string, labelKeys ...string) service.MetricsExporterTimerCtor {
+	return func(labelValues ...string) service.MetricsExporterTimer {
+		return otlpSeries(o, o.timers, name, labelKeys, labelValues, func(attrs []*commonpb.KeyValue) *otlpTimer {
+			return &otlpTimer{
+				attrs:   attrs,
+				bounds:  o.bounds,
+				buckets: make([]uint64, len(o.bounds)+1),
+			}
+		})
+	}
+}
+
+func (o *otlpMetrics) NewGaugeCtor(name string, labelKeys ...string) service.MetricsExporterGaugeCtor {
+	return func(labelValues ...string) service.MetricsExporterGauge {
+		return otlpSeries(o, o.gauges, name, labelKeys, labelValues, func(attrs []*commonpb.KeyValue) *otlpGauge {
+			return &otlpGauge{attrs: attrs}
+		})
+	}
+}
+
+func (o *otlpMetrics) Close(ctx context.Context) error {
+	var err error
+	o.closeOnce.Do(func() {
+		close(o.shutSig)
+		<-o.loopDone
+
+		// Push any metrics recorded since the last push before closing.
+		ctx, done := context.WithTimeout(ctx, o.timeout)
+		defer done()
+		if eErr := o.export(ctx); eErr != nil && !errors.Is(eErr, context.Canceled) {
+			o.log.Errorf("Failed to push metrics: %v", eErr)
+		}
+		err = o.conn.Close()
+	})
+	return err
+}
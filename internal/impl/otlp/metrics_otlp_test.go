@@ -0,0 +1,229 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otlp
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+type testMetricsCollector struct {
+	colmetricspb.UnimplementedMetricsServiceServer
+
+	mut      sync.Mutex
+	requests []*colmetricspb.ExportMetricsServiceRequest
+	headers  []metadata.MD
+}
+
+func (c *testMetricsCollector) Export(ctx context.Context, req *colmetricspb.ExportMetricsServiceRequest) (*colmetricspb.ExportMetricsServiceResponse, error) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	c.requests = append(c.requests, req)
+	c.headers = append(c.headers, md)
+	return &colmetricspb.ExportMetricsServiceResponse{}, nil
+}
+
+func (c *testMetricsCollector) last(t *testing.T) (map[string]*metricspb.Metric, metadata.MD) {
+	t.Helper()
+
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	require.NotEmpty(t, c.requests)
+	req := c.requests[len(c.requests)-1]
+	require.Len(t, req.ResourceMetrics, 1)
+	require.Len(t, req.ResourceMetrics[0].ScopeMetrics, 1)
+
+	metrics := map[string]*metricspb.Metric{}
+	for _, m := range req.ResourceMetrics[0].ScopeMetrics[0].Metrics {
+		metrics[m.Name] = m
+	}
+	return metrics, c.headers[len(c.headers)-1]
+}
+
+// newTestMetricsCollector serves a collector on a random port and returns it
+// along with its address.
+func newTestMetricsCollector(t *testing.T) (*testMetricsCollector, string) {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	collector := &testMetricsCollector{}
+	srv := grpc.NewServer()
+	colmetricspb.RegisterMetricsServiceServer(srv, collector)
+	go func() {
+		_ = srv.Serve(lis)
+	}()
+	t.Cleanup(srv.Stop)
+	return collector, lis.Addr().String()
+}
+
+func TestOtlpMetricsCumulative(t *testing.T) {
+	collector, addr := newTestMetricsCollector(t)
+
+	pConf, err := otlpMetricsSpec().ParseYAML(`
+address: `+addr+`
+push_interval: 1h
+headers:
+  api-key: foo
+resource_attributes:
+  service.name: meow
+histogram_buckets: [ 0.1, 1 ]
+`, nil)
+	require.NoError(t, err)
+
+	o, err := newOtlpMetricsFromParsed(pConf, service.MockResources().Logger())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, o.Close(context.Background()))
+	})
+
+	o.NewCounterCtor("counter", "label")("a").Incr(2)
+	o.NewCounterCtor("counter", "label")("a").Incr(3)
+	o.NewCounterCtor("counter", "label")("b").Incr(1)
+	o.NewGaugeCtor("gauge")().Set(10)
+	timer := o.NewTimerCtor("timer")()
+	timer.Timing(int64(time.Millisecond * 50))
+	timer.Timing(int64(time.Millisecond * 500))
+	timer.Timing(int64(time.Second * 2))
+
+	require.NoError(t, o.export(context.Background()))
+	metrics, md := collector.last(t)
+	assert.Equal(t, []string{"foo"}, md.Get("api-key"))
+
+	collector.mut.Lock()
+	res := collector.requests[0].ResourceMetrics[0].Resource
+	collector.mut.Unlock()
+	require.Len(t, res.Attributes, 1)
+	assert.Equal(t, "service.name", res.Attributes[0].Key)
+	assert.Equal(t, "meow", res.Attributes[0].Value.GetStringValue())
+
+	sum := metrics["counter"].GetSum()
+	require.NotNil(t, sum)
+	assert.True(t, sum.IsMonotonic)
+	assert.Equal(t, metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE, sum.AggregationTemporality)
+	require.Len(t, sum.DataPoints, 2)
+	assert.Equal(t, "a", sum.DataPoints[0].Attributes[0].Value.GetStringValue())
+	assert.Equal(t, int64(5), sum.DataPoints[0].GetAsInt())
+	assert.Equal(t, "b", sum.DataPoints[1].Attributes[0].Value.GetStringValue())
+	assert.Equal(t, int64(1), sum.DataPoints[1].GetAsInt())
+
+	gauge := metrics["gauge"].GetGauge()
+	require.NotNil(t, gauge)
+	require.Len(t, gauge.DataPoints, 1)
+	assert.Equal(t, int64(10), gauge.DataPoints[0].GetAsInt())
+
+	hist := metrics["timer"].GetHistogram()
+	require.NotNil(t, hist)
+	require.Len(t, hist.DataPoints, 1)
+	assert.Equal(t, "s", metrics["timer"].Unit)
+	assert.Equal(t, uint64(3), hist.DataPoints[0].Count)
+	assert.Equal(t, []float64{0.1, 1}, hist.DataPoints[0].ExplicitBounds)
+	assert.Equal(t, []uint64{1, 1, 1}, hist.DataPoints[0].BucketCounts)
+	assert.InDelta(t, 2.55, hist.DataPoints[0].GetSum(), 0.0001)
+	assert.InDelta(t, 0.05, hist.DataPoints[0].GetMin(), 0.0001)
+	assert.InDelta(t, 2, hist.DataPoints[0].GetMax(), 0.0001)
+
+	// Cumulative values keep growing across pushes.
+	o.NewCounterCtor("counter", "label")("a").Incr(1)
+	require.NoError(t, o.export(context.Background()))
+	metrics, _ = collector.last(t)
+	assert.Equal(t, int64(6), metrics["counter"].GetSum().DataPoints[0].GetAsInt())
+	assert.Equal(t, uint64(3), metrics["timer"].GetHistogram().DataPoints[0].Count)
+}
+
+func TestOtlpMetricsDelta(t *testing.T) {
+	collector, addr := newTestMetricsCollector(t)
+
+	pConf, err := otlpMetricsSpec().ParseYAML(`
+address: `+addr+`
+push_interval: 1h
+temporality: delta
+`, nil)
+	require.NoError(t, err)
+
+	o, err := newOtlpMetricsFromParsed(pConf, service.MockResources().Logger())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, o.Close(context.Background()))
+	})
+
+	counterA := o.NewCounterCtor("counter", "label")("a")
+	counterB := o.NewCounterCtor("counter", "label")("b")
+	timer := o.NewTimerCtor("timer")()
+
+	counterA.Incr(2)
+	counterB.Incr(1)
+	timer.Timing(int64(time.Millisecond))
+	require.NoError(t, o.export(context.Background()))
+
+	metrics, _ := collector.last(t)
+	sum := metrics["counter"].GetSum()
+	assert.Equal(t, metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA, sum.AggregationTemporality)
+	require.Len(t, sum.DataPoints, 2)
+	assert.Equal(t, int64(2), sum.DataPoints[0].GetAsInt())
+	assert.Equal(t, uint64(1), metrics["timer"].GetHistogram().DataPoints[0].Count)
+
+	// Only the changes since the previous push are reported, and unchanged
+	// series are omitted.
+	counterA.Incr(3)
+	require.NoError(t, o.export(context.Background()))
+
+	metrics, _ = collector.last(t)
+	sum = metrics["counter"].GetSum()
+	require.Len(t, sum.DataPoints, 1)
+	assert.Equal(t, "a", sum.DataPoints[0].Attributes[0].Value.GetStringValue())
+	assert.Equal(t, int64(3), sum.DataPoints[0].GetAsInt())
+	assert.Greater(t, sum.DataPoints[0].StartTimeUnixNano, uint64(0))
+	assert.NotContains(t, metrics, "timer")
+}
+
+func TestOtlpMetricsDefaultResource(t *testing.T) {
+	res := otlpResource(map[string]string{"foo": "bar"}, "1.2.3")
+	require.Len(t, res.Attributes, 3)
+	assert.Equal(t, "foo", res.Attributes[0].Key)
+	assert.Equal(t, "service.name", res.Attributes[1].Key)
+	assert.Equal(t, "benthos", res.Attributes[1].Value.GetStringValue())
+	assert.Equal(t, "service.version", res.Attributes[2].Key)
+	assert.Equal(t, "1.2.3", res.Attributes[2].Value.GetStringValue())
+}
+
+func TestOtlpMetricsConfigErrors(t *testing.T) {
+	for _, conf := range []string{
+		`push_interval: 0s`,
+		`histogram_buckets: [ 1, 0.5 ]`,
+	} {
+		pConf, err := otlpMetricsSpec().ParseYAML(conf, nil)
+		require.NoError(t, err)
+
+		_, err = newOtlpMetricsFromParsed(pConf, service.MockResources().Logger())
+		require.Error(t, err, conf)
+	}
+}
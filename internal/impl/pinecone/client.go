@@ -31,6 +31,7 @@ type (
 		UpdateVector(ctx context.Context, req *pinecone.UpdateVectorRequest) error
 		UpsertVectors(ctx context.Context, req []*pinecone.Vector) error
 		DeleteVectorsByID(ctx context.Context, ids []string) error
+		DeleteVectorsByFilter(ctx context.Context, filter *pinecone.MetadataFilter) error
 		io.Closer
 	}
 )
@@ -70,6 +71,10 @@ func (c *realIndexClient) DeleteVectorsByID(ctx context.Context, ids []string) e
 	return c.client.DeleteVectorsById(ctx, ids)
 }
 
+func (c *realIndexClient) DeleteVectorsByFilter(ctx context.Context, filter *pinecone.MetadataFilter) error {
+	return c.client.DeleteVectorsByFilter(ctx, filter)
+}
+
 func (c *realIndexClient) Close() error {
 	return c.client.Close()
 }
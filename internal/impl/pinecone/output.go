@@ -23,6 +23,7 @@ import (
 	"github.com/pinecone-io/go-pinecone/pinecone"
 	"github.com/redpanda-data/benthos/v4/public/bloblang"
 	"github.com/redpanda-data/benthos/v4/public/service"
+	"google.golang.org/protobuf/types/known/structpb"
 )
 
 const (
@@ -34,6 +35,8 @@ const (
 	poFieldOp              = "operation"
 	poFieldVectorMapping   = "vector_mapping"
 	poFieldMetadataMapping = "metadata_mapping"
+	poFieldFilterMapping   = "filter_mapping"
+	poFieldMaxVectors      = "max_vectors_per_request"
 )
 
 func outputSpec() *service.ConfigSpec {
@@ -41,7 +44,9 @@ func outputSpec() *service.ConfigSpec {
 		Version("4.31.0").
 		Categories("AI").
 		Summary("Inserts items into a Pinecone index.").
-		Description(service.OutputPerformanceDocs(true, true)).
+		Description(`
+Namespaces are created automatically when vectors are first upserted to them, and the vectors of a batch are upserted to each namespace with requests of up to `+"`max_vectors_per_request`"+` vectors. Vectors can also be deleted by filtering on their metadata with the `+"`delete-vectors-by-filter`"+` operation, in which case `+"`filter_mapping`"+` results in a https://docs.pinecone.io/guides/data/understanding-metadata#metadata-query-language[Pinecone metadata filter^].
+`+service.OutputPerformanceDocs(true, true)).
 		Fields(
 			service.NewOutputMaxInFlightField(),
 			service.NewBatchPolicyField(poFieldBatching),
@@ -51,7 +56,7 @@ func outputSpec() *service.ConfigSpec {
 			service.NewStringField(poFieldAPIKey).
 				Secret().
 				Description("The Pinecone api key."),
-			service.NewStringEnumField(poFieldOp, string(operationUpdate), string(operationUpsert), string(operationDelete)).
+			service.NewStringEnumField(poFieldOp, string(operationUpdate), string(operationUpsert), string(operationDelete), string(operationDeleteByFilter)).
 				Default(string(operationUpsert)).
 				Description("The operation to perform against the Pinecone index."),
 			service.NewInterpolatedStringField(poFieldNamespace).
@@ -59,7 +64,8 @@ func outputSpec() *service.ConfigSpec {
 				Advanced().
 				Description("The namespace to write to - writes to the default namespace by default."),
 			service.NewInterpolatedStringField(poFieldID).
-				Description("The ID for the index entry in Pinecone."),
+				Optional().
+				Description("The ID for the index entry in Pinecone. Required unless the operation is `delete-vectors-by-filter`."),
 			service.NewBloblangField(poFieldVectorMapping).
 				Optional().
 				Description("The mapping to extract out the vector from the document. The result must be a floating point array. Required if not a delete operation.").
@@ -71,7 +77,40 @@ func outputSpec() *service.ConfigSpec {
 				Example(`root = @`).
 				Example(`root = metadata()`).
 				Example(`root = {"summary": this.summary, "foo": this.other_field}`),
-		)
+			service.NewBloblangField(poFieldFilterMapping).
+				Optional().
+				Version("4.45.0").
+				Description("A mapping to the metadata filter of the vectors to delete. Required if the operation is `delete-vectors-by-filter`.").
+				Example(`root = {"document_id": {"$eq": this.document_id}}`).
+				Example(`root = {"genre": {"$in": ["comedy", "drama"]}}`),
+			service.NewIntField(poFieldMaxVectors).
+				Default(100).
+				Version("4.45.0").
+				Advanced().
+				Description("The maximum number of vectors to upsert within a single request, larger batches are split into multiple requests."),
+		).
+		Example(
+			"Maintain a RAG index",
+			"Replace the chunks of documents in an index whenever a document changes, by deleting the previous chunks of a document before upserting the new ones. This assumes that the chunks of each document are consumed in order, starting with the chunk at index zero.",
+			`
+output:
+  broker:
+    pattern: fan_out_sequential
+    outputs:
+      - pinecone:
+          host: documents-abc123.svc.us-east1-gcp.pinecone.io
+          api_key: "${PINECONE_API_KEY}"
+          operation: delete-vectors-by-filter
+          filter_mapping: 'root = {"document_id": {"$eq": this.document_id}}'
+        processors:
+          - mapping: 'root = if this.chunk_index != 0 { deleted() }'
+      - pinecone:
+          host: documents-abc123.svc.us-east1-gcp.pinecone.io
+          api_key: "${PINECONE_API_KEY}"
+          id: '${! json("document_id") }-${! json("chunk_index") }'
+          vector_mapping: 'root = this.embedding'
+          metadata_mapping: 'root = this.without("embedding")'
+`)
 }
 
 func init() {
@@ -98,9 +137,10 @@ func init() {
 type operation string
 
 const (
-	operationUpdate operation = "update-vector"
-	operationUpsert operation = "upsert-vectors"
-	operationDelete operation = "delete-vectors"
+	operationUpdate         operation = "update-vector"
+	operationUpsert         operation = "upsert-vectors"
+	operationDelete         operation = "delete-vectors"
+	operationDeleteByFilter operation = "delete-vectors-by-filter"
 )
 
 type outputWriter struct {
@@ -113,6 +153,8 @@ type outputWriter struct {
 	id              *service.InterpolatedString
 	vectorMapping   *bloblang.Executor
 	metadataMapping *bloblang.Executor
+	filterMapping   *bloblang.Executor
+	maxVectors      int
 
 	pool sync.Pool
 }
@@ -141,6 +183,8 @@ func newOutputWriter(conf *service.ParsedConfig, mgr *service.Resources) (*outpu
 		op = operationUpdate
 	case string(operationDelete):
 		op = operationDelete
+	case string(operationDeleteByFilter):
+		op = operationDeleteByFilter
 	default:
 		return nil, fmt.Errorf("invalid operation: %s", rawOp)
 	}
@@ -151,9 +195,14 @@ func newOutputWriter(conf *service.ParsedConfig, mgr *service.Resources) (*outpu
 	if strings.HasPrefix(host, "https://") {
 		return nil, fmt.Errorf("host field must be a FQDN not a URL: %q (remove the https:// prefix)", host)
 	}
-	id, err := conf.FieldInterpolatedString(poFieldID)
-	if err != nil {
-		return nil, err
+	var id *service.InterpolatedString
+	if op != operationDeleteByFilter {
+		if !conf.Contains(poFieldID) {
+			return nil, fmt.Errorf("field %s is required for the operation %s", poFieldID, op)
+		}
+		if id, err = conf.FieldInterpolatedString(poFieldID); err != nil {
+			return nil, err
+		}
 	}
 	ns, err := conf.FieldInterpolatedString(poFieldNamespace)
 	if err != nil {
@@ -161,7 +210,16 @@ func newOutputWriter(conf *service.ParsedConfig, mgr *service.Resources) (*outpu
 	}
 	var vectorMapping *bloblang.Executor
 	var metadataMapping *bloblang.Executor
-	if op != operationDelete {
+	var filterMapping *bloblang.Executor
+	switch op {
+	case operationDeleteByFilter:
+		if !conf.Contains(poFieldFilterMapping) {
+			return nil, fmt.Errorf("field %s is required for the operation %s", poFieldFilterMapping, op)
+		}
+		if filterMapping, err = conf.FieldBloblang(poFieldFilterMapping); err != nil {
+			return nil, err
+		}
+	case operationUpdate, operationUpsert:
 		vectorMapping, err = conf.FieldBloblang(poFieldVectorMapping)
 		if err != nil {
 			return nil, err
@@ -173,6 +231,13 @@ func newOutputWriter(conf *service.ParsedConfig, mgr *service.Resources) (*outpu
 			}
 		}
 	}
+	maxVectors, err := conf.FieldInt(poFieldMaxVectors)
+	if err != nil {
+		return nil, err
+	}
+	if maxVectors < 1 {
+		return nil, fmt.Errorf("field %s must be at least 1", poFieldMaxVectors)
+	}
 	w := outputWriter{
 		client:          &realClient{pc},
 		host:            host,
@@ -182,6 +247,8 @@ func newOutputWriter(conf *service.ParsedConfig, mgr *service.Resources) (*outpu
 		id:              id,
 		vectorMapping:   vectorMapping,
 		metadataMapping: metadataMapping,
+		filterMapping:   filterMapping,
+		maxVectors:      maxVectors,
 	}
 	return &w, nil
 }
@@ -226,6 +293,8 @@ func (w *outputWriter) WriteBatch(ctx context.Context, batch service.MessageBatc
 		err = w.UpsertBatch(ctx, c, batch)
 	case operationDelete:
 		err = w.DeleteBatch(ctx, c, batch)
+	case operationDeleteByFilter:
+		err = w.DeleteBatchByFilter(ctx, c, batch)
 	default:
 		err = fmt.Errorf("unknown operation: %s", w.op)
 	}
@@ -258,10 +327,14 @@ func (w *outputWriter) UpsertBatch(ctx context.Context, ic indexClient, batch se
 	if err != nil {
 		return err
 	}
-	for ns, batch := range batches {
+	for ns, vectors := range batches {
 		ic.SetNamespace(ns)
-		if err := ic.UpsertVectors(ctx, batch); err != nil {
-			return err
+		for len(vectors) > 0 {
+			n := min(len(vectors), w.maxVectors)
+			if err := ic.UpsertVectors(ctx, vectors[:n]); err != nil {
+				return err
+			}
+			vectors = vectors[n:]
 		}
 	}
 	return nil
@@ -372,6 +445,49 @@ func (w *outputWriter) DeleteBatch(ctx context.Context, ic indexClient, batch se
 	return nil
 }
 
+func (w *outputWriter) DeleteBatchByFilter(ctx context.Context, ic indexClient, batch service.MessageBatch) error {
+	nsExec := batch.InterpolationExecutor(w.namespace)
+	filterExec := batch.BloblangExecutor(w.filterMapping)
+	batches := map[string][]any{}
+	for i := 0; i < len(batch); i++ {
+		ns, err := nsExec.TryString(i)
+		if err != nil {
+			return fmt.Errorf("%s interpolation error: %w", poFieldNamespace, err)
+		}
+		rawFilter, err := filterExec.Query(i)
+		if err != nil {
+			return fmt.Errorf("failed to execute %s: %w", poFieldFilterMapping, err)
+		}
+		if rawFilter == nil {
+			continue
+		}
+		filter, err := rawFilter.AsStructured()
+		if err != nil {
+			return fmt.Errorf("%s extraction failed: %w", poFieldFilterMapping, err)
+		}
+		if _, ok := filter.(map[string]any); !ok {
+			return fmt.Errorf("expected %s to result in an object, got: %T", poFieldFilterMapping, filter)
+		}
+		batches[ns] = append(batches[ns], filter)
+	}
+	for ns, filters := range batches {
+		// Vectors matching any of the filters of the batch are deleted.
+		raw := filters[0].(map[string]any)
+		if len(filters) > 1 {
+			raw = map[string]any{"$or": filters}
+		}
+		filter, err := structpb.NewStruct(raw)
+		if err != nil {
+			return fmt.Errorf("failed to convert %s to Pinecone metadata filter: %w", poFieldFilterMapping, err)
+		}
+		ic.SetNamespace(ns)
+		if err := ic.DeleteVectorsByFilter(ctx, filter); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (w *outputWriter) Close(ctx context.Context) error {
 	for {
 		item := w.pool.Get()
@@ -24,11 +24,13 @@ import (
 	"github.com/redpanda-data/benthos/v4/public/bloblang"
 	"github.com/redpanda-data/benthos/v4/public/service"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/structpb"
 )
 
 type mockClient struct {
 	data            map[string]map[string]map[string]*pinecone.Vector
 	openConnections int
+	upserts         int
 }
 
 func (c *mockClient) Index(host string) (indexClient, error) {
@@ -38,7 +40,7 @@ func (c *mockClient) Index(host string) (indexClient, error) {
 		i = c.data[host]
 	}
 	c.openConnections++
-	return &mockIndexClient{index: i, openConnections: &c.openConnections}, nil
+	return &mockIndexClient{index: i, openConnections: &c.openConnections, upserts: &c.upserts}, nil
 }
 
 func (c *mockClient) Write(host string, ns string, value *pinecone.Vector) {
@@ -63,6 +65,7 @@ type mockIndexClient struct {
 	namespace       string
 	index           map[string]map[string]*pinecone.Vector
 	openConnections *int
+	upserts         *int
 }
 
 func (c *mockIndexClient) SetNamespace(namespace string) {
@@ -92,6 +95,7 @@ func (c *mockIndexClient) UpdateVector(ctx context.Context, req *pinecone.Update
 }
 
 func (c *mockIndexClient) UpsertVectors(ctx context.Context, batch []*pinecone.Vector) error {
+	*c.upserts++
 	vectors := c.GetNamespace()
 	for _, req := range batch {
 		entry, ok := vectors[req.Id]
@@ -115,6 +119,35 @@ func (c *mockIndexClient) DeleteVectorsByID(ctx context.Context, ids []string) e
 	return nil
 }
 
+// DeleteVectorsByFilter supports the subset of the metadata filter language of
+// top level $or and $eq operators.
+func (c *mockIndexClient) DeleteVectorsByFilter(ctx context.Context, filter *pinecone.MetadataFilter) error {
+	filters := []any{filter.AsMap()}
+	if or, ok := filter.AsMap()["$or"]; ok {
+		filters = or.([]any)
+	}
+	vectors := c.GetNamespace()
+	for id, vec := range vectors {
+		if vec.Metadata == nil {
+			continue
+		}
+		meta := vec.Metadata.AsMap()
+		for _, f := range filters {
+			matches := true
+			for k, cond := range f.(map[string]any) {
+				if meta[k] != cond.(map[string]any)["$eq"] {
+					matches = false
+				}
+			}
+			if matches {
+				delete(vectors, id)
+				break
+			}
+		}
+	}
+	return nil
+}
+
 func (c *mockIndexClient) Close() error {
 	*c.openConnections--
 	return nil
@@ -176,6 +209,7 @@ func setup(op operation) (*outputWriter, *mockClient) {
 		namespace:     nsMapping,
 		id:            idMapping,
 		vectorMapping: vectorMapping,
+		maxVectors:    100,
 	}
 	return &w, &c
 }
@@ -234,3 +268,41 @@ func TestMapping(t *testing.T) {
 	}
 	require.Equal(t, m.AsVector(), c.Get(w.host, m.namespace, m.id))
 }
+
+func TestUpsertMaxVectors(t *testing.T) {
+	w, c := setup(operationUpsert)
+	w.maxVectors = 2
+	var batch service.MessageBatch
+	for _, id := range []string{"a", "b", "c", "d", "e"} {
+		m := newMessage("foo", id)
+		batch = append(batch, m.AsMessage())
+	}
+	err := w.WriteBatch(context.Background(), batch)
+	require.NoError(t, err)
+	require.Equal(t, 3, c.upserts)
+	for _, id := range []string{"a", "b", "c", "d", "e"} {
+		require.NotNil(t, c.Get(w.host, "foo", id))
+	}
+}
+
+func TestDeleteByFilter(t *testing.T) {
+	w, c := setup(operationDeleteByFilter)
+	var err error
+	w.filterMapping, err = bloblang.GlobalEnvironment().Parse(`root = {"doc": {"$eq": this.doc}}`)
+	require.NoError(t, err)
+	for id, doc := range map[string]string{"a": "x", "b": "x", "c": "y", "d": "z"} {
+		meta, err := structpb.NewStruct(map[string]any{"doc": doc})
+		require.NoError(t, err)
+		c.Write(w.host, "foo", &pinecone.Vector{Id: id, Values: []float32{1, 2, 3}, Metadata: meta})
+	}
+	m1 := service.NewMessage([]byte(`{"doc":"x"}`))
+	m1.MetaSetMut("ns", "foo")
+	m2 := service.NewMessage([]byte(`{"doc":"y"}`))
+	m2.MetaSetMut("ns", "foo")
+	err = w.WriteBatch(context.Background(), service.MessageBatch{m1, m2})
+	require.NoError(t, err)
+	for _, id := range []string{"a", "b", "c"} {
+		require.Nil(t, c.Get(w.host, "foo", id))
+	}
+	require.NotNil(t, c.Get(w.host, "foo", "d"))
+}
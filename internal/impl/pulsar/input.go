@@ -83,11 +83,46 @@ xref:configuration:interpolation.adoc#bloblang-queries[function interpolation].
 		Field(service.NewStringField("subscription_name").
 			Description("Specify the subscription name for this consumer.")).
 		Field(service.NewStringEnumField("subscription_type", "shared", "key_shared", "failover", "exclusive").
-			Description("Specify the subscription type for this consumer.\n\n> NOTE: Using a `key_shared` subscription type will __allow out-of-order delivery__ since nack-ing messages sets non-zero nack delivery delay - this can potentially cause consumers to stall. See https://pulsar.apache.org/docs/en/2.8.1/concepts-messaging/#negative-acknowledgement[Pulsar documentation^] and https://github.com/apache/pulsar/issues/12208[this Github issue^] for more details.").
+			Description("Specify the subscription type for this consumer.\n\n> NOTE: Using a `key_shared` subscription type will __allow out-of-order delivery__ by default since nack-ing messages sets non-zero nack delivery delay - this can potentially cause consumers to stall. See https://pulsar.apache.org/docs/en/2.8.1/concepts-messaging/#negative-acknowledgement[Pulsar documentation^] and https://github.com/apache/pulsar/issues/12208[this Github issue^] for more details. This behaviour can be changed with the field `key_shared.allow_out_of_order_delivery`.").
 			Default(defaultSubscriptionType)).
+		Field(service.NewObjectField("key_shared",
+			service.NewBoolField("allow_out_of_order_delivery").
+				Description("Whether messages of the same key may be delivered out of order when consumers join or leave the subscription, or when messages are nacked. Disabling this guarantees ordering per key at the cost of potential stalls.").
+				Default(true),
+		).
+			Description("Options specific to `key_shared` subscriptions.").
+			Version("4.45.0").
+			Advanced()).
 		Field(service.NewStringEnumField("subscription_initial_position", "latest", "earliest").
 			Description("Specify the subscription initial position for this consumer.").
 			Default(defaultSubscriptionInitialPosition)).
+		Field(service.NewObjectField("nack_backoff",
+			service.NewDurationField("min_delay").
+				Description("The delay before the first redelivery of a nacked message.").
+				Example("1s"),
+			service.NewDurationField("max_delay").
+				Description("The maximum delay before redelivery of a nacked message.").
+				Example("10m"),
+		).
+			Description("Optionally apply an exponential backoff to the redelivery of nacked messages, where the delay doubles with each redelivery of a message from `min_delay` up to `max_delay`. When omitted nacked messages are redelivered after a fixed delay of one minute.").
+			Version("4.45.0").
+			Advanced().
+			Optional()).
+		Field(service.NewObjectField("dead_letter_policy",
+			service.NewIntField("max_deliveries").
+				Description("The maximum number of times a message is delivered before it is sent to the dead letter topic."),
+			service.NewStringField("dead_letter_topic").
+				Description("The topic to send dead lettered messages to. When empty the topic `<topic>-<subscription>-DLQ` is used.").
+				Default(""),
+			service.NewStringField("retry_letter_topic").
+				Description("The topic to send messages that are to be retried to. When empty the topic `<topic>-<subscription>-RETRY` is used.").
+				Default(""),
+		).
+			Description("Optionally send messages that fail to be processed after a number of deliveries to a dead letter topic. This is only supported for `shared` and `key_shared` subscriptions.").
+			Version("4.45.0").
+			Advanced().
+			Optional()).
+		Field(schemaField()).
 		Field(service.NewObjectField("tls",
 			service.NewStringField("root_cas_file").
 				Description("An optional path of a root certificate authority file to use. This is a file, often with a .pem extension, containing a certificate chain from the parent trusted root certificate, to possible intermediate signing certificates, to the host certificate.").
@@ -114,6 +149,11 @@ type pulsarReader struct {
 	subType       string
 	subInitial    string
 	rootCasFile   string
+
+	keySharedOutOfOrder bool
+	nackBackoff         pulsar.NackBackoffPolicy
+	dlqPolicy           *pulsar.DLQPolicy
+	schema              pulsar.Schema
 }
 
 func newPulsarReaderFromParsed(conf *service.ParsedConfig, log *service.Logger) (p *pulsarReader, err error) {
@@ -145,6 +185,44 @@ func newPulsarReaderFromParsed(conf *service.ParsedConfig, log *service.Logger)
 	if p.rootCasFile, err = conf.FieldString("tls", "root_cas_file"); err != nil {
 		return
 	}
+	if p.keySharedOutOfOrder, err = conf.FieldBool("key_shared", "allow_out_of_order_delivery"); err != nil {
+		return
+	}
+	if conf.Contains("nack_backoff") {
+		var b expNackBackoff
+		if b.minDelay, err = conf.FieldDuration("nack_backoff", "min_delay"); err != nil {
+			return
+		}
+		if b.maxDelay, err = conf.FieldDuration("nack_backoff", "max_delay"); err != nil {
+			return
+		}
+		if b.minDelay <= 0 || b.maxDelay < b.minDelay {
+			err = errors.New("field nack_backoff is invalid: min_delay must be positive and must not exceed max_delay")
+			return
+		}
+		p.nackBackoff = &b
+	}
+	if conf.Contains("dead_letter_policy") {
+		var maxDeliveries int
+		if maxDeliveries, err = conf.FieldInt("dead_letter_policy", "max_deliveries"); err != nil {
+			return
+		}
+		if maxDeliveries <= 0 {
+			err = errors.New("field dead_letter_policy.max_deliveries must be greater than zero")
+			return
+		}
+		p.dlqPolicy = &pulsar.DLQPolicy{MaxDeliveries: uint32(maxDeliveries)}
+		if p.dlqPolicy.DeadLetterTopic, err = conf.FieldString("dead_letter_policy", "dead_letter_topic"); err != nil {
+			return
+		}
+		if p.dlqPolicy.RetryLetterTopic, err = conf.FieldString("dead_letter_policy", "retry_letter_topic"); err != nil {
+			return
+		}
+	}
+	if p.schema, err = schemaFromParsed(conf); err != nil {
+		err = fmt.Errorf("field schema is invalid: %w", err)
+		return
+	}
 
 	if p.url == "" {
 		err = errors.New("field url must not be empty")
@@ -166,6 +244,10 @@ func newPulsarReaderFromParsed(conf *service.ParsedConfig, log *service.Logger)
 		err = fmt.Errorf("field subscription_type is invalid: %v", err)
 		return
 	}
+	if p.dlqPolicy != nil && p.subType != "shared" && p.subType != "key_shared" {
+		err = fmt.Errorf("field dead_letter_policy is not supported with subscription_type %v", p.subType)
+		return
+	}
 	if p.subInitial == "" {
 		p.subInitial = defaultSubscriptionInitialPosition
 	}
@@ -204,6 +286,23 @@ func parseSubscriptionInitialPosition(subInitial string) (pulsar.SubscriptionIni
 	return pulsar.SubscriptionPositionLatest, fmt.Errorf("could not parse subscription initial position: %s", subInitial)
 }
 
+// expNackBackoff implements pulsar.NackBackoffPolicy with a delay that doubles
+// with each redelivery of a message.
+type expNackBackoff struct {
+	minDelay time.Duration
+	maxDelay time.Duration
+}
+
+func (e *expNackBackoff) Next(redeliveryCount uint32) time.Duration {
+	delay := e.minDelay
+	for i := uint32(0); i < redeliveryCount; i++ {
+		if delay *= 2; delay >= e.maxDelay {
+			return e.maxDelay
+		}
+	}
+	return delay
+}
+
 //------------------------------------------------------------------------------
 
 func (p *pulsarReader) Connect(ctx context.Context) error {
@@ -254,8 +353,11 @@ func (p *pulsarReader) Connect(ctx context.Context) error {
 		SubscriptionInitialPosition: subInitial,
 		Type:                        subType,
 		KeySharedPolicy: &pulsar.KeySharedPolicy{
-			AllowOutOfOrderDelivery: true,
+			AllowOutOfOrderDelivery: p.keySharedOutOfOrder,
 		},
+		NackBackoffPolicy: p.nackBackoff,
+		DLQ:               p.dlqPolicy,
+		Schema:            p.schema,
 	}
 	if consumer, err = client.Subscribe(options); err != nil {
 		client.Close()
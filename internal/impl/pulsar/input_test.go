@@ -17,7 +17,9 @@ package pulsar
 import (
 	"context"
 	"testing"
+	"time"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/redpanda-data/benthos/v4/public/service"
@@ -73,3 +75,78 @@ subscription_name: "sub"
 		})
 	}
 }
+
+func TestParseInputDeliveryPolicies(t *testing.T) {
+	baseConfig := `
+url: pulsar://localhost:6650/
+subscription_name: "sub"
+topics: [ "foo" ]
+`
+
+	t.Run("defaults", func(t *testing.T) {
+		parsed, err := inputConfigSpec().ParseYAML(baseConfig, nil)
+		require.NoError(t, err)
+
+		reader, err := newPulsarReaderFromParsed(parsed, service.MockResources().Logger())
+		require.NoError(t, err)
+		assert.True(t, reader.keySharedOutOfOrder)
+		assert.Nil(t, reader.nackBackoff)
+		assert.Nil(t, reader.dlqPolicy)
+		assert.Nil(t, reader.schema)
+	})
+
+	t.Run("all policies", func(t *testing.T) {
+		parsed, err := inputConfigSpec().ParseYAML(baseConfig+`
+subscription_type: key_shared
+key_shared:
+  allow_out_of_order_delivery: false
+nack_backoff:
+  min_delay: 1s
+  max_delay: 5s
+dead_letter_policy:
+  max_deliveries: 3
+  dead_letter_topic: foo-dlq
+schema:
+  type: json
+  definition: '{"type":"record","name":"Example","fields":[{"name":"id","type":"string"}]}'
+`, nil)
+		require.NoError(t, err)
+
+		reader, err := newPulsarReaderFromParsed(parsed, service.MockResources().Logger())
+		require.NoError(t, err)
+		assert.False(t, reader.keySharedOutOfOrder)
+		require.NotNil(t, reader.dlqPolicy)
+		assert.Equal(t, uint32(3), reader.dlqPolicy.MaxDeliveries)
+		assert.Equal(t, "foo-dlq", reader.dlqPolicy.DeadLetterTopic)
+		require.NotNil(t, reader.schema)
+
+		require.NotNil(t, reader.nackBackoff)
+		assert.Equal(t, time.Second, reader.nackBackoff.Next(0))
+		assert.Equal(t, 4*time.Second, reader.nackBackoff.Next(2))
+		assert.Equal(t, 5*time.Second, reader.nackBackoff.Next(3))
+		assert.Equal(t, 5*time.Second, reader.nackBackoff.Next(100))
+	})
+
+	t.Run("dead letter with exclusive subscription", func(t *testing.T) {
+		parsed, err := inputConfigSpec().ParseYAML(baseConfig+`
+subscription_type: exclusive
+dead_letter_policy:
+  max_deliveries: 3
+`, nil)
+		require.NoError(t, err)
+
+		_, err = newPulsarReaderFromParsed(parsed, service.MockResources().Logger())
+		require.EqualError(t, err, "field dead_letter_policy is not supported with subscription_type exclusive")
+	})
+
+	t.Run("schema without definition", func(t *testing.T) {
+		parsed, err := inputConfigSpec().ParseYAML(baseConfig+`
+schema:
+  type: avro
+`, nil)
+		require.NoError(t, err)
+
+		_, err = newPulsarReaderFromParsed(parsed, service.MockResources().Logger())
+		require.EqualError(t, err, "field schema is invalid: a schema definition is required for avro schemas")
+	})
+}
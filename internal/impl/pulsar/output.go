@@ -16,6 +16,7 @@ package pulsar
 
 import (
 	"context"
+	"fmt"
 	"sync"
 	"time"
 
@@ -71,6 +72,7 @@ func outputConfigSpec() *service.ConfigSpec {
 		Field(service.NewIntField("max_in_flight").
 			Description("The maximum number of messages to have in flight at a given time. Increase this to improve throughput.").
 			Default(64)).
+		Field(schemaField()).
 		Field(authField())
 }
 
@@ -89,6 +91,7 @@ type pulsarWriter struct {
 	rootCasFile string
 	key         *service.InterpolatedString
 	orderingKey *service.InterpolatedString
+	schema      pulsar.Schema
 }
 
 func newPulsarWriterFromParsed(conf *service.ParsedConfig, log *service.Logger) (p *pulsarWriter, err error) {
@@ -115,6 +118,10 @@ func newPulsarWriterFromParsed(conf *service.ParsedConfig, log *service.Logger)
 	if p.orderingKey, err = conf.FieldInterpolatedString("ordering_key"); err != nil {
 		return
 	}
+	if p.schema, err = schemaFromParsed(conf); err != nil {
+		err = fmt.Errorf("field schema is invalid: %w", err)
+		return
+	}
 	return
 }
 
@@ -152,7 +159,8 @@ func (p *pulsarWriter) Connect(ctx context.Context) error {
 	}
 
 	if producer, err = client.CreateProducer(pulsar.ProducerOptions{
-		Topic: p.topic,
+		Topic:  p.topic,
+		Schema: p.schema,
 	}); err != nil {
 		client.Close()
 		return err
@@ -0,0 +1,68 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pulsar
+
+import (
+	"fmt"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func schemaField() *service.ConfigField {
+	return service.NewObjectField("schema",
+		service.NewStringEnumField("type", "json", "avro", "protobuf", "string").
+			Description("The type of schema."),
+		service.NewStringField("definition").
+			Description("The schema definition, which is required for `json`, `avro` and `protobuf` schemas and is expressed as an Avro schema JSON document as expected by Pulsar.").
+			Default("").
+			Example(`{"type":"record","name":"Example","fields":[{"name":"id","type":"string"}]}`),
+	).Description("An optional schema to attach to the topic. When set the schema is registered with (or checked for compatibility against) the broker schema registry. Message payloads are not transcoded and must already be serialized in the format of the schema.").
+		Version("4.45.0").
+		Advanced().
+		Optional()
+}
+
+func schemaFromParsed(p *service.ParsedConfig) (pulsar.Schema, error) {
+	if !p.Contains("schema") {
+		return nil, nil
+	}
+	p = p.Namespace("schema")
+
+	sType, err := p.FieldString("type")
+	if err != nil {
+		return nil, err
+	}
+	def, err := p.FieldString("definition")
+	if err != nil {
+		return nil, err
+	}
+	if sType != "string" && def == "" {
+		return nil, fmt.Errorf("a schema definition is required for %v schemas", sType)
+	}
+
+	switch sType {
+	case "json":
+		return pulsar.NewJSONSchemaWithValidation(def, nil)
+	case "avro":
+		return pulsar.NewAvroSchemaWithValidation(def, nil)
+	case "protobuf":
+		return pulsar.NewProtoSchemaWithValidation(def, nil)
+	case "string":
+		return pulsar.NewStringSchema(nil), nil
+	}
+	return nil, fmt.Errorf("schema type not recognised: %v", sType)
+}
@@ -0,0 +1,184 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/redpanda-data/benthos/v4/public/bloblang"
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	auFieldEvent       = "event"
+	auFieldAuditOutput = "audit_output"
+	auFieldComponent   = "component"
+	auFieldDetails     = "details"
+
+	// The metadata key under which the audit ID of a message is stored.
+	auditIDKey = "audit_id"
+)
+
+func auditDescription() string {
+	return `
+Each event is written as a JSON object to the output resource named by ` + "`" + auFieldAuditOutput + "`" + `, and has the following fields:
+
+` + "```text" + `
+- event: The type of the event, e.g. received, transformed, delivered or dead_lettered
+- message_id: The audit ID of the message
+- component: Where the event was emitted, which defaults to the label of the component
+- timestamp: When the event was emitted in RFC 3339 format
+- error: The error of the message, if any
+- details: The result of the ` + "`" + auFieldDetails + "`" + ` mapping, if any
+` + "```" + `
+
+The audit ID of a message is stored in the metadata field ` + "`" + auditIDKey + "`" + `, and is generated as a UUID by the first ` + "`audit`" + ` processor or output that sees the message when it is not already set. In order to follow a message through a pipeline the ID should therefore be assigned as early as possible, typically by an ` + "`audit`" + ` processor with the event ` + "`received`" + ` within the processors of an input. The ID can also be set beforehand with a mapping, e.g. ` + "`meta audit_id = @kafka_key`" + `.`
+}
+
+func auditFields() []*service.ConfigField {
+	return []*service.ConfigField{
+		service.NewStringField(auFieldAuditOutput).
+			Description("The name of an xref:components:outputs/about.adoc[output resource] to write audit events to."),
+		service.NewStringField(auFieldComponent).
+			Description("A name identifying where events are emitted, which defaults to the label of this component.").
+			Example("enrichment").
+			Default(""),
+		service.NewBloblangField(auFieldDetails).
+			Description("An optional mapping executed on each message, where the result is added to its events as the field `details`.").
+			Example(`root.topic = @kafka_topic`).
+			Optional().
+			Advanced(),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type auditor struct {
+	component string
+	details   *bloblang.Executor
+	writeFn   func(ctx context.Context, batch service.MessageBatch) error
+	nowFn     func() time.Time
+	log       *service.Logger
+}
+
+func newAuditor(conf *service.ParsedConfig, mgr *service.Resources) (*auditor, error) {
+	a := &auditor{
+		nowFn: time.Now,
+		log:   mgr.Logger(),
+	}
+
+	outputName, err := conf.FieldString(auFieldAuditOutput)
+	if err != nil {
+		return nil, err
+	}
+	if a.component, err = conf.FieldString(auFieldComponent); err != nil {
+		return nil, err
+	}
+	if a.component == "" {
+		a.component = mgr.Label()
+	}
+	if conf.Contains(auFieldDetails) {
+		if a.details, err = conf.FieldBloblang(auFieldDetails); err != nil {
+			return nil, err
+		}
+	}
+
+	a.writeFn = func(ctx context.Context, batch service.MessageBatch) error {
+		var wErr error
+		if err := mgr.AccessOutput(ctx, outputName, func(o *service.ResourceOutput) {
+			wErr = o.WriteBatch(ctx, batch)
+		}); err != nil {
+			return err
+		}
+		return wErr
+	}
+	return a, nil
+}
+
+// auditMessageID returns the audit ID of a message, and when the message does
+// not have one a new ID is generated and stored within its metadata.
+func auditMessageID(m *service.Message) (string, error) {
+	if id, exists := m.MetaGet(auditIDKey); exists && id != "" {
+		return id, nil
+	}
+	u, err := uuid.NewV4()
+	if err != nil {
+		return "", err
+	}
+	id := u.String()
+	m.MetaSetMut(auditIDKey, id)
+	return id, nil
+}
+
+// event creates an audit event for a message of a batch.
+func (a *auditor) event(batch service.MessageBatch, i int, eventType string, msgErr error) (*service.Message, error) {
+	id, err := auditMessageID(batch[i])
+	if err != nil {
+		return nil, err
+	}
+
+	obj := map[string]any{
+		"event":      eventType,
+		"message_id": id,
+		"component":  a.component,
+		"timestamp":  a.nowFn().Format(time.RFC3339Nano),
+	}
+	if msgErr != nil {
+		obj["error"] = msgErr.Error()
+	}
+	if a.details != nil {
+		dMsg, err := batch.BloblangQuery(i, a.details)
+		if err != nil {
+			return nil, fmt.Errorf("%v mapping failed: %w", auFieldDetails, err)
+		}
+		if dMsg != nil {
+			if obj["details"], err = dMsg.AsStructured(); err != nil {
+				return nil, fmt.Errorf("%v mapping failed: %w", auFieldDetails, err)
+			}
+		}
+	}
+
+	e := service.NewMessage(nil)
+	e.SetStructuredMut(obj)
+	return e, nil
+}
+
+// emit writes an audit event for each message of a batch, where errs provides
+// the individual error of each message and may be nil.
+func (a *auditor) emit(ctx context.Context, batch service.MessageBatch, eventType string, errs []error) error {
+	events := make(service.MessageBatch, 0, len(batch))
+	for i := range batch {
+		var msgErr error
+		if errs != nil {
+			msgErr = errs[i]
+		}
+		e, err := a.event(batch, i, eventType, msgErr)
+		if err != nil {
+			return err
+		}
+		events = append(events, e)
+	}
+	if len(events) == 0 {
+		return nil
+	}
+	if err := a.writeFn(ctx, events); err != nil {
+		return fmt.Errorf("failed to write audit events: %w", err)
+	}
+	return nil
+}
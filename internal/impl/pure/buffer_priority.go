@@ -0,0 +1,349 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"container/heap"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/redpanda-data/benthos/v4/public/bloblang"
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	pbFieldPriority      = "priority"
+	pbFieldAgingInterval = "aging_interval"
+	pbFieldLimit         = "limit"
+)
+
+func priorityBufferSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Version("4.45.0").
+		Summary("Stores messages in memory and delivers them in order of a priority derived from each message, so that urgent messages overtake bulk traffic.").
+		Description(`
+The priority of each message is determined by executing the `+"`"+pbFieldPriority+"`"+` mapping against it when it is added to the buffer, which must result in a number. Messages with higher priorities are consumed first, and messages of equal priority are consumed in the order that they were written.
+
+Messages are stored and delivered individually, and each batch written to the buffer is therefore split into single messages.
+
+== Starvation
+
+In order to prevent a constant stream of high priority messages from holding back lower priority ones indefinitely, the priority of a message increases by one for every `+"`"+pbFieldAgingInterval+"`"+` that it spends within the buffer. For example, with an interval of `+"`1s`"+` a message of priority `+"`0`"+` that has waited for five seconds is consumed before a message of priority `+"`4`"+` that has just arrived.
+
+== Delivery guarantees
+
+Messages are acknowledged at the input level once they are added to the buffer, and a message that fails to be delivered is returned to the buffer with its original priority and age. Since messages are held in memory they are lost if the service is stopped before they are delivered.
+`).
+		Fields(
+			service.NewBloblangField(pbFieldPriority).
+				Description("A mapping that determines the priority of a message, where higher numbers are delivered first.").
+				Examples(`root = if @kafka_topic == "control" { 10 } else { 0 }`, `root = this.priority`),
+			service.NewDurationField(pbFieldAgingInterval).
+				Description("The time a message must spend within the buffer for its priority to increase by one. Set to `0s` to disable aging, in which case lower priority messages are only delivered once there are no higher priority messages remaining.").
+				Default("1s"),
+			service.NewIntField(pbFieldLimit).
+				Description("The maximum buffer size (in bytes) to allow before applying backpressure upstream.").
+				Default(524288000),
+		).
+		Example("Control Messages", "Deliver control messages ahead of bulk data read from the same topics.", `
+input:
+  kafka_franz:
+    seed_brokers: [ localhost:9092 ]
+    topics: [ data, control ]
+    consumer_group: processor
+
+buffer:
+  priority:
+    priority: 'root = if @kafka_topic == "control" { 100 } else { 0 }'
+    aging_interval: 500ms
+`)
+}
+
+func init() {
+	err := service.RegisterBatchBuffer(
+		"priority", priorityBufferSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchBuffer, error) {
+			return newPriorityBufferFromConfig(conf)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+func newPriorityBufferFromConfig(conf *service.ParsedConfig) (*priorityBuffer, error) {
+	priority, err := conf.FieldBloblang(pbFieldPriority)
+	if err != nil {
+		return nil, err
+	}
+	agingInterval, err := conf.FieldDuration(pbFieldAgingInterval)
+	if err != nil {
+		return nil, err
+	}
+	limit, err := conf.FieldInt(pbFieldLimit)
+	if err != nil {
+		return nil, err
+	}
+	if agingInterval < 0 {
+		return nil, fmt.Errorf("%v must not be negative", pbFieldAgingInterval)
+	}
+	if limit <= 0 {
+		return nil, fmt.Errorf("%v must be greater than zero", pbFieldLimit)
+	}
+	return &priorityBuffer{
+		priority:      priority,
+		agingInterval: agingInterval,
+		limit:         limit,
+		start:         time.Now(),
+		nowFn:         time.Now,
+		cond:          sync.NewCond(&sync.Mutex{}),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+type priorityItem struct {
+	msg  *service.Message
+	size int
+
+	// The effective priority of an item at time t is its priority plus the
+	// time it has waited divided by the aging interval. The time t is common
+	// to all items and so they can be compared by their priority less the
+	// time they were written divided by the interval, which does not change.
+	rank float64
+	seq  uint64
+}
+
+type priorityHeap []*priorityItem
+
+func (h priorityHeap) Len() int { return len(h) }
+
+func (h priorityHeap) Less(i, j int) bool {
+	if h[i].rank != h[j].rank {
+		return h[i].rank > h[j].rank
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h priorityHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *priorityHeap) Push(x any) { *h = append(*h, x.(*priorityItem)) }
+
+func (h *priorityHeap) Pop() any {
+	old := *h
+	item := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return item
+}
+
+type priorityBuffer struct {
+	priority      *bloblang.Executor
+	agingInterval time.Duration
+	limit         int
+
+	start time.Time
+	nowFn func() time.Time
+
+	cond       *sync.Cond
+	items      priorityHeap
+	nextSeq    uint64
+	size       int
+	inFlight   int
+	endOfInput bool
+	closed     bool
+}
+
+// broadcast wakes pending reads and writes, and holds the lock so that the
+// wake up cannot be missed by a call that is about to wait.
+func (p *priorityBuffer) broadcast() {
+	p.cond.L.Lock()
+	p.cond.Broadcast()
+	p.cond.L.Unlock()
+}
+
+func priorityNumber(v any) (float64, error) {
+	switch t := v.(type) {
+	case json.Number:
+		return t.Float64()
+	case int64:
+		return float64(t), nil
+	case uint64:
+		return float64(t), nil
+	case int:
+		return float64(t), nil
+	case float64:
+		return t, nil
+	}
+	return 0, fmt.Errorf("expected a number, got %T", v)
+}
+
+func (p *priorityBuffer) priorityOf(msg *service.Message) (float64, error) {
+	res, err := msg.BloblangQuery(p.priority)
+	if err != nil {
+		return 0, err
+	}
+	if res == nil {
+		return 0, errors.New("mapping deleted the priority")
+	}
+	// Mappings that result in strings produce raw messages rather than
+	// structured ones.
+	v, err := res.AsStructured()
+	if err != nil {
+		b, bErr := res.AsBytes()
+		if bErr != nil {
+			return 0, bErr
+		}
+		v = string(b)
+	}
+	return priorityNumber(v)
+}
+
+func (p *priorityBuffer) ackFn(item *priorityItem) service.AckFunc {
+	return func(ctx context.Context, err error) error {
+		p.cond.L.Lock()
+		defer p.cond.L.Unlock()
+
+		p.inFlight--
+		if err != nil && !p.closed {
+			heap.Push(&p.items, item)
+		} else {
+			p.size -= item.size
+		}
+		p.cond.Broadcast()
+		return nil
+	}
+}
+
+// ReadBatch waits for a message and returns the one with the highest priority.
+func (p *priorityBuffer) ReadBatch(ctx context.Context) (service.MessageBatch, service.AckFunc, error) {
+	ctx, done := context.WithCancel(ctx)
+	defer done()
+
+	go func() {
+		<-ctx.Done()
+		p.broadcast()
+	}()
+
+	p.cond.L.Lock()
+	defer p.cond.L.Unlock()
+
+	for {
+		if p.closed {
+			return nil, nil, service.ErrEndOfBuffer
+		}
+		if ctx.Err() != nil {
+			return nil, nil, ctx.Err()
+		}
+		if len(p.items) > 0 {
+			item := heap.Pop(&p.items).(*priorityItem)
+			p.inFlight++
+			return service.MessageBatch{item.msg.Copy()}, p.ackFn(item), nil
+		}
+		if p.endOfInput && p.inFlight == 0 {
+			return nil, nil, service.ErrEndOfBuffer
+		}
+		p.cond.Wait()
+	}
+}
+
+// WriteBatch adds the messages of a batch to the buffer along with their
+// priorities, blocking while the buffer is full.
+func (p *priorityBuffer) WriteBatch(ctx context.Context, msgBatch service.MessageBatch, aFn service.AckFunc) error {
+	items := make([]*priorityItem, 0, len(msgBatch))
+	var batchSize int
+	for i, msg := range msgBatch {
+		priority, err := p.priorityOf(msg)
+		if err != nil {
+			return fmt.Errorf("failed to execute %v mapping for message %v: %w", pbFieldPriority, i, err)
+		}
+		b, err := msg.AsBytes()
+		if err != nil {
+			return err
+		}
+
+		rank := priority
+		if p.agingInterval > 0 {
+			rank -= float64(p.nowFn().Sub(p.start)) / float64(p.agingInterval)
+		}
+		items = append(items, &priorityItem{msg: msg, size: len(b), rank: rank})
+		batchSize += len(b)
+	}
+
+	ctx, done := context.WithCancel(ctx)
+	defer done()
+
+	go func() {
+		<-ctx.Done()
+		p.broadcast()
+	}()
+
+	p.cond.L.Lock()
+	defer p.cond.L.Unlock()
+
+	// A batch larger than the limit is accepted once the buffer is empty, as
+	// it would otherwise never be.
+	for p.size > 0 && p.size+batchSize > p.limit {
+		if p.closed {
+			return service.ErrEndOfBuffer
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		p.cond.Wait()
+	}
+	if p.closed {
+		return service.ErrEndOfBuffer
+	}
+
+	for _, item := range items {
+		item.seq = p.nextSeq
+		p.nextSeq++
+		heap.Push(&p.items, item)
+	}
+	p.size += batchSize
+
+	if err := aFn(ctx, nil); err != nil {
+		return err
+	}
+	p.cond.Broadcast()
+	return nil
+}
+
+// EndOfInput signals to the buffer that the input is finished and therefore
+// once all messages have been delivered it should close.
+func (p *priorityBuffer) EndOfInput() {
+	go func() {
+		p.cond.L.Lock()
+		defer p.cond.L.Unlock()
+
+		p.endOfInput = true
+		p.cond.Broadcast()
+	}()
+}
+
+func (p *priorityBuffer) Close(ctx context.Context) error {
+	p.cond.L.Lock()
+	defer p.cond.L.Unlock()
+
+	p.closed = true
+	p.cond.Broadcast()
+	return nil
+}
@@ -0,0 +1,184 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func writePriorityMessages(t *testing.T, buf *priorityBuffer, contents ...string) {
+	t.Helper()
+
+	var batch service.MessageBatch
+	for _, c := range contents {
+		batch = append(batch, service.NewMessage([]byte(c)))
+	}
+	require.NoError(t, buf.WriteBatch(context.Background(), batch, func(context.Context, error) error {
+		return nil
+	}))
+}
+
+func readPriorityMessage(t *testing.T, buf *priorityBuffer) (string, service.AckFunc) {
+	t.Helper()
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+
+	batch, aFn, err := buf.ReadBatch(ctx)
+	require.NoError(t, err)
+	require.Len(t, batch, 1)
+
+	b, err := batch[0].AsBytes()
+	require.NoError(t, err)
+	return string(b), aFn
+}
+
+func TestPriorityBufferOrdering(t *testing.T) {
+	pConf, err := priorityBufferSpec().ParseYAML(`
+priority: root = this.p
+aging_interval: 0s
+`, nil)
+	require.NoError(t, err)
+
+	buf, err := newPriorityBufferFromConfig(pConf)
+	require.NoError(t, err)
+
+	writePriorityMessages(t, buf, `{"id":"a","p":1}`, `{"id":"b","p":5}`, `{"id":"c","p":1}`)
+	writePriorityMessages(t, buf, `{"id":"d","p":3}`)
+
+	var ids []string
+	for range 4 {
+		content, aFn := readPriorityMessage(t, buf)
+		ids = append(ids, content[7:8])
+		require.NoError(t, aFn(context.Background(), nil))
+	}
+	assert.Equal(t, []string{"b", "d", "a", "c"}, ids)
+
+	buf.EndOfInput()
+	_, _, err = buf.ReadBatch(context.Background())
+	require.ErrorIs(t, err, service.ErrEndOfBuffer)
+}
+
+func TestPriorityBufferAging(t *testing.T) {
+	pConf, err := priorityBufferSpec().ParseYAML(`
+priority: root = this.p
+aging_interval: 1s
+`, nil)
+	require.NoError(t, err)
+
+	buf, err := newPriorityBufferFromConfig(pConf)
+	require.NoError(t, err)
+
+	now := buf.start
+	buf.nowFn = func() time.Time { return now }
+
+	writePriorityMessages(t, buf, `{"id":"a","p":0}`)
+	now = now.Add(time.Second * 5)
+	writePriorityMessages(t, buf, `{"id":"b","p":4}`, `{"id":"c","p":6}`)
+
+	var ids []string
+	for range 3 {
+		content, aFn := readPriorityMessage(t, buf)
+		ids = append(ids, content[7:8])
+		require.NoError(t, aFn(context.Background(), nil))
+	}
+	assert.Equal(t, []string{"c", "a", "b"}, ids)
+}
+
+func TestPriorityBufferNack(t *testing.T) {
+	pConf, err := priorityBufferSpec().ParseYAML(`
+priority: root = this.p
+`, nil)
+	require.NoError(t, err)
+
+	buf, err := newPriorityBufferFromConfig(pConf)
+	require.NoError(t, err)
+
+	writePriorityMessages(t, buf, `{"id":"a","p":2}`, `{"id":"b","p":1}`)
+
+	content, aFn := readPriorityMessage(t, buf)
+	assert.Equal(t, "a", content[7:8])
+	buf.EndOfInput()
+	require.NoError(t, aFn(context.Background(), errors.New("nope")))
+
+	var ids []string
+	for range 2 {
+		content, aFn := readPriorityMessage(t, buf)
+		ids = append(ids, content[7:8])
+		require.NoError(t, aFn(context.Background(), nil))
+	}
+	assert.Equal(t, []string{"a", "b"}, ids)
+
+	_, _, err = buf.ReadBatch(context.Background())
+	require.ErrorIs(t, err, service.ErrEndOfBuffer)
+}
+
+func TestPriorityBufferBackpressure(t *testing.T) {
+	pConf, err := priorityBufferSpec().ParseYAML(`
+priority: root = 0
+limit: 10
+`, nil)
+	require.NoError(t, err)
+
+	buf, err := newPriorityBufferFromConfig(pConf)
+	require.NoError(t, err)
+
+	writePriorityMessages(t, buf, "aaaaaaaa")
+
+	ctx, done := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer done()
+	err = buf.WriteBatch(ctx, service.MessageBatch{service.NewMessage([]byte("bbbbbbbb"))}, func(context.Context, error) error {
+		return nil
+	})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	writeErr := make(chan error)
+	go func() {
+		writeErr <- buf.WriteBatch(context.Background(), service.MessageBatch{service.NewMessage([]byte("bbbbbbbb"))}, func(context.Context, error) error {
+			return nil
+		})
+	}()
+
+	content, aFn := readPriorityMessage(t, buf)
+	assert.Equal(t, "aaaaaaaa", content)
+	require.NoError(t, aFn(context.Background(), nil))
+	require.NoError(t, <-writeErr)
+
+	content, _ = readPriorityMessage(t, buf)
+	assert.Equal(t, "bbbbbbbb", content)
+}
+
+func TestPriorityBufferBadPriority(t *testing.T) {
+	pConf, err := priorityBufferSpec().ParseYAML(`
+priority: root = this.p
+`, nil)
+	require.NoError(t, err)
+
+	buf, err := newPriorityBufferFromConfig(pConf)
+	require.NoError(t, err)
+
+	err = buf.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"p":"high"}`)),
+	}, func(context.Context, error) error { return nil })
+	require.ErrorContains(t, err, "expected a number")
+}
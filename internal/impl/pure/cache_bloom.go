@@ -0,0 +1,433 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	bcFieldCapacity          = "capacity"
+	bcFieldFalsePositiveRate = "false_positive_rate"
+	bcFieldGenerations       = "generations"
+	bcFieldPersistence       = "persistence"
+	bcFieldPersistPath       = "path"
+	bcFieldPersistInterval   = "interval"
+)
+
+func bloomCacheSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Version("4.45.0").
+		Summary("Stores the presence of keys within bloom filters of bounded memory, intended for deduplicating high cardinality keys where storing each key would be too expensive.").
+		Description(`
+This cache records whether a key has been added rather than storing values, and is primarily intended for use with the `+"xref:components:processors/dedupe.adoc[`dedupe` processor]"+`. A bloom filter never fails to report a key that has been added, but may report a key that has not been added as present, with a probability up to the configured `+"`false_positive_rate`"+`, which results in a message being wrongly considered a duplicate.
+
+Keys are added to the newest of a number of `+"`generations`"+` of filters, and checked against all of them. Once the newest filter contains its `+"`capacity`"+` of keys a new generation is started and the oldest is discarded, and therefore memory usage is bounded and keys are eventually forgotten after between `+"`capacity * (generations - 1)`"+` and `+"`capacity * generations`"+` further keys have been added. Each filter uses approximately `+"`-capacity * ln(false_positive_rate) / ln(2)^2`"+` bits of memory, for example one million keys at a rate of 0.001 uses roughly 1.8MB per filter.
+
+Getting a key returns an empty value when the key may have been added, and setting a key adds it. Keys cannot be deleted, and TTLs are ignored.
+
+== Persistence
+
+When a `+"`persistence`"+` path is configured the filters are written to it periodically and when the cache is closed, and are read from it when the cache is created, in order for keys to be remembered across restarts. Filters persisted with a different `+"`capacity`"+` or `+"`false_positive_rate`"+` are discarded.`).
+		Fields(
+			service.NewIntField(bcFieldCapacity).
+				Description("The number of keys of each generation of filter.").
+				Default(1000000),
+			service.NewFloatField(bcFieldFalsePositiveRate).
+				Description("The target probability of a key that has not been added being reported as present, when each generation is at capacity.").
+				Default(0.001),
+			service.NewIntField(bcFieldGenerations).
+				Description("The number of generations of filters to keep.").
+				Default(2).
+				Advanced(),
+			service.NewObjectField(bcFieldPersistence,
+				service.NewStringField(bcFieldPersistPath).
+					Description("A file path to persist filters to."),
+				service.NewDurationField(bcFieldPersistInterval).
+					Description("The period between writing filters to the path when keys have been added.").
+					Default("1m"),
+			).
+				Description("Optionally persist filters to a file so that keys are remembered across restarts.").
+				Optional(),
+		).
+		Example("Deduplicate High Cardinality IDs", "Deduplicate messages by ID across tens of millions of IDs with bounded memory, persisting the filters to disk.", `
+pipeline:
+  processors:
+    - dedupe:
+        cache: seen
+        key: ${! json("id") }
+
+cache_resources:
+  - label: seen
+    bloom:
+      capacity: 10000000
+      false_positive_rate: 0.0001
+      persistence:
+        path: /var/lib/connect/seen.bloom
+`)
+}
+
+func init() {
+	err := service.RegisterCache("bloom", bloomCacheSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Cache, error) {
+			return newBloomCacheFromParsed(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type bloomFilter struct {
+	bits  []uint64
+	count int
+}
+
+type bloomCache struct {
+	capacity    int
+	nBits       uint64
+	nHashes     uint64
+	generations int
+	log         *service.Logger
+
+	persistPath     string
+	persistInterval time.Duration
+
+	mut     sync.Mutex
+	filters []*bloomFilter
+	dirty   bool
+
+	closeOnce sync.Once
+	closeChan chan struct{}
+	doneChan  chan struct{}
+}
+
+func newBloomCacheFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*bloomCache, error) {
+	capacity, err := conf.FieldInt(bcFieldCapacity)
+	if err != nil {
+		return nil, err
+	}
+	fpRate, err := conf.FieldFloat(bcFieldFalsePositiveRate)
+	if err != nil {
+		return nil, err
+	}
+	generations, err := conf.FieldInt(bcFieldGenerations)
+	if err != nil {
+		return nil, err
+	}
+
+	var persistPath string
+	var persistInterval time.Duration
+	if conf.Contains(bcFieldPersistence) {
+		pConf := conf.Namespace(bcFieldPersistence)
+		if persistPath, err = pConf.FieldString(bcFieldPersistPath); err != nil {
+			return nil, err
+		}
+		if persistInterval, err = pConf.FieldDuration(bcFieldPersistInterval); err != nil {
+			return nil, err
+		}
+	}
+
+	c, err := newBloomCache(capacity, fpRate, generations, mgr.Logger())
+	if err != nil {
+		return nil, err
+	}
+	c.persistPath, c.persistInterval = persistPath, persistInterval
+
+	if c.persistPath != "" {
+		if err := c.load(); err != nil {
+			return nil, err
+		}
+	}
+	if c.persistPath != "" && c.persistInterval > 0 {
+		go c.persistLoop()
+	} else {
+		close(c.doneChan)
+	}
+	return c, nil
+}
+
+func newBloomCache(capacity int, fpRate float64, generations int, log *service.Logger) (*bloomCache, error) {
+	if capacity <= 0 {
+		return nil, errors.New("capacity must be greater than zero")
+	}
+	if fpRate <= 0 || fpRate >= 1 {
+		return nil, errors.New("false_positive_rate must be between zero and one")
+	}
+	if generations <= 0 {
+		return nil, errors.New("generations must be greater than zero")
+	}
+
+	nBits := uint64(math.Ceil(-float64(capacity) * math.Log(fpRate) / (math.Ln2 * math.Ln2)))
+	nBits = (nBits + 63) &^ 63
+	nHashes := uint64(math.Max(1, math.Round(float64(nBits)/float64(capacity)*math.Ln2)))
+
+	c := &bloomCache{
+		capacity:    capacity,
+		nBits:       nBits,
+		nHashes:     nHashes,
+		generations: generations,
+		log:         log,
+		closeChan:   make(chan struct{}),
+		doneChan:    make(chan struct{}),
+	}
+	c.filters = []*bloomFilter{c.newFilter()}
+	return c, nil
+}
+
+func (c *bloomCache) newFilter() *bloomFilter {
+	return &bloomFilter{bits: make([]uint64, c.nBits/64)}
+}
+
+// positions calls fn with each bit position of a key, using double hashing of
+// the two halves of a 128 bit FNV-1a hash, which is stable across processes
+// and therefore suitable for persisted filters.
+func (c *bloomCache) positions(key string, fn func(pos uint64) bool) {
+	h := fnv.New128a()
+	_, _ = h.Write([]byte(key))
+	sum := h.Sum(nil)
+
+	h1 := binary.BigEndian.Uint64(sum[:8])
+	h2 := binary.BigEndian.Uint64(sum[8:]) | 1
+	for i := uint64(0); i < c.nHashes; i++ {
+		if !fn((h1 + i*h2) % c.nBits) {
+			return
+		}
+	}
+}
+
+func (c *bloomCache) containsLocked(key string) bool {
+	for _, f := range c.filters {
+		found := true
+		c.positions(key, func(pos uint64) bool {
+			if f.bits[pos/64]&(1<<(pos%64)) == 0 {
+				found = false
+			}
+			return found
+		})
+		if found {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *bloomCache) addLocked(key string) {
+	f := c.filters[len(c.filters)-1]
+	if f.count >= c.capacity {
+		f = c.newFilter()
+		c.filters = append(c.filters, f)
+		if len(c.filters) > c.generations {
+			c.filters = c.filters[len(c.filters)-c.generations:]
+		}
+	}
+
+	c.positions(key, func(pos uint64) bool {
+		f.bits[pos/64] |= 1 << (pos % 64)
+		return true
+	})
+	f.count++
+	c.dirty = true
+}
+
+func (c *bloomCache) Get(ctx context.Context, key string) ([]byte, error) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	if !c.containsLocked(key) {
+		return nil, service.ErrKeyNotFound
+	}
+	return []byte{}, nil
+}
+
+func (c *bloomCache) Set(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	if !c.containsLocked(key) {
+		c.addLocked(key)
+	}
+	return nil
+}
+
+func (c *bloomCache) Add(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	if c.containsLocked(key) {
+		return service.ErrKeyAlreadyExists
+	}
+	c.addLocked(key)
+	return nil
+}
+
+func (c *bloomCache) Delete(ctx context.Context, key string) error {
+	return errors.New("keys cannot be deleted from a bloom cache")
+}
+
+//------------------------------------------------------------------------------
+
+var bloomFileMagic = [4]byte{'C', 'B', 'L', '1'}
+
+func (c *bloomCache) persistLoop() {
+	defer close(c.doneChan)
+
+	ticker := time.NewTicker(c.persistInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := c.persist(); err != nil {
+				c.log.Errorf("Failed to persist bloom filters: %v", err)
+			}
+		case <-c.closeChan:
+			return
+		}
+	}
+}
+
+// persist writes the filters to the path when keys have been added since they
+// were last written, replacing the file atomically.
+func (c *bloomCache) persist() error {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	if !c.dirty {
+		return nil
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(c.persistPath), filepath.Base(c.persistPath)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	if err := c.writeFiltersLocked(w); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), c.persistPath); err != nil {
+		return err
+	}
+	c.dirty = false
+	return nil
+}
+
+func (c *bloomCache) writeFiltersLocked(w io.Writer) error {
+	header := []any{bloomFileMagic, c.nBits, c.nHashes, uint64(len(c.filters))}
+	for _, v := range header {
+		if err := binary.Write(w, binary.LittleEndian, v); err != nil {
+			return err
+		}
+	}
+	for _, f := range c.filters {
+		if err := binary.Write(w, binary.LittleEndian, uint64(f.count)); err != nil {
+			return err
+		}
+		if err := binary.Write(w, binary.LittleEndian, f.bits); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *bloomCache) load() error {
+	f, err := os.Open(c.persistPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read persisted bloom filters: %w", err)
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+
+	var magic [4]byte
+	var nBits, nHashes, nFilters uint64
+	for _, v := range []any{&magic, &nBits, &nHashes, &nFilters} {
+		if err := binary.Read(r, binary.LittleEndian, v); err != nil {
+			return fmt.Errorf("failed to read persisted bloom filters: %w", err)
+		}
+	}
+	if magic != bloomFileMagic {
+		return fmt.Errorf("file '%v' does not contain persisted bloom filters", c.persistPath)
+	}
+	if nBits != c.nBits || nHashes != c.nHashes {
+		c.log.Warnf("Discarding persisted bloom filters from '%v' as they were created with a different capacity or false positive rate", c.persistPath)
+		return nil
+	}
+
+	filters := make([]*bloomFilter, 0, nFilters)
+	for i := uint64(0); i < nFilters; i++ {
+		bf := c.newFilter()
+		var count uint64
+		if err := binary.Read(r, binary.LittleEndian, &count); err != nil {
+			return fmt.Errorf("failed to read persisted bloom filters: %w", err)
+		}
+		if err := binary.Read(r, binary.LittleEndian, bf.bits); err != nil {
+			return fmt.Errorf("failed to read persisted bloom filters: %w", err)
+		}
+		bf.count = int(count)
+		filters = append(filters, bf)
+	}
+	if len(filters) > c.generations {
+		filters = filters[len(filters)-c.generations:]
+	}
+	if len(filters) > 0 {
+		c.filters = filters
+	}
+	return nil
+}
+
+func (c *bloomCache) Close(ctx context.Context) error {
+	c.closeOnce.Do(func() {
+		close(c.closeChan)
+	})
+	select {
+	case <-c.doneChan:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	if c.persistPath == "" {
+		return nil
+	}
+	return c.persist()
+}
@@ -0,0 +1,174 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func testBloomCache(t *testing.T, yamlStr string) *bloomCache {
+	t.Helper()
+
+	conf, err := bloomCacheSpec().ParseYAML(yamlStr, nil)
+	require.NoError(t, err)
+
+	c, err := newBloomCacheFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+	return c
+}
+
+func TestBloomCacheAdd(t *testing.T) {
+	c := testBloomCache(t, ``)
+	t.Cleanup(func() {
+		_ = c.Close(context.Background())
+	})
+
+	tCtx := context.Background()
+
+	_, err := c.Get(tCtx, "foo")
+	assert.ErrorIs(t, err, service.ErrKeyNotFound)
+
+	require.NoError(t, c.Add(tCtx, "foo", nil, nil))
+	assert.ErrorIs(t, c.Add(tCtx, "foo", nil, nil), service.ErrKeyAlreadyExists)
+
+	v, err := c.Get(tCtx, "foo")
+	require.NoError(t, err)
+	assert.Empty(t, v)
+
+	require.NoError(t, c.Set(tCtx, "bar", []byte("ignored"), nil))
+	assert.ErrorIs(t, c.Add(tCtx, "bar", nil, nil), service.ErrKeyAlreadyExists)
+
+	assert.Error(t, c.Delete(tCtx, "foo"))
+}
+
+func TestBloomCacheFalsePositiveRate(t *testing.T) {
+	c, err := newBloomCache(10000, 0.01, 1, nil)
+	require.NoError(t, err)
+
+	tCtx := context.Background()
+	for i := 0; i < 10000; i++ {
+		require.NoError(t, c.Set(tCtx, fmt.Sprintf("key-%v", i), nil, nil))
+	}
+	for i := 0; i < 10000; i++ {
+		_, err := c.Get(tCtx, fmt.Sprintf("key-%v", i))
+		require.NoError(t, err)
+	}
+
+	falsePositives := 0
+	for i := 0; i < 10000; i++ {
+		if _, err := c.Get(tCtx, fmt.Sprintf("other-%v", i)); err == nil {
+			falsePositives++
+		}
+	}
+	assert.Less(t, falsePositives, 200)
+}
+
+func TestBloomCacheGenerations(t *testing.T) {
+	c, err := newBloomCache(10, 0.001, 2, nil)
+	require.NoError(t, err)
+
+	tCtx := context.Background()
+	add := func(from, to int) {
+		for i := from; i < to; i++ {
+			require.NoError(t, c.Add(tCtx, fmt.Sprintf("key-%v", i), nil, nil))
+		}
+	}
+
+	add(0, 20)
+	assert.Len(t, c.filters, 2)
+	assert.ErrorIs(t, c.Add(tCtx, "key-0", nil, nil), service.ErrKeyAlreadyExists)
+
+	// The first generation is discarded once a third begins.
+	add(20, 21)
+	assert.Len(t, c.filters, 2)
+	assert.NoError(t, c.Add(tCtx, "key-0", nil, nil))
+	assert.ErrorIs(t, c.Add(tCtx, "key-15", nil, nil), service.ErrKeyAlreadyExists)
+}
+
+func TestBloomCachePersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "seen.bloom")
+	confStr := fmt.Sprintf(`
+capacity: 100
+persistence:
+  path: %v
+  interval: 1h
+`, path)
+
+	tCtx := context.Background()
+
+	c := testBloomCache(t, confStr)
+	require.NoError(t, c.Add(tCtx, "foo", nil, nil))
+	require.NoError(t, c.Close(tCtx))
+
+	_, err := os.Stat(path)
+	require.NoError(t, err)
+
+	c = testBloomCache(t, confStr)
+	assert.ErrorIs(t, c.Add(tCtx, "foo", nil, nil), service.ErrKeyAlreadyExists)
+	require.NoError(t, c.Add(tCtx, "bar", nil, nil))
+	require.NoError(t, c.persist())
+	require.NoError(t, c.Close(tCtx))
+
+	c = testBloomCache(t, confStr)
+	assert.ErrorIs(t, c.Add(tCtx, "bar", nil, nil), service.ErrKeyAlreadyExists)
+	require.NoError(t, c.Close(tCtx))
+
+	// Filters of a different size are discarded.
+	c = testBloomCache(t, fmt.Sprintf(`
+capacity: 200
+persistence:
+  path: %v
+`, path))
+	assert.NoError(t, c.Add(tCtx, "foo", nil, nil))
+	require.NoError(t, c.Close(tCtx))
+}
+
+func TestBloomCacheBadPersistedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "seen.bloom")
+	require.NoError(t, os.WriteFile(path, []byte("not a bloom filter"), 0o644))
+
+	conf, err := bloomCacheSpec().ParseYAML(fmt.Sprintf(`
+persistence:
+  path: %v
+`, path), nil)
+	require.NoError(t, err)
+
+	_, err = newBloomCacheFromParsed(conf, service.MockResources())
+	require.Error(t, err)
+}
+
+func TestBloomCacheConfigErrors(t *testing.T) {
+	for _, conf := range []string{
+		`capacity: 0`,
+		`false_positive_rate: 1`,
+		`false_positive_rate: 0`,
+		`generations: 0`,
+	} {
+		pConf, err := bloomCacheSpec().ParseYAML(conf, nil)
+		require.NoError(t, err)
+
+		_, err = newBloomCacheFromParsed(pConf, service.MockResources())
+		assert.Error(t, err, conf)
+	}
+}
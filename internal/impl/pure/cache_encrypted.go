@@ -0,0 +1,143 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+
+	"github.com/redpanda-data/connect/v4/internal/encryption"
+)
+
+const (
+	ecFieldResource   = "resource"
+	ecFieldEncryption = "encryption"
+)
+
+func encryptedCacheSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Version("4.45.0").
+		Summary("Encrypts values with AES-GCM before storing them in another cache resource, and decrypts them when they are read.").
+		Description(`
+This cache wraps another xref:components:caches/about.adoc[cache resource], such as a cache that persists data to disk, in order to ensure that values are not stored in plaintext. Keys are stored unencrypted and are authenticated along with each value, so a value cannot be read back under a different key than the one it was stored with.
+
+Values that were stored in the wrapped cache without encryption, or with a different key, result in an error when read.`).
+		Fields(
+			service.NewStringField(ecFieldResource).
+				Description("The name of the cache resource to store encrypted values in."),
+			encryption.AESGCMField(ecFieldEncryption),
+		).
+		Example("Encrypted Redis Cache", "Store values in a Redis cache encrypted with a key obtained from an environment variable.", `
+cache_resources:
+  - label: encrypted
+    encrypted:
+      resource: plain
+      encryption:
+        key: ${CACHE_KEY}
+  - label: plain
+    redis:
+      url: redis://localhost:6379
+`)
+}
+
+func init() {
+	err := service.RegisterCache("encrypted", encryptedCacheSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Cache, error) {
+			return newEncryptedCacheFromParsed(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type encryptedCache struct {
+	resource string
+	envelope *encryption.Envelope
+	mgr      *service.Resources
+}
+
+func newEncryptedCacheFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*encryptedCache, error) {
+	c := &encryptedCache{mgr: mgr}
+
+	var err error
+	if c.resource, err = conf.FieldString(ecFieldResource); err != nil {
+		return nil, err
+	}
+	if c.envelope, err = encryption.AESGCMFromParsed(conf.Namespace(ecFieldEncryption)); err != nil {
+		return nil, err
+	}
+	if c.envelope == nil {
+		return nil, fmt.Errorf("field %v.key must be set", ecFieldEncryption)
+	}
+	return c, nil
+}
+
+func (c *encryptedCache) access(ctx context.Context, fn func(cache service.Cache) error) error {
+	var cErr error
+	if err := c.mgr.AccessCache(ctx, c.resource, func(cache service.Cache) {
+		cErr = fn(cache)
+	}); err != nil {
+		return err
+	}
+	return cErr
+}
+
+func (c *encryptedCache) Get(ctx context.Context, key string) (value []byte, err error) {
+	err = c.access(ctx, func(cache service.Cache) error {
+		sealed, err := cache.Get(ctx, key)
+		if err != nil {
+			return err
+		}
+		value, err = c.envelope.Open(sealed, []byte(key))
+		return err
+	})
+	return
+}
+
+func (c *encryptedCache) Set(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
+	sealed, err := c.envelope.Seal(value, []byte(key))
+	if err != nil {
+		return err
+	}
+	return c.access(ctx, func(cache service.Cache) error {
+		return cache.Set(ctx, key, sealed, ttl)
+	})
+}
+
+func (c *encryptedCache) Add(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
+	sealed, err := c.envelope.Seal(value, []byte(key))
+	if err != nil {
+		return err
+	}
+	return c.access(ctx, func(cache service.Cache) error {
+		return cache.Add(ctx, key, sealed, ttl)
+	})
+}
+
+func (c *encryptedCache) Delete(ctx context.Context, key string) error {
+	return c.access(ctx, func(cache service.Cache) error {
+		return cache.Delete(ctx, key)
+	})
+}
+
+func (c *encryptedCache) Close(context.Context) error {
+	return nil
+}
@@ -0,0 +1,79 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func TestEncryptedCache(t *testing.T) {
+	ctx := context.Background()
+	mgr := service.MockResources(service.MockResourcesOptAddCache("plain"))
+
+	pConf, err := encryptedCacheSpec().ParseYAML(`
+resource: plain
+encryption:
+  key: 000102030405060708090a0b0c0d0e0f
+`, nil)
+	require.NoError(t, err)
+
+	c, err := newEncryptedCacheFromParsed(pConf, mgr)
+	require.NoError(t, err)
+
+	require.NoError(t, c.Set(ctx, "foo", []byte("hello world"), nil))
+	require.NoError(t, c.Add(ctx, "bar", []byte("bar value"), nil))
+
+	v, err := c.Get(ctx, "foo")
+	require.NoError(t, err)
+	assert.Equal(t, "hello world", string(v))
+
+	v, err = c.Get(ctx, "bar")
+	require.NoError(t, err)
+	assert.Equal(t, "bar value", string(v))
+
+	var stored []byte
+	require.NoError(t, mgr.AccessCache(ctx, "plain", func(cache service.Cache) {
+		stored, err = cache.Get(ctx, "foo")
+		require.NoError(t, err)
+
+		// Values moved to a different key can not be decrypted.
+		require.NoError(t, cache.Set(ctx, "baz", stored, nil))
+	}))
+	assert.NotContains(t, string(stored), "hello world")
+
+	_, err = c.Get(ctx, "baz")
+	require.Error(t, err)
+
+	require.NoError(t, c.Delete(ctx, "foo"))
+	_, err = c.Get(ctx, "foo")
+	assert.True(t, errors.Is(err, service.ErrKeyNotFound), err)
+}
+
+func TestEncryptedCacheRequiresKey(t *testing.T) {
+	pConf, err := encryptedCacheSpec().ParseYAML(`
+resource: plain
+`, nil)
+	require.NoError(t, err)
+
+	_, err = newEncryptedCacheFromParsed(pConf, service.MockResources())
+	require.Error(t, err)
+}
@@ -0,0 +1,382 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/shutdown"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	tcFieldBack              = "back"
+	tcFieldFrontSize         = "front_size"
+	tcFieldFrontTTL          = "front_ttl"
+	tcFieldWritePolicy       = "write_policy"
+	tcFieldWriteBackInterval = "write_back_interval"
+	tcFieldNegativeTTL       = "negative_ttl"
+)
+
+func tieredCacheSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Version("4.45.0").
+		Summary("Places an in-memory LRU cache in front of another cache resource, such as a remote Redis or memcached cache, in order to serve repeated lookups without a network round trip.").
+		Description(`
+Values read from or written to the `+"`"+tcFieldBack+"`"+` cache are also stored in memory, where the least recently used of them are evicted once there are more than `+"`"+tcFieldFrontSize+"`"+`. Values are held in memory for at most `+"`"+tcFieldFrontTTL+"`"+`, or the TTL they were written with if it is shorter, after which they are read from the back cache again. Since other processes may modify the back cache, the front TTL limits how stale a value read from memory can be.
+
+== Write Policies
+
+With the `+"`write_through`"+` policy writes are made to the back cache before they are acknowledged. With the `+"`write_back`"+` policy values are written to memory and acknowledged immediately, and written to the back cache in the background every `+"`"+tcFieldWriteBackInterval+"`"+`, where only the latest value of a key is written. Writes that fail in the background are logged and dropped, and writes that are pending when the cache is closed are flushed, therefore `+"`write_back`"+` trades durability for latency and is only suitable for values that can be recalculated, such as the results of enrichment lookups.
+
+Adding a value that must not already exist, and deleting a value, are always made to the back cache immediately.
+
+== Negative Caching
+
+When `+"`"+tcFieldNegativeTTL+"`"+` is greater than zero, keys that are not found within the back cache are remembered as missing for that duration, so that repeated lookups of keys that do not exist are also served from memory.`).
+		Fields(
+			service.NewStringField(tcFieldBack).
+				Description("The name of the cache resource to place the in-memory cache in front of."),
+			service.NewIntField(tcFieldFrontSize).
+				Description("The maximum number of keys to hold in memory.").
+				Default(1000),
+			service.NewDurationField(tcFieldFrontTTL).
+				Description("The maximum duration to hold a value in memory before reading it from the back cache again.").
+				Default("1m"),
+			service.NewStringAnnotatedEnumField(tcFieldWritePolicy, map[string]string{
+				"write_through": "Writes are made to the back cache before they are acknowledged.",
+				"write_back":    "Writes are acknowledged once stored in memory, and made to the back cache in the background.",
+			}).
+				Description("How writes are made to the back cache.").
+				Default("write_through"),
+			service.NewDurationField(tcFieldWriteBackInterval).
+				Description("The interval at which pending writes are made to the back cache with the `write_back` policy.").
+				Default("100ms").
+				Advanced(),
+			service.NewDurationField(tcFieldNegativeTTL).
+				Description("The duration to remember that a key does not exist within the back cache, where zero disables negative caching.").
+				Default("0s"),
+		).
+		Example("Enrichment Lookups", "Serve repeated lookups of customer records from memory, and remember customers that do not exist for ten seconds.", `
+pipeline:
+  processors:
+    - branch:
+        request_map: root = ""
+        processors:
+          - cache:
+              resource: customers
+              operator: get
+              key: ${! this.customer_id }
+        result_map: root.customer = this
+
+cache_resources:
+  - label: customers
+    tiered:
+      back: customers_redis
+      front_size: 10000
+      front_ttl: 30s
+      negative_ttl: 10s
+  - label: customers_redis
+    redis:
+      url: redis://localhost:6379
+`)
+}
+
+func init() {
+	err := service.RegisterCache("tiered", tieredCacheSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Cache, error) {
+			return newTieredCacheFromParsed(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type tieredEntry struct {
+	key      string
+	value    []byte
+	negative bool
+	expires  time.Time
+}
+
+// tieredLRU is a size bounded map that evicts its least recently used keys.
+type tieredLRU struct {
+	mut     sync.Mutex
+	size    int
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+func newTieredLRU(size int) *tieredLRU {
+	return &tieredLRU{
+		size:    size,
+		order:   list.New(),
+		entries: map[string]*list.Element{},
+	}
+}
+
+func (l *tieredLRU) get(key string) (tieredEntry, bool) {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+
+	e, exists := l.entries[key]
+	if !exists {
+		return tieredEntry{}, false
+	}
+	l.order.MoveToFront(e)
+	return e.Value.(tieredEntry), true
+}
+
+func (l *tieredLRU) add(entry tieredEntry) {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+
+	if e, exists := l.entries[entry.key]; exists {
+		e.Value = entry
+		l.order.MoveToFront(e)
+		return
+	}
+	l.entries[entry.key] = l.order.PushFront(entry)
+	for l.order.Len() > l.size {
+		oldest := l.order.Back()
+		l.order.Remove(oldest)
+		delete(l.entries, oldest.Value.(tieredEntry).key)
+	}
+}
+
+func (l *tieredLRU) remove(key string) {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+
+	if e, exists := l.entries[key]; exists {
+		l.order.Remove(e)
+		delete(l.entries, key)
+	}
+}
+
+type tieredWrite struct {
+	value []byte
+	ttl   *time.Duration
+}
+
+type tieredCache struct {
+	back        string
+	frontTTL    time.Duration
+	negativeTTL time.Duration
+	writeBack   bool
+
+	front *tieredLRU
+
+	pendingMut sync.Mutex
+	pending    map[string]tieredWrite
+	shutSig    *shutdown.Signaller
+
+	mgr *service.Resources
+	log *service.Logger
+
+	nowFn func() time.Time
+}
+
+func newTieredCacheFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*tieredCache, error) {
+	c := &tieredCache{
+		pending: map[string]tieredWrite{},
+		shutSig: shutdown.NewSignaller(),
+		mgr:     mgr,
+		log:     mgr.Logger(),
+		nowFn:   time.Now,
+	}
+
+	var err error
+	if c.back, err = conf.FieldString(tcFieldBack); err != nil {
+		return nil, err
+	}
+	frontSize, err := conf.FieldInt(tcFieldFrontSize)
+	if err != nil {
+		return nil, err
+	}
+	if frontSize <= 0 {
+		return nil, fmt.Errorf("%v must be greater than zero", tcFieldFrontSize)
+	}
+	c.front = newTieredLRU(frontSize)
+	if c.frontTTL, err = conf.FieldDuration(tcFieldFrontTTL); err != nil {
+		return nil, err
+	}
+	if c.frontTTL <= 0 {
+		return nil, fmt.Errorf("%v must be greater than zero", tcFieldFrontTTL)
+	}
+	if c.negativeTTL, err = conf.FieldDuration(tcFieldNegativeTTL); err != nil {
+		return nil, err
+	}
+	policy, err := conf.FieldString(tcFieldWritePolicy)
+	if err != nil {
+		return nil, err
+	}
+	c.writeBack = policy == "write_back"
+
+	interval, err := conf.FieldDuration(tcFieldWriteBackInterval)
+	if err != nil {
+		return nil, err
+	}
+	if c.writeBack {
+		if interval <= 0 {
+			return nil, fmt.Errorf("%v must be greater than zero", tcFieldWriteBackInterval)
+		}
+		go c.writeBackLoop(interval)
+	} else {
+		c.shutSig.TriggerHasStopped()
+	}
+	return c, nil
+}
+
+func (c *tieredCache) access(ctx context.Context, fn func(cache service.Cache) error) error {
+	var cErr error
+	if err := c.mgr.AccessCache(ctx, c.back, func(cache service.Cache) {
+		cErr = fn(cache)
+	}); err != nil {
+		return err
+	}
+	return cErr
+}
+
+func (c *tieredCache) store(key string, value []byte, ttl *time.Duration) {
+	d := c.frontTTL
+	if ttl != nil && *ttl > 0 && *ttl < d {
+		d = *ttl
+	}
+	c.front.add(tieredEntry{key: key, value: value, expires: c.nowFn().Add(d)})
+}
+
+func (c *tieredCache) Get(ctx context.Context, key string) ([]byte, error) {
+	if e, ok := c.front.get(key); ok {
+		if c.nowFn().Before(e.expires) {
+			if e.negative {
+				return nil, service.ErrKeyNotFound
+			}
+			return e.value, nil
+		}
+		c.front.remove(key)
+	}
+
+	var value []byte
+	err := c.access(ctx, func(cache service.Cache) error {
+		var err error
+		value, err = cache.Get(ctx, key)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrKeyNotFound) && c.negativeTTL > 0 {
+			c.front.add(tieredEntry{key: key, negative: true, expires: c.nowFn().Add(c.negativeTTL)})
+		}
+		return nil, err
+	}
+	c.store(key, value, nil)
+	return value, nil
+}
+
+func (c *tieredCache) Set(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
+	if c.writeBack {
+		c.pendingMut.Lock()
+		c.pending[key] = tieredWrite{value: value, ttl: ttl}
+		c.pendingMut.Unlock()
+		c.store(key, value, ttl)
+		return nil
+	}
+
+	if err := c.access(ctx, func(cache service.Cache) error {
+		return cache.Set(ctx, key, value, ttl)
+	}); err != nil {
+		// The value held in memory may no longer reflect the back cache.
+		c.front.remove(key)
+		return err
+	}
+	c.store(key, value, ttl)
+	return nil
+}
+
+func (c *tieredCache) Add(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
+	if err := c.access(ctx, func(cache service.Cache) error {
+		return cache.Add(ctx, key, value, ttl)
+	}); err != nil {
+		return err
+	}
+	c.store(key, value, ttl)
+	return nil
+}
+
+func (c *tieredCache) Delete(ctx context.Context, key string) error {
+	c.pendingMut.Lock()
+	delete(c.pending, key)
+	c.pendingMut.Unlock()
+	c.front.remove(key)
+
+	return c.access(ctx, func(cache service.Cache) error {
+		return cache.Delete(ctx, key)
+	})
+}
+
+func (c *tieredCache) flush(ctx context.Context) {
+	c.pendingMut.Lock()
+	pending := c.pending
+	c.pending = map[string]tieredWrite{}
+	c.pendingMut.Unlock()
+
+	for key, w := range pending {
+		if err := c.access(ctx, func(cache service.Cache) error {
+			return cache.Set(ctx, key, w.value, w.ttl)
+		}); err != nil {
+			c.log.Errorf("Failed to write key %v to back cache: %v", key, err)
+		}
+	}
+}
+
+func (c *tieredCache) writeBackLoop(interval time.Duration) {
+	defer c.shutSig.TriggerHasStopped()
+
+	ctx, done := c.shutSig.HardStopCtx(context.Background())
+	defer done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.flush(ctx)
+		case <-c.shutSig.SoftStopChan():
+			c.flush(ctx)
+			return
+		}
+	}
+}
+
+func (c *tieredCache) Close(ctx context.Context) error {
+	c.shutSig.TriggerSoftStop()
+	select {
+	case <-c.shutSig.HasStoppedChan():
+	case <-ctx.Done():
+		c.shutSig.TriggerHardStop()
+		return ctx.Err()
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func remoteGet(t *testing.T, mgr *service.Resources, key string) ([]byte, error) {
+	t.Helper()
+
+	var v []byte
+	var err error
+	require.NoError(t, mgr.AccessCache(context.Background(), "remote", func(cache service.Cache) {
+		v, err = cache.Get(context.Background(), key)
+	}))
+	return v, err
+}
+
+func remoteSet(t *testing.T, mgr *service.Resources, key, value string) {
+	t.Helper()
+
+	require.NoError(t, mgr.AccessCache(context.Background(), "remote", func(cache service.Cache) {
+		require.NoError(t, cache.Set(context.Background(), key, []byte(value), nil))
+	}))
+}
+
+func TestTieredCacheWriteThrough(t *testing.T) {
+	ctx := context.Background()
+	mgr := service.MockResources(service.MockResourcesOptAddCache("remote"))
+
+	pConf, err := tieredCacheSpec().ParseYAML(`
+back: remote
+front_size: 2
+front_ttl: 10s
+`, nil)
+	require.NoError(t, err)
+
+	c, err := newTieredCacheFromParsed(pConf, mgr)
+	require.NoError(t, err)
+
+	now := time.Unix(1000, 0)
+	c.nowFn = func() time.Time { return now }
+
+	defer c.Close(ctx)
+
+	require.NoError(t, c.Set(ctx, "foo", []byte("foo1"), nil))
+	v, err := remoteGet(t, mgr, "foo")
+	require.NoError(t, err)
+	assert.Equal(t, "foo1", string(v))
+
+	// Reads are served from memory until the front TTL passes.
+	remoteSet(t, mgr, "foo", "foo2")
+	v, err = c.Get(ctx, "foo")
+	require.NoError(t, err)
+	assert.Equal(t, "foo1", string(v))
+
+	now = now.Add(11 * time.Second)
+	v, err = c.Get(ctx, "foo")
+	require.NoError(t, err)
+	assert.Equal(t, "foo2", string(v))
+
+	// The least recently used key is evicted from memory.
+	require.NoError(t, c.Set(ctx, "bar", []byte("bar1"), nil))
+	require.NoError(t, c.Set(ctx, "baz", []byte("baz1"), nil))
+	remoteSet(t, mgr, "foo", "foo3")
+	v, err = c.Get(ctx, "foo")
+	require.NoError(t, err)
+	assert.Equal(t, "foo3", string(v))
+
+	require.Error(t, c.Add(ctx, "bar", []byte("bar2"), nil))
+
+	require.NoError(t, c.Delete(ctx, "bar"))
+	_, err = c.Get(ctx, "bar")
+	assert.True(t, errors.Is(err, service.ErrKeyNotFound), err)
+	_, err = remoteGet(t, mgr, "bar")
+	assert.True(t, errors.Is(err, service.ErrKeyNotFound), err)
+}
+
+func TestTieredCacheNegative(t *testing.T) {
+	ctx := context.Background()
+	mgr := service.MockResources(service.MockResourcesOptAddCache("remote"))
+
+	pConf, err := tieredCacheSpec().ParseYAML(`
+back: remote
+negative_ttl: 5s
+`, nil)
+	require.NoError(t, err)
+
+	c, err := newTieredCacheFromParsed(pConf, mgr)
+	require.NoError(t, err)
+
+	now := time.Unix(1000, 0)
+	c.nowFn = func() time.Time { return now }
+
+	defer c.Close(ctx)
+
+	_, err = c.Get(ctx, "foo")
+	assert.True(t, errors.Is(err, service.ErrKeyNotFound), err)
+
+	remoteSet(t, mgr, "foo", "foo1")
+	_, err = c.Get(ctx, "foo")
+	assert.True(t, errors.Is(err, service.ErrKeyNotFound), err)
+
+	now = now.Add(6 * time.Second)
+	v, err := c.Get(ctx, "foo")
+	require.NoError(t, err)
+	assert.Equal(t, "foo1", string(v))
+
+	// Writes replace the negative entry.
+	_, err = c.Get(ctx, "bar")
+	require.Error(t, err)
+	require.NoError(t, c.Set(ctx, "bar", []byte("bar1"), nil))
+	v, err = c.Get(ctx, "bar")
+	require.NoError(t, err)
+	assert.Equal(t, "bar1", string(v))
+}
+
+func TestTieredCacheWriteBack(t *testing.T) {
+	ctx := context.Background()
+	mgr := service.MockResources(service.MockResourcesOptAddCache("remote"))
+
+	pConf, err := tieredCacheSpec().ParseYAML(`
+back: remote
+write_policy: write_back
+write_back_interval: 1h
+`, nil)
+	require.NoError(t, err)
+
+	c, err := newTieredCacheFromParsed(pConf, mgr)
+	require.NoError(t, err)
+
+	require.NoError(t, c.Set(ctx, "foo", []byte("foo1"), nil))
+	require.NoError(t, c.Set(ctx, "foo", []byte("foo2"), nil))
+	require.NoError(t, c.Set(ctx, "bar", []byte("bar1"), nil))
+	require.NoError(t, c.Delete(ctx, "bar"))
+
+	v, err := c.Get(ctx, "foo")
+	require.NoError(t, err)
+	assert.Equal(t, "foo2", string(v))
+
+	_, err = remoteGet(t, mgr, "foo")
+	assert.True(t, errors.Is(err, service.ErrKeyNotFound), err)
+
+	// Pending writes are flushed on close.
+	require.NoError(t, c.Close(ctx))
+
+	v, err = remoteGet(t, mgr, "foo")
+	require.NoError(t, err)
+	assert.Equal(t, "foo2", string(v))
+
+	_, err = remoteGet(t, mgr, "bar")
+	assert.True(t, errors.Is(err, service.ErrKeyNotFound), err)
+}
@@ -0,0 +1,319 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	cbFieldErrorRate        = "error_rate"
+	cbFieldMinRequests      = "min_requests"
+	cbFieldWindow           = "window"
+	cbFieldOpenDuration     = "open_duration"
+	cbFieldHalfOpenRequests = "half_open_requests"
+	cbFieldWhenOpen         = "when_open"
+
+	cbWhenOpenReject = "reject"
+	cbWhenOpenWait   = "wait"
+)
+
+// The number of buckets that the rolling window of a circuit breaker is split
+// into.
+const cbWindowBuckets = 10
+
+var errCircuitOpen = errors.New("circuit breaker is open")
+
+func circuitBreakerDescription(subject string) string {
+	return `
+The breaker starts closed, and counts the ` + subject + ` that succeed and fail within a rolling ` + "`" + cbFieldWindow + "`" + `. Once at least ` + "`" + cbFieldMinRequests + "`" + ` have been counted within the window and the fraction that failed reaches ` + "`" + cbFieldErrorRate + "`" + ` the breaker opens.
+
+While open no ` + subject + ` are attempted, and they are instead handled according to ` + "`" + cbFieldWhenOpen + "`" + `. After ` + "`" + cbFieldOpenDuration + "`" + ` the breaker becomes half-open and allows ` + "`" + cbFieldHalfOpenRequests + "`" + ` trial attempts through. When all of the trials succeed the breaker closes again, and when any of them fails it opens again for another ` + "`" + cbFieldOpenDuration + "`" + `.
+
+== Metrics
+
+This component emits a gauge ` + "`circuit_breaker_state`" + ` which is set to 0 while the breaker is closed, 1 while it is half-open and 2 while it is open, a counter ` + "`circuit_breaker_opened`" + ` which is incremented each time the breaker opens, and a counter ` + "`circuit_breaker_rejected`" + ` which is incremented each time an attempt is rejected while the breaker is open.`
+}
+
+func circuitBreakerFields(rejectDesc string) []*service.ConfigField {
+	return []*service.ConfigField{
+		service.NewFloatField(cbFieldErrorRate).
+			Description("The fraction of attempts within the window that must fail for the breaker to open, between zero and one.").
+			Default(0.5),
+		service.NewIntField(cbFieldMinRequests).
+			Description("The minimum number of attempts within the window before the error rate is evaluated.").
+			Default(10),
+		service.NewDurationField(cbFieldWindow).
+			Description("The rolling period of time over which attempts are counted.").
+			Default("10s"),
+		service.NewDurationField(cbFieldOpenDuration).
+			Description("The period of time for which the breaker stays open before allowing trial attempts.").
+			Default("30s"),
+		service.NewIntField(cbFieldHalfOpenRequests).
+			Description("The number of trial attempts that must succeed while half-open for the breaker to close.").
+			Default(3).
+			Advanced(),
+		service.NewStringAnnotatedEnumField(cbFieldWhenOpen, map[string]string{
+			cbWhenOpenReject: rejectDesc,
+			cbWhenOpenWait:   "Block until the breaker allows attempts again, which applies backpressure upstream.",
+		}).
+			Description("How to handle attempts while the breaker is open.").
+			Default(cbWhenOpenReject),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type cbState int
+
+const (
+	cbStateClosed cbState = iota
+	cbStateHalfOpen
+	cbStateOpen
+)
+
+type cbBucket struct {
+	start  time.Time
+	total  int
+	failed int
+}
+
+type circuitBreaker struct {
+	errorRate        float64
+	minRequests      int
+	window           time.Duration
+	openDuration     time.Duration
+	halfOpenRequests int
+	wait             bool
+	nowFn            func() time.Time
+
+	mState    *service.MetricGauge
+	mOpened   *service.MetricCounter
+	mRejected *service.MetricCounter
+	log       *service.Logger
+
+	mut            sync.Mutex
+	state          cbState
+	buckets        []cbBucket
+	openUntil      time.Time
+	trialsInFlight int
+	trialsPassed   int
+	changed        chan struct{}
+}
+
+func newCircuitBreaker(conf *service.ParsedConfig, mgr *service.Resources) (*circuitBreaker, error) {
+	c := &circuitBreaker{
+		nowFn:     time.Now,
+		mState:    mgr.Metrics().NewGauge("circuit_breaker_state"),
+		mOpened:   mgr.Metrics().NewCounter("circuit_breaker_opened"),
+		mRejected: mgr.Metrics().NewCounter("circuit_breaker_rejected"),
+		log:       mgr.Logger(),
+		changed:   make(chan struct{}),
+	}
+
+	var err error
+	if c.errorRate, err = conf.FieldFloat(cbFieldErrorRate); err != nil {
+		return nil, err
+	}
+	if c.minRequests, err = conf.FieldInt(cbFieldMinRequests); err != nil {
+		return nil, err
+	}
+	if c.window, err = conf.FieldDuration(cbFieldWindow); err != nil {
+		return nil, err
+	}
+	if c.openDuration, err = conf.FieldDuration(cbFieldOpenDuration); err != nil {
+		return nil, err
+	}
+	if c.halfOpenRequests, err = conf.FieldInt(cbFieldHalfOpenRequests); err != nil {
+		return nil, err
+	}
+	whenOpen, err := conf.FieldString(cbFieldWhenOpen)
+	if err != nil {
+		return nil, err
+	}
+	c.wait = whenOpen == cbWhenOpenWait
+
+	if c.errorRate <= 0 || c.errorRate > 1 {
+		return nil, fmt.Errorf("%v must be greater than zero and no more than one", cbFieldErrorRate)
+	}
+	if c.window <= 0 {
+		return nil, fmt.Errorf("%v must be greater than zero", cbFieldWindow)
+	}
+	if c.halfOpenRequests <= 0 {
+		return nil, fmt.Errorf("%v must be greater than zero", cbFieldHalfOpenRequests)
+	}
+	c.mState.Set(int64(cbStateClosed))
+	return c, nil
+}
+
+// setState must be called with the lock held.
+func (c *circuitBreaker) setState(s cbState) {
+	if c.state == s {
+		return
+	}
+	c.state = s
+	c.mState.Set(int64(s))
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+// open must be called with the lock held.
+func (c *circuitBreaker) open(now time.Time, err error) {
+	c.log.Warnf("Circuit breaker opening for %v: %v", c.openDuration, err)
+	c.mOpened.Incr(1)
+	c.openUntil = now.Add(c.openDuration)
+	c.trialsInFlight, c.trialsPassed = 0, 0
+	c.buckets = nil
+	c.setState(cbStateOpen)
+}
+
+// prune removes buckets that have fallen out of the window, and must be called
+// with the lock held.
+func (c *circuitBreaker) prune(now time.Time) {
+	i := 0
+	for i < len(c.buckets) && now.Sub(c.buckets[i].start) >= c.window {
+		i++
+	}
+	c.buckets = c.buckets[i:]
+}
+
+// count an attempt within the current bucket and returns the attempts counted
+// within the window, and must be called with the lock held.
+func (c *circuitBreaker) count(now time.Time, failed bool) (total, failedTotal int) {
+	c.prune(now)
+
+	bucketSize := c.window / cbWindowBuckets
+	if n := len(c.buckets); n == 0 || now.Sub(c.buckets[n-1].start) >= bucketSize {
+		c.buckets = append(c.buckets, cbBucket{start: now})
+	}
+	b := &c.buckets[len(c.buckets)-1]
+	b.total++
+	if failed {
+		b.failed++
+	}
+
+	for _, b := range c.buckets {
+		total += b.total
+		failedTotal += b.failed
+	}
+	return
+}
+
+// acquire waits until the breaker allows an attempt, and returns a function to
+// be called with the result of the attempt. Returns errCircuitOpen when the
+// breaker is open and attempts are rejected.
+func (c *circuitBreaker) acquire(ctx context.Context) (func(error), error) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	for {
+		now := c.nowFn()
+		if c.state == cbStateOpen && !now.Before(c.openUntil) {
+			c.log.Info("Circuit breaker half-open, allowing trial attempts")
+			c.setState(cbStateHalfOpen)
+		}
+
+		switch c.state {
+		case cbStateClosed:
+			return c.releaseFn(false), nil
+		case cbStateHalfOpen:
+			if c.trialsInFlight+c.trialsPassed < c.halfOpenRequests {
+				c.trialsInFlight++
+				return c.releaseFn(true), nil
+			}
+		}
+
+		if !c.wait {
+			c.mRejected.Incr(1)
+			return nil, errCircuitOpen
+		}
+
+		// Wait for the open duration to pass, or for the trials of a
+		// half-open breaker to complete.
+		changed := c.changed
+		var timer *time.Timer
+		var timerC <-chan time.Time
+		if c.state == cbStateOpen {
+			timer = time.NewTimer(c.openUntil.Sub(now))
+			timerC = timer.C
+		}
+
+		c.mut.Unlock()
+		select {
+		case <-changed:
+		case <-timerC:
+		case <-ctx.Done():
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		c.mut.Lock()
+
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (c *circuitBreaker) releaseFn(trial bool) func(error) {
+	var once sync.Once
+	return func(err error) {
+		once.Do(func() {
+			c.release(trial, err)
+		})
+	}
+}
+
+func (c *circuitBreaker) release(trial bool, err error) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	now := c.nowFn()
+	cancelled := errors.Is(err, context.Canceled)
+
+	if trial {
+		if c.state != cbStateHalfOpen {
+			return
+		}
+		c.trialsInFlight--
+		switch {
+		case cancelled:
+		case err != nil:
+			c.open(now, err)
+		default:
+			c.trialsPassed++
+			if c.trialsPassed >= c.halfOpenRequests {
+				c.log.Info("Circuit breaker closing after successful trial attempts")
+				c.trialsPassed = 0
+				c.setState(cbStateClosed)
+			}
+		}
+		return
+	}
+
+	if c.state != cbStateClosed || cancelled {
+		return
+	}
+	total, failed := c.count(now, err != nil)
+	if err != nil && total >= c.minRequests && float64(failed)/float64(total) >= c.errorRate {
+		c.open(now, err)
+	}
+}
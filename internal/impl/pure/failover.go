@@ -0,0 +1,283 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	foFieldHealthCheck      = "health_check"
+	foFieldCheckPeriod      = "check_period"
+	foFieldFailureThreshold = "failure_threshold"
+	foFieldFailbackDelay    = "failback_delay"
+)
+
+func failoverCommonFields() []*service.ConfigField {
+	return []*service.ConfigField{
+		service.NewDurationField(foFieldCheckPeriod).
+			Description("The period at which the health checks of each child are executed. Each check must complete within this period or it is considered failed.").
+			Default("10s"),
+		service.NewIntField(foFieldFailureThreshold).
+			Description("The number of consecutive failures after which a child is considered unhealthy.").
+			Default(3),
+		service.NewDurationField(foFieldFailbackDelay).
+			Description("The period of time that a higher priority child must remain healthy after recovering before traffic is switched back to it, which prevents flapping between children.").
+			Default("1m"),
+	}
+}
+
+func failoverHealthCheckField() *service.ConfigField {
+	return service.NewProcessorListField(foFieldHealthCheck).
+		Description("An optional list of processors that are executed with an empty message at each `check_period`. A check fails when it returns an error or results in a message flagged with an error, and the check succeeds otherwise.").
+		Optional()
+}
+
+//------------------------------------------------------------------------------
+
+type failoverChildState struct {
+	label      string
+	hasChecks  bool
+	failures   int
+	unhealthy  bool
+	ended      bool
+	eligibleAt time.Time
+}
+
+// failoverHealth tracks the health of an ordered list of children and selects
+// the active child, which is the highest priority child that is healthy.
+type failoverHealth struct {
+	threshold     int
+	failbackDelay time.Duration
+	nowFn         func() time.Time
+
+	mActive   *service.MetricGauge
+	mSwitches *service.MetricCounter
+	log       *service.Logger
+
+	mut      sync.Mutex
+	children []*failoverChildState
+	active   int
+	changed  chan struct{}
+}
+
+func newFailoverHealth(conf *service.ParsedConfig, mgr *service.Resources, labels []string, hasChecks []bool) (*failoverHealth, error) {
+	f := &failoverHealth{
+		nowFn:     time.Now,
+		mActive:   mgr.Metrics().NewGauge("failover_active_index"),
+		mSwitches: mgr.Metrics().NewCounter("failover_switched"),
+		log:       mgr.Logger(),
+		changed:   make(chan struct{}),
+	}
+
+	var err error
+	if f.threshold, err = conf.FieldInt(foFieldFailureThreshold); err != nil {
+		return nil, err
+	}
+	if f.failbackDelay, err = conf.FieldDuration(foFieldFailbackDelay); err != nil {
+		return nil, err
+	}
+	for i, l := range labels {
+		f.children = append(f.children, &failoverChildState{
+			label:     l,
+			hasChecks: hasChecks[i],
+		})
+	}
+	f.mActive.Set(0)
+	return f, nil
+}
+
+// current returns the index of the active child along with a channel that is
+// closed once the active child changes.
+func (f *failoverHealth) current() (int, <-chan struct{}) {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	f.reselectLocked()
+	return f.active, f.changed
+}
+
+// candidates returns the active child followed by every other child that is
+// healthy, in priority order.
+func (f *failoverHealth) candidates() []int {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	f.reselectLocked()
+
+	indexes := []int{f.active}
+	for i, c := range f.children {
+		if i == f.active || c.ended || c.unhealthy {
+			continue
+		}
+		indexes = append(indexes, i)
+	}
+	return indexes
+}
+
+func (f *failoverHealth) reselect() {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	f.reselectLocked()
+}
+
+// record the result of a health check or write made against a child.
+func (f *failoverHealth) record(i int, err error) {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	c := f.children[i]
+	if err == nil {
+		c.failures = 0
+		if c.unhealthy {
+			f.log.Infof("Child %v has recovered", c.label)
+			c.unhealthy = false
+			c.eligibleAt = f.nowFn().Add(f.failbackDelay)
+		}
+	} else {
+		c.failures++
+		if !c.unhealthy && f.threshold > 0 && c.failures >= f.threshold {
+			f.log.Warnf("Child %v failed %v consecutive times and is now considered unhealthy: %v", c.label, c.failures, err)
+			c.unhealthy = true
+			// Children without health checks have no way to recover other
+			// than being tried again, which happens after the failback delay.
+			c.eligibleAt = f.nowFn().Add(f.failbackDelay)
+		}
+	}
+	f.reselectLocked()
+}
+
+// markEnded flags a child as having no more data to give, and returns true if
+// all children have now ended.
+func (f *failoverHealth) markEnded(i int) bool {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	f.children[i].ended = true
+	f.reselectLocked()
+	for _, c := range f.children {
+		if !c.ended {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *failoverHealth) reselectLocked() {
+	now := f.nowFn()
+
+	selected := -1
+	for i, c := range f.children {
+		if c.ended {
+			continue
+		}
+		if c.unhealthy {
+			if c.hasChecks || now.Before(c.eligibleAt) {
+				continue
+			}
+			c.unhealthy = false
+			c.failures = 0
+		}
+		if i < f.active && now.Before(c.eligibleAt) {
+			continue
+		}
+		selected = i
+		break
+	}
+	if selected == -1 {
+		// Nothing is healthy, stay where we are unless the active child has
+		// ended, in which case any child with data left is better.
+		if !f.children[f.active].ended {
+			return
+		}
+		for i, c := range f.children {
+			if !c.ended {
+				selected = i
+				break
+			}
+		}
+		if selected == -1 {
+			return
+		}
+	}
+	if selected == f.active {
+		return
+	}
+
+	f.log.Warnf("Switching from child %v to child %v", f.children[f.active].label, f.children[selected].label)
+	f.active = selected
+	f.mActive.Set(int64(selected))
+	f.mSwitches.Incr(1)
+	close(f.changed)
+	f.changed = make(chan struct{})
+}
+
+//------------------------------------------------------------------------------
+
+func failoverRunHealthCheck(ctx context.Context, procs []*service.OwnedProcessor) error {
+	batches, err := service.ExecuteProcessors(ctx, procs, service.MessageBatch{service.NewMessage(nil)})
+	if err != nil {
+		return err
+	}
+	for _, b := range batches {
+		for _, m := range b {
+			if err := m.GetError(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// failoverCheckLoop executes the health checks of each child at the given
+// period until the context is cancelled.
+func failoverCheckLoop(ctx context.Context, period time.Duration, health *failoverHealth, checks [][]*service.OwnedProcessor) {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+		for i, procs := range checks {
+			if len(procs) == 0 {
+				continue
+			}
+			checkCtx, done := context.WithTimeout(ctx, period)
+			err := failoverRunHealthCheck(checkCtx, procs)
+			done()
+			if ctx.Err() != nil {
+				return
+			}
+			health.record(i, err)
+		}
+		health.reselect()
+	}
+}
+
+func failoverCloseChecks(ctx context.Context, checks [][]*service.OwnedProcessor) error {
+	for _, procs := range checks {
+		for _, p := range procs {
+			if err := p.Close(ctx); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	fiFieldInputs = "inputs"
+	fiFieldInput  = "input"
+)
+
+func failoverInputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Version("4.45.0").
+		Summary("Reads messages from the highest priority healthy input from a list, switching over to lower priority inputs when it becomes unhealthy and switching back once it recovers.").
+		Description(`
+Inputs are listed in priority order, and messages are only consumed from the active input, which is the first one that is considered healthy. Since inputs do not report failures when reading, the health of each input is determined by its `+"`health_check`"+`, and inputs without a health check are always considered healthy. An input that has reached the end of its data is skipped, and once all inputs have ended this input also ends.
+
+=== Catch Up
+
+Inputs that are not active are left connected but are not consumed from. When traffic switches back to a recovered input it resumes consumption from its last acknowledged position, and so any data that it accumulated during the outage is caught up on. Messages that were consumed from the previously active input are still acknowledged against it once delivered.
+
+When a higher priority input recovers it must remain healthy for `+"`failback_delay`"+` before consumption is switched back to it.
+
+== Metrics
+
+This input emits a gauge `+"`failover_active_index`"+` which is set to the index of the active input, and a counter `+"`failover_switched`"+` which is incremented each time the active input changes.`).
+		Fields(
+			service.NewObjectListField(fiFieldInputs,
+				service.NewInputField(fiFieldInput).
+					Description("The input to consume messages from."),
+				failoverHealthCheckField(),
+			).Description("A list of inputs in priority order, each with an optional health check."),
+		).
+		Fields(failoverCommonFields()...).
+		Example("Multi-Region Kafka", "Consume from a Kafka cluster in the local region, switching over to a replicated cluster in another region whenever the local cluster reports itself as unavailable.", `
+input:
+  failover:
+    failback_delay: 5m
+    inputs:
+      - input:
+          kafka_franz:
+            seed_brokers: [ kafka.eu-west.example.com:9092 ]
+            topics: [ events ]
+            consumer_group: events_consumer
+        health_check:
+          - http:
+              url: https://kafka.eu-west.example.com/health
+              verb: GET
+      - input:
+          kafka_franz:
+            seed_brokers: [ kafka.eu-central.example.com:9092 ]
+            topics: [ events ]
+            consumer_group: events_consumer
+`)
+}
+
+func init() {
+	err := service.RegisterBatchInput("failover", failoverInputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchInput, error) {
+			return newFailoverInputFromParsed(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type failoverInput struct {
+	inputs      []*service.OwnedInput
+	checks      [][]*service.OwnedProcessor
+	checkPeriod time.Duration
+	health      *failoverHealth
+
+	startMut sync.Mutex
+	started  bool
+
+	checksCtx  context.Context
+	checksDone func()
+	checksWG   sync.WaitGroup
+}
+
+func newFailoverInputFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*failoverInput, error) {
+	f := &failoverInput{}
+
+	var err error
+	if f.checkPeriod, err = conf.FieldDuration(foFieldCheckPeriod); err != nil {
+		return nil, err
+	}
+
+	childConfs, err := conf.FieldObjectList(fiFieldInputs)
+	if err != nil {
+		return nil, err
+	}
+	if len(childConfs) == 0 {
+		return nil, errors.New("at least one input must be specified")
+	}
+
+	var labels []string
+	var hasChecks []bool
+	for i, cConf := range childConfs {
+		var procs []*service.OwnedProcessor
+		if cConf.Contains(foFieldHealthCheck) {
+			if procs, err = cConf.FieldProcessorList(foFieldHealthCheck); err != nil {
+				return nil, fmt.Errorf("input %v: %w", i, err)
+			}
+		}
+		in, err := cConf.FieldInput(fiFieldInput)
+		if err != nil {
+			return nil, fmt.Errorf("input %v: %w", i, err)
+		}
+		f.inputs = append(f.inputs, in)
+		f.checks = append(f.checks, procs)
+		labels = append(labels, strconv.Itoa(i))
+		hasChecks = append(hasChecks, len(procs) > 0)
+	}
+
+	if f.health, err = newFailoverHealth(conf, mgr, labels, hasChecks); err != nil {
+		return nil, err
+	}
+	f.checksCtx, f.checksDone = context.WithCancel(context.Background())
+	return f, nil
+}
+
+func (f *failoverInput) Connect(ctx context.Context) error {
+	f.startMut.Lock()
+	defer f.startMut.Unlock()
+	if f.started {
+		return nil
+	}
+	f.checksWG.Add(1)
+	go func() {
+		defer f.checksWG.Done()
+		failoverCheckLoop(f.checksCtx, f.checkPeriod, f.health, f.checks)
+	}()
+	f.started = true
+	return nil
+}
+
+func (f *failoverInput) ReadBatch(ctx context.Context) (service.MessageBatch, service.AckFunc, error) {
+	for {
+		i, changed := f.health.current()
+
+		// Abandon the read as soon as the active input changes, a read that
+		// is cancelled does not consume a message from the child.
+		readCtx, cancel := context.WithCancel(ctx)
+		go func() {
+			select {
+			case <-changed:
+				cancel()
+			case <-readCtx.Done():
+			}
+		}()
+
+		batch, aFn, err := f.inputs[i].ReadBatch(readCtx)
+		cancel()
+		if err == nil {
+			return batch, aFn, nil
+		}
+		if errors.Is(err, service.ErrEndOfInput) {
+			if f.health.markEnded(i) {
+				return nil, nil, service.ErrEndOfInput
+			}
+			continue
+		}
+		if ctx.Err() != nil {
+			return nil, nil, ctx.Err()
+		}
+		if !errors.Is(err, context.Canceled) {
+			return nil, nil, err
+		}
+	}
+}
+
+func (f *failoverInput) Close(ctx context.Context) error {
+	f.checksDone()
+	f.checksWG.Wait()
+
+	errs := []error{failoverCloseChecks(ctx, f.checks)}
+	for _, in := range f.inputs {
+		errs = append(errs, in.Close(ctx))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,124 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+type constInput struct {
+	content string
+}
+
+func (c *constInput) Connect(context.Context) error { return nil }
+
+func (c *constInput) Read(ctx context.Context) (*service.Message, service.AckFunc, error) {
+	select {
+	case <-time.After(time.Millisecond):
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+	return service.NewMessage([]byte(c.content)), func(context.Context, error) error { return nil }, nil
+}
+
+func (c *constInput) Close(context.Context) error { return nil }
+
+type funcProcessor struct {
+	fn func() error
+}
+
+func (f *funcProcessor) Process(context.Context, *service.Message) (service.MessageBatch, error) {
+	return nil, f.fn()
+}
+
+func (f *funcProcessor) Close(context.Context) error { return nil }
+
+func TestFailoverInputSwitchover(t *testing.T) {
+	var primaryDown atomic.Bool
+
+	env := service.NewEnvironment()
+	for _, name := range []string{"primary", "secondary"} {
+		name := name
+		require.NoError(t, env.RegisterInput("failover_test_"+name, service.NewConfigSpec(),
+			func(*service.ParsedConfig, *service.Resources) (service.Input, error) {
+				return &constInput{content: name}, nil
+			}))
+	}
+	require.NoError(t, env.RegisterProcessor("failover_test_check", service.NewConfigSpec(),
+		func(*service.ParsedConfig, *service.Resources) (service.Processor, error) {
+			return &funcProcessor{fn: func() error {
+				if primaryDown.Load() {
+					return errors.New("primary down")
+				}
+				return nil
+			}}, nil
+		}))
+
+	pConf, err := failoverInputSpec().ParseYAML(`
+check_period: 10ms
+failure_threshold: 1
+failback_delay: 0s
+inputs:
+  - input:
+      failover_test_primary: {}
+    health_check:
+      - failover_test_check: {}
+  - input:
+      failover_test_secondary: {}
+`, env)
+	require.NoError(t, err)
+
+	f, err := newFailoverInputFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+	require.NoError(t, f.Connect(context.Background()))
+	t.Cleanup(func() {
+		ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+		defer done()
+		require.NoError(t, f.Close(ctx))
+	})
+
+	readContent := func() string {
+		ctx, done := context.WithTimeout(context.Background(), time.Second)
+		defer done()
+		batch, aFn, err := f.ReadBatch(ctx)
+		require.NoError(t, err)
+		require.NoError(t, aFn(ctx, nil))
+		require.Len(t, batch, 1)
+		b, err := batch[0].AsBytes()
+		require.NoError(t, err)
+		return string(b)
+	}
+
+	assert.Equal(t, "primary", readContent())
+
+	primaryDown.Store(true)
+	assert.Eventually(t, func() bool {
+		return readContent() == "secondary"
+	}, time.Second*5, time.Millisecond*10)
+
+	primaryDown.Store(false)
+	assert.Eventually(t, func() bool {
+		return readContent() == "primary"
+	}, time.Second*5, time.Millisecond*10)
+}
@@ -0,0 +1,163 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"errors"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+
+	"github.com/redpanda-data/connect/v4/internal/pausing"
+)
+
+const (
+	paFieldInput  = "input"
+	paFieldOutput = "output"
+	paFieldName   = "name"
+	paFieldPaused = "paused"
+)
+
+func pausableDescription() string {
+	return `
+The component can be paused and resumed by name via the admin endpoints served when the ` + "`--admin-address`" + ` run flag is set, where ` + "`POST /admin/pause/{name}`" + ` pauses it, ` + "`POST /admin/resume/{name}`" + ` resumes it and ` + "`GET /admin/pause`" + ` lists the state of each pausable component. A pause request responds once in flight messages have drained, or fails with a 504 status once the query parameter ` + "`timeout`" + ` (defaulting to 30s) has elapsed, in which case the component remains paused. Components that share a name are paused and resumed together.
+
+When a token is set with the ` + "`--admin-token`" + ` run flag these requests must provide it as a bearer token within the ` + "`Authorization`" + ` header.`
+}
+
+func pausableFields() []*service.ConfigField {
+	return []*service.ConfigField{
+		service.NewStringField(paFieldName).
+			Description("The name used to pause and resume this component, which defaults to its label.").
+			Default(""),
+		service.NewBoolField(paFieldPaused).
+			Description("Whether the component starts paused.").
+			Default(false).
+			Advanced(),
+	}
+}
+
+func pausableGateFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*pausing.Gate, error) {
+	name, err := conf.FieldString(paFieldName)
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		if name = mgr.Label(); name == "" {
+			return nil, errors.New("a name or label must be specified")
+		}
+	}
+	paused, err := conf.FieldBool(paFieldPaused)
+	if err != nil {
+		return nil, err
+	}
+
+	g := pausing.FromResources(mgr).Gate(name)
+	if paused {
+		g.Pause()
+	}
+	return g, nil
+}
+
+func pausableInputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Version("4.45.0").
+		Summary("Wraps an input so that it can be paused and resumed at runtime, in order to hold ingestion during downstream maintenance without stopping the process.").
+		Description(`
+Whilst paused no messages are read from the child input, and a pending read is abandoned. Messages that were already read continue through the pipeline, and the pause is considered drained once all of them have been acknowledged. The child input remains connected whilst paused and so, for example, a Kafka consumer keeps its partitions.
+`+pausableDescription()).
+		Fields(
+			service.NewInputField(paFieldInput).
+				Description("The input to read messages from."),
+		).
+		Fields(pausableFields()...).
+		Example("Holding Ingestion", "Consume from Kafka, allowing ingestion to be paused with `curl -X POST -H \"Authorization: Bearer $TOKEN\" http://localhost:4197/admin/pause/orders`.", `
+input:
+  pausable:
+    name: orders
+    input:
+      kafka_franz:
+        seed_brokers: [ localhost:9092 ]
+        topics: [ orders ]
+        consumer_group: orders_pipeline
+`)
+}
+
+func init() {
+	err := service.RegisterBatchInput("pausable", pausableInputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchInput, error) {
+			return newPausableInputFromParsed(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type pausableInput struct {
+	input *service.OwnedInput
+	gate  *pausing.Gate
+}
+
+func newPausableInputFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*pausableInput, error) {
+	p := &pausableInput{}
+
+	var err error
+	if p.gate, err = pausableGateFromParsed(conf, mgr); err != nil {
+		return nil, err
+	}
+	if p.input, err = conf.FieldInput(paFieldInput); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *pausableInput) Connect(ctx context.Context) error {
+	return nil
+}
+
+func (p *pausableInput) ReadBatch(ctx context.Context) (service.MessageBatch, service.AckFunc, error) {
+	for {
+		if err := p.gate.Enter(ctx); err != nil {
+			return nil, nil, err
+		}
+
+		// A read that is cancelled does not consume a message from the child,
+		// and so pending reads are abandoned once paused.
+		readCtx, cancel := p.gate.WithPause(ctx)
+		batch, aFn, err := p.input.ReadBatch(readCtx)
+		paused := readCtx.Err() != nil && ctx.Err() == nil
+		cancel()
+
+		if err != nil {
+			p.gate.Done()
+			if paused && errors.Is(err, context.Canceled) {
+				continue
+			}
+			return nil, nil, err
+		}
+		return batch, func(ctx context.Context, err error) error {
+			defer p.gate.Done()
+			return aFn(ctx, err)
+		}, nil
+	}
+}
+
+func (p *pausableInput) Close(ctx context.Context) error {
+	return p.input.Close(ctx)
+}
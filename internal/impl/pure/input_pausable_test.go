@@ -0,0 +1,74 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+
+	"github.com/redpanda-data/connect/v4/internal/pausing"
+)
+
+func TestPausableInput(t *testing.T) {
+	env := service.NewEnvironment()
+	require.NoError(t, env.RegisterInput("pausable_test_input", service.NewConfigSpec(),
+		func(*service.ParsedConfig, *service.Resources) (service.Input, error) {
+			return &constInput{content: "hello"}, nil
+		}))
+
+	res := service.MockResources()
+	pConf, err := pausableInputSpec().ParseYAML(`
+name: foo
+input:
+  pausable_test_input: {}
+`, env)
+	require.NoError(t, err)
+
+	p, err := newPausableInputFromParsed(pConf, res)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, p.Close(context.Background()))
+	})
+	require.NoError(t, p.Connect(context.Background()))
+
+	batch, aFn, err := p.ReadBatch(context.Background())
+	require.NoError(t, err)
+	require.Len(t, batch, 1)
+
+	g := pausing.FromResources(res).Gate("foo")
+	g.Pause()
+
+	ctx, done := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer done()
+	_, _, err = p.ReadBatch(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// The pause drains once the message that was read is acknowledged.
+	assert.ErrorIs(t, g.WaitDrained(ctx), context.DeadlineExceeded)
+	require.NoError(t, aFn(context.Background(), nil))
+	require.NoError(t, g.WaitDrained(context.Background()))
+
+	g.Resume()
+	batch, aFn, err = p.ReadBatch(context.Background())
+	require.NoError(t, err)
+	require.Len(t, batch, 1)
+	require.NoError(t, aFn(context.Background(), nil))
+}
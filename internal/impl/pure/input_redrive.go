@@ -0,0 +1,263 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	riFieldInput           = "input"
+	riFieldPark            = "park"
+	riFieldMaxRedrives     = "max_redrives"
+	riFieldInitialInterval = "initial_interval"
+	riFieldMaxInterval     = "max_interval"
+)
+
+func redriveInputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Version("4.45.0").
+		Summary("Consumes messages from a dead letter source and feeds them back into the pipeline with a backoff, parking messages permanently once they have been redriven too many times.").
+		Description(`
+Each message consumed from the child `+"`"+riFieldInput+"`"+` is given a metadata field `+"`redrive_attempts`"+`, which is one more than the value of the same field on the message when it was consumed, or one when the field is absent. The count therefore survives a message failing again and being written back to the dead letter source, as long as the source preserves metadata, such as with Kafka headers or the `+"xref:components:outputs/dead_letter.adoc[`dead_letter` output]"+`.
+
+Messages that have been redriven fewer than `+"`"+riFieldMaxRedrives+"`"+` times are held until their backoff has passed, and are then emitted with their original contents and metadata. The backoff starts at `+"`"+riFieldInitialInterval+"`"+` and doubles with each attempt up to `+"`"+riFieldMaxInterval+"`"+`, and is measured from the `+"`dead_letter_failed_at`"+` metadata field when it is present, falling back to the time the message was consumed.
+
+Messages that have already been redriven `+"`"+riFieldMaxRedrives+"`"+` times are written to the `+"`"+riFieldPark+"`"+` output instead and are not emitted, and are acknowledged at the source once they are written to it.
+
+Messages are held in the order that they are consumed, and so a message with a long backoff also holds back those that follow it. Acknowledgements are passed through to the source, and therefore a message that fails once more is consumed again according to the semantics of the source input.
+
+== Metrics
+
+This input emits a counter `+"`redrive_parked`"+` which is incremented for each message written to the park output.`).
+		Fields(
+			service.NewInputField(riFieldInput).
+				Description("The dead letter source to consume messages from."),
+			service.NewOutputField(riFieldPark).
+				Description("The output to write messages to once they have been redriven `"+riFieldMaxRedrives+"` times."),
+			service.NewIntField(riFieldMaxRedrives).
+				Description("The maximum number of times a message is redriven before it is parked.").
+				Default(3),
+			service.NewDurationField(riFieldInitialInterval).
+				Description("The delay before a message is first redriven.").
+				Default("1s"),
+			service.NewDurationField(riFieldMaxInterval).
+				Description("The maximum delay before a message is redriven.").
+				Default("5m"),
+		).
+		Example("Kafka Redrive", "Feed messages from a dead letter topic back into an HTTP delivery pipeline, parking them in a separate topic after five attempts.", `
+input:
+  redrive:
+    max_redrives: 5
+    initial_interval: 30s
+    max_interval: 30m
+    input:
+      kafka_franz:
+        seed_brokers: [ localhost:9092 ]
+        topics: [ ingest_dlq ]
+        consumer_group: ingest_redrive
+    park:
+      kafka_franz:
+        seed_brokers: [ localhost:9092 ]
+        topic: ingest_parked
+
+output:
+  dead_letter:
+    output:
+      http_client:
+        url: https://example.com/ingest
+        verb: POST
+    dead_letter:
+      kafka_franz:
+        seed_brokers: [ localhost:9092 ]
+        topic: ingest_dlq
+`)
+}
+
+func init() {
+	err := service.RegisterBatchInput("redrive", redriveInputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchInput, error) {
+			return newRedriveInputFromParsed(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type redriveInput struct {
+	input           *service.OwnedInput
+	park            *service.OwnedOutput
+	maxRedrives     int
+	initialInterval time.Duration
+	maxInterval     time.Duration
+
+	mParked *service.MetricCounter
+	log     *service.Logger
+	nowFn   func() time.Time
+
+	primeMut sync.Mutex
+	primed   bool
+}
+
+func newRedriveInputFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*redriveInput, error) {
+	r := &redriveInput{
+		mParked: mgr.Metrics().NewCounter("redrive_parked"),
+		log:     mgr.Logger(),
+		nowFn:   time.Now,
+	}
+
+	var err error
+	if r.maxRedrives, err = conf.FieldInt(riFieldMaxRedrives); err != nil {
+		return nil, err
+	}
+	if r.initialInterval, err = conf.FieldDuration(riFieldInitialInterval); err != nil {
+		return nil, err
+	}
+	if r.maxInterval, err = conf.FieldDuration(riFieldMaxInterval); err != nil {
+		return nil, err
+	}
+	if r.maxRedrives < 0 {
+		return nil, fmt.Errorf("%v must not be negative", riFieldMaxRedrives)
+	}
+	if r.maxInterval < r.initialInterval {
+		return nil, fmt.Errorf("%v must not be less than %v", riFieldMaxInterval, riFieldInitialInterval)
+	}
+	if r.park, err = conf.FieldOutput(riFieldPark); err != nil {
+		return nil, err
+	}
+	if r.input, err = conf.FieldInput(riFieldInput); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *redriveInput) Connect(ctx context.Context) error {
+	r.primeMut.Lock()
+	defer r.primeMut.Unlock()
+	if r.primed {
+		return nil
+	}
+	if err := r.park.Prime(); err != nil {
+		return err
+	}
+	r.primed = true
+	return nil
+}
+
+func redriveAttempts(msg *service.Message) int {
+	v, exists := msg.MetaGetMut("redrive_attempts")
+	if !exists {
+		return 0
+	}
+	switch t := v.(type) {
+	case int:
+		return t
+	case int64:
+		return int(t)
+	case string:
+		i, _ := strconv.Atoi(t)
+		return i
+	}
+	return 0
+}
+
+// redriveDelay returns the delay before a message is redriven for a given
+// attempt.
+func (r *redriveInput) redriveDelay(attempt int) time.Duration {
+	delay := r.initialInterval
+	for i := 1; i < attempt && delay < r.maxInterval; i++ {
+		delay *= 2
+	}
+	return min(delay, r.maxInterval)
+}
+
+func (r *redriveInput) redriveAt(msg *service.Message, attempt int) time.Time {
+	from := r.nowFn()
+	if v, exists := msg.MetaGet("dead_letter_failed_at"); exists {
+		if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			from = t
+		}
+	}
+	return from.Add(r.redriveDelay(attempt))
+}
+
+func (r *redriveInput) ReadBatch(ctx context.Context) (service.MessageBatch, service.AckFunc, error) {
+	for {
+		batch, aFn, err := r.input.ReadBatch(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		var redrive, parked service.MessageBatch
+		var redriveAt time.Time
+		for _, msg := range batch {
+			attempt := redriveAttempts(msg) + 1
+			if attempt > r.maxRedrives {
+				parked = append(parked, msg)
+				continue
+			}
+			msg.MetaSetMut("redrive_attempts", attempt)
+			if at := r.redriveAt(msg, attempt); at.After(redriveAt) {
+				redriveAt = at
+			}
+			redrive = append(redrive, msg)
+		}
+
+		if len(parked) > 0 {
+			r.log.Warnf("Parking %v messages that have been redriven %v times", len(parked), r.maxRedrives)
+			if err := r.park.WriteBatch(ctx, parked); err != nil {
+				err = fmt.Errorf("failed to write to park output: %w", err)
+				_ = aFn(ctx, err)
+				if ctx.Err() != nil {
+					return nil, nil, ctx.Err()
+				}
+				r.log.Error(err.Error())
+				continue
+			}
+			r.mParked.Incr(int64(len(parked)))
+		}
+		if len(redrive) == 0 {
+			if err := aFn(ctx, nil); err != nil {
+				r.log.Errorf("Failed to acknowledge parked messages: %v", err)
+			}
+			continue
+		}
+
+		if wait := redriveAt.Sub(r.nowFn()); wait > 0 {
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				_ = aFn(context.Background(), ctx.Err())
+				return nil, nil, ctx.Err()
+			}
+		}
+		return redrive, aFn, nil
+	}
+}
+
+func (r *redriveInput) Close(ctx context.Context) error {
+	return errors.Join(r.input.Close(ctx), r.park.Close(ctx))
+}
@@ -0,0 +1,224 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+type sliceInput struct {
+	batches []service.MessageBatch
+	acks    chan error
+}
+
+func (s *sliceInput) Connect(context.Context) error { return nil }
+
+func (s *sliceInput) ReadBatch(ctx context.Context) (service.MessageBatch, service.AckFunc, error) {
+	if len(s.batches) == 0 {
+		return nil, nil, service.ErrEndOfInput
+	}
+	batch := s.batches[0]
+	s.batches = s.batches[1:]
+	return batch, func(_ context.Context, err error) error {
+		s.acks <- err
+		return nil
+	}, nil
+}
+
+func (s *sliceInput) Close(context.Context) error { return nil }
+
+func redriveTestEnv(t *testing.T, in *sliceInput, park *funcOutput) *service.Environment {
+	t.Helper()
+
+	env := service.NewEnvironment()
+	require.NoError(t, env.RegisterBatchInput("redrive_test_input", service.NewConfigSpec(),
+		func(*service.ParsedConfig, *service.Resources) (service.BatchInput, error) {
+			return in, nil
+		}))
+	require.NoError(t, env.RegisterBatchOutput("redrive_test_park", service.NewConfigSpec(),
+		func(*service.ParsedConfig, *service.Resources) (service.BatchOutput, service.BatchPolicy, int, error) {
+			return park, service.BatchPolicy{}, 1, nil
+		}))
+	return env
+}
+
+func redriveMsg(content string, meta map[string]any) *service.Message {
+	msg := service.NewMessage([]byte(content))
+	for k, v := range meta {
+		msg.MetaSetMut(k, v)
+	}
+	return msg
+}
+
+func TestRedriveInputAttempts(t *testing.T) {
+	in := &sliceInput{
+		acks: make(chan error, 10),
+		batches: []service.MessageBatch{
+			{redriveMsg("first", nil)},
+			{redriveMsg("second", map[string]any{"redrive_attempts": "2"})},
+		},
+	}
+	park := &funcOutput{writeFn: func(context.Context, service.MessageBatch) error { return nil }}
+
+	pConf, err := redriveInputSpec().ParseYAML(`
+max_redrives: 3
+initial_interval: 1ms
+max_interval: 1ms
+input:
+  redrive_test_input: {}
+park:
+  redrive_test_park: {}
+`, redriveTestEnv(t, in, park))
+	require.NoError(t, err)
+
+	r, err := newRedriveInputFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+	require.NoError(t, r.Connect(context.Background()))
+	t.Cleanup(func() {
+		ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+		defer done()
+		require.NoError(t, r.Close(ctx))
+	})
+
+	tCtx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	for _, exp := range []struct {
+		content  string
+		attempts int
+	}{{"first", 1}, {"second", 3}} {
+		batch, aFn, err := r.ReadBatch(tCtx)
+		require.NoError(t, err)
+		require.Len(t, batch, 1)
+
+		b, err := batch[0].AsBytes()
+		require.NoError(t, err)
+		assert.Equal(t, exp.content, string(b))
+		attempts, _ := batch[0].MetaGetMut("redrive_attempts")
+		assert.Equal(t, exp.attempts, attempts)
+
+		require.NoError(t, aFn(tCtx, nil))
+		require.NoError(t, <-in.acks)
+	}
+	assert.Equal(t, int64(0), park.calls.Load())
+}
+
+func TestRedriveInputPark(t *testing.T) {
+	in := &sliceInput{
+		acks: make(chan error, 10),
+		batches: []service.MessageBatch{
+			{
+				redriveMsg("parked", map[string]any{"redrive_attempts": 2}),
+				redriveMsg("redriven", map[string]any{"redrive_attempts": 1}),
+			},
+		},
+	}
+
+	var parked service.MessageBatch
+	park := &funcOutput{writeFn: func(_ context.Context, b service.MessageBatch) error {
+		parked = b
+		return nil
+	}}
+
+	pConf, err := redriveInputSpec().ParseYAML(`
+max_redrives: 2
+initial_interval: 1ms
+max_interval: 1ms
+input:
+  redrive_test_input: {}
+park:
+  redrive_test_park: {}
+`, redriveTestEnv(t, in, park))
+	require.NoError(t, err)
+
+	r, err := newRedriveInputFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+	require.NoError(t, r.Connect(context.Background()))
+	t.Cleanup(func() {
+		ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+		defer done()
+		require.NoError(t, r.Close(ctx))
+	})
+
+	tCtx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	batch, aFn, err := r.ReadBatch(tCtx)
+	require.NoError(t, err)
+	require.Len(t, batch, 1)
+	b, err := batch[0].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, "redriven", string(b))
+
+	require.Len(t, parked, 1)
+	b, err = parked[0].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, "parked", string(b))
+
+	require.NoError(t, aFn(tCtx, nil))
+	require.NoError(t, <-in.acks)
+}
+
+func TestRedriveInputBackoff(t *testing.T) {
+	failedAt := time.Now().Add(-time.Millisecond * 100)
+	in := &sliceInput{
+		acks: make(chan error, 10),
+		batches: []service.MessageBatch{
+			{redriveMsg("foo", map[string]any{
+				"redrive_attempts":      1,
+				"dead_letter_failed_at": failedAt.Format(time.RFC3339Nano),
+			})},
+		},
+	}
+	park := &funcOutput{writeFn: func(context.Context, service.MessageBatch) error { return nil }}
+
+	pConf, err := redriveInputSpec().ParseYAML(`
+initial_interval: 200ms
+input:
+  redrive_test_input: {}
+park:
+  redrive_test_park: {}
+`, redriveTestEnv(t, in, park))
+	require.NoError(t, err)
+
+	r, err := newRedriveInputFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+	require.NoError(t, r.Connect(context.Background()))
+	t.Cleanup(func() {
+		ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+		defer done()
+		require.NoError(t, r.Close(ctx))
+	})
+
+	assert.Equal(t, time.Millisecond*200, r.redriveDelay(1))
+	assert.Equal(t, time.Millisecond*800, r.redriveDelay(3))
+
+	tCtx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	// The second attempt waits 400ms from the time the message failed.
+	_, aFn, err := r.ReadBatch(tCtx)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(failedAt), time.Millisecond*400)
+	assert.Less(t, time.Since(failedAt), time.Second*2)
+	require.NoError(t, aFn(tCtx, nil))
+}
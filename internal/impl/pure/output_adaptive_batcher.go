@@ -0,0 +1,368 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	abFieldOutput        = "output"
+	abFieldMinCount      = "min_count"
+	abFieldMaxCount      = "max_count"
+	abFieldPeriod        = "period"
+	abFieldTargetLatency = "target_latency"
+	abFieldIncrease      = "increase"
+	abFieldDecreaseRatio = "decrease_ratio"
+)
+
+func adaptiveBatcherOutputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Version("4.45.0").
+		Summary("Batches messages for a child output with a batch size that is tuned automatically within configured bounds based on the observed latency and errors of each write.").
+		Description(`
+Choosing a batch size for outputs such as `+"`aws_s3`, `elasticsearch` or `kafka`"+` often involves a trade-off between throughput and latency that shifts with the load of the downstream service. This output removes the guesswork by adjusting the batch size with an additive-increase/multiplicative-decrease (AIMD) algorithm.
+
+Each time a batch is written to the child output the write latency and outcome are observed. When the write succeeds within `+"`target_latency`"+` the batch size is increased by `+"`increase`"+`, up to `+"`max_count`"+`. When the write fails or exceeds the target latency the batch size is multiplied by `+"`decrease_ratio`"+`, down to `+"`min_count`"+`.
+
+A batch is flushed once it reaches the current batch size, or once the oldest message of the batch has been waiting for `+"`period`"+`, whichever happens first.
+
+The child output should not have its own batching policy configured, as that would defeat the tuning performed here. Processors that should be applied to each batch, such as an `+"`archive`"+`, can instead be added to the `+"`processors`"+` field of the child output.
+
+== Metrics
+
+This output emits a gauge `+"`adaptive_batcher_count`"+` reflecting the current batch size.`).
+		Fields(
+			service.NewOutputField(abFieldOutput).
+				Description("The child output to write batches to."),
+			service.NewIntField(abFieldMinCount).
+				Description("The minimum batch size.").
+				Default(1),
+			service.NewIntField(abFieldMaxCount).
+				Description("The maximum batch size.").
+				Default(500),
+			service.NewDurationField(abFieldPeriod).
+				Description("The maximum period of time a message waits before its batch is flushed regardless of size.").
+				Default("1s"),
+			service.NewDurationField(abFieldTargetLatency).
+				Description("Writes that take longer than this latency cause the batch size to decrease.").
+				Default("1s"),
+			service.NewIntField(abFieldIncrease).
+				Description("The amount to increase the batch size by after each successful write within the target latency.").
+				Default(10).
+				Advanced(),
+			service.NewFloatField(abFieldDecreaseRatio).
+				Description("The ratio to multiply the batch size by after each failed or slow write.").
+				Default(0.5).
+				Advanced(),
+		).
+		Example("Tuned S3 Uploads", "Upload archives of up to ten thousand messages to S3, backing off when uploads slow down.", `
+output:
+  adaptive_batcher:
+    min_count: 100
+    max_count: 10000
+    period: 10s
+    target_latency: 5s
+    increase: 100
+    output:
+      aws_s3:
+        bucket: example-bucket
+        path: ${! timestamp_unix_nano() }.jsonl
+      processors:
+        - archive:
+            format: lines
+`)
+}
+
+func init() {
+	err := service.RegisterBatchOutput("adaptive_batcher", adaptiveBatcherOutputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPol service.BatchPolicy, maxInFlight int, err error) {
+			var a *adaptiveBatcherOutput
+			if a, err = newAdaptiveBatcherFromParsed(conf, mgr); err != nil {
+				return
+			}
+			out, maxInFlight = a, a.sizer.maxCount
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// aimdSizer tracks a batch size that increases additively after successful
+// writes and decreases multiplicatively after failed or slow writes.
+type aimdSizer struct {
+	minCount      int
+	maxCount      int
+	increase      int
+	decreaseRatio float64
+	targetLatency time.Duration
+
+	mut   sync.Mutex
+	count int
+}
+
+func (a *aimdSizer) Count() int {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+	return a.count
+}
+
+// Observe adjusts the batch size based on the outcome of a write and returns
+// the new size.
+func (a *aimdSizer) Observe(latency time.Duration, err error) int {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	if err != nil || latency > a.targetLatency {
+		a.count = int(float64(a.count) * a.decreaseRatio)
+	} else {
+		a.count += a.increase
+	}
+	a.count = min(max(a.count, a.minCount), a.maxCount)
+	return a.count
+}
+
+//------------------------------------------------------------------------------
+
+type adaptiveBatchRequest struct {
+	batch   service.MessageBatch
+	resChan chan error
+}
+
+type adaptiveBatcherOutput struct {
+	child  *service.OwnedOutput
+	sizer  *aimdSizer
+	period time.Duration
+
+	mCount *service.MetricGauge
+	log    *service.Logger
+
+	reqChan  chan adaptiveBatchRequest
+	shutSig  chan struct{}
+	doneChan chan struct{}
+	startMut sync.Mutex
+	started  bool
+	shutOnce sync.Once
+}
+
+func newAdaptiveBatcherFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*adaptiveBatcherOutput, error) {
+	a := &adaptiveBatcherOutput{
+		sizer:    &aimdSizer{},
+		mCount:   mgr.Metrics().NewGauge("adaptive_batcher_count"),
+		log:      mgr.Logger(),
+		reqChan:  make(chan adaptiveBatchRequest),
+		shutSig:  make(chan struct{}),
+		doneChan: make(chan struct{}),
+	}
+
+	var err error
+	if a.sizer.minCount, err = conf.FieldInt(abFieldMinCount); err != nil {
+		return nil, err
+	}
+	if a.sizer.maxCount, err = conf.FieldInt(abFieldMaxCount); err != nil {
+		return nil, err
+	}
+	if a.sizer.minCount < 1 || a.sizer.maxCount < a.sizer.minCount {
+		return nil, errors.New("min_count must be at least one and must not exceed max_count")
+	}
+	if a.period, err = conf.FieldDuration(abFieldPeriod); err != nil {
+		return nil, err
+	}
+	if a.sizer.targetLatency, err = conf.FieldDuration(abFieldTargetLatency); err != nil {
+		return nil, err
+	}
+	if a.sizer.increase, err = conf.FieldInt(abFieldIncrease); err != nil {
+		return nil, err
+	}
+	if a.sizer.decreaseRatio, err = conf.FieldFloat(abFieldDecreaseRatio); err != nil {
+		return nil, err
+	}
+	if a.sizer.decreaseRatio <= 0 || a.sizer.decreaseRatio >= 1 {
+		return nil, errors.New("decrease_ratio must be greater than zero and less than one")
+	}
+	a.sizer.count = a.sizer.minCount
+
+	if a.child, err = conf.FieldOutput(abFieldOutput); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func (a *adaptiveBatcherOutput) Connect(ctx context.Context) error {
+	a.startMut.Lock()
+	defer a.startMut.Unlock()
+	if a.started {
+		return nil
+	}
+	if err := a.child.Prime(); err != nil {
+		return err
+	}
+	a.started = true
+	a.mCount.Set(int64(a.sizer.Count()))
+	go a.loop()
+	return nil
+}
+
+func (a *adaptiveBatcherOutput) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	req := adaptiveBatchRequest{
+		batch:   batch,
+		resChan: make(chan error, 1),
+	}
+	select {
+	case a.reqChan <- req:
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-a.shutSig:
+		return service.ErrNotConnected
+	}
+	select {
+	case err := <-req.resChan:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (a *adaptiveBatcherOutput) loop() {
+	defer close(a.doneChan)
+
+	var pending []adaptiveBatchRequest
+	var pendingCount int
+
+	timer := time.NewTimer(a.period)
+	timer.Stop()
+
+	flush := func() {
+		timer.Stop()
+		if len(pending) == 0 {
+			return
+		}
+		a.flush(pending)
+		pending, pendingCount = nil, 0
+	}
+
+	for {
+		select {
+		case req := <-a.reqChan:
+			if len(pending) == 0 {
+				timer.Reset(a.period)
+			}
+			pending = append(pending, req)
+			if pendingCount += len(req.batch); pendingCount >= a.sizer.Count() {
+				flush()
+			}
+		case <-timer.C:
+			flush()
+		case <-a.shutSig:
+			for _, req := range pending {
+				req.resChan <- service.ErrNotConnected
+			}
+			return
+		}
+	}
+}
+
+func (a *adaptiveBatcherOutput) flush(reqs []adaptiveBatchRequest) {
+	var combined service.MessageBatch
+	for _, req := range reqs {
+		combined = append(combined, req.batch...)
+	}
+	indexer := combined.Index()
+
+	ctx, done := context.WithCancel(context.Background())
+	go func() {
+		select {
+		case <-a.shutSig:
+			done()
+		case <-ctx.Done():
+		}
+	}()
+
+	start := time.Now()
+	err := a.child.WriteBatch(ctx, combined)
+	done()
+
+	newCount := a.sizer.Observe(time.Since(start), err)
+	a.mCount.Set(int64(newCount))
+
+	if err == nil {
+		for _, req := range reqs {
+			req.resChan <- nil
+		}
+		return
+	}
+
+	var bErr *service.BatchError
+	if !errors.As(err, &bErr) {
+		for _, req := range reqs {
+			req.resChan <- err
+		}
+		return
+	}
+
+	msgErrs := make([]error, len(combined))
+	bErr.WalkMessagesIndexedBy(indexer, func(i int, _ *service.Message, mErr error) bool {
+		if i >= 0 && i < len(msgErrs) && mErr != nil {
+			msgErrs[i] = mErr
+		}
+		return true
+	})
+
+	offset := 0
+	for _, req := range reqs {
+		var reqErr *service.BatchError
+		for i := range req.batch {
+			if mErr := msgErrs[offset+i]; mErr != nil {
+				if reqErr == nil {
+					reqErr = service.NewBatchError(req.batch, err)
+				}
+				reqErr.Failed(i, mErr)
+			}
+		}
+		offset += len(req.batch)
+		if reqErr != nil {
+			req.resChan <- reqErr
+		} else {
+			req.resChan <- nil
+		}
+	}
+}
+
+func (a *adaptiveBatcherOutput) Close(ctx context.Context) error {
+	a.shutOnce.Do(func() {
+		close(a.shutSig)
+	})
+	a.startMut.Lock()
+	started := a.started
+	a.startMut.Unlock()
+	if started {
+		select {
+		case <-a.doneChan:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return a.child.Close(ctx)
+}
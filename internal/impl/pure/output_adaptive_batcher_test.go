@@ -0,0 +1,203 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func TestAIMDSizer(t *testing.T) {
+	s := &aimdSizer{
+		minCount:      2,
+		maxCount:      25,
+		increase:      10,
+		decreaseRatio: 0.5,
+		targetLatency: time.Second,
+		count:         2,
+	}
+
+	assert.Equal(t, 12, s.Observe(time.Millisecond, nil))
+	assert.Equal(t, 22, s.Observe(time.Millisecond, nil))
+	assert.Equal(t, 25, s.Observe(time.Millisecond, nil))
+	assert.Equal(t, 12, s.Observe(2*time.Second, nil))
+	assert.Equal(t, 6, s.Observe(time.Millisecond, errors.New("nope")))
+	assert.Equal(t, 3, s.Observe(time.Millisecond, errors.New("nope")))
+	assert.Equal(t, 2, s.Observe(time.Millisecond, errors.New("nope")))
+	assert.Equal(t, 2, s.Count())
+}
+
+type recordingOutput struct {
+	mut     sync.Mutex
+	sizes   []int
+	failIdx map[string]error
+}
+
+func (r *recordingOutput) Connect(context.Context) error { return nil }
+
+func (r *recordingOutput) WriteBatch(_ context.Context, b service.MessageBatch) error {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	r.sizes = append(r.sizes, len(b))
+
+	var bErr *service.BatchError
+	for i, m := range b {
+		mBytes, _ := m.AsBytes()
+		if err, exists := r.failIdx[string(mBytes)]; exists {
+			if bErr == nil {
+				bErr = service.NewBatchError(b, errors.New("some failed"))
+			}
+			bErr.Failed(i, err)
+		}
+	}
+	if bErr != nil {
+		return bErr
+	}
+	return nil
+}
+
+func (r *recordingOutput) Close(context.Context) error { return nil }
+
+func testAdaptiveBatcher(t *testing.T, rec *recordingOutput, conf string) *adaptiveBatcherOutput {
+	t.Helper()
+
+	env := service.NewEnvironment()
+	require.NoError(t, env.RegisterBatchOutput("adaptive_batcher_test", service.NewConfigSpec(),
+		func(*service.ParsedConfig, *service.Resources) (service.BatchOutput, service.BatchPolicy, int, error) {
+			return rec, service.BatchPolicy{}, 1, nil
+		}))
+
+	pConf, err := adaptiveBatcherOutputSpec().ParseYAML(conf, env)
+	require.NoError(t, err)
+
+	a, err := newAdaptiveBatcherFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+	require.NoError(t, a.Connect(context.Background()))
+	t.Cleanup(func() {
+		ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+		defer done()
+		require.NoError(t, a.Close(ctx))
+	})
+	return a
+}
+
+func TestAdaptiveBatcherGrowsBatches(t *testing.T) {
+	rec := &recordingOutput{}
+	a := testAdaptiveBatcher(t, rec, `
+min_count: 1
+max_count: 5
+increase: 2
+period: 50ms
+output:
+  adaptive_batcher_test: {}
+`)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, a.WriteBatch(ctx, service.MessageBatch{service.NewMessage([]byte("hello"))}))
+		}()
+	}
+	wg.Wait()
+
+	rec.mut.Lock()
+	defer rec.mut.Unlock()
+
+	var total, largest int
+	for _, s := range rec.sizes {
+		total += s
+		largest = max(largest, s)
+	}
+	assert.Equal(t, 20, total)
+	assert.LessOrEqual(t, largest, 5)
+	assert.Greater(t, largest, 1)
+}
+
+func TestAdaptiveBatcherPeriodFlush(t *testing.T) {
+	rec := &recordingOutput{}
+	a := testAdaptiveBatcher(t, rec, `
+min_count: 10
+max_count: 10
+period: 10ms
+output:
+  adaptive_batcher_test: {}
+`)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	require.NoError(t, a.WriteBatch(ctx, service.MessageBatch{service.NewMessage([]byte("hello"))}))
+
+	rec.mut.Lock()
+	defer rec.mut.Unlock()
+	assert.Equal(t, []int{1}, rec.sizes)
+}
+
+func TestAdaptiveBatcherPartialErrors(t *testing.T) {
+	rec := &recordingOutput{
+		failIdx: map[string]error{
+			"b2": errors.New("b2 failed"),
+		},
+	}
+	a := testAdaptiveBatcher(t, rec, `
+min_count: 4
+max_count: 4
+period: 10s
+output:
+  adaptive_batcher_test: {}
+`)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	var aErr, bErr error
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		aErr = a.WriteBatch(ctx, service.MessageBatch{
+			service.NewMessage([]byte("a1")),
+			service.NewMessage([]byte("a2")),
+		})
+	}()
+	go func() {
+		defer wg.Done()
+		bErr = a.WriteBatch(ctx, service.MessageBatch{
+			service.NewMessage([]byte("b1")),
+			service.NewMessage([]byte("b2")),
+		})
+	}()
+	wg.Wait()
+
+	require.NoError(t, aErr)
+	require.Error(t, bErr)
+
+	var batchErr *service.BatchError
+	require.ErrorAs(t, bErr, &batchErr)
+	assert.Equal(t, 1, batchErr.IndexedErrors())
+}
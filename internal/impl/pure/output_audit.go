@@ -0,0 +1,170 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	auoFieldOutput      = "output"
+	auoFieldFailedEvent = "failed_event"
+)
+
+func auditOutputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Version("4.45.0").
+		Summary("Wraps an output in order to emit a structured audit event for each message that it delivers or fails to deliver.").
+		Description(`
+An event of the type `+"`"+auFieldEvent+"`"+` is written for each message once the child output has written it, and an event of the type `+"`"+auoFieldFailedEvent+"`"+` is written along with the error for each message that it fails to write. Writes that fail are retried as normal, and therefore a message may have multiple failure events before it is delivered. Wrapping the dead letter output of a `+"`dead_letter`"+` output with the event `+"`dead_lettered`"+` records messages that are given up on.
+`+auditDescription()+`
+
+When events cannot be written after a successful write the batch is rejected so that it is written again, which ensures that deliveries are never unaudited at the cost of potential duplicates.`).
+		Fields(
+			service.NewOutputField(auoFieldOutput).
+				Description("The output to write messages to."),
+			service.NewStringField(auFieldEvent).
+				Description("The type of event to emit for messages that are written.").
+				Default("delivered"),
+			service.NewStringField(auoFieldFailedEvent).
+				Description("The type of event to emit for messages that fail to be written.").
+				Default("failed").
+				Advanced(),
+		).
+		Fields(auditFields()...).
+		Fields(service.NewOutputMaxInFlightField()).
+		Example("Auditing Dead Letters", "Record deliveries to an HTTP API, along with the messages that are moved to a dead letter topic after exhausting their retries.", `
+output:
+  dead_letter:
+    output:
+      audit:
+        audit_output: audit_log
+        output:
+          http_client:
+            url: https://example.com/ingest
+            verb: POST
+    dead_letter:
+      audit:
+        event: dead_lettered
+        audit_output: audit_log
+        output:
+          kafka_franz:
+            seed_brokers: [ localhost:9092 ]
+            topic: ingest_dlq
+
+output_resources:
+  - label: audit_log
+    file:
+      path: ./audit.jsonl
+      codec: lines
+`)
+}
+
+func init() {
+	err := service.RegisterBatchOutput("audit", auditOutputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPol service.BatchPolicy, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldMaxInFlight(); err != nil {
+				return
+			}
+			out, err = newAuditOutputFromParsed(conf, mgr)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type auditOutput struct {
+	output      *service.OwnedOutput
+	eventType   string
+	failedEvent string
+	auditor     *auditor
+
+	primeMut sync.Mutex
+	primed   bool
+}
+
+func newAuditOutputFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*auditOutput, error) {
+	a := &auditOutput{}
+
+	var err error
+	if a.eventType, err = conf.FieldString(auFieldEvent); err != nil {
+		return nil, err
+	}
+	if a.failedEvent, err = conf.FieldString(auoFieldFailedEvent); err != nil {
+		return nil, err
+	}
+	if a.auditor, err = newAuditor(conf, mgr); err != nil {
+		return nil, err
+	}
+	if a.output, err = conf.FieldOutput(auoFieldOutput); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func (a *auditOutput) Connect(ctx context.Context) error {
+	a.primeMut.Lock()
+	defer a.primeMut.Unlock()
+	if a.primed {
+		return nil
+	}
+	if err := a.output.Prime(); err != nil {
+		return err
+	}
+	a.primed = true
+	return nil
+}
+
+func (a *auditOutput) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	indexer := batch.Index()
+	err := a.output.WriteBatch(ctx, batch.Copy())
+	if err != nil && ctx.Err() != nil {
+		return err
+	}
+
+	var written, failed service.MessageBatch
+	var failedErrs []error
+	for i, mErr := range messageErrors(batch, indexer, err) {
+		if mErr != nil {
+			failed = append(failed, batch[i])
+			failedErrs = append(failedErrs, mErr)
+		} else {
+			written = append(written, batch[i])
+		}
+	}
+
+	// Events are emitted from copies as the messages of a batch are owned by
+	// the caller and may be shared.
+	if aErr := a.auditor.emit(ctx, written.Copy(), a.eventType, nil); aErr != nil {
+		return errors.Join(aErr, err)
+	}
+	if aErr := a.auditor.emit(ctx, failed.Copy(), a.failedEvent, failedErrs); aErr != nil {
+		a.auditor.log.Errorf("Dropping audit events of failed writes: %v", aErr)
+	}
+	return err
+}
+
+func (a *auditOutput) Close(ctx context.Context) error {
+	return a.output.Close(ctx)
+}
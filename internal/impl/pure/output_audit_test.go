@@ -0,0 +1,145 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func auditTestEnv(t *testing.T, child *funcOutput) *service.Environment {
+	t.Helper()
+
+	env := service.NewEnvironment()
+	require.NoError(t, env.RegisterBatchOutput("audit_test_output", service.NewConfigSpec(),
+		func(*service.ParsedConfig, *service.Resources) (service.BatchOutput, service.BatchPolicy, int, error) {
+			return child, service.BatchPolicy{}, 1, nil
+		}))
+	return env
+}
+
+func auditTestBatch(ids ...string) service.MessageBatch {
+	var batch service.MessageBatch
+	for _, id := range ids {
+		m := service.NewMessage([]byte(id))
+		m.MetaSetMut(auditIDKey, id)
+		batch = append(batch, m)
+	}
+	return batch
+}
+
+func TestAuditOutputDelivered(t *testing.T) {
+	child := &funcOutput{writeFn: func(context.Context, service.MessageBatch) error { return nil }}
+	pConf, err := auditOutputSpec().ParseYAML(`
+component: sink
+audit_output: foo
+output:
+  audit_test_output: {}
+`, auditTestEnv(t, child))
+	require.NoError(t, err)
+
+	a, err := newAuditOutputFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+	require.NoError(t, a.Connect(context.Background()))
+	t.Cleanup(func() {
+		ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+		defer done()
+		require.NoError(t, a.Close(ctx))
+	})
+
+	events := &auditEvents{}
+	events.attach(t, a.auditor)
+
+	require.NoError(t, a.WriteBatch(context.Background(), auditTestBatch("a", "b")))
+
+	got := events.get()
+	require.Len(t, got, 2)
+	for i, id := range []string{"a", "b"} {
+		assert.Equal(t, "delivered", got[i]["event"])
+		assert.Equal(t, id, got[i]["message_id"])
+		assert.Equal(t, "sink", got[i]["component"])
+		assert.NotContains(t, got[i], "error")
+	}
+}
+
+func TestAuditOutputPartialFailure(t *testing.T) {
+	child := &funcOutput{writeFn: func(_ context.Context, b service.MessageBatch) error {
+		return service.NewBatchError(b, errors.New("nope")).Failed(1, errors.New("rejected"))
+	}}
+	pConf, err := auditOutputSpec().ParseYAML(`
+audit_output: foo
+output:
+  audit_test_output: {}
+`, auditTestEnv(t, child))
+	require.NoError(t, err)
+
+	a, err := newAuditOutputFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+	require.NoError(t, a.Connect(context.Background()))
+	t.Cleanup(func() {
+		ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+		defer done()
+		require.NoError(t, a.Close(ctx))
+	})
+
+	events := &auditEvents{}
+	events.attach(t, a.auditor)
+
+	err = a.WriteBatch(context.Background(), auditTestBatch("a", "b"))
+	var bErr *service.BatchError
+	require.ErrorAs(t, err, &bErr)
+
+	got := events.get()
+	require.Len(t, got, 2)
+	assert.Equal(t, "delivered", got[0]["event"])
+	assert.Equal(t, "a", got[0]["message_id"])
+	assert.Equal(t, "failed", got[1]["event"])
+	assert.Equal(t, "b", got[1]["message_id"])
+	assert.Equal(t, "rejected", got[1]["error"])
+}
+
+func TestAuditOutputWriteError(t *testing.T) {
+	child := &funcOutput{writeFn: func(context.Context, service.MessageBatch) error { return nil }}
+	pConf, err := auditOutputSpec().ParseYAML(`
+audit_output: foo
+output:
+  audit_test_output: {}
+`, auditTestEnv(t, child))
+	require.NoError(t, err)
+
+	a, err := newAuditOutputFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+	require.NoError(t, a.Connect(context.Background()))
+	t.Cleanup(func() {
+		ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+		defer done()
+		require.NoError(t, a.Close(ctx))
+	})
+
+	events := &auditEvents{}
+	events.attach(t, a.auditor)
+	events.err = errors.New("nope")
+
+	// Deliveries that cannot be audited are rejected in order to be retried.
+	require.ErrorContains(t, a.WriteBatch(context.Background(), auditTestBatch("a")), "failed to write audit events: nope")
+	assert.Equal(t, int64(1), child.calls.Load())
+}
@@ -0,0 +1,116 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"sync"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	cboFieldOutput = "output"
+)
+
+func circuitBreakerOutputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Version("4.45.0").
+		Summary("Wraps an output with a circuit breaker that stops writes to it after a high rate of failures, in order to give the system behind it a chance to recover.").
+		Description(circuitBreakerDescription("writes")).
+		Fields(
+			service.NewOutputField(cboFieldOutput).
+				Description("The output to write batches to."),
+		).
+		Fields(circuitBreakerFields("Fail writes immediately, which rejects the batches so that they are retried or handled by a `fallback` or `dead_letter` output.")...).
+		Fields(service.NewOutputMaxInFlightField()).
+		Example("Protect an API", "Stop sending to an HTTP API for a minute when more than a quarter of requests within the last ten seconds fail, holding messages back until it recovers.", `
+output:
+  circuit_breaker:
+    error_rate: 0.25
+    open_duration: 1m
+    when_open: wait
+    output:
+      http_client:
+        url: https://example.com/ingest
+        verb: POST
+`)
+}
+
+func init() {
+	err := service.RegisterBatchOutput("circuit_breaker", circuitBreakerOutputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPol service.BatchPolicy, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldMaxInFlight(); err != nil {
+				return
+			}
+			out, err = newCircuitBreakerOutputFromParsed(conf, mgr)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type circuitBreakerOutput struct {
+	output  *service.OwnedOutput
+	breaker *circuitBreaker
+
+	primeMut sync.Mutex
+	primed   bool
+}
+
+func newCircuitBreakerOutputFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*circuitBreakerOutput, error) {
+	c := &circuitBreakerOutput{}
+
+	var err error
+	if c.breaker, err = newCircuitBreaker(conf, mgr); err != nil {
+		return nil, err
+	}
+	if c.output, err = conf.FieldOutput(cboFieldOutput); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *circuitBreakerOutput) Connect(ctx context.Context) error {
+	c.primeMut.Lock()
+	defer c.primeMut.Unlock()
+	if c.primed {
+		return nil
+	}
+	if err := c.output.Prime(); err != nil {
+		return err
+	}
+	c.primed = true
+	return nil
+}
+
+func (c *circuitBreakerOutput) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	release, err := c.breaker.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	err = c.output.WriteBatch(ctx, batch)
+	release(err)
+	return err
+}
+
+func (c *circuitBreakerOutput) Close(ctx context.Context) error {
+	return c.output.Close(ctx)
+}
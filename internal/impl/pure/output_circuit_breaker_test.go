@@ -0,0 +1,203 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func attempt(t *testing.T, c *circuitBreaker, err error) error {
+	t.Helper()
+
+	release, aErr := c.acquire(context.Background())
+	if aErr != nil {
+		return aErr
+	}
+	release(err)
+	return nil
+}
+
+func TestCircuitBreakerOpens(t *testing.T) {
+	pConf, err := service.NewConfigSpec().Fields(circuitBreakerFields("")...).ParseYAML(`
+error_rate: 0.5
+min_requests: 4
+`, nil)
+	require.NoError(t, err)
+
+	c, err := newCircuitBreaker(pConf, service.MockResources())
+	require.NoError(t, err)
+
+	nope := errors.New("nope")
+	require.NoError(t, attempt(t, c, nil))
+	require.NoError(t, attempt(t, c, nope))
+	require.NoError(t, attempt(t, c, nil))
+	assert.Equal(t, cbStateClosed, c.state)
+
+	// The fourth attempt meets the minimum and brings the rate to a half.
+	require.NoError(t, attempt(t, c, nope))
+	assert.Equal(t, cbStateOpen, c.state)
+	require.ErrorIs(t, attempt(t, c, nil), errCircuitOpen)
+}
+
+func TestCircuitBreakerWindow(t *testing.T) {
+	pConf, err := service.NewConfigSpec().Fields(circuitBreakerFields("")...).ParseYAML(`
+error_rate: 0.5
+min_requests: 2
+window: 10s
+`, nil)
+	require.NoError(t, err)
+
+	c, err := newCircuitBreaker(pConf, service.MockResources())
+	require.NoError(t, err)
+
+	now := time.Unix(1000, 0)
+	c.nowFn = func() time.Time { return now }
+
+	nope := errors.New("nope")
+	require.NoError(t, attempt(t, c, nope))
+
+	// The first failure falls out of the window.
+	now = now.Add(time.Second * 11)
+	require.NoError(t, attempt(t, c, nil))
+	require.NoError(t, attempt(t, c, nil))
+	require.NoError(t, attempt(t, c, nope))
+	assert.Equal(t, cbStateClosed, c.state)
+
+	require.NoError(t, attempt(t, c, nope))
+	assert.Equal(t, cbStateOpen, c.state)
+}
+
+func TestCircuitBreakerHalfOpen(t *testing.T) {
+	pConf, err := service.NewConfigSpec().Fields(circuitBreakerFields("")...).ParseYAML(`
+min_requests: 1
+open_duration: 30s
+half_open_requests: 2
+`, nil)
+	require.NoError(t, err)
+
+	c, err := newCircuitBreaker(pConf, service.MockResources())
+	require.NoError(t, err)
+
+	now := time.Unix(1000, 0)
+	c.nowFn = func() time.Time { return now }
+
+	nope := errors.New("nope")
+	require.NoError(t, attempt(t, c, nope))
+	assert.Equal(t, cbStateOpen, c.state)
+
+	now = now.Add(time.Second * 30)
+
+	// Only the trial attempts are allowed through.
+	release1, err := c.acquire(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, cbStateHalfOpen, c.state)
+	release2, err := c.acquire(context.Background())
+	require.NoError(t, err)
+	_, err = c.acquire(context.Background())
+	require.ErrorIs(t, err, errCircuitOpen)
+
+	// A failed trial opens the breaker again.
+	release1(nil)
+	release2(nope)
+	assert.Equal(t, cbStateOpen, c.state)
+
+	now = now.Add(time.Second * 30)
+	require.NoError(t, attempt(t, c, nil))
+	require.NoError(t, attempt(t, c, nil))
+	assert.Equal(t, cbStateClosed, c.state)
+}
+
+func TestCircuitBreakerWait(t *testing.T) {
+	pConf, err := service.NewConfigSpec().Fields(circuitBreakerFields("")...).ParseYAML(`
+min_requests: 1
+open_duration: 50ms
+when_open: wait
+`, nil)
+	require.NoError(t, err)
+
+	c, err := newCircuitBreaker(pConf, service.MockResources())
+	require.NoError(t, err)
+	c.nowFn = time.Now
+
+	require.NoError(t, attempt(t, c, errors.New("nope")))
+	assert.Equal(t, cbStateOpen, c.state)
+
+	start := time.Now()
+	release, err := c.acquire(context.Background())
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), time.Millisecond*40)
+	release(nil)
+
+	c.mut.Lock()
+	c.open(time.Now(), errors.New("nope"))
+	c.mut.Unlock()
+
+	ctx, done := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer done()
+	_, err = c.acquire(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestCircuitBreakerOutput(t *testing.T) {
+	var failing atomic.Bool
+	failing.Store(true)
+
+	child := &funcOutput{writeFn: func(context.Context, service.MessageBatch) error {
+		if failing.Load() {
+			return errors.New("nope")
+		}
+		return nil
+	}}
+
+	env := service.NewEnvironment()
+	require.NoError(t, env.RegisterBatchOutput("circuit_breaker_test_output", service.NewConfigSpec(),
+		func(*service.ParsedConfig, *service.Resources) (service.BatchOutput, service.BatchPolicy, int, error) {
+			return child, service.BatchPolicy{}, 1, nil
+		}))
+
+	pConf, err := circuitBreakerOutputSpec().ParseYAML(`
+min_requests: 2
+open_duration: 1h
+output:
+  circuit_breaker_test_output: {}
+`, env)
+	require.NoError(t, err)
+
+	c, err := newCircuitBreakerOutputFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+	require.NoError(t, c.Connect(context.Background()))
+	t.Cleanup(func() {
+		ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+		defer done()
+		require.NoError(t, c.Close(ctx))
+	})
+
+	for range 2 {
+		require.EqualError(t, c.WriteBatch(context.Background(), testBatch()), "nope")
+	}
+	failing.Store(false)
+
+	require.ErrorIs(t, c.WriteBatch(context.Background(), testBatch()), errCircuitOpen)
+	assert.Equal(t, int64(2), child.calls.Load())
+}
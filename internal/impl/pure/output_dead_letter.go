@@ -0,0 +1,235 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+
+	"github.com/redpanda-data/connect/v4/internal/retries"
+)
+
+const (
+	dloFieldOutput     = "output"
+	dloFieldDeadLetter = "dead_letter"
+)
+
+func deadLetterOutputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Version("4.45.0").
+		Summary("Writes batches to an output, retrying failed messages with a backoff and routing those that exhaust their retries to a dead letter output.").
+		Description(`
+When a write to the `+"`"+dloFieldOutput+"`"+` fails only the messages that failed are retried, which for outputs that report individual message errors may be a subset of the batch. Once `+"`max_retries`"+` or `+"`backoff.max_elapsed_time`"+` is reached the messages that are still failing are written to the `+"`"+dloFieldDeadLetter+"`"+` output, and the original batch is acknowledged once they are written to it.
+
+Messages written to the dead letter output retain their original contents and metadata, with the following metadata fields added:
+
+- `+"`dead_letter_error`"+`: The error returned by the last attempt to write the message.
+- `+"`dead_letter_attempts`"+`: The number of attempts made to write the message.
+- `+"`dead_letter_failed_at`"+`: The time at which the message was given up on, as an RFC 3339 timestamp.
+
+When the write to the dead letter output also fails the batch is rejected, and will be retried from the start in the same way as a failed write to any other output.
+
+== Metrics
+
+This output emits a counter `+"`dead_letter_sent`"+` which is incremented for each message written to the dead letter output.`).
+		Fields(
+			service.NewOutputField(dloFieldOutput).
+				Description("The output to write batches to."),
+			service.NewOutputField(dloFieldDeadLetter).
+				Description("The output to write messages to once they have exhausted their retries."),
+		).
+		Fields(retries.CommonRetryBackOffFields(3, "500ms", "10s", "0s")...).
+		Fields(service.NewOutputMaxInFlightField()).
+		Example("Dead Letter Topic", "Write to an HTTP endpoint, moving messages that fail five times to a Kafka topic.", `
+output:
+  dead_letter:
+    max_retries: 5
+    output:
+      http_client:
+        url: https://example.com/ingest
+        verb: POST
+    dead_letter:
+      kafka_franz:
+        seed_brokers: [ localhost:9092 ]
+        topic: ingest_dlq
+`)
+}
+
+func init() {
+	err := service.RegisterBatchOutput("dead_letter", deadLetterOutputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPol service.BatchPolicy, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldMaxInFlight(); err != nil {
+				return
+			}
+			out, err = newDeadLetterOutputFromParsed(conf, mgr)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type deadLetterOutput struct {
+	output      *service.OwnedOutput
+	deadLetter  *service.OwnedOutput
+	backoffCtor func() backoff.BackOff
+
+	mSent *service.MetricCounter
+	log   *service.Logger
+	nowFn func() time.Time
+
+	primeMut sync.Mutex
+	primed   bool
+}
+
+func newDeadLetterOutputFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*deadLetterOutput, error) {
+	d := &deadLetterOutput{
+		mSent: mgr.Metrics().NewCounter("dead_letter_sent"),
+		log:   mgr.Logger(),
+		nowFn: time.Now,
+	}
+
+	var err error
+	if d.backoffCtor, err = retries.CommonRetryBackOffCtorFromParsed(conf); err != nil {
+		return nil, err
+	}
+	if d.output, err = conf.FieldOutput(dloFieldOutput); err != nil {
+		return nil, err
+	}
+	if d.deadLetter, err = conf.FieldOutput(dloFieldDeadLetter); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *deadLetterOutput) Connect(ctx context.Context) error {
+	d.primeMut.Lock()
+	defer d.primeMut.Unlock()
+	if d.primed {
+		return nil
+	}
+	if err := d.output.Prime(); err != nil {
+		return err
+	}
+	if err := d.deadLetter.Prime(); err != nil {
+		return err
+	}
+	d.primed = true
+	return nil
+}
+
+// messageErrors returns the individual error of each message of a batch from
+// the result of a write, where messages that were written have a nil error.
+func messageErrors(batch service.MessageBatch, indexer *service.Indexer, err error) []error {
+	msgErrs := make([]error, len(batch))
+	if err == nil {
+		return msgErrs
+	}
+
+	var bErr *service.BatchError
+	if !errors.As(err, &bErr) || bErr.IndexedErrors() == 0 {
+		for i := range msgErrs {
+			msgErrs[i] = err
+		}
+		return msgErrs
+	}
+
+	bErr.WalkMessagesIndexedBy(indexer, func(i int, _ *service.Message, mErr error) bool {
+		if i >= 0 && i < len(batch) && mErr != nil {
+			msgErrs[i] = mErr
+		}
+		return true
+	})
+	return msgErrs
+}
+
+// failedMessages returns the messages of a batch that failed a write along with
+// their individual errors.
+func failedMessages(batch service.MessageBatch, indexer *service.Indexer, err error) (service.MessageBatch, []error) {
+	var failed service.MessageBatch
+	var errs []error
+	for i, mErr := range messageErrors(batch, indexer, err) {
+		if mErr != nil {
+			failed = append(failed, batch[i])
+			errs = append(errs, mErr)
+		}
+	}
+	return failed, errs
+}
+
+func (d *deadLetterOutput) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	boff := d.backoffCtor()
+
+	pending := batch
+	for attempts := 1; ; attempts++ {
+		indexer := pending.Index()
+		err := d.output.WriteBatch(ctx, pending.Copy())
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		var errs []error
+		pending, errs = failedMessages(pending, indexer, err)
+
+		wait := boff.NextBackOff()
+		if wait == backoff.Stop {
+			return d.writeDeadLetters(ctx, pending, errs, attempts)
+		}
+		d.log.Debugf("Failed to write %v messages, retrying in %v: %v", len(pending), wait, err)
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (d *deadLetterOutput) writeDeadLetters(ctx context.Context, batch service.MessageBatch, errs []error, attempts int) error {
+	d.log.Warnf("Writing %v messages to dead letter output after %v attempts: %v", len(batch), attempts, errs[0])
+
+	failedAt := d.nowFn().Format(time.RFC3339Nano)
+	dlBatch := make(service.MessageBatch, len(batch))
+	for i, msg := range batch {
+		dlMsg := msg.Copy()
+		dlMsg.MetaSetMut("dead_letter_error", errs[i].Error())
+		dlMsg.MetaSetMut("dead_letter_attempts", attempts)
+		dlMsg.MetaSetMut("dead_letter_failed_at", failedAt)
+		dlBatch[i] = dlMsg
+	}
+	if err := d.deadLetter.WriteBatch(ctx, dlBatch); err != nil {
+		return fmt.Errorf("failed to write to dead letter output: %w", err)
+	}
+	d.mSent.Incr(int64(len(dlBatch)))
+	return nil
+}
+
+func (d *deadLetterOutput) Close(ctx context.Context) error {
+	return errors.Join(d.output.Close(ctx), d.deadLetter.Close(ctx))
+}
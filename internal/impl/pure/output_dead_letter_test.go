@@ -0,0 +1,226 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func deadLetterTestEnv(t *testing.T, output, deadLetter *funcOutput) *service.Environment {
+	t.Helper()
+
+	env := service.NewEnvironment()
+	for name, out := range map[string]*funcOutput{
+		"dead_letter_test_output":      output,
+		"dead_letter_test_dead_letter": deadLetter,
+	} {
+		out := out
+		require.NoError(t, env.RegisterBatchOutput(name, service.NewConfigSpec(),
+			func(*service.ParsedConfig, *service.Resources) (service.BatchOutput, service.BatchPolicy, int, error) {
+				return out, service.BatchPolicy{}, 1, nil
+			}))
+	}
+	return env
+}
+
+func TestDeadLetterOutputRecovers(t *testing.T) {
+	output := &funcOutput{}
+	output.writeFn = func(context.Context, service.MessageBatch) error {
+		if output.calls.Load() < 3 {
+			return errors.New("nope")
+		}
+		return nil
+	}
+	deadLetter := &funcOutput{writeFn: func(context.Context, service.MessageBatch) error { return nil }}
+
+	pConf, err := deadLetterOutputSpec().ParseYAML(`
+max_retries: 3
+backoff:
+  initial_interval: 1ms
+  max_interval: 1ms
+output:
+  dead_letter_test_output: {}
+dead_letter:
+  dead_letter_test_dead_letter: {}
+`, deadLetterTestEnv(t, output, deadLetter))
+	require.NoError(t, err)
+
+	d, err := newDeadLetterOutputFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+	require.NoError(t, d.Connect(context.Background()))
+	t.Cleanup(func() {
+		ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+		defer done()
+		require.NoError(t, d.Close(ctx))
+	})
+
+	require.NoError(t, d.WriteBatch(context.Background(), testBatch()))
+	assert.Equal(t, int64(3), output.calls.Load())
+	assert.Equal(t, int64(0), deadLetter.calls.Load())
+}
+
+func TestDeadLetterOutputExhausted(t *testing.T) {
+	output := &funcOutput{writeFn: func(context.Context, service.MessageBatch) error { return errors.New("nope") }}
+
+	var dead service.MessageBatch
+	deadLetter := &funcOutput{writeFn: func(_ context.Context, b service.MessageBatch) error {
+		dead = b
+		return nil
+	}}
+
+	pConf, err := deadLetterOutputSpec().ParseYAML(`
+max_retries: 2
+backoff:
+  initial_interval: 1ms
+  max_interval: 1ms
+output:
+  dead_letter_test_output: {}
+dead_letter:
+  dead_letter_test_dead_letter: {}
+`, deadLetterTestEnv(t, output, deadLetter))
+	require.NoError(t, err)
+
+	d, err := newDeadLetterOutputFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+	d.nowFn = func() time.Time { return time.Unix(1700000000, 0).UTC() }
+	require.NoError(t, d.Connect(context.Background()))
+	t.Cleanup(func() {
+		ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+		defer done()
+		require.NoError(t, d.Close(ctx))
+	})
+
+	msg := service.NewMessage([]byte("hello"))
+	msg.MetaSetMut("origin", "foo")
+	require.NoError(t, d.WriteBatch(context.Background(), service.MessageBatch{msg}))
+	assert.Equal(t, int64(3), output.calls.Load())
+
+	require.Len(t, dead, 1)
+	b, err := dead[0].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(b))
+
+	meta := map[string]any{}
+	require.NoError(t, dead[0].MetaWalkMut(func(k string, v any) error {
+		meta[k] = v
+		return nil
+	}))
+	assert.Equal(t, map[string]any{
+		"origin":                "foo",
+		"dead_letter_error":     "nope",
+		"dead_letter_attempts":  3,
+		"dead_letter_failed_at": "2023-11-14T22:13:20Z",
+	}, meta)
+}
+
+func TestDeadLetterOutputPartialFailure(t *testing.T) {
+	var mut sync.Mutex
+	var writes [][]string
+
+	output := &funcOutput{writeFn: func(_ context.Context, b service.MessageBatch) error {
+		var contents []string
+		bErr := service.NewBatchError(b, errors.New("some failed"))
+		for i, m := range b {
+			c, _ := m.AsBytes()
+			contents = append(contents, string(c))
+			if string(c) == "bad" {
+				bErr = bErr.Failed(i, errors.New("bad message"))
+			}
+		}
+		mut.Lock()
+		writes = append(writes, contents)
+		mut.Unlock()
+		if bErr.IndexedErrors() == 0 {
+			return nil
+		}
+		return bErr
+	}}
+
+	var dead service.MessageBatch
+	deadLetter := &funcOutput{writeFn: func(_ context.Context, b service.MessageBatch) error {
+		dead = b
+		return nil
+	}}
+
+	pConf, err := deadLetterOutputSpec().ParseYAML(`
+max_retries: 1
+backoff:
+  initial_interval: 1ms
+  max_interval: 1ms
+output:
+  dead_letter_test_output: {}
+dead_letter:
+  dead_letter_test_dead_letter: {}
+`, deadLetterTestEnv(t, output, deadLetter))
+	require.NoError(t, err)
+
+	d, err := newDeadLetterOutputFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+	require.NoError(t, d.Connect(context.Background()))
+	t.Cleanup(func() {
+		ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+		defer done()
+		require.NoError(t, d.Close(ctx))
+	})
+
+	require.NoError(t, d.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte("good")),
+		service.NewMessage([]byte("bad")),
+		service.NewMessage([]byte("also good")),
+	}))
+
+	assert.Equal(t, [][]string{{"good", "bad", "also good"}, {"bad"}}, writes)
+	require.Len(t, dead, 1)
+	errStr, _ := dead[0].MetaGetMut("dead_letter_error")
+	assert.Equal(t, "bad message", errStr)
+}
+
+func TestDeadLetterOutputDeadLetterFails(t *testing.T) {
+	output := &funcOutput{writeFn: func(context.Context, service.MessageBatch) error { return errors.New("nope") }}
+	deadLetter := &funcOutput{writeFn: func(context.Context, service.MessageBatch) error { return errors.New("also nope") }}
+
+	pConf, err := deadLetterOutputSpec().ParseYAML(`
+max_retries: 1
+backoff:
+  initial_interval: 1ms
+  max_interval: 1ms
+output:
+  dead_letter_test_output: {}
+dead_letter:
+  dead_letter_test_dead_letter: {}
+`, deadLetterTestEnv(t, output, deadLetter))
+	require.NoError(t, err)
+
+	d, err := newDeadLetterOutputFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+	require.NoError(t, d.Connect(context.Background()))
+	t.Cleanup(func() {
+		ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+		defer done()
+		require.NoError(t, d.Close(ctx))
+	})
+
+	err = d.WriteBatch(context.Background(), testBatch())
+	require.ErrorContains(t, err, "failed to write to dead letter output")
+}
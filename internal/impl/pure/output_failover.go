@@ -0,0 +1,198 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	foFieldOutputs = "outputs"
+	foFieldOutput  = "output"
+)
+
+func failoverOutputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Version("4.45.0").
+		Summary("Writes batches to the highest priority healthy output from a list, switching over to lower priority outputs when it becomes unhealthy and switching back once it recovers.").
+		Description(`
+Outputs are listed in priority order, and the active output is the first one that is considered healthy. When a write to the active output fails the batch is written to each of the other healthy outputs in order until one succeeds, and the write is only considered failed when all of them have failed.
+
+=== Health
+
+An output becomes unhealthy after `+"`failure_threshold`"+` consecutive failures, where a failure is either a failed write or a failed health check. When an output has a `+"`health_check`"+` it remains unhealthy until a check succeeds, otherwise it is tried again once `+"`failback_delay`"+` has passed.
+
+When a higher priority output recovers it must remain healthy for `+"`failback_delay`"+` before batches are switched back to it. Batches that were delivered to a lower priority output during the outage are not replayed to the recovered output, and therefore deployments that require all data to end up in the same place should replicate data between the targets.
+
+== Metrics
+
+This output emits a gauge `+"`failover_active_index`"+` which is set to the index of the active output, and a counter `+"`failover_switched`"+` which is incremented each time the active output changes.`).
+		Fields(
+			service.NewObjectListField(foFieldOutputs,
+				service.NewOutputField(foFieldOutput).
+					Description("The output to write batches to."),
+				failoverHealthCheckField(),
+			).Description("A list of outputs in priority order, each with an optional health check."),
+		).
+		Fields(failoverCommonFields()...).
+		Fields(service.NewOutputMaxInFlightField()).
+		Example("Multi-Region Kafka", "Write to a Kafka cluster in the local region, switching over to a cluster in another region when the local cluster fails writes or its health endpoint reports it unavailable.", `
+output:
+  failover:
+    failure_threshold: 3
+    failback_delay: 5m
+    outputs:
+      - output:
+          kafka_franz:
+            seed_brokers: [ kafka.eu-west.example.com:9092 ]
+            topic: events
+        health_check:
+          - http:
+              url: https://kafka.eu-west.example.com/health
+              verb: GET
+      - output:
+          kafka_franz:
+            seed_brokers: [ kafka.eu-central.example.com:9092 ]
+            topic: events
+`)
+}
+
+func init() {
+	err := service.RegisterBatchOutput("failover", failoverOutputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPol service.BatchPolicy, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldMaxInFlight(); err != nil {
+				return
+			}
+			out, err = newFailoverOutputFromParsed(conf, mgr)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type failoverOutput struct {
+	outputs     []*service.OwnedOutput
+	checks      [][]*service.OwnedProcessor
+	checkPeriod time.Duration
+	health      *failoverHealth
+
+	primeMut sync.Mutex
+	primed   bool
+
+	checksCtx  context.Context
+	checksDone func()
+	checksWG   sync.WaitGroup
+}
+
+func newFailoverOutputFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*failoverOutput, error) {
+	f := &failoverOutput{}
+
+	var err error
+	if f.checkPeriod, err = conf.FieldDuration(foFieldCheckPeriod); err != nil {
+		return nil, err
+	}
+
+	childConfs, err := conf.FieldObjectList(foFieldOutputs)
+	if err != nil {
+		return nil, err
+	}
+	if len(childConfs) == 0 {
+		return nil, errors.New("at least one output must be specified")
+	}
+
+	var labels []string
+	var hasChecks []bool
+	for i, cConf := range childConfs {
+		out, err := cConf.FieldOutput(foFieldOutput)
+		if err != nil {
+			return nil, fmt.Errorf("output %v: %w", i, err)
+		}
+		var procs []*service.OwnedProcessor
+		if cConf.Contains(foFieldHealthCheck) {
+			if procs, err = cConf.FieldProcessorList(foFieldHealthCheck); err != nil {
+				return nil, fmt.Errorf("output %v: %w", i, err)
+			}
+		}
+		f.outputs = append(f.outputs, out)
+		f.checks = append(f.checks, procs)
+		labels = append(labels, strconv.Itoa(i))
+		hasChecks = append(hasChecks, len(procs) > 0)
+	}
+
+	if f.health, err = newFailoverHealth(conf, mgr, labels, hasChecks); err != nil {
+		return nil, err
+	}
+	f.checksCtx, f.checksDone = context.WithCancel(context.Background())
+	return f, nil
+}
+
+func (f *failoverOutput) Connect(ctx context.Context) error {
+	f.primeMut.Lock()
+	defer f.primeMut.Unlock()
+	if f.primed {
+		return nil
+	}
+	for _, out := range f.outputs {
+		if err := out.Prime(); err != nil {
+			return err
+		}
+	}
+	f.checksWG.Add(1)
+	go func() {
+		defer f.checksWG.Done()
+		failoverCheckLoop(f.checksCtx, f.checkPeriod, f.health, f.checks)
+	}()
+	f.primed = true
+	return nil
+}
+
+func (f *failoverOutput) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	var errs []error
+	for _, i := range f.health.candidates() {
+		err := f.outputs[i].WriteBatch(ctx, batch.Copy())
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		f.health.record(i, err)
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, fmt.Errorf("output %v: %w", i, err))
+	}
+	return errors.Join(errs...)
+}
+
+func (f *failoverOutput) Close(ctx context.Context) error {
+	f.checksDone()
+	f.checksWG.Wait()
+
+	errs := []error{failoverCloseChecks(ctx, f.checks)}
+	for _, out := range f.outputs {
+		errs = append(errs, out.Close(ctx))
+	}
+	return errors.Join(errs...)
+}
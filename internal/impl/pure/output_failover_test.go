@@ -0,0 +1,147 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func testFailoverHealth(t *testing.T, conf string, hasChecks ...bool) (*failoverHealth, *time.Time) {
+	t.Helper()
+
+	pConf, err := service.NewConfigSpec().Fields(failoverCommonFields()...).ParseYAML(conf, nil)
+	require.NoError(t, err)
+
+	labels := make([]string, len(hasChecks))
+	f, err := newFailoverHealth(pConf, service.MockResources(), labels, hasChecks)
+	require.NoError(t, err)
+
+	now := time.Unix(1000, 0)
+	f.nowFn = func() time.Time { return now }
+	return f, &now
+}
+
+func TestFailoverHealthFailback(t *testing.T) {
+	f, now := testFailoverHealth(t, `
+failure_threshold: 2
+failback_delay: 1m
+`, true, false)
+
+	i, changed := f.current()
+	assert.Equal(t, 0, i)
+
+	f.record(0, errors.New("nope"))
+	i, _ = f.current()
+	assert.Equal(t, 0, i)
+
+	f.record(0, errors.New("nope"))
+	i, _ = f.current()
+	assert.Equal(t, 1, i)
+
+	select {
+	case <-changed:
+	default:
+		t.Fatal("expected change channel to be closed")
+	}
+
+	// Recovered but must remain healthy for the failback delay.
+	f.record(0, nil)
+	i, _ = f.current()
+	assert.Equal(t, 1, i)
+
+	*now = now.Add(time.Minute)
+	i, _ = f.current()
+	assert.Equal(t, 0, i)
+}
+
+func TestFailoverHealthUncheckedRetry(t *testing.T) {
+	f, now := testFailoverHealth(t, `
+failure_threshold: 1
+failback_delay: 10s
+`, false, false)
+
+	f.record(0, errors.New("nope"))
+	assert.Equal(t, []int{1}, f.candidates())
+
+	// Without health checks the child is tried again after the delay.
+	*now = now.Add(time.Second * 10)
+	assert.Equal(t, []int{0, 1}, f.candidates())
+}
+
+func TestFailoverHealthEnded(t *testing.T) {
+	f, _ := testFailoverHealth(t, ``, false, false)
+
+	assert.False(t, f.markEnded(0))
+	i, _ := f.current()
+	assert.Equal(t, 1, i)
+	assert.True(t, f.markEnded(1))
+}
+
+func TestFailoverOutputSwitchover(t *testing.T) {
+	failing := &funcOutput{writeFn: func(context.Context, service.MessageBatch) error { return errors.New("region down") }}
+	healthy := &funcOutput{writeFn: func(context.Context, service.MessageBatch) error { return nil }}
+
+	env := service.NewEnvironment()
+	for name, out := range map[string]*funcOutput{
+		"failover_test_failing": failing,
+		"failover_test_healthy": healthy,
+	} {
+		out := out
+		require.NoError(t, env.RegisterBatchOutput(name, service.NewConfigSpec(),
+			func(*service.ParsedConfig, *service.Resources) (service.BatchOutput, service.BatchPolicy, int, error) {
+				return out, service.BatchPolicy{}, 1, nil
+			}))
+	}
+
+	pConf, err := failoverOutputSpec().ParseYAML(`
+failure_threshold: 2
+failback_delay: 1h
+outputs:
+  - output:
+      failover_test_failing: {}
+  - output:
+      failover_test_healthy: {}
+`, env)
+	require.NoError(t, err)
+
+	f, err := newFailoverOutputFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+	require.NoError(t, f.Connect(context.Background()))
+	t.Cleanup(func() {
+		ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+		defer done()
+		require.NoError(t, f.Close(ctx))
+	})
+
+	for j := 0; j < 2; j++ {
+		require.NoError(t, f.WriteBatch(context.Background(), testBatch()))
+		i, _ := f.health.current()
+		assert.Equal(t, j, i)
+	}
+	assert.Equal(t, int64(2), healthy.calls.Load())
+
+	failingCalls := failing.calls.Load()
+	require.NoError(t, f.WriteBatch(context.Background(), testBatch()))
+	assert.Equal(t, failingCalls, failing.calls.Load())
+	assert.Equal(t, int64(3), healthy.calls.Load())
+}
@@ -0,0 +1,302 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	hoFieldPrimary          = "primary"
+	hoFieldSecondary        = "secondary"
+	hoFieldHedgeDelay       = "hedge_delay"
+	hoFieldFailureThreshold = "failure_threshold"
+	hoFieldFailbackPeriod   = "failback_period"
+	hoFieldMaxInFlight      = "max_in_flight"
+)
+
+func hedgedOutputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Version("4.45.0").
+		Summary("Writes each batch to a primary output and falls back to, or races against, a secondary output in order to improve the availability and tail latency of a delivery path.").
+		Description(`
+Each batch is first written to the `+"`primary`"+` output. If the write fails the batch is then written to the `+"`secondary`"+` output, and the write is only considered failed when both outputs have failed.
+
+When `+"`hedge_delay`"+` is set and the primary has not completed a write within that delay the batch is also written to the secondary, and the first of the two to succeed acknowledges the batch. The result of the slower of the two writes is ignored but it may still be delivered, and therefore downstream consumers must tolerate duplicates when hedging is enabled.
+
+=== Failback
+
+After `+"`failure_threshold`"+` consecutive failed writes to the primary it is bypassed, and batches are written only to the secondary for the duration of `+"`failback_period`"+`. Once the period has passed the primary is tried again, and the output fails back to it as soon as it succeeds.
+
+== Metrics
+
+This output emits a counter `+"`hedged_secondary_sent`"+` which is incremented each time a batch is written to the secondary output, and a counter `+"`hedged_primary_bypassed`"+` which is incremented each time the primary is bypassed after reaching the failure threshold.`).
+		Fields(
+			service.NewOutputField(hoFieldPrimary).
+				Description("The primary output to write batches to."),
+			service.NewOutputField(hoFieldSecondary).
+				Description("The secondary output to write batches to when the primary fails, is slow to respond, or is bypassed."),
+			service.NewDurationField(hoFieldHedgeDelay).
+				Description("An optional delay after which a batch that has not yet been written by the primary is also written to the secondary. When omitted the secondary is only used after the primary fails.").
+				Example("100ms").
+				Example("1s").
+				Optional(),
+			service.NewIntField(hoFieldFailureThreshold).
+				Description("The number of consecutive failed writes to the primary after which it is bypassed for `failback_period`. Set to zero in order to disable bypassing the primary.").
+				Default(3),
+			service.NewDurationField(hoFieldFailbackPeriod).
+				Description("The period of time for which the primary is bypassed after reaching the failure threshold, after which it is tried again.").
+				Default("30s"),
+			service.NewOutputMaxInFlightField(),
+		).
+		Example("Hedged Requests", "Send batches to the closest of two HTTP endpoints, also sending to a second region when the first is slow to respond.", `
+output:
+  hedged:
+    hedge_delay: 200ms
+    primary:
+      http_client:
+        url: https://eu-west.example.com/ingest
+    secondary:
+      http_client:
+        url: https://eu-central.example.com/ingest
+`).
+		Example("Active-Passive", "Write to a primary Kafka cluster, switching to a standby cluster for a minute whenever the primary fails five writes in a row.", `
+output:
+  hedged:
+    failure_threshold: 5
+    failback_period: 1m
+    primary:
+      kafka_franz:
+        seed_brokers: [ primary-cluster:9092 ]
+        topic: events
+    secondary:
+      kafka_franz:
+        seed_brokers: [ standby-cluster:9092 ]
+        topic: events
+`)
+}
+
+func init() {
+	err := service.RegisterBatchOutput("hedged", hedgedOutputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPol service.BatchPolicy, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldMaxInFlight(); err != nil {
+				return
+			}
+			out, err = newHedgedOutputFromParsed(conf, mgr)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type hedgedOutput struct {
+	primary   *service.OwnedOutput
+	secondary *service.OwnedOutput
+
+	hedgeDelay       time.Duration
+	failureThreshold int
+	failbackPeriod   time.Duration
+
+	mSecondarySent   *service.MetricCounter
+	mPrimaryBypassed *service.MetricCounter
+	log              *service.Logger
+
+	primeMut sync.Mutex
+	primed   bool
+
+	// Tracks child writes that may outlive the write that started them.
+	writesWG sync.WaitGroup
+
+	nowFn        func() time.Time
+	stateMut     sync.Mutex
+	failures     int
+	bypassUntil  time.Time
+	bypassLogged bool
+}
+
+func newHedgedOutputFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*hedgedOutput, error) {
+	h := &hedgedOutput{
+		mSecondarySent:   mgr.Metrics().NewCounter("hedged_secondary_sent"),
+		mPrimaryBypassed: mgr.Metrics().NewCounter("hedged_primary_bypassed"),
+		log:              mgr.Logger(),
+		nowFn:            time.Now,
+	}
+
+	var err error
+	if conf.Contains(hoFieldHedgeDelay) {
+		if h.hedgeDelay, err = conf.FieldDuration(hoFieldHedgeDelay); err != nil {
+			return nil, err
+		}
+	}
+	if h.failureThreshold, err = conf.FieldInt(hoFieldFailureThreshold); err != nil {
+		return nil, err
+	}
+	if h.failbackPeriod, err = conf.FieldDuration(hoFieldFailbackPeriod); err != nil {
+		return nil, err
+	}
+	if h.primary, err = conf.FieldOutput(hoFieldPrimary); err != nil {
+		return nil, err
+	}
+	if h.secondary, err = conf.FieldOutput(hoFieldSecondary); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+func (h *hedgedOutput) Connect(ctx context.Context) error {
+	h.primeMut.Lock()
+	defer h.primeMut.Unlock()
+	if h.primed {
+		return nil
+	}
+	if err := h.primary.Prime(); err != nil {
+		return err
+	}
+	if err := h.secondary.Prime(); err != nil {
+		return err
+	}
+	h.primed = true
+	return nil
+}
+
+func (h *hedgedOutput) primaryBypassed() bool {
+	h.stateMut.Lock()
+	defer h.stateMut.Unlock()
+	return h.nowFn().Before(h.bypassUntil)
+}
+
+func (h *hedgedOutput) recordPrimary(err error) {
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+
+	h.stateMut.Lock()
+	defer h.stateMut.Unlock()
+
+	if err == nil {
+		if h.bypassLogged {
+			h.log.Info("Primary output has recovered, failing back")
+			h.bypassLogged = false
+		}
+		h.failures = 0
+		return
+	}
+
+	h.failures++
+	if h.failureThreshold > 0 && h.failures >= h.failureThreshold {
+		h.log.Warnf("Primary output failed %v consecutive writes, bypassing it for %v: %v", h.failures, h.failbackPeriod, err)
+		h.mPrimaryBypassed.Incr(1)
+		h.bypassUntil = h.nowFn().Add(h.failbackPeriod)
+		h.bypassLogged = true
+		h.failures = 0
+	}
+}
+
+type hedgedResult struct {
+	primary bool
+	err     error
+}
+
+func (h *hedgedOutput) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	if h.primaryBypassed() {
+		h.mSecondarySent.Incr(1)
+		return h.secondary.WriteBatch(ctx, batch)
+	}
+
+	writeCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan hedgedResult, 2)
+	write := func(primary bool, out *service.OwnedOutput) {
+		defer h.writesWG.Done()
+		err := out.WriteBatch(writeCtx, batch.Copy())
+		results <- hedgedResult{primary: primary, err: err}
+	}
+
+	h.writesWG.Add(1)
+	go write(true, h.primary)
+	pending := 1
+
+	var hedgeChan <-chan time.Time
+	if h.hedgeDelay > 0 {
+		hedgeTimer := time.NewTimer(h.hedgeDelay)
+		defer hedgeTimer.Stop()
+		hedgeChan = hedgeTimer.C
+	}
+
+	secondaryStarted := false
+	startSecondary := func() {
+		if secondaryStarted {
+			return
+		}
+		secondaryStarted = true
+		h.mSecondarySent.Incr(1)
+		h.writesWG.Add(1)
+		go write(false, h.secondary)
+		pending++
+	}
+
+	var primaryErr, secondaryErr error
+	for {
+		select {
+		case res := <-results:
+			pending--
+			if res.primary {
+				h.recordPrimary(res.err)
+				primaryErr = res.err
+			} else {
+				secondaryErr = res.err
+			}
+			if res.err == nil {
+				return nil
+			}
+			startSecondary()
+			if pending == 0 {
+				return fmt.Errorf("primary failed: %w, secondary failed: %w", primaryErr, secondaryErr)
+			}
+		case <-hedgeChan:
+			startSecondary()
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (h *hedgedOutput) Close(ctx context.Context) error {
+	// Abandoned writes are cancelled and so this wait should be brief, but
+	// they must finish before the children are closed.
+	writesDone := make(chan struct{})
+	go func() {
+		h.writesWG.Wait()
+		close(writesDone)
+	}()
+	select {
+	case <-writesDone:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return errors.Join(h.primary.Close(ctx), h.secondary.Close(ctx))
+}
@@ -0,0 +1,170 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+type funcOutput struct {
+	calls   atomic.Int64
+	writeFn func(ctx context.Context, b service.MessageBatch) error
+}
+
+func (f *funcOutput) Connect(context.Context) error { return nil }
+
+func (f *funcOutput) WriteBatch(ctx context.Context, b service.MessageBatch) error {
+	f.calls.Add(1)
+	return f.writeFn(ctx, b)
+}
+
+func (f *funcOutput) Close(context.Context) error { return nil }
+
+func testHedgedOutput(t *testing.T, primary, secondary *funcOutput, conf string) *hedgedOutput {
+	t.Helper()
+
+	env := service.NewEnvironment()
+	for name, out := range map[string]*funcOutput{
+		"hedged_test_primary":   primary,
+		"hedged_test_secondary": secondary,
+	} {
+		out := out
+		require.NoError(t, env.RegisterBatchOutput(name, service.NewConfigSpec(),
+			func(*service.ParsedConfig, *service.Resources) (service.BatchOutput, service.BatchPolicy, int, error) {
+				return out, service.BatchPolicy{}, 1, nil
+			}))
+	}
+
+	pConf, err := hedgedOutputSpec().ParseYAML(conf+`
+primary:
+  hedged_test_primary: {}
+secondary:
+  hedged_test_secondary: {}
+`, env)
+	require.NoError(t, err)
+
+	h, err := newHedgedOutputFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+	require.NoError(t, h.Connect(context.Background()))
+	t.Cleanup(func() {
+		ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+		defer done()
+		require.NoError(t, h.Close(ctx))
+	})
+	return h
+}
+
+func testBatch() service.MessageBatch {
+	return service.MessageBatch{service.NewMessage([]byte("hello"))}
+}
+
+func TestHedgedOutputPrimaryOnly(t *testing.T) {
+	primary := &funcOutput{writeFn: func(context.Context, service.MessageBatch) error { return nil }}
+	secondary := &funcOutput{writeFn: func(context.Context, service.MessageBatch) error { return nil }}
+
+	h := testHedgedOutput(t, primary, secondary, ``)
+	require.NoError(t, h.WriteBatch(context.Background(), testBatch()))
+
+	assert.Equal(t, int64(1), primary.calls.Load())
+	assert.Equal(t, int64(0), secondary.calls.Load())
+}
+
+func TestHedgedOutputFailover(t *testing.T) {
+	primary := &funcOutput{writeFn: func(context.Context, service.MessageBatch) error { return errors.New("primary down") }}
+	secondary := &funcOutput{writeFn: func(context.Context, service.MessageBatch) error { return nil }}
+
+	h := testHedgedOutput(t, primary, secondary, `failure_threshold: 0`)
+	require.NoError(t, h.WriteBatch(context.Background(), testBatch()))
+
+	// Double counted at the OwnedOutput layer is possible due to retries, so
+	// assert only that the secondary took the batch.
+	assert.GreaterOrEqual(t, primary.calls.Load(), int64(1))
+	assert.Equal(t, int64(1), secondary.calls.Load())
+}
+
+func TestHedgedOutputBothFail(t *testing.T) {
+	primary := &funcOutput{writeFn: func(context.Context, service.MessageBatch) error { return errors.New("primary down") }}
+	secondary := &funcOutput{writeFn: func(context.Context, service.MessageBatch) error { return errors.New("secondary down") }}
+
+	h := testHedgedOutput(t, primary, secondary, `failure_threshold: 0`)
+	err := h.WriteBatch(context.Background(), testBatch())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "primary down")
+	assert.Contains(t, err.Error(), "secondary down")
+}
+
+func TestHedgedOutputHedgeDelay(t *testing.T) {
+	primary := &funcOutput{writeFn: func(context.Context, service.MessageBatch) error {
+		time.Sleep(time.Millisecond * 200)
+		return nil
+	}}
+	secondary := &funcOutput{writeFn: func(context.Context, service.MessageBatch) error { return nil }}
+
+	h := testHedgedOutput(t, primary, secondary, `hedge_delay: 10ms`)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	require.NoError(t, h.WriteBatch(ctx, testBatch()))
+	assert.Equal(t, int64(1), secondary.calls.Load())
+}
+
+func TestHedgedOutputFailback(t *testing.T) {
+	var primaryDown atomic.Bool
+	primaryDown.Store(true)
+
+	primary := &funcOutput{writeFn: func(context.Context, service.MessageBatch) error {
+		if primaryDown.Load() {
+			return errors.New("primary down")
+		}
+		return nil
+	}}
+	secondary := &funcOutput{writeFn: func(context.Context, service.MessageBatch) error { return nil }}
+
+	h := testHedgedOutput(t, primary, secondary, `
+failure_threshold: 2
+failback_period: 1m
+`)
+
+	now := time.Now()
+	h.nowFn = func() time.Time { return now }
+
+	ctx := context.Background()
+	require.NoError(t, h.WriteBatch(ctx, testBatch()))
+	require.NoError(t, h.WriteBatch(ctx, testBatch()))
+	assert.True(t, h.primaryBypassed())
+
+	primaryCalls := primary.calls.Load()
+	require.NoError(t, h.WriteBatch(ctx, testBatch()))
+	assert.Equal(t, primaryCalls, primary.calls.Load())
+	assert.Equal(t, int64(3), secondary.calls.Load())
+
+	primaryDown.Store(false)
+	now = now.Add(time.Minute * 2)
+	assert.False(t, h.primaryBypassed())
+
+	require.NoError(t, h.WriteBatch(ctx, testBatch()))
+	assert.Equal(t, primaryCalls+1, primary.calls.Load())
+	assert.Equal(t, int64(3), secondary.calls.Load())
+}
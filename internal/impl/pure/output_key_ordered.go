@@ -0,0 +1,260 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	koFieldOutput   = "output"
+	koFieldKey      = "key"
+	koFieldRetries  = "retries"
+	koFieldBatching = "batching"
+)
+
+func keyOrderedOutputSpec() *service.ConfigSpec {
+	retriesDefaults := backoff.NewExponentialBackOff()
+	retriesDefaults.InitialInterval = time.Millisecond * 500
+	retriesDefaults.MaxInterval = time.Second * 10
+	retriesDefaults.MaxElapsedTime = 0
+
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Version("4.45.0").
+		Summary("Writes messages to a child output with strict ordering of messages that share a key, even in the presence of write failures and retries.").
+		Description(`
+Outputs dispatch messages in parallel up to their `+"`max_in_flight`"+`, and when a write fails the message is retried later, by which time messages that followed it may have already been delivered. This output prevents such reordering for messages that share a key: batches are written one at a time, and failed writes are retried in place with a backoff until they succeed before the next batch is written.
+
+Within a batch the messages of each key are written to the child output in parallel, and therefore parallelism is achieved by forming batches that span many keys with the `+"`batching`"+` field. The child output receives one batch per key, with messages in the order in which they reached this output.
+
+Messages are ordered by the order in which they reach this output, and therefore the order of messages that share a key must also be preserved by the input and processors of the pipeline, which is typically the case for inputs that consume partitions in order, such as Kafka.
+
+If `+"`retries.max_elapsed_time`"+` is set and exceeded the failed messages are rejected, at which point ordering is no longer guaranteed for their keys. Ordering is also not guaranteed for messages that are still being retried when the output is shut down. By default retries are unbounded.
+
+This output can be used to give an ordering guarantee to any output, including brokers such as `+"`fan_out`"+`, in which case all the outputs of the broker receive messages of a key in order.`).
+		Fields(
+			service.NewOutputField(koFieldOutput).
+				Description("The child output to write messages to."),
+			service.NewInterpolatedStringField(koFieldKey).
+				Description("The key by which messages are ordered.").
+				Example(`${! meta("kafka_key") }`).
+				Example(`${! json("customer_id") }`),
+			service.NewBackOffField(koFieldRetries, true, retriesDefaults).
+				Advanced(),
+			service.NewBatchPolicyField(koFieldBatching),
+		).
+		Example("Ordered Fan Out", "Deliver events to two HTTP services, ensuring that the events of each customer arrive at both services in order even when writes fail.", `
+output:
+  key_ordered:
+    key: ${! meta("kafka_key") }
+    batching:
+      count: 100
+      period: 100ms
+    output:
+      broker:
+        pattern: fan_out
+        outputs:
+          - http_client:
+              url: https://billing.example.com/events
+          - http_client:
+              url: https://audit.example.com/events
+`)
+}
+
+func init() {
+	err := service.RegisterBatchOutput("key_ordered", keyOrderedOutputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPol service.BatchPolicy, maxInFlight int, err error) {
+			if batchPol, err = conf.FieldBatchPolicy(koFieldBatching); err != nil {
+				return
+			}
+			// Writing more than one batch at a time would allow batches that
+			// share a key to reach the child output in either order.
+			maxInFlight = 1
+			out, err = newKeyOrderedOutputFromParsed(conf, mgr)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type keyOrderedOutput struct {
+	child   *service.OwnedOutput
+	key     *service.InterpolatedString
+	retries *backoff.ExponentialBackOff
+	log     *service.Logger
+
+	primeOnce sync.Once
+	primeErr  error
+}
+
+func newKeyOrderedOutputFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*keyOrderedOutput, error) {
+	k := &keyOrderedOutput{
+		log: mgr.Logger(),
+	}
+
+	var err error
+	if k.key, err = conf.FieldInterpolatedString(koFieldKey); err != nil {
+		return nil, err
+	}
+	if k.retries, err = conf.FieldBackOff(koFieldRetries); err != nil {
+		return nil, err
+	}
+	if k.child, err = conf.FieldOutput(koFieldOutput); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+func (k *keyOrderedOutput) Connect(ctx context.Context) error {
+	k.primeOnce.Do(func() {
+		k.primeErr = k.child.Prime()
+	})
+	return k.primeErr
+}
+
+func (k *keyOrderedOutput) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	keyExec := batch.InterpolationExecutor(k.key)
+
+	// Group the indexes of the batch by key, preserving the order of messages
+	// within each key.
+	var groups [][]int
+	groupOf := map[string]int{}
+	for i := range batch {
+		key, err := keyExec.TryString(i)
+		if err != nil {
+			return err
+		}
+		g, exists := groupOf[key]
+		if !exists {
+			g = len(groups)
+			groupOf[key] = g
+			groups = append(groups, nil)
+		}
+		groups[g] = append(groups[g], i)
+	}
+
+	if len(groups) == 1 {
+		failed, err := k.writeWithRetries(ctx, batch)
+		if err == nil || len(failed) == len(batch) {
+			return err
+		}
+		retErr := service.NewBatchError(batch, err)
+		for _, i := range failed {
+			retErr.Failed(i, err)
+		}
+		return retErr
+	}
+
+	var wg sync.WaitGroup
+	var resMut sync.Mutex
+	var retErr *service.BatchError
+	for _, indexes := range groups {
+		wg.Add(1)
+		go func(indexes []int) {
+			defer wg.Done()
+
+			group := make(service.MessageBatch, len(indexes))
+			for i, index := range indexes {
+				group[i] = batch[index]
+			}
+			failed, err := k.writeWithRetries(ctx, group)
+			if err == nil {
+				return
+			}
+
+			resMut.Lock()
+			defer resMut.Unlock()
+			if retErr == nil {
+				retErr = service.NewBatchError(batch, err)
+			}
+			for _, i := range failed {
+				retErr.Failed(indexes[i], err)
+			}
+		}(indexes)
+	}
+	wg.Wait()
+
+	if retErr != nil {
+		return retErr
+	}
+	return nil
+}
+
+// writeWithRetries writes a batch to the child output, retrying only the
+// messages that failed until all are delivered or the retries are exhausted,
+// in which case the indexes of the undelivered messages are returned.
+func (k *keyOrderedOutput) writeWithRetries(ctx context.Context, batch service.MessageBatch) ([]int, error) {
+	boff := *k.retries
+	boff.Reset()
+
+	// Tracks the indexes of the original batch that are yet to be delivered.
+	pendingIndexes := make([]int, len(batch))
+	for i := range batch {
+		pendingIndexes[i] = i
+	}
+
+	pending := batch
+	for {
+		indexer := pending.Index()
+		err := k.child.WriteBatch(ctx, pending)
+		if err == nil {
+			return nil, nil
+		}
+
+		var bErr *service.BatchError
+		if errors.As(err, &bErr) {
+			var failed service.MessageBatch
+			var failedIndexes []int
+			bErr.WalkMessagesIndexedBy(indexer, func(i int, m *service.Message, mErr error) bool {
+				if mErr != nil && i >= 0 && i < len(pendingIndexes) {
+					failed = append(failed, m)
+					failedIndexes = append(failedIndexes, pendingIndexes[i])
+				}
+				return true
+			})
+			if len(failed) > 0 {
+				pending, pendingIndexes = failed, failedIndexes
+			}
+		}
+
+		wait := boff.NextBackOff()
+		if wait == backoff.Stop {
+			return pendingIndexes, err
+		}
+		k.log.Warnf("Failed to write ordered messages, retrying in %v: %v", wait, err)
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return pendingIndexes, ctx.Err()
+		}
+	}
+}
+
+func (k *keyOrderedOutput) Close(ctx context.Context) error {
+	return k.child.Close(ctx)
+}
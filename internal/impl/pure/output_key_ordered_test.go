@@ -0,0 +1,193 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func testKeyOrderedOutput(t *testing.T, child *funcOutput, conf string) *keyOrderedOutput {
+	t.Helper()
+
+	env := service.NewEnvironment()
+	require.NoError(t, env.RegisterBatchOutput("key_ordered_test", service.NewConfigSpec(),
+		func(*service.ParsedConfig, *service.Resources) (service.BatchOutput, service.BatchPolicy, int, error) {
+			return child, service.BatchPolicy{}, 10, nil
+		}))
+
+	pConf, err := keyOrderedOutputSpec().ParseYAML(conf+`
+output:
+  key_ordered_test: {}
+`, env)
+	require.NoError(t, err)
+
+	k, err := newKeyOrderedOutputFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+	require.NoError(t, k.Connect(context.Background()))
+	t.Cleanup(func() {
+		ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+		defer done()
+		require.NoError(t, k.Close(ctx))
+	})
+	return k
+}
+
+func TestKeyOrderedOutputRetriesInOrder(t *testing.T) {
+	var mut sync.Mutex
+	var delivered []string
+	failuresLeft := map[string]int{"a1": 2}
+
+	child := &funcOutput{writeFn: func(_ context.Context, b service.MessageBatch) error {
+		mut.Lock()
+		defer mut.Unlock()
+
+		var bErr *service.BatchError
+		for i, m := range b {
+			mBytes, _ := m.AsBytes()
+			if failuresLeft[string(mBytes)] > 0 {
+				failuresLeft[string(mBytes)]--
+				if bErr == nil {
+					bErr = service.NewBatchError(b, errors.New("nope"))
+				}
+				bErr.Failed(i, errors.New("nope"))
+				continue
+			}
+			delivered = append(delivered, string(mBytes))
+		}
+		if bErr != nil {
+			return bErr
+		}
+		return nil
+	}}
+
+	k := testKeyOrderedOutput(t, child, `
+key: ${! content().slice(0, 1) }
+retries:
+  initial_interval: 10ms
+  max_interval: 10ms
+`)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	write := func(contents ...string) {
+		var b service.MessageBatch
+		for _, c := range contents {
+			b = append(b, service.NewMessage([]byte(c)))
+		}
+		require.NoError(t, k.WriteBatch(ctx, b))
+	}
+
+	write("a1", "b1", "b2")
+	write("a2", "b3")
+
+	mut.Lock()
+	defer mut.Unlock()
+
+	indexOf := func(v string) int {
+		for i, d := range delivered {
+			if d == v {
+				return i
+			}
+		}
+		return -1
+	}
+	require.Len(t, delivered, 5)
+	assert.Less(t, indexOf("b1"), indexOf("a1"), "messages of other keys should not wait for retries")
+	assert.Less(t, indexOf("b2"), indexOf("b3"))
+	assert.Less(t, indexOf("a1"), indexOf("a2"))
+}
+
+func TestKeyOrderedOutputSplitsKeys(t *testing.T) {
+	var mut sync.Mutex
+	var batches [][]string
+
+	child := &funcOutput{writeFn: func(_ context.Context, b service.MessageBatch) error {
+		var contents []string
+		for _, m := range b {
+			mBytes, _ := m.AsBytes()
+			contents = append(contents, string(mBytes))
+		}
+		mut.Lock()
+		batches = append(batches, contents)
+		mut.Unlock()
+		return nil
+	}}
+
+	k := testKeyOrderedOutput(t, child, `
+key: ${! content().slice(0, 1) }
+`)
+
+	require.NoError(t, k.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte("a1")),
+		service.NewMessage([]byte("b1")),
+		service.NewMessage([]byte("a2")),
+		service.NewMessage([]byte("b2")),
+	}))
+
+	mut.Lock()
+	defer mut.Unlock()
+	assert.ElementsMatch(t, [][]string{{"a1", "a2"}, {"b1", "b2"}}, batches)
+}
+
+func TestKeyOrderedOutputRetriesExhausted(t *testing.T) {
+	child := &funcOutput{writeFn: func(_ context.Context, b service.MessageBatch) error {
+		bErr := service.NewBatchError(b, errors.New("nope"))
+		for i, m := range b {
+			if mBytes, _ := m.AsBytes(); string(mBytes) == "bad" {
+				bErr.Failed(i, errors.New("nope"))
+			}
+		}
+		return bErr
+	}}
+
+	k := testKeyOrderedOutput(t, child, `
+key: foo
+retries:
+  initial_interval: 1ms
+  max_interval: 1ms
+  max_elapsed_time: 20ms
+`)
+
+	batch := service.MessageBatch{
+		service.NewMessage([]byte("good")),
+		service.NewMessage([]byte("bad")),
+	}
+	indexer := batch.Index()
+	err := k.WriteBatch(context.Background(), batch)
+	require.Error(t, err)
+
+	var bErr *service.BatchError
+	require.ErrorAs(t, err, &bErr)
+	assert.Equal(t, 1, bErr.IndexedErrors())
+
+	var failed []int
+	bErr.WalkMessagesIndexedBy(indexer, func(i int, _ *service.Message, err error) bool {
+		if err != nil {
+			failed = append(failed, i)
+		}
+		return true
+	})
+	assert.Equal(t, []int{1}, failed)
+}
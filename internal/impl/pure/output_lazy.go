@@ -0,0 +1,376 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	loFieldOutput    = "output"
+	loFieldInit      = "init"
+	loFieldDependsOn = "depends_on"
+	loFieldBackoff   = "backoff"
+
+	loInitFirstUse = "first_use"
+	loInitStartup  = "startup"
+)
+
+func lazyOutputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Version("4.45.0").
+		Summary("Defers the initialisation of a child output until it is first used, or until other outputs have been initialised, retrying failed initialisations with an exponential backoff.").
+		Description(`
+By default the child output is only created once the first batch is written to it, which allows pipelines with many rarely used outputs, such as the cases of a `+"`switch`"+` output, to start quickly. Failing to create the child output, which some outputs do when a downstream service is unavailable, does not prevent the pipeline from starting, and is instead retried according to `+"`backoff`"+` while writes wait for it. If the backoff is exhausted then all writes to this output fail.
+
+Once created the child connects in the background as usual, and therefore initialisation refers to the creation of the output rather than a successful connection.
+
+=== Initialisation Order
+
+The field `+"`depends_on`"+` lists the labels of other `+"`lazy`"+` outputs that must finish initialising before this one is initialised, which includes outputs that are used for the first time by this one, and dependencies that have not yet been used are initialised on demand. Dependencies that form a cycle are rejected when the config is loaded. A label that does not belong to a `+"`lazy`"+` output is never initialised, and an output depending on it therefore waits indefinitely.
+
+== Metrics
+
+This output emits a counter `+"`lazy_init_failed`"+` which is incremented each time an attempt to initialise the child output fails.`).
+		Fields(
+			service.NewOutputField(loFieldOutput).
+				Description("The child output to initialise lazily."),
+			service.NewStringAnnotatedEnumField(loFieldInit, map[string]string{
+				loInitFirstUse: "Initialise the child output when the first batch is written to it, or when another output depends on it.",
+				loInitStartup:  "Initialise the child output in the background when the pipeline starts, after any dependencies.",
+			}).
+				Description("When to initialise the child output.").
+				Default(loInitFirstUse),
+			service.NewStringListField(loFieldDependsOn).
+				Description("The labels of other `lazy` outputs that must be initialised before this one.").
+				Default([]string{}),
+			service.NewBackOffField(loFieldBackoff, true, &backoff.ExponentialBackOff{
+				InitialInterval: time.Second,
+				MaxInterval:     time.Minute,
+				MaxElapsedTime:  0,
+			}).Advanced(),
+			service.NewOutputMaxInFlightField(),
+		).
+		Example("Optional Sinks", "Route rare events to an archive bucket that is only created once an event requires it.", `
+output:
+  switch:
+    cases:
+      - check: this.type == "audit"
+        output:
+          lazy:
+            output:
+              aws_s3:
+                bucket: audit-archive
+                path: ${! timestamp_unix_nano() }.json
+      - output:
+          kafka_franz:
+            seed_brokers: [ localhost:9092 ]
+            topic: events
+`).
+		Example("Ordered Startup", "Initialise a database output only after the schema migration output it depends on has been initialised.", `
+output:
+  broker:
+    pattern: fan_out_sequential
+    outputs:
+      - label: migrations
+        lazy:
+          init: startup
+          output:
+            sql_raw:
+              driver: postgres
+              dsn: postgres://localhost:5432/db
+              query: SELECT 1
+      - label: rows
+        lazy:
+          init: startup
+          depends_on: [ migrations ]
+          output:
+            sql_insert:
+              driver: postgres
+              dsn: postgres://localhost:5432/db
+              table: rows
+              columns: [ id, doc ]
+              args_mapping: root = [ this.id, content().string() ]
+`)
+}
+
+func init() {
+	err := service.RegisterBatchOutput("lazy", lazyOutputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPol service.BatchPolicy, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldMaxInFlight(); err != nil {
+				return
+			}
+			out, err = newLazyOutputFromParsed(conf, mgr, mgr.Label())
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type lazyOutputKeyType int
+
+var lazyOutputKey lazyOutputKeyType
+
+type lazyOutputNode struct {
+	registered bool
+	requested  bool
+	start      func()
+	dependsOn  []string
+
+	// Closed once initialisation has finished, successfully or not.
+	done chan struct{}
+}
+
+// lazyOutputRegister tracks the lazy outputs of a manager by label in order to
+// resolve dependencies between them.
+type lazyOutputRegister struct {
+	mut   sync.Mutex
+	nodes map[string]*lazyOutputNode
+}
+
+func getLazyOutputRegister(res *service.Resources) *lazyOutputRegister {
+	reg, _ := res.GetOrSetGeneric(lazyOutputKey, &lazyOutputRegister{
+		nodes: map[string]*lazyOutputNode{},
+	})
+	return reg.(*lazyOutputRegister)
+}
+
+func (r *lazyOutputRegister) nodeLocked(label string) *lazyOutputNode {
+	n, exists := r.nodes[label]
+	if !exists {
+		n = &lazyOutputNode{done: make(chan struct{})}
+		r.nodes[label] = n
+	}
+	return n
+}
+
+func (r *lazyOutputRegister) register(label string, dependsOn []string, start func()) (*lazyOutputNode, error) {
+	if label == "" {
+		return &lazyOutputNode{
+			registered: true,
+			start:      start,
+			dependsOn:  dependsOn,
+			done:       make(chan struct{}),
+		}, nil
+	}
+
+	r.mut.Lock()
+	n := r.nodeLocked(label)
+	if n.registered {
+		r.mut.Unlock()
+		return nil, fmt.Errorf("a lazy output labelled '%v' already exists", label)
+	}
+	if path := r.cycleLocked(label, dependsOn, []string{label}); path != nil {
+		r.mut.Unlock()
+		return nil, fmt.Errorf("dependencies form a cycle: %v", strings.Join(path, " -> "))
+	}
+	n.registered = true
+	n.start = start
+	n.dependsOn = dependsOn
+	requested := n.requested
+	r.mut.Unlock()
+
+	if requested {
+		start()
+	}
+	return n, nil
+}
+
+func (r *lazyOutputRegister) cycleLocked(label string, dependsOn, path []string) []string {
+	for _, dep := range dependsOn {
+		depPath := append(append([]string{}, path...), dep)
+		if dep == label {
+			return depPath
+		}
+		if n, exists := r.nodes[dep]; exists {
+			if cycle := r.cycleLocked(label, n.dependsOn, depPath); cycle != nil {
+				return cycle
+			}
+		}
+	}
+	return nil
+}
+
+// require starts the initialisation of the output with a label, or flags it to
+// start once registered, and returns a channel that is closed once it is done.
+func (r *lazyOutputRegister) require(label string) <-chan struct{} {
+	r.mut.Lock()
+	n := r.nodeLocked(label)
+	n.requested = true
+	start := n.start
+	r.mut.Unlock()
+
+	if start != nil {
+		start()
+	}
+	return n.done
+}
+
+//------------------------------------------------------------------------------
+
+type lazyOutput struct {
+	conf          *service.ParsedConfig
+	initOnStartup bool
+	dependsOn     []string
+	boff          *backoff.ExponentialBackOff
+
+	reg  *lazyOutputRegister
+	node *lazyOutputNode
+
+	mInitFailed *service.MetricCounter
+	log         *service.Logger
+
+	startOnce sync.Once
+	loopDone  chan struct{}
+	closeOnce sync.Once
+	closeChan chan struct{}
+
+	// Set before node.done is closed.
+	out     *service.OwnedOutput
+	initErr error
+}
+
+func newLazyOutputFromParsed(conf *service.ParsedConfig, mgr *service.Resources, label string) (*lazyOutput, error) {
+	l := &lazyOutput{
+		conf:        conf,
+		reg:         getLazyOutputRegister(mgr),
+		mInitFailed: mgr.Metrics().NewCounter("lazy_init_failed"),
+		log:         mgr.Logger(),
+		loopDone:    make(chan struct{}),
+		closeChan:   make(chan struct{}),
+	}
+
+	initStr, err := conf.FieldString(loFieldInit)
+	if err != nil {
+		return nil, err
+	}
+	l.initOnStartup = initStr == loInitStartup
+	if l.dependsOn, err = conf.FieldStringList(loFieldDependsOn); err != nil {
+		return nil, err
+	}
+	if l.boff, err = conf.FieldBackOff(loFieldBackoff); err != nil {
+		return nil, err
+	}
+	if l.node, err = l.reg.register(label, l.dependsOn, l.start); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *lazyOutput) start() {
+	l.startOnce.Do(func() {
+		go l.initLoop()
+	})
+}
+
+func (l *lazyOutput) initLoop() {
+	defer close(l.loopDone)
+
+	for _, dep := range l.dependsOn {
+		select {
+		case <-l.reg.require(dep):
+		case <-l.closeChan:
+			return
+		}
+	}
+
+	l.boff.Reset()
+	for {
+		out, err := l.conf.FieldOutput(loFieldOutput)
+		if err == nil {
+			if err = out.Prime(); err != nil {
+				_ = out.Close(context.Background())
+			}
+		}
+		if err == nil {
+			l.out = out
+			close(l.node.done)
+			return
+		}
+
+		l.mInitFailed.Incr(1)
+		wait := l.boff.NextBackOff()
+		if wait == backoff.Stop {
+			l.log.Errorf("Failed to initialise output, giving up: %v", err)
+			l.initErr = err
+			close(l.node.done)
+			return
+		}
+		l.log.Warnf("Failed to initialise output, retrying in %v: %v", wait, err)
+
+		select {
+		case <-time.After(wait):
+		case <-l.closeChan:
+			return
+		}
+	}
+}
+
+func (l *lazyOutput) Connect(ctx context.Context) error {
+	if l.initOnStartup {
+		l.start()
+	}
+	return nil
+}
+
+func (l *lazyOutput) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	l.start()
+	select {
+	case <-l.node.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if l.initErr != nil {
+		return fmt.Errorf("failed to initialise output: %w", l.initErr)
+	}
+	return l.out.WriteBatch(ctx, batch)
+}
+
+func (l *lazyOutput) Close(ctx context.Context) error {
+	l.closeOnce.Do(func() {
+		close(l.closeChan)
+	})
+
+	started := true
+	l.startOnce.Do(func() {
+		started = false
+	})
+	if !started {
+		return nil
+	}
+
+	select {
+	case <-l.loopDone:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if l.out == nil {
+		return nil
+	}
+	return l.out.Close(ctx)
+}
@@ -0,0 +1,206 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+type lazyTestChildren struct {
+	mut      sync.Mutex
+	created  []string
+	failures map[string]int
+	outputs  map[string]*funcOutput
+}
+
+func testLazyEnv(t *testing.T) (*service.Environment, *lazyTestChildren) {
+	t.Helper()
+
+	children := &lazyTestChildren{
+		failures: map[string]int{},
+		outputs:  map[string]*funcOutput{},
+	}
+
+	env := service.NewEnvironment()
+	require.NoError(t, env.RegisterBatchOutput("lazy_test_child",
+		service.NewConfigSpec().Field(service.NewStringField("name")),
+		func(conf *service.ParsedConfig, _ *service.Resources) (service.BatchOutput, service.BatchPolicy, int, error) {
+			name, err := conf.FieldString("name")
+			if err != nil {
+				return nil, service.BatchPolicy{}, 0, err
+			}
+
+			children.mut.Lock()
+			defer children.mut.Unlock()
+			if children.failures[name] > 0 {
+				children.failures[name]--
+				return nil, service.BatchPolicy{}, 0, errors.New("downstream unavailable")
+			}
+			children.created = append(children.created, name)
+
+			out := &funcOutput{writeFn: func(context.Context, service.MessageBatch) error { return nil }}
+			children.outputs[name] = out
+			return out, service.BatchPolicy{}, 1, nil
+		}))
+	return env, children
+}
+
+func (c *lazyTestChildren) createdNames() []string {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	return append([]string{}, c.created...)
+}
+
+func testLazyOutput(t *testing.T, env *service.Environment, res *service.Resources, label, conf string) *lazyOutput {
+	t.Helper()
+
+	pConf, err := lazyOutputSpec().ParseYAML(conf, env)
+	require.NoError(t, err)
+
+	l, err := newLazyOutputFromParsed(pConf, res, label)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+		defer done()
+		require.NoError(t, l.Close(ctx))
+	})
+	return l
+}
+
+func lazyTestConf(name, extra string) string {
+	return fmt.Sprintf(`
+output:
+  lazy_test_child:
+    name: %v
+backoff:
+  initial_interval: 1ms
+  max_interval: 1ms
+%v`, name, extra)
+}
+
+func TestLazyOutputFirstUse(t *testing.T) {
+	env, children := testLazyEnv(t)
+	l := testLazyOutput(t, env, service.MockResources(), "", lazyTestConf("foo", ""))
+
+	require.NoError(t, l.Connect(context.Background()))
+	time.Sleep(time.Millisecond * 10)
+	assert.Empty(t, children.createdNames())
+
+	require.NoError(t, l.WriteBatch(context.Background(), testBatch()))
+	assert.Equal(t, []string{"foo"}, children.createdNames())
+	assert.Equal(t, int64(1), children.outputs["foo"].calls.Load())
+}
+
+func TestLazyOutputStartup(t *testing.T) {
+	env, children := testLazyEnv(t)
+	l := testLazyOutput(t, env, service.MockResources(), "", lazyTestConf("foo", "init: startup"))
+
+	require.NoError(t, l.Connect(context.Background()))
+	assert.Eventually(t, func() bool {
+		return len(children.createdNames()) == 1
+	}, time.Second, time.Millisecond)
+}
+
+func TestLazyOutputRetry(t *testing.T) {
+	env, children := testLazyEnv(t)
+	children.failures["foo"] = 3
+
+	l := testLazyOutput(t, env, service.MockResources(), "", lazyTestConf("foo", ""))
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+
+	require.NoError(t, l.WriteBatch(ctx, testBatch()))
+	assert.Equal(t, []string{"foo"}, children.createdNames())
+	assert.Equal(t, 0, children.failures["foo"])
+}
+
+func TestLazyOutputRetryExhausted(t *testing.T) {
+	env, children := testLazyEnv(t)
+	children.failures["foo"] = 1000
+
+	l := testLazyOutput(t, env, service.MockResources(), "", lazyTestConf("foo", "  max_elapsed_time: 5ms"))
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+
+	err := l.WriteBatch(ctx, testBatch())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "downstream unavailable")
+	assert.Empty(t, children.createdNames())
+}
+
+func TestLazyOutputWriteCancelled(t *testing.T) {
+	env, children := testLazyEnv(t)
+	children.failures["foo"] = 1000
+
+	l := testLazyOutput(t, env, service.MockResources(), "", lazyTestConf("foo", ""))
+
+	ctx, done := context.WithTimeout(context.Background(), time.Millisecond*20)
+	defer done()
+
+	require.ErrorIs(t, l.WriteBatch(ctx, testBatch()), context.DeadlineExceeded)
+}
+
+func TestLazyOutputDependencies(t *testing.T) {
+	env, children := testLazyEnv(t)
+	children.failures["first"] = 3
+
+	res := service.MockResources()
+
+	// Created in reverse order in order to check that dependencies registered
+	// later are still waited for.
+	third := testLazyOutput(t, env, res, "third", lazyTestConf("third", `
+init: startup
+depends_on: [ second ]
+`))
+	second := testLazyOutput(t, env, res, "second", lazyTestConf("second", `
+depends_on: [ first ]
+`))
+	_ = testLazyOutput(t, env, res, "first", lazyTestConf("first", ""))
+
+	require.NoError(t, third.Connect(context.Background()))
+	require.NoError(t, second.Connect(context.Background()))
+
+	assert.Eventually(t, func() bool {
+		return len(children.createdNames()) == 3
+	}, time.Second*5, time.Millisecond)
+	assert.Equal(t, []string{"first", "second", "third"}, children.createdNames())
+}
+
+func TestLazyOutputDependencyCycle(t *testing.T) {
+	env, _ := testLazyEnv(t)
+	res := service.MockResources()
+
+	_ = testLazyOutput(t, env, res, "a", lazyTestConf("a", "depends_on: [ b ]"))
+	_ = testLazyOutput(t, env, res, "b", lazyTestConf("b", "depends_on: [ c ]"))
+
+	pConf, err := lazyOutputSpec().ParseYAML(lazyTestConf("c", "depends_on: [ a ]"), env)
+	require.NoError(t, err)
+
+	_, err = newLazyOutputFromParsed(pConf, res, "c")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "dependencies form a cycle: c -> a -> b -> c")
+}
@@ -0,0 +1,391 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	lbFieldOutputs          = "outputs"
+	lbFieldOutput           = "output"
+	lbFieldWeight           = "weight"
+	lbFieldStrategy         = "strategy"
+	lbFieldLatencySmoothing = "latency_smoothing"
+	lbFieldEjectThreshold   = "eject_threshold"
+	lbFieldEjectPeriod      = "eject_period"
+
+	lbStrategyWeightedRoundRobin = "weighted_round_robin"
+	lbStrategyLeastLatency       = "least_latency"
+)
+
+func loadBalancerOutputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Version("4.45.0").
+		Summary("Distributes batches across a list of outputs by weight or by their observed latency, temporarily ejecting outputs that repeatedly fail.").
+		Description(`
+Each batch is written to a single output chosen by the `+"`"+lbFieldStrategy+"`"+`. When the write fails the batch is written to each of the other outputs in turn, in the order that the strategy ranks them, and the write is only considered failed when all of them have failed.
+
+=== Strategies
+
+- `+"`"+lbStrategyWeightedRoundRobin+"`"+`: Batches are distributed in proportion to the `+"`"+lbFieldWeight+"`"+` of each output, interleaving them evenly rather than sending consecutive batches to the same output.
+- `+"`"+lbStrategyLeastLatency+"`"+`: Batches are written to the output with the lowest moving average latency of successful writes, scaled by the number of writes it currently has in flight so that a fast output is not overloaded. Outputs that have not yet completed a write are tried first in order to measure them.
+
+=== Ejection
+
+After `+"`"+lbFieldEjectThreshold+"`"+` consecutive failed writes an output is ejected, and receives no batches for the duration of `+"`"+lbFieldEjectPeriod+"`"+`, after which it is restored. When every output is ejected they are all used as though none were, since refusing to write at all would not help them recover.
+
+== Metrics
+
+This output emits a counter `+"`load_balancer_ejected`"+` which is incremented each time an output is ejected, and a counter `+"`load_balancer_sent`"+` which is incremented for each batch written to an output. Both are labelled with the `+"`index`"+` of the output.`).
+		Fields(
+			service.NewObjectListField(lbFieldOutputs,
+				service.NewOutputField(lbFieldOutput).
+					Description("The output to write batches to."),
+				service.NewIntField(lbFieldWeight).
+					Description("The relative share of batches to write to this output with the `"+lbStrategyWeightedRoundRobin+"` strategy.").
+					Default(1),
+			).Description("A list of outputs to distribute batches across."),
+			service.NewStringEnumField(lbFieldStrategy, lbStrategyWeightedRoundRobin, lbStrategyLeastLatency).
+				Description("The strategy used to choose the output for each batch.").
+				Default(lbStrategyWeightedRoundRobin),
+			service.NewFloatField(lbFieldLatencySmoothing).
+				Description("The weight given to the latest write when updating the moving average latency of an output, between zero and one. Higher values adapt to changes in latency faster.").
+				Default(0.2).
+				Advanced(),
+			service.NewIntField(lbFieldEjectThreshold).
+				Description("The number of consecutive failed writes after which an output is ejected. Set to zero in order to disable ejection.").
+				Default(3),
+			service.NewDurationField(lbFieldEjectPeriod).
+				Description("The period of time for which an ejected output receives no batches.").
+				Default("30s"),
+			service.NewOutputMaxInFlightField(),
+		).
+		Example("Multi-Region Sinks", "Write to whichever of three regional endpoints is responding fastest, ejecting any region that fails five writes in a row for a minute.", `
+output:
+  load_balancer:
+    strategy: least_latency
+    eject_threshold: 5
+    eject_period: 1m
+    outputs:
+      - output:
+          http_client:
+            url: https://us-east.example.com/ingest
+      - output:
+          http_client:
+            url: https://us-west.example.com/ingest
+      - output:
+          http_client:
+            url: https://eu-west.example.com/ingest
+`).
+		Example("Weighted Clusters", "Send three quarters of batches to a larger cluster and a quarter to a smaller one.", `
+output:
+  load_balancer:
+    outputs:
+      - weight: 3
+        output:
+          kafka_franz:
+            seed_brokers: [ large-cluster:9092 ]
+            topic: events
+      - weight: 1
+        output:
+          kafka_franz:
+            seed_brokers: [ small-cluster:9092 ]
+            topic: events
+`)
+}
+
+func init() {
+	err := service.RegisterBatchOutput("load_balancer", loadBalancerOutputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPol service.BatchPolicy, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldMaxInFlight(); err != nil {
+				return
+			}
+			out, err = newLoadBalancerOutputFromParsed(conf, mgr)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type lbChildState struct {
+	weight   int
+	current  int
+	latency  float64
+	sampled  bool
+	inFlight int
+	failures int
+
+	ejected      bool
+	ejectedUntil time.Time
+}
+
+// lbBalancer tracks the state of each child of a load balancer and ranks them
+// for each write.
+type lbBalancer struct {
+	strategy       string
+	smoothing      float64
+	ejectThreshold int
+	ejectPeriod    time.Duration
+	nowFn          func() time.Time
+
+	mEjected *service.MetricCounter
+	mSent    *service.MetricCounter
+	log      *service.Logger
+
+	mut      sync.Mutex
+	children []*lbChildState
+}
+
+func newLBBalancer(conf *service.ParsedConfig, mgr *service.Resources, weights []int) (*lbBalancer, error) {
+	b := &lbBalancer{
+		nowFn:    time.Now,
+		mEjected: mgr.Metrics().NewCounter("load_balancer_ejected", "index"),
+		mSent:    mgr.Metrics().NewCounter("load_balancer_sent", "index"),
+		log:      mgr.Logger(),
+	}
+
+	var err error
+	if b.strategy, err = conf.FieldString(lbFieldStrategy); err != nil {
+		return nil, err
+	}
+	if b.smoothing, err = conf.FieldFloat(lbFieldLatencySmoothing); err != nil {
+		return nil, err
+	}
+	if b.smoothing <= 0 || b.smoothing > 1 {
+		return nil, fmt.Errorf("%v must be greater than zero and no more than one", lbFieldLatencySmoothing)
+	}
+	if b.ejectThreshold, err = conf.FieldInt(lbFieldEjectThreshold); err != nil {
+		return nil, err
+	}
+	if b.ejectPeriod, err = conf.FieldDuration(lbFieldEjectPeriod); err != nil {
+		return nil, err
+	}
+	for i, w := range weights {
+		if w <= 0 {
+			return nil, fmt.Errorf("output %v: %v must be greater than zero", i, lbFieldWeight)
+		}
+		b.children = append(b.children, &lbChildState{weight: w})
+	}
+	return b, nil
+}
+
+// candidates returns the indexes of the children that a batch should be
+// written to, in the order that they should be tried.
+func (b *lbBalancer) candidates() []int {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	now := b.nowFn()
+	var healthy []int
+	for i, c := range b.children {
+		if c.ejected && !now.Before(c.ejectedUntil) {
+			b.log.Infof("Restoring output %v after ejection", i)
+			c.ejected = false
+		}
+		if !c.ejected {
+			healthy = append(healthy, i)
+		}
+	}
+	if len(healthy) == 0 {
+		for i := range b.children {
+			healthy = append(healthy, i)
+		}
+	}
+
+	switch b.strategy {
+	case lbStrategyLeastLatency:
+		score := func(i int) float64 {
+			c := b.children[i]
+			return c.latency * float64(c.inFlight+1)
+		}
+		slices.SortStableFunc(healthy, func(i, j int) int {
+			ci, cj := b.children[i], b.children[j]
+			if ci.sampled != cj.sampled {
+				if !ci.sampled {
+					return -1
+				}
+				return 1
+			}
+			si, sj := score(i), score(j)
+			switch {
+			case si < sj:
+				return -1
+			case si > sj:
+				return 1
+			}
+			return 0
+		})
+	default:
+		// Smooth weighted round robin, which spreads the picks of each child
+		// evenly across each cycle.
+		total, best := 0, -1
+		for _, i := range healthy {
+			c := b.children[i]
+			c.current += c.weight
+			total += c.weight
+			if best == -1 || c.current > b.children[best].current {
+				best = i
+			}
+		}
+		b.children[best].current -= total
+
+		// The remaining children are tried in order of weight should the
+		// chosen one fail.
+		slices.SortStableFunc(healthy, func(i, j int) int {
+			switch {
+			case i == best:
+				return -1
+			case j == best:
+				return 1
+			}
+			return b.children[j].weight - b.children[i].weight
+		})
+	}
+	return healthy
+}
+
+func (b *lbBalancer) begin(i int) {
+	b.mut.Lock()
+	b.children[i].inFlight++
+	b.mut.Unlock()
+	b.mSent.Incr(1, strconv.Itoa(i))
+}
+
+// record the result of a write to a child.
+func (b *lbBalancer) record(i int, latency time.Duration, err error) {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	c := b.children[i]
+	c.inFlight--
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+
+	if err == nil {
+		c.failures = 0
+		if c.sampled {
+			c.latency = b.smoothing*float64(latency) + (1-b.smoothing)*c.latency
+		} else {
+			c.latency = float64(latency)
+			c.sampled = true
+		}
+		return
+	}
+
+	c.failures++
+	if b.ejectThreshold > 0 && c.failures >= b.ejectThreshold && !c.ejected {
+		b.log.Warnf("Output %v failed %v consecutive writes, ejecting it for %v: %v", i, c.failures, b.ejectPeriod, err)
+		b.mEjected.Incr(1, strconv.Itoa(i))
+		c.ejected = true
+		c.ejectedUntil = b.nowFn().Add(b.ejectPeriod)
+		c.failures = 0
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type loadBalancerOutput struct {
+	outputs  []*service.OwnedOutput
+	balancer *lbBalancer
+
+	primeMut sync.Mutex
+	primed   bool
+}
+
+func newLoadBalancerOutputFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*loadBalancerOutput, error) {
+	childConfs, err := conf.FieldObjectList(lbFieldOutputs)
+	if err != nil {
+		return nil, err
+	}
+	if len(childConfs) == 0 {
+		return nil, errors.New("at least one output must be specified")
+	}
+
+	l := &loadBalancerOutput{}
+	var weights []int
+	for i, cConf := range childConfs {
+		out, err := cConf.FieldOutput(lbFieldOutput)
+		if err != nil {
+			return nil, fmt.Errorf("output %v: %w", i, err)
+		}
+		weight, err := cConf.FieldInt(lbFieldWeight)
+		if err != nil {
+			return nil, fmt.Errorf("output %v: %w", i, err)
+		}
+		l.outputs = append(l.outputs, out)
+		weights = append(weights, weight)
+	}
+
+	if l.balancer, err = newLBBalancer(conf, mgr, weights); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *loadBalancerOutput) Connect(ctx context.Context) error {
+	l.primeMut.Lock()
+	defer l.primeMut.Unlock()
+	if l.primed {
+		return nil
+	}
+	for _, out := range l.outputs {
+		if err := out.Prime(); err != nil {
+			return err
+		}
+	}
+	l.primed = true
+	return nil
+}
+
+func (l *loadBalancerOutput) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	var errs []error
+	for _, i := range l.balancer.candidates() {
+		l.balancer.begin(i)
+		start := time.Now()
+		err := l.outputs[i].WriteBatch(ctx, batch.Copy())
+		l.balancer.record(i, time.Since(start), err)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, fmt.Errorf("output %v: %w", i, err))
+	}
+	return errors.Join(errs...)
+}
+
+func (l *loadBalancerOutput) Close(ctx context.Context) error {
+	var errs []error
+	for _, out := range l.outputs {
+		errs = append(errs, out.Close(ctx))
+	}
+	return errors.Join(errs...)
+}
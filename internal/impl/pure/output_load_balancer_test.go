@@ -0,0 +1,159 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func TestLoadBalancerWeightedRoundRobin(t *testing.T) {
+	pConf, err := loadBalancerOutputSpec().ParseYAML(`outputs: []`, nil)
+	require.NoError(t, err)
+
+	b, err := newLBBalancer(pConf, service.MockResources(), []int{3, 1})
+	require.NoError(t, err)
+
+	var picks []int
+	for range 8 {
+		picks = append(picks, b.candidates()[0])
+	}
+	assert.Equal(t, []int{0, 0, 1, 0, 0, 0, 1, 0}, picks)
+
+	// The fallback order places the heavier child first.
+	pConf, err = loadBalancerOutputSpec().ParseYAML(`outputs: []`, nil)
+	require.NoError(t, err)
+
+	b, err = newLBBalancer(pConf, service.MockResources(), []int{1, 1, 5})
+	require.NoError(t, err)
+	assert.Equal(t, []int{2, 0, 1}, b.candidates())
+}
+
+func TestLoadBalancerLeastLatency(t *testing.T) {
+	pConf, err := loadBalancerOutputSpec().ParseYAML(`
+strategy: least_latency
+latency_smoothing: 0.5
+outputs: []
+`, nil)
+	require.NoError(t, err)
+
+	b, err := newLBBalancer(pConf, service.MockResources(), []int{1, 1, 1})
+	require.NoError(t, err)
+
+	// Unmeasured children are tried first.
+	b.begin(0)
+	b.record(0, time.Millisecond*10, nil)
+	assert.Equal(t, []int{1, 2, 0}, b.candidates())
+
+	b.begin(1)
+	b.record(1, time.Millisecond*30, nil)
+	b.begin(2)
+	b.record(2, time.Millisecond*20, nil)
+	assert.Equal(t, []int{0, 2, 1}, b.candidates())
+
+	// Child 0 slows down and its average moves past child 2.
+	b.begin(0)
+	b.record(0, time.Millisecond*50, nil)
+	assert.Equal(t, []int{2, 0, 1}, b.candidates())
+
+	// Writes in flight count against a child.
+	b.begin(2)
+	b.begin(2)
+	assert.Equal(t, []int{0, 1, 2}, b.candidates())
+}
+
+func TestLoadBalancerEjection(t *testing.T) {
+	pConf, err := loadBalancerOutputSpec().ParseYAML(`
+eject_threshold: 2
+eject_period: 10s
+outputs: []
+`, nil)
+	require.NoError(t, err)
+
+	b, err := newLBBalancer(pConf, service.MockResources(), []int{1, 1})
+	require.NoError(t, err)
+
+	now := time.Unix(1000, 0)
+	b.nowFn = func() time.Time { return now }
+
+	for range 2 {
+		b.begin(0)
+		b.record(0, 0, errors.New("nope"))
+	}
+	assert.Equal(t, []int{1}, b.candidates())
+	assert.Equal(t, []int{1}, b.candidates())
+
+	now = now.Add(time.Second * 10)
+	assert.ElementsMatch(t, []int{0, 1}, b.candidates())
+
+	// When every child is ejected they are all used.
+	for i := range 2 {
+		for range 2 {
+			b.begin(i)
+			b.record(i, 0, errors.New("nope"))
+		}
+	}
+	assert.ElementsMatch(t, []int{0, 1}, b.candidates())
+}
+
+func TestLoadBalancerOutputFallthrough(t *testing.T) {
+	failing := &funcOutput{writeFn: func(context.Context, service.MessageBatch) error { return errors.New("region down") }}
+	healthy := &funcOutput{writeFn: func(context.Context, service.MessageBatch) error { return nil }}
+
+	env := service.NewEnvironment()
+	for name, out := range map[string]*funcOutput{
+		"load_balancer_test_failing": failing,
+		"load_balancer_test_healthy": healthy,
+	} {
+		out := out
+		require.NoError(t, env.RegisterBatchOutput(name, service.NewConfigSpec(),
+			func(*service.ParsedConfig, *service.Resources) (service.BatchOutput, service.BatchPolicy, int, error) {
+				return out, service.BatchPolicy{}, 1, nil
+			}))
+	}
+
+	pConf, err := loadBalancerOutputSpec().ParseYAML(`
+eject_threshold: 1
+eject_period: 1h
+outputs:
+  - output:
+      load_balancer_test_failing: {}
+  - output:
+      load_balancer_test_healthy: {}
+`, env)
+	require.NoError(t, err)
+
+	l, err := newLoadBalancerOutputFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+	require.NoError(t, l.Connect(context.Background()))
+	t.Cleanup(func() {
+		ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+		defer done()
+		require.NoError(t, l.Close(ctx))
+	})
+
+	for range 4 {
+		require.NoError(t, l.WriteBatch(context.Background(), testBatch()))
+	}
+	assert.Equal(t, int64(1), failing.calls.Load())
+	assert.Equal(t, int64(4), healthy.calls.Load())
+}
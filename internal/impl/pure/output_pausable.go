@@ -0,0 +1,115 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"sync"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+
+	"github.com/redpanda-data/connect/v4/internal/pausing"
+)
+
+func pausableOutputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Version("4.45.0").
+		Summary("Wraps an output so that it can be paused and resumed at runtime, in order to hold writes during downstream maintenance without stopping the process.").
+		Description(`
+Whilst paused writes are held until the output is resumed, which applies back pressure to the rest of the pipeline rather than failing messages. Writes that are already in progress are allowed to complete, and the pause is considered drained once all of them have finished.
+`+pausableDescription()).
+		Fields(
+			service.NewOutputField(paFieldOutput).
+				Description("The output to write messages to."),
+		).
+		Fields(pausableFields()...).
+		Fields(service.NewOutputMaxInFlightField()).
+		Example("Holding Writes", "Write to a database that can be taken offline for maintenance by pausing the output named `warehouse`.", `
+output:
+  pausable:
+    name: warehouse
+    output:
+      sql_insert:
+        driver: postgres
+        dsn: postgres://localhost:5432/warehouse
+        table: events
+        columns: [ id, body ]
+        args_mapping: 'root = [ this.id, content().string() ]'
+`)
+}
+
+func init() {
+	err := service.RegisterBatchOutput("pausable", pausableOutputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPol service.BatchPolicy, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldMaxInFlight(); err != nil {
+				return
+			}
+			out, err = newPausableOutputFromParsed(conf, mgr)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type pausableOutput struct {
+	output *service.OwnedOutput
+	gate   *pausing.Gate
+
+	primeMut sync.Mutex
+	primed   bool
+}
+
+func newPausableOutputFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*pausableOutput, error) {
+	p := &pausableOutput{}
+
+	var err error
+	if p.gate, err = pausableGateFromParsed(conf, mgr); err != nil {
+		return nil, err
+	}
+	if p.output, err = conf.FieldOutput(paFieldOutput); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *pausableOutput) Connect(ctx context.Context) error {
+	p.primeMut.Lock()
+	defer p.primeMut.Unlock()
+	if p.primed {
+		return nil
+	}
+	if err := p.output.Prime(); err != nil {
+		return err
+	}
+	p.primed = true
+	return nil
+}
+
+func (p *pausableOutput) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	if err := p.gate.Enter(ctx); err != nil {
+		return err
+	}
+	defer p.gate.Done()
+	return p.output.WriteBatch(ctx, batch)
+}
+
+func (p *pausableOutput) Close(ctx context.Context) error {
+	return p.output.Close(ctx)
+}
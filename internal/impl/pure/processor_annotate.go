@@ -0,0 +1,199 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redpanda-data/benthos/v4/public/bloblang"
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	anFieldStage = "stage"
+	anFieldNote  = "note"
+	anFieldStrip = "strip"
+
+	annotationTrailKey = "annotation_trail"
+)
+
+func annotateProcessorSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Version("4.45.0").
+		Summary("Appends a breadcrumb to a trail of annotations carried in the metadata of each message, or strips the trail.").
+		Description(`
+Each annotation is an object containing the `+"`stage`"+`, a `+"`timestamp`"+` of when it was added in RFC 3339 format, and a `+"`note`"+`, and annotations are appended to a list stored in the metadata field `+"`"+annotationTrailKey+"`"+`. Placing this processor between the stages of a pipeline records the path that each message took along with when, which can be inspected with the mapping `+"`@"+annotationTrailKey+"`"+` when debugging. Annotations can also be added within mappings with the `+"`annotate`"+` Bloblang method.
+
+== Stripping Annotations
+
+With `+"`"+anFieldStrip+"`"+` set to `+"`true`"+` the processor removes the trail instead, which is typically done within the processors of an output so that annotations are not delivered to downstream systems. By setting the field from an environment variable, e.g. `+"`"+anFieldStrip+`: ${STRIP_ANNOTATIONS:true}`+"`"+`, annotations can be kept only in environments where they are being debugged.`).
+		Fields(
+			service.NewStringField(anFieldStage).
+				Description("The name of the stage that the annotation describes.").
+				Example("enrichment").
+				Default(""),
+			service.NewInterpolatedStringField(anFieldNote).
+				Description("A note to attach to the annotation.").
+				Example("customer ${! this.customer_id } found").
+				Default(""),
+			service.NewBoolField(anFieldStrip).
+				Description("Whether to remove the trail of annotations from messages rather than add to it.").
+				Default(false),
+		).
+		Example("Debugging Stages", "Annotate messages as they pass through each stage of a pipeline, and strip the annotations before delivery unless debugging.", `
+pipeline:
+  processors:
+    - annotate:
+        stage: ingest
+    - cached:
+        key: '${! this.customer_id }'
+        cache: customers
+        processors:
+          - http:
+              url: http://example.com/customers/${! this.customer_id }
+              verb: GET
+    - annotate:
+        stage: enrich
+        note: 'customer ${! this.customer_id } resolved'
+
+output:
+  http_client:
+    url: http://example.com/events
+    verb: POST
+  processors:
+    - log:
+        level: DEBUG
+        message: '${! @annotation_trail.format_json() }'
+    - annotate:
+        strip: ${STRIP_ANNOTATIONS:true}
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor("annotate", annotateProcessorSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return newAnnotateProcessorFromParsed(conf)
+		})
+	if err != nil {
+		panic(err)
+	}
+
+	annotateSpec := bloblang.NewPluginSpec().
+		Beta().
+		Version("4.45.0").
+		Category("Object & Array Manipulation").
+		Description("Appends an annotation to a trail of annotations, as added by the `annotate` processor, returning the new trail. When the target is `null` a new trail is returned. The result is typically assigned back to the metadata field `" + annotationTrailKey + "`, e.g. `meta " + annotationTrailKey + " = @" + annotationTrailKey + ".annotate(\"enrich\", \"cache miss\")`.").
+		Param(bloblang.NewStringParam("stage").Description("The name of the stage that the annotation describes.")).
+		Param(bloblang.NewStringParam("note").Description("A note to attach to the annotation.").Default(""))
+
+	if err := bloblang.RegisterMethodV2(
+		"annotate", annotateSpec,
+		func(args *bloblang.ParsedParams) (bloblang.Method, error) {
+			stage, err := args.GetString("stage")
+			if err != nil {
+				return nil, err
+			}
+			note, err := args.GetString("note")
+			if err != nil {
+				return nil, err
+			}
+			return func(v any) (any, error) {
+				return appendAnnotation(v, stage, note)
+			}, nil
+		},
+	); err != nil {
+		panic(err)
+	}
+}
+
+// appendAnnotation returns a trail of annotations with a new annotation
+// appended, where the trail is copied so that other references to it are
+// unaffected.
+func appendAnnotation(trail any, stage, note string) ([]any, error) {
+	var existing []any
+	if trail != nil {
+		var ok bool
+		if existing, ok = trail.([]any); !ok {
+			return nil, fmt.Errorf("expected annotation trail to be an array, got %T", trail)
+		}
+	}
+
+	res := make([]any, 0, len(existing)+1)
+	res = append(res, existing...)
+	return append(res, map[string]any{
+		"stage":     stage,
+		"timestamp": time.Now().UTC().Format(time.RFC3339Nano),
+		"note":      note,
+	}), nil
+}
+
+//------------------------------------------------------------------------------
+
+type annotateProcessor struct {
+	stage string
+	note  *service.InterpolatedString
+	strip bool
+}
+
+func newAnnotateProcessorFromParsed(conf *service.ParsedConfig) (*annotateProcessor, error) {
+	a := &annotateProcessor{}
+
+	var err error
+	if a.stage, err = conf.FieldString(anFieldStage); err != nil {
+		return nil, err
+	}
+	if a.note, err = conf.FieldInterpolatedString(anFieldNote); err != nil {
+		return nil, err
+	}
+	if a.strip, err = conf.FieldBool(anFieldStrip); err != nil {
+		return nil, err
+	}
+	if !a.strip && a.stage == "" {
+		return nil, errors.New("a stage must be specified unless strip is enabled")
+	}
+	return a, nil
+}
+
+func (a *annotateProcessor) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	if a.strip {
+		msg.MetaDelete(annotationTrailKey)
+		return service.MessageBatch{msg}, nil
+	}
+
+	note, err := a.note.TryString(msg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to interpolate note: %w", err)
+	}
+
+	var trail any
+	if v, exists := msg.MetaGetMut(annotationTrailKey); exists {
+		trail = v
+	}
+	res, err := appendAnnotation(trail, a.stage, note)
+	if err != nil {
+		return nil, err
+	}
+	msg.MetaSetMut(annotationTrailKey, res)
+	return service.MessageBatch{msg}, nil
+}
+
+func (a *annotateProcessor) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,90 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/bloblang"
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func TestAnnotateTrail(t *testing.T) {
+	var procs []*annotateProcessor
+	for _, conf := range []string{`
+stage: ingest
+`, `
+stage: enrich
+note: 'customer ${! this.id } found'
+`, `
+strip: true
+`} {
+		pConf, err := annotateProcessorSpec().ParseYAML(conf, nil)
+		require.NoError(t, err)
+
+		p, err := newAnnotateProcessorFromParsed(pConf)
+		require.NoError(t, err)
+		procs = append(procs, p)
+	}
+
+	msg := service.NewMessage([]byte(`{"id":"foo"}`))
+	for _, p := range procs[:2] {
+		batch, err := p.Process(context.Background(), msg)
+		require.NoError(t, err)
+		require.Len(t, batch, 1)
+		msg = batch[0]
+	}
+
+	exec, err := bloblang.Parse(`meta annotation_trail = @annotation_trail.annotate("map", "mapped")`)
+	require.NoError(t, err)
+	msg, err = msg.BloblangQuery(exec)
+	require.NoError(t, err)
+
+	v, exists := msg.MetaGetMut(annotationTrailKey)
+	require.True(t, exists)
+
+	trail := v.([]any)
+	require.Len(t, trail, 3)
+
+	var stages, notes []string
+	for _, a := range trail {
+		obj := a.(map[string]any)
+		stages = append(stages, obj["stage"].(string))
+		notes = append(notes, obj["note"].(string))
+
+		_, err := time.Parse(time.RFC3339Nano, obj["timestamp"].(string))
+		require.NoError(t, err)
+	}
+	assert.Equal(t, []string{"ingest", "enrich", "map"}, stages)
+	assert.Equal(t, []string{"", "customer foo found", "mapped"}, notes)
+
+	batch, err := procs[2].Process(context.Background(), msg)
+	require.NoError(t, err)
+	_, exists = batch[0].MetaGetMut(annotationTrailKey)
+	assert.False(t, exists)
+}
+
+func TestAnnotateRequiresStage(t *testing.T) {
+	pConf, err := annotateProcessorSpec().ParseYAML(`note: foo`, nil)
+	require.NoError(t, err)
+
+	_, err = newAnnotateProcessorFromParsed(pConf)
+	require.Error(t, err)
+}
@@ -0,0 +1,119 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func auditProcessorSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Version("4.45.0").
+		Summary("Emits a structured audit event for each message that passes through it, in order to record the lifecycle of messages for data lineage.").
+		Description(`
+Messages pass through this processor unchanged, other than being assigned an audit ID, and an event of the type `+"`"+auFieldEvent+"`"+` is written for each of them. Placing this processor at each stage of a pipeline records when messages were received and transformed, and the `+"`audit`"+` output records when they are delivered. Messages that are intentionally dropped can be recorded by placing this processor with the event `+"`dropped`"+` ahead of the mapping that deletes them.
+`+auditDescription()+`
+
+When events cannot be written the messages of the batch are flagged with the error, so that they can be handled with xref:configuration:error_handling.adoc[error handling methods] rather than continuing unaudited.`).
+		Fields(
+			service.NewStringField(auFieldEvent).
+				Description("The type of event to emit.").
+				Examples("received", "transformed", "dropped"),
+		).
+		Fields(auditFields()...).
+		Example("Tracking Lineage", "Record when each message is received and enriched, and when it is delivered, writing the events to a Kafka topic.", `
+input:
+  kafka_franz:
+    seed_brokers: [ localhost:9092 ]
+    topics: [ orders ]
+    consumer_group: orders_pipeline
+  processors:
+    - audit:
+        event: received
+        audit_output: audit_log
+        details: 'root.offset = @kafka_offset'
+
+pipeline:
+  processors:
+    - mapping: 'root.total = this.items.map_each(item -> item.price).sum()'
+    - audit:
+        event: transformed
+        component: totals
+        audit_output: audit_log
+
+output:
+  audit:
+    audit_output: audit_log
+    output:
+      kafka_franz:
+        seed_brokers: [ localhost:9092 ]
+        topic: orders_enriched
+
+output_resources:
+  - label: audit_log
+    kafka_franz:
+      seed_brokers: [ localhost:9092 ]
+      topic: audit_events
+`)
+}
+
+func init() {
+	err := service.RegisterBatchProcessor("audit", auditProcessorSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchProcessor, error) {
+			return newAuditProcessorFromParsed(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type auditProcessor struct {
+	eventType string
+	auditor   *auditor
+}
+
+func newAuditProcessorFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*auditProcessor, error) {
+	p := &auditProcessor{}
+
+	var err error
+	if p.eventType, err = conf.FieldString(auFieldEvent); err != nil {
+		return nil, err
+	}
+	if p.auditor, err = newAuditor(conf, mgr); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *auditProcessor) ProcessBatch(ctx context.Context, batch service.MessageBatch) ([]service.MessageBatch, error) {
+	errs := make([]error, len(batch))
+	for i, m := range batch {
+		errs[i] = m.GetError()
+	}
+	if err := p.auditor.emit(ctx, batch, p.eventType, errs); err != nil {
+		return nil, err
+	}
+	return []service.MessageBatch{batch}, nil
+}
+
+func (p *auditProcessor) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,128 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+type auditEvents struct {
+	mut    sync.Mutex
+	events []map[string]any
+	err    error
+}
+
+func (e *auditEvents) attach(t *testing.T, a *auditor) {
+	t.Helper()
+
+	a.nowFn = func() time.Time { return time.Unix(1000, 0).UTC() }
+	a.writeFn = func(_ context.Context, batch service.MessageBatch) error {
+		e.mut.Lock()
+		defer e.mut.Unlock()
+		if e.err != nil {
+			return e.err
+		}
+		for _, m := range batch {
+			v, err := m.AsStructured()
+			require.NoError(t, err)
+			e.events = append(e.events, v.(map[string]any))
+		}
+		return nil
+	}
+}
+
+func (e *auditEvents) get() []map[string]any {
+	e.mut.Lock()
+	defer e.mut.Unlock()
+	return e.events
+}
+
+func TestAuditProcessor(t *testing.T) {
+	pConf, err := auditProcessorSpec().ParseYAML(`
+event: received
+audit_output: foo
+component: ingest
+details: 'root.topic = @topic'
+`, nil)
+	require.NoError(t, err)
+
+	p, err := newAuditProcessorFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+
+	var events auditEvents
+	events.attach(t, p.auditor)
+
+	withID := service.NewMessage([]byte("a"))
+	withID.MetaSetMut(auditIDKey, "abc")
+	withID.MetaSetMut("topic", "orders")
+
+	withErr := service.NewMessage([]byte("b"))
+	withErr.MetaSetMut("topic", "orders")
+	withErr.SetError(errors.New("nope"))
+
+	results, err := p.ProcessBatch(context.Background(), service.MessageBatch{withID, withErr})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.Len(t, results[0], 2)
+
+	// A missing audit ID is generated and carried by the message.
+	generated, exists := results[0][1].MetaGet(auditIDKey)
+	require.True(t, exists)
+	assert.Len(t, generated, 36)
+
+	assert.Equal(t, []map[string]any{
+		{
+			"event":      "received",
+			"message_id": "abc",
+			"component":  "ingest",
+			"timestamp":  "1970-01-01T00:16:40Z",
+			"details":    map[string]any{"topic": "orders"},
+		},
+		{
+			"event":      "received",
+			"message_id": generated,
+			"component":  "ingest",
+			"timestamp":  "1970-01-01T00:16:40Z",
+			"error":      "nope",
+			"details":    map[string]any{"topic": "orders"},
+		},
+	}, events.get())
+}
+
+func TestAuditProcessorWriteError(t *testing.T) {
+	pConf, err := auditProcessorSpec().ParseYAML(`
+event: transformed
+audit_output: foo
+`, nil)
+	require.NoError(t, err)
+
+	p, err := newAuditProcessorFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+
+	events := auditEvents{err: errors.New("nope")}
+	events.attach(t, p.auditor)
+
+	_, err = p.ProcessBatch(context.Background(), testBatch())
+	require.ErrorContains(t, err, "failed to write audit events: nope")
+}
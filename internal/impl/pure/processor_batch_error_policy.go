@@ -0,0 +1,225 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	bepFieldPolicy     = "policy"
+	bepFieldMaxRetries = "max_retries"
+	bepFieldProcessors = "processors"
+)
+
+const (
+	bepPolicyFailBatch = "fail_batch"
+	bepPolicyDrop      = "drop"
+	bepPolicySplit     = "split"
+)
+
+func batchErrorPolicyProcessorSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Composition").
+		Version("4.45.0").
+		Summary("Executes a list of child processors on batches and applies a consistent policy to batches within which some messages fail.").
+		Description(`
+How a processing error of one message affects the other messages of a batch otherwise depends on the processor, some processors flag only the message that failed, whereas others flag every message of the batch. Wrapping processors with a `+"`batch_error_policy`"+` makes this behaviour explicit after the child processors have been executed:
+
+- `+"`fail_batch`"+`: When any message of a batch fails then every message of the batch is flagged as failed, which results in the whole batch being handled by xref:configuration:error_handling.adoc[error handling methods] or being rejected by outputs together.
+- `+"`drop`"+`: Messages that fail are removed from the batch and acknowledged, and their errors are logged along with their metadata, which allows the remaining messages to continue.
+- `+"`split`"+`: When any message of a batch fails then the results are discarded, and each original message of the batch is executed again by the child processors within a batch of its own. Messages that fail individually are retried up to `+"`max_retries`"+` times, after which they remain flagged as failed, and the messages that succeed continue. Child processors with side effects should therefore tolerate successful messages being executed again.
+
+== Metrics
+
+This processor emits a counter `+"`batch_error_policy_failed`"+` which is incremented for each batch within which messages failed, and a counter `+"`batch_error_policy_dropped`"+` which is incremented for each message removed with the `+"`drop`"+` policy.`).
+		Fields(
+			service.NewStringAnnotatedEnumField(bepFieldPolicy, map[string]string{
+				bepPolicyFailBatch: "Flag every message of a batch as failed when any of its messages fails.",
+				bepPolicyDrop:      "Remove messages that fail from the batch.",
+				bepPolicySplit:     "Execute the messages of a batch individually when any fails, retrying those that fail.",
+			}).
+				Description("The policy to apply to batches within which messages fail."),
+			service.NewIntField(bepFieldMaxRetries).
+				Description("The maximum number of times to retry a message that fails individually with the `split` policy.").
+				Default(0),
+			service.NewProcessorListField(bepFieldProcessors).
+				Description("The child processors to execute."),
+		).
+		Example("Isolate Failing Messages", "Retry the messages of a failed batch of HTTP requests one at a time so that a single bad message does not fail its neighbours.", `
+pipeline:
+  processors:
+    - batch_error_policy:
+        policy: split
+        max_retries: 2
+        processors:
+          - http:
+              url: http://localhost:8080/enrich
+              batch_as_multipart: true
+`)
+}
+
+func init() {
+	err := service.RegisterBatchProcessor("batch_error_policy", batchErrorPolicyProcessorSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchProcessor, error) {
+			return newBatchErrorPolicyProcessorFromParsed(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type batchErrorPolicyProcessor struct {
+	policy     string
+	maxRetries int
+	children   []*service.OwnedProcessor
+
+	mFailed  *service.MetricCounter
+	mDropped *service.MetricCounter
+	log      *service.Logger
+}
+
+func newBatchErrorPolicyProcessorFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*batchErrorPolicyProcessor, error) {
+	p := &batchErrorPolicyProcessor{
+		mFailed:  mgr.Metrics().NewCounter("batch_error_policy_failed"),
+		mDropped: mgr.Metrics().NewCounter("batch_error_policy_dropped"),
+		log:      mgr.Logger(),
+	}
+
+	var err error
+	if p.policy, err = conf.FieldString(bepFieldPolicy); err != nil {
+		return nil, err
+	}
+	if p.maxRetries, err = conf.FieldInt(bepFieldMaxRetries); err != nil {
+		return nil, err
+	}
+	if p.maxRetries < 0 {
+		return nil, errors.New("max_retries must not be negative")
+	}
+	if p.children, err = conf.FieldProcessorList(bepFieldProcessors); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func firstBatchError(batches []service.MessageBatch) (int, error) {
+	i := 0
+	for _, b := range batches {
+		for _, m := range b {
+			if err := m.GetError(); err != nil {
+				return i, err
+			}
+			i++
+		}
+	}
+	return -1, nil
+}
+
+func (p *batchErrorPolicyProcessor) ProcessBatch(ctx context.Context, batch service.MessageBatch) ([]service.MessageBatch, error) {
+	var originals service.MessageBatch
+	if p.policy == bepPolicySplit {
+		originals = batch.Copy()
+	}
+
+	results, err := service.ExecuteProcessors(ctx, p.children, batch)
+	if err != nil {
+		return nil, err
+	}
+
+	index, firstErr := firstBatchError(results)
+	if firstErr == nil {
+		return results, nil
+	}
+	p.mFailed.Incr(1)
+
+	switch p.policy {
+	case bepPolicyFailBatch:
+		batchErr := fmt.Errorf("batch failed due to message %v: %w", index, firstErr)
+		for _, b := range results {
+			for _, m := range b {
+				if m.GetError() == nil {
+					m.SetError(batchErr)
+				}
+			}
+		}
+		return results, nil
+	case bepPolicyDrop:
+		var kept []service.MessageBatch
+		for _, b := range results {
+			var keptBatch service.MessageBatch
+			for _, m := range b {
+				if err := m.GetError(); err != nil {
+					p.mDropped.Incr(1)
+					meta := map[string]any{}
+					_ = m.MetaWalkMut(func(k string, v any) error {
+						meta[k] = v
+						return nil
+					})
+					p.log.With("metadata", meta).Warnf("Dropping message that failed processing: %v", err)
+					continue
+				}
+				keptBatch = append(keptBatch, m)
+			}
+			if len(keptBatch) > 0 {
+				kept = append(kept, keptBatch)
+			}
+		}
+		return kept, nil
+	}
+
+	var split []service.MessageBatch
+	for _, m := range originals {
+		res, err := p.processIndividually(ctx, m)
+		if err != nil {
+			return nil, err
+		}
+		split = append(split, res...)
+	}
+	return split, nil
+}
+
+func (p *batchErrorPolicyProcessor) processIndividually(ctx context.Context, msg *service.Message) ([]service.MessageBatch, error) {
+	for attempt := 0; ; attempt++ {
+		input := msg
+		if attempt < p.maxRetries {
+			input = msg.Copy()
+		}
+		res, err := service.ExecuteProcessors(ctx, p.children, service.MessageBatch{input})
+		if err != nil {
+			return nil, err
+		}
+		_, firstErr := firstBatchError(res)
+		if firstErr == nil || attempt >= p.maxRetries {
+			return res, nil
+		}
+		p.log.Debugf("Retrying message that failed processing: %v", firstErr)
+	}
+}
+
+func (p *batchErrorPolicyProcessor) Close(ctx context.Context) error {
+	for _, c := range p.children {
+		if err := c.Close(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+// bepTestChild fails messages for which the number of remaining failures of
+// their contents is positive, and counts the messages it receives.
+type bepTestChild struct {
+	mut      sync.Mutex
+	failures map[string]int
+	seen     []string
+	batches  int
+}
+
+func (c *bepTestChild) ProcessBatch(ctx context.Context, b service.MessageBatch) ([]service.MessageBatch, error) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	c.batches++
+	for _, m := range b {
+		mBytes, _ := m.AsBytes()
+		c.seen = append(c.seen, string(mBytes))
+		if c.failures[string(mBytes)] > 0 {
+			c.failures[string(mBytes)]--
+			m.SetError(errors.New("failed " + string(mBytes)))
+		}
+		m.SetBytes(append(mBytes, '!'))
+	}
+	return []service.MessageBatch{b}, nil
+}
+
+func (c *bepTestChild) Close(context.Context) error { return nil }
+
+func testBatchErrorPolicy(t *testing.T, child *bepTestChild, conf string) *batchErrorPolicyProcessor {
+	t.Helper()
+
+	env := service.NewEnvironment()
+	require.NoError(t, env.RegisterBatchProcessor("bep_test_child", service.NewConfigSpec(),
+		func(*service.ParsedConfig, *service.Resources) (service.BatchProcessor, error) {
+			return child, nil
+		}))
+
+	pConf, err := batchErrorPolicyProcessorSpec().ParseYAML(conf+`
+processors:
+  - bep_test_child: {}
+`, env)
+	require.NoError(t, err)
+
+	p, err := newBatchErrorPolicyProcessorFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, p.Close(context.Background()))
+	})
+	return p
+}
+
+func bepTestBatch(contents ...string) service.MessageBatch {
+	var b service.MessageBatch
+	for _, c := range contents {
+		b = append(b, service.NewMessage([]byte(c)))
+	}
+	return b
+}
+
+type bepResult struct {
+	content string
+	err     string
+}
+
+func bepResults(t *testing.T, batches []service.MessageBatch) (res [][]bepResult) {
+	t.Helper()
+
+	for _, b := range batches {
+		var bRes []bepResult
+		for _, m := range b {
+			mBytes, err := m.AsBytes()
+			require.NoError(t, err)
+
+			r := bepResult{content: string(mBytes)}
+			if err := m.GetError(); err != nil {
+				r.err = err.Error()
+			}
+			bRes = append(bRes, r)
+		}
+		res = append(res, bRes)
+	}
+	return
+}
+
+func TestBatchErrorPolicyNoErrors(t *testing.T) {
+	for _, policy := range []string{bepPolicyFailBatch, bepPolicyDrop, bepPolicySplit} {
+		child := &bepTestChild{}
+		p := testBatchErrorPolicy(t, child, "policy: "+policy)
+
+		out, err := p.ProcessBatch(context.Background(), bepTestBatch("a", "b"))
+		require.NoError(t, err)
+		assert.Equal(t, [][]bepResult{{{content: "a!"}, {content: "b!"}}}, bepResults(t, out), policy)
+		assert.Equal(t, 1, child.batches)
+	}
+}
+
+func TestBatchErrorPolicyFailBatch(t *testing.T) {
+	child := &bepTestChild{failures: map[string]int{"b": 1}}
+	p := testBatchErrorPolicy(t, child, "policy: fail_batch")
+
+	out, err := p.ProcessBatch(context.Background(), bepTestBatch("a", "b", "c"))
+	require.NoError(t, err)
+	assert.Equal(t, [][]bepResult{{
+		{content: "a!", err: "batch failed due to message 1: failed b"},
+		{content: "b!", err: "failed b"},
+		{content: "c!", err: "batch failed due to message 1: failed b"},
+	}}, bepResults(t, out))
+}
+
+func TestBatchErrorPolicyDrop(t *testing.T) {
+	child := &bepTestChild{failures: map[string]int{"b": 1}}
+	p := testBatchErrorPolicy(t, child, "policy: drop")
+
+	out, err := p.ProcessBatch(context.Background(), bepTestBatch("a", "b", "c"))
+	require.NoError(t, err)
+	assert.Equal(t, [][]bepResult{{{content: "a!"}, {content: "c!"}}}, bepResults(t, out))
+
+	child.failures["a"] = 1
+	out, err = p.ProcessBatch(context.Background(), bepTestBatch("a"))
+	require.NoError(t, err)
+	assert.Empty(t, out)
+}
+
+func TestBatchErrorPolicySplit(t *testing.T) {
+	child := &bepTestChild{failures: map[string]int{"b": 2, "c": 5}}
+	p := testBatchErrorPolicy(t, child, `
+policy: split
+max_retries: 2
+`)
+
+	out, err := p.ProcessBatch(context.Background(), bepTestBatch("a", "b", "c"))
+	require.NoError(t, err)
+	assert.Equal(t, [][]bepResult{
+		{{content: "a!"}},
+		{{content: "b!"}},
+		{{content: "c!", err: "failed c"}},
+	}, bepResults(t, out))
+
+	// The whole batch, then a once, b three times and c three times.
+	assert.Equal(t, []string{"a", "b", "c", "a", "b", "b", "c", "c", "c"}, child.seen)
+}
+
+func TestBatchErrorPolicySplitNoRetries(t *testing.T) {
+	child := &bepTestChild{failures: map[string]int{"a": 1}}
+	p := testBatchErrorPolicy(t, child, "policy: split")
+
+	out, err := p.ProcessBatch(context.Background(), bepTestBatch("a", "b"))
+	require.NoError(t, err)
+	assert.Equal(t, [][]bepResult{
+		{{content: "a!"}},
+		{{content: "b!"}},
+	}, bepResults(t, out))
+}
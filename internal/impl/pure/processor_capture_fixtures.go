@@ -0,0 +1,267 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	cfFieldProcessors       = "processors"
+	cfFieldPath             = "path"
+	cfFieldTargetProcessors = "target_processors"
+	cfFieldSampleRatio      = "sample_ratio"
+	cfFieldMaxFixtures      = "max_fixtures"
+	cfFieldIncludeMetadata  = "include_metadata"
+)
+
+func captureFixturesProcessorSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Version("4.45.0").
+		Summary("Executes a list of child processors and records a sample of the batches they process as unit test fixtures.").
+		Description(`
+Each sampled batch becomes a test case within a xref:configuration:unit_testing.adoc[unit test definition] file, where the batch received by this processor is the input of the test, and the batches produced by the child processors are its expected output. This allows regression tests of existing pipelines to be bootstrapped from live traffic, which can then be run with `+"`rpk connect test`"+`.
+
+The test definitions are rewritten to the file at `+"`path`"+` each time a new fixture is captured, and once `+"`max_fixtures`"+` have been captured this processor only executes its child processors. The `+"`target_processors`"+` of each test must point to the child processors of this processor, or to an equivalent list of processors, such that the tests execute the same processing logic when it is removed from the config.
+
+Captured fixtures contain the raw contents of messages and should be reviewed for sensitive data before being committed.`).
+		Fields(
+			service.NewProcessorListField(cfFieldProcessors).
+				Description("The child processors to execute and capture fixtures of."),
+			service.NewStringField(cfFieldPath).
+				Description("The path of the test definitions file to write.").
+				Example("./tests/captured_benthos_test.yaml"),
+			service.NewStringField(cfFieldTargetProcessors).
+				Description("A JSON Pointer that identifies the processors executed by each test, relative to the config file being tested.").
+				Example("/pipeline/processors/0/capture_fixtures/processors").
+				Example("/pipeline/processors"),
+			service.NewFloatField(cfFieldSampleRatio).
+				Description("The ratio of batches to capture, between 0 and 1.").
+				Default(0.1),
+			service.NewIntField(cfFieldMaxFixtures).
+				Description("The maximum number of fixtures to capture.").
+				Default(10),
+			service.NewBoolField(cfFieldIncludeMetadata).
+				Description("Whether the metadata of messages should be included in fixtures.").
+				Default(true),
+		).
+		Example("Capture Regression Tests", "Capture the first 20 messages processed by a pipeline as unit tests.", `
+pipeline:
+  processors:
+    - capture_fixtures:
+        path: ./tests/pipeline_benthos_test.yaml
+        target_processors: /pipeline/processors/0/capture_fixtures/processors
+        sample_ratio: 1
+        max_fixtures: 20
+        processors:
+          - mapping: |
+              root = this
+              root.name = this.name.uppercase()
+`)
+}
+
+func init() {
+	err := service.RegisterBatchProcessor("capture_fixtures", captureFixturesProcessorSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchProcessor, error) {
+			return newCaptureFixturesProcessorFromParsed(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type fixtureInputPart struct {
+	Content  string         `yaml:"content"`
+	Metadata map[string]any `yaml:"metadata,omitempty"`
+}
+
+type fixtureOutputCondition struct {
+	ContentEquals  string         `yaml:"content_equals"`
+	MetadataEquals map[string]any `yaml:"metadata_equals,omitempty"`
+}
+
+type fixtureTestCase struct {
+	Name             string                     `yaml:"name"`
+	TargetProcessors string                     `yaml:"target_processors"`
+	InputBatch       []fixtureInputPart         `yaml:"input_batch"`
+	OutputBatches    [][]fixtureOutputCondition `yaml:"output_batches"`
+}
+
+type fixtureDefinition struct {
+	Tests []fixtureTestCase `yaml:"tests"`
+}
+
+type captureFixturesProcessor struct {
+	procs            []*service.OwnedProcessor
+	path             string
+	targetProcessors string
+	sampleRatio      float64
+	maxFixtures      int
+	includeMetadata  bool
+	log              *service.Logger
+
+	mut      sync.Mutex
+	fixtures fixtureDefinition
+}
+
+func newCaptureFixturesProcessorFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*captureFixturesProcessor, error) {
+	p := &captureFixturesProcessor{log: mgr.Logger()}
+
+	var err error
+	if p.procs, err = conf.FieldProcessorList(cfFieldProcessors); err != nil {
+		return nil, err
+	}
+	if p.path, err = conf.FieldString(cfFieldPath); err != nil {
+		return nil, err
+	}
+	if p.targetProcessors, err = conf.FieldString(cfFieldTargetProcessors); err != nil {
+		return nil, err
+	}
+	if p.sampleRatio, err = conf.FieldFloat(cfFieldSampleRatio); err != nil {
+		return nil, err
+	}
+	if p.sampleRatio < 0 || p.sampleRatio > 1 {
+		return nil, fmt.Errorf("sample_ratio must be between 0 and 1, got %v", p.sampleRatio)
+	}
+	if p.maxFixtures, err = conf.FieldInt(cfFieldMaxFixtures); err != nil {
+		return nil, err
+	}
+	if p.includeMetadata, err = conf.FieldBool(cfFieldIncludeMetadata); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *captureFixturesProcessor) metadataOf(msg *service.Message) map[string]any {
+	if !p.includeMetadata {
+		return nil
+	}
+	meta := map[string]any{}
+	_ = msg.MetaWalkMut(func(k string, v any) error {
+		meta[k] = v
+		return nil
+	})
+	if len(meta) == 0 {
+		return nil
+	}
+	return meta
+}
+
+func (p *captureFixturesProcessor) sample() bool {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	if len(p.fixtures.Tests) >= p.maxFixtures {
+		return false
+	}
+	return rand.Float64() < p.sampleRatio
+}
+
+func (p *captureFixturesProcessor) ProcessBatch(ctx context.Context, batch service.MessageBatch) ([]service.MessageBatch, error) {
+	if !p.sample() {
+		return service.ExecuteProcessors(ctx, p.procs, batch)
+	}
+
+	// The input is recorded before processing as processors may mutate the
+	// messages of the batch.
+	inputBatch := make([]fixtureInputPart, len(batch))
+	for i, msg := range batch {
+		b, err := msg.AsBytes()
+		if err != nil {
+			return nil, err
+		}
+		inputBatch[i] = fixtureInputPart{
+			Content:  string(b),
+			Metadata: p.metadataOf(msg),
+		}
+	}
+
+	outBatches, err := service.ExecuteProcessors(ctx, p.procs, batch)
+	if err != nil {
+		return nil, err
+	}
+
+	outputBatches := make([][]fixtureOutputCondition, 0, len(outBatches))
+	for _, outBatch := range outBatches {
+		conds := make([]fixtureOutputCondition, len(outBatch))
+		for i, msg := range outBatch {
+			b, err := msg.AsBytes()
+			if err != nil {
+				return nil, err
+			}
+			conds[i] = fixtureOutputCondition{
+				ContentEquals:  string(b),
+				MetadataEquals: p.metadataOf(msg),
+			}
+		}
+		outputBatches = append(outputBatches, conds)
+	}
+
+	if err := p.record(inputBatch, outputBatches); err != nil {
+		p.log.Errorf("Failed to write captured fixtures: %v", err)
+	}
+	return outBatches, nil
+}
+
+func (p *captureFixturesProcessor) record(inputBatch []fixtureInputPart, outputBatches [][]fixtureOutputCondition) error {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	if len(p.fixtures.Tests) >= p.maxFixtures {
+		return nil
+	}
+	p.fixtures.Tests = append(p.fixtures.Tests, fixtureTestCase{
+		Name:             fmt.Sprintf("captured fixture %v", len(p.fixtures.Tests)+1),
+		TargetProcessors: p.targetProcessors,
+		InputBatch:       inputBatch,
+		OutputBatches:    outputBatches,
+	})
+
+	b, err := yaml.Marshal(p.fixtures)
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so that the definitions are never left
+	// partially written.
+	if err := os.MkdirAll(filepath.Dir(p.path), 0o755); err != nil {
+		return err
+	}
+	tmpPath := p.path + ".tmp"
+	if err := os.WriteFile(tmpPath, b, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, p.path)
+}
+
+func (p *captureFixturesProcessor) Close(ctx context.Context) error {
+	for _, proc := range p.procs {
+		if err := proc.Close(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+type upperProcessor struct{}
+
+func (upperProcessor) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	b, err := msg.AsBytes()
+	if err != nil {
+		return nil, err
+	}
+	out := msg.Copy()
+	out.SetBytes([]byte(strings.ToUpper(string(b))))
+	out.MetaSetMut("processed", "yes")
+	return service.MessageBatch{out}, nil
+}
+
+func (upperProcessor) Close(ctx context.Context) error {
+	return nil
+}
+
+func testCaptureFixturesProcessor(t *testing.T, yamlStr string) *captureFixturesProcessor {
+	t.Helper()
+
+	env := service.NewEnvironment()
+	require.NoError(t, env.RegisterProcessor("capture_fixtures_test_upper", service.NewConfigSpec(),
+		func(*service.ParsedConfig, *service.Resources) (service.Processor, error) {
+			return upperProcessor{}, nil
+		}))
+
+	conf, err := captureFixturesProcessorSpec().ParseYAML(yamlStr, env)
+	require.NoError(t, err)
+
+	p, err := newCaptureFixturesProcessorFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = p.Close(context.Background())
+	})
+	return p
+}
+
+func TestCaptureFixturesProcessor(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tests", "captured_benthos_test.yaml")
+
+	p := testCaptureFixturesProcessor(t, `
+path: `+path+`
+target_processors: /pipeline/processors/0/capture_fixtures/processors
+sample_ratio: 1
+max_fixtures: 2
+processors:
+  - capture_fixtures_test_upper: {}
+`)
+
+	for _, contents := range []string{"foo", "bar", "baz"} {
+		inMsg := service.NewMessage([]byte(contents))
+		inMsg.MetaSetMut("source", contents)
+
+		batches, err := p.ProcessBatch(context.Background(), service.MessageBatch{inMsg})
+		require.NoError(t, err)
+		require.Len(t, batches, 1)
+		require.Len(t, batches[0], 1)
+
+		b, err := batches[0][0].AsBytes()
+		require.NoError(t, err)
+		assert.Equal(t, strings.ToUpper(contents), string(b))
+	}
+
+	fileBytes, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	var def fixtureDefinition
+	require.NoError(t, yaml.Unmarshal(fileBytes, &def))
+	assert.Equal(t, fixtureDefinition{Tests: []fixtureTestCase{
+		{
+			Name:             "captured fixture 1",
+			TargetProcessors: "/pipeline/processors/0/capture_fixtures/processors",
+			InputBatch: []fixtureInputPart{
+				{Content: "foo", Metadata: map[string]any{"source": "foo"}},
+			},
+			OutputBatches: [][]fixtureOutputCondition{
+				{{ContentEquals: "FOO", MetadataEquals: map[string]any{"source": "foo", "processed": "yes"}}},
+			},
+		},
+		{
+			Name:             "captured fixture 2",
+			TargetProcessors: "/pipeline/processors/0/capture_fixtures/processors",
+			InputBatch: []fixtureInputPart{
+				{Content: "bar", Metadata: map[string]any{"source": "bar"}},
+			},
+			OutputBatches: [][]fixtureOutputCondition{
+				{{ContentEquals: "BAR", MetadataEquals: map[string]any{"source": "bar", "processed": "yes"}}},
+			},
+		},
+	}}, def)
+}
+
+func TestCaptureFixturesProcessorNoSample(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "captured_benthos_test.yaml")
+
+	p := testCaptureFixturesProcessor(t, `
+path: `+path+`
+target_processors: /pipeline/processors
+sample_ratio: 0
+include_metadata: false
+processors:
+  - capture_fixtures_test_upper: {}
+`)
+
+	batches, err := p.ProcessBatch(context.Background(), service.MessageBatch{service.NewMessage([]byte("foo"))})
+	require.NoError(t, err)
+	require.Len(t, batches, 1)
+
+	_, err = os.Stat(path)
+	require.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,530 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	chFieldStrategy             = "strategy"
+	chFieldChunkSize            = "chunk_size"
+	chFieldChunkOverlap         = "chunk_overlap"
+	chFieldSeparators           = "separators"
+	chFieldEmbeddings           = "embeddings"
+	chFieldBreakpointPercentile = "breakpoint_percentile"
+)
+
+func chunkerProcessorSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("AI").
+		Version("4.45.0").
+		Summary("Splits the text of each message into chunks suitable for embedding and indexing, emitting a message per chunk.").
+		Description(`
+Each chunk is emitted as a copy of the original message, including its metadata, with the metadata fields `+"`chunk_index`"+` and `+"`chunk_count`"+` added. Chunks are trimmed of surrounding whitespace, and empty chunks are dropped.
+
+== Strategies
+
+=== `+"`recursive_character`"+`
+
+Splits text on the first of the `+"`separators`"+` that it contains, and merges the resulting pieces back together into chunks of at most `+"`chunk_size`"+` characters. Pieces that are still too large are split again with the next separator. With the default separators paragraphs are kept together where possible, followed by lines and then words.
+
+=== `+"`token`"+`
+
+Splits text into windows of `+"`chunk_size`"+` tokens. Tokens are approximated as runs of letters and digits, with each symbol counted as its own token, which gives counts in the same region as the tokenizers of common embedding models for English text. Chunks preserve the original text between tokens.
+
+=== `+"`sentence`"+`
+
+Splits text into sentences, and packs whole sentences into chunks of at most `+"`chunk_size`"+` characters. Sentences longer than the chunk size are split with the `+"`recursive_character`"+` strategy.
+
+=== `+"`semantic`"+`
+
+Splits text into sentences and calculates an embedding for each of them by executing the `+"`embeddings`"+` processors against a batch with a message per sentence, which must result in each message being an array of numbers, such as the output of the `+"`openai_embeddings`"+` or `+"`ollama_embeddings`"+` processors. A chunk boundary is placed wherever the cosine distance between consecutive sentences is above the `+"`breakpoint_percentile`"+` of all distances within the message, so that chunks contain sentences with related topics. Chunks larger than `+"`chunk_size`"+` characters are split further by sentence.
+
+== Overlap
+
+Consecutive chunks repeat up to `+"`chunk_overlap`"+` units of the end of the previous chunk, measured in the same units as `+"`chunk_size`"+`. The `+"`sentence`"+` and `+"`semantic`"+` strategies only ever repeat whole sentences.`).
+		Fields(
+			service.NewStringEnumField(chFieldStrategy, "recursive_character", "token", "sentence", "semantic").
+				Description("The strategy used to split text into chunks.").
+				Default("recursive_character"),
+			service.NewIntField(chFieldChunkSize).
+				Description("The maximum size of each chunk, in tokens for the `token` strategy and in characters otherwise.").
+				Default(1000),
+			service.NewIntField(chFieldChunkOverlap).
+				Description("The amount of each chunk that is repeated at the start of the next, in the same units as `chunk_size`.").
+				Default(0),
+			service.NewStringListField(chFieldSeparators).
+				Description("The separators used by the `recursive_character` strategy in order of preference, where an empty separator splits between characters.").
+				Default([]any{"\n\n", "\n", " ", ""}).
+				Advanced(),
+			service.NewProcessorListField(chFieldEmbeddings).
+				Description("Processors that convert each message of a batch of sentences into an embedding, required by the `semantic` strategy.").
+				Optional(),
+			service.NewFloatField(chFieldBreakpointPercentile).
+				Description("The percentile of distances between consecutive sentences above which the `semantic` strategy places a chunk boundary.").
+				Default(95).
+				Advanced(),
+		).
+		Example("Indexing Documents", "Split documents into overlapping chunks of tokens, calculate an embedding for each chunk, and write them to a vector database.", `
+pipeline:
+  processors:
+    - chunker:
+        strategy: token
+        chunk_size: 256
+        chunk_overlap: 32
+    - branch:
+        processors:
+          - ollama_embeddings:
+              model: nomic-embed-text
+        result_map: root.embeddings = this
+output:
+  qdrant:
+    grpc_host: localhost:6334
+    collection_name: documents
+    id: root = uuid_v4()
+    vector_mapping: root = this.embeddings
+    payload_mapping: 'root = { "text": content().string(), "doc": @doc_id, "chunk": @chunk_index }'
+`).
+		Example("Semantic Chunking", "Group sentences that are about the same topic into chunks.", `
+pipeline:
+  processors:
+    - chunker:
+        strategy: semantic
+        chunk_size: 2000
+        embeddings:
+          - openai_embeddings:
+              api_key: "${OPENAI_API_KEY}"
+              model: text-embedding-3-small
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor("chunker", chunkerProcessorSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return newChunkerProcessorFromParsed(conf)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type chunkerProcessor struct {
+	strategy   string
+	size       int
+	overlap    int
+	separators []string
+	embeddings []*service.OwnedProcessor
+	percentile float64
+}
+
+func newChunkerProcessorFromParsed(conf *service.ParsedConfig) (*chunkerProcessor, error) {
+	c := &chunkerProcessor{}
+
+	var err error
+	if c.strategy, err = conf.FieldString(chFieldStrategy); err != nil {
+		return nil, err
+	}
+	if c.size, err = conf.FieldInt(chFieldChunkSize); err != nil {
+		return nil, err
+	}
+	if c.size <= 0 {
+		return nil, fmt.Errorf("%v must be greater than zero", chFieldChunkSize)
+	}
+	if c.overlap, err = conf.FieldInt(chFieldChunkOverlap); err != nil {
+		return nil, err
+	}
+	if c.overlap < 0 || c.overlap >= c.size {
+		return nil, fmt.Errorf("%v must be at least zero and less than %v", chFieldChunkOverlap, chFieldChunkSize)
+	}
+	if c.separators, err = conf.FieldStringList(chFieldSeparators); err != nil {
+		return nil, err
+	}
+	if c.percentile, err = conf.FieldFloat(chFieldBreakpointPercentile); err != nil {
+		return nil, err
+	}
+	if c.percentile < 0 || c.percentile > 100 {
+		return nil, fmt.Errorf("%v must be between 0 and 100", chFieldBreakpointPercentile)
+	}
+	if conf.Contains(chFieldEmbeddings) {
+		if c.embeddings, err = conf.FieldProcessorList(chFieldEmbeddings); err != nil {
+			return nil, err
+		}
+	}
+	if c.strategy == "semantic" && len(c.embeddings) == 0 {
+		return nil, fmt.Errorf("the semantic strategy requires %v processors", chFieldEmbeddings)
+	}
+	return c, nil
+}
+
+func (c *chunkerProcessor) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	mBytes, err := msg.AsBytes()
+	if err != nil {
+		return nil, err
+	}
+	text := string(mBytes)
+
+	var chunks []string
+	switch c.strategy {
+	case "token":
+		chunks = chunkTokens(text, c.size, c.overlap)
+	case "sentence":
+		chunks = c.packSentences(splitSentences(text))
+	case "semantic":
+		if chunks, err = c.chunkSemantic(ctx, splitSentences(text)); err != nil {
+			return nil, err
+		}
+	default:
+		chunks = chunkRecursive(text, c.separators, c.size, c.overlap)
+	}
+
+	chunks = slices.DeleteFunc(chunks, func(s string) bool {
+		return s == ""
+	})
+
+	batch := make(service.MessageBatch, 0, len(chunks))
+	for i, chunk := range chunks {
+		part := msg.Copy()
+		part.SetBytes([]byte(chunk))
+		part.MetaSetMut("chunk_index", i)
+		part.MetaSetMut("chunk_count", len(chunks))
+		batch = append(batch, part)
+	}
+	return batch, nil
+}
+
+func (c *chunkerProcessor) Close(ctx context.Context) error {
+	for _, p := range c.embeddings {
+		if err := p.Close(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+func runeLen(s string) int {
+	return utf8.RuneCountInString(s)
+}
+
+// chunkRecursive splits text on the first separator it contains, recursing
+// with the remaining separators for pieces that exceed the chunk size.
+func chunkRecursive(text string, separators []string, size, overlap int) []string {
+	sep, remaining := "", []string(nil)
+	for i, s := range separators {
+		if s == "" || strings.Contains(text, s) {
+			sep, remaining = s, separators[i+1:]
+			break
+		}
+	}
+
+	var pieces []string
+	if sep == "" {
+		for _, r := range text {
+			pieces = append(pieces, string(r))
+		}
+	} else {
+		pieces = strings.Split(text, sep)
+	}
+
+	var chunks, small []string
+	for _, p := range pieces {
+		if runeLen(p) <= size {
+			small = append(small, p)
+			continue
+		}
+		if len(small) > 0 {
+			chunks = append(chunks, mergePieces(small, sep, size, overlap)...)
+			small = nil
+		}
+		if len(remaining) > 0 {
+			chunks = append(chunks, chunkRecursive(p, remaining, size, overlap)...)
+		} else {
+			chunks = append(chunks, strings.TrimSpace(p))
+		}
+	}
+	if len(small) > 0 {
+		chunks = append(chunks, mergePieces(small, sep, size, overlap)...)
+	}
+	return chunks
+}
+
+// mergePieces joins pieces with a separator into chunks of at most size
+// characters, where each chunk begins with the trailing pieces of the
+// previous chunk totalling at most overlap characters.
+func mergePieces(pieces []string, sep string, size, overlap int) []string {
+	sepLen := runeLen(sep)
+
+	var chunks, current []string
+	total := 0
+	for _, p := range pieces {
+		l := runeLen(p)
+		joinLen := 0
+		if len(current) > 0 {
+			joinLen = sepLen
+		}
+		if total+l+joinLen > size && len(current) > 0 {
+			if chunk := strings.TrimSpace(strings.Join(current, sep)); chunk != "" {
+				chunks = append(chunks, chunk)
+			}
+			for len(current) > 0 && (total > overlap || total+l+sepLen > size) {
+				total -= runeLen(current[0])
+				if len(current) > 1 {
+					total -= sepLen
+				}
+				current = current[1:]
+			}
+		}
+		if len(current) > 0 {
+			total += sepLen
+		}
+		current = append(current, p)
+		total += l
+	}
+	if chunk := strings.TrimSpace(strings.Join(current, sep)); chunk != "" {
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
+
+var tokenRegexp = regexp.MustCompile(`[\p{L}\p{N}]+|[^\s\p{L}\p{N}]`)
+
+// chunkTokens splits text into windows of tokens, where each window is the
+// original text spanning its tokens.
+func chunkTokens(text string, size, overlap int) []string {
+	tokens := tokenRegexp.FindAllStringIndex(text, -1)
+
+	var chunks []string
+	for start := 0; start < len(tokens); start += size - overlap {
+		end := min(start+size, len(tokens))
+		chunks = append(chunks, text[tokens[start][0]:tokens[end-1][1]])
+		if end == len(tokens) {
+			break
+		}
+	}
+	return chunks
+}
+
+// splitSentences splits text after terminal punctuation that is followed by
+// whitespace, and at blank lines.
+func splitSentences(text string) []string {
+	var sentences []string
+	emit := func(s string) {
+		if s = strings.TrimSpace(s); s != "" {
+			sentences = append(sentences, s)
+		}
+	}
+
+	start := 0
+	for i := 0; i < len(text); {
+		r, width := utf8.DecodeRuneInString(text[i:])
+		if r == '\n' && strings.HasPrefix(strings.TrimLeft(text[i+1:], " \t\r"), "\n") {
+			emit(text[start:i])
+			start = i + 1
+			i++
+			continue
+		}
+		if r != '.' && r != '!' && r != '?' {
+			i += width
+			continue
+		}
+
+		// Include repeated punctuation along with closing quotes and
+		// brackets in the sentence.
+		end := i + width
+		for end < len(text) {
+			next, w := utf8.DecodeRuneInString(text[end:])
+			if !strings.ContainsRune(".!?\"')]”’", next) {
+				break
+			}
+			end += w
+		}
+		if end == len(text) {
+			break
+		}
+		if next, _ := utf8.DecodeRuneInString(text[end:]); unicode.IsSpace(next) {
+			emit(text[start:end])
+			start = end
+		}
+		i = end
+	}
+	emit(text[start:])
+	return sentences
+}
+
+// packSentences packs whole sentences into chunks, where each chunk begins
+// with the trailing sentences of the previous chunk that fit within the
+// overlap.
+func (c *chunkerProcessor) packSentences(sentences []string) []string {
+	var chunks, current []string
+	total := 0
+	flush := func() {
+		if len(current) == 0 {
+			return
+		}
+		chunks = append(chunks, strings.Join(current, " "))
+		for len(current) > 0 && total > c.overlap {
+			total -= runeLen(current[0]) + 1
+			current = current[1:]
+		}
+	}
+
+	for _, s := range sentences {
+		l := runeLen(s)
+		if l > c.size {
+			flush()
+			current, total = nil, 0
+			chunks = append(chunks, chunkRecursive(s, c.separators, c.size, c.overlap)...)
+			continue
+		}
+		if total+l > c.size {
+			flush()
+			for len(current) > 0 && total+l > c.size {
+				total -= runeLen(current[0]) + 1
+				current = current[1:]
+			}
+		}
+		current = append(current, s)
+		total += l + 1
+	}
+	if len(current) > 0 {
+		chunks = append(chunks, strings.Join(current, " "))
+	}
+	return chunks
+}
+
+func (c *chunkerProcessor) chunkSemantic(ctx context.Context, sentences []string) ([]string, error) {
+	if len(sentences) < 2 {
+		return c.packSentences(sentences), nil
+	}
+
+	batch := make(service.MessageBatch, len(sentences))
+	for i, s := range sentences {
+		batch[i] = service.NewMessage([]byte(s))
+	}
+	results, err := service.ExecuteProcessors(ctx, c.embeddings, batch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate embeddings: %w", err)
+	}
+
+	var embeddings [][]float64
+	for _, b := range results {
+		for _, m := range b {
+			if err := m.GetError(); err != nil {
+				return nil, fmt.Errorf("failed to calculate embeddings: %w", err)
+			}
+			v, err := m.AsStructured()
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse embedding: %w", err)
+			}
+			e, err := embeddingVector(v)
+			if err != nil {
+				return nil, err
+			}
+			embeddings = append(embeddings, e)
+		}
+	}
+	if len(embeddings) != len(sentences) {
+		return nil, fmt.Errorf("embeddings processors resulted in %v embeddings for %v sentences", len(embeddings), len(sentences))
+	}
+
+	distances := make([]float64, len(sentences)-1)
+	for i := range distances {
+		distances[i] = 1 - cosineSimilarity(embeddings[i], embeddings[i+1])
+	}
+	threshold := percentileOf(distances, c.percentile)
+
+	var chunks, group []string
+	for i, s := range sentences {
+		group = append(group, s)
+		if i == len(sentences)-1 || distances[i] > threshold {
+			chunks = append(chunks, c.packSentences(group)...)
+			group = nil
+		}
+	}
+	return chunks, nil
+}
+
+func embeddingVector(v any) ([]float64, error) {
+	arr, ok := v.([]any)
+	if !ok {
+		return nil, fmt.Errorf("expected embedding to be an array, got %T", v)
+	}
+	e := make([]float64, len(arr))
+	for i, n := range arr {
+		f, err := embeddingNumber(n)
+		if err != nil {
+			return nil, fmt.Errorf("embedding index %v: %w", i, err)
+		}
+		e[i] = f
+	}
+	return e, nil
+}
+
+func embeddingNumber(v any) (float64, error) {
+	switch t := v.(type) {
+	case float64:
+		return t, nil
+	case float32:
+		return float64(t), nil
+	case int:
+		return float64(t), nil
+	case int64:
+		return float64(t), nil
+	case uint64:
+		return float64(t), nil
+	case json.Number:
+		return t.Float64()
+	}
+	return 0, fmt.Errorf("expected number, got %T", v)
+}
+
+func cosineSimilarity(a, b []float64) float64 {
+	var dot, normA, normB float64
+	for i := 0; i < len(a) && i < len(b); i++ {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+// percentileOf returns the linearly interpolated percentile of values.
+func percentileOf(values []float64, p float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(rank-float64(lower))
+}
@@ -0,0 +1,173 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func testChunker(t *testing.T, conf string, env *service.Environment) *chunkerProcessor {
+	t.Helper()
+
+	pConf, err := chunkerProcessorSpec().ParseYAML(conf, env)
+	require.NoError(t, err)
+
+	p, err := newChunkerProcessorFromParsed(pConf)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, p.Close(context.Background()))
+	})
+	return p
+}
+
+func testChunks(t *testing.T, p *chunkerProcessor, text string) []string {
+	t.Helper()
+
+	msg := service.NewMessage([]byte(text))
+	msg.MetaSetMut("doc_id", "foo")
+
+	batch, err := p.Process(context.Background(), msg)
+	require.NoError(t, err)
+
+	var chunks []string
+	for i, m := range batch {
+		b, err := m.AsBytes()
+		require.NoError(t, err)
+		chunks = append(chunks, string(b))
+
+		index, _ := m.MetaGetMut("chunk_index")
+		assert.Equal(t, i, index)
+		count, _ := m.MetaGetMut("chunk_count")
+		assert.Equal(t, len(batch), count)
+		docID, _ := m.MetaGet("doc_id")
+		assert.Equal(t, "foo", docID)
+	}
+	return chunks
+}
+
+func TestChunkerRecursiveCharacter(t *testing.T) {
+	p := testChunker(t, `
+chunk_size: 30
+`, nil)
+	assert.Equal(t, []string{
+		"The first paragraph is short.",
+		"The second paragraph is much",
+		"longer than the chunk size and",
+		"must be split up into words.",
+	}, testChunks(t, p, "The first paragraph is short.\n\nThe second paragraph is much longer than the chunk size and must be split up into words."))
+
+	p = testChunker(t, `
+chunk_size: 10
+chunk_overlap: 4
+`, nil)
+	assert.Equal(t, []string{
+		"aa bb cc",
+		"cc dd ee",
+		"ee ff",
+	}, testChunks(t, p, "aa bb cc dd ee ff"))
+
+	p = testChunker(t, `
+chunk_size: 4
+`, nil)
+	assert.Equal(t, []string{"abcd", "efgh", "ij"}, testChunks(t, p, "abcdefghij"))
+}
+
+func TestChunkerToken(t *testing.T) {
+	p := testChunker(t, `
+strategy: token
+chunk_size: 4
+chunk_overlap: 1
+`, nil)
+	assert.Equal(t, []string{
+		"Hello, world!",
+		"! This is a",
+		"a test of tokens",
+		"tokens.",
+	}, testChunks(t, p, "Hello, world! This is a test of tokens."))
+}
+
+func TestChunkerSentence(t *testing.T) {
+	p := testChunker(t, `
+strategy: sentence
+chunk_size: 40
+chunk_overlap: 20
+`, nil)
+	assert.Equal(t, []string{
+		"First sentence. Second one!",
+		"Second one! Is this the third?",
+		`Is this the third? "Yes," it said.`,
+	}, testChunks(t, p, `First sentence. Second one! Is this the third? "Yes," it said.`))
+
+	assert.Equal(t, []string{"Version 1.2 is out.", "Next"}, splitSentences("Version 1.2 is out.\n\nNext"))
+}
+
+// chunkerTestEmbedder embeds sentences about cats close to each other and far
+// from everything else.
+type chunkerTestEmbedder struct{}
+
+func (chunkerTestEmbedder) Process(ctx context.Context, m *service.Message) (service.MessageBatch, error) {
+	b, err := m.AsBytes()
+	if err != nil {
+		return nil, err
+	}
+	if strings.Contains(string(b), "cat") {
+		m.SetStructuredMut([]any{1.0, 0.1})
+	} else {
+		m.SetStructuredMut([]any{0.1, 1.0})
+	}
+	return service.MessageBatch{m}, nil
+}
+
+func (chunkerTestEmbedder) Close(context.Context) error { return nil }
+
+func TestChunkerSemantic(t *testing.T) {
+	env := service.NewEnvironment()
+	require.NoError(t, env.RegisterProcessor("chunker_test_embed", service.NewConfigSpec(),
+		func(*service.ParsedConfig, *service.Resources) (service.Processor, error) {
+			return chunkerTestEmbedder{}, nil
+		}))
+
+	p := testChunker(t, `
+strategy: semantic
+breakpoint_percentile: 50
+embeddings:
+  - chunker_test_embed: {}
+`, env)
+	assert.Equal(t, []string{
+		"My cat is asleep. The cat purrs.",
+		"Stocks fell today. Markets are down.",
+	}, testChunks(t, p, "My cat is asleep. The cat purrs. Stocks fell today. Markets are down."))
+}
+
+func TestChunkerConfigErrors(t *testing.T) {
+	for _, conf := range []string{
+		`chunk_size: 0`,
+		`chunk_overlap: 1000`,
+		`strategy: semantic`,
+	} {
+		pConf, err := chunkerProcessorSpec().ParseYAML(conf, nil)
+		require.NoError(t, err)
+
+		_, err = newChunkerProcessorFromParsed(pConf)
+		assert.Error(t, err, conf)
+	}
+}
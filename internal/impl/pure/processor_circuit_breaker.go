@@ -0,0 +1,125 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"errors"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	cbpFieldProcessors = "processors"
+)
+
+func circuitBreakerProcessorSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Composition").
+		Version("4.45.0").
+		Summary("Executes a list of child processors behind a circuit breaker that stops executing them after a high rate of failures, in order to protect the systems they call.").
+		Description(circuitBreakerDescription("executions")+`
+
+An execution of the child processors on a batch is counted as failed when it returns an error or when any message of the resulting batches is flagged with an error.`).
+		Fields(
+			service.NewProcessorListField(cbpFieldProcessors).
+				Description("The child processors to execute."),
+		).
+		Fields(circuitBreakerFields("Skip the child processors and flag each message of the batch with an error, so that it can be handled by xref:configuration:error_handling.adoc[error handling methods].")...).
+		Example("Optional Enrichment", "Enrich messages from an HTTP service, skipping the enrichment while the service is failing rather than slowing down the pipeline.", `
+pipeline:
+  processors:
+    - circuit_breaker:
+        error_rate: 0.5
+        open_duration: 30s
+        processors:
+          - branch:
+              request_map: 'root.id = this.user_id'
+              processors:
+                - http:
+                    url: http://users.example.com/lookup
+                    verb: POST
+              result_map: 'root.user = this'
+    - catch:
+        - log:
+            message: 'Enrichment skipped: ${! error() }'
+`)
+}
+
+func init() {
+	err := service.RegisterBatchProcessor("circuit_breaker", circuitBreakerProcessorSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchProcessor, error) {
+			return newCircuitBreakerProcessorFromParsed(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type circuitBreakerProcessor struct {
+	children []*service.OwnedProcessor
+	breaker  *circuitBreaker
+}
+
+func newCircuitBreakerProcessorFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*circuitBreakerProcessor, error) {
+	p := &circuitBreakerProcessor{}
+
+	var err error
+	if p.breaker, err = newCircuitBreaker(conf, mgr); err != nil {
+		return nil, err
+	}
+	if p.children, err = conf.FieldProcessorList(cbpFieldProcessors); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *circuitBreakerProcessor) ProcessBatch(ctx context.Context, batch service.MessageBatch) ([]service.MessageBatch, error) {
+	release, err := p.breaker.acquire(ctx)
+	if err != nil {
+		if !errors.Is(err, errCircuitOpen) {
+			return nil, err
+		}
+		for _, m := range batch {
+			m.SetError(err)
+		}
+		return []service.MessageBatch{batch}, nil
+	}
+
+	results, err := service.ExecuteProcessors(ctx, p.children, batch)
+	resultErr := err
+	for _, b := range results {
+		for _, m := range b {
+			if resultErr != nil {
+				break
+			}
+			resultErr = m.GetError()
+		}
+	}
+	release(resultErr)
+	return results, err
+}
+
+func (p *circuitBreakerProcessor) Close(ctx context.Context) error {
+	for _, c := range p.children {
+		if err := c.Close(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func TestCircuitBreakerProcessor(t *testing.T) {
+	var calls atomic.Int64
+
+	env := service.NewEnvironment()
+	require.NoError(t, env.RegisterProcessor("circuit_breaker_test_child", service.NewConfigSpec(),
+		func(*service.ParsedConfig, *service.Resources) (service.Processor, error) {
+			return &funcProcessor{fn: func() error {
+				calls.Add(1)
+				return errors.New("nope")
+			}}, nil
+		}))
+
+	pConf, err := circuitBreakerProcessorSpec().ParseYAML(`
+min_requests: 2
+open_duration: 1h
+processors:
+  - circuit_breaker_test_child: {}
+`, env)
+	require.NoError(t, err)
+
+	p, err := newCircuitBreakerProcessorFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, p.Close(context.Background()))
+	})
+
+	for range 2 {
+		results, err := p.ProcessBatch(context.Background(), testBatch())
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		require.Len(t, results[0], 1)
+		require.EqualError(t, results[0][0].GetError(), "nope")
+	}
+
+	// The breaker is now open and the child is skipped.
+	results, err := p.ProcessBatch(context.Background(), testBatch())
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.Len(t, results[0], 1)
+	require.ErrorIs(t, results[0][0].GetError(), errCircuitOpen)
+
+	b, err := results[0][0].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(b))
+	assert.Equal(t, int64(2), calls.Load())
+}
@@ -0,0 +1,341 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	ccFieldCache     = "cache"
+	ccFieldThreshold = "threshold"
+	ccFieldKey       = "key"
+	ccFieldTTL       = "ttl"
+	ccFieldDelete    = "delete"
+
+	ccMetaKey = "claim_check_key"
+)
+
+func claimCheckProcessorSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Version("4.45.0").
+		Summary("Offloads the payloads of large messages to a cache resource, such as an object store, replacing them with a reference that can be restored with the `claim_check_rehydrate` processor.").
+		Description(`
+Messages with payloads larger than the `+"`threshold`"+` are stored within the cache under the `+"`key`"+`, and their payload is replaced with a JSON reference of the form:
+
+`+"```json"+`
+{"claim_check":{"key":"<key>","size":<bytes>,"sha256":"<hex digest>"}}
+`+"```"+`
+
+When a `+"`ttl`"+` is set the reference also contains the field `+"`expires_at`"+`, an RFC 3339 timestamp after which the payload may no longer be restored.
+
+The key is also added to the message as the metadata field `+"`claim_check_key`"+`. Metadata of the message is not offloaded and therefore remains with the reference. Smaller messages are left unchanged.
+
+Caches backed by object storage, such as `+"`aws_s3`"+`, `+"`gcp_cloud_storage`"+` and `+"`azure_blob_storage`"+`, are able to hold payloads far beyond the message size limits of brokers such as Kafka. By default the key is derived from the payload, which results in retried messages and duplicate payloads being stored only once.
+
+=== Cleanup
+
+Stored payloads can be cleaned up either by a `+"`ttl`"+`, for caches that support one, by the `+"`delete`"+` field of the `+"`claim_check_rehydrate`"+` processor, or by the lifecycle rules of an object store. Object store caches do not support TTLs, and so when using lifecycle rules the `+"`ttl`"+` should be set to match them, in which case it is recorded within the reference and a payload that is restored after it expires results in a descriptive error.
+
+== Metrics
+
+This processor emits a counter `+"`claim_check_offloaded`"+` which is incremented for each message that is offloaded.`).
+		Fields(
+			service.NewStringField(ccFieldCache).
+				Description("The cache resource to store payloads within."),
+			service.NewIntField(ccFieldThreshold).
+				Description("The size in bytes above which the payload of a message is offloaded.").
+				Default(900000),
+			service.NewInterpolatedStringField(ccFieldKey).
+				Description("The key to store a payload under, which is resolved before the payload is replaced.").
+				Default(`claim_check/${! content().hash("sha256").encode("hex") }`).
+				Example(`${! meta("kafka_topic") }/${! uuid_v4() }`),
+			service.NewDurationField(ccFieldTTL).
+				Description("An optional TTL for stored payloads, which is passed to the cache and recorded within the reference.").
+				Optional().
+				Advanced(),
+		).
+		Example("Large Kafka Messages", "Store payloads larger than Kafka allows within S3, sending references to them instead.", `
+pipeline:
+  processors:
+    - claim_check:
+        cache: payloads
+
+output:
+  kafka_franz:
+    seed_brokers: [ localhost:9092 ]
+    topic: documents
+
+cache_resources:
+  - label: payloads
+    aws_s3:
+      bucket: large-payloads
+`)
+}
+
+func claimCheckRehydrateProcessorSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Version("4.45.0").
+		Summary("Restores the payloads of messages that were offloaded to a cache resource by the `claim_check` processor.").
+		Description(`
+Messages with a payload that is a reference created by the `+"`claim_check`"+` processor have their payload replaced with the one stored within the cache, and the metadata field `+"`claim_check_key`"+` is removed. The size and digest of the stored payload are checked against the reference. Other messages are left unchanged.
+
+== Metrics
+
+This processor emits a counter `+"`claim_check_rehydrated`"+` which is incremented for each message that is restored.`).
+		Fields(
+			service.NewStringField(ccFieldCache).
+				Description("The cache resource that payloads are stored within."),
+			service.NewBoolField(ccFieldDelete).
+				Description("Whether to delete payloads from the cache once restored. This should only be enabled when keys are unique to each message, and a message that is retried after being restored cannot be restored again.").
+				Default(false).
+				Advanced(),
+		).
+		Example("Large Kafka Messages", "Restore payloads that were stored within S3 by a producer using the `claim_check` processor.", `
+input:
+  kafka_franz:
+    seed_brokers: [ localhost:9092 ]
+    topics: [ documents ]
+    consumer_group: documents
+
+pipeline:
+  processors:
+    - claim_check_rehydrate:
+        cache: payloads
+
+cache_resources:
+  - label: payloads
+    aws_s3:
+      bucket: large-payloads
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor("claim_check", claimCheckProcessorSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return newClaimCheckProcessorFromParsed(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+
+	err = service.RegisterProcessor("claim_check_rehydrate", claimCheckRehydrateProcessorSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return newClaimCheckRehydrateProcessorFromParsed(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type claimCheckRef struct {
+	Key       string     `json:"key"`
+	Size      int        `json:"size"`
+	SHA256    string     `json:"sha256"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+type claimCheckDoc struct {
+	ClaimCheck *claimCheckRef `json:"claim_check"`
+}
+
+func claimCheckCacheFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (string, error) {
+	cache, err := conf.FieldString(ccFieldCache)
+	if err != nil {
+		return "", err
+	}
+	if !mgr.HasCache(cache) {
+		return "", fmt.Errorf("cache resource '%v' was not found", cache)
+	}
+	return cache, nil
+}
+
+type claimCheckProcessor struct {
+	cache     string
+	threshold int
+	key       *service.InterpolatedString
+	ttl       *time.Duration
+
+	mgr        *service.Resources
+	mOffloaded *service.MetricCounter
+}
+
+func newClaimCheckProcessorFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*claimCheckProcessor, error) {
+	c := &claimCheckProcessor{
+		mgr:        mgr,
+		mOffloaded: mgr.Metrics().NewCounter("claim_check_offloaded"),
+	}
+
+	var err error
+	if c.cache, err = claimCheckCacheFromParsed(conf, mgr); err != nil {
+		return nil, err
+	}
+	if c.threshold, err = conf.FieldInt(ccFieldThreshold); err != nil {
+		return nil, err
+	}
+	if c.key, err = conf.FieldInterpolatedString(ccFieldKey); err != nil {
+		return nil, err
+	}
+	if conf.Contains(ccFieldTTL) {
+		ttl, err := conf.FieldDuration(ccFieldTTL)
+		if err != nil {
+			return nil, err
+		}
+		c.ttl = &ttl
+	}
+	return c, nil
+}
+
+func (c *claimCheckProcessor) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	payload, err := msg.AsBytes()
+	if err != nil {
+		return nil, err
+	}
+	if len(payload) <= c.threshold {
+		return service.MessageBatch{msg}, nil
+	}
+
+	key, err := c.key.TryString(msg)
+	if err != nil {
+		return nil, fmt.Errorf("key interpolation error: %w", err)
+	}
+
+	var setErr error
+	if err := c.mgr.AccessCache(ctx, c.cache, func(cache service.Cache) {
+		setErr = cache.Set(ctx, key, payload, c.ttl)
+	}); err != nil {
+		return nil, err
+	}
+	if setErr != nil {
+		return nil, fmt.Errorf("failed to store payload: %w", setErr)
+	}
+
+	sum := sha256.Sum256(payload)
+	ref := &claimCheckRef{
+		Key:    key,
+		Size:   len(payload),
+		SHA256: hex.EncodeToString(sum[:]),
+	}
+	if c.ttl != nil {
+		expiresAt := time.Now().Add(*c.ttl).UTC().Truncate(time.Second)
+		ref.ExpiresAt = &expiresAt
+	}
+
+	refBytes, err := json.Marshal(claimCheckDoc{ClaimCheck: ref})
+	if err != nil {
+		return nil, err
+	}
+
+	c.mOffloaded.Incr(1)
+	msg.SetBytes(refBytes)
+	msg.MetaSetMut(ccMetaKey, key)
+	return service.MessageBatch{msg}, nil
+}
+
+func (c *claimCheckProcessor) Close(ctx context.Context) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+type claimCheckRehydrateProcessor struct {
+	cache  string
+	delete bool
+
+	mgr         *service.Resources
+	mRehydrated *service.MetricCounter
+}
+
+func newClaimCheckRehydrateProcessorFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*claimCheckRehydrateProcessor, error) {
+	c := &claimCheckRehydrateProcessor{
+		mgr:         mgr,
+		mRehydrated: mgr.Metrics().NewCounter("claim_check_rehydrated"),
+	}
+
+	var err error
+	if c.cache, err = claimCheckCacheFromParsed(conf, mgr); err != nil {
+		return nil, err
+	}
+	if c.delete, err = conf.FieldBool(ccFieldDelete); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *claimCheckRehydrateProcessor) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	body, err := msg.AsBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	// References are small, and so larger payloads are not parsed.
+	var doc claimCheckDoc
+	if len(body) > 1024 || json.Unmarshal(body, &doc) != nil || doc.ClaimCheck == nil || doc.ClaimCheck.Key == "" {
+		return service.MessageBatch{msg}, nil
+	}
+	ref := doc.ClaimCheck
+
+	var payload []byte
+	var cacheErr error
+	if err := c.mgr.AccessCache(ctx, c.cache, func(cache service.Cache) {
+		if payload, cacheErr = cache.Get(ctx, ref.Key); cacheErr != nil {
+			return
+		}
+		if c.delete {
+			cacheErr = cache.Delete(ctx, ref.Key)
+		}
+	}); err != nil {
+		return nil, err
+	}
+	if cacheErr != nil {
+		if errors.Is(cacheErr, service.ErrKeyNotFound) && ref.ExpiresAt != nil && time.Now().After(*ref.ExpiresAt) {
+			return nil, fmt.Errorf("failed to restore payload %v: expired at %v", ref.Key, ref.ExpiresAt.Format(time.RFC3339))
+		}
+		return nil, fmt.Errorf("failed to restore payload %v: %w", ref.Key, cacheErr)
+	}
+
+	if len(payload) != ref.Size {
+		return nil, fmt.Errorf("restored payload %v has a size of %v bytes, expected %v", ref.Key, len(payload), ref.Size)
+	}
+	if ref.SHA256 != "" {
+		sum := sha256.Sum256(payload)
+		if digest := hex.EncodeToString(sum[:]); digest != ref.SHA256 {
+			return nil, fmt.Errorf("restored payload %v has a digest of %v, expected %v", ref.Key, digest, ref.SHA256)
+		}
+	}
+
+	c.mRehydrated.Incr(1)
+	msg.SetBytes(payload)
+	msg.MetaDelete(ccMetaKey)
+	return service.MessageBatch{msg}, nil
+}
+
+func (c *claimCheckRehydrateProcessor) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,165 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func testClaimCheckProcessors(t *testing.T, res *service.Resources, offloadConf, rehydrateConf string) (*claimCheckProcessor, *claimCheckRehydrateProcessor) {
+	t.Helper()
+
+	pConf, err := claimCheckProcessorSpec().ParseYAML(offloadConf, nil)
+	require.NoError(t, err)
+	offload, err := newClaimCheckProcessorFromParsed(pConf, res)
+	require.NoError(t, err)
+
+	pConf, err = claimCheckRehydrateProcessorSpec().ParseYAML(rehydrateConf, nil)
+	require.NoError(t, err)
+	rehydrate, err := newClaimCheckRehydrateProcessorFromParsed(pConf, res)
+	require.NoError(t, err)
+
+	return offload, rehydrate
+}
+
+func processOne(t *testing.T, p service.Processor, msg *service.Message) *service.Message {
+	t.Helper()
+
+	batch, err := p.Process(context.Background(), msg)
+	require.NoError(t, err)
+	require.Len(t, batch, 1)
+	return batch[0]
+}
+
+func TestClaimCheckRoundTrip(t *testing.T) {
+	res := service.MockResources(service.MockResourcesOptAddCache("payloads"))
+	offload, rehydrate := testClaimCheckProcessors(t, res, `
+cache: payloads
+threshold: 10
+`, `cache: payloads`)
+
+	large := strings.Repeat("x", 20)
+
+	small := processOne(t, offload, service.NewMessage([]byte("small")))
+	b, err := small.AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, "small", string(b))
+	_, exists := small.MetaGet(ccMetaKey)
+	assert.False(t, exists)
+
+	msg := service.NewMessage([]byte(large))
+	msg.MetaSetMut("foo", "bar")
+	ref := processOne(t, offload, msg)
+
+	key := "claim_check/d4fc1db665446507dc51b0c9392dd9649291581bfe1b48e241b2b08032b3b647"
+	refBytes, err := ref.AsBytes()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"claim_check":{"key":"`+key+`","size":20,"sha256":"`+strings.TrimPrefix(key, "claim_check/")+`"}}`, string(refBytes))
+
+	metaKey, _ := ref.MetaGet(ccMetaKey)
+	assert.Equal(t, key, metaKey)
+
+	require.NoError(t, res.AccessCache(context.Background(), "payloads", func(c service.Cache) {
+		v, err := c.Get(context.Background(), key)
+		require.NoError(t, err)
+		assert.Equal(t, large, string(v))
+	}))
+
+	restored := processOne(t, rehydrate, ref)
+	b, err = restored.AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, large, string(b))
+
+	foo, _ := restored.MetaGet("foo")
+	assert.Equal(t, "bar", foo)
+	_, exists = restored.MetaGet(ccMetaKey)
+	assert.False(t, exists)
+
+	passthrough := processOne(t, rehydrate, service.NewMessage([]byte(`{"claim_check":"not a reference"}`)))
+	b, err = passthrough.AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, `{"claim_check":"not a reference"}`, string(b))
+}
+
+func TestClaimCheckRehydrateErrors(t *testing.T) {
+	res := service.MockResources(service.MockResourcesOptAddCache("payloads"))
+	offload, rehydrate := testClaimCheckProcessors(t, res, `
+cache: payloads
+threshold: 1
+key: ${! meta("id") }
+`, `
+cache: payloads
+delete: true
+`)
+
+	msg := service.NewMessage([]byte("hello world"))
+	msg.MetaSetMut("id", "a")
+	ref := processOne(t, offload, msg)
+
+	require.NoError(t, res.AccessCache(context.Background(), "payloads", func(c service.Cache) {
+		require.NoError(t, c.Set(context.Background(), "a", []byte("hello there"), nil))
+	}))
+
+	_, err := rehydrate.Process(context.Background(), ref.Copy())
+	require.ErrorContains(t, err, "restored payload a has a digest of")
+
+	require.NoError(t, res.AccessCache(context.Background(), "payloads", func(c service.Cache) {
+		require.NoError(t, c.Set(context.Background(), "a", []byte("hello world"), nil))
+	}))
+
+	restored := processOne(t, rehydrate, ref.Copy())
+	b, err := restored.AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, "hello world", string(b))
+
+	_, err = rehydrate.Process(context.Background(), ref.Copy())
+	require.ErrorContains(t, err, "failed to restore payload a")
+}
+
+func TestClaimCheckExpiry(t *testing.T) {
+	res := service.MockResources(service.MockResourcesOptAddCache("payloads"))
+	offload, rehydrate := testClaimCheckProcessors(t, res, `
+cache: payloads
+threshold: 1
+ttl: 1h
+`, `cache: payloads`)
+
+	ref := processOne(t, offload, service.NewMessage([]byte("hello world")))
+	doc, err := ref.AsStructured()
+	require.NoError(t, err)
+
+	expiresAt, err := time.Parse(time.RFC3339, doc.(map[string]any)["claim_check"].(map[string]any)["expires_at"].(string))
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), expiresAt, time.Minute)
+
+	_, err = rehydrate.Process(context.Background(), service.NewMessage([]byte(`{"claim_check":{"key":"gone","size":5,"expires_at":"2020-01-01T00:00:00Z"}}`)))
+	require.EqualError(t, err, "failed to restore payload gone: expired at 2020-01-01T00:00:00Z")
+}
+
+func TestClaimCheckMissingCache(t *testing.T) {
+	pConf, err := claimCheckProcessorSpec().ParseYAML(`cache: nope`, nil)
+	require.NoError(t, err)
+
+	_, err = newClaimCheckProcessorFromParsed(pConf, service.MockResources())
+	require.ErrorContains(t, err, "cache resource 'nope' was not found")
+}
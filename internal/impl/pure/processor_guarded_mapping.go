@@ -0,0 +1,174 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/redpanda-data/benthos/v4/public/bloblang"
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	gmFieldMapping      = "mapping"
+	gmFieldTimeout      = "timeout"
+	gmFieldMaxAbandoned = "max_abandoned"
+)
+
+func guardedMappingProcessorSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Mapping").
+		Version("4.45.0").
+		Summary("Executes a Bloblang mapping like the `mapping` processor, failing messages that take longer than a timeout to map.").
+		Description(`
+This processor behaves like the xref:components:processors/mapping.adoc[`+"`mapping`"+` processor], with each execution of the mapping limited to a duration. A message that exceeds the `+"`timeout`"+` is flagged as failed and left unchanged, and can be handled with xref:configuration:error_handling.adoc[error handling methods], allowing a pathological regular expression or an unbounded loop within a mapping to be detected rather than wedging a processing thread indefinitely.
+
+=== Abandoned Executions
+
+Bloblang executions cannot be interrupted, and so an execution that exceeds the timeout continues to run in the background until it completes, and its result is discarded. In order to prevent such executions from accumulating and exhausting CPU and memory at most `+"`max_abandoned`"+` of them may be running at any given time, and whilst that many are running messages are failed immediately without being mapped.
+
+== Metrics
+
+This processor emits a counter `+"`guarded_mapping_timeouts`"+` which is incremented each time an execution exceeds the timeout, and a counter `+"`guarded_mapping_rejected`"+` which is incremented each time a message is failed due to abandoned executions.`).
+		Fields(
+			service.NewBloblangField(gmFieldMapping).
+				Description("The mapping to execute against each message."),
+			service.NewDurationField(gmFieldTimeout).
+				Description("The maximum duration of an execution of the mapping.").
+				Default("1s"),
+			service.NewIntField(gmFieldMaxAbandoned).
+				Description("The maximum number of executions that have exceeded the timeout that may continue to run in the background.").
+				Default(1).
+				Advanced(),
+		).
+		Example("Untrusted Mappings", "Guard against mappings supplied by users that may contain pathological expressions.", `
+pipeline:
+  processors:
+    - guarded_mapping:
+        timeout: 100ms
+        mapping: |
+          root = this
+          root.matches = this.text.re_find_all(env("USER_PATTERN"))
+    - catch:
+        - log:
+            message: 'Mapping failed: ${! error() }'
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor("guarded_mapping", guardedMappingProcessorSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return newGuardedMappingProcessorFromParsed(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type guardedMappingResult struct {
+	msg *service.Message
+	err error
+}
+
+type guardedMappingProcessor struct {
+	exec         *bloblang.Executor
+	timeout      time.Duration
+	maxAbandoned int64
+	abandoned    atomic.Int64
+
+	mTimeouts *service.MetricCounter
+	mRejected *service.MetricCounter
+}
+
+func newGuardedMappingProcessorFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*guardedMappingProcessor, error) {
+	g := &guardedMappingProcessor{
+		mTimeouts: mgr.Metrics().NewCounter("guarded_mapping_timeouts"),
+		mRejected: mgr.Metrics().NewCounter("guarded_mapping_rejected"),
+	}
+
+	var err error
+	if g.exec, err = conf.FieldBloblang(gmFieldMapping); err != nil {
+		return nil, err
+	}
+	if g.timeout, err = conf.FieldDuration(gmFieldTimeout); err != nil {
+		return nil, err
+	}
+	if g.timeout <= 0 {
+		return nil, errors.New("timeout must be greater than zero")
+	}
+	maxAbandoned, err := conf.FieldInt(gmFieldMaxAbandoned)
+	if err != nil {
+		return nil, err
+	}
+	if maxAbandoned < 0 {
+		return nil, errors.New("max_abandoned must not be negative")
+	}
+	g.maxAbandoned = int64(maxAbandoned)
+	return g, nil
+}
+
+func (g *guardedMappingProcessor) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	if abandoned := g.abandoned.Load(); abandoned > 0 && abandoned >= g.maxAbandoned {
+		g.mRejected.Incr(1)
+		return nil, fmt.Errorf("mapping rejected as %v timed out executions are still running", abandoned)
+	}
+
+	// The mapping is executed against a copy so that the original can be
+	// failed without racing an abandoned execution.
+	var err error
+	input := msg.Copy()
+	resChan := make(chan guardedMappingResult, 1)
+	go func() {
+		res, err := input.BloblangQuery(g.exec)
+		resChan <- guardedMappingResult{msg: res, err: err}
+	}()
+
+	timer := time.NewTimer(g.timeout)
+	defer timer.Stop()
+
+	select {
+	case res := <-resChan:
+		if res.err != nil {
+			return nil, res.err
+		}
+		if res.msg == nil {
+			return nil, nil
+		}
+		return service.MessageBatch{res.msg}, nil
+	case <-timer.C:
+		g.mTimeouts.Incr(1)
+		err = fmt.Errorf("mapping exceeded the timeout of %v", g.timeout)
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	g.abandoned.Add(1)
+	go func() {
+		<-resChan
+		g.abandoned.Add(-1)
+	}()
+	return nil, err
+}
+
+func (g *guardedMappingProcessor) Close(ctx context.Context) error {
+	return nil
+}
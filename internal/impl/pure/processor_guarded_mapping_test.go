@@ -0,0 +1,101 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/bloblang"
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+// testGuardedMapping creates a guarded_mapping processor with a function
+// gm_block available to its mapping, which blocks until release is closed.
+func testGuardedMapping(t *testing.T, conf string, release <-chan struct{}) *guardedMappingProcessor {
+	t.Helper()
+
+	bEnv := bloblang.NewEnvironment()
+	require.NoError(t, bEnv.RegisterFunctionV2("gm_block", bloblang.NewPluginSpec(),
+		func(*bloblang.ParsedParams) (bloblang.Function, error) {
+			return func() (any, error) {
+				<-release
+				return "released", nil
+			}, nil
+		}))
+
+	env := service.NewEnvironment()
+	env.UseBloblangEnvironment(bEnv)
+
+	pConf, err := guardedMappingProcessorSpec().ParseYAML(conf, env)
+	require.NoError(t, err)
+
+	p, err := newGuardedMappingProcessorFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+	return p
+}
+
+func TestGuardedMappingWithinTimeout(t *testing.T) {
+	p := testGuardedMapping(t, `mapping: 'root.doubled = this.value * 2'`, nil)
+
+	out, err := p.Process(context.Background(), service.NewMessage([]byte(`{"value":21}`)))
+	require.NoError(t, err)
+	require.Len(t, out, 1)
+
+	b, err := out[0].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, `{"doubled":42}`, string(b))
+
+	_, err = p.Process(context.Background(), service.NewMessage([]byte(`{}`)))
+	require.ErrorContains(t, err, "cannot multiply types null")
+	assert.Zero(t, p.abandoned.Load())
+
+	p = testGuardedMapping(t, `mapping: 'root = deleted()'`, nil)
+	out, err = p.Process(context.Background(), service.NewMessage([]byte(`{}`)))
+	require.NoError(t, err)
+	assert.Empty(t, out)
+}
+
+func TestGuardedMappingTimeout(t *testing.T) {
+	release := make(chan struct{})
+	p := testGuardedMapping(t, `
+timeout: 10ms
+mapping: 'root = if content() == "block" { gm_block() } else { content().uppercase() }'
+`, release)
+
+	_, err := p.Process(context.Background(), service.NewMessage([]byte("block")))
+	require.EqualError(t, err, "mapping exceeded the timeout of 10ms")
+
+	// Whilst the abandoned execution is running messages are rejected.
+	_, err = p.Process(context.Background(), service.NewMessage([]byte("hello")))
+	require.EqualError(t, err, "mapping rejected as 1 timed out executions are still running")
+
+	close(release)
+	require.Eventually(t, func() bool {
+		return p.abandoned.Load() == 0
+	}, time.Second, time.Millisecond*5)
+
+	out, err := p.Process(context.Background(), service.NewMessage([]byte("hello")))
+	require.NoError(t, err)
+	require.Len(t, out, 1)
+
+	b, err := out[0].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, "HELLO", string(b))
+}
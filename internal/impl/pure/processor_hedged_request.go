@@ -0,0 +1,386 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redpanda-data/benthos/v4/public/bloblang"
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	hrFieldProcessors = "processors"
+	hrFieldRateLimit  = "rate_limit"
+	hrFieldHedgeAfter = "hedge_after"
+	hrFieldFallback   = "fallback"
+
+	hrMetaError = "hedged_request_error"
+
+	hrLatencySamples    = 1000
+	hrMinLatencySamples = 20
+)
+
+func hedgedRequestProcessorSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Composition").
+		Version("4.45.0").
+		Summary("Executes child processors, such as requests to an enrichment API, within the quota of a rate limit, hedging slow requests with a duplicate and falling back to a partial result when they fail.").
+		Description(`
+This processor is intended to wrap the `+"`processors`"+` of a `+"`branch`"+` processor, or of the branches of a `+"`workflow`"+` processor, giving each branch of a multi-API enrichment its own quota and latency behaviour.
+
+=== Rate Limiting
+
+When a `+"`rate_limit`"+` is set each execution of the child processors waits until the rate limit resource permits it.
+
+=== Hedging
+
+When `+"`hedge_after`"+` is set and the child processors have not completed within it, a duplicate execution is started with a copy of the batch, and the result of whichever succeeds first is used whilst the other is cancelled. The delay is either a fixed duration, or a percentile of the latencies of recent successful executions, such as `+"`p99`"+`, which hedges only the slowest requests. Percentile delays only take effect once 20 executions have succeeded. A duplicate is only started if the rate limit permits it immediately in order to stay within quota, and therefore child processors must tolerate a batch being processed twice.
+
+=== Partial Results
+
+When a `+"`fallback`"+` mapping is set and the child processors fail, each message is replaced with the result of executing the mapping against the original message, and the error is added to the metadata field `+"`hedged_request_error`"+`. Within a `+"`workflow`"+` this allows the `+"`result_map`"+` of a failed branch to be applied with a partial result rather than the message being flagged as failed. Without a fallback the results of the failed execution are returned unchanged.
+
+== Metrics
+
+This processor emits a counter `+"`hedged_request_hedged`"+` which is incremented each time a duplicate execution is started, and a counter `+"`hedged_request_fallback`"+` which is incremented each time the fallback is used.`).
+		Fields(
+			service.NewProcessorListField(hrFieldProcessors).
+				Description("The child processors to execute."),
+			service.NewStringField(hrFieldRateLimit).
+				Description("An optional rate limit resource to throttle executions of the child processors with.").
+				Optional(),
+			service.NewStringField(hrFieldHedgeAfter).
+				Description("An optional delay after which a duplicate execution is started, either a duration or a percentile of recent latencies in the form `pNN`.").
+				Example("200ms").
+				Example("p99").
+				Optional(),
+			service.NewBloblangField(hrFieldFallback).
+				Description("An optional mapping executed against each original message when the child processors fail, the result of which replaces the message.").
+				Example(`root = {}`).
+				Example(`root.status = "unknown"`).
+				Optional(),
+		).
+		Example("Multi-API Enrichment", "Enrich users and orders from two APIs with separate quotas, hedging slow user lookups and tolerating failed order lookups.", `
+pipeline:
+  processors:
+    - workflow:
+        branches:
+          user:
+            request_map: root.id = this.user_id
+            processors:
+              - hedged_request:
+                  rate_limit: users_api
+                  hedge_after: p99
+                  processors:
+                    - http:
+                        url: http://users.example.com/users/${! this.id }
+                        verb: GET
+            result_map: root.user = this
+          orders:
+            request_map: root.id = this.user_id
+            processors:
+              - hedged_request:
+                  rate_limit: orders_api
+                  fallback: root = []
+                  processors:
+                    - http:
+                        url: http://orders.example.com/users/${! this.id }/orders
+                        verb: GET
+            result_map: root.orders = this
+
+rate_limit_resources:
+  - label: users_api
+    local:
+      count: 100
+      interval: 1s
+  - label: orders_api
+    local:
+      count: 20
+      interval: 1s
+`)
+}
+
+func init() {
+	err := service.RegisterBatchProcessor("hedged_request", hedgedRequestProcessorSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchProcessor, error) {
+			return newHedgedRequestProcessorFromParsed(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type hedgedRequestProcessor struct {
+	children   []*service.OwnedProcessor
+	rateLimit  string
+	hedgeDelay time.Duration
+	percentile float64
+	fallback   *bloblang.Executor
+
+	latMut       sync.Mutex
+	latencies    []time.Duration
+	latIndex     int
+	latThreshold time.Duration
+
+	mgr       *service.Resources
+	mHedged   *service.MetricCounter
+	mFallback *service.MetricCounter
+}
+
+func newHedgedRequestProcessorFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*hedgedRequestProcessor, error) {
+	h := &hedgedRequestProcessor{
+		mgr:       mgr,
+		mHedged:   mgr.Metrics().NewCounter("hedged_request_hedged"),
+		mFallback: mgr.Metrics().NewCounter("hedged_request_fallback"),
+	}
+
+	var err error
+	if h.children, err = conf.FieldProcessorList(hrFieldProcessors); err != nil {
+		return nil, err
+	}
+	if conf.Contains(hrFieldRateLimit) {
+		if h.rateLimit, err = conf.FieldString(hrFieldRateLimit); err != nil {
+			return nil, err
+		}
+		if !mgr.HasRateLimit(h.rateLimit) {
+			return nil, fmt.Errorf("rate limit resource '%v' was not found", h.rateLimit)
+		}
+	}
+	if conf.Contains(hrFieldHedgeAfter) {
+		hedgeStr, err := conf.FieldString(hrFieldHedgeAfter)
+		if err != nil {
+			return nil, err
+		}
+		if h.hedgeDelay, h.percentile, err = parseHedgeAfter(hedgeStr); err != nil {
+			return nil, err
+		}
+	}
+	if conf.Contains(hrFieldFallback) {
+		if h.fallback, err = conf.FieldBloblang(hrFieldFallback); err != nil {
+			return nil, err
+		}
+	}
+	return h, nil
+}
+
+func parseHedgeAfter(s string) (time.Duration, float64, error) {
+	if pStr, ok := strings.CutPrefix(s, "p"); ok {
+		p, err := strconv.ParseFloat(pStr, 64)
+		if err != nil || p <= 0 || p >= 100 {
+			return 0, 0, fmt.Errorf("invalid hedge percentile %q, expected a value between p0 and p100", s)
+		}
+		return 0, p, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to parse hedge_after: %w", err)
+	}
+	if d <= 0 {
+		return 0, 0, errors.New("hedge_after must be greater than zero")
+	}
+	return d, 0, nil
+}
+
+func (h *hedgedRequestProcessor) recordLatency(d time.Duration) {
+	if h.percentile == 0 {
+		return
+	}
+
+	h.latMut.Lock()
+	defer h.latMut.Unlock()
+
+	if len(h.latencies) < hrLatencySamples {
+		h.latencies = append(h.latencies, d)
+	} else {
+		h.latencies[h.latIndex] = d
+		h.latIndex = (h.latIndex + 1) % hrLatencySamples
+	}
+	if len(h.latencies) < hrMinLatencySamples {
+		return
+	}
+
+	sorted := slices.Clone(h.latencies)
+	slices.Sort(sorted)
+	i := int(float64(len(sorted)) * h.percentile / 100)
+	h.latThreshold = sorted[min(i, len(sorted)-1)]
+}
+
+// currentHedgeDelay returns the delay after which to hedge, or false when
+// hedging is disabled or there are not yet enough latency samples.
+func (h *hedgedRequestProcessor) currentHedgeDelay() (time.Duration, bool) {
+	if h.hedgeDelay > 0 {
+		return h.hedgeDelay, true
+	}
+	if h.percentile == 0 {
+		return 0, false
+	}
+
+	h.latMut.Lock()
+	defer h.latMut.Unlock()
+	return h.latThreshold, h.latThreshold > 0
+}
+
+// acquire obtains permission from the rate limit, waiting for it when wait is
+// true, and otherwise returning false if it is not immediately available.
+func (h *hedgedRequestProcessor) acquire(ctx context.Context, wait bool) (bool, error) {
+	if h.rateLimit == "" {
+		return true, nil
+	}
+	for {
+		var period time.Duration
+		var rlErr error
+		if err := h.mgr.AccessRateLimit(ctx, h.rateLimit, func(r service.RateLimit) {
+			period, rlErr = r.Access(ctx)
+		}); err != nil {
+			return false, err
+		}
+		if rlErr != nil {
+			return false, rlErr
+		}
+		if period <= 0 {
+			return true, nil
+		}
+		if !wait {
+			return false, nil
+		}
+		select {
+		case <-time.After(period):
+		case <-ctx.Done():
+			return false, ctx.Err()
+		}
+	}
+}
+
+type hedgedRequestResult struct {
+	batches []service.MessageBatch
+	err     error
+	failed  error
+	taken   time.Duration
+}
+
+func (h *hedgedRequestProcessor) execute(ctx context.Context, batch service.MessageBatch, results chan<- hedgedRequestResult) {
+	start := time.Now()
+	batches, err := service.ExecuteProcessors(ctx, h.children, batch)
+	res := hedgedRequestResult{batches: batches, err: err, taken: time.Since(start)}
+	if err == nil {
+		if _, firstErr := firstBatchError(batches); firstErr != nil {
+			res.failed = firstErr
+		}
+	}
+	results <- res
+}
+
+func (h *hedgedRequestProcessor) ProcessBatch(ctx context.Context, batch service.MessageBatch) ([]service.MessageBatch, error) {
+	var originals service.MessageBatch
+	if h.fallback != nil || h.hedgeDelay > 0 || h.percentile > 0 {
+		originals = batch.Copy()
+	}
+
+	if _, err := h.acquire(ctx, true); err != nil {
+		return nil, err
+	}
+
+	attemptCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan hedgedRequestResult, 2)
+	go h.execute(attemptCtx, batch, results)
+	pending := 1
+
+	var hedgeChan <-chan time.Time
+	if delay, ok := h.currentHedgeDelay(); ok {
+		hedgeTimer := time.NewTimer(delay)
+		defer hedgeTimer.Stop()
+		hedgeChan = hedgeTimer.C
+	}
+
+	var last hedgedRequestResult
+	for {
+		select {
+		case res := <-results:
+			pending--
+			if res.err == nil && res.failed == nil {
+				h.recordLatency(res.taken)
+				return res.batches, nil
+			}
+			last = res
+			if pending == 0 {
+				return h.handleFailure(originals, last)
+			}
+		case <-hedgeChan:
+			hedgeChan = nil
+			ok, err := h.acquire(ctx, false)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				h.mHedged.Incr(1)
+				go h.execute(attemptCtx, originals.Copy(), results)
+				pending++
+			}
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (h *hedgedRequestProcessor) handleFailure(originals service.MessageBatch, res hedgedRequestResult) ([]service.MessageBatch, error) {
+	if h.fallback == nil {
+		return res.batches, res.err
+	}
+
+	failure := res.err
+	if failure == nil {
+		failure = res.failed
+	}
+	h.mFallback.Incr(1)
+
+	var fallbackBatch service.MessageBatch
+	for _, msg := range originals {
+		fMsg, err := msg.BloblangQuery(h.fallback)
+		if err != nil {
+			return nil, fmt.Errorf("fallback mapping failed: %w", err)
+		}
+		if fMsg == nil {
+			continue
+		}
+		fMsg.MetaSetMut(hrMetaError, failure.Error())
+		fallbackBatch = append(fallbackBatch, fMsg)
+	}
+	if len(fallbackBatch) == 0 {
+		return nil, nil
+	}
+	return []service.MessageBatch{fallbackBatch}, nil
+}
+
+func (h *hedgedRequestProcessor) Close(ctx context.Context) error {
+	for _, c := range h.children {
+		if err := c.Close(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,200 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+// hrTestChild executes a function for each batch and counts the executions.
+type hrTestChild struct {
+	calls     atomic.Int64
+	processFn func(ctx context.Context, call int64, b service.MessageBatch) ([]service.MessageBatch, error)
+}
+
+func (c *hrTestChild) ProcessBatch(ctx context.Context, b service.MessageBatch) ([]service.MessageBatch, error) {
+	return c.processFn(ctx, c.calls.Add(1), b)
+}
+
+func (c *hrTestChild) Close(context.Context) error { return nil }
+
+func testHedgedRequest(t *testing.T, child *hrTestChild, conf string, mgr *service.Resources) *hedgedRequestProcessor {
+	t.Helper()
+
+	env := service.NewEnvironment()
+	require.NoError(t, env.RegisterBatchProcessor("hr_test_child", service.NewConfigSpec(),
+		func(*service.ParsedConfig, *service.Resources) (service.BatchProcessor, error) {
+			return child, nil
+		}))
+
+	pConf, err := hedgedRequestProcessorSpec().ParseYAML(conf+`
+processors:
+  - hr_test_child: {}
+`, env)
+	require.NoError(t, err)
+
+	if mgr == nil {
+		mgr = service.MockResources()
+	}
+	p, err := newHedgedRequestProcessorFromParsed(pConf, mgr)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, p.Close(context.Background()))
+	})
+	return p
+}
+
+func TestHedgedRequestHedgeAfterParse(t *testing.T) {
+	d, p, err := parseHedgeAfter("150ms")
+	require.NoError(t, err)
+	assert.Equal(t, 150*time.Millisecond, d)
+	assert.Zero(t, p)
+
+	d, p, err = parseHedgeAfter("p99.5")
+	require.NoError(t, err)
+	assert.Zero(t, d)
+	assert.InDelta(t, 99.5, p, 0.001)
+
+	for _, bad := range []string{"p100", "pfoo", "nope", "0s"} {
+		_, _, err = parseHedgeAfter(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestHedgedRequestHedgesSlowExecution(t *testing.T) {
+	child := &hrTestChild{
+		processFn: func(ctx context.Context, call int64, b service.MessageBatch) ([]service.MessageBatch, error) {
+			if call == 1 {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			}
+			b[0].SetStructured("hedged")
+			return []service.MessageBatch{b}, nil
+		},
+	}
+	p := testHedgedRequest(t, child, `hedge_after: 10ms`, nil)
+
+	out, err := p.ProcessBatch(context.Background(), testBatch())
+	require.NoError(t, err)
+	require.Len(t, out, 1)
+	require.Len(t, out[0], 1)
+
+	b, err := out[0][0].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, `"hedged"`, string(b))
+	assert.Equal(t, int64(2), child.calls.Load())
+}
+
+func TestHedgedRequestHedgeWithinRateLimit(t *testing.T) {
+	var accesses atomic.Int64
+	mgr := service.MockResources(service.MockResourcesOptAddRateLimit("quota", func(context.Context) (time.Duration, error) {
+		if accesses.Add(1) > 1 {
+			return time.Hour, nil
+		}
+		return 0, nil
+	}))
+
+	child := &hrTestChild{
+		processFn: func(ctx context.Context, call int64, b service.MessageBatch) ([]service.MessageBatch, error) {
+			time.Sleep(50 * time.Millisecond)
+			return []service.MessageBatch{b}, nil
+		},
+	}
+	p := testHedgedRequest(t, child, `
+rate_limit: quota
+hedge_after: 5ms
+`, mgr)
+
+	out, err := p.ProcessBatch(context.Background(), testBatch())
+	require.NoError(t, err)
+	require.Len(t, out, 1)
+	assert.Equal(t, int64(1), child.calls.Load())
+	assert.Equal(t, int64(2), accesses.Load())
+}
+
+func TestHedgedRequestPercentile(t *testing.T) {
+	child := &hrTestChild{
+		processFn: func(ctx context.Context, call int64, b service.MessageBatch) ([]service.MessageBatch, error) {
+			return []service.MessageBatch{b}, nil
+		},
+	}
+	p := testHedgedRequest(t, child, `hedge_after: p50`, nil)
+
+	_, ok := p.currentHedgeDelay()
+	assert.False(t, ok)
+
+	for i := 1; i <= hrMinLatencySamples; i++ {
+		p.recordLatency(time.Duration(i) * time.Millisecond)
+	}
+
+	d, ok := p.currentHedgeDelay()
+	require.True(t, ok)
+	assert.Equal(t, 11*time.Millisecond, d)
+}
+
+func TestHedgedRequestFallback(t *testing.T) {
+	child := &hrTestChild{
+		processFn: func(ctx context.Context, call int64, b service.MessageBatch) ([]service.MessageBatch, error) {
+			for _, m := range b {
+				m.SetError(errors.New("api unavailable"))
+			}
+			return []service.MessageBatch{b}, nil
+		},
+	}
+	p := testHedgedRequest(t, child, `fallback: 'root.id = this.id'`, nil)
+
+	out, err := p.ProcessBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"id":"a","body":"foo"}`)),
+	})
+	require.NoError(t, err)
+	require.Len(t, out, 1)
+	require.Len(t, out[0], 1)
+
+	m := out[0][0]
+	require.NoError(t, m.GetError())
+	b, err := m.AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, `{"id":"a"}`, string(b))
+
+	v, _ := m.MetaGet(hrMetaError)
+	assert.Equal(t, "api unavailable", v)
+}
+
+func TestHedgedRequestNoFallback(t *testing.T) {
+	child := &hrTestChild{
+		processFn: func(ctx context.Context, call int64, b service.MessageBatch) ([]service.MessageBatch, error) {
+			for _, m := range b {
+				m.SetError(errors.New("api unavailable"))
+			}
+			return []service.MessageBatch{b}, nil
+		},
+	}
+	p := testHedgedRequest(t, child, ``, nil)
+
+	out, err := p.ProcessBatch(context.Background(), testBatch())
+	require.NoError(t, err)
+	require.Len(t, out, 1)
+	require.Len(t, out[0], 1)
+	assert.EqualError(t, out[0][0].GetError(), "api unavailable")
+}
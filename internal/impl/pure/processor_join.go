@@ -0,0 +1,387 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/redpanda-data/benthos/v4/public/bloblang"
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	jpFieldLeftCheck  = "left_check"
+	jpFieldKey        = "key"
+	jpFieldResultMap  = "result_map"
+	jpFieldTTL        = "ttl"
+	jpFieldUnmatched  = "unmatched"
+	jpFieldMaxPending = "max_pending"
+	jpFieldSnapshot   = "snapshot"
+)
+
+const (
+	joinUnmatchedDrop  = "drop"
+	joinUnmatchedEmit  = "emit"
+	joinUnmatchedError = "error"
+)
+
+func joinProcessorSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Version("4.45.0").
+		Summary("Joins messages from two logical streams that share a key within a window of time.").
+		Description(`
+Messages are split into a left and a right side by the `+"`left_check`"+` query, and each message is held until a message of the opposite side with the same key arrives, at which point both are removed and the result of the `+"`result_map`"+` is emitted. Messages are matched one to one in the order that they arrived, and a message that is not matched within the `+"`ttl`"+` expires and is handled according to the `+"`unmatched`"+` policy.
+
+The two streams are typically consumed by a `+"`broker`"+` input, with the side of each message identified by metadata or by its structure.
+
+== Delivery guarantees
+
+Messages held by this processor are acknowledged at the time they are received, and therefore held messages are lost if the process stops, unless a `+"`snapshot`"+` is configured.
+
+When a `+"`snapshot`"+` is configured the held messages are written to a cache resource periodically and when the processor is closed, and are restored from it when the first batch is processed after a restart. Using a cache backed by object storage, such as `+"`aws_s3`"+` or `+"`gcp_cloud_storage`"+`, allows long windows to survive restarts and rescheduling. Messages received since the last snapshot are lost if the process stops abruptly, setting the `+"`interval`"+` to `+"`0s`"+` writes a snapshot after every batch before it is acknowledged, at the cost of throughput, which ensures that every held message that has been acknowledged is within a snapshot provided that writing it succeeds.
+
+Messages that expire are emitted along with the results of the next batch processed, which means that expired messages are not emitted while no messages are being received.
+
+== Metadata
+
+Messages emitted because they were not matched contain the metadata field `+"`join_side`"+`, which is either `+"`left` or `right`"+`.`).
+		Fields(
+			service.NewBloblangField(jpFieldLeftCheck).
+				Description("A xref:guides:bloblang/about.adoc[Bloblang query] that should return a boolean value indicating whether a message belongs to the left side of the join, all other messages belong to the right side.").
+				Example(`@kafka_topic == "orders"`),
+			service.NewInterpolatedStringField(jpFieldKey).
+				Description("The key by which messages are joined.").
+				Example(`${! json("order_id") }`),
+			service.NewBloblangField(jpFieldResultMap).
+				Description("A xref:guides:bloblang/about.adoc[Bloblang mapping] that creates the result of a match, executed on a document containing the left message as `left` and the right message as `right`. Metadata of the result is that of the left message, followed by any metadata of the right message not already present.").
+				Default(`root = this.left.assign(this.right)`),
+			service.NewDurationField(jpFieldTTL).
+				Description("The maximum period that a message is held before it expires.").
+				Default("1m"),
+			service.NewStringAnnotatedEnumField(jpFieldUnmatched, map[string]string{
+				joinUnmatchedDrop:  "Expired messages are dropped.",
+				joinUnmatchedEmit:  "Expired messages are emitted unchanged.",
+				joinUnmatchedError: "Expired messages are emitted and flagged as failed, which allows them to be handled using xref:configuration:error_handling.adoc[error handling patterns].",
+			}).
+				Description("The policy for messages that expire without a match.").
+				Default(joinUnmatchedDrop),
+			service.NewIntField(jpFieldMaxPending).
+				Description("The maximum number of messages to hold, once reached the oldest held message expires early for each new message held.").
+				Default(10000).
+				Advanced(),
+			snapshotField(jpFieldSnapshot, "Optionally persist the held messages to a cache resource so that they survive restarts.", "join_state"),
+		).
+		Example("Join Orders and Payments", "Join orders with their payments, consumed from two Kafka topics, and flag orders that are not paid within ten minutes.", `
+input:
+  kafka_franz:
+    seed_brokers: [ localhost:9092 ]
+    topics: [ orders, payments ]
+    consumer_group: joiner
+
+pipeline:
+  processors:
+    - join:
+        left_check: '@kafka_topic == "orders"'
+        key: ${! json("order_id") }
+        ttl: 10m
+        unmatched: error
+        result_map: |
+          root = this.left
+          root.payment = this.right
+`)
+}
+
+func init() {
+	err := service.RegisterBatchProcessor("join", joinProcessorSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchProcessor, error) {
+			return newJoinProcessorFromParsed(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type joinEntry struct {
+	msg     *service.Message
+	key     string
+	left    bool
+	expires time.Time
+	elem    *list.Element
+}
+
+type joinProcessor struct {
+	leftCheck  *bloblang.Executor
+	key        *service.InterpolatedString
+	resultMap  *bloblang.Executor
+	ttl        time.Duration
+	unmatched  string
+	maxPending int
+	log        *service.Logger
+
+	mMatched   *service.MetricCounter
+	mUnmatched *service.MetricCounter
+	mPending   *service.MetricGauge
+
+	nowFn func() time.Time
+
+	snapshot *stateSnapshotter
+
+	mut      sync.Mutex
+	restored bool
+	// Held messages of each side by key, in the order they arrived.
+	pending [2]map[string][]*joinEntry
+	// All held messages in the order they arrived, and therefore the order in
+	// which they expire.
+	order *list.List
+}
+
+func newJoinProcessorFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*joinProcessor, error) {
+	j := &joinProcessor{
+		log:        mgr.Logger(),
+		mMatched:   mgr.Metrics().NewCounter("join_matched"),
+		mUnmatched: mgr.Metrics().NewCounter("join_unmatched"),
+		mPending:   mgr.Metrics().NewGauge("join_pending"),
+		nowFn:      time.Now,
+		pending:    [2]map[string][]*joinEntry{{}, {}},
+		order:      list.New(),
+	}
+
+	var err error
+	if j.leftCheck, err = conf.FieldBloblang(jpFieldLeftCheck); err != nil {
+		return nil, err
+	}
+	if j.key, err = conf.FieldInterpolatedString(jpFieldKey); err != nil {
+		return nil, err
+	}
+	if j.resultMap, err = conf.FieldBloblang(jpFieldResultMap); err != nil {
+		return nil, err
+	}
+	if j.ttl, err = conf.FieldDuration(jpFieldTTL); err != nil {
+		return nil, err
+	}
+	if j.unmatched, err = conf.FieldString(jpFieldUnmatched); err != nil {
+		return nil, err
+	}
+	if j.maxPending, err = conf.FieldInt(jpFieldMaxPending); err != nil {
+		return nil, err
+	}
+	if j.maxPending <= 0 {
+		return nil, errors.New("max_pending must be greater than zero")
+	}
+
+	if j.snapshot, err = stateSnapshotterFromParsed(conf, jpFieldSnapshot, "join", mgr); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+func joinSide(left bool) int {
+	if left {
+		return 0
+	}
+	return 1
+}
+
+func (j *joinProcessor) removeLocked(e *joinEntry) {
+	j.order.Remove(e.elem)
+
+	side := j.pending[joinSide(e.left)]
+	entries := side[e.key]
+	for i, other := range entries {
+		if other == e {
+			entries = append(entries[:i], entries[i+1:]...)
+			break
+		}
+	}
+	if len(entries) == 0 {
+		delete(side, e.key)
+	} else {
+		side[e.key] = entries
+	}
+}
+
+func (j *joinProcessor) expireLocked(e *joinEntry, out service.MessageBatch) service.MessageBatch {
+	j.removeLocked(e)
+	j.mUnmatched.Incr(1)
+
+	switch j.unmatched {
+	case joinUnmatchedEmit, joinUnmatchedError:
+		side := "right"
+		if e.left {
+			side = "left"
+		}
+		e.msg.MetaSetMut("join_side", side)
+		if j.unmatched == joinUnmatchedError {
+			e.msg.SetError(fmt.Errorf("no match found for key '%v' within %v", e.key, j.ttl))
+		}
+		out = append(out, e.msg)
+	}
+	return out
+}
+
+func (j *joinProcessor) isLeft(msg *service.Message) (bool, error) {
+	res, err := msg.BloblangQuery(j.leftCheck)
+	if err != nil {
+		return false, fmt.Errorf("left check failed: %w", err)
+	}
+	if res == nil {
+		return false, errors.New("left check failed: mapping deleted the message")
+	}
+	v, err := res.AsStructured()
+	if err != nil {
+		return false, fmt.Errorf("left check failed: %w", err)
+	}
+	left, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("left check must return a boolean, got %T", v)
+	}
+	return left, nil
+}
+
+func (j *joinProcessor) merge(left, right *service.Message) (*service.Message, error) {
+	leftV, err := left.AsStructured()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse left message: %w", err)
+	}
+	rightV, err := right.AsStructured()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse right message: %w", err)
+	}
+
+	joined := left.Copy()
+	_ = right.MetaWalkMut(func(k string, v any) error {
+		if _, exists := joined.MetaGetMut(k); !exists {
+			joined.MetaSetMut(k, v)
+		}
+		return nil
+	})
+	joined.SetStructuredMut(map[string]any{
+		"left":  leftV,
+		"right": rightV,
+	})
+
+	res, err := joined.BloblangQuery(j.resultMap)
+	if err != nil {
+		return nil, fmt.Errorf("result mapping failed: %w", err)
+	}
+	return res, nil
+}
+
+func (j *joinProcessor) ProcessBatch(ctx context.Context, batch service.MessageBatch) ([]service.MessageBatch, error) {
+	j.mut.Lock()
+	defer j.mut.Unlock()
+
+	if !j.restored {
+		if err := j.restoreLocked(ctx); err != nil {
+			return nil, err
+		}
+		j.restored = true
+	}
+
+	now := j.nowFn()
+
+	var out service.MessageBatch
+	for front := j.order.Front(); front != nil; front = j.order.Front() {
+		e := front.Value.(*joinEntry)
+		if e.expires.After(now) {
+			break
+		}
+		out = j.expireLocked(e, out)
+	}
+
+	for i, msg := range batch {
+		left, err := j.isLeft(msg)
+		if err != nil {
+			msg.SetError(err)
+			out = append(out, msg)
+			continue
+		}
+
+		key, err := batch.TryInterpolatedString(i, j.key)
+		if err != nil {
+			msg.SetError(fmt.Errorf("key interpolation failed: %w", err))
+			out = append(out, msg)
+			continue
+		}
+
+		if matches := j.pending[joinSide(!left)][key]; len(matches) > 0 {
+			match := matches[0]
+			j.removeLocked(match)
+
+			leftMsg, rightMsg := msg, match.msg
+			if !left {
+				leftMsg, rightMsg = match.msg, msg
+			}
+			joined, err := j.merge(leftMsg, rightMsg)
+			if err != nil {
+				msg.SetError(err)
+				out = append(out, msg)
+				continue
+			}
+			j.mMatched.Incr(1)
+			if joined != nil {
+				out = append(out, joined)
+			}
+			continue
+		}
+
+		if j.order.Len() >= j.maxPending {
+			out = j.expireLocked(j.order.Front().Value.(*joinEntry), out)
+		}
+
+		e := &joinEntry{
+			msg:     msg,
+			key:     key,
+			left:    left,
+			expires: now.Add(j.ttl),
+		}
+		e.elem = j.order.PushBack(e)
+		side := j.pending[joinSide(left)]
+		side[key] = append(side[key], e)
+	}
+
+	j.mPending.Set(int64(j.order.Len()))
+	if j.snapshot != nil {
+		j.snapshot.writeIfDue(ctx, now, j.snapshotLocked)
+	}
+
+	if len(out) == 0 {
+		return nil, nil
+	}
+	return []service.MessageBatch{out}, nil
+}
+
+func (j *joinProcessor) Close(ctx context.Context) error {
+	j.mut.Lock()
+	defer j.mut.Unlock()
+
+	if j.snapshot != nil {
+		if j.restored {
+			j.snapshot.writeNow(ctx, j.nowFn(), j.snapshotLocked)
+		}
+	} else if n := j.order.Len(); n > 0 {
+		j.log.Warnf("Dropping %v messages held without a match", n)
+	}
+	j.order.Init()
+	j.pending = [2]map[string][]*joinEntry{{}, {}}
+	return nil
+}
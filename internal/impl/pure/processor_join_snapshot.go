@@ -0,0 +1,86 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const joinSnapshotVersion = 1
+
+type joinSnapshot struct {
+	Version int                 `json:"version"`
+	Entries []joinSnapshotEntry `json:"entries"`
+}
+
+type joinSnapshotEntry struct {
+	snapshotMessage
+	Key     string `json:"key"`
+	Left    bool   `json:"left"`
+	Expires int64  `json:"expires"`
+}
+
+func (j *joinProcessor) snapshotLocked() (any, error) {
+	snap := joinSnapshot{
+		Version: joinSnapshotVersion,
+		Entries: make([]joinSnapshotEntry, 0, j.order.Len()),
+	}
+	for elem := j.order.Front(); elem != nil; elem = elem.Next() {
+		e := elem.Value.(*joinEntry)
+
+		sMsg, err := newSnapshotMessage(e.msg)
+		if err != nil {
+			return nil, err
+		}
+		snap.Entries = append(snap.Entries, joinSnapshotEntry{
+			snapshotMessage: sMsg,
+			Key:             e.key,
+			Left:            e.left,
+			Expires:         e.expires.UnixNano(),
+		})
+	}
+	return snap, nil
+}
+
+func (j *joinProcessor) restoreLocked(ctx context.Context) error {
+	if j.snapshot == nil {
+		return nil
+	}
+
+	var snap joinSnapshot
+	if exists, err := j.snapshot.read(ctx, &snap); err != nil || !exists {
+		return err
+	}
+	if snap.Version != joinSnapshotVersion {
+		return fmt.Errorf("unsupported snapshot version: %v", snap.Version)
+	}
+
+	for _, se := range snap.Entries {
+		e := &joinEntry{
+			msg:     se.message(),
+			key:     se.Key,
+			left:    se.Left,
+			expires: time.Unix(0, se.Expires),
+		}
+		e.elem = j.order.PushBack(e)
+		side := j.pending[joinSide(e.left)]
+		side[e.key] = append(side[e.key], e)
+	}
+
+	j.log.Infof("Restored %v held messages from snapshot", len(snap.Entries))
+	return nil
+}
@@ -0,0 +1,348 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func testJoinProcessor(t *testing.T, yamlStr string) *joinProcessor {
+	t.Helper()
+	return testJoinProcessorWithResources(t, yamlStr, service.MockResources())
+}
+
+func testJoinProcessorWithResources(t *testing.T, yamlStr string, res *service.Resources) *joinProcessor {
+	t.Helper()
+
+	conf, err := joinProcessorSpec().ParseYAML(yamlStr, nil)
+	require.NoError(t, err)
+
+	p, err := newJoinProcessorFromParsed(conf, res)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = p.Close(context.Background())
+	})
+	return p
+}
+
+func joinTestBatch(docs ...string) service.MessageBatch {
+	var batch service.MessageBatch
+	for _, d := range docs {
+		batch = append(batch, service.NewMessage([]byte(d)))
+	}
+	return batch
+}
+
+func joinResults(t *testing.T, batches []service.MessageBatch) []string {
+	t.Helper()
+
+	var res []string
+	for _, b := range batches {
+		for _, m := range b {
+			mBytes, err := m.AsBytes()
+			require.NoError(t, err)
+			res = append(res, string(mBytes))
+		}
+	}
+	return res
+}
+
+func TestJoinProcessorMatch(t *testing.T) {
+	p := testJoinProcessor(t, `
+left_check: 'this.type == "order"'
+key: ${! json("id") }
+`)
+
+	tCtx := context.Background()
+
+	res, err := p.ProcessBatch(tCtx, joinTestBatch(
+		`{"type":"order","id":"a","item":"foo"}`,
+		`{"type":"order","id":"b","item":"bar"}`,
+	))
+	require.NoError(t, err)
+	assert.Empty(t, res)
+
+	res, err = p.ProcessBatch(tCtx, joinTestBatch(
+		`{"type":"payment","id":"b","paid":true}`,
+		`{"type":"payment","id":"c","paid":true}`,
+	))
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		`{"id":"b","item":"bar","paid":true,"type":"payment"}`,
+	}, joinResults(t, res))
+
+	res, err = p.ProcessBatch(tCtx, joinTestBatch(
+		`{"type":"order","id":"c","item":"baz"}`,
+	))
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		`{"id":"c","item":"baz","paid":true,"type":"payment"}`,
+	}, joinResults(t, res))
+
+	assert.Equal(t, 1, p.order.Len())
+}
+
+func TestJoinProcessorResultMap(t *testing.T) {
+	p := testJoinProcessor(t, `
+left_check: '@side == "left"'
+key: ${! json("id") }
+result_map: |
+  root.order = this.left
+  root.payment = this.right
+`)
+
+	tCtx := context.Background()
+
+	left := service.NewMessage([]byte(`{"id":"a"}`))
+	left.MetaSetMut("side", "left")
+	left.MetaSetMut("from", "orders")
+
+	right := service.NewMessage([]byte(`{"id":"a","amount":10}`))
+	right.MetaSetMut("side", "right")
+	right.MetaSetMut("from", "payments")
+	right.MetaSetMut("currency", "gbp")
+
+	res, err := p.ProcessBatch(tCtx, service.MessageBatch{right})
+	require.NoError(t, err)
+	assert.Empty(t, res)
+
+	res, err = p.ProcessBatch(tCtx, service.MessageBatch{left})
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	require.Len(t, res[0], 1)
+
+	mBytes, err := res[0][0].AsBytes()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"order":{"id":"a"},"payment":{"id":"a","amount":10}}`, string(mBytes))
+
+	v, _ := res[0][0].MetaGet("from")
+	assert.Equal(t, "orders", v)
+	v, _ = res[0][0].MetaGet("currency")
+	assert.Equal(t, "gbp", v)
+}
+
+func TestJoinProcessorUnmatched(t *testing.T) {
+	tests := []struct {
+		name      string
+		unmatched string
+		results   []string
+		errored   bool
+	}{
+		{name: "drop", unmatched: "drop"},
+		{name: "emit", unmatched: "emit", results: []string{`{"id":"a","side":"left"}`}},
+		{name: "error", unmatched: "error", results: []string{`{"id":"a","side":"left"}`}, errored: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p := testJoinProcessor(t, `
+left_check: 'this.side == "left"'
+key: ${! json("id") }
+ttl: 10s
+unmatched: `+test.unmatched+`
+`)
+
+			now := time.Unix(1000, 0)
+			p.nowFn = func() time.Time { return now }
+
+			tCtx := context.Background()
+
+			res, err := p.ProcessBatch(tCtx, joinTestBatch(`{"id":"a","side":"left"}`))
+			require.NoError(t, err)
+			assert.Empty(t, res)
+
+			now = now.Add(11 * time.Second)
+
+			res, err = p.ProcessBatch(tCtx, joinTestBatch(`{"id":"a","side":"right"}`))
+			require.NoError(t, err)
+			assert.Equal(t, test.results, joinResults(t, res))
+
+			if len(test.results) > 0 {
+				m := res[0][0]
+				v, _ := m.MetaGet("join_side")
+				assert.Equal(t, "left", v)
+				if test.errored {
+					assert.Error(t, m.GetError())
+				} else {
+					assert.NoError(t, m.GetError())
+				}
+			}
+
+			// The right message is now held rather than matched.
+			assert.Equal(t, 1, p.order.Len())
+		})
+	}
+}
+
+func TestJoinProcessorMaxPending(t *testing.T) {
+	p := testJoinProcessor(t, `
+left_check: 'this.side == "left"'
+key: ${! json("id") }
+unmatched: emit
+max_pending: 2
+`)
+
+	res, err := p.ProcessBatch(context.Background(), joinTestBatch(
+		`{"id":"a","side":"left"}`,
+		`{"id":"b","side":"left"}`,
+		`{"id":"c","side":"left"}`,
+	))
+	require.NoError(t, err)
+	assert.Equal(t, []string{`{"id":"a","side":"left"}`}, joinResults(t, res))
+	assert.Equal(t, 2, p.order.Len())
+}
+
+func TestJoinProcessorBadCheck(t *testing.T) {
+	p := testJoinProcessor(t, `
+left_check: 'this.side'
+key: ${! json("id") }
+`)
+
+	res, err := p.ProcessBatch(context.Background(), joinTestBatch(`{"id":"a","side":"nope"}`))
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	require.Len(t, res[0], 1)
+	assert.Error(t, res[0][0].GetError())
+}
+
+func TestJoinProcessorSnapshotRestore(t *testing.T) {
+	res := service.MockResources(service.MockResourcesOptAddCache("snapshots"))
+
+	confStr := `
+left_check: '@side == "left"'
+key: ${! json("id") }
+ttl: 1h
+unmatched: emit
+snapshot:
+  cache: snapshots
+  interval: 0s
+`
+
+	tCtx := context.Background()
+
+	now := time.Unix(1000, 0)
+	nowFn := func() time.Time { return now }
+
+	p := testJoinProcessorWithResources(t, confStr, res)
+	p.nowFn = nowFn
+
+	left := service.NewMessage([]byte(`{"id":"a","from":"left"}`))
+	left.MetaSetMut("side", "left")
+	left.MetaSetMut("extra", "foo")
+
+	stale := service.NewMessage([]byte(`{"id":"b","from":"left"}`))
+	stale.MetaSetMut("side", "left")
+
+	out, err := p.ProcessBatch(tCtx, service.MessageBatch{stale})
+	require.NoError(t, err)
+	assert.Empty(t, out)
+
+	now = now.Add(30 * time.Minute)
+
+	out, err = p.ProcessBatch(tCtx, service.MessageBatch{left})
+	require.NoError(t, err)
+	assert.Empty(t, out)
+
+	// A second processor sharing the cache resumes from the snapshot.
+	now = now.Add(45 * time.Minute)
+
+	q := testJoinProcessorWithResources(t, confStr, res)
+	q.nowFn = nowFn
+
+	right := service.NewMessage([]byte(`{"id":"a","to":"right"}`))
+	right.MetaSetMut("side", "right")
+
+	out, err = q.ProcessBatch(tCtx, service.MessageBatch{right})
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		`{"id":"b","from":"left"}`,
+		`{"from":"left","id":"a","to":"right"}`,
+	}, joinResults(t, out))
+
+	v, _ := out[0][1].MetaGet("extra")
+	assert.Equal(t, "foo", v)
+	assert.Equal(t, 0, q.order.Len())
+}
+
+func TestJoinProcessorSnapshotInterval(t *testing.T) {
+	res := service.MockResources(service.MockResourcesOptAddCache("snapshots"))
+
+	p := testJoinProcessorWithResources(t, `
+left_check: '@side == "left"'
+key: ${! json("id") }
+snapshot:
+  cache: snapshots
+  key: foo
+  interval: 10s
+`, res)
+
+	now := time.Unix(1000, 0)
+	p.nowFn = func() time.Time { return now }
+
+	tCtx := context.Background()
+
+	snapshotLen := func() int {
+		var snapBytes []byte
+		require.NoError(t, res.AccessCache(tCtx, "snapshots", func(c service.Cache) {
+			var err error
+			snapBytes, err = c.Get(tCtx, "foo")
+			require.NoError(t, err)
+		}))
+		var snap joinSnapshot
+		require.NoError(t, json.Unmarshal(snapBytes, &snap))
+		return len(snap.Entries)
+	}
+
+	msg := func(id string) service.MessageBatch {
+		m := service.NewMessage([]byte(`{"id":"` + id + `"}`))
+		m.MetaSetMut("side", "left")
+		return service.MessageBatch{m}
+	}
+
+	_, err := p.ProcessBatch(tCtx, msg("a"))
+	require.NoError(t, err)
+	assert.Equal(t, 1, snapshotLen())
+
+	now = now.Add(5 * time.Second)
+	_, err = p.ProcessBatch(tCtx, msg("b"))
+	require.NoError(t, err)
+	assert.Equal(t, 1, snapshotLen())
+
+	now = now.Add(5 * time.Second)
+	_, err = p.ProcessBatch(tCtx, msg("c"))
+	require.NoError(t, err)
+	assert.Equal(t, 3, snapshotLen())
+}
+
+func TestJoinProcessorSnapshotMissingCache(t *testing.T) {
+	conf, err := joinProcessorSpec().ParseYAML(`
+left_check: 'true'
+key: foo
+snapshot:
+  cache: nope
+`, nil)
+	require.NoError(t, err)
+
+	_, err = newJoinProcessorFromParsed(conf, service.MockResources())
+	require.Error(t, err)
+}
@@ -0,0 +1,163 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	rlfFieldResource   = "resource"
+	rlfFieldStatusCode = "status_code"
+	rlfFieldRetryAfter = "retry_after"
+)
+
+func rateLimitFeedbackSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Version("4.45.0").
+		Summary("Reports the status codes of responses to an `adaptive` rate limit, allowing it to adjust the rate of requests.").
+		Description(`
+This processor should be placed after the component that makes the requests limited by the rate limit, such as an `+"`http`"+` processor, which sets the metadata field `+"`http_status_code`"+` of each message to the status code of its response, including for requests that failed. Messages are not modified, and messages without a status code are ignored.`).
+		Fields(
+			service.NewStringField(rlfFieldResource).
+				Description("The label of the `adaptive` rate limit resource to report to."),
+			service.NewInterpolatedStringField(rlfFieldStatusCode).
+				Description("The status code of the response of each message.").
+				Default("${! @http_status_code }"),
+			service.NewInterpolatedStringField(rlfFieldRetryAfter).
+				Description("An optional duration to pause requests for when a response was throttled, either as a number of seconds or an HTTP date as used by the `Retry-After` header.").
+				Optional().
+				Example("${! @retry-after }"),
+		).
+		Example("Respect Retry-After", "Report status codes to an adaptive rate limit and pause requests for as long as the API asks.", `
+pipeline:
+  processors:
+    - http:
+        url: https://api.example.com/enrich
+        verb: POST
+        rate_limit: api_quota
+        extract_headers:
+          include_patterns: [ '^retry-after$' ]
+    - rate_limit_feedback:
+        resource: api_quota
+        retry_after: ${! @retry-after }
+`)
+}
+
+func init() {
+	err := service.RegisterBatchProcessor("rate_limit_feedback", rateLimitFeedbackSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchProcessor, error) {
+			return newRateLimitFeedbackFromConfig(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type rateLimitFeedback struct {
+	resource   string
+	statusCode *service.InterpolatedString
+	retryAfter *service.InterpolatedString
+
+	log *service.Logger
+	mgr *service.Resources
+}
+
+func newRateLimitFeedbackFromConfig(conf *service.ParsedConfig, mgr *service.Resources) (*rateLimitFeedback, error) {
+	p := &rateLimitFeedback{log: mgr.Logger(), mgr: mgr}
+
+	var err error
+	if p.resource, err = conf.FieldString(rlfFieldResource); err != nil {
+		return nil, err
+	}
+	if p.statusCode, err = conf.FieldInterpolatedString(rlfFieldStatusCode); err != nil {
+		return nil, err
+	}
+	if conf.Contains(rlfFieldRetryAfter) {
+		if p.retryAfter, err = conf.FieldInterpolatedString(rlfFieldRetryAfter); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// parseRetryAfter parses a duration as either a number of seconds or an HTTP
+// date, returning zero when it cannot be parsed.
+func parseRetryAfter(s string, now time.Time) time.Duration {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0
+	}
+	if secs, err := strconv.ParseFloat(s, 64); err == nil {
+		return time.Duration(secs * float64(time.Second))
+	}
+	if t, err := http.ParseTime(s); err == nil {
+		return t.Sub(now)
+	}
+	return 0
+}
+
+func (p *rateLimitFeedback) ProcessBatch(ctx context.Context, batch service.MessageBatch) ([]service.MessageBatch, error) {
+	v, exists := p.mgr.GetGeneric(adaptiveRateLimitKey{label: p.resource})
+	if !exists {
+		return nil, fmt.Errorf("adaptive rate limit resource %v was not found", p.resource)
+	}
+	rl := v.(*adaptiveRateLimit)
+
+	codeExec := batch.InterpolationExecutor(p.statusCode)
+	var retryExec *service.MessageBatchInterpolationExecutor
+	if p.retryAfter != nil {
+		retryExec = batch.InterpolationExecutor(p.retryAfter)
+	}
+
+	now := time.Now()
+	for i := range batch {
+		codeStr, err := codeExec.TryString(i)
+		if err != nil {
+			p.log.Debugf("Status code interpolation error: %v", err)
+			continue
+		}
+		if codeStr = strings.TrimSpace(codeStr); codeStr == "" || codeStr == "null" {
+			continue
+		}
+		code, err := strconv.Atoi(codeStr)
+		if err != nil {
+			p.log.Debugf("Ignoring status code %q: %v", codeStr, err)
+			continue
+		}
+
+		var retryAfter time.Duration
+		if retryExec != nil {
+			if s, err := retryExec.TryString(i); err == nil {
+				retryAfter = parseRetryAfter(s, now)
+			}
+		}
+		rl.Feedback(code, retryAfter)
+	}
+	return []service.MessageBatch{batch}, nil
+}
+
+func (p *rateLimitFeedback) Close(ctx context.Context) error {
+	return nil
+}
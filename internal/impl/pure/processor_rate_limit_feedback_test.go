@@ -0,0 +1,87 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func TestRateLimitFeedback(t *testing.T) {
+	mgr := service.MockResources()
+
+	rlConf, err := adaptiveRateLimitSpec().ParseYAML(`
+count: 10
+interval: 1h
+`, nil)
+	require.NoError(t, err)
+	rl, err := newAdaptiveRateLimitFromConfig(rlConf, mgr)
+	require.NoError(t, err)
+	mgr.SetGeneric(adaptiveRateLimitKey{label: "api"}, rl)
+
+	pConf, err := rateLimitFeedbackSpec().ParseYAML(`
+resource: api
+retry_after: ${! @retry_after | "" }
+`, nil)
+	require.NoError(t, err)
+	proc, err := newRateLimitFeedbackFromConfig(pConf, mgr)
+	require.NoError(t, err)
+
+	batch := service.MessageBatch{
+		service.NewMessage([]byte("a")),
+		service.NewMessage([]byte("b")),
+		service.NewMessage([]byte("c")),
+		service.NewMessage([]byte("d")),
+	}
+	batch[0].MetaSetMut("http_status_code", 200)
+	batch[1].MetaSetMut("http_status_code", "429")
+	batch[1].MetaSetMut("retry_after", "120")
+	batch[2].MetaSetMut("http_status_code", "nope")
+
+	res, err := proc.ProcessBatch(context.Background(), batch)
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	assert.Equal(t, batch, res[0])
+
+	rl.mut.Lock()
+	assert.Equal(t, 1, rl.successes)
+	assert.True(t, rl.throttled)
+	assert.Equal(t, 5.0, rl.count)
+	assert.WithinDuration(t, time.Now().Add(2*time.Minute), rl.pausedUntil, 10*time.Second)
+	rl.mut.Unlock()
+
+	pConf, err = rateLimitFeedbackSpec().ParseYAML(`resource: missing`, nil)
+	require.NoError(t, err)
+	proc, err = newRateLimitFeedbackFromConfig(pConf, mgr)
+	require.NoError(t, err)
+	_, err = proc.ProcessBatch(context.Background(), batch)
+	require.ErrorContains(t, err, "adaptive rate limit resource missing was not found")
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, 30*time.Second, parseRetryAfter("30", now))
+	assert.Equal(t, 1500*time.Millisecond, parseRetryAfter(" 1.5 ", now))
+	assert.Equal(t, time.Minute, parseRetryAfter(now.Add(time.Minute).Format(http.TimeFormat), now))
+	assert.Equal(t, time.Duration(0), parseRetryAfter("", now))
+	assert.Equal(t, time.Duration(0), parseRetryAfter("soon", now))
+}
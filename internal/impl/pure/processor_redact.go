@@ -0,0 +1,569 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/redpanda-data/benthos/v4/public/bloblang"
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	rpFieldDetectors       = "detectors"
+	rpFieldCustomDetectors = "custom_detectors"
+	rpFieldCustomName      = "name"
+	rpFieldCustomPattern   = "pattern"
+	rpFieldCustomCheck     = "check"
+	rpFieldAction          = "action"
+	rpFieldMask            = "mask"
+	rpFieldMaskCharacter   = "character"
+	rpFieldMaskKeepLast    = "keep_last"
+	rpFieldHash            = "hash"
+	rpFieldHashKey         = "key"
+	rpFieldTokenize        = "tokenize"
+	rpFieldTokenizeCache   = "cache"
+	rpFieldTokenizePrefix  = "prefix"
+	rpFieldTokenizeTTL     = "ttl"
+)
+
+const (
+	redactActionMask     = "mask"
+	redactActionHash     = "hash"
+	redactActionTokenize = "tokenize"
+)
+
+const (
+	redactDetectorEmail      = "email"
+	redactDetectorCreditCard = "credit_card"
+	redactDetectorSSN        = "ssn"
+	redactDetectorPhone      = "phone"
+	redactDetectorIP         = "ip"
+)
+
+var redactBuiltinDetectors = []string{
+	redactDetectorEmail,
+	redactDetectorCreditCard,
+	redactDetectorSSN,
+	redactDetectorPhone,
+	redactDetectorIP,
+}
+
+func redactProcessorSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Version("4.45.0").
+		Summary("Detects personally identifiable information within messages and masks, hashes or tokenizes it.").
+		Description(`
+Every string value within a structured document is scanned, including those within nested objects and arrays, and messages that are not structured are scanned as a single string. Object keys, numbers and other values are left unchanged. Each part of a string that is found by a detector is replaced according to the `+"`action`"+`, and where detections overlap the one that starts first, and then the longest, is replaced.
+
+=== Built-in Detectors
+
+- `+"`email`"+`: Email addresses.
+- `+"`credit_card`"+`: Payment card numbers of 13 to 19 digits, optionally separated by spaces or hyphens, that pass a Luhn check.
+- `+"`ssn`"+`: US social security numbers in the form `+"`123-45-6789`"+`, excluding numbers that are never issued.
+- `+"`phone`"+`: Phone numbers of ten digits with an optional international prefix, such as `+"`+1 (555) 123-4567`"+`.
+- `+"`ip`"+`: IPv4 and IPv6 addresses.
+
+=== Custom Detectors
+
+Custom detectors find either the parts of a string that match a `+"`pattern`"+`, or whole strings for which a Bloblang `+"`check`"+` returns `+"`true`"+`. When both are set the check is executed against each match of the pattern, and only matches for which it returns `+"`true`"+` are replaced.
+
+=== Tokenization
+
+The `+"`tokenize`"+` action replaces each detected value with a random token, and stores the value under the token within a cache resource, in order for it to be recovered by consumers with access to the cache. A reference to the token is also stored under a hash of the value, and therefore repeated occurrences of a value are replaced with the same token.
+
+== Metrics
+
+This processor emits a counter `+"`redact_detected`"+` with a label `+"`detector`"+`, which is incremented for each detected value that is replaced.`).
+		Fields(
+			service.NewStringListField(rpFieldDetectors).
+				Description("The built-in detectors to enable, any of `"+strings.Join(redactBuiltinDetectors, "`, `")+"`.").
+				Default(redactBuiltinDetectors),
+			service.NewObjectListField(rpFieldCustomDetectors,
+				service.NewStringField(rpFieldCustomName).
+					Description("A name for the detector, which is used as the `detector` label of metrics."),
+				service.NewStringField(rpFieldCustomPattern).
+					Description("A regular expression that finds values to replace.").
+					Example(`EMP-\d{6}`).
+					Optional(),
+				service.NewBloblangField(rpFieldCustomCheck).
+					Description("A Bloblang query that is executed against a string, either a match of the `pattern` or, when no pattern is set, a whole string value, which should return a boolean indicating whether it should be replaced.").
+					Example(`this.has_prefix("sk_live_")`).
+					Optional(),
+			).
+				Description("Detectors for values that are specific to an organisation, each of which requires a `pattern`, a `check`, or both.").
+				Default([]any{}),
+			service.NewStringAnnotatedEnumField(rpFieldAction, map[string]string{
+				redactActionMask:     "Replace each character of a detected value with the mask character.",
+				redactActionHash:     "Replace a detected value with the hex encoded SHA-256 hash of it, or an HMAC-SHA256 when a key is set.",
+				redactActionTokenize: "Replace a detected value with a random token, storing the value within a cache under the token.",
+			}).
+				Description("How detected values are replaced.").
+				Default(redactActionMask),
+			service.NewObjectField(rpFieldMask,
+				service.NewStringField(rpFieldMaskCharacter).
+					Description("The character used to replace each character of a detected value.").
+					Default("*"),
+				service.NewIntField(rpFieldMaskKeepLast).
+					Description("The number of trailing characters of a detected value to leave unmasked.").
+					Default(0),
+			).
+				Description("Options for the `mask` action.").
+				Advanced(),
+			service.NewObjectField(rpFieldHash,
+				service.NewStringField(rpFieldHashKey).
+					Description("An optional key used to produce an HMAC of detected values, which prevents values from being recovered by hashing guesses.").
+					Secret().
+					Default(""),
+			).
+				Description("Options for the `hash` action.").
+				Advanced(),
+			service.NewObjectField(rpFieldTokenize,
+				service.NewStringField(rpFieldTokenizeCache).
+					Description("A cache resource to store the values of tokens within.").
+					Default(""),
+				service.NewStringField(rpFieldTokenizePrefix).
+					Description("A prefix added to each token.").
+					Default("tok_"),
+				service.NewDurationField(rpFieldTokenizeTTL).
+					Description("An optional TTL for stored tokens, if supported by the cache.").
+					Optional(),
+			).
+				Description("Options for the `tokenize` action, which requires a `cache`.").
+				Advanced(),
+		).
+		Example("Mask Everything", "Mask all values found by the built-in detectors, leaving the last four digits of card numbers and other values visible.", `
+pipeline:
+  processors:
+    - redact:
+        mask:
+          keep_last: 4
+`).
+		Example("Tokenize Customer Data", "Replace emails, phone numbers and internal employee identifiers with tokens that can be reversed using a Redis cache.", `
+pipeline:
+  processors:
+    - redact:
+        detectors: [ email, phone ]
+        custom_detectors:
+          - name: employee_id
+            pattern: 'EMP-\d{6}'
+        action: tokenize
+        tokenize:
+          cache: tokens
+
+cache_resources:
+  - label: tokens
+    redis:
+      url: redis://localhost:6379
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor("redact", redactProcessorSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return newRedactProcessorFromParsed(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type redactMatch struct {
+	start, end int
+	detector   string
+}
+
+type redactDetector struct {
+	name    string
+	pattern *regexp.Regexp
+	check   *bloblang.Executor
+	valid   func(s string) bool
+}
+
+func (d *redactDetector) checkValue(s string) (bool, error) {
+	if d.valid != nil && !d.valid(s) {
+		return false, nil
+	}
+	if d.check == nil {
+		return true, nil
+	}
+	v, err := d.check.Query(s)
+	if err != nil {
+		return false, fmt.Errorf("detector %v check failed: %w", d.name, err)
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("detector %v check returned %T, expected boolean", d.name, v)
+	}
+	return b, nil
+}
+
+func (d *redactDetector) find(s string) ([]redactMatch, error) {
+	if d.pattern == nil {
+		ok, err := d.checkValue(s)
+		if err != nil || !ok || s == "" {
+			return nil, err
+		}
+		return []redactMatch{{start: 0, end: len(s), detector: d.name}}, nil
+	}
+
+	var matches []redactMatch
+	for _, loc := range d.pattern.FindAllStringIndex(s, -1) {
+		if loc[0] == loc[1] {
+			continue
+		}
+		ok, err := d.checkValue(s[loc[0]:loc[1]])
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			matches = append(matches, redactMatch{start: loc[0], end: loc[1], detector: d.name})
+		}
+	}
+	return matches, nil
+}
+
+func luhnValid(s string) bool {
+	var sum, n int
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if n%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return n >= 13 && n <= 19 && sum%10 == 0
+}
+
+func ssnValid(s string) bool {
+	area, group, serial := s[0:3], s[4:6], s[7:11]
+	return area != "000" && area != "666" && area[0] != '9' && group != "00" && serial != "0000"
+}
+
+func ipValid(s string) bool {
+	return net.ParseIP(s) != nil
+}
+
+func builtinRedactDetector(name string) (*redactDetector, error) {
+	switch name {
+	case redactDetectorEmail:
+		return &redactDetector{
+			name:    name,
+			pattern: regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9\-]+(?:\.[A-Za-z0-9\-]+)*\.[A-Za-z]{2,}`),
+		}, nil
+	case redactDetectorCreditCard:
+		return &redactDetector{
+			name:    name,
+			pattern: regexp.MustCompile(`\b\d(?:[ \-]?\d){12,18}\b`),
+			valid:   luhnValid,
+		}, nil
+	case redactDetectorSSN:
+		return &redactDetector{
+			name:    name,
+			pattern: regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`),
+			valid:   ssnValid,
+		}, nil
+	case redactDetectorPhone:
+		return &redactDetector{
+			name:    name,
+			pattern: regexp.MustCompile(`(?:\+\d{1,3}[ .\-]?)?(?:\(\d{3}\)|\b\d{3})[ .\-]?\d{3}[ .\-]?\d{4}\b`),
+		}, nil
+	case redactDetectorIP:
+		return &redactDetector{
+			name:    name,
+			pattern: regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b|[0-9A-Fa-f]*:[0-9A-Fa-f]*(?::[0-9A-Fa-f]*)+`),
+			valid:   ipValid,
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown detector: %v", name)
+}
+
+//------------------------------------------------------------------------------
+
+type redactProcessor struct {
+	detectors []*redactDetector
+	action    string
+
+	maskChar     string
+	maskKeepLast int
+
+	hashKey []byte
+
+	cache       string
+	tokenPrefix string
+	tokenTTL    *time.Duration
+	mgr         *service.Resources
+
+	mDetected *service.MetricCounter
+}
+
+func newRedactProcessorFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*redactProcessor, error) {
+	r := &redactProcessor{
+		mgr:       mgr,
+		mDetected: mgr.Metrics().NewCounter("redact_detected", "detector"),
+	}
+
+	builtins, err := conf.FieldStringList(rpFieldDetectors)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range builtins {
+		d, err := builtinRedactDetector(name)
+		if err != nil {
+			return nil, err
+		}
+		r.detectors = append(r.detectors, d)
+	}
+
+	customConfs, err := conf.FieldObjectList(rpFieldCustomDetectors)
+	if err != nil {
+		return nil, err
+	}
+	for i, cConf := range customConfs {
+		d := &redactDetector{}
+		if d.name, err = cConf.FieldString(rpFieldCustomName); err != nil {
+			return nil, err
+		}
+		if cConf.Contains(rpFieldCustomPattern) {
+			patternStr, err := cConf.FieldString(rpFieldCustomPattern)
+			if err != nil {
+				return nil, err
+			}
+			if d.pattern, err = regexp.Compile(patternStr); err != nil {
+				return nil, fmt.Errorf("custom detector %v: failed to compile pattern: %w", i, err)
+			}
+		}
+		if cConf.Contains(rpFieldCustomCheck) {
+			if d.check, err = cConf.FieldBloblang(rpFieldCustomCheck); err != nil {
+				return nil, err
+			}
+		}
+		if d.pattern == nil && d.check == nil {
+			return nil, fmt.Errorf("custom detector %v: a pattern or a check is required", i)
+		}
+		r.detectors = append(r.detectors, d)
+	}
+
+	if r.action, err = conf.FieldString(rpFieldAction); err != nil {
+		return nil, err
+	}
+	switch r.action {
+	case redactActionMask:
+		if r.maskChar, err = conf.FieldString(rpFieldMask, rpFieldMaskCharacter); err != nil {
+			return nil, err
+		}
+		if utf8.RuneCountInString(r.maskChar) != 1 {
+			return nil, fmt.Errorf("mask character must be a single character, got %q", r.maskChar)
+		}
+		if r.maskKeepLast, err = conf.FieldInt(rpFieldMask, rpFieldMaskKeepLast); err != nil {
+			return nil, err
+		}
+	case redactActionHash:
+		hashKey, err := conf.FieldString(rpFieldHash, rpFieldHashKey)
+		if err != nil {
+			return nil, err
+		}
+		r.hashKey = []byte(hashKey)
+	case redactActionTokenize:
+		if r.cache, err = conf.FieldString(rpFieldTokenize, rpFieldTokenizeCache); err != nil {
+			return nil, err
+		}
+		if r.cache == "" {
+			return nil, errors.New("the tokenize action requires a cache")
+		}
+		if !mgr.HasCache(r.cache) {
+			return nil, fmt.Errorf("cache resource '%v' was not found", r.cache)
+		}
+		if r.tokenPrefix, err = conf.FieldString(rpFieldTokenize, rpFieldTokenizePrefix); err != nil {
+			return nil, err
+		}
+		if conf.Contains(rpFieldTokenize, rpFieldTokenizeTTL) {
+			ttl, err := conf.FieldDuration(rpFieldTokenize, rpFieldTokenizeTTL)
+			if err != nil {
+				return nil, err
+			}
+			r.tokenTTL = &ttl
+		}
+	}
+	return r, nil
+}
+
+func (r *redactProcessor) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	if structured, err := msg.AsStructuredMut(); err == nil {
+		res, err := r.redactValue(ctx, structured)
+		if err != nil {
+			return nil, err
+		}
+		msg.SetStructuredMut(res)
+		return service.MessageBatch{msg}, nil
+	}
+
+	b, err := msg.AsBytes()
+	if err != nil {
+		return nil, err
+	}
+	res, err := r.redactString(ctx, string(b))
+	if err != nil {
+		return nil, err
+	}
+	msg.SetBytes([]byte(res))
+	return service.MessageBatch{msg}, nil
+}
+
+func (r *redactProcessor) redactValue(ctx context.Context, v any) (any, error) {
+	var err error
+	switch t := v.(type) {
+	case string:
+		return r.redactString(ctx, t)
+	case map[string]any:
+		for k, e := range t {
+			if t[k], err = r.redactValue(ctx, e); err != nil {
+				return nil, err
+			}
+		}
+	case []any:
+		for i, e := range t {
+			if t[i], err = r.redactValue(ctx, e); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return v, nil
+}
+
+func (r *redactProcessor) redactString(ctx context.Context, s string) (string, error) {
+	var matches []redactMatch
+	for _, d := range r.detectors {
+		m, err := d.find(s)
+		if err != nil {
+			return "", err
+		}
+		matches = append(matches, m...)
+	}
+	if len(matches) == 0 {
+		return s, nil
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].start != matches[j].start {
+			return matches[i].start < matches[j].start
+		}
+		return matches[i].end > matches[j].end
+	})
+
+	var sb strings.Builder
+	last := 0
+	for _, m := range matches {
+		if m.start < last {
+			continue
+		}
+		replacement, err := r.replace(ctx, s[m.start:m.end])
+		if err != nil {
+			return "", err
+		}
+		r.mDetected.Incr(1, m.detector)
+
+		sb.WriteString(s[last:m.start])
+		sb.WriteString(replacement)
+		last = m.end
+	}
+	sb.WriteString(s[last:])
+	return sb.String(), nil
+}
+
+func (r *redactProcessor) replace(ctx context.Context, value string) (string, error) {
+	switch r.action {
+	case redactActionHash:
+		return r.hash(value), nil
+	case redactActionTokenize:
+		return r.tokenize(ctx, value)
+	}
+
+	runes := []rune(value)
+	keep := min(r.maskKeepLast, len(runes))
+	return strings.Repeat(r.maskChar, len(runes)-keep) + string(runes[len(runes)-keep:]), nil
+}
+
+func (r *redactProcessor) hash(value string) string {
+	if len(r.hashKey) == 0 {
+		sum := sha256.Sum256([]byte(value))
+		return hex.EncodeToString(sum[:])
+	}
+	h := hmac.New(sha256.New, r.hashKey)
+	_, _ = h.Write([]byte(value))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (r *redactProcessor) tokenize(ctx context.Context, value string) (string, error) {
+	tokenBytes := make([]byte, 16)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return "", err
+	}
+	token := r.tokenPrefix + hex.EncodeToString(tokenBytes)
+
+	sum := sha256.Sum256([]byte(value))
+	valueKey := "redact_value_" + hex.EncodeToString(sum[:])
+
+	// The value is stored under the token before the token is referenced by
+	// the hash of the value, and a token that loses a race with a concurrent
+	// occurrence of the same value is removed again.
+	var cErr error
+	if err := r.mgr.AccessCache(ctx, r.cache, func(c service.Cache) {
+		if cErr = c.Set(ctx, token, []byte(value), r.tokenTTL); cErr != nil {
+			return
+		}
+		if cErr = c.Add(ctx, valueKey, []byte(token), r.tokenTTL); errors.Is(cErr, service.ErrKeyAlreadyExists) {
+			_ = c.Delete(ctx, token)
+
+			var existing []byte
+			if existing, cErr = c.Get(ctx, valueKey); cErr == nil {
+				token = string(existing)
+			}
+		}
+	}); err != nil {
+		return "", err
+	}
+	if cErr != nil {
+		return "", fmt.Errorf("failed to store token: %w", cErr)
+	}
+	return token, nil
+}
+
+func (r *redactProcessor) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,199 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func testRedactProcessor(t *testing.T, res *service.Resources, conf string) *redactProcessor {
+	t.Helper()
+
+	pConf, err := redactProcessorSpec().ParseYAML(conf, nil)
+	require.NoError(t, err)
+
+	r, err := newRedactProcessorFromParsed(pConf, res)
+	require.NoError(t, err)
+	return r
+}
+
+func redactDoc(t *testing.T, r *redactProcessor, doc string) string {
+	t.Helper()
+
+	batch, err := r.Process(context.Background(), service.NewMessage([]byte(doc)))
+	require.NoError(t, err)
+	require.Len(t, batch, 1)
+
+	b, err := batch[0].AsBytes()
+	require.NoError(t, err)
+	return string(b)
+}
+
+func TestRedactBuiltinDetectors(t *testing.T) {
+	r := testRedactProcessor(t, service.MockResources(), ``)
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{input: "contact foo.bar+baz@example.co.uk now", expected: "contact ************************* now"},
+		{input: "card 4111 1111 1111 1111 ok", expected: "card ******************* ok"},
+		{input: "card 4111-1111-1111-1112 fails luhn", expected: "card 4111-1111-1111-1112 fails luhn"},
+		{input: "ssn 123-45-6789", expected: "ssn ***********"},
+		{input: "ssn 000-45-6789 is never issued", expected: "ssn 000-45-6789 is never issued"},
+		{input: "call +1 (555) 123-4567 or 555.123.4567", expected: "call ***************** or ************"},
+		{input: "from 192.168.0.1 and 2001:db8::ff00:42:8329", expected: "from *********** and **********************"},
+		{input: "not an ip 999.1.1.1 at 12:30:45", expected: "not an ip 999.1.1.1 at 12:30:45"},
+		{input: "nothing to see here", expected: "nothing to see here"},
+	}
+	for _, test := range tests {
+		actual, err := r.redactString(context.Background(), test.input)
+		require.NoError(t, err)
+		assert.Equal(t, test.expected, actual, test.input)
+	}
+}
+
+func TestRedactNestedStructures(t *testing.T) {
+	r := testRedactProcessor(t, service.MockResources(), `
+detectors: [ email ]
+mask:
+  character: "#"
+  keep_last: 4
+`)
+
+	assert.JSONEq(t, `{
+  "user": {"email": "###########.com", "age": 30},
+  "contacts": ["###.com", {"a@b.com": "not an email"}],
+  "count": 5
+}`, redactDoc(t, r, `{
+  "user": {"email": "foo@example.com", "age": 30},
+  "contacts": ["a@b.com", {"a@b.com": "not an email"}],
+  "count": 5
+}`))
+
+	assert.Equal(t, "raw ###.com text", redactDoc(t, r, `raw a@b.com text`))
+}
+
+func TestRedactCustomDetectors(t *testing.T) {
+	r := testRedactProcessor(t, service.MockResources(), `
+detectors: []
+custom_detectors:
+  - name: employee_id
+    pattern: 'EMP-\d{6}'
+  - name: api_key
+    check: 'this.has_prefix("sk_live_")'
+  - name: big_order
+    pattern: 'ORD-\d+'
+    check: 'this.trim_prefix("ORD-").number() > 1000'
+`)
+
+	assert.JSONEq(t, `{
+  "by": "**********",
+  "key": "*****************",
+  "other": "sk_test_abc",
+  "orders": "ORD-10 and ********"
+}`, redactDoc(t, r, `{
+  "by": "EMP-123456",
+  "key": "sk_live_abcdefghi",
+  "other": "sk_test_abc",
+  "orders": "ORD-10 and ORD-5000"
+}`))
+}
+
+func TestRedactCustomDetectorErrors(t *testing.T) {
+	pConf, err := redactProcessorSpec().ParseYAML(`
+custom_detectors:
+  - name: nothing
+`, nil)
+	require.NoError(t, err)
+
+	_, err = newRedactProcessorFromParsed(pConf, service.MockResources())
+	require.ErrorContains(t, err, "a pattern or a check is required")
+
+	pConf, err = redactProcessorSpec().ParseYAML(`detectors: [ passport ]`, nil)
+	require.NoError(t, err)
+
+	_, err = newRedactProcessorFromParsed(pConf, service.MockResources())
+	require.ErrorContains(t, err, "unknown detector: passport")
+
+	r := testRedactProcessor(t, service.MockResources(), `
+detectors: []
+custom_detectors:
+  - name: bad
+    check: 'this.length()'
+`)
+	_, err = r.Process(context.Background(), service.NewMessage([]byte(`"foo"`)))
+	require.ErrorContains(t, err, "detector bad check returned int64, expected boolean")
+}
+
+func TestRedactHash(t *testing.T) {
+	r := testRedactProcessor(t, service.MockResources(), `
+detectors: [ email ]
+action: hash
+`)
+	assert.Equal(t, `"hash fb98d44ad7501a959f3f4f4a3f004fe2d9e581ea6207e218c4b02c08a4d75adf"`, redactDoc(t, r, `"hash a@b.com"`))
+
+	r = testRedactProcessor(t, service.MockResources(), `
+detectors: [ email ]
+action: hash
+hash:
+  key: secret
+`)
+	out := redactDoc(t, r, `"hash a@b.com"`)
+	assert.NotContains(t, out, "fb98d44ad750")
+	assert.Len(t, out, len(`"hash "`)+64)
+}
+
+func TestRedactTokenize(t *testing.T) {
+	res := service.MockResources(service.MockResourcesOptAddCache("tokens"))
+	r := testRedactProcessor(t, res, `
+detectors: [ email ]
+action: tokenize
+tokenize:
+  cache: tokens
+`)
+
+	first := redactDoc(t, r, `"a@b.com"`)
+	second := redactDoc(t, r, `"again a@b.com"`)
+	other := redactDoc(t, r, `"c@d.com"`)
+
+	token := strings.Trim(first, `"`)
+	assert.True(t, strings.HasPrefix(token, "tok_"), token)
+	assert.Equal(t, `"again `+token+`"`, second)
+	assert.NotEqual(t, first, other)
+
+	require.NoError(t, res.AccessCache(context.Background(), "tokens", func(c service.Cache) {
+		v, err := c.Get(context.Background(), token)
+		require.NoError(t, err)
+		assert.Equal(t, "a@b.com", string(v))
+	}))
+
+	pConf, err := redactProcessorSpec().ParseYAML(`
+action: tokenize
+tokenize:
+  cache: nope
+`, nil)
+	require.NoError(t, err)
+
+	_, err = newRedactProcessorFromParsed(pConf, service.MockResources())
+	require.ErrorContains(t, err, "cache resource 'nope' was not found")
+}
@@ -0,0 +1,214 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/redpanda-data/benthos/v4/public/bloblang"
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	slFieldMaxBytes        = "max_bytes"
+	slFieldIncludeMetadata = "include_metadata"
+	slFieldAction          = "action"
+	slFieldOutput          = "output"
+	slFieldPointerMapping  = "pointer_mapping"
+)
+
+const (
+	slActionError = "error"
+	slActionDrop  = "drop"
+	slActionRoute = "route"
+)
+
+func sizeLimitProcessorSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Version("4.45.0").
+		Summary("Enforces a maximum message size, failing, dropping or routing oversized messages to a dedicated output.").
+		Description(`
+This processor can be placed within the `+"`processors`"+` of an input in order to enforce a limit as messages are consumed, or between other processors of a pipeline in order to enforce a limit before messages reach an output that would otherwise reject them. A limit applied to every stream can be defined once as a processor resource and referenced with the `+"`resource`"+` processor.
+
+The size of a message is the length of its payload in bytes, and when `+"`include_metadata`"+` is enabled the lengths of the keys and string values of its metadata are also counted, which is closer to the size of a record for brokers that carry metadata as headers, such as Kafka.
+
+=== Routing
+
+With the `+"`route`"+` action oversized messages are written to the `+"`output`"+`, and the processor waits for the write to be acknowledged. If the write fails the message is flagged as failed and continues. Otherwise, when a `+"`pointer_mapping`"+` is set the message is replaced with the result of executing it against the original message, which allows a pointer to the routed message to continue downstream, and without one the message is removed.
+
+== Metrics
+
+This processor emits a counter `+"`size_limit_exceeded`"+` which is incremented for each message that exceeds the limit.`).
+		Fields(
+			service.NewIntField(slFieldMaxBytes).
+				Description("The maximum size of a message in bytes.").
+				Example(1048576),
+			service.NewBoolField(slFieldIncludeMetadata).
+				Description("Whether to count metadata towards the size of a message.").
+				Default(false),
+			service.NewStringAnnotatedEnumField(slFieldAction, map[string]string{
+				slActionError: "Flag oversized messages as failed, in order for them to be handled with xref:configuration:error_handling.adoc[error handling methods].",
+				slActionDrop:  "Remove oversized messages.",
+				slActionRoute: "Write oversized messages to the `output`.",
+			}).
+				Description("What to do with messages that exceed the limit.").
+				Default(slActionError),
+			service.NewOutputField(slFieldOutput).
+				Description("An output to write oversized messages to, which is required by the `route` action.").
+				Optional(),
+			service.NewBloblangField(slFieldPointerMapping).
+				Description("An optional mapping, executed against an oversized message after it has been routed, the result of which replaces the message.").
+				Example(`root.oversized = { "path": meta("size_limit_path"), "size": content().length() }`).
+				Optional(),
+		).
+		Example("Claim Check", "Write messages that exceed the Kafka limit to S3 and send a pointer to them to Kafka instead.", `
+pipeline:
+  processors:
+    - mapping: meta size_limit_path = "oversized/%v.json".format(uuid_v4())
+    - size_limit:
+        max_bytes: 1000000
+        include_metadata: true
+        action: route
+        output:
+          aws_s3:
+            bucket: oversized-messages
+            path: ${! meta("size_limit_path") }
+        pointer_mapping: |
+          root.oversized = {
+            "bucket": "oversized-messages",
+            "path": meta("size_limit_path"),
+            "size": content().length(),
+          }
+
+output:
+  kafka_franz:
+    seed_brokers: [ localhost:9092 ]
+    topic: events
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor("size_limit", sizeLimitProcessorSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return newSizeLimitProcessorFromParsed(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type sizeLimitProcessor struct {
+	maxBytes        int
+	includeMetadata bool
+	action          string
+	output          *service.OwnedOutput
+	pointerMapping  *bloblang.Executor
+
+	mExceeded *service.MetricCounter
+}
+
+func newSizeLimitProcessorFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*sizeLimitProcessor, error) {
+	s := &sizeLimitProcessor{
+		mExceeded: mgr.Metrics().NewCounter("size_limit_exceeded"),
+	}
+
+	var err error
+	if s.maxBytes, err = conf.FieldInt(slFieldMaxBytes); err != nil {
+		return nil, err
+	}
+	if s.maxBytes <= 0 {
+		return nil, errors.New("max_bytes must be greater than zero")
+	}
+	if s.includeMetadata, err = conf.FieldBool(slFieldIncludeMetadata); err != nil {
+		return nil, err
+	}
+	if s.action, err = conf.FieldString(slFieldAction); err != nil {
+		return nil, err
+	}
+	if s.action == slActionRoute {
+		if !conf.Contains(slFieldOutput) {
+			return nil, errors.New("an output is required by the route action")
+		}
+		if s.output, err = conf.FieldOutput(slFieldOutput); err != nil {
+			return nil, err
+		}
+		if conf.Contains(slFieldPointerMapping) {
+			if s.pointerMapping, err = conf.FieldBloblang(slFieldPointerMapping); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return s, nil
+}
+
+func (s *sizeLimitProcessor) messageSize(msg *service.Message) (int, error) {
+	b, err := msg.AsBytes()
+	if err != nil {
+		return 0, err
+	}
+	size := len(b)
+	if s.includeMetadata {
+		_ = msg.MetaWalk(func(k, v string) error {
+			size += len(k) + len(v)
+			return nil
+		})
+	}
+	return size, nil
+}
+
+func (s *sizeLimitProcessor) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	size, err := s.messageSize(msg)
+	if err != nil {
+		return nil, err
+	}
+	if size <= s.maxBytes {
+		return service.MessageBatch{msg}, nil
+	}
+	s.mExceeded.Incr(1)
+
+	switch s.action {
+	case slActionDrop:
+		return nil, nil
+	case slActionRoute:
+		if err := s.output.Write(ctx, msg.Copy()); err != nil {
+			return nil, fmt.Errorf("failed to route oversized message: %w", err)
+		}
+		if s.pointerMapping == nil {
+			return nil, nil
+		}
+		pointer, err := msg.BloblangQuery(s.pointerMapping)
+		if err != nil {
+			return nil, fmt.Errorf("pointer mapping failed: %w", err)
+		}
+		if pointer == nil {
+			return nil, nil
+		}
+		return service.MessageBatch{pointer}, nil
+	}
+	return nil, fmt.Errorf("message size of %v bytes exceeds the limit of %v bytes", size, s.maxBytes)
+}
+
+func (s *sizeLimitProcessor) Close(ctx context.Context) error {
+	if s.output != nil {
+		return s.output.Close(ctx)
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func testSizeLimitProcessor(t *testing.T, out *funcOutput, conf string) *sizeLimitProcessor {
+	t.Helper()
+
+	env := service.NewEnvironment()
+	require.NoError(t, env.RegisterBatchOutput("size_limit_test_output", service.NewConfigSpec(),
+		func(*service.ParsedConfig, *service.Resources) (service.BatchOutput, service.BatchPolicy, int, error) {
+			return out, service.BatchPolicy{}, 1, nil
+		}))
+
+	pConf, err := sizeLimitProcessorSpec().ParseYAML(conf, env)
+	require.NoError(t, err)
+
+	s, err := newSizeLimitProcessorFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, s.Close(context.Background()))
+	})
+	return s
+}
+
+func TestSizeLimitError(t *testing.T) {
+	s := testSizeLimitProcessor(t, nil, `max_bytes: 5`)
+
+	batch, err := s.Process(context.Background(), service.NewMessage([]byte("hello")))
+	require.NoError(t, err)
+	require.Len(t, batch, 1)
+
+	_, err = s.Process(context.Background(), service.NewMessage([]byte("hello world")))
+	require.EqualError(t, err, "message size of 11 bytes exceeds the limit of 5 bytes")
+}
+
+func TestSizeLimitMetadata(t *testing.T) {
+	s := testSizeLimitProcessor(t, nil, `
+max_bytes: 8
+include_metadata: true
+action: drop
+`)
+
+	msg := service.NewMessage([]byte("hello"))
+	batch, err := s.Process(context.Background(), msg)
+	require.NoError(t, err)
+	assert.Len(t, batch, 1)
+
+	msg.MetaSetMut("a", "bc")
+	batch, err = s.Process(context.Background(), msg)
+	require.NoError(t, err)
+	assert.Len(t, batch, 1)
+
+	msg.MetaSetMut("d", "e")
+	batch, err = s.Process(context.Background(), msg)
+	require.NoError(t, err)
+	assert.Empty(t, batch)
+}
+
+func TestSizeLimitRoute(t *testing.T) {
+	var mut sync.Mutex
+	var routed []string
+	out := &funcOutput{writeFn: func(_ context.Context, b service.MessageBatch) error {
+		mut.Lock()
+		defer mut.Unlock()
+		for _, m := range b {
+			mBytes, _ := m.AsBytes()
+			routed = append(routed, string(mBytes))
+		}
+		return nil
+	}}
+
+	s := testSizeLimitProcessor(t, out, `
+max_bytes: 5
+action: route
+output:
+  size_limit_test_output: {}
+pointer_mapping: 'root.size = content().length()'
+`)
+
+	batch, err := s.Process(context.Background(), service.NewMessage([]byte("hello world")))
+	require.NoError(t, err)
+	require.Len(t, batch, 1)
+
+	b, err := batch[0].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, `{"size":11}`, string(b))
+	assert.Equal(t, []string{"hello world"}, routed)
+}
+
+func TestSizeLimitRouteNoPointer(t *testing.T) {
+	out := &funcOutput{writeFn: func(context.Context, service.MessageBatch) error { return nil }}
+	s := testSizeLimitProcessor(t, out, `
+max_bytes: 5
+action: route
+output:
+  size_limit_test_output: {}
+`)
+
+	batch, err := s.Process(context.Background(), service.NewMessage([]byte("hello world")))
+	require.NoError(t, err)
+	assert.Empty(t, batch)
+	assert.Equal(t, int64(1), out.calls.Load())
+}
+
+func TestSizeLimitRouteFailed(t *testing.T) {
+	out := &funcOutput{writeFn: func(context.Context, service.MessageBatch) error { return errors.New("nope") }}
+	s := testSizeLimitProcessor(t, out, `
+max_bytes: 5
+action: route
+output:
+  size_limit_test_output: {}
+`)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Millisecond*100)
+	defer done()
+
+	_, err := s.Process(ctx, service.NewMessage([]byte("hello world")))
+	require.ErrorContains(t, err, "failed to route oversized message")
+}
+
+func TestSizeLimitRouteRequiresOutput(t *testing.T) {
+	pConf, err := sizeLimitProcessorSpec().ParseYAML(`
+max_bytes: 5
+action: route
+`, nil)
+	require.NoError(t, err)
+
+	_, err = newSizeLimitProcessorFromParsed(pConf, service.MockResources())
+	require.EqualError(t, err, "an output is required by the route action")
+}
@@ -0,0 +1,188 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	psFieldCache    = "cache"
+	psFieldKey      = "key"
+	psFieldInterval = "interval"
+)
+
+// snapshotField returns the config field of stateful processors that persist
+// their state to a cache resource.
+func snapshotField(name, description, defaultKey string) *service.ConfigField {
+	return service.NewObjectField(name,
+		service.NewStringField(psFieldCache).
+			Description("A cache resource to store snapshots within."),
+		service.NewStringField(psFieldKey).
+			Description("The key within the cache used to store snapshots, which must be unique to each processor sharing the cache.").
+			Default(defaultKey),
+		service.NewDurationField(psFieldInterval).
+			Description("The period between snapshots, a snapshot is written after every batch when set to `0s`.").
+			Default("10s"),
+	).
+		Description(description).
+		Optional().
+		Advanced()
+}
+
+// stateSnapshotter reads and writes the state of a processor to a cache
+// resource.
+type stateSnapshotter struct {
+	mgr      *service.Resources
+	log      *service.Logger
+	cache    string
+	key      string
+	interval time.Duration
+	mErr     *service.MetricCounter
+
+	lastWrite time.Time
+}
+
+// stateSnapshotterFromParsed returns a snapshotter from a field created with
+// snapshotField, or nil if the field is not set.
+func stateSnapshotterFromParsed(conf *service.ParsedConfig, name, metricPrefix string, mgr *service.Resources) (*stateSnapshotter, error) {
+	if !conf.Contains(name) {
+		return nil, nil
+	}
+	conf = conf.Namespace(name)
+
+	s := &stateSnapshotter{
+		mgr:  mgr,
+		log:  mgr.Logger(),
+		mErr: mgr.Metrics().NewCounter(metricPrefix + "_snapshot_error"),
+	}
+
+	var err error
+	if s.cache, err = conf.FieldString(psFieldCache); err != nil {
+		return nil, err
+	}
+	if !mgr.HasCache(s.cache) {
+		return nil, fmt.Errorf("cache resource '%v' was not found", s.cache)
+	}
+	if s.key, err = conf.FieldString(psFieldKey); err != nil {
+		return nil, err
+	}
+	if s.interval, err = conf.FieldDuration(psFieldInterval); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// read attempts to parse a snapshot into v, returning false if a snapshot does
+// not exist.
+func (s *stateSnapshotter) read(ctx context.Context, v any) (bool, error) {
+	var snapBytes []byte
+	var getErr error
+	if err := s.mgr.AccessCache(ctx, s.cache, func(c service.Cache) {
+		snapBytes, getErr = c.Get(ctx, s.key)
+	}); err != nil {
+		return false, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	if getErr != nil {
+		if errors.Is(getErr, service.ErrKeyNotFound) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to read snapshot: %w", getErr)
+	}
+
+	if err := json.Unmarshal(snapBytes, v); err != nil {
+		return false, fmt.Errorf("failed to parse snapshot: %w", err)
+	}
+	return true, nil
+}
+
+func (s *stateSnapshotter) write(ctx context.Context, v any) error {
+	snapBytes, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	var setErr error
+	if err := s.mgr.AccessCache(ctx, s.cache, func(c service.Cache) {
+		setErr = c.Set(ctx, s.key, snapBytes, nil)
+	}); err != nil {
+		return err
+	}
+	return setErr
+}
+
+// writeIfDue writes a snapshot of the state returned by fn when the interval
+// has passed since the last successful write. Errors are logged, as failing to
+// write a snapshot should not prevent processing.
+func (s *stateSnapshotter) writeIfDue(ctx context.Context, now time.Time, fn func() (any, error)) {
+	if now.Sub(s.lastWrite) < s.interval {
+		return
+	}
+
+	s.writeNow(ctx, now, fn)
+}
+
+// writeNow writes a snapshot of the state returned by fn regardless of the
+// interval.
+func (s *stateSnapshotter) writeNow(ctx context.Context, now time.Time, fn func() (any, error)) {
+	v, err := fn()
+	if err == nil {
+		err = s.write(ctx, v)
+	}
+	if err != nil {
+		s.mErr.Incr(1)
+		s.log.Errorf("Failed to write snapshot of processor state: %v", err)
+		return
+	}
+	s.lastWrite = now
+}
+
+// snapshotMessage is the serialised form of a message held within the state of
+// a processor.
+type snapshotMessage struct {
+	Content  []byte         `json:"content"`
+	Metadata map[string]any `json:"metadata,omitempty"`
+}
+
+func newSnapshotMessage(msg *service.Message) (snapshotMessage, error) {
+	content, err := msg.AsBytes()
+	if err != nil {
+		return snapshotMessage{}, err
+	}
+
+	var meta map[string]any
+	_ = msg.MetaWalkMut(func(k string, v any) error {
+		if meta == nil {
+			meta = map[string]any{}
+		}
+		meta[k] = v
+		return nil
+	})
+	return snapshotMessage{Content: content, Metadata: meta}, nil
+}
+
+func (s snapshotMessage) message() *service.Message {
+	msg := service.NewMessage(s.Content)
+	for k, v := range s.Metadata {
+		msg.MetaSetMut(k, v)
+	}
+	return msg
+}
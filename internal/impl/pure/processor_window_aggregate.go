@@ -0,0 +1,610 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/redpanda-data/benthos/v4/public/bloblang"
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	wapFieldType             = "type"
+	wapFieldTimestampMapping = "timestamp_mapping"
+	wapFieldKey              = "key"
+	wapFieldSize             = "size"
+	wapFieldSlide            = "slide"
+	wapFieldGap              = "gap"
+	wapFieldAllowedLateness  = "allowed_lateness"
+	wapFieldLate             = "late"
+	wapFieldAggregateMapping = "aggregate_mapping"
+	wapFieldSnapshot         = "snapshot"
+)
+
+const (
+	windowTypeTumbling = "tumbling"
+	windowTypeSliding  = "sliding"
+	windowTypeSession  = "session"
+
+	windowLateDrop  = "drop"
+	windowLateError = "error"
+)
+
+func windowAggregateProcessorSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Version("4.45.0").
+		Summary("Aggregates messages into tumbling, sliding or session windows of event time, emitting the result of a Bloblang mapping for each window once it closes.").
+		Description(`
+Each message is assigned a timestamp by the `+"`timestamp_mapping`"+` and allocated to one or more windows of messages that share the same `+"`key`"+`. Progress through time is measured by a watermark, which is the latest timestamp observed minus the `+"`allowed_lateness`"+`, and a window closes once the watermark passes its end. When a window closes the `+"`aggregate_mapping`"+` is executed on a document describing it and the result is emitted.
+
+Messages with a timestamp behind the watermark that can no longer be allocated to an open window are late, and are handled according to the `+"`late`"+` policy.
+
+Unlike the `+"xref:components:buffers/system_window.adoc[`system_window` buffer]"+`, windows are closed by the timestamps of the messages themselves, and therefore windows only close as new messages are processed. Closed windows are emitted along with the results of the batch that advanced the watermark.
+
+== Window types
+
+In `+"`tumbling`"+` mode windows of a fixed `+"`size`"+` follow each other without overlapping, aligned to the unix epoch.
+
+In `+"`sliding`"+` mode windows of a fixed `+"`size`"+` begin at every `+"`slide`"+` period, and a message is allocated to each of the windows that it falls within.
+
+In `+"`session`"+` mode a window is opened by a message and extended by each message of the same key with a timestamp within the `+"`gap`"+` of it, the window closes once the watermark passes the `+"`gap`"+` after the latest message within it. Sessions that grow to overlap are merged.
+
+== Aggregate mapping
+
+The `+"`aggregate_mapping`"+` is executed on a document of the following form:
+
+`+"```json"+`
+{
+  "key": "the window key",
+  "start": "2024-01-01T00:00:00Z",
+  "end": "2024-01-01T00:01:00Z",
+  "messages": [ "the contents of each message of the window" ]
+}
+`+"```"+`
+
+The result has the metadata fields `+"`window_key`"+`, `+"`window_start_timestamp`"+` and `+"`window_end_timestamp`"+`, containing RFC3339 timestamps, and `+"`window_count`"+`.
+
+== Delivery guarantees
+
+Messages allocated to windows are acknowledged at the time they are received, and therefore open windows are lost if the process stops, unless a `+"`snapshot`"+` is configured.
+
+When a `+"`snapshot`"+` is configured the open windows are written to a cache resource periodically and when the processor is closed, and are restored from it when the first batch is processed after a restart. Using a cache backed by object storage, such as `+"`aws_s3`"+` or `+"`gcp_cloud_storage`"+`, allows long windows to survive restarts and rescheduling. Setting the `+"`interval`"+` to `+"`0s`"+` writes a snapshot after every batch before it is acknowledged, at the cost of throughput, so that no acknowledged message is lost from an open window provided that writing it succeeds.`).
+		Fields(
+			service.NewStringAnnotatedEnumField(wapFieldType, map[string]string{
+				windowTypeTumbling: "Fixed size windows that do not overlap.",
+				windowTypeSliding:  "Fixed size windows that begin at every slide period and may overlap.",
+				windowTypeSession:  "Windows of messages separated by no more than a gap period.",
+			}).
+				Description("The type of window.").
+				Default(windowTypeTumbling),
+			service.NewBloblangField(wapFieldTimestampMapping).
+				Description("A xref:guides:bloblang/about.adoc[Bloblang mapping] that provides the event time of each message, which must be either a timestamp, a numerical unix time in seconds or a string in RFC3339 format.").
+				Default("root = now()").
+				Example("root = this.created_at").
+				Example(`root = meta("kafka_timestamp_unix").number()`),
+			service.NewInterpolatedStringField(wapFieldKey).
+				Description("An optional key by which windows are separated, messages of different keys are never aggregated together.").
+				Example(`${! json("user_id") }`).
+				Default(""),
+			service.NewDurationField(wapFieldSize).
+				Description("The size of each window, required for tumbling and sliding windows.").
+				Example("1m").
+				Optional(),
+			service.NewDurationField(wapFieldSlide).
+				Description("The period between the beginning of each sliding window, required for sliding windows and must not be greater than the `size`.").
+				Example("10s").
+				Optional(),
+			service.NewDurationField(wapFieldGap).
+				Description("The maximum period between messages of a session window, required for session windows.").
+				Example("30m").
+				Optional(),
+			service.NewDurationField(wapFieldAllowedLateness).
+				Description("The period of time behind the latest timestamp observed that messages are accepted into windows.").
+				Default("0s"),
+			service.NewStringAnnotatedEnumField(wapFieldLate, map[string]string{
+				windowLateDrop:  "Late messages are dropped.",
+				windowLateError: "Late messages are emitted and flagged as failed, which allows them to be handled using xref:configuration:error_handling.adoc[error handling patterns].",
+			}).
+				Description("The policy for messages that arrive after the windows that they belong to have closed.").
+				Default(windowLateDrop),
+			service.NewBloblangField(wapFieldAggregateMapping).
+				Description("A xref:guides:bloblang/about.adoc[Bloblang mapping] that creates the aggregate emitted for each window once it closes.").
+				Example(`root.user = this.key
+root.count = this.messages.length()
+root.total = this.messages.map_each(m -> m.amount).sum()`),
+			snapshotField(wapFieldSnapshot, "Optionally persist open windows to a cache resource so that they survive restarts.", "window_aggregate_state"),
+		).
+		Example("Sessions of User Activity", "Aggregate the clicks of each user into sessions that end after thirty minutes of inactivity, accepting events up to one minute out of order.", `
+pipeline:
+  processors:
+    - window_aggregate:
+        type: session
+        gap: 30m
+        allowed_lateness: 1m
+        timestamp_mapping: root = this.timestamp
+        key: ${! json("user_id") }
+        aggregate_mapping: |
+          root.user_id = this.key
+          root.started_at = this.start
+          root.clicks = this.messages.length()
+          root.pages = this.messages.map_each(m -> m.page).unique()
+`).
+		Example("Sliding Totals", "Emit the total amount of the last five minutes of orders every minute.", `
+pipeline:
+  processors:
+    - window_aggregate:
+        type: sliding
+        size: 5m
+        slide: 1m
+        timestamp_mapping: root = this.created_at
+        aggregate_mapping: |
+          root.window_end = this.end
+          root.total = this.messages.map_each(m -> m.amount).sum()
+`)
+}
+
+func init() {
+	err := service.RegisterBatchProcessor("window_aggregate", windowAggregateProcessorSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchProcessor, error) {
+			return newWindowAggregateProcessorFromParsed(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type aggWindow struct {
+	key   string
+	start time.Time
+	end   time.Time
+	msgs  []*service.Message
+}
+
+type windowAggregateProcessor struct {
+	windowType string
+	tsMapping  *bloblang.Executor
+	key        *service.InterpolatedString
+	size       time.Duration
+	slide      time.Duration
+	gap        time.Duration
+	lateness   time.Duration
+	late       string
+	aggMapping *bloblang.Executor
+	log        *service.Logger
+
+	mLate    *service.MetricCounter
+	mEmitted *service.MetricCounter
+	mOpen    *service.MetricGauge
+
+	snapshot *stateSnapshotter
+
+	mut      sync.Mutex
+	restored bool
+	// The latest event time observed, zero until the first message.
+	maxTimestamp time.Time
+	// Open windows of each key, ordered by their start.
+	windows map[string][]*aggWindow
+}
+
+func newWindowAggregateProcessorFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*windowAggregateProcessor, error) {
+	w := &windowAggregateProcessor{
+		log:      mgr.Logger(),
+		mLate:    mgr.Metrics().NewCounter("window_aggregate_late"),
+		mEmitted: mgr.Metrics().NewCounter("window_aggregate_emitted"),
+		mOpen:    mgr.Metrics().NewGauge("window_aggregate_open"),
+		windows:  map[string][]*aggWindow{},
+	}
+
+	var err error
+	if w.windowType, err = conf.FieldString(wapFieldType); err != nil {
+		return nil, err
+	}
+	if w.tsMapping, err = conf.FieldBloblang(wapFieldTimestampMapping); err != nil {
+		return nil, err
+	}
+	if w.key, err = conf.FieldInterpolatedString(wapFieldKey); err != nil {
+		return nil, err
+	}
+	if w.lateness, err = conf.FieldDuration(wapFieldAllowedLateness); err != nil {
+		return nil, err
+	}
+	if w.late, err = conf.FieldString(wapFieldLate); err != nil {
+		return nil, err
+	}
+	if w.aggMapping, err = conf.FieldBloblang(wapFieldAggregateMapping); err != nil {
+		return nil, err
+	}
+
+	durationField := func(name string, required bool) (time.Duration, error) {
+		if !conf.Contains(name) {
+			if required {
+				return 0, fmt.Errorf("field %v is required for %v windows", name, w.windowType)
+			}
+			return 0, nil
+		}
+		d, err := conf.FieldDuration(name)
+		if err != nil {
+			return 0, err
+		}
+		if d <= 0 {
+			return 0, fmt.Errorf("field %v must be greater than zero", name)
+		}
+		return d, nil
+	}
+
+	isSession := w.windowType == windowTypeSession
+	if w.size, err = durationField(wapFieldSize, !isSession); err != nil {
+		return nil, err
+	}
+	if w.slide, err = durationField(wapFieldSlide, w.windowType == windowTypeSliding); err != nil {
+		return nil, err
+	}
+	if w.gap, err = durationField(wapFieldGap, isSession); err != nil {
+		return nil, err
+	}
+	if w.windowType == windowTypeSliding && w.slide > w.size {
+		return nil, errors.New("field slide must not be greater than the size")
+	}
+
+	if w.snapshot, err = stateSnapshotterFromParsed(conf, wapFieldSnapshot, "window_aggregate", mgr); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *windowAggregateProcessor) watermarkLocked() time.Time {
+	return w.maxTimestamp.Add(-w.lateness)
+}
+
+func (w *windowAggregateProcessor) timestamp(msg *service.Message) (time.Time, error) {
+	res, err := msg.BloblangQuery(w.tsMapping)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("timestamp mapping failed: %w", err)
+	}
+	if res == nil {
+		return time.Time{}, errors.New("timestamp mapping deleted the message")
+	}
+	v, err := res.AsStructured()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("timestamp mapping failed: %w", err)
+	}
+	return bloblang.ValueAsTimestamp(v)
+}
+
+// windowStarts returns the start of each fixed size window that a timestamp
+// belongs to.
+func (w *windowAggregateProcessor) windowStarts(ts time.Time) []time.Time {
+	step := w.size
+	if w.windowType == windowTypeSliding {
+		step = w.slide
+	}
+
+	nanos := ts.UnixNano()
+	latest := nanos - (nanos % int64(step))
+	if nanos < 0 && nanos%int64(step) != 0 {
+		latest -= int64(step)
+	}
+
+	var starts []time.Time
+	for start := latest; start > nanos-int64(w.size); start -= int64(step) {
+		starts = append(starts, time.Unix(0, start))
+	}
+	return starts
+}
+
+// addLocked allocates a message to windows, returning false if it is late.
+func (w *windowAggregateProcessor) addLocked(key string, ts time.Time, msg *service.Message) bool {
+	if w.windowType == windowTypeSession {
+		return w.addSessionLocked(key, ts, msg)
+	}
+
+	watermark := w.watermarkLocked()
+
+	added := false
+	for _, start := range w.windowStarts(ts) {
+		end := start.Add(w.size)
+		if !end.After(watermark) {
+			continue
+		}
+		added = true
+
+		windows := w.windows[key]
+		i := sort.Search(len(windows), func(i int) bool {
+			return !windows[i].start.Before(start)
+		})
+		if i < len(windows) && windows[i].start.Equal(start) {
+			windows[i].msgs = append(windows[i].msgs, msg)
+			continue
+		}
+
+		windows = append(windows, nil)
+		copy(windows[i+1:], windows[i:])
+		windows[i] = &aggWindow{key: key, start: start, end: end, msgs: []*service.Message{msg}}
+		w.windows[key] = windows
+	}
+	return added
+}
+
+// addSessionLocked allocates a message to a session, merging all sessions that
+// it overlaps, and returns false if it does not overlap any session and would
+// begin a session that has already closed.
+func (w *windowAggregateProcessor) addSessionLocked(key string, ts time.Time, msg *service.Message) bool {
+	session := &aggWindow{key: key, start: ts, end: ts.Add(w.gap)}
+
+	var remaining, overlapping []*aggWindow
+	for _, s := range w.windows[key] {
+		if s.start.After(session.end) || session.start.After(s.end) {
+			remaining = append(remaining, s)
+		} else {
+			overlapping = append(overlapping, s)
+		}
+	}
+	if len(overlapping) == 0 && !session.end.After(w.watermarkLocked()) {
+		return false
+	}
+
+	for _, s := range overlapping {
+		if s.start.Before(session.start) {
+			session.start = s.start
+		}
+		if s.end.After(session.end) {
+			session.end = s.end
+		}
+		session.msgs = append(session.msgs, s.msgs...)
+	}
+	session.msgs = append(session.msgs, msg)
+
+	remaining = append(remaining, session)
+	sort.Slice(remaining, func(i, j int) bool {
+		return remaining[i].start.Before(remaining[j].start)
+	})
+	w.windows[key] = remaining
+	return true
+}
+
+func (w *windowAggregateProcessor) aggregate(win *aggWindow) *service.Message {
+	contents := make([]any, 0, len(win.msgs))
+	for _, m := range win.msgs {
+		v, err := m.AsStructured()
+		if err != nil {
+			mBytes, _ := m.AsBytes()
+			v = string(mBytes)
+		}
+		contents = append(contents, v)
+	}
+
+	doc := service.NewMessage(nil)
+	doc.SetStructuredMut(map[string]any{
+		"key":      win.key,
+		"start":    win.start.UTC().Format(time.RFC3339Nano),
+		"end":      win.end.UTC().Format(time.RFC3339Nano),
+		"messages": contents,
+	})
+	doc.MetaSetMut("window_key", win.key)
+	doc.MetaSetMut("window_start_timestamp", win.start.UTC().Format(time.RFC3339Nano))
+	doc.MetaSetMut("window_end_timestamp", win.end.UTC().Format(time.RFC3339Nano))
+	doc.MetaSetMut("window_count", len(win.msgs))
+
+	res, err := doc.BloblangQuery(w.aggMapping)
+	if err != nil {
+		doc.SetError(fmt.Errorf("aggregate mapping failed: %w", err))
+		return doc
+	}
+	return res
+}
+
+// closeLocked removes all windows that end at or before the watermark and
+// returns their aggregates, ordered by their end.
+func (w *windowAggregateProcessor) closeLocked(out service.MessageBatch) service.MessageBatch {
+	watermark := w.watermarkLocked()
+
+	var closed []*aggWindow
+	for key, windows := range w.windows {
+		var open []*aggWindow
+		for _, win := range windows {
+			if win.end.After(watermark) {
+				open = append(open, win)
+			} else {
+				closed = append(closed, win)
+			}
+		}
+		if len(open) == 0 {
+			delete(w.windows, key)
+		} else {
+			w.windows[key] = open
+		}
+	}
+
+	sort.Slice(closed, func(i, j int) bool {
+		if !closed[i].end.Equal(closed[j].end) {
+			return closed[i].end.Before(closed[j].end)
+		}
+		if closed[i].key != closed[j].key {
+			return closed[i].key < closed[j].key
+		}
+		return closed[i].start.Before(closed[j].start)
+	})
+
+	for _, win := range closed {
+		if res := w.aggregate(win); res != nil {
+			out = append(out, res)
+		}
+	}
+	w.mEmitted.Incr(int64(len(closed)))
+	return out
+}
+
+func (w *windowAggregateProcessor) ProcessBatch(ctx context.Context, batch service.MessageBatch) ([]service.MessageBatch, error) {
+	w.mut.Lock()
+	defer w.mut.Unlock()
+
+	if !w.restored {
+		if err := w.restoreLocked(ctx); err != nil {
+			return nil, err
+		}
+		w.restored = true
+	}
+
+	var out service.MessageBatch
+	for i, msg := range batch {
+		ts, err := w.timestamp(msg)
+		if err != nil {
+			msg.SetError(err)
+			out = append(out, msg)
+			continue
+		}
+
+		key, err := batch.TryInterpolatedString(i, w.key)
+		if err != nil {
+			msg.SetError(fmt.Errorf("key interpolation failed: %w", err))
+			out = append(out, msg)
+			continue
+		}
+
+		if ts.After(w.maxTimestamp) {
+			w.maxTimestamp = ts
+		}
+		if !w.addLocked(key, ts, msg) {
+			w.mLate.Incr(1)
+			if w.late == windowLateError {
+				msg.SetError(fmt.Errorf("message timestamp %v is behind the watermark %v", ts.Format(time.RFC3339Nano), w.watermarkLocked().Format(time.RFC3339Nano)))
+				out = append(out, msg)
+			}
+		}
+	}
+	out = w.closeLocked(out)
+
+	open := 0
+	for _, windows := range w.windows {
+		open += len(windows)
+	}
+	w.mOpen.Set(int64(open))
+
+	if w.snapshot != nil {
+		w.snapshot.writeIfDue(ctx, time.Now(), w.snapshotLocked)
+	}
+
+	if len(out) == 0 {
+		return nil, nil
+	}
+	return []service.MessageBatch{out}, nil
+}
+
+func (w *windowAggregateProcessor) Close(ctx context.Context) error {
+	w.mut.Lock()
+	defer w.mut.Unlock()
+
+	if w.snapshot != nil {
+		if w.restored {
+			w.snapshot.writeNow(ctx, time.Now(), w.snapshotLocked)
+		}
+	} else if n := len(w.windows); n > 0 {
+		w.log.Warnf("Dropping open windows of %v keys", n)
+	}
+	w.windows = map[string][]*aggWindow{}
+	return nil
+}
+
+const windowAggregateSnapshotVersion = 1
+
+type windowAggregateSnapshot struct {
+	Version      int                             `json:"version"`
+	MaxTimestamp int64                           `json:"max_timestamp"`
+	Windows      []windowAggregateSnapshotWindow `json:"windows"`
+}
+
+type windowAggregateSnapshotWindow struct {
+	Key      string            `json:"key"`
+	Start    int64             `json:"start"`
+	End      int64             `json:"end"`
+	Messages []snapshotMessage `json:"messages"`
+}
+
+func (w *windowAggregateProcessor) snapshotLocked() (any, error) {
+	snap := windowAggregateSnapshot{
+		Version: windowAggregateSnapshotVersion,
+		Windows: []windowAggregateSnapshotWindow{},
+	}
+	if !w.maxTimestamp.IsZero() {
+		snap.MaxTimestamp = w.maxTimestamp.UnixNano()
+	}
+
+	keys := make([]string, 0, len(w.windows))
+	for k := range w.windows {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		for _, win := range w.windows[k] {
+			sWin := windowAggregateSnapshotWindow{
+				Key:      win.key,
+				Start:    win.start.UnixNano(),
+				End:      win.end.UnixNano(),
+				Messages: make([]snapshotMessage, 0, len(win.msgs)),
+			}
+			for _, m := range win.msgs {
+				sMsg, err := newSnapshotMessage(m)
+				if err != nil {
+					return nil, err
+				}
+				sWin.Messages = append(sWin.Messages, sMsg)
+			}
+			snap.Windows = append(snap.Windows, sWin)
+		}
+	}
+	return snap, nil
+}
+
+func (w *windowAggregateProcessor) restoreLocked(ctx context.Context) error {
+	if w.snapshot == nil {
+		return nil
+	}
+
+	var snap windowAggregateSnapshot
+	if exists, err := w.snapshot.read(ctx, &snap); err != nil || !exists {
+		return err
+	}
+	if snap.Version != windowAggregateSnapshotVersion {
+		return fmt.Errorf("unsupported snapshot version: %v", snap.Version)
+	}
+
+	if snap.MaxTimestamp != 0 {
+		w.maxTimestamp = time.Unix(0, snap.MaxTimestamp)
+	}
+	for _, sWin := range snap.Windows {
+		win := &aggWindow{
+			key:   sWin.Key,
+			start: time.Unix(0, sWin.Start),
+			end:   time.Unix(0, sWin.End),
+			msgs:  make([]*service.Message, 0, len(sWin.Messages)),
+		}
+		for _, sMsg := range sWin.Messages {
+			win.msgs = append(win.msgs, sMsg.message())
+		}
+		w.windows[win.key] = append(w.windows[win.key], win)
+	}
+
+	w.log.Infof("Restored %v open windows from snapshot", len(snap.Windows))
+	return nil
+}
@@ -0,0 +1,241 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func testWindowAggregateProcessor(t *testing.T, yamlStr string, res *service.Resources) *windowAggregateProcessor {
+	t.Helper()
+
+	conf, err := windowAggregateProcessorSpec().ParseYAML(yamlStr, nil)
+	require.NoError(t, err)
+
+	p, err := newWindowAggregateProcessorFromParsed(conf, res)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = p.Close(context.Background())
+	})
+	return p
+}
+
+func windowAggregateResults(t *testing.T, p *windowAggregateProcessor, docs ...string) []string {
+	t.Helper()
+
+	res, err := p.ProcessBatch(context.Background(), joinTestBatch(docs...))
+	require.NoError(t, err)
+	return joinResults(t, res)
+}
+
+func TestWindowAggregateTumbling(t *testing.T) {
+	p := testWindowAggregateProcessor(t, `
+size: 10s
+timestamp_mapping: root = this.ts
+key: ${! json("k") }
+aggregate_mapping: |
+  root.key = this.key
+  root.start = this.start
+  root.sum = this.messages.map_each(m -> m.v).sum()
+`, service.MockResources())
+
+	assert.Empty(t, windowAggregateResults(t, p,
+		`{"ts":1,"k":"a","v":1}`,
+		`{"ts":5,"k":"a","v":2}`,
+		`{"ts":9,"k":"b","v":3}`,
+	))
+
+	assert.Equal(t, []string{
+		`{"key":"a","start":"1970-01-01T00:00:00Z","sum":3}`,
+		`{"key":"b","start":"1970-01-01T00:00:00Z","sum":3}`,
+	}, windowAggregateResults(t, p, `{"ts":12,"k":"a","v":4}`))
+
+	assert.Equal(t, []string{
+		`{"key":"a","start":"1970-01-01T00:00:10Z","sum":4}`,
+	}, windowAggregateResults(t, p, `{"ts":20,"k":"a","v":5}`))
+}
+
+func TestWindowAggregateMetadata(t *testing.T) {
+	p := testWindowAggregateProcessor(t, `
+size: 10s
+timestamp_mapping: root = this.ts
+aggregate_mapping: root = this.messages.length()
+`, service.MockResources())
+
+	res, err := p.ProcessBatch(context.Background(), joinTestBatch(`{"ts":1}`, `{"ts":2}`, `{"ts":10}`))
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	require.Len(t, res[0], 1)
+
+	m := res[0][0]
+	mBytes, err := m.AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, "2", string(mBytes))
+
+	v, _ := m.MetaGet("window_start_timestamp")
+	assert.Equal(t, "1970-01-01T00:00:00Z", v)
+	v, _ = m.MetaGet("window_end_timestamp")
+	assert.Equal(t, "1970-01-01T00:00:10Z", v)
+	v, _ = m.MetaGet("window_count")
+	assert.Equal(t, "2", v)
+}
+
+func TestWindowAggregateSliding(t *testing.T) {
+	p := testWindowAggregateProcessor(t, `
+type: sliding
+size: 10s
+slide: 5s
+timestamp_mapping: root = this.ts
+aggregate_mapping: |
+  root.start = this.start
+  root.values = this.messages.map_each(m -> m.v)
+`, service.MockResources())
+
+	assert.Equal(t, []string{
+		`{"start":"1969-12-31T23:59:55Z","values":[1,2]}`,
+	}, windowAggregateResults(t, p,
+		`{"ts":1,"v":1}`,
+		`{"ts":4,"v":2}`,
+		`{"ts":7,"v":3}`,
+	))
+
+	assert.Equal(t, []string{
+		`{"start":"1970-01-01T00:00:00Z","values":[1,2,3]}`,
+		`{"start":"1970-01-01T00:00:05Z","values":[3]}`,
+	}, windowAggregateResults(t, p, `{"ts":15,"v":4}`))
+}
+
+func TestWindowAggregateSession(t *testing.T) {
+	p := testWindowAggregateProcessor(t, `
+type: session
+gap: 10s
+allowed_lateness: 30s
+timestamp_mapping: root = this.ts
+key: ${! json("k") }
+aggregate_mapping: |
+  root.key = this.key
+  root.start = this.start
+  root.end = this.end
+  root.values = this.messages.map_each(m -> m.v)
+`, service.MockResources())
+
+	assert.Empty(t, windowAggregateResults(t, p,
+		`{"ts":0,"k":"a","v":1}`,
+		`{"ts":18,"k":"a","v":2}`,
+		`{"ts":5,"k":"b","v":3}`,
+	))
+
+	// Bridges the two sessions of key a, which are merged.
+	assert.Empty(t, windowAggregateResults(t, p, `{"ts":9,"k":"a","v":4}`))
+	require.Len(t, p.windows["a"], 1)
+
+	assert.Equal(t, []string{
+		`{"end":"1970-01-01T00:00:15Z","key":"b","start":"1970-01-01T00:00:05Z","values":[3]}`,
+	}, windowAggregateResults(t, p, `{"ts":50,"k":"c","v":5}`))
+
+	assert.Equal(t, []string{
+		`{"end":"1970-01-01T00:00:28Z","key":"a","start":"1970-01-01T00:00:00Z","values":[1,2,4]}`,
+	}, windowAggregateResults(t, p, `{"ts":60,"k":"c","v":6}`))
+}
+
+func TestWindowAggregateLate(t *testing.T) {
+	for _, test := range []struct {
+		late    string
+		results int
+	}{
+		{late: "drop"},
+		{late: "error", results: 1},
+	} {
+		t.Run(test.late, func(t *testing.T) {
+			p := testWindowAggregateProcessor(t, `
+size: 10s
+allowed_lateness: 5s
+late: `+test.late+`
+timestamp_mapping: root = this.ts
+aggregate_mapping: root = this.messages.length()
+`, service.MockResources())
+
+			assert.Empty(t, windowAggregateResults(t, p, `{"ts":1}`, `{"ts":12}`))
+
+			// Within the allowed lateness.
+			assert.Empty(t, windowAggregateResults(t, p, `{"ts":8}`))
+			assert.Equal(t, []string{"2"}, windowAggregateResults(t, p, `{"ts":16}`))
+
+			// Behind the watermark and its window has closed.
+			res, err := p.ProcessBatch(context.Background(), joinTestBatch(`{"ts":3}`))
+			require.NoError(t, err)
+			if test.results == 0 {
+				assert.Empty(t, res)
+				return
+			}
+			require.Len(t, res, 1)
+			require.Len(t, res[0], test.results)
+			assert.Error(t, res[0][0].GetError())
+		})
+	}
+}
+
+func TestWindowAggregateSnapshotRestore(t *testing.T) {
+	res := service.MockResources(service.MockResourcesOptAddCache("snapshots"))
+
+	confStr := `
+size: 10s
+timestamp_mapping: root = this.ts
+aggregate_mapping: root = this.messages.map_each(m -> m.v)
+snapshot:
+  cache: snapshots
+  interval: 0s
+`
+
+	p := testWindowAggregateProcessor(t, confStr, res)
+	assert.Equal(t, []string{`[1]`}, windowAggregateResults(t, p, `{"ts":1,"v":1}`, `{"ts":12,"v":2}`))
+	assert.Empty(t, windowAggregateResults(t, p, `{"ts":14,"v":3}`))
+
+	q := testWindowAggregateProcessor(t, confStr, res)
+
+	// The restored watermark means the first window remains closed.
+	assert.Empty(t, windowAggregateResults(t, q, `{"ts":2,"v":4}`))
+	assert.Equal(t, []string{`[2,3,5]`}, windowAggregateResults(t, q, `{"ts":15,"v":5}`, `{"ts":22,"v":6}`))
+}
+
+func TestWindowAggregateConfigErrors(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		config string
+		errStr string
+	}{
+		{name: "tumbling without size", config: `type: tumbling`, errStr: "field size is required"},
+		{name: "sliding without slide", config: "type: sliding\nsize: 10s", errStr: "field slide is required"},
+		{name: "sliding slide too large", config: "type: sliding\nsize: 10s\nslide: 20s", errStr: "must not be greater"},
+		{name: "session without gap", config: `type: session`, errStr: "field gap is required"},
+		{name: "zero size", config: "size: 0s", errStr: "must be greater than zero"},
+		{name: "missing cache", config: "size: 1s\nsnapshot:\n  cache: nope", errStr: "was not found"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			conf, err := windowAggregateProcessorSpec().ParseYAML(test.config+"\naggregate_mapping: root = this\n", nil)
+			require.NoError(t, err)
+
+			_, err = newWindowAggregateProcessorFromParsed(conf, service.MockResources())
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.errStr)
+		})
+	}
+}
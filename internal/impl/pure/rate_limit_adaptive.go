@@ -0,0 +1,264 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	arlFieldCount          = "count"
+	arlFieldInterval       = "interval"
+	arlFieldMinCount       = "min_count"
+	arlFieldMaxCount       = "max_count"
+	arlFieldIncrease       = "increase"
+	arlFieldDecreaseFactor = "decrease_factor"
+	arlFieldThrottleCodes  = "throttle_codes"
+)
+
+func adaptiveRateLimitSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Version("4.45.0").
+		Summary("A rate limit that adjusts the number of requests allowed per interval from the responses of the requests it limits, in order to track upstream quotas that fluctuate.").
+		Description(`
+The rate limit starts by allowing `+"`"+arlFieldCount+"`"+` requests per interval, and adjusts this count with an additive increase, multiplicative decrease (AIMD) algorithm from the status codes reported to it with a `+"xref:components:processors/rate_limit_feedback.adoc[`rate_limit_feedback` processor]"+`:
+
+- When a status code within `+"`"+arlFieldThrottleCodes+"`"+` is reported the count is multiplied by `+"`"+arlFieldDecreaseFactor+"`"+`, at most once per interval since responses to requests already in flight are likely to be throttled too.
+- When an interval ends in which requests succeeded and none were throttled the count is increased by `+"`"+arlFieldIncrease+"`"+`.
+
+The count is kept between `+"`"+arlFieldMinCount+"`"+` and `+"`"+arlFieldMaxCount+"`"+`. When a throttled response reports a duration to retry after, such as with a `+"`Retry-After`"+` header, all requests are paused for that duration.
+
+The state of the rate limit is held in memory, and therefore each instance of Redpanda Connect adapts independently.
+
+== Metrics
+
+The gauge `+"`adaptive_rate_limit_count`"+` reports the current count allowed per interval.`).
+		Fields(
+			service.NewIntField(arlFieldCount).
+				Description("The initial number of requests to allow per interval.").
+				Default(100).
+				LintRule(`root = if this <= 0 { [ "count must be larger than zero" ] }`),
+			service.NewDurationField(arlFieldInterval).
+				Description("The time window to limit requests by, which is also the period at which the count is increased.").
+				Default("1s"),
+			service.NewIntField(arlFieldMinCount).
+				Description("The minimum number of requests to allow per interval.").
+				Default(1),
+			service.NewIntField(arlFieldMaxCount).
+				Description("The maximum number of requests to allow per interval, where zero means ten times the initial count.").
+				Default(0),
+			service.NewFloatField(arlFieldIncrease).
+				Description("The number of requests to add to the count after each interval without throttling.").
+				Default(1.0),
+			service.NewFloatField(arlFieldDecreaseFactor).
+				Description("The factor to multiply the count by when a request is throttled.").
+				Default(0.5),
+			service.NewIntListField(arlFieldThrottleCodes).
+				Description("The status codes that indicate a request was throttled.").
+				Default([]any{429, 500, 502, 503, 504}).
+				Advanced(),
+		).
+		Example("HTTP Quota", "Call an API with a quota that varies, starting at ten requests per second and backing off whenever the API responds with 429 or a 5xx status code.", `
+rate_limit_resources:
+  - label: api_quota
+    adaptive:
+      count: 10
+      interval: 1s
+
+pipeline:
+  processors:
+    - http:
+        url: https://api.example.com/enrich
+        verb: POST
+        rate_limit: api_quota
+    - rate_limit_feedback:
+        resource: api_quota
+`)
+}
+
+func init() {
+	err := service.RegisterRateLimit("adaptive", adaptiveRateLimitSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.RateLimit, error) {
+			r, err := newAdaptiveRateLimitFromConfig(conf, mgr)
+			if err != nil {
+				return nil, err
+			}
+			mgr.SetGeneric(adaptiveRateLimitKey{label: mgr.Label()}, r)
+			return r, nil
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+// adaptiveRateLimitKey is the key of an adaptive rate limit within the generic
+// values of resources, allowing the feedback processor to find the rate limit
+// of a given label.
+type adaptiveRateLimitKey struct {
+	label string
+}
+
+type adaptiveRateLimit struct {
+	interval       time.Duration
+	minCount       float64
+	maxCount       float64
+	increase       float64
+	decreaseFactor float64
+	throttleCodes  map[int]struct{}
+
+	mCount *service.MetricGauge
+	nowFn  func() time.Time
+
+	mut          sync.Mutex
+	count        float64
+	remaining    int
+	windowStart  time.Time
+	successes    int
+	throttled    bool
+	lastDecrease time.Time
+	pausedUntil  time.Time
+}
+
+func newAdaptiveRateLimitFromConfig(conf *service.ParsedConfig, mgr *service.Resources) (*adaptiveRateLimit, error) {
+	r := &adaptiveRateLimit{
+		throttleCodes: map[int]struct{}{},
+		mCount:        mgr.Metrics().NewGauge("adaptive_rate_limit_count"),
+		nowFn:         time.Now,
+	}
+
+	count, err := conf.FieldInt(arlFieldCount)
+	if err != nil {
+		return nil, err
+	}
+	if count <= 0 {
+		return nil, errors.New("count must be larger than zero")
+	}
+	if r.interval, err = conf.FieldDuration(arlFieldInterval); err != nil {
+		return nil, err
+	}
+	if r.interval <= 0 {
+		return nil, errors.New("interval must be larger than zero")
+	}
+	minCount, err := conf.FieldInt(arlFieldMinCount)
+	if err != nil {
+		return nil, err
+	}
+	if minCount <= 0 {
+		return nil, errors.New("min_count must be larger than zero")
+	}
+	maxCount, err := conf.FieldInt(arlFieldMaxCount)
+	if err != nil {
+		return nil, err
+	}
+	if maxCount == 0 {
+		maxCount = count * 10
+	}
+	if minCount > count || count > maxCount {
+		return nil, errors.New("count must be between min_count and max_count")
+	}
+	if r.increase, err = conf.FieldFloat(arlFieldIncrease); err != nil {
+		return nil, err
+	}
+	if r.decreaseFactor, err = conf.FieldFloat(arlFieldDecreaseFactor); err != nil {
+		return nil, err
+	}
+	if r.decreaseFactor <= 0 || r.decreaseFactor >= 1 {
+		return nil, errors.New("decrease_factor must be between zero and one")
+	}
+	codes, err := conf.FieldIntList(arlFieldThrottleCodes)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range codes {
+		r.throttleCodes[c] = struct{}{}
+	}
+
+	r.count = float64(count)
+	r.minCount = float64(minCount)
+	r.maxCount = float64(maxCount)
+	r.mCount.Set(int64(count))
+	return r, nil
+}
+
+// endWindowLocked increases the count when the previous window had successful
+// requests without any throttling, and starts a new window.
+func (r *adaptiveRateLimit) endWindowLocked(now time.Time) {
+	if !r.windowStart.IsZero() && !r.throttled && r.successes > 0 {
+		r.count = math.Min(r.maxCount, r.count+r.increase)
+		r.mCount.Set(int64(r.count))
+	}
+	r.successes = 0
+	r.throttled = false
+	r.windowStart = now
+	r.remaining = int(r.count)
+}
+
+func (r *adaptiveRateLimit) Access(ctx context.Context) (time.Duration, error) {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	now := r.nowFn()
+	if now.Before(r.pausedUntil) {
+		return r.pausedUntil.Sub(now), nil
+	}
+	if r.windowStart.IsZero() || now.Sub(r.windowStart) >= r.interval {
+		r.endWindowLocked(now)
+	}
+	if r.remaining <= 0 {
+		return r.interval - now.Sub(r.windowStart), nil
+	}
+	r.remaining--
+	return 0, nil
+}
+
+// Feedback reports the status code of a response to a request limited by the
+// rate limit, along with an optional duration after which to retry when the
+// request was throttled.
+func (r *adaptiveRateLimit) Feedback(code int, retryAfter time.Duration) {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	if _, throttled := r.throttleCodes[code]; !throttled {
+		if code < 400 {
+			r.successes++
+		}
+		return
+	}
+
+	now := r.nowFn()
+	r.throttled = true
+	if r.lastDecrease.IsZero() || now.Sub(r.lastDecrease) >= r.interval {
+		r.count = math.Max(r.minCount, r.count*r.decreaseFactor)
+		r.lastDecrease = now
+		r.remaining = min(r.remaining, int(r.count))
+		r.mCount.Set(int64(r.count))
+	}
+	if retryAfter > 0 {
+		if until := now.Add(retryAfter); until.After(r.pausedUntil) {
+			r.pausedUntil = until
+		}
+	}
+}
+
+func (r *adaptiveRateLimit) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,155 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func accessN(t *testing.T, r *adaptiveRateLimit) (allowed int, wait time.Duration) {
+	t.Helper()
+	for {
+		d, err := r.Access(context.Background())
+		require.NoError(t, err)
+		if d > 0 {
+			return allowed, d
+		}
+		allowed++
+	}
+}
+
+func TestAdaptiveRateLimitAIMD(t *testing.T) {
+	pConf, err := adaptiveRateLimitSpec().ParseYAML(`
+count: 10
+interval: 1s
+min_count: 2
+max_count: 12
+increase: 1
+decrease_factor: 0.5
+`, nil)
+	require.NoError(t, err)
+
+	r, err := newAdaptiveRateLimitFromConfig(pConf, service.MockResources())
+	require.NoError(t, err)
+
+	now := time.Unix(1700000000, 0)
+	r.nowFn = func() time.Time { return now }
+
+	allowed, wait := accessN(t, r)
+	assert.Equal(t, 10, allowed)
+	assert.Equal(t, time.Second, wait)
+
+	// Successes within a window increase the count of the next window.
+	r.Feedback(200, 0)
+	now = now.Add(time.Second)
+	allowed, _ = accessN(t, r)
+	assert.Equal(t, 11, allowed)
+
+	r.Feedback(200, 0)
+	now = now.Add(time.Second)
+	allowed, _ = accessN(t, r)
+	assert.Equal(t, 12, allowed)
+
+	// The count never exceeds the maximum.
+	r.Feedback(200, 0)
+	now = now.Add(time.Second)
+	allowed, _ = accessN(t, r)
+	assert.Equal(t, 12, allowed)
+
+	// Throttling halves the count once per interval, and prevents an
+	// increase at the end of the window.
+	now = now.Add(time.Second)
+	_, _ = r.Access(context.Background())
+	r.Feedback(200, 0)
+	r.Feedback(429, 0)
+	r.Feedback(503, 0)
+	assert.Equal(t, 6.0, r.count)
+	allowed, _ = accessN(t, r)
+	assert.Equal(t, 6, allowed)
+
+	now = now.Add(time.Second)
+	allowed, _ = accessN(t, r)
+	assert.Equal(t, 6, allowed)
+
+	r.Feedback(500, 0)
+	now = now.Add(time.Second)
+	r.Feedback(500, 0)
+	now = now.Add(time.Second)
+	r.Feedback(500, 0)
+	assert.Equal(t, 2.0, r.count)
+
+	// Client errors that aren't throttling are neither successes nor
+	// failures.
+	now = now.Add(time.Second)
+	_, _ = r.Access(context.Background())
+	r.Feedback(404, 0)
+	now = now.Add(time.Second)
+	allowed, _ = accessN(t, r)
+	assert.Equal(t, 2, allowed)
+}
+
+func TestAdaptiveRateLimitRetryAfter(t *testing.T) {
+	pConf, err := adaptiveRateLimitSpec().ParseYAML(`
+count: 10
+interval: 1s
+`, nil)
+	require.NoError(t, err)
+
+	r, err := newAdaptiveRateLimitFromConfig(pConf, service.MockResources())
+	require.NoError(t, err)
+
+	now := time.Unix(1700000000, 0)
+	r.nowFn = func() time.Time { return now }
+
+	d, err := r.Access(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, time.Duration(0), d)
+
+	r.Feedback(429, 5*time.Second)
+	now = now.Add(2 * time.Second)
+
+	d, err = r.Access(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 3*time.Second, d)
+
+	now = now.Add(3 * time.Second)
+	d, err = r.Access(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, time.Duration(0), d)
+}
+
+func TestAdaptiveRateLimitConfigErrors(t *testing.T) {
+	for _, conf := range []string{
+		`count: 10
+min_count: 20`,
+		`count: 10
+max_count: 5`,
+		`decrease_factor: 1.5`,
+		`interval: 0s`,
+	} {
+		pConf, err := adaptiveRateLimitSpec().ParseYAML(conf, nil)
+		require.NoError(t, err)
+
+		_, err = newAdaptiveRateLimitFromConfig(pConf, service.MockResources())
+		assert.Error(t, err, conf)
+	}
+}
@@ -0,0 +1,241 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package python
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+//go:embed runner.py
+var runnerScript string
+
+const (
+	pyFieldScript   = "script"
+	pyFieldFile     = "file"
+	pyFieldFunction = "function"
+	pyFieldBinary   = "python_binary"
+	pyFieldWorkers  = "workers"
+)
+
+func pythonProcessorConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Mapping").
+		Version("4.45.0").
+		Summary("Executes a Python function for each message within a pool of Python subprocesses.").
+		Description(`
+The script is executed once by each subprocess and must define a function, named `+"`process`"+` by default, which is called for each message with an object of class `+"`Message`"+`. Messages have the attributes `+"`content`"+`, the payload as bytes, and `+"`metadata`"+`, a dictionary of metadata, along with the methods `+"`json()`"+`, which parses the payload, and `+"`set_json(value)`"+`, which replaces it.
+
+The function may mutate the message and return `+"`None`"+`, return a message, or return a list of messages, in which case an empty list removes the message. An exception raised by the function fails the message, which is left unchanged and can be handled with xref:configuration:error_handling.adoc[error handling]. Anything written to stdout or stderr by the script is logged.
+
+Any Python packages installed within the environment of the interpreter, such as pandas or pydantic, can be imported by the script.
+
+== Concurrency
+
+Batches are processed by the `+"`workers`"+` subprocesses, each of which processes one batch at a time. When all subprocesses are busy processing threads wait for one to become available, applying back pressure to the pipeline. Subprocesses are started as they're needed, and a subprocess that fails is restarted for the next batch.
+
+State defined within the global scope of the script therefore persists across messages but is not shared between subprocesses.
+
+== Protocol
+
+Messages are exchanged with subprocesses as length prefixed https://msgpack.org/[MessagePack^] documents over stdin and stdout. The `+"`msgpack`"+` Python package is used when installed, and otherwise a built-in implementation is used.`).
+		Fields(
+			service.NewStringField(pyFieldScript).
+				Description("An inline Python script to execute. One of `"+pyFieldScript+"` or `"+pyFieldFile+"` must be defined.").
+				Optional(),
+			service.NewStringField(pyFieldFile).
+				Description("A file containing a Python script to execute. One of `"+pyFieldScript+"` or `"+pyFieldFile+"` must be defined.").
+				Optional(),
+			service.NewStringField(pyFieldFunction).
+				Description("The name of the function defined by the script to call for each message.").
+				Default("process"),
+			service.NewStringField(pyFieldBinary).
+				Description("The Python interpreter to execute the script with.").
+				Default("python3").
+				Advanced(),
+			service.NewIntField(pyFieldWorkers).
+				Description("The maximum number of Python subprocesses to process batches with in parallel.").
+				Default(1),
+		).
+		LintRule(fmt.Sprintf(`
+let scriptLen = (this.%v | "").length()
+let fileLen = (this.%v | "").length()
+root = if $scriptLen == 0 && $fileLen == 0 {
+  "either the script or file field must be specified"
+} else if $scriptLen > 0 && $fileLen > 0 {
+  "cannot specify both the script and file fields"
+}`, pyFieldScript, pyFieldFile)).
+		Example("Structured Mutation", "Validate documents with pydantic and enrich them with a computed field.", `
+pipeline:
+  processors:
+    - python:
+        workers: 4
+        script: |
+          from pydantic import BaseModel
+
+          class Order(BaseModel):
+            id: str
+            quantity: int
+            unit_price: float
+
+          def process(msg):
+            order = Order.model_validate(msg.json())
+            doc = order.model_dump()
+            doc["total"] = order.quantity * order.unit_price
+            msg.set_json(doc)
+            msg.metadata["validated"] = "true"
+`).
+		Example("Filtering", "Remove messages by returning an empty list.", `
+pipeline:
+  processors:
+    - python:
+        script: |
+          def process(msg):
+            if msg.json().get("type") == "heartbeat":
+              return []
+`)
+}
+
+func init() {
+	err := service.RegisterBatchProcessor("python", pythonProcessorConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchProcessor, error) {
+			return newPythonProcessorFromConfig(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type pythonProcessor struct {
+	log  *service.Logger
+	init pythonInit
+
+	binary  string
+	workers chan *pythonWorker
+}
+
+func newPythonProcessorFromConfig(conf *service.ParsedConfig, mgr *service.Resources) (*pythonProcessor, error) {
+	script, _ := conf.FieldString(pyFieldScript)
+	file, _ := conf.FieldString(pyFieldFile)
+	if script == "" && file == "" {
+		return nil, fmt.Errorf("either a `%s` or `%s` must be specified", pyFieldScript, pyFieldFile)
+	}
+
+	filename := "<script>"
+	if file != "" {
+		scriptBytes, err := service.ReadFile(mgr.FS(), file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open target file: %s", err)
+		}
+		filename = file
+		script = string(scriptBytes)
+	}
+
+	function, err := conf.FieldString(pyFieldFunction)
+	if err != nil {
+		return nil, err
+	}
+	binary, err := conf.FieldString(pyFieldBinary)
+	if err != nil {
+		return nil, err
+	}
+	workers, err := conf.FieldInt(pyFieldWorkers)
+	if err != nil {
+		return nil, err
+	}
+	if workers < 1 {
+		return nil, errors.New("workers must be at least one")
+	}
+
+	return newPythonProcessor(mgr.Logger(), binary, pythonInit{
+		Code:     script,
+		Filename: filename,
+		Function: function,
+	}, workers)
+}
+
+func newPythonProcessor(log *service.Logger, binary string, init pythonInit, workers int) (*pythonProcessor, error) {
+	p := &pythonProcessor{
+		log:     log,
+		init:    init,
+		binary:  binary,
+		workers: make(chan *pythonWorker, workers),
+	}
+
+	// Ensure that the script can be executed by starting the first worker,
+	// the remaining slots are filled as they're needed.
+	w, err := startPythonWorker(log, binary, init)
+	if err != nil {
+		return nil, err
+	}
+	p.workers <- w
+	for i := 1; i < workers; i++ {
+		p.workers <- nil
+	}
+	return p, nil
+}
+
+func (p *pythonProcessor) ProcessBatch(ctx context.Context, batch service.MessageBatch) ([]service.MessageBatch, error) {
+	var w *pythonWorker
+	select {
+	case w = <-p.workers:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	if w == nil {
+		var err error
+		if w, err = startPythonWorker(p.log, p.binary, p.init); err != nil {
+			p.workers <- nil
+			return nil, err
+		}
+	}
+
+	res, err := w.process(ctx, batch)
+	if err != nil {
+		// The state of the subprocess is unknown after a failed exchange and
+		// so it is replaced.
+		w.close()
+		p.workers <- nil
+		return nil, err
+	}
+	p.workers <- w
+
+	if len(res) == 0 {
+		return nil, nil
+	}
+	return []service.MessageBatch{res}, nil
+}
+
+func (p *pythonProcessor) Close(ctx context.Context) error {
+	for i := 0; i < cap(p.workers); i++ {
+		select {
+		case w := <-p.workers:
+			if w != nil {
+				w.close()
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package python
+
+import (
+	"context"
+	"os/exec"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func testPythonProcessor(t *testing.T, conf string) *pythonProcessor {
+	t.Helper()
+
+	if _, err := exec.LookPath("python3"); err != nil {
+		t.Skip("skipping as python3 is not installed")
+	}
+
+	pConf, err := pythonProcessorConfig().ParseYAML(conf, nil)
+	require.NoError(t, err)
+
+	proc, err := newPythonProcessorFromConfig(pConf, service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, proc.Close(context.Background()))
+	})
+	return proc
+}
+
+func TestPythonProcessor(t *testing.T) {
+	proc := testPythonProcessor(t, `
+script: |
+  def process(msg):
+    doc = msg.json()
+    if doc.get("drop"):
+      return []
+    if doc.get("fail"):
+      raise ValueError("nope")
+    doc["count"] = doc["count"] + 1
+    doc["from"] = msg.metadata["from"]
+    msg.set_json(doc)
+    msg.metadata["seen"] = True
+    del msg.metadata["from"]
+    print("processed", doc["id"])
+`)
+
+	inBatch := service.MessageBatch{
+		service.NewMessage([]byte(`{"id":"a","count":1}`)),
+		service.NewMessage([]byte(`{"id":"b","drop":true}`)),
+		service.NewMessage([]byte(`{"id":"c","fail":true}`)),
+	}
+	for _, m := range inBatch {
+		m.MetaSetMut("from", "test")
+	}
+
+	resBatches, err := proc.ProcessBatch(context.Background(), inBatch)
+	require.NoError(t, err)
+	require.Len(t, resBatches, 1)
+	require.Len(t, resBatches[0], 2)
+
+	a := resBatches[0][0]
+	require.NoError(t, a.GetError())
+	aBytes, err := a.AsBytes()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"id":"a","count":2,"from":"test"}`, string(aBytes))
+
+	seen, ok := a.MetaGetMut("seen")
+	require.True(t, ok)
+	assert.Equal(t, true, seen)
+	_, ok = a.MetaGet("from")
+	assert.False(t, ok)
+
+	c := resBatches[0][1]
+	require.EqualError(t, c.GetError(), "ValueError: nope")
+	cBytes, err := c.AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, `{"id":"c","fail":true}`, string(cBytes))
+	from, _ := c.MetaGet("from")
+	assert.Equal(t, "test", from)
+}
+
+func TestPythonProcessorFanOut(t *testing.T) {
+	proc := testPythonProcessor(t, `
+function: split
+script: |
+  def split(msg):
+    return [Message(line, msg.metadata) for line in msg.content.split(b"\n")]
+`)
+
+	resBatches, err := proc.ProcessBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte("foo\nbar\nbaz")),
+	})
+	require.NoError(t, err)
+	require.Len(t, resBatches, 1)
+	require.Len(t, resBatches[0], 3)
+
+	for i, exp := range []string{"foo", "bar", "baz"} {
+		b, err := resBatches[0][i].AsBytes()
+		require.NoError(t, err)
+		assert.Equal(t, exp, string(b))
+	}
+}
+
+func TestPythonProcessorParallel(t *testing.T) {
+	proc := testPythonProcessor(t, `
+workers: 3
+script: |
+  def process(msg):
+    msg.content = msg.content.upper()
+`)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				resBatches, err := proc.ProcessBatch(context.Background(), service.MessageBatch{
+					service.NewMessage([]byte("hello world")),
+				})
+				require.NoError(t, err)
+				require.Len(t, resBatches, 1)
+				require.Len(t, resBatches[0], 1)
+
+				b, err := resBatches[0][0].AsBytes()
+				require.NoError(t, err)
+				assert.Equal(t, "HELLO WORLD", string(b))
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestPythonProcessorRestartsWorker(t *testing.T) {
+	proc := testPythonProcessor(t, `
+script: |
+  import os, time
+
+  def process(msg):
+    if msg.content == b"exit":
+      os._exit(1)
+    if msg.content == b"slow":
+      time.sleep(10)
+`)
+
+	_, err := proc.ProcessBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte("exit")),
+	})
+	require.EqualError(t, err, "python subprocess exited unexpectedly")
+
+	ctx, done := context.WithTimeout(context.Background(), time.Millisecond*100)
+	defer done()
+	_, err = proc.ProcessBatch(ctx, service.MessageBatch{
+		service.NewMessage([]byte("slow")),
+	})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	resBatches, err := proc.ProcessBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte("hello")),
+	})
+	require.NoError(t, err)
+	require.Len(t, resBatches, 1)
+	require.Len(t, resBatches[0], 1)
+}
+
+func TestPythonProcessorScriptErrors(t *testing.T) {
+	if _, err := exec.LookPath("python3"); err != nil {
+		t.Skip("skipping as python3 is not installed")
+	}
+
+	for _, test := range []struct {
+		script string
+		errStr string
+	}{
+		{script: "def process(msg) pass", errStr: "SyntaxError"},
+		{script: "def other(msg): pass", errStr: "NameError: function process is not defined"},
+	} {
+		pConf, err := pythonProcessorConfig().ParseYAML("script: '"+test.script+"'", nil)
+		require.NoError(t, err)
+
+		_, err = newPythonProcessorFromConfig(pConf, service.MockResources())
+		require.ErrorContains(t, err, test.errStr)
+	}
+}
@@ -0,0 +1,226 @@
+# Copyright 2024 Redpanda Data, Inc.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#    http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+# Worker executed by the python processor. Frames are a four byte big endian
+# length followed by a msgpack document, and are read from stdin and written to
+# stdout. The first frame received contains the user script, which is answered
+# with an empty document or an error, and each subsequent frame contains a
+# batch of messages.
+
+import json
+import struct
+import sys
+import traceback
+
+try:
+    import msgpack
+
+    def pack(v):
+        return msgpack.packb(v, use_bin_type=True)
+
+    def unpack(b):
+        return msgpack.unpackb(b, raw=False)
+
+except ImportError:
+    # A minimal implementation of msgpack covering the types exchanged with the
+    # processor, used when the msgpack package is not installed.
+    def _pack(v, out):
+        if v is None:
+            out.append(b"\xc0")
+        elif v is True:
+            out.append(b"\xc3")
+        elif v is False:
+            out.append(b"\xc2")
+        elif isinstance(v, int):
+            if 0 <= v < 0x80:
+                out.append(struct.pack("B", v))
+            elif -32 <= v < 0:
+                out.append(struct.pack("b", v))
+            elif v >= 0:
+                out.append(b"\xcf" + struct.pack(">Q", v))
+            else:
+                out.append(b"\xd3" + struct.pack(">q", v))
+        elif isinstance(v, float):
+            out.append(b"\xcb" + struct.pack(">d", v))
+        elif isinstance(v, str):
+            b = v.encode("utf-8")
+            out.append(b"\xdb" + struct.pack(">I", len(b)))
+            out.append(b)
+        elif isinstance(v, (bytes, bytearray)):
+            out.append(b"\xc6" + struct.pack(">I", len(v)))
+            out.append(bytes(v))
+        elif isinstance(v, (list, tuple)):
+            out.append(b"\xdd" + struct.pack(">I", len(v)))
+            for i in v:
+                _pack(i, out)
+        elif isinstance(v, dict):
+            out.append(b"\xdf" + struct.pack(">I", len(v)))
+            for k, i in v.items():
+                _pack(k, out)
+                _pack(i, out)
+        else:
+            raise TypeError("cannot serialise value of type %s" % type(v).__name__)
+
+    def pack(v):
+        out = []
+        _pack(v, out)
+        return b"".join(out)
+
+    _FIXED = {
+        0xCA: ">f", 0xCB: ">d",
+        0xCC: ">B", 0xCD: ">H", 0xCE: ">I", 0xCF: ">Q",
+        0xD0: ">b", 0xD1: ">h", 0xD2: ">i", 0xD3: ">q",
+    }
+
+    _SIZED = {
+        0xD9: ("str", ">B"), 0xDA: ("str", ">H"), 0xDB: ("str", ">I"),
+        0xC4: ("bin", ">B"), 0xC5: ("bin", ">H"), 0xC6: ("bin", ">I"),
+        0xDC: ("arr", ">H"), 0xDD: ("arr", ">I"),
+        0xDE: ("map", ">H"), 0xDF: ("map", ">I"),
+    }
+
+    def _collection(kind, b, i, n):
+        if kind == "arr":
+            v = []
+            for _ in range(n):
+                item, i = _unpack(b, i)
+                v.append(item)
+            return v, i
+        v = {}
+        for _ in range(n):
+            key, i = _unpack(b, i)
+            v[key], i = _unpack(b, i)
+        return v, i
+
+    def _unpack(b, i):
+        t = b[i]
+        i += 1
+        if t <= 0x7F:
+            return t, i
+        if t >= 0xE0:
+            return t - 0x100, i
+        if t <= 0x8F:
+            return _collection("map", b, i, t & 0x0F)
+        if t <= 0x9F:
+            return _collection("arr", b, i, t & 0x0F)
+        if t <= 0xBF:
+            n = t & 0x1F
+            return b[i:i + n].decode("utf-8"), i + n
+        if t == 0xC0:
+            return None, i
+        if t == 0xC2:
+            return False, i
+        if t == 0xC3:
+            return True, i
+        if t in _FIXED:
+            fmt = _FIXED[t]
+            return struct.unpack_from(fmt, b, i)[0], i + struct.calcsize(fmt)
+        if t in _SIZED:
+            kind, fmt = _SIZED[t]
+            n = struct.unpack_from(fmt, b, i)[0]
+            i += struct.calcsize(fmt)
+            if kind == "str":
+                return b[i:i + n].decode("utf-8"), i + n
+            if kind == "bin":
+                return bytes(b[i:i + n]), i + n
+            return _collection(kind, b, i, n)
+        raise ValueError("unsupported msgpack type 0x%02x" % t)
+
+    def unpack(b):
+        return _unpack(b, 0)[0]
+
+
+class Message:
+    """A message being processed, consisting of content and metadata."""
+
+    def __init__(self, content=b"", metadata=None):
+        self.content = content
+        self.metadata = dict(metadata or {})
+
+    def json(self):
+        """Parse the content of the message as JSON."""
+        return json.loads(self.content)
+
+    def set_json(self, value):
+        """Set the content of the message to a value serialised as JSON."""
+        self.content = json.dumps(value).encode("utf-8")
+
+
+def _read_exact(stream, n):
+    chunks = []
+    while n > 0:
+        chunk = stream.read(n)
+        if not chunk:
+            return None
+        chunks.append(chunk)
+        n -= len(chunk)
+    return b"".join(chunks)
+
+
+def main():
+    stdin, stdout = sys.stdin.buffer, sys.stdout.buffer
+
+    # Output written by the script is logged by the processor rather than
+    # interfering with frames.
+    sys.stdout = sys.stderr
+
+    def read():
+        header = _read_exact(stdin, 4)
+        if header is None:
+            return None
+        return unpack(_read_exact(stdin, struct.unpack(">I", header)[0]))
+
+    def write(v):
+        b = pack(v)
+        stdout.write(struct.pack(">I", len(b)) + b)
+        stdout.flush()
+
+    init = read()
+    if init is None:
+        return
+    try:
+        scope = {"__name__": "__connect__", "Message": Message}
+        exec(compile(init["code"], init["filename"], "exec"), scope)
+        fn = scope.get(init["function"])
+        if not callable(fn):
+            raise NameError("function %s is not defined" % init["function"])
+    except BaseException:
+        write({"error": traceback.format_exc()})
+        return
+    write({})
+
+    while True:
+        req = read()
+        if req is None:
+            return
+        out = []
+        for index, m in enumerate(req["batch"]):
+            msg = Message(m["content"], m["metadata"])
+            try:
+                res = fn(msg)
+                if res is None:
+                    res = [msg]
+                elif isinstance(res, Message):
+                    res = [res]
+                for r in res:
+                    content = r.content
+                    if isinstance(content, str):
+                        content = content.encode("utf-8")
+                    out.append({"index": index, "content": bytes(content), "metadata": r.metadata})
+            except Exception as e:
+                out.append({"index": index, "error": "%s: %s" % (type(e).__name__, e)})
+        write({"batch": out})
+
+
+main()
@@ -0,0 +1,229 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package python
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+type pythonInit struct {
+	Code     string `msgpack:"code"`
+	Filename string `msgpack:"filename"`
+	Function string `msgpack:"function"`
+}
+
+type pythonMessage struct {
+	Content  []byte         `msgpack:"content"`
+	Metadata map[string]any `msgpack:"metadata"`
+}
+
+type pythonRequest struct {
+	Batch []pythonMessage `msgpack:"batch"`
+}
+
+type pythonResult struct {
+	Index    int            `msgpack:"index"`
+	Content  []byte         `msgpack:"content"`
+	Metadata map[string]any `msgpack:"metadata"`
+	Error    *string        `msgpack:"error"`
+}
+
+type pythonResponse struct {
+	Batch []pythonResult `msgpack:"batch"`
+	Error string         `msgpack:"error"`
+}
+
+// pythonWorker is a Python subprocess executing the runner script, which
+// processes one batch at a time.
+type pythonWorker struct {
+	log    *service.Logger
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout *bufio.Reader
+
+	// Closed once stderr has been fully read, which happens when the
+	// subprocess exits.
+	stderrDone chan struct{}
+}
+
+func startPythonWorker(log *service.Logger, binary string, init pythonInit) (*pythonWorker, error) {
+	cmd := exec.Command(binary, "-u", "-c", runnerScript)
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start python subprocess: %w", err)
+	}
+
+	w := &pythonWorker{
+		log:    log,
+		cmd:    cmd,
+		stdin:  stdin,
+		stdout: bufio.NewReader(stdout),
+
+		stderrDone: make(chan struct{}),
+	}
+	go func() {
+		defer close(w.stderrDone)
+		scanner := bufio.NewScanner(stderr)
+		for scanner.Scan() {
+			log.Info(scanner.Text())
+		}
+	}()
+
+	var res pythonResponse
+	if err := w.exchange(context.Background(), init, &res); err != nil {
+		w.close()
+		return nil, err
+	}
+	if res.Error != "" {
+		w.close()
+		return nil, fmt.Errorf("failed to execute python script: %v", strings.TrimSpace(res.Error))
+	}
+	return w, nil
+}
+
+func (w *pythonWorker) writeFrame(v any) error {
+	var buf bytes.Buffer
+	buf.Write([]byte{0, 0, 0, 0})
+	if err := msgpack.NewEncoder(&buf).Encode(v); err != nil {
+		return err
+	}
+	frame := buf.Bytes()
+	binary.BigEndian.PutUint32(frame, uint32(len(frame)-4))
+	_, err := w.stdin.Write(frame)
+	return err
+}
+
+func (w *pythonWorker) readFrame(v any) error {
+	var header [4]byte
+	if _, err := io.ReadFull(w.stdout, header[:]); err != nil {
+		return err
+	}
+	frame := make([]byte, binary.BigEndian.Uint32(header[:]))
+	if _, err := io.ReadFull(w.stdout, frame); err != nil {
+		return err
+	}
+
+	dec := msgpack.NewDecoder(bytes.NewReader(frame))
+	dec.UseLooseInterfaceDecoding(true)
+	return dec.Decode(v)
+}
+
+// exchange writes a request frame and reads the response, killing the
+// subprocess if the context is cancelled before it responds.
+func (w *pythonWorker) exchange(ctx context.Context, req, res any) error {
+	errChan := make(chan error, 1)
+	go func() {
+		if err := w.writeFrame(req); err != nil {
+			errChan <- err
+			return
+		}
+		errChan <- w.readFrame(res)
+	}()
+
+	select {
+	case err := <-errChan:
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			err = errors.New("python subprocess exited unexpectedly")
+		}
+		return err
+	case <-ctx.Done():
+		_ = w.cmd.Process.Kill()
+		return ctx.Err()
+	}
+}
+
+func (w *pythonWorker) process(ctx context.Context, batch service.MessageBatch) (service.MessageBatch, error) {
+	req := pythonRequest{Batch: make([]pythonMessage, len(batch))}
+	for i, msg := range batch {
+		content, err := msg.AsBytes()
+		if err != nil {
+			return nil, err
+		}
+		meta := map[string]any{}
+		_ = msg.MetaWalkMut(func(k string, v any) error {
+			meta[k] = v
+			return nil
+		})
+		req.Batch[i] = pythonMessage{Content: content, Metadata: meta}
+	}
+
+	var res pythonResponse
+	if err := w.exchange(ctx, req, &res); err != nil {
+		return nil, err
+	}
+
+	newBatch := make(service.MessageBatch, 0, len(res.Batch))
+	for _, r := range res.Batch {
+		if r.Index < 0 || r.Index >= len(batch) {
+			return nil, fmt.Errorf("python subprocess returned a result for unknown message %v", r.Index)
+		}
+
+		msg := batch[r.Index].Copy()
+		if r.Error != nil {
+			msg.SetError(errors.New(*r.Error))
+			newBatch = append(newBatch, msg)
+			continue
+		}
+
+		msg.SetBytes(r.Content)
+		_ = msg.MetaWalkMut(func(k string, _ any) error {
+			msg.MetaDelete(k)
+			return nil
+		})
+		for k, v := range r.Metadata {
+			msg.MetaSetMut(k, v)
+		}
+		newBatch = append(newBatch, msg)
+	}
+	return newBatch, nil
+}
+
+func (w *pythonWorker) close() {
+	// Closing stdin signals the runner to exit, after which it's killed if it
+	// hasn't.
+	_ = w.stdin.Close()
+	select {
+	case <-w.stderrDone:
+	case <-time.After(time.Second * 5):
+		_ = w.cmd.Process.Kill()
+		<-w.stderrDone
+	}
+	_ = w.cmd.Wait()
+}
@@ -82,6 +82,32 @@ func (c *qdrantClient) Upsert(ctx context.Context, collectionName string, points
 	return err
 }
 
+func (c *qdrantClient) Delete(ctx context.Context, collectionName string, points *qdrant.PointsSelector) error {
+	c.logger.Debugf("Deleting points from collection %s", collectionName)
+	wait := true
+	request := &qdrant.DeletePoints{
+		CollectionName: collectionName,
+		Points:         points,
+		Wait:           &wait,
+	}
+	_, err := c.client.Delete(ctx, request)
+
+	return err
+}
+
+func (c *qdrantClient) CollectionExists(ctx context.Context, collectionName string) (bool, error) {
+	return c.client.CollectionExists(ctx, collectionName)
+}
+
+func (c *qdrantClient) CreateCollection(ctx context.Context, collectionName string, vectors *qdrant.VectorsConfig, sparseVectors *qdrant.SparseVectorConfig) error {
+	c.logger.Infof("Creating collection %s", collectionName)
+	return c.client.CreateCollection(ctx, &qdrant.CreateCollection{
+		CollectionName:      collectionName,
+		VectorsConfig:       vectors,
+		SparseVectorsConfig: sparseVectors,
+	})
+}
+
 func (c *qdrantClient) Connect(ctx context.Context) error {
 	c.logger.Debug("Checking connection to Qdrant")
 	_, err := c.client.HealthCheck(ctx)
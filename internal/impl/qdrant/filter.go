@@ -0,0 +1,158 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package qdrant
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/qdrant/go-client/qdrant"
+	"github.com/redpanda-data/benthos/v4/public/bloblang"
+)
+
+// newFilter converts an object of payload keys to the values they must match
+// into a filter, where an array value matches any of its elements.
+// root = {"source": "docs", "chunk": 3, "tags": ["a", "b"]}
+func newFilter(input any) (*qdrant.Filter, error) {
+	obj, ok := input.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("expected an object, got: %T", input)
+	}
+	if len(obj) == 0 {
+		return nil, errors.New("filter must contain at least one key")
+	}
+
+	// Sorted for deterministic requests.
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	filter := &qdrant.Filter{}
+	for _, k := range keys {
+		cond, err := newMatchCondition(k, obj[k])
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %w", k, err)
+		}
+		filter.Must = append(filter.Must, cond)
+	}
+	return filter, nil
+}
+
+func newMatchCondition(key string, value any) (*qdrant.Condition, error) {
+	switch v := value.(type) {
+	case string:
+		return qdrant.NewMatchKeyword(key, v), nil
+	case bool:
+		return qdrant.NewMatchBool(key, v), nil
+	case []any:
+		if len(v) == 0 {
+			return nil, errors.New("array must contain at least one value")
+		}
+		if _, isStr := v[0].(string); isStr {
+			keywords := make([]string, len(v))
+			for i, e := range v {
+				s, ok := e.(string)
+				if !ok {
+					return nil, fmt.Errorf("expected a string at index %d, got: %T", i, e)
+				}
+				keywords[i] = s
+			}
+			return qdrant.NewMatchKeywords(key, keywords...), nil
+		}
+		ints := make([]int64, len(v))
+		for i, e := range v {
+			n, err := valueAsInt(e)
+			if err != nil {
+				return nil, fmt.Errorf("index %d: %w", i, err)
+			}
+			ints[i] = n
+		}
+		return qdrant.NewMatchInts(key, ints...), nil
+	}
+	n, err := valueAsInt(value)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported value %v, expected a string, bool, integer or array", value)
+	}
+	return qdrant.NewMatchInt(key, n), nil
+}
+
+// valueAsInt coerces a value into an integer, rejecting numbers with a
+// fractional part rather than truncating them.
+func valueAsInt(value any) (int64, error) {
+	f, err := bloblang.ValueAsFloat64(value)
+	if err != nil {
+		return 0, err
+	}
+	if f != math.Trunc(f) {
+		return 0, fmt.Errorf("expected an integer, got: %v", value)
+	}
+	return bloblang.ValueAsInt64(value)
+}
+
+// newCollectionConfig derives the vector configuration of a collection from
+// the vectors of a point.
+func newCollectionConfig(vectors *qdrant.Vectors, distance qdrant.Distance) (*qdrant.VectorsConfig, *qdrant.SparseVectorConfig, error) {
+	named := vectors.GetVectors().GetVectors()
+	if len(named) == 0 {
+		return nil, nil, errors.New("point does not contain any vectors")
+	}
+
+	params := map[string]*qdrant.VectorParams{}
+	sparse := map[string]*qdrant.SparseVectorParams{}
+	for name, vec := range named {
+		switch {
+		case vec.GetIndices() != nil:
+			sparse[name] = &qdrant.SparseVectorParams{}
+		case vec.VectorsCount != nil:
+			if *vec.VectorsCount == 0 {
+				return nil, nil, fmt.Errorf("multi-vector %q is empty", name)
+			}
+			params[name] = &qdrant.VectorParams{
+				Size:     uint64(len(vec.GetData())) / uint64(*vec.VectorsCount),
+				Distance: distance,
+				MultivectorConfig: &qdrant.MultiVectorConfig{
+					Comparator: qdrant.MultiVectorComparator_MaxSim,
+				},
+			}
+		default:
+			params[name] = &qdrant.VectorParams{
+				Size:     uint64(len(vec.GetData())),
+				Distance: distance,
+			}
+		}
+	}
+
+	if _, exists := sparse[""]; exists {
+		return nil, nil, errors.New("sparse vectors must be named")
+	}
+
+	if len(named) == 1 && params[""] != nil {
+		return qdrant.NewVectorsConfig(params[""]), nil, nil
+	}
+
+	var vectorsConfig *qdrant.VectorsConfig
+	if len(params) > 0 {
+		vectorsConfig = qdrant.NewVectorsConfigMap(params)
+	}
+	var sparseConfig *qdrant.SparseVectorConfig
+	if len(sparse) > 0 {
+		sparseConfig = qdrant.NewSparseVectorsConfig(sparse)
+	}
+	return vectorsConfig, sparseConfig, nil
+}
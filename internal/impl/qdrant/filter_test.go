@@ -0,0 +1,96 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package qdrant
+
+import (
+	"testing"
+
+	"github.com/qdrant/go-client/qdrant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestNewFilter(t *testing.T) {
+	filter, err := newFilter(map[string]any{
+		"source":  "wiki",
+		"chunk":   int64(3),
+		"draft":   false,
+		"tags":    []any{"a", "b"},
+		"version": []any{int64(1), 2.0},
+	})
+	require.NoError(t, err)
+	assert.True(t, proto.Equal(&qdrant.Filter{
+		Must: []*qdrant.Condition{
+			qdrant.NewMatchInt("chunk", 3),
+			qdrant.NewMatchBool("draft", false),
+			qdrant.NewMatchKeyword("source", "wiki"),
+			qdrant.NewMatchKeywords("tags", "a", "b"),
+			qdrant.NewMatchInts("version", 1, 2),
+		},
+	}, filter), filter.String())
+
+	for _, input := range []any{
+		"nope",
+		map[string]any{},
+		map[string]any{"foo": 1.5},
+		map[string]any{"foo": []any{}},
+		map[string]any{"foo": []any{"a", int64(1)}},
+		map[string]any{"foo": map[string]any{"bar": "baz"}},
+	} {
+		_, err := newFilter(input)
+		assert.Error(t, err, input)
+	}
+}
+
+func TestNewCollectionConfig(t *testing.T) {
+	vectors, sparse, err := newCollectionConfig(qdrant.NewVectorsMap(map[string]*qdrant.Vector{
+		"": qdrant.NewVectorDense([]float32{0.1, 0.2, 0.3}),
+	}), qdrant.Distance_Dot)
+	require.NoError(t, err)
+	assert.Nil(t, sparse)
+	assert.True(t, proto.Equal(qdrant.NewVectorsConfig(&qdrant.VectorParams{
+		Size:     3,
+		Distance: qdrant.Distance_Dot,
+	}), vectors), vectors.String())
+
+	vectors, sparse, err = newCollectionConfig(qdrant.NewVectorsMap(map[string]*qdrant.Vector{
+		"some_dense":  qdrant.NewVectorDense([]float32{0.1, 0.2}),
+		"some_multi":  qdrant.NewVectorMulti([][]float32{{0.1, 0.2, 0.3}, {0.4, 0.5, 0.6}}),
+		"some_sparse": qdrant.NewVectorSparse([]uint32{1, 5}, []float32{0.1, 0.2}),
+	}), qdrant.Distance_Cosine)
+	require.NoError(t, err)
+	assert.True(t, proto.Equal(qdrant.NewVectorsConfigMap(map[string]*qdrant.VectorParams{
+		"some_dense": {
+			Size:     2,
+			Distance: qdrant.Distance_Cosine,
+		},
+		"some_multi": {
+			Size:     3,
+			Distance: qdrant.Distance_Cosine,
+			MultivectorConfig: &qdrant.MultiVectorConfig{
+				Comparator: qdrant.MultiVectorComparator_MaxSim,
+			},
+		},
+	}), vectors), vectors.String())
+	assert.True(t, proto.Equal(qdrant.NewSparseVectorsConfig(map[string]*qdrant.SparseVectorParams{
+		"some_sparse": {},
+	}), sparse), sparse.String())
+
+	_, _, err = newCollectionConfig(qdrant.NewVectorsMap(map[string]*qdrant.Vector{
+		"": qdrant.NewVectorSparse([]uint32{1}, []float32{0.1}),
+	}), qdrant.Distance_Cosine)
+	require.Error(t, err)
+}
@@ -18,6 +18,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync"
 
 	"github.com/qdrant/go-client/qdrant"
 	"github.com/redpanda-data/benthos/v4/public/bloblang"
@@ -33,17 +34,49 @@ const (
 	qoFieldID             = "id"
 	qoFieldVectorMapping  = "vector_mapping"
 	qoFieldPayloadMapping = "payload_mapping"
+	qoFieldOp             = "operation"
+	qoFieldFilterMapping  = "filter_mapping"
+	qoFieldMaxPoints      = "max_points_per_request"
+
+	qoFieldCreateCollection         = "create_collection"
+	qoFieldCreateCollectionEnabled  = "enabled"
+	qoFieldCreateCollectionDistance = "distance"
+)
+
+type operation string
+
+const (
+	operationUpsert         operation = "upsert-points"
+	operationDelete         operation = "delete-points"
+	operationDeleteByFilter operation = "delete-points-by-filter"
 )
 
+var distances = map[string]qdrant.Distance{
+	"cosine":    qdrant.Distance_Cosine,
+	"euclid":    qdrant.Distance_Euclid,
+	"dot":       qdrant.Distance_Dot,
+	"manhattan": qdrant.Distance_Manhattan,
+}
+
 func outputSpec() *service.ConfigSpec {
 	return service.NewConfigSpec().
 		Version("4.33.0").
 		Categories("AI").
 		Summary("Adds items to a https://qdrant.tech/[Qdrant^] collection").
-		Description(service.OutputPerformanceDocs(true, true)).
+		Description(`
+The points of a batch are upserted to each collection with requests of up to `+"`max_points_per_request`"+` points. Alternatively, points can be deleted by their ID, or by filtering on their payload with the `+"`delete-points-by-filter`"+` operation, in which case `+"`filter_mapping`"+` results in an object of payload keys and the values they must match, where an array matches any of its values.
+`+service.OutputPerformanceDocs(true, true)).
 		Fields(
 			service.NewOutputMaxInFlightField(),
 			service.NewBatchPolicyField(qoFieldBatching),
+			service.NewStringAnnotatedEnumField(qoFieldOp, map[string]string{
+				string(operationUpsert):         "Upsert the points of messages.",
+				string(operationDelete):         "Delete the points with the IDs of messages.",
+				string(operationDeleteByFilter): "Delete the points with payloads that match the filter of messages.",
+			}).
+				Default(string(operationUpsert)).
+				Version("4.45.0").
+				Description("The operation to perform against the collection."),
 			service.NewStringField(qoFieldGrpcHost).
 				Description("The gRPC host of the Qdrant server.").
 				Example("localhost:6334").
@@ -55,11 +88,13 @@ func outputSpec() *service.ConfigSpec {
 			service.NewInterpolatedStringField(qoFieldCollectionName).
 				Description("The name of the collection in Qdrant."),
 			service.NewBloblangField(qoFieldID).
-				Description("The ID of the point to insert. Can be a UUID string or positive integer.").
+				Optional().
+				Description("The ID of the point to insert or delete. Can be a UUID string or positive integer. Required unless the operation is `delete-points-by-filter`.").
 				Example(`root = "dc88c126-679f-49f5-ab85-04b77e8c2791"`).
 				Example(`root = 832`),
 			service.NewBloblangField(qoFieldVectorMapping).
-				Description("The mapping to extract the vector from the document.").
+				Optional().
+				Description("The mapping to extract the vector from the document. Required if the operation is `upsert-points`.").
 				Example(`root = {"dense_vector": [0.352,0.532,0.754],"sparse_vector": {"indices": [23,325,532],"values": [0.352,0.532,0.532]}, "multi_vector": [[0.352,0.532],[0.352,0.532]]}`).
 				Example(`root = [1.2, 0.5, 0.76]`).
 				Example(`root = this.vector`).
@@ -72,7 +107,53 @@ func outputSpec() *service.ConfigSpec {
 				Description("An optional mapping of message to payload associated with the point.").
 				Example(`root = {"field": this.value, "field_2": 987}`).
 				Example(`root = metadata()`),
-		)
+			service.NewBloblangField(qoFieldFilterMapping).
+				Optional().
+				Version("4.45.0").
+				Description("A mapping to an object of payload keys and the values that points to delete must match, where an array value matches any of its values. Required if the operation is `delete-points-by-filter`.").
+				Example(`root = {"document_id": this.document_id}`).
+				Example(`root = {"source": "wiki", "tags": ["outdated", "draft"]}`),
+			service.NewIntField(qoFieldMaxPoints).
+				Default(256).
+				Version("4.45.0").
+				Advanced().
+				Description("The maximum number of points to upsert within a single request, larger batches are split into multiple requests."),
+			service.NewObjectField(qoFieldCreateCollection,
+				service.NewBoolField(qoFieldCreateCollectionEnabled).
+					Default(false).
+					Description("Whether to create collections that do not exist."),
+				service.NewStringEnumField(qoFieldCreateCollectionDistance, "cosine", "euclid", "dot", "manhattan").
+					Default("cosine").
+					Description("The distance function used to compare the vectors of created collections."),
+			).
+				Version("4.45.0").
+				Advanced().
+				Description("Create collections that do not exist before upserting points to them. The vectors of the collection are configured from the vectors of the first point written to it, with the size of each dense and multi-vector matching the size of its vectors."),
+		).
+		Example(
+			"Maintain a RAG index",
+			"Replace the chunks of documents in a collection whenever a document changes, by deleting the previous chunks of a document before upserting the new ones. This assumes that the chunks of each document are consumed in order, starting with the chunk at index zero.",
+			`
+output:
+  broker:
+    pattern: fan_out_sequential
+    outputs:
+      - qdrant:
+          grpc_host: localhost:6334
+          collection_name: documents
+          operation: delete-points-by-filter
+          filter_mapping: 'root = {"document_id": this.document_id}'
+        processors:
+          - mapping: 'root = if this.chunk_index != 0 { deleted() }'
+      - qdrant:
+          grpc_host: localhost:6334
+          collection_name: documents
+          id: 'root = uuid_v4()'
+          vector_mapping: 'root = this.embedding'
+          payload_mapping: 'root = this.without("embedding")'
+          create_collection:
+            enabled: true
+`)
 }
 
 func init() {
@@ -98,11 +179,20 @@ func init() {
 
 type outputWriter struct {
 	client *qdrantClient
+	op     operation
 
 	collectionName *service.InterpolatedString
 	id             *bloblang.Executor
 	vectorMapping  *bloblang.Executor
 	payloadMapping *bloblang.Executor
+	filterMapping  *bloblang.Executor
+	maxPoints      int
+
+	createCollections bool
+	distance          qdrant.Distance
+
+	collectionsMut sync.Mutex
+	collections    map[string]struct{}
 }
 
 func newOutputWriter(conf *service.ParsedConfig, mgr *service.Resources) (*outputWriter, error) {
@@ -125,48 +215,188 @@ func newOutputWriter(conf *service.ParsedConfig, mgr *service.Resources) (*outpu
 	if err != nil {
 		return nil, err
 	}
-	id, err := conf.FieldBloblang(qoFieldID)
+	rawOp, err := conf.FieldString(qoFieldOp)
 	if err != nil {
 		return nil, err
 	}
+	op := operation(rawOp)
 
-	vectorMapping, err := conf.FieldBloblang(qoFieldVectorMapping)
-	if err != nil {
-		return nil, err
+	w := outputWriter{
+		op:             op,
+		collectionName: collectionName,
+		collections:    map[string]struct{}{},
 	}
 
-	payloadMapping, err := conf.FieldBloblang(qoFieldPayloadMapping)
-	if err != nil {
+	if op != operationDeleteByFilter {
+		if !conf.Contains(qoFieldID) {
+			return nil, fmt.Errorf("field %s is required for the operation %s", qoFieldID, op)
+		}
+		if w.id, err = conf.FieldBloblang(qoFieldID); err != nil {
+			return nil, err
+		}
+	}
+
+	switch op {
+	case operationUpsert:
+		if !conf.Contains(qoFieldVectorMapping) {
+			return nil, fmt.Errorf("field %s is required for the operation %s", qoFieldVectorMapping, op)
+		}
+		if w.vectorMapping, err = conf.FieldBloblang(qoFieldVectorMapping); err != nil {
+			return nil, err
+		}
+		if w.payloadMapping, err = conf.FieldBloblang(qoFieldPayloadMapping); err != nil {
+			return nil, err
+		}
+	case operationDeleteByFilter:
+		if !conf.Contains(qoFieldFilterMapping) {
+			return nil, fmt.Errorf("field %s is required for the operation %s", qoFieldFilterMapping, op)
+		}
+		if w.filterMapping, err = conf.FieldBloblang(qoFieldFilterMapping); err != nil {
+			return nil, err
+		}
+	case operationDelete:
+	default:
+		return nil, fmt.Errorf("invalid operation: %s", op)
+	}
+
+	if w.maxPoints, err = conf.FieldInt(qoFieldMaxPoints); err != nil {
 		return nil, err
 	}
+	if w.maxPoints < 1 {
+		return nil, fmt.Errorf("field %s must be at least 1", qoFieldMaxPoints)
+	}
 
-	client, err := newQdrantClient(host, apiToken, enabled, config, mgr.Logger())
+	ccConf := conf.Namespace(qoFieldCreateCollection)
+	if w.createCollections, err = ccConf.FieldBool(qoFieldCreateCollectionEnabled); err != nil {
+		return nil, err
+	}
+	distance, err := ccConf.FieldString(qoFieldCreateCollectionDistance)
 	if err != nil {
 		return nil, err
 	}
+	var ok bool
+	if w.distance, ok = distances[distance]; !ok {
+		return nil, fmt.Errorf("invalid distance: %s", distance)
+	}
 
-	w := outputWriter{
-		client: client,
-
-		collectionName: collectionName,
-		id:             id,
-		vectorMapping:  vectorMapping,
-		payloadMapping: payloadMapping,
+	if w.client, err = newQdrantClient(host, apiToken, enabled, config, mgr.Logger()); err != nil {
+		return nil, err
 	}
 	return &w, nil
 }
 
 func (w *outputWriter) Connect(ctx context.Context) error {
+	w.collectionsMut.Lock()
+	w.collections = map[string]struct{}{}
+	w.collectionsMut.Unlock()
 	return w.client.Connect(ctx)
 }
 
 func (w *outputWriter) WriteBatch(ctx context.Context, batch service.MessageBatch) (err error) {
+	switch w.op {
+	case operationDelete:
+		return w.deleteBatch(ctx, batch)
+	case operationDeleteByFilter:
+		return w.deleteBatchByFilter(ctx, batch)
+	}
+
 	batches, err := w.batchPointsByCollection(batch)
 	if err != nil {
 		return err
 	}
-	for cn, batch := range batches {
-		if err := w.client.Upsert(ctx, cn, batch); err != nil {
+	for cn, points := range batches {
+		if w.createCollections {
+			if err := w.ensureCollection(ctx, cn, points[0]); err != nil {
+				return err
+			}
+		}
+		for len(points) > 0 {
+			n := min(len(points), w.maxPoints)
+			if err := w.client.Upsert(ctx, cn, points[:n]); err != nil {
+				return err
+			}
+			points = points[n:]
+		}
+	}
+	return nil
+}
+
+// ensureCollection creates a collection configured for the vectors of a point
+// unless it is already known to exist.
+func (w *outputWriter) ensureCollection(ctx context.Context, collectionName string, point *qdrant.PointStruct) error {
+	w.collectionsMut.Lock()
+	defer w.collectionsMut.Unlock()
+
+	if _, exists := w.collections[collectionName]; exists {
+		return nil
+	}
+	exists, err := w.client.CollectionExists(ctx, collectionName)
+	if err != nil {
+		return fmt.Errorf("failed to check whether collection %s exists: %w", collectionName, err)
+	}
+	if !exists {
+		vectors, sparseVectors, err := newCollectionConfig(point.Vectors, w.distance)
+		if err != nil {
+			return fmt.Errorf("unable to derive the vectors of collection %s: %w", collectionName, err)
+		}
+		if err := w.client.CreateCollection(ctx, collectionName, vectors, sparseVectors); err != nil {
+			return fmt.Errorf("failed to create collection %s: %w", collectionName, err)
+		}
+	}
+	w.collections[collectionName] = struct{}{}
+	return nil
+}
+
+func (w *outputWriter) deleteBatch(ctx context.Context, batch service.MessageBatch) error {
+	cnExec := batch.InterpolationExecutor(w.collectionName)
+	idExec := batch.BloblangExecutor(w.id)
+	batches := map[string][]*qdrant.PointId{}
+	for i := 0; i < len(batch); i++ {
+		collectionName, err := cnExec.TryString(i)
+		if err != nil {
+			return fmt.Errorf("%s interpolation error: %w", qoFieldCollectionName, err)
+		}
+		rawID, err := idExec.QueryValue(i)
+		if err != nil {
+			return fmt.Errorf("failed to execute %s: %w", qoFieldID, err)
+		}
+		id, err := newPointID(rawID)
+		if err != nil {
+			return fmt.Errorf("failed to coerce point ID type: %w", err)
+		}
+		batches[collectionName] = append(batches[collectionName], id)
+	}
+	for cn, ids := range batches {
+		if err := w.client.Delete(ctx, cn, qdrant.NewPointsSelectorIDs(ids)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *outputWriter) deleteBatchByFilter(ctx context.Context, batch service.MessageBatch) error {
+	cnExec := batch.InterpolationExecutor(w.collectionName)
+	filterExec := batch.BloblangExecutor(w.filterMapping)
+	batches := map[string][]*qdrant.Condition{}
+	for i := 0; i < len(batch); i++ {
+		collectionName, err := cnExec.TryString(i)
+		if err != nil {
+			return fmt.Errorf("%s interpolation error: %w", qoFieldCollectionName, err)
+		}
+		rawFilter, err := filterExec.QueryValue(i)
+		if err != nil {
+			return fmt.Errorf("failed to execute %s: %w", qoFieldFilterMapping, err)
+		}
+		filter, err := newFilter(rawFilter)
+		if err != nil {
+			return fmt.Errorf("unable to coerce filter output type: %w", err)
+		}
+		batches[collectionName] = append(batches[collectionName], qdrant.NewFilterAsCondition(filter))
+	}
+	for cn, filters := range batches {
+		// Points matching any of the filters of the batch are deleted.
+		selector := qdrant.NewPointsSelectorFilter(&qdrant.Filter{Should: filters})
+		if err := w.client.Delete(ctx, cn, selector); err != nil {
 			return err
 		}
 	}
@@ -0,0 +1,488 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redis
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/redpanda-data/benthos/v4/public/bloblang"
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	joFieldFormat         = "format"
+	joFieldQueue          = "queue"
+	joFieldTask           = "task"
+	joFieldArgsMapping    = "args_mapping"
+	joFieldKwargsMapping  = "kwargs_mapping"
+	joFieldIdempotencyKey = "idempotency_key"
+	joFieldIdempotencyTTL = "idempotency_ttl"
+	joFieldBatching       = "batching"
+)
+
+func redisJobOutputConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Version("4.45.0").
+		Summary(`Enqueues a job for each message onto a Redis backed job queue using the wire format of either Sidekiq or Celery.`).
+		Description(`
+The arguments of each job are obtained with `+"`"+joFieldArgsMapping+"`"+`, which allows streams to trigger jobs of existing workers without any changes to them.
+
+=== Formats
+
+==== `+"`sidekiq`"+`
+
+Jobs are pushed onto the list `+"`queue:<queue>`"+` and the queue is added to the set `+"`queues`"+`, in the same way as `+"`Sidekiq::Client.push`"+`. The field `+"`"+joFieldTask+"`"+` is the class of the worker.
+
+==== `+"`celery`"+`
+
+Jobs are pushed onto the list named after the queue as messages of version 2 of the Celery protocol, encoded as JSON, in the same way as the Redis transport of Kombu. The field `+"`"+joFieldTask+"`"+` is the name of the task, and its keyword arguments can be set with `+"`"+joFieldKwargsMapping+"`"+`.
+
+=== Idempotency
+
+When an `+"`"+joFieldIdempotencyKey+"`"+` is set the ID of each job is derived from its key, and a job is only enqueued when no job with the same key has been enqueued within the `+"`"+joFieldIdempotencyTTL+"`"+`, which is checked and recorded with the key `+"`idempotency:<key>`"+` atomically with enqueuing the job. This makes it safe to retry messages, and messages of duplicate jobs are acknowledged without enqueuing them.
+
+Since the check and enqueue are performed with a script, when the `+"`cluster`"+` kind is used the keys of the queue and of the idempotency keys must share a https://redis.io/docs/latest/operate/oss_and_stack/reference/cluster-spec/#hash-tags[hash tag^].`+service.OutputPerformanceDocs(true, true)).
+		Categories("Services").
+		Fields(clientFields()...).
+		Fields(
+			service.NewStringEnumField(joFieldFormat, "sidekiq", "celery").
+				Description("The wire format of the job queue."),
+			service.NewInterpolatedStringField(joFieldQueue).
+				Description("The queue to enqueue each job onto.").
+				Default("default"),
+			service.NewInterpolatedStringField(joFieldTask).
+				Description("The worker class (Sidekiq) or task name (Celery) of each job.").
+				Examples("HardWorker", "tasks.add"),
+			service.NewBloblangField(joFieldArgsMapping).
+				Description("A xref:guides:bloblang/about.adoc[Bloblang mapping] that should evaluate to an array of the positional arguments of each job.").
+				Default("root = [ this ]").
+				Example(`root = [ this.user_id, this.amount ]`),
+			service.NewBloblangField(joFieldKwargsMapping).
+				Description("A xref:guides:bloblang/about.adoc[Bloblang mapping] that should evaluate to an object of the keyword arguments of each job, which is only supported by the `celery` format.").
+				Optional().
+				Example(`root.priority = "high"`),
+			service.NewInterpolatedStringField(joFieldIdempotencyKey).
+				Description("An optional key that identifies duplicate jobs.").
+				Optional().
+				Example(`${! this.order_id }`),
+			service.NewDurationField(joFieldIdempotencyTTL).
+				Description("The duration to remember idempotency keys for.").
+				Default("24h").
+				Advanced(),
+			service.NewOutputMaxInFlightField(),
+			service.NewBatchPolicyField(joFieldBatching),
+		).
+		Example("Trigger Sidekiq Workers", "Enqueue a Sidekiq job for each order, where orders are delivered at least once but each is only processed once.", `
+output:
+  redis_job:
+    url: redis://localhost:6379
+    format: sidekiq
+    queue: orders
+    task: FulfilOrderWorker
+    args_mapping: 'root = [ this.order_id, this.items ]'
+    idempotency_key: ${! this.order_id }
+`).
+		Example("Trigger Celery Tasks", "Call a Celery task for each message with keyword arguments.", `
+output:
+  redis_job:
+    url: redis://localhost:6379/0
+    format: celery
+    queue: celery
+    task: tasks.send_email
+    args_mapping: 'root = [ this.to ]'
+    kwargs_mapping: 'root.template = this.template'
+`)
+}
+
+func init() {
+	err := service.RegisterBatchOutput(
+		"redis_job", redisJobOutputConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPol service.BatchPolicy, mif int, err error) {
+			if batchPol, err = conf.FieldBatchPolicy(joFieldBatching); err != nil {
+				return
+			}
+			if mif, err = conf.FieldMaxInFlight(); err != nil {
+				return
+			}
+			out, err = newRedisJobWriter(conf, mgr)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+// enqueueScript pushes a job onto a list, optionally only when an idempotency
+// key could be set, and optionally adds the queue to a set.
+//
+// KEYS: list, [idempotency key], [queues set]
+// ARGV: payload, idempotency TTL in milliseconds or 0, queue name or empty
+var enqueueScript = redis.NewScript(`
+local i = 2
+if ARGV[2] ~= "0" then
+  if not redis.call("SET", KEYS[i], "1", "NX", "PX", ARGV[2]) then
+    return 0
+  end
+  i = i + 1
+end
+if ARGV[3] ~= "" then
+  redis.call("SADD", KEYS[i], ARGV[3])
+end
+redis.call("LPUSH", KEYS[1], ARGV[1])
+return 1
+`)
+
+type redisJobWriter struct {
+	log *service.Logger
+
+	format         string
+	queue          *service.InterpolatedString
+	task           *service.InterpolatedString
+	args           *bloblang.Executor
+	kwargs         *bloblang.Executor
+	idempotencyKey *service.InterpolatedString
+	idempotencyTTL time.Duration
+	nowFn          func() time.Time
+
+	clientCtor func() (redis.UniversalClient, error)
+	client     redis.UniversalClient
+	connMut    sync.RWMutex
+}
+
+func newRedisJobWriter(conf *service.ParsedConfig, mgr *service.Resources) (r *redisJobWriter, err error) {
+	r = &redisJobWriter{
+		log:   mgr.Logger(),
+		nowFn: time.Now,
+		clientCtor: func() (redis.UniversalClient, error) {
+			return getClient(conf)
+		},
+	}
+
+	if r.format, err = conf.FieldString(joFieldFormat); err != nil {
+		return
+	}
+	if r.queue, err = conf.FieldInterpolatedString(joFieldQueue); err != nil {
+		return
+	}
+	if r.task, err = conf.FieldInterpolatedString(joFieldTask); err != nil {
+		return
+	}
+	if r.args, err = conf.FieldBloblang(joFieldArgsMapping); err != nil {
+		return
+	}
+	if conf.Contains(joFieldKwargsMapping) {
+		if r.format != "celery" {
+			return nil, fmt.Errorf("field %v is only supported by the celery format", joFieldKwargsMapping)
+		}
+		if r.kwargs, err = conf.FieldBloblang(joFieldKwargsMapping); err != nil {
+			return
+		}
+	}
+	if conf.Contains(joFieldIdempotencyKey) {
+		if r.idempotencyKey, err = conf.FieldInterpolatedString(joFieldIdempotencyKey); err != nil {
+			return
+		}
+	}
+	if r.idempotencyTTL, err = conf.FieldDuration(joFieldIdempotencyTTL); err != nil {
+		return
+	}
+
+	if _, err := getClient(conf); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *redisJobWriter) Connect(ctx context.Context) error {
+	r.connMut.Lock()
+	defer r.connMut.Unlock()
+
+	client, err := r.clientCtor()
+	if err != nil {
+		return err
+	}
+	if _, err = client.Ping(ctx).Result(); err != nil {
+		return err
+	}
+
+	r.client = client
+	return nil
+}
+
+// redisJob is a job encoded in the wire format of the queue, along with the
+// keys and arguments of the enqueue script.
+type redisJob struct {
+	keys []string
+	args []any
+}
+
+// jobID returns the ID of a job, which is derived from the idempotency key
+// when there is one.
+func jobID(idempotencyKey string, size int) []byte {
+	id := make([]byte, size)
+	if idempotencyKey != "" {
+		sum := sha256.Sum256([]byte(idempotencyKey))
+		copy(id, sum[:])
+	} else {
+		_, _ = rand.Read(id)
+	}
+	return id
+}
+
+func (r *redisJobWriter) buildJob(batch service.MessageBatch, i int, queueExec, taskExec, idemExec *service.MessageBatchInterpolationExecutor) (*redisJob, error) {
+	queue, err := queueExec.TryString(i)
+	if err != nil {
+		return nil, fmt.Errorf("queue interpolation error: %w", err)
+	}
+	if queue == "" {
+		return nil, errors.New("queue interpolation resulted in an empty queue")
+	}
+	task, err := taskExec.TryString(i)
+	if err != nil {
+		return nil, fmt.Errorf("task interpolation error: %w", err)
+	}
+	if task == "" {
+		return nil, errors.New("task interpolation resulted in an empty task")
+	}
+	var idemKey string
+	if idemExec != nil {
+		if idemKey, err = idemExec.TryString(i); err != nil {
+			return nil, fmt.Errorf("idempotency key interpolation error: %w", err)
+		}
+	}
+
+	args, err := queryStructured(batch, i, r.args)
+	if err != nil {
+		return nil, fmt.Errorf("args mapping failed: %w", err)
+	}
+	argsArr, ok := args.([]any)
+	if !ok {
+		return nil, fmt.Errorf("args mapping returned non-array result: %T", args)
+	}
+
+	now := r.nowFn()
+
+	var payload []byte
+	var listKey, queuesSet string
+	switch r.format {
+	case "sidekiq":
+		ts := float64(now.UnixNano()) / float64(time.Second)
+		if payload, err = json.Marshal(map[string]any{
+			"class":       task,
+			"args":        argsArr,
+			"queue":       queue,
+			"jid":         hex.EncodeToString(jobID(idemKey, 12)),
+			"retry":       true,
+			"created_at":  ts,
+			"enqueued_at": ts,
+		}); err != nil {
+			return nil, err
+		}
+		listKey, queuesSet = "queue:"+queue, "queues"
+	case "celery":
+		kwargs := map[string]any{}
+		if r.kwargs != nil {
+			v, err := queryStructured(batch, i, r.kwargs)
+			if err != nil {
+				return nil, fmt.Errorf("kwargs mapping failed: %w", err)
+			}
+			if kwargs, ok = v.(map[string]any); !ok {
+				return nil, fmt.Errorf("kwargs mapping returned non-object result: %T", v)
+			}
+		}
+		if payload, err = celeryMessage(queue, task, idemKey, argsArr, kwargs); err != nil {
+			return nil, err
+		}
+		listKey = queue
+	}
+
+	job := &redisJob{keys: []string{listKey}}
+	ttl := "0"
+	if idemKey != "" {
+		job.keys = append(job.keys, "idempotency:"+idemKey)
+		ttl = strconv.FormatInt(r.idempotencyTTL.Milliseconds(), 10)
+	}
+	queueName := ""
+	if queuesSet != "" {
+		job.keys = append(job.keys, queuesSet)
+		queueName = queue
+	}
+	job.args = []any{payload, ttl, queueName}
+	return job, nil
+}
+
+func queryStructured(batch service.MessageBatch, i int, exec *bloblang.Executor) (any, error) {
+	msg, err := batch.BloblangQuery(i, exec)
+	if err != nil {
+		return nil, err
+	}
+	if msg == nil {
+		return nil, errors.New("mapping deleted the message")
+	}
+	return msg.AsStructured()
+}
+
+// celeryMessage encodes a task as a message of version 2 of the Celery
+// protocol, as sent by the Redis transport of Kombu.
+func celeryMessage(queue, task, idempotencyKey string, args []any, kwargs map[string]any) ([]byte, error) {
+	id, err := uuid.FromBytes(jobID(idempotencyKey, 16))
+	if err != nil {
+		return nil, err
+	}
+	id.SetVersion(uuid.V4)
+	id.SetVariant(uuid.VariantRFC4122)
+
+	body, err := json.Marshal([]any{args, kwargs, map[string]any{
+		"callbacks": nil,
+		"errbacks":  nil,
+		"chain":     nil,
+		"chord":     nil,
+	}})
+	if err != nil {
+		return nil, err
+	}
+
+	argsRepr, _ := json.Marshal(args)
+	kwargsRepr, _ := json.Marshal(kwargs)
+	return json.Marshal(map[string]any{
+		"body":             base64.StdEncoding.EncodeToString(body),
+		"content-encoding": "utf-8",
+		"content-type":     "application/json",
+		"headers": map[string]any{
+			"lang":       "py",
+			"task":       task,
+			"id":         id.String(),
+			"root_id":    id.String(),
+			"parent_id":  nil,
+			"group":      nil,
+			"shadow":     nil,
+			"eta":        nil,
+			"expires":    nil,
+			"retries":    0,
+			"timelimit":  []any{nil, nil},
+			"argsrepr":   string(argsRepr),
+			"kwargsrepr": string(kwargsRepr),
+			"origin":     "redpanda-connect",
+		},
+		"properties": map[string]any{
+			"correlation_id": id.String(),
+			"reply_to":       "",
+			"delivery_mode":  2,
+			"delivery_info": map[string]any{
+				"exchange":    "",
+				"routing_key": queue,
+			},
+			"priority":      0,
+			"body_encoding": "base64",
+			"delivery_tag":  uuid.Must(uuid.NewV4()).String(),
+		},
+	})
+}
+
+func (r *redisJobWriter) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	r.connMut.RLock()
+	client := r.client
+	r.connMut.RUnlock()
+
+	if client == nil {
+		return service.ErrNotConnected
+	}
+
+	queueExec := batch.InterpolationExecutor(r.queue)
+	taskExec := batch.InterpolationExecutor(r.task)
+	var idemExec *service.MessageBatchInterpolationExecutor
+	if r.idempotencyKey != nil {
+		idemExec = batch.InterpolationExecutor(r.idempotencyKey)
+	}
+
+	jobs := make([]*redisJob, len(batch))
+	for i := range batch {
+		job, err := r.buildJob(batch, i, queueExec, taskExec, idemExec)
+		if err != nil {
+			return err
+		}
+		jobs[i] = job
+	}
+
+	// Ensure the script is cached so that it can be called by its hash
+	// within the pipeline.
+	if err := enqueueScript.Load(ctx, client).Err(); err != nil {
+		_ = r.disconnect()
+		r.log.Errorf("Error from redis: %v\n", err)
+		return service.ErrNotConnected
+	}
+
+	pipe := client.Pipeline()
+	cmds := make([]*redis.Cmd, len(jobs))
+	for i, job := range jobs {
+		cmds[i] = enqueueScript.EvalSha(ctx, pipe, job.keys, job.args...)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		var redisErr redis.Error
+		if !errors.As(err, &redisErr) {
+			_ = r.disconnect()
+			r.log.Errorf("Error from redis: %v\n", err)
+			return service.ErrNotConnected
+		}
+	}
+
+	var batchErr *service.BatchError
+	for i, cmd := range cmds {
+		res, err := cmd.Int()
+		if err != nil {
+			if batchErr == nil {
+				batchErr = service.NewBatchError(batch, err)
+			}
+			batchErr.Failed(i, err)
+			continue
+		}
+		if res == 0 {
+			r.log.Debugf("Skipping duplicate job of message %v", i)
+		}
+	}
+	if batchErr != nil {
+		return batchErr
+	}
+	return nil
+}
+
+func (r *redisJobWriter) disconnect() error {
+	r.connMut.Lock()
+	defer r.connMut.Unlock()
+	if r.client != nil {
+		err := r.client.Close()
+		r.client = nil
+		return err
+	}
+	return nil
+}
+
+func (r *redisJobWriter) Close(context.Context) error {
+	return r.disconnect()
+}
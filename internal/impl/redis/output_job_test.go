@@ -0,0 +1,155 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redis
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func buildTestJob(t *testing.T, w *redisJobWriter, msg *service.Message) (*redisJob, map[string]any) {
+	t.Helper()
+
+	batch := service.MessageBatch{msg}
+	var idemExec *service.MessageBatchInterpolationExecutor
+	if w.idempotencyKey != nil {
+		idemExec = batch.InterpolationExecutor(w.idempotencyKey)
+	}
+	job, err := w.buildJob(batch, 0, batch.InterpolationExecutor(w.queue), batch.InterpolationExecutor(w.task), idemExec)
+	require.NoError(t, err)
+
+	var payload map[string]any
+	require.NoError(t, json.Unmarshal(job.args[0].([]byte), &payload))
+	return job, payload
+}
+
+func TestRedisJobSidekiq(t *testing.T) {
+	pConf, err := redisJobOutputConfig().ParseYAML(`
+url: redis://localhost:6379
+format: sidekiq
+queue: ${! @queue }
+task: HardWorker
+args_mapping: 'root = [ this.id, this.name ]'
+idempotency_key: ${! this.id }
+idempotency_ttl: 1h
+`, nil)
+	require.NoError(t, err)
+
+	w, err := newRedisJobWriter(pConf, service.MockResources())
+	require.NoError(t, err)
+	w.nowFn = func() time.Time {
+		return time.Unix(1700000000, 500000000)
+	}
+
+	msg := service.NewMessage([]byte(`{"id":"abc","name":"foo"}`))
+	msg.MetaSetMut("queue", "critical")
+	job, payload := buildTestJob(t, w, msg)
+
+	assert.Equal(t, []string{"queue:critical", "idempotency:abc", "queues"}, job.keys)
+	assert.Equal(t, "3600000", job.args[1])
+	assert.Equal(t, "critical", job.args[2])
+
+	assert.Equal(t, map[string]any{
+		"class":       "HardWorker",
+		"args":        []any{"abc", "foo"},
+		"queue":       "critical",
+		"jid":         "ba7816bf8f01cfea414140de",
+		"retry":       true,
+		"created_at":  1700000000.5,
+		"enqueued_at": 1700000000.5,
+	}, payload)
+
+	// Jobs without an idempotency key get random IDs.
+	w.idempotencyKey = nil
+	job, payload = buildTestJob(t, w, msg)
+	assert.Equal(t, []string{"queue:critical", "queues"}, job.keys)
+	assert.Equal(t, "0", job.args[1])
+	assert.Len(t, payload["jid"], 24)
+	assert.NotEqual(t, "ba7816bf8f01cfea414140de", payload["jid"])
+}
+
+func TestRedisJobCelery(t *testing.T) {
+	pConf, err := redisJobOutputConfig().ParseYAML(`
+url: redis://localhost:6379
+format: celery
+task: tasks.add
+args_mapping: 'root = [ this.a, this.b ]'
+kwargs_mapping: 'root.round = true'
+idempotency_key: ${! this.a }
+`, nil)
+	require.NoError(t, err)
+
+	w, err := newRedisJobWriter(pConf, service.MockResources())
+	require.NoError(t, err)
+	w.nowFn = func() time.Time {
+		return time.Unix(1700000000, 500000000)
+	}
+
+	job, payload := buildTestJob(t, w, service.NewMessage([]byte(`{"a":"abc","b":2}`)))
+	assert.Equal(t, []string{"default", "idempotency:abc"}, job.keys)
+	assert.Equal(t, "86400000", job.args[1])
+	assert.Equal(t, "", job.args[2])
+
+	headers := payload["headers"].(map[string]any)
+	assert.Equal(t, "tasks.add", headers["task"])
+	assert.Equal(t, "ba7816bf-8f01-4fea-8141-40de5dae2223", headers["id"])
+	assert.Equal(t, headers["id"], headers["root_id"])
+	assert.Equal(t, `["abc",2]`, headers["argsrepr"])
+
+	props := payload["properties"].(map[string]any)
+	assert.Equal(t, "base64", props["body_encoding"])
+	assert.Equal(t, map[string]any{"exchange": "", "routing_key": "default"}, props["delivery_info"])
+
+	body, err := base64.StdEncoding.DecodeString(payload["body"].(string))
+	require.NoError(t, err)
+	assert.JSONEq(t, `[["abc",2],{"round":true},{"callbacks":null,"errbacks":null,"chain":null,"chord":null}]`, string(body))
+}
+
+func TestRedisJobErrors(t *testing.T) {
+	pConf, err := redisJobOutputConfig().ParseYAML(`
+url: redis://localhost:6379
+format: sidekiq
+task: HardWorker
+kwargs_mapping: 'root = {}'
+`, nil)
+	require.NoError(t, err)
+	_, err = newRedisJobWriter(pConf, service.MockResources())
+	require.ErrorContains(t, err, "only supported by the celery format")
+
+	pConf, err = redisJobOutputConfig().ParseYAML(`
+url: redis://localhost:6379
+format: sidekiq
+task: HardWorker
+args_mapping: 'root = this'
+`, nil)
+	require.NoError(t, err)
+
+	w, err := newRedisJobWriter(pConf, service.MockResources())
+	require.NoError(t, err)
+	w.nowFn = func() time.Time {
+		return time.Unix(1700000000, 500000000)
+	}
+
+	batch := service.MessageBatch{service.NewMessage([]byte(`{"a":"b"}`))}
+	_, err = w.buildJob(batch, 0, batch.InterpolationExecutor(w.queue), batch.InterpolationExecutor(w.task), nil)
+	require.ErrorContains(t, err, "non-array result")
+}
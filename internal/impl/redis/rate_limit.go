@@ -18,6 +18,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -27,7 +28,13 @@ import (
 
 func redisRatelimitConfig() *service.ConfigSpec {
 	spec := service.NewConfigSpec().
-		Summary(`A rate limit implementation using Redis. It works by using a simple token bucket algorithm to limit the number of requests to a given count within a given time period. The rate limit is shared across all instances of Redpanda Connect that use the same Redis instance, which must all have a consistent count and interval.`).
+		Summary(`A rate limit implementation using Redis. It limits the number of requests to a given count within a given time period. The rate limit is shared across all instances of Redpanda Connect that use the same Redis instance, which must all have a consistent count and interval.`).
+		Description(`
+=== Algorithms
+
+By default the ` + "`fixed_window`" + ` algorithm counts the requests made within each window of the interval, which allows bursts of up to twice the count where the end of one window meets the start of the next.
+
+The ` + "`token_bucket`" + ` algorithm instead refills a bucket with tokens at a constant rate of the count per interval, where each request consumes a token, so that requests are spread evenly across the interval while still allowing bursts of up to ` + "`burst`" + ` requests. The bucket is updated atomically by a script using the clock of the Redis server, and therefore instances sharing the bucket do not need synchronised clocks.`).
 		Version("4.12.0")
 
 	for _, f := range clientFields() {
@@ -41,7 +48,21 @@ func redisRatelimitConfig() *service.ConfigSpec {
 			Description("The time window to limit requests by.").
 			Default("1s")).
 		Field(service.NewStringField("key").
-			Description("The key to use for the rate limit."))
+			Description("The key to use for the rate limit.")).
+		Field(service.NewStringAnnotatedEnumField("algorithm", map[string]string{
+			"fixed_window": "Counts requests within fixed windows of the interval.",
+			"token_bucket": "Refills a bucket of tokens continuously at the rate of the count per interval.",
+		}).
+			Description("The algorithm used to limit requests.").
+			Default("fixed_window").
+			Advanced().
+			Version("4.45.0")).
+		Field(service.NewIntField("burst").
+			Description("The maximum number of tokens the bucket of the `token_bucket` algorithm holds, which is the largest burst of requests allowed. Defaults to the count.").
+			Optional().
+			Advanced().
+			Version("4.45.0").
+			LintRule(`root = if this <= 0 { [ "burst must be larger than zero" ] }`))
 
 	return spec
 }
@@ -60,15 +81,68 @@ func init() {
 //------------------------------------------------------------------------------
 
 type redisRatelimit struct {
-	size   int
-	key    string
-	period time.Duration
+	key string
 
 	client redis.UniversalClient
 
 	accessScript *redis.Script
+	accessArgs   []any
 }
 
+// fixedWindowScript counts requests within a window that starts with the first
+// request, returning the remaining duration of the window in milliseconds once
+// the count is exceeded.
+//
+// KEYS: counter
+// ARGV: count, interval in milliseconds
+var fixedWindowScript = redis.NewScript(`
+local current = redis.call("INCR",KEYS[1])
+
+if current == 1 then
+    redis.call("PEXPIRE", KEYS[1], tonumber(ARGV[2]))
+end
+
+if current > tonumber(ARGV[1]) then
+	return redis.call("PTTL", KEYS[1])
+end
+
+return 0
+`)
+
+// tokenBucketScript consumes a token from a bucket that is refilled at a rate
+// of tokens per millisecond up to its capacity, returning the duration in
+// milliseconds until a token is available when the bucket is empty. Time is
+// obtained from the server so that clients don't need synchronised clocks.
+//
+// KEYS: bucket
+// ARGV: tokens per millisecond, capacity
+var tokenBucketScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local capacity = tonumber(ARGV[2])
+
+local t = redis.call("TIME")
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+
+local state = redis.call("HMGET", KEYS[1], "tokens", "ts")
+local tokens = tonumber(state[1]) or capacity
+local ts = tonumber(state[2]) or now
+if now > ts then
+	tokens = math.min(capacity, tokens + (now - ts) * rate)
+	ts = now
+end
+
+local wait = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+else
+	wait = math.ceil((1 - tokens) / rate)
+end
+
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "ts", ts)
+redis.call("PEXPIRE", KEYS[1], math.ceil(capacity / rate) + 1000)
+return wait
+`)
+
 func newRedisRatelimitFromConfig(conf *service.ParsedConfig) (*redisRatelimit, error) {
 	client, err := getClient(conf)
 	if err != nil {
@@ -90,35 +164,53 @@ func newRedisRatelimitFromConfig(conf *service.ParsedConfig) (*redisRatelimit, e
 		return nil, err
 	}
 
+	algorithm, err := conf.FieldString("algorithm")
+	if err != nil {
+		return nil, err
+	}
+
 	if count <= 0 {
 		return nil, errors.New("count must be larger than zero")
 	}
 
-	return &redisRatelimit{
-		size:   count,
-		period: interval,
+	r := &redisRatelimit{
 		client: client,
 		key:    key,
-		accessScript: redis.NewScript(`
-local current = redis.call("INCR",KEYS[1])
-
-if current == 1 then
-    redis.call("PEXPIRE", KEYS[1], tonumber(ARGV[2]))
-end
-
-if current > tonumber(ARGV[1]) then
-	return redis.call("PTTL", KEYS[1])
-end
+	}
 
-return 0
-`),
-	}, nil
+	switch algorithm {
+	case "fixed_window":
+		if conf.Contains("burst") {
+			return nil, errors.New("burst is only supported by the token_bucket algorithm")
+		}
+		r.accessScript = fixedWindowScript
+		r.accessArgs = []any{count, int(interval.Milliseconds())}
+	case "token_bucket":
+		if interval.Milliseconds() <= 0 {
+			return nil, errors.New("interval must be at least one millisecond")
+		}
+		burst := count
+		if conf.Contains("burst") {
+			if burst, err = conf.FieldInt("burst"); err != nil {
+				return nil, err
+			}
+			if burst <= 0 {
+				return nil, errors.New("burst must be larger than zero")
+			}
+		}
+		rate := float64(count) / float64(interval.Milliseconds())
+		r.accessScript = tokenBucketScript
+		r.accessArgs = []any{strconv.FormatFloat(rate, 'g', -1, 64), burst}
+	default:
+		return nil, fmt.Errorf("algorithm %v is not supported", algorithm)
+	}
+	return r, nil
 }
 
 //------------------------------------------------------------------------------
 
 func (r *redisRatelimit) Access(ctx context.Context) (time.Duration, error) {
-	result := r.accessScript.Run(ctx, r.client, []string{r.key}, r.size, int(r.period.Milliseconds()))
+	result := r.accessScript.Run(ctx, r.client, []string{r.key}, r.accessArgs...)
 
 	if result.Err() != nil {
 		return 0, fmt.Errorf("accessing redis rate limit: %w", result.Err())
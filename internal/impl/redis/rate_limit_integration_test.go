@@ -76,6 +76,10 @@ func TestIntegrationRedisRateLimit(t *testing.T) {
 	t.Run("testRedisRateLimitRefresh", func(t *testing.T) {
 		testRedisRateLimitRefresh(t, urlStr)
 	})
+
+	t.Run("testRedisRateLimitTokenBucket", func(t *testing.T) {
+		testRedisRateLimitTokenBucket(t, urlStr)
+	})
 }
 
 func testRedisRateLimitBasic(t *testing.T, url string) {
@@ -165,3 +169,43 @@ url: `+url, nil)
 		t.Errorf("Period beyond interval: %v", period)
 	}
 }
+
+func testRedisRateLimitTokenBucket(t *testing.T, url string) {
+	conf, err := redisRatelimitConfig().ParseYAML(`
+key: rate_limit_token_bucket
+count: 10
+interval: 1s
+burst: 5
+algorithm: token_bucket
+url: `+url, nil)
+	require.NoError(t, err)
+
+	rl, err := newRedisRatelimitFromConfig(conf)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	for i := 0; i < 5; i++ {
+		period, err := rl.Access(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, time.Duration(0), period)
+	}
+
+	// The bucket is empty and refills a token every 100ms.
+	period, err := rl.Access(ctx)
+	require.NoError(t, err)
+	assert.Greater(t, period, time.Duration(0))
+	assert.LessOrEqual(t, period, 100*time.Millisecond)
+
+	<-time.After(250 * time.Millisecond)
+
+	for i := 0; i < 2; i++ {
+		period, err := rl.Access(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, time.Duration(0), period)
+	}
+
+	period, err = rl.Access(ctx)
+	require.NoError(t, err)
+	assert.Greater(t, period, time.Duration(0))
+}
@@ -17,6 +17,7 @@ package redis
 import (
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -45,3 +46,52 @@ key: asdf`, nil)
 	_, err = redisRatelimitConfig().ParseYAML(`url: redis://localhost:6379`, nil)
 	require.Error(t, err)
 }
+
+func TestRedisRateLimitAlgorithms(t *testing.T) {
+	conf, err := redisRatelimitConfig().ParseYAML(`
+url: redis://localhost:6379
+count: 10
+interval: 1s
+key: asdf`, nil)
+	require.NoError(t, err)
+
+	rl, err := newRedisRatelimitFromConfig(conf)
+	require.NoError(t, err)
+	assert.Equal(t, fixedWindowScript, rl.accessScript)
+	assert.Equal(t, []any{10, 1000}, rl.accessArgs)
+
+	conf, err = redisRatelimitConfig().ParseYAML(`
+url: redis://localhost:6379
+count: 10
+interval: 1s
+key: asdf
+algorithm: token_bucket`, nil)
+	require.NoError(t, err)
+
+	rl, err = newRedisRatelimitFromConfig(conf)
+	require.NoError(t, err)
+	assert.Equal(t, tokenBucketScript, rl.accessScript)
+	assert.Equal(t, []any{"0.01", 10}, rl.accessArgs)
+
+	conf, err = redisRatelimitConfig().ParseYAML(`
+url: redis://localhost:6379
+count: 10
+interval: 1m
+key: asdf
+algorithm: token_bucket
+burst: 100`, nil)
+	require.NoError(t, err)
+
+	rl, err = newRedisRatelimitFromConfig(conf)
+	require.NoError(t, err)
+	assert.Equal(t, []any{"0.00016666666666666666", 100}, rl.accessArgs)
+
+	conf, err = redisRatelimitConfig().ParseYAML(`
+url: redis://localhost:6379
+key: asdf
+burst: 100`, nil)
+	require.NoError(t, err)
+
+	_, err = newRedisRatelimitFromConfig(conf)
+	require.ErrorContains(t, err, "only supported by the token_bucket algorithm")
+}
@@ -0,0 +1,431 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package slo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/redpanda-data/benthos/v4/public/bloblang"
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	spFieldName             = "name"
+	spFieldTarget           = "target"
+	spFieldLatency          = "latency"
+	spFieldLatencyMax       = "max"
+	spFieldLatencyTimestamp = "timestamp"
+	spFieldCountErrors      = "count_errors"
+	spFieldAlerts           = "alerts"
+	spFieldAlertLongWindow  = "long_window"
+	spFieldAlertShortWindow = "short_window"
+	spFieldAlertBurnRate    = "burn_rate"
+	spFieldWebhook          = "webhook"
+	spFieldWebhookURL       = "url"
+	spFieldWebhookHeaders   = "headers"
+	spFieldWebhookTimeout   = "timeout"
+	spFieldAlertCooldown    = "cooldown"
+)
+
+func sloProcessorSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Version("4.45.0").
+		Summary("Tracks a service level objective (SLO) for the messages that pass through it, exposing burn-rate metrics and optionally firing webhook alerts when the error budget is being consumed too quickly.").
+		Description(`
+Messages are not modified by this processor. Each message that passes through is counted as an event, and an event is considered bad when it either carries an error flag (when `+"`count_errors` is `true`"+`), or when the time elapsed since the timestamp extracted by `+"`latency.timestamp`"+` exceeds `+"`latency.max`"+`.
+
+Placing this processor at the end of a pipeline therefore tracks the end-to-end latency and error ratio of a stream against the objective `+"`target`"+`.
+
+== Burn rates
+
+For each configured alert the ratio of bad events over both the long and short windows is divided by the error budget (`+"`1 - target`"+`) in order to calculate a burn rate. A burn rate of 1 means the error budget is being consumed at exactly the rate that would exhaust it at the end of the objective period.
+
+An alert fires when the burn rates over both its long and short windows exceed its `+"`burn_rate`"+` threshold, following the multiwindow approach popularised by the Google SRE workbook. The short window ensures that alerts stop firing soon after an incident is resolved.
+
+== Metrics
+
+The following metrics are emitted by this processor, labelled by the objective `+"`name`"+`:
+
+- `+"`slo_events_total`"+`: A counter of all events observed.
+- `+"`slo_bad_events_total`"+`: A counter of bad events observed.
+- `+"`slo_burn_rate`"+`: A gauge of the current burn rate, additionally labelled by `+"`window`"+`.
+- `+"`slo_alerts_fired`"+`: A counter of alerts fired.
+
+== Webhooks
+
+When a `+"`webhook`"+` is configured alerts are delivered as a JSON document via a POST request of the form:
+
+`+"```json"+`
+{
+  "slo": "orders",
+  "target": 0.999,
+  "long_window": "1h0m0s",
+  "short_window": "5m0s",
+  "burn_rate_threshold": 14.4,
+  "long_burn_rate": 20.1,
+  "short_burn_rate": 31.7,
+  "fired_at": "2024-12-18T11:57:32Z"
+}
+`+"```"+`
+
+Webhook requests are made in the background and do not block the processing of messages.`).
+		Fields(
+			service.NewStringField(spFieldName).
+				Description("A name that identifies the objective within metrics and alerts.").
+				Example("orders_delivery"),
+			service.NewFloatField(spFieldTarget).
+				Description("The objective ratio of good events to total events, which must be greater than zero and less than one.").
+				Default(0.999),
+			service.NewObjectField(spFieldLatency,
+				service.NewDurationField(spFieldLatencyMax).
+					Description("The maximum latency of an event before it is considered bad.").
+					Example("10s"),
+				service.NewBloblangField(spFieldLatencyTimestamp).
+					Description("A Bloblang mapping that extracts the time at which a message entered the system, which can be either a timestamp or a number of seconds since the unix epoch.").
+					Examples(`root = @kafka_timestamp_ms.number() / 1000`, `root = this.created_at.ts_parse("2006-01-02T15:04:05Z07:00")`),
+			).
+				Description("An optional latency objective, when omitted only errors are considered bad events.").
+				Optional(),
+			service.NewBoolField(spFieldCountErrors).
+				Description("Whether messages that have failed processing should be considered bad events.").
+				Default(true),
+			service.NewObjectListField(spFieldAlerts,
+				service.NewDurationField(spFieldAlertLongWindow).
+					Description("The long window over which burn rates are calculated.").
+					Example("1h"),
+				service.NewDurationField(spFieldAlertShortWindow).
+					Description("The short window over which burn rates are calculated, which is typically a twelfth of the long window.").
+					Example("5m"),
+				service.NewFloatField(spFieldAlertBurnRate).
+					Description("The burn rate that both windows must exceed in order for the alert to fire.").
+					Example(14.4),
+			).
+				Description("A list of burn-rate alerts to evaluate.").
+				Default([]any{}),
+			service.NewObjectField(spFieldWebhook,
+				service.NewURLField(spFieldWebhookURL).
+					Description("The URL to POST alerts to."),
+				service.NewStringMapField(spFieldWebhookHeaders).
+					Description("A map of headers to add to webhook requests.").
+					Default(map[string]any{}),
+				service.NewDurationField(spFieldWebhookTimeout).
+					Description("The maximum period to wait for a webhook request to complete.").
+					Default("5s"),
+			).
+				Description("An optional webhook to deliver fired alerts to.").
+				Optional(),
+			service.NewDurationField(spFieldAlertCooldown).
+				Description("The minimum period of time to wait before the same alert can fire again.").
+				Default("10m"),
+		).
+		Example("End-to-end Latency", "Track the proportion of messages consumed from Kafka that are processed within ten seconds, and call a webhook when the error budget of a 99.9% objective is burning at over fourteen times the sustainable rate.", `
+pipeline:
+  processors:
+    - mapping: 'root = this.apply("enrich")'
+    - slo:
+        name: orders
+        target: 0.999
+        latency:
+          max: 10s
+          timestamp: 'root = @kafka_timestamp_ms.number() / 1000'
+        alerts:
+          - long_window: 1h
+            short_window: 5m
+            burn_rate: 14.4
+        webhook:
+          url: https://alerts.example.com/hooks/connect
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor("slo", sloProcessorSpec(), func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+		return newSLOProcessorFromConfig(conf, mgr)
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type burnRateAlert struct {
+	longWindow  *slidingWindow
+	shortWindow *slidingWindow
+	threshold   float64
+
+	lastFired time.Time
+}
+
+type webhookConfig struct {
+	url     string
+	headers map[string]string
+	timeout time.Duration
+}
+
+type sloProcessor struct {
+	name          string
+	target        float64
+	latencyMax    time.Duration
+	latencyTS     *bloblang.Executor
+	countErrors   bool
+	alerts        []*burnRateAlert
+	alertsMut     sync.Mutex
+	cooldown      time.Duration
+	webhook       *webhookConfig
+	webhookClient *http.Client
+
+	mEvents    *service.MetricCounter
+	mBadEvents *service.MetricCounter
+	mBurnRate  *service.MetricGauge
+	mFired     *service.MetricCounter
+
+	log   *service.Logger
+	nowFn func() time.Time
+
+	wg sync.WaitGroup
+}
+
+func newSLOProcessorFromConfig(conf *service.ParsedConfig, mgr *service.Resources) (*sloProcessor, error) {
+	s := &sloProcessor{
+		log:   mgr.Logger(),
+		nowFn: time.Now,
+	}
+
+	var err error
+	if s.name, err = conf.FieldString(spFieldName); err != nil {
+		return nil, err
+	}
+	if s.target, err = conf.FieldFloat(spFieldTarget); err != nil {
+		return nil, err
+	}
+	if s.target <= 0 || s.target >= 1 {
+		return nil, fmt.Errorf("target must be greater than zero and less than one, got %v", s.target)
+	}
+
+	if conf.Contains(spFieldLatency) {
+		lConf := conf.Namespace(spFieldLatency)
+		if s.latencyMax, err = lConf.FieldDuration(spFieldLatencyMax); err != nil {
+			return nil, err
+		}
+		if s.latencyTS, err = lConf.FieldBloblang(spFieldLatencyTimestamp); err != nil {
+			return nil, err
+		}
+	}
+	if s.countErrors, err = conf.FieldBool(spFieldCountErrors); err != nil {
+		return nil, err
+	}
+	if s.latencyTS == nil && !s.countErrors {
+		return nil, errors.New("a latency objective must be configured when count_errors is disabled")
+	}
+
+	alertConfs, err := conf.FieldObjectList(spFieldAlerts)
+	if err != nil {
+		return nil, err
+	}
+	for i, aConf := range alertConfs {
+		longWindow, err := aConf.FieldDuration(spFieldAlertLongWindow)
+		if err != nil {
+			return nil, err
+		}
+		shortWindow, err := aConf.FieldDuration(spFieldAlertShortWindow)
+		if err != nil {
+			return nil, err
+		}
+		if shortWindow > longWindow {
+			return nil, fmt.Errorf("alert %v: short_window must not exceed long_window", i)
+		}
+		threshold, err := aConf.FieldFloat(spFieldAlertBurnRate)
+		if err != nil {
+			return nil, err
+		}
+		s.alerts = append(s.alerts, &burnRateAlert{
+			longWindow:  newSlidingWindow(longWindow),
+			shortWindow: newSlidingWindow(shortWindow),
+			threshold:   threshold,
+		})
+	}
+
+	if s.cooldown, err = conf.FieldDuration(spFieldAlertCooldown); err != nil {
+		return nil, err
+	}
+
+	if conf.Contains(spFieldWebhook) {
+		wConf := conf.Namespace(spFieldWebhook)
+		s.webhook = &webhookConfig{}
+		if s.webhook.url, err = wConf.FieldString(spFieldWebhookURL); err != nil {
+			return nil, err
+		}
+		if s.webhook.headers, err = wConf.FieldStringMap(spFieldWebhookHeaders); err != nil {
+			return nil, err
+		}
+		if s.webhook.timeout, err = wConf.FieldDuration(spFieldWebhookTimeout); err != nil {
+			return nil, err
+		}
+		s.webhookClient = &http.Client{Timeout: s.webhook.timeout}
+	}
+
+	metrics := mgr.Metrics()
+	s.mEvents = metrics.NewCounter("slo_events_total", "slo")
+	s.mBadEvents = metrics.NewCounter("slo_bad_events_total", "slo")
+	s.mBurnRate = metrics.NewGauge("slo_burn_rate", "slo", "window")
+	s.mFired = metrics.NewCounter("slo_alerts_fired", "slo")
+	return s, nil
+}
+
+func (s *sloProcessor) isBad(msg *service.Message, now time.Time) bool {
+	if s.countErrors && msg.GetError() != nil {
+		return true
+	}
+	if s.latencyTS == nil {
+		return false
+	}
+
+	v, err := msg.BloblangQueryValue(s.latencyTS)
+	if err != nil {
+		s.log.Debugf("Failed to extract latency timestamp: %v", err)
+		return true
+	}
+	var ts time.Time
+	switch t := v.(type) {
+	case time.Time:
+		ts = t
+	default:
+		secs, err := bloblang.ValueAsFloat64(v)
+		if err != nil {
+			s.log.Debugf("Failed to extract latency timestamp: %v", err)
+			return true
+		}
+		ts = time.Unix(0, int64(secs*float64(time.Second)))
+	}
+	return now.Sub(ts) > s.latencyMax
+}
+
+func (s *sloProcessor) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	now := s.nowFn()
+	bad := s.isBad(msg, now)
+
+	s.mEvents.Incr(1, s.name)
+	if bad {
+		s.mBadEvents.Incr(1, s.name)
+	}
+
+	s.alertsMut.Lock()
+	defer s.alertsMut.Unlock()
+
+	for _, a := range s.alerts {
+		a.longWindow.Add(now, bad)
+		a.shortWindow.Add(now, bad)
+
+		longRate := a.longWindow.BurnRate(now, s.target)
+		shortRate := a.shortWindow.BurnRate(now, s.target)
+		s.mBurnRate.SetFloat64(longRate, s.name, a.longWindow.period.String())
+		s.mBurnRate.SetFloat64(shortRate, s.name, a.shortWindow.period.String())
+
+		if longRate < a.threshold || shortRate < a.threshold {
+			continue
+		}
+		if !a.lastFired.IsZero() && now.Sub(a.lastFired) < s.cooldown {
+			continue
+		}
+		a.lastFired = now
+		s.fire(alertPayload{
+			SLO:               s.name,
+			Target:            s.target,
+			LongWindow:        a.longWindow.period.String(),
+			ShortWindow:       a.shortWindow.period.String(),
+			BurnRateThreshold: a.threshold,
+			LongBurnRate:      longRate,
+			ShortBurnRate:     shortRate,
+			FiredAt:           now.UTC().Format(time.RFC3339),
+		})
+	}
+	return service.MessageBatch{msg}, nil
+}
+
+type alertPayload struct {
+	SLO               string  `json:"slo"`
+	Target            float64 `json:"target"`
+	LongWindow        string  `json:"long_window"`
+	ShortWindow       string  `json:"short_window"`
+	BurnRateThreshold float64 `json:"burn_rate_threshold"`
+	LongBurnRate      float64 `json:"long_burn_rate"`
+	ShortBurnRate     float64 `json:"short_burn_rate"`
+	FiredAt           string  `json:"fired_at"`
+}
+
+func (s *sloProcessor) fire(p alertPayload) {
+	s.mFired.Incr(1, s.name)
+	s.log.Warnf("SLO '%v' burn rate alert fired: %.2f over %v and %.2f over %v exceed threshold %v", p.SLO, p.LongBurnRate, p.LongWindow, p.ShortBurnRate, p.ShortWindow, p.BurnRateThreshold)
+	if s.webhook == nil {
+		return
+	}
+
+	body, err := json.Marshal(p)
+	if err != nil {
+		s.log.Errorf("Failed to marshal SLO alert: %v", err)
+		return
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ctx, done := context.WithTimeout(context.Background(), s.webhook.timeout)
+		defer done()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.webhook.url, bytes.NewReader(body))
+		if err != nil {
+			s.log.Errorf("Failed to create SLO alert webhook request: %v", err)
+			return
+		}
+		req.Header.Set("Content-Type", "application/json")
+		for k, v := range s.webhook.headers {
+			req.Header.Set(k, v)
+		}
+
+		res, err := s.webhookClient.Do(req)
+		if err != nil {
+			s.log.Errorf("Failed to deliver SLO alert webhook: %v", err)
+			return
+		}
+		_ = res.Body.Close()
+		if res.StatusCode < 200 || res.StatusCode > 299 {
+			s.log.Errorf("SLO alert webhook returned unexpected status code: %v", res.StatusCode)
+		}
+	}()
+}
+
+// Close waits for any in-flight webhook requests to complete, which are bound
+// by the webhook timeout.
+func (s *sloProcessor) Close(ctx context.Context) error {
+	waitChan := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(waitChan)
+	}()
+	select {
+	case <-waitChan:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return nil
+}
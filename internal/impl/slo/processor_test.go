@@ -0,0 +1,166 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package slo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func TestSlidingWindowBurnRate(t *testing.T) {
+	w := newSlidingWindow(time.Minute)
+	now := time.Unix(1000, 0)
+
+	for i := 0; i < 98; i++ {
+		w.Add(now, false)
+	}
+	w.Add(now, true)
+	w.Add(now, true)
+
+	total, bad := w.Counts(now)
+	assert.Equal(t, int64(100), total)
+	assert.Equal(t, int64(2), bad)
+	assert.InDelta(t, 20.0, w.BurnRate(now, 0.999), 0.0001)
+
+	// Events fall out of the window once the period has elapsed.
+	total, bad = w.Counts(now.Add(time.Minute + time.Second))
+	assert.Equal(t, int64(0), total)
+	assert.Equal(t, int64(0), bad)
+}
+
+func TestSLOProcessorConfigErrors(t *testing.T) {
+	for _, test := range []struct {
+		name        string
+		config      string
+		errContains string
+	}{
+		{
+			name: "bad target",
+			config: `
+name: foo
+target: 1
+`,
+			errContains: "target must be",
+		},
+		{
+			name: "no objective",
+			config: `
+name: foo
+count_errors: false
+`,
+			errContains: "latency objective",
+		},
+		{
+			name: "bad windows",
+			config: `
+name: foo
+alerts:
+  - long_window: 1m
+    short_window: 1h
+    burn_rate: 2
+`,
+			errContains: "short_window",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			conf, err := sloProcessorSpec().ParseYAML(test.config, nil)
+			require.NoError(t, err)
+
+			_, err = newSLOProcessorFromConfig(conf, service.MockResources())
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.errContains)
+		})
+	}
+}
+
+func TestSLOProcessorWebhookAlert(t *testing.T) {
+	var alertsMut sync.Mutex
+	var alerts []alertPayload
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "bar", r.Header.Get("X-Foo"))
+
+		var p alertPayload
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&p))
+
+		alertsMut.Lock()
+		alerts = append(alerts, p)
+		alertsMut.Unlock()
+	}))
+	defer ts.Close()
+
+	conf, err := sloProcessorSpec().ParseYAML(`
+name: orders
+target: 0.9
+latency:
+  max: 10s
+  timestamp: 'root = this.ts'
+alerts:
+  - long_window: 1h
+    short_window: 5m
+    burn_rate: 2
+webhook:
+  url: `+ts.URL+`
+  headers:
+    X-Foo: bar
+cooldown: 1h
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := newSLOProcessorFromConfig(conf, service.MockResources())
+	require.NoError(t, err)
+
+	now := time.Unix(10000, 0)
+	proc.nowFn = func() time.Time { return now }
+
+	// Fresh messages are within the latency objective.
+	for i := 0; i < 10; i++ {
+		batch, err := proc.Process(context.Background(), service.NewMessage([]byte(`{"ts":9999}`)))
+		require.NoError(t, err)
+		require.Len(t, batch, 1)
+	}
+
+	// Stale and errored messages are bad events, and push the burn rate over
+	// the threshold.
+	for i := 0; i < 3; i++ {
+		_, err := proc.Process(context.Background(), service.NewMessage([]byte(`{"ts":100}`)))
+		require.NoError(t, err)
+	}
+	errMsg := service.NewMessage([]byte(`{"ts":9999}`))
+	errMsg.SetError(errors.New("nope"))
+	_, err = proc.Process(context.Background(), errMsg)
+	require.NoError(t, err)
+
+	require.NoError(t, proc.Close(context.Background()))
+
+	alertsMut.Lock()
+	defer alertsMut.Unlock()
+
+	// The cooldown prevents more than one alert from firing.
+	require.Len(t, alerts, 1)
+	assert.Equal(t, "orders", alerts[0].SLO)
+	assert.Equal(t, "1h0m0s", alerts[0].LongWindow)
+	assert.GreaterOrEqual(t, alerts[0].LongBurnRate, 2.0)
+}
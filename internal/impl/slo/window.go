@@ -0,0 +1,97 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package slo
+
+import (
+	"sync"
+	"time"
+)
+
+const windowBuckets = 60
+
+type windowBucket struct {
+	start time.Time
+	total int64
+	bad   int64
+}
+
+// slidingWindow keeps approximate counts of total and bad events observed
+// within a trailing period of time by distributing them into a fixed number of
+// buckets.
+type slidingWindow struct {
+	period     time.Duration
+	bucketSize time.Duration
+
+	mut     sync.Mutex
+	buckets [windowBuckets]windowBucket
+}
+
+func newSlidingWindow(period time.Duration) *slidingWindow {
+	bucketSize := period / windowBuckets
+	if bucketSize <= 0 {
+		bucketSize = 1
+	}
+	return &slidingWindow{
+		period:     period,
+		bucketSize: bucketSize,
+	}
+}
+
+func (w *slidingWindow) bucketFor(t time.Time) *windowBucket {
+	start := t.Truncate(w.bucketSize)
+	b := &w.buckets[(start.UnixNano()/int64(w.bucketSize))%windowBuckets]
+	if !b.start.Equal(start) {
+		*b = windowBucket{start: start}
+	}
+	return b
+}
+
+// Add records an event at a given time.
+func (w *slidingWindow) Add(t time.Time, bad bool) {
+	w.mut.Lock()
+	b := w.bucketFor(t)
+	b.total++
+	if bad {
+		b.bad++
+	}
+	w.mut.Unlock()
+}
+
+// Counts returns the total and bad event counts of all buckets that fall
+// within the window period ending at the provided time.
+func (w *slidingWindow) Counts(now time.Time) (total, bad int64) {
+	w.mut.Lock()
+	defer w.mut.Unlock()
+
+	cutoff := now.Add(-w.period)
+	for _, b := range w.buckets {
+		if b.start.After(cutoff) && !b.start.After(now) {
+			total += b.total
+			bad += b.bad
+		}
+	}
+	return
+}
+
+// BurnRate returns the rate at which the error budget of an objective is being
+// consumed over the window, where a value of 1 means the budget would be
+// exactly exhausted by the end of the objective period.
+func (w *slidingWindow) BurnRate(now time.Time, target float64) float64 {
+	total, bad := w.Counts(now)
+	if total == 0 {
+		return 0
+	}
+	return (float64(bad) / float64(total)) / (1 - target)
+}
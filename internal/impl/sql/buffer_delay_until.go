@@ -0,0 +1,413 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+
+	"github.com/redpanda-data/benthos/v4/public/bloblang"
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	duFieldPath                 = "path"
+	duFieldDeliverAt            = "deliver_at"
+	duFieldRetryInitialInterval = "retry_initial_interval"
+	duFieldRetryMaxInterval     = "retry_max_interval"
+)
+
+// DelayUntilBufferConfig returns a config spec for a delay_until buffer.
+func DelayUntilBufferConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Version("4.45.0").
+		Summary("Stores messages in an SQLite database and holds each of them until a time derived from the message, retrying failed deliveries with an exponential backoff.").
+		Description(`
+The time at which each message is delivered is determined by executing the `+"`"+duFieldDeliverAt+"`"+` mapping against it when it is added to the buffer, which must result in a timestamp, a unix timestamp in seconds, or a string in RFC 3339 format. Messages are consumed from the buffer in the order of their delivery times, and messages with times in the past are delivered immediately.
+
+Messages are stored and delivered individually, and each batch written to the buffer is therefore split into single messages.
+
+== Retries
+
+When a message fails to be delivered at the output level it is held once again, and redelivered after `+"`"+duFieldRetryInitialInterval+"`"+`. The interval doubles with each consecutive failure of a message up to `+"`"+duFieldRetryMaxInterval+"`"+`. While a message is being retried its previous number of failed attempts is available in the metadata field `+"`delay_until_attempts`"+`. Messages are retried until they are delivered, and so in order to give up after a number of attempts a condition on this field can be used to route messages to a dead letter output.
+
+== Delivery guarantees
+
+Messages are acknowledged at the input level once they are added to the database, and are only removed from the database once they have been delivered. Messages that were in flight when the service was shut down are delivered again when it restarts, and therefore delivery is at-least-once as long as the database file is not lost.
+`).
+		Fields(
+			service.NewStringField(duFieldPath).
+				Description("The path of the database file, which will be created if it does not already exist."),
+			service.NewBloblangField(duFieldDeliverAt).
+				Description("A mapping that determines the time at which a message is to be delivered.").
+				Examples(`root = this.scheduled_at`, `root = now().ts_add_iso8601("PT1H")`).
+				Default(`root = now()`),
+			service.NewDurationField(duFieldRetryInitialInterval).
+				Description("The delay before a message that failed to be delivered is first retried.").
+				Default("1s").
+				Advanced(),
+			service.NewDurationField(duFieldRetryMaxInterval).
+				Description("The maximum delay between attempts to deliver a message.").
+				Default("10m").
+				Advanced(),
+		).
+		Example("Scheduled Notifications", "Deliver reminders at the time given within them, routing reminders that have failed ten times to a dead letter topic.", `
+input:
+  kafka_franz:
+    seed_brokers: [ localhost:9092 ]
+    topics: [ reminders ]
+    consumer_group: reminder_scheduler
+
+buffer:
+  delay_until:
+    path: ./reminders.db
+    deliver_at: root = this.remind_at.ts_parse("2006-01-02T15:04:05Z07:00")
+
+output:
+  switch:
+    cases:
+      - check: '@delay_until_attempts.or(0) >= 10'
+        output:
+          kafka_franz:
+            seed_brokers: [ localhost:9092 ]
+            topic: reminders_dlq
+      - output:
+          http_client:
+            url: http://example.com/notify
+            verb: POST
+`)
+}
+
+func init() {
+	err := service.RegisterBatchBuffer(
+		"delay_until", DelayUntilBufferConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchBuffer, error) {
+			return NewDelayUntilBufferFromConfig(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+// NewDelayUntilBufferFromConfig creates a new delay_until buffer from a
+// parsed config.
+func NewDelayUntilBufferFromConfig(conf *service.ParsedConfig, res *service.Resources) (*DelayUntilBuffer, error) {
+	path, err := conf.FieldString(duFieldPath)
+	if err != nil {
+		return nil, err
+	}
+	deliverAt, err := conf.FieldBloblang(duFieldDeliverAt)
+	if err != nil {
+		return nil, err
+	}
+	initialInterval, err := conf.FieldDuration(duFieldRetryInitialInterval)
+	if err != nil {
+		return nil, err
+	}
+	maxInterval, err := conf.FieldDuration(duFieldRetryMaxInterval)
+	if err != nil {
+		return nil, err
+	}
+	if initialInterval <= 0 || maxInterval < initialInterval {
+		return nil, fmt.Errorf("%v must be greater than zero and no more than %v", duFieldRetryInitialInterval, duFieldRetryMaxInterval)
+	}
+	return newDelayUntilBuffer(path, deliverAt, initialInterval, maxInterval)
+}
+
+//------------------------------------------------------------------------------
+
+// DelayUntilBuffer stores messages within an SQLite DB until their delivery
+// times.
+type DelayUntilBuffer struct {
+	db              *sql.DB
+	deliverAt       *bloblang.Executor
+	initialInterval time.Duration
+	maxInterval     time.Duration
+
+	cond       *sync.Cond
+	endOfInput bool
+	closed     bool
+}
+
+func newDelayUntilBuffer(path string, deliverAt *bloblang.Executor, initialInterval, maxInterval time.Duration) (*DelayUntilBuffer, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Messages that were in flight when the buffer was last closed are
+	// delivered again.
+	if _, err = db.Exec(`
+CREATE TABLE IF NOT EXISTS delayed_messages (
+  id          INTEGER PRIMARY KEY AUTOINCREMENT,
+  content     BLOB NOT NULL,
+  deliver_at  INTEGER NOT NULL,
+  attempts    INTEGER NOT NULL DEFAULT 0,
+  in_flight   INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS delayed_messages_deliver_at ON delayed_messages (in_flight, deliver_at, id);
+
+UPDATE delayed_messages SET in_flight = 0;
+`); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	return &DelayUntilBuffer{
+		db:              db,
+		deliverAt:       deliverAt,
+		initialInterval: initialInterval,
+		maxInterval:     maxInterval,
+		cond:            sync.NewCond(&sync.Mutex{}),
+	}, nil
+}
+
+// broadcast wakes pending reads, and holds the lock so that the wake up cannot
+// be missed by a read that is about to wait.
+func (d *DelayUntilBuffer) broadcast() {
+	d.cond.L.Lock()
+	d.cond.Broadcast()
+	d.cond.L.Unlock()
+}
+
+func deliveryTime(v any) (time.Time, error) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, nil
+	case string:
+		return time.Parse(time.RFC3339Nano, t)
+	case json.Number:
+		f, err := t.Float64()
+		if err != nil {
+			return time.Time{}, err
+		}
+		return deliveryTime(f)
+	case int64:
+		return time.Unix(t, 0), nil
+	case uint64:
+		return time.Unix(int64(t), 0), nil
+	case int:
+		return time.Unix(int64(t), 0), nil
+	case float64:
+		return time.Unix(0, int64(t*float64(time.Second))), nil
+	}
+	return time.Time{}, fmt.Errorf("expected a timestamp, got %T", v)
+}
+
+func (d *DelayUntilBuffer) deliveryTimeOf(msg *service.Message) (time.Time, error) {
+	res, err := msg.BloblangQuery(d.deliverAt)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if res == nil {
+		return time.Time{}, errors.New("mapping deleted the delivery time")
+	}
+
+	// Mappings that result in strings produce raw messages rather than
+	// structured ones.
+	v, err := res.AsStructured()
+	if err != nil {
+		b, bErr := res.AsBytes()
+		if bErr != nil {
+			return time.Time{}, bErr
+		}
+		v = string(b)
+	}
+	return deliveryTime(v)
+}
+
+// retryDelay returns the delay before a message that has failed a number of
+// attempts is retried.
+func (d *DelayUntilBuffer) retryDelay(attempts int) time.Duration {
+	delay := d.initialInterval
+	for i := 1; i < attempts && delay < d.maxInterval; i++ {
+		delay *= 2
+	}
+	return min(delay, d.maxInterval)
+}
+
+func (d *DelayUntilBuffer) ackFn(id, attempts int) service.AckFunc {
+	return func(ctx context.Context, err error) (ackErr error) {
+		d.cond.L.Lock()
+		defer d.cond.L.Unlock()
+
+		if d.closed {
+			return errors.New("buffer closed")
+		}
+		if err == nil {
+			_, ackErr = execRetries(ctx, squirrel.Delete("delayed_messages").
+				Where(squirrel.Eq{"id": id}).
+				RunWith(d.db))
+			d.cond.Broadcast()
+			return
+		}
+
+		attempts++
+		_, ackErr = execRetries(ctx, squirrel.Update("delayed_messages").
+			Set("in_flight", 0).
+			Set("attempts", attempts).
+			Set("deliver_at", time.Now().Add(d.retryDelay(attempts)).UnixNano()).
+			Where(squirrel.Eq{"id": id}).
+			RunWith(d.db))
+		d.cond.Broadcast()
+		return
+	}
+}
+
+// ReadBatch waits for the next message to become due, and returns it.
+func (d *DelayUntilBuffer) ReadBatch(ctx context.Context) (service.MessageBatch, service.AckFunc, error) {
+	ctx, done := context.WithCancel(ctx)
+	defer done()
+
+	go func() {
+		<-ctx.Done()
+		d.broadcast()
+	}()
+
+	d.cond.L.Lock()
+	defer d.cond.L.Unlock()
+
+	for {
+		if d.closed {
+			return nil, nil, service.ErrEndOfBuffer
+		}
+		if ctx.Err() != nil {
+			return nil, nil, ctx.Err()
+		}
+
+		var id, attempts int
+		var deliverAt int64
+		var content []byte
+		err := queryRowRetries(ctx, squirrel.Select("id", "content", "attempts", "deliver_at").
+			From("delayed_messages").
+			Where(squirrel.Eq{"in_flight": 0}).
+			OrderBy("deliver_at, id").
+			Limit(1).
+			RunWith(d.db), &id, &content, &attempts, &deliverAt)
+		found := err == nil
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, err
+		}
+
+		if found && deliverAt <= time.Now().UnixNano() {
+			if _, err := execRetries(ctx, squirrel.Update("delayed_messages").
+				Set("in_flight", 1).
+				Where(squirrel.Eq{"id": id}).
+				RunWith(d.db)); err != nil {
+				return nil, nil, err
+			}
+
+			batch, _, err := readBatch(content)
+			if err != nil {
+				return nil, nil, err
+			}
+			if attempts > 0 {
+				for _, m := range batch {
+					m.MetaSetMut("delay_until_attempts", attempts)
+				}
+			}
+			return batch, d.ackFn(id, attempts), nil
+		}
+
+		if !found && d.endOfInput {
+			var remaining int
+			if err := queryRowRetries(ctx, squirrel.Select("COUNT(*)").
+				From("delayed_messages").
+				RunWith(d.db), &remaining); err != nil {
+				return nil, nil, err
+			}
+			if remaining == 0 {
+				return nil, nil, service.ErrEndOfBuffer
+			}
+		}
+
+		var timer *time.Timer
+		if found {
+			timer = time.AfterFunc(time.Until(time.Unix(0, deliverAt)), d.broadcast)
+		}
+		d.cond.Wait()
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+// WriteBatch adds the messages of a batch to the DB along with their delivery
+// times.
+func (d *DelayUntilBuffer) WriteBatch(ctx context.Context, msgBatch service.MessageBatch, aFn service.AckFunc) error {
+	builder := squirrel.Insert("delayed_messages").Columns("content", "deliver_at")
+	for i, msg := range msgBatch {
+		deliverAt, err := d.deliveryTimeOf(msg)
+		if err != nil {
+			return fmt.Errorf("failed to execute %v mapping for message %v: %w", duFieldDeliverAt, i, err)
+		}
+
+		contentBytes, err := appendBatchV0(nil, service.MessageBatch{msg})
+		if err != nil {
+			return err
+		}
+		builder = builder.Values(contentBytes, deliverAt.UnixNano())
+	}
+
+	d.cond.L.Lock()
+	defer d.cond.L.Unlock()
+
+	if d.closed {
+		return service.ErrEndOfBuffer
+	}
+	if len(msgBatch) > 0 {
+		if _, err := execRetries(ctx, builder.RunWith(d.db)); err != nil {
+			return err
+		}
+	}
+	if err := aFn(ctx, nil); err != nil {
+		return err
+	}
+
+	d.cond.Broadcast()
+	return nil
+}
+
+// EndOfInput signals to the buffer that the input is finished and therefore
+// once all messages have been delivered it should close.
+func (d *DelayUntilBuffer) EndOfInput() {
+	go func() {
+		d.cond.L.Lock()
+		defer d.cond.L.Unlock()
+
+		d.endOfInput = true
+		d.cond.Broadcast()
+	}()
+}
+
+// Close the underlying DB connection.
+func (d *DelayUntilBuffer) Close(ctx context.Context) error {
+	d.cond.L.Lock()
+	defer d.cond.L.Unlock()
+
+	d.closed = true
+	d.cond.Broadcast()
+	return d.db.Close()
+}
@@ -0,0 +1,155 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sql_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+
+	"github.com/redpanda-data/connect/v4/internal/impl/sql"
+)
+
+func delayBufFromConf(t testing.TB, conf string) *sql.DelayUntilBuffer {
+	t.Helper()
+
+	parsedConf, err := sql.DelayUntilBufferConfig().ParseYAML(conf, nil)
+	require.NoError(t, err)
+
+	buf, err := sql.NewDelayUntilBufferFromConfig(parsedConf, service.MockResources())
+	require.NoError(t, err)
+	return buf
+}
+
+func noopAck(context.Context, error) error { return nil }
+
+func TestDelayUntilBufferOrdering(t *testing.T) {
+	tCtx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	path := filepath.Join(t.TempDir(), "delay.db")
+	buf := delayBufFromConf(t, fmt.Sprintf(`
+path: %v
+deliver_at: root = this.at
+`, path))
+	defer buf.Close(tCtx)
+
+	now := float64(time.Now().UnixNano()) / float64(time.Second)
+	require.NoError(t, buf.WriteBatch(tCtx, service.MessageBatch{
+		service.NewMessage([]byte(fmt.Sprintf(`{"id":"later","at":%v}`, now+0.3))),
+		service.NewMessage([]byte(fmt.Sprintf(`{"id":"now","at":%v}`, now-1))),
+	}, noopAck))
+
+	start := time.Now()
+
+	batch, aFn, err := buf.ReadBatch(tCtx)
+	require.NoError(t, err)
+	require.Len(t, batch, 1)
+	assert.Contains(t, string(mustBytes(t, batch[0])), `"now"`)
+	require.NoError(t, aFn(tCtx, nil))
+
+	batch, aFn, err = buf.ReadBatch(tCtx)
+	require.NoError(t, err)
+	require.Len(t, batch, 1)
+	assert.Contains(t, string(mustBytes(t, batch[0])), `"later"`)
+	assert.GreaterOrEqual(t, time.Since(start), time.Millisecond*250)
+	require.NoError(t, aFn(tCtx, nil))
+
+	buf.EndOfInput()
+	_, _, err = buf.ReadBatch(tCtx)
+	require.ErrorIs(t, err, service.ErrEndOfBuffer)
+}
+
+func TestDelayUntilBufferRetries(t *testing.T) {
+	tCtx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	buf := delayBufFromConf(t, fmt.Sprintf(`
+path: %v
+retry_initial_interval: 100ms
+retry_max_interval: 150ms
+`, filepath.Join(t.TempDir(), "delay.db")))
+	defer buf.Close(tCtx)
+
+	msg := service.NewMessage([]byte("hello"))
+	msg.MetaSetMut("foo", "bar")
+	require.NoError(t, buf.WriteBatch(tCtx, service.MessageBatch{msg}, noopAck))
+
+	var last time.Time
+	for i := 0; i < 3; i++ {
+		batch, aFn, err := buf.ReadBatch(tCtx)
+		require.NoError(t, err)
+		require.Len(t, batch, 1)
+		msgEqualStr(t, "hello", batch[0])
+
+		v, _ := batch[0].MetaGet("foo")
+		assert.Equal(t, "bar", v)
+
+		attempts, exists := batch[0].MetaGetMut("delay_until_attempts")
+		if i == 0 {
+			assert.False(t, exists)
+		} else {
+			assert.EqualValues(t, i, attempts)
+			assert.GreaterOrEqual(t, time.Since(last), time.Millisecond*90)
+		}
+		last = time.Now()
+		require.NoError(t, aFn(tCtx, errors.New("nope")))
+	}
+}
+
+func TestDelayUntilBufferPersistence(t *testing.T) {
+	tCtx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	conf := fmt.Sprintf(`
+path: %v
+`, filepath.Join(t.TempDir(), "delay.db"))
+
+	buf := delayBufFromConf(t, conf)
+	require.NoError(t, buf.WriteBatch(tCtx, service.MessageBatch{
+		service.NewMessage([]byte("first")),
+	}, noopAck))
+
+	// Reading without acknowledging leaves the message in flight when the
+	// buffer is closed.
+	_, _, err := buf.ReadBatch(tCtx)
+	require.NoError(t, err)
+	require.NoError(t, buf.Close(tCtx))
+
+	buf = delayBufFromConf(t, conf)
+	defer buf.Close(tCtx)
+
+	batch, aFn, err := buf.ReadBatch(tCtx)
+	require.NoError(t, err)
+	require.Len(t, batch, 1)
+	msgEqualStr(t, "first", batch[0])
+	require.NoError(t, aFn(tCtx, nil))
+}
+
+func mustBytes(t testing.TB, m *service.Message) []byte {
+	t.Helper()
+
+	b, err := m.AsBytes()
+	require.NoError(t, err)
+	return b
+}
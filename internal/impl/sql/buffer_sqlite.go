@@ -29,6 +29,8 @@ import (
 	"github.com/vmihailenco/msgpack/v5"
 
 	"github.com/redpanda-data/benthos/v4/public/service"
+
+	"github.com/redpanda-data/connect/v4/internal/encryption"
 )
 
 // SQLiteBufferConfig returns a config spec for an SQLite buffer.
@@ -47,6 +49,10 @@ Messages are not acknowledged at the input level until they have been added to t
 == Batching
 
 Messages that are logically batched at the point where they are added to the buffer will continue to be associated with that batch when they are consumed. This buffer is also more efficient when storing messages within batches, and therefore it is recommended to use batching at the input level in high-throughput use cases even if they are not required for processing.
+
+== Encryption
+
+When `+"`encryption.key`"+` is set messages are encrypted with AES-GCM before they are written to the database, and therefore spooled data is not stored in plaintext. Messages stored before encryption was enabled, or with a different key, cannot be read, and so the buffer should be drained before the key is changed.
 `).
 		Field(service.NewStringField("path").
 			Description(`The path of the database file, which will be created if it does not already exist.`)).
@@ -56,6 +62,7 @@ Messages that are logically batched at the point where they are added to the buf
 		Field(service.NewProcessorListField("post_processors").
 			Description("An optional list of processors to apply to messages after they are consumed from the buffer. These processors are useful for undoing any compression, archiving, etc that may have been done by your `pre_processors`.").
 			Optional()).
+		Field(encryption.AESGCMField("encryption")).
 		Example("Batching for optimization", "Batching at the input level greatly increases the throughput of this buffer. If logical batches aren't needed for processing add a xref:components:processors/split.adoc[`split` processor] to the `post_processors`.", `
 input:
   batched:
@@ -109,7 +116,12 @@ func NewSQLiteBufferFromConfig(conf *service.ParsedConfig, res *service.Resource
 		}
 	}
 
-	return newSQLiteBuffer(path, preProcs, postProcs)
+	envelope, err := encryption.AESGCMFromParsed(conf.Namespace("encryption"))
+	if err != nil {
+		return nil, err
+	}
+
+	return newSQLiteBuffer(path, preProcs, postProcs, envelope)
 }
 
 //------------------------------------------------------------------------------
@@ -119,6 +131,7 @@ type SQLiteBuffer struct {
 	db        *sql.DB
 	preProcs  []*service.OwnedProcessor
 	postProcs []*service.OwnedProcessor
+	envelope  *encryption.Envelope
 
 	pending     []ackableBatch
 	cond        *sync.Cond
@@ -128,7 +141,7 @@ type SQLiteBuffer struct {
 	closed      bool
 }
 
-func newSQLiteBuffer(path string, preProcs, postProcs []*service.OwnedProcessor) (*SQLiteBuffer, error) {
+func newSQLiteBuffer(path string, preProcs, postProcs []*service.OwnedProcessor, envelope *encryption.Envelope) (*SQLiteBuffer, error) {
 	db, err := sql.Open("sqlite", path)
 	if err != nil {
 		return nil, err
@@ -150,6 +163,7 @@ CREATE TABLE IF NOT EXISTS messages (
 		db:        db,
 		preProcs:  preProcs,
 		postProcs: postProcs,
+		envelope:  envelope,
 		cond:      sync.NewCond(&sync.Mutex{}),
 	}, nil
 }
@@ -185,6 +199,13 @@ func (m *SQLiteBuffer) tryGetBatch(ctx context.Context) (service.MessageBatch, i
 	}
 	m.nextIndex = index + 1
 
+	if m.envelope != nil {
+		var err error
+		if contentBytes, err = m.envelope.Open(contentBytes, nil); err != nil {
+			return nil, 0, err
+		}
+	}
+
 	batch, _, err := readBatch(contentBytes)
 	return batch, index, err
 }
@@ -328,6 +349,11 @@ func (m *SQLiteBuffer) WriteBatch(ctx context.Context, msgBatch service.MessageB
 		if err != nil {
 			return err
 		}
+		if m.envelope != nil {
+			if contentBytes, err = m.envelope.Seal(contentBytes, nil); err != nil {
+				return err
+			}
+		}
 		builder = builder.Values(contentBytes, maxRequeue)
 	}
 
@@ -18,6 +18,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
 	"path/filepath"
 	"strings"
 	"sync"
@@ -110,6 +111,45 @@ path: "%v"
 	}
 }
 
+func TestBufferSQLiteEncryption(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "foo.db")
+
+	ctx := context.Background()
+	block := memBufFromConf(t, fmt.Sprintf(`
+path: "%v"
+encryption:
+  key: 000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f
+`, dbPath))
+
+	for i := 0; i < 10; i++ {
+		require.NoError(t, block.WriteBatch(ctx, service.MessageBatch{
+			service.NewMessage([]byte(fmt.Sprintf("secret message %v", i))),
+		}, func(ctx context.Context, err error) error { return nil }))
+	}
+
+	dbBytes, err := os.ReadFile(dbPath)
+	require.NoError(t, err)
+	assert.NotContains(t, string(dbBytes), "secret message")
+
+	m, ackFunc, err := block.ReadBatch(ctx)
+	require.NoError(t, err)
+	require.Len(t, m, 1)
+	msgEqualStr(t, "secret message 0", m[0])
+	require.NoError(t, ackFunc(ctx, nil))
+	require.NoError(t, block.Close(ctx))
+
+	block = memBufFromConf(t, fmt.Sprintf(`
+path: "%v"
+encryption:
+  key: 1f1e1d1c1b1a191817161514131211100f0e0d0c0b0a09080706050403020100
+`, dbPath))
+	defer block.Close(ctx)
+
+	_, _, err = block.ReadBatch(ctx)
+	require.Error(t, err)
+}
+
 func TestBufferSQLiteBatchPreservation(t *testing.T) {
 	tmpDir := t.TempDir()
 
@@ -65,6 +65,7 @@ func sqlSelectInputConfig() *service.ConfigSpec {
 	for _, f := range connFields() {
 		spec = spec.Field(f)
 	}
+	spec = spec.Field(readReplicasField())
 
 	spec = spec.
 		Version("3.59.0").
@@ -109,6 +110,7 @@ type sqlSelectInput struct {
 	driver  string
 	dsn     string
 	db      *sql.DB
+	router  *replicaRouter
 	rows    *sql.Rows
 	builder squirrel.SelectBuilder
 	dbMut   sync.Mutex
@@ -116,7 +118,8 @@ type sqlSelectInput struct {
 	where       string
 	argsMapping *bloblang.Executor
 
-	connSettings *connSettings
+	connSettings    *connSettings
+	replicaSettings *replicaSettings
 
 	logger  *service.Logger
 	shutSig *shutdown.Signaller
@@ -186,6 +189,9 @@ func newSQLSelectInputFromConfig(conf *service.ParsedConfig, mgr *service.Resour
 	if s.connSettings, err = connSettingsFromParsed(conf, mgr); err != nil {
 		return nil, err
 	}
+	if s.replicaSettings, err = replicaSettingsFromParsed(conf); err != nil {
+		return nil, err
+	}
 	return s, nil
 }
 
@@ -209,6 +215,16 @@ func (s *sqlSelectInput) Connect(ctx context.Context) (err error) {
 
 	s.connSettings.apply(ctx, db, s.logger)
 
+	var router *replicaRouter
+	if router, err = s.replicaSettings.open(ctx, s.logger, s.driver, db, s.connSettings); err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			_ = router.Close()
+		}
+	}()
+
 	var args []any
 	if s.argsMapping != nil {
 		var iargs any
@@ -228,13 +244,16 @@ func (s *sqlSelectInput) Connect(ctx context.Context) (err error) {
 		queryBuilder = queryBuilder.Where(s.where, args...)
 	}
 	var rows *sql.Rows
-	if rows, err = queryBuilder.RunWith(db).Query(); err != nil {
+	if rows, err = router.query(func(db *sql.DB) (*sql.Rows, error) {
+		return queryBuilder.RunWith(db).Query()
+	}); err != nil {
 		return
 	} else if err = rows.Err(); err != nil {
 		s.logger.With("err", err).Warn("unexpected error while execute raw select")
 	}
 
 	s.db = db
+	s.router = router
 	s.rows = rows
 
 	go func() {
@@ -245,6 +264,9 @@ func (s *sqlSelectInput) Connect(ctx context.Context) (err error) {
 			_ = s.rows.Close()
 			s.rows = nil
 		}
+		if s.router != nil {
+			_ = s.router.Close()
+		}
 		if s.db != nil {
 			_ = s.db.Close()
 		}
@@ -67,6 +67,7 @@ If the query fails to execute then the message will remain unchanged and the err
 	for _, f := range connFields() {
 		spec = spec.Field(f)
 	}
+	spec = spec.Field(readReplicasField())
 
 	spec = spec.Version("3.59.0").
 		Example("Table Query (PostgreSQL)",
@@ -108,6 +109,7 @@ func init() {
 
 type sqlSelectProcessor struct {
 	db      *sql.DB
+	router  *replicaRouter
 	builder squirrel.SelectBuilder
 	dbMut   sync.RWMutex
 
@@ -185,15 +187,26 @@ func NewSQLSelectProcessorFromConfig(conf *service.ParsedConfig, mgr *service.Re
 		return nil, err
 	}
 
+	replicaSettings, err := replicaSettingsFromParsed(conf)
+	if err != nil {
+		return nil, err
+	}
+
 	if s.db, err = sqlOpenWithReworks(mgr.Logger(), driverStr, dsnStr); err != nil {
 		return nil, err
 	}
 	connSettings.apply(context.Background(), s.db, s.logger)
 
+	if s.router, err = replicaSettings.open(context.Background(), s.logger, driverStr, s.db, connSettings); err != nil {
+		_ = s.db.Close()
+		return nil, err
+	}
+
 	go func() {
 		<-s.shutSig.HardStopChan()
 
 		s.dbMut.Lock()
+		_ = s.router.Close()
 		_ = s.db.Close()
 		s.dbMut.Unlock()
 
@@ -242,7 +255,9 @@ func (s *sqlSelectProcessor) ProcessBatch(ctx context.Context, batch service.Mes
 			queryBuilder = queryBuilder.Where(s.where, args...)
 		}
 
-		rows, err := queryBuilder.RunWith(s.db).QueryContext(ctx)
+		rows, err := s.router.query(func(db *sql.DB) (*sql.Rows, error) {
+			return queryBuilder.RunWith(db).QueryContext(ctx)
+		})
 		if err != nil {
 			s.logger.Debugf("Failed to run query: %v", err)
 			msg.SetError(err)
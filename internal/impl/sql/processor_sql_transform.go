@@ -0,0 +1,246 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+// TransformProcessorConfig returns a config spec for an sql_transform
+// processor.
+func TransformProcessorConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Mapping").
+		Version("4.45.0").
+		Summary("Loads a batch of messages into a table of an in-process SQLite database and replaces the batch with the rows resulting from an SQL query against it.").
+		Description(`
+Each message of a batch must be a JSON object, and is inserted as a row of the table, with a column for each key found within the batch. Keys missing from a message are `+"`NULL`"+`, booleans are stored as `+"`1`"+` or `+"`0`"+`, and nested objects and arrays are stored as JSON text, which can be queried with the https://www.sqlite.org/json1.html[JSON functions^] of SQLite.
+
+The query can make use of any features of SQLite, including joins, aggregations and window functions, and each row of the result becomes a message of the new batch in the form of a JSON object. Resulting messages do not retain the metadata of the input messages, and a query that returns no rows results in the batch being removed.
+
+Each batch is loaded into a new database held in memory, and so no state is carried between batches. If the query fails the batch remains unchanged and the error can be caught using xref:configuration:error_handling.adoc[error handling methods].
+
+This processor is only available on platforms supported by the `+"`sqlite`"+` driver.`).
+		Field(service.NewStringField("query").
+			Description("The SQL query to execute against the table.").
+			Example("SELECT customer, SUM(amount) AS total FROM batch GROUP BY customer").
+			Example("SELECT *, ROW_NUMBER() OVER (PARTITION BY customer ORDER BY ts) AS seq FROM batch")).
+		Field(service.NewStringField("table").
+			Description("The name of the table that messages are loaded into.").
+			Default("batch").
+			Advanced()).
+		Example("Aggregation", "Summarise windows of orders by customer.", `
+input:
+  kafka_franz:
+    seed_brokers: [ localhost:9092 ]
+    topics: [ orders ]
+    consumer_group: order_totals
+    batching:
+      period: 10s
+
+pipeline:
+  processors:
+    - sql_transform:
+        query: |
+          SELECT customer, COUNT(*) AS orders, SUM(amount) AS total
+          FROM batch
+          GROUP BY customer
+          ORDER BY total DESC
+`).
+		Example("Joining Nested Data", "Flatten the line items of orders with a join against a JSON table function.", `
+pipeline:
+  processors:
+    - sql_transform:
+        query: |
+          SELECT o.id AS order_id, json_extract(i.value, '$.sku') AS sku
+          FROM batch AS o, json_each(o.items) AS i
+`)
+}
+
+func init() {
+	err := service.RegisterBatchProcessor(
+		"sql_transform", TransformProcessorConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchProcessor, error) {
+			return NewSQLTransformProcessorFromConfig(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type sqlTransformProcessor struct {
+	query string
+	table string
+}
+
+// NewSQLTransformProcessorFromConfig returns an internal sql_transform
+// processor.
+func NewSQLTransformProcessorFromConfig(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchProcessor, error) {
+	query, err := conf.FieldString("query")
+	if err != nil {
+		return nil, err
+	}
+	table, err := conf.FieldString("table")
+	if err != nil {
+		return nil, err
+	}
+	return &sqlTransformProcessor{query: query, table: table}, nil
+}
+
+func quoteSQLiteIdentifier(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+// sqliteValue converts a structured value into one that can be stored within
+// an SQLite column.
+func sqliteValue(v any) (any, error) {
+	switch t := v.(type) {
+	case nil, string, int64, float64:
+		return t, nil
+	case bool:
+		if t {
+			return int64(1), nil
+		}
+		return int64(0), nil
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return i, nil
+		}
+		return t.Float64()
+	case int:
+		return int64(t), nil
+	case map[string]any, []any:
+		b, err := json.Marshal(t)
+		if err != nil {
+			return nil, err
+		}
+		return string(b), nil
+	}
+	return fmt.Sprintf("%v", v), nil
+}
+
+func (s *sqlTransformProcessor) ProcessBatch(ctx context.Context, batch service.MessageBatch) ([]service.MessageBatch, error) {
+	var columns []string
+	seen := map[string]struct{}{}
+	rows := make([]map[string]any, len(batch))
+	for i, msg := range batch {
+		v, err := msg.AsStructured()
+		if err != nil {
+			return nil, fmt.Errorf("message %v: %w", i, err)
+		}
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("message %v: expected a JSON object, got %T", i, v)
+		}
+		for k := range obj {
+			if _, exists := seen[k]; !exists {
+				seen[k] = struct{}{}
+				columns = append(columns, k)
+			}
+		}
+		rows[i] = obj
+	}
+	if len(columns) == 0 {
+		return nil, errors.New("batch contains no columns")
+	}
+
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	// Each connection to an in-memory database is a different database.
+	db.SetMaxOpenConns(1)
+
+	if err := s.load(ctx, db, columns, rows); err != nil {
+		return nil, err
+	}
+
+	res, err := db.QueryContext(ctx, s.query)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close()
+
+	resRows, err := sqlRowsToArray(res)
+	if err != nil {
+		return nil, err
+	}
+	if len(resRows) == 0 {
+		return nil, nil
+	}
+
+	newBatch := make(service.MessageBatch, len(resRows))
+	for i, row := range resRows {
+		newBatch[i] = service.NewMessage(nil)
+		newBatch[i].SetStructuredMut(row)
+	}
+	return []service.MessageBatch{newBatch}, nil
+}
+
+func (s *sqlTransformProcessor) load(ctx context.Context, db *sql.DB, columns []string, rows []map[string]any) error {
+	quoted := make([]string, len(columns))
+	for i, c := range columns {
+		quoted[i] = quoteSQLiteIdentifier(c)
+	}
+	table := quoteSQLiteIdentifier(s.table)
+
+	if _, err := db.ExecContext(ctx, fmt.Sprintf("CREATE TABLE %v (%v)", table, strings.Join(quoted, ", "))); err != nil {
+		return fmt.Errorf("failed to create table: %w", err)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	stmt, err := tx.PrepareContext(ctx, fmt.Sprintf("INSERT INTO %v (%v) VALUES (%v)",
+		table, strings.Join(quoted, ", "), strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ")))
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	args := make([]any, len(columns))
+	for i, row := range rows {
+		for j, c := range columns {
+			if args[j], err = sqliteValue(row[c]); err != nil {
+				return fmt.Errorf("message %v: %w", i, err)
+			}
+		}
+		if _, err := stmt.ExecContext(ctx, args...); err != nil {
+			return fmt.Errorf("failed to load message %v: %w", i, err)
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *sqlTransformProcessor) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,113 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sql_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+
+	isql "github.com/redpanda-data/connect/v4/internal/impl/sql"
+	_ "github.com/redpanda-data/connect/v4/public/components/sql"
+)
+
+func runSQLTransform(t *testing.T, conf string, docs ...string) ([]service.MessageBatch, error) {
+	t.Helper()
+
+	pConf, err := isql.TransformProcessorConfig().ParseYAML(conf, nil)
+	require.NoError(t, err)
+
+	proc, err := isql.NewSQLTransformProcessorFromConfig(pConf, service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, proc.Close(context.Background()))
+	})
+
+	var batch service.MessageBatch
+	for _, d := range docs {
+		batch = append(batch, service.NewMessage([]byte(d)))
+	}
+	return proc.ProcessBatch(context.Background(), batch)
+}
+
+func sqlTransformResults(t *testing.T, batches []service.MessageBatch) []string {
+	t.Helper()
+
+	require.Len(t, batches, 1)
+	var res []string
+	for _, m := range batches[0] {
+		b, err := m.AsBytes()
+		require.NoError(t, err)
+		res = append(res, string(b))
+	}
+	return res
+}
+
+func TestSQLTransformAggregation(t *testing.T) {
+	batches, err := runSQLTransform(t, `
+query: |
+  SELECT customer, COUNT(*) AS orders, SUM(amount) AS total, MAX(priority) AS priority
+  FROM batch
+  GROUP BY customer
+  ORDER BY customer
+`,
+		`{"customer":"a","amount":10,"priority":true}`,
+		`{"customer":"b","amount":2.5}`,
+		`{"customer":"a","amount":5,"priority":false}`,
+	)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		`{"customer":"a","orders":2,"priority":1,"total":15}`,
+		`{"customer":"b","orders":1,"priority":null,"total":2.5}`,
+	}, sqlTransformResults(t, batches))
+}
+
+func TestSQLTransformWindowAndJSON(t *testing.T) {
+	batches, err := runSQLTransform(t, `
+table: orders
+query: |
+  SELECT o.id, json_extract(i.value, '$.sku') AS sku,
+    ROW_NUMBER() OVER (PARTITION BY o.id ORDER BY i.key) AS seq
+  FROM orders AS o, json_each(o.items) AS i
+  ORDER BY o.id, seq
+`,
+		`{"id":"x","items":[{"sku":"foo"},{"sku":"bar"}]}`,
+		`{"id":"y","items":[{"sku":"baz"}]}`,
+	)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		`{"id":"x","seq":1,"sku":"foo"}`,
+		`{"id":"x","seq":2,"sku":"bar"}`,
+		`{"id":"y","seq":1,"sku":"baz"}`,
+	}, sqlTransformResults(t, batches))
+}
+
+func TestSQLTransformNoRows(t *testing.T) {
+	batches, err := runSQLTransform(t, `query: SELECT * FROM batch WHERE id = 'nope'`, `{"id":"a"}`)
+	require.NoError(t, err)
+	assert.Empty(t, batches)
+}
+
+func TestSQLTransformErrors(t *testing.T) {
+	_, err := runSQLTransform(t, `query: SELECT * FROM batch`, `{"id":"a"}`, `["not","an","object"]`)
+	require.EqualError(t, err, "message 1: expected a JSON object, got []interface {}")
+
+	_, err = runSQLTransform(t, `query: SELECT nope FROM batch`, `{"id":"a"}`)
+	require.ErrorContains(t, err, "no such column: nope")
+}
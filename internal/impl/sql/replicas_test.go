@@ -0,0 +1,118 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sql_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+
+	isql "github.com/redpanda-data/connect/v4/internal/impl/sql"
+)
+
+func createReplicaTestDB(t *testing.T, path, content string) {
+	t.Helper()
+
+	db, err := sql.Open("sqlite", "file:"+path)
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec(`CREATE TABLE things (source varchar(50) not null)`)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO things (source) VALUES (?)`, content)
+	require.NoError(t, err)
+}
+
+func TestSQLSelectReadReplicas(t *testing.T) {
+	tmpDir := t.TempDir()
+	createReplicaTestDB(t, filepath.Join(tmpDir, "primary.db"), "primary")
+	createReplicaTestDB(t, filepath.Join(tmpDir, "replica.db"), "replica")
+
+	tests := []struct {
+		name     string
+		replicas string
+		expected string
+	}{
+		{
+			name: "healthy replica",
+			replicas: fmt.Sprintf(`
+  dsns: [ "file:%v/replica.db" ]
+`, tmpDir),
+			expected: "replica",
+		},
+		{
+			name: "lagging replica",
+			replicas: fmt.Sprintf(`
+  dsns: [ "file:%v/replica.db" ]
+  lag_query: SELECT 120
+  max_lag: 1m
+`, tmpDir),
+			expected: "primary",
+		},
+		{
+			name: "unreachable replica",
+			replicas: fmt.Sprintf(`
+  dsns: [ "file:%v/nope/replica.db?mode=ro", "file:%v/replica.db" ]
+  routing: ordered
+`, tmpDir, tmpDir),
+			expected: "replica",
+		},
+		{
+			name:     "no replicas",
+			replicas: `{}`,
+			expected: "primary",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			conf, err := isql.SelectProcessorConfig().ParseYAML(fmt.Sprintf(`
+driver: sqlite
+dsn: file:%v/primary.db
+table: things
+columns: [ source ]
+read_replicas: %v
+`, tmpDir, test.replicas), nil)
+			require.NoError(t, err)
+
+			proc, err := isql.NewSQLSelectProcessorFromConfig(conf, service.MockResources())
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+				defer done()
+				require.NoError(t, proc.Close(ctx))
+			})
+
+			batches, err := proc.ProcessBatch(context.Background(), service.MessageBatch{service.NewMessage(nil)})
+			require.NoError(t, err)
+			require.Len(t, batches, 1)
+			require.Len(t, batches[0], 1)
+			require.NoError(t, batches[0][0].GetError())
+
+			v, err := batches[0][0].AsStructured()
+			require.NoError(t, err)
+			assert.Equal(t, []any{map[string]any{"source": test.expected}}, v)
+		})
+	}
+}
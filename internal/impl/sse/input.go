@@ -0,0 +1,491 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sse
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	siFieldURL           = "url"
+	siFieldHeaders       = "headers"
+	siFieldEventTypes    = "event_types"
+	siFieldLastEventID   = "last_event_id"
+	siFieldRetryInterval = "retry_interval"
+	siFieldCache         = "cache"
+	siFieldCacheKey      = "cache_key"
+	siFieldTLS           = "tls"
+)
+
+func inputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Categories("Network").
+		Version("4.45.0").
+		Summary("Consumes events from an HTTP endpoint that streams https://html.spec.whatwg.org/multipage/server-sent-events.html[Server-Sent Events^].").
+		Description(`
+Each event received becomes a message containing the data of the event. Comments and events without data are ignored.
+
+When the connection is closed by the server, or fails, the input reconnects after the `+"`retry_interval`"+`, or the reconnection time specified by the server with a `+"`retry`"+` field. Reconnection requests include a `+"`Last-Event-ID`"+` header with the identifier of the last event received, allowing servers that support it to resume the stream. A server responding with the status code 204 indicates that the stream has ended, at which point the input shuts down.
+
+== Resuming across restarts
+
+By default the last event identifier is only retained for the lifetime of the input. When a `+"`cache`"+` is specified the identifier of each acknowledged event is stored within it, and used for the first connection once the input is restarted, the cache should therefore be persisted across restarts.
+
+== Metadata
+
+This input adds the following metadata fields to each message:
+
+- sse_event
+- sse_id
+
+You can access these metadata fields using xref:configuration:interpolation.adoc#bloblang-queries[function interpolation].`).
+		Fields(
+			service.NewURLField(siFieldURL).
+				Description("The URL of the event stream.").
+				Example("https://api.example.com/v1/stream"),
+			service.NewStringMapField(siFieldHeaders).
+				Description("A map of headers to add to each request.").
+				Example(map[string]any{"Authorization": "Bearer ${API_TOKEN}"}).
+				Default(map[string]any{}),
+			service.NewStringListField(siFieldEventTypes).
+				Description("An optional list of event types to consume, where events without an `event` field have the type `message`. When empty all events are consumed.").
+				Example([]string{"message", "update"}).
+				Default([]any{}),
+			service.NewStringField(siFieldLastEventID).
+				Description("An optional event identifier to resume from when first connecting, which is sent as the `Last-Event-ID` header. A value stored in the `cache` takes precedence.").
+				Default("").
+				Advanced(),
+			service.NewDurationField(siFieldRetryInterval).
+				Description("The period to wait before reconnecting, unless the server specifies a reconnection time.").
+				Default("3s"),
+			service.NewStringField(siFieldCache).
+				Description("An optional cache resource used to store the identifier of the last event acknowledged, allowing the stream to be resumed after a restart.").
+				Default("").
+				Advanced(),
+			service.NewStringField(siFieldCacheKey).
+				Description("The key within the cache used to store the last event identifier.").
+				Default("sse_last_event_id").
+				Advanced(),
+			service.NewTLSToggledField(siFieldTLS),
+			service.NewAutoRetryNacksToggleField(),
+		).
+		Example("Consume Updates", "Consume only `update` events from a stream, resuming from the last event acknowledged after restarts.", `
+input:
+  sse:
+    url: https://api.example.com/v1/stream
+    event_types: [ update ]
+    cache: event_ids
+
+cache_resources:
+  - label: event_ids
+    file:
+      directory: ./sse_state
+`)
+}
+
+func init() {
+	err := service.RegisterInput("sse", inputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Input, error) {
+			i, err := newInputFromParsed(conf, mgr)
+			if err != nil {
+				return nil, err
+			}
+			return service.AutoRetryNacksToggled(conf, i)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type event struct {
+	id        string
+	eventType string
+	data      string
+}
+
+type input struct {
+	url           string
+	headers       map[string]string
+	eventTypes    map[string]struct{}
+	retryInterval time.Duration
+	cache         string
+	cacheKey      string
+	client        *http.Client
+
+	mgr *service.Resources
+	log *service.Logger
+
+	events chan event
+
+	mut         sync.Mutex
+	lastEventID string
+	retryDelay  time.Duration
+	connected   bool
+	ended       bool
+	loaded      bool
+	cancelFn    context.CancelFunc
+	done        chan struct{}
+}
+
+func newInputFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*input, error) {
+	i := &input{
+		mgr:    mgr,
+		log:    mgr.Logger(),
+		events: make(chan event),
+	}
+
+	var err error
+	if i.url, err = conf.FieldString(siFieldURL); err != nil {
+		return nil, err
+	}
+	if i.headers, err = conf.FieldStringMap(siFieldHeaders); err != nil {
+		return nil, err
+	}
+
+	eventTypes, err := conf.FieldStringList(siFieldEventTypes)
+	if err != nil {
+		return nil, err
+	}
+	if len(eventTypes) > 0 {
+		i.eventTypes = map[string]struct{}{}
+		for _, t := range eventTypes {
+			i.eventTypes[t] = struct{}{}
+		}
+	}
+
+	if i.lastEventID, err = conf.FieldString(siFieldLastEventID); err != nil {
+		return nil, err
+	}
+	if i.retryInterval, err = conf.FieldDuration(siFieldRetryInterval); err != nil {
+		return nil, err
+	}
+	if i.cache, err = conf.FieldString(siFieldCache); err != nil {
+		return nil, err
+	}
+	if i.cache != "" && !mgr.HasCache(i.cache) {
+		return nil, fmt.Errorf("cache resource '%v' was not found", i.cache)
+	}
+	if i.cacheKey, err = conf.FieldString(siFieldCacheKey); err != nil {
+		return nil, err
+	}
+
+	tlsConf, tlsEnabled, err := conf.FieldTLSToggled(siFieldTLS)
+	if err != nil {
+		return nil, err
+	}
+	i.client = &http.Client{}
+	if tlsEnabled && tlsConf != nil {
+		if c, ok := http.DefaultTransport.(*http.Transport); ok {
+			cloned := c.Clone()
+			cloned.TLSClientConfig = tlsConf
+			i.client.Transport = cloned
+		} else {
+			i.client.Transport = &http.Transport{
+				TLSClientConfig: tlsConf,
+			}
+		}
+	}
+	return i, nil
+}
+
+func (i *input) loadLastEventID(ctx context.Context) error {
+	if i.cache == "" {
+		return nil
+	}
+
+	var idBytes []byte
+	var cacheErr error
+	if err := i.mgr.AccessCache(ctx, i.cache, func(c service.Cache) {
+		idBytes, cacheErr = c.Get(ctx, i.cacheKey)
+	}); err != nil {
+		return err
+	}
+	if cacheErr != nil {
+		if errors.Is(cacheErr, service.ErrKeyNotFound) {
+			return nil
+		}
+		return cacheErr
+	}
+	i.lastEventID = string(idBytes)
+	return nil
+}
+
+func (i *input) Connect(ctx context.Context) error {
+	i.mut.Lock()
+	defer i.mut.Unlock()
+
+	if i.connected {
+		return nil
+	}
+	if i.ended {
+		return service.ErrEndOfInput
+	}
+	if !i.loaded {
+		if err := i.loadLastEventID(ctx); err != nil {
+			return fmt.Errorf("failed to read last event id from cache: %w", err)
+		}
+		i.loaded = true
+	}
+
+	// Wait for the reconnection time when a previous stream has ended.
+	if i.done != nil {
+		delay := i.retryInterval
+		if i.retryDelay > 0 {
+			delay = i.retryDelay
+		}
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	streamCtx, cancelFn := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(streamCtx, http.MethodGet, i.url, http.NoBody)
+	if err != nil {
+		cancelFn()
+		return err
+	}
+	for k, v := range i.headers {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Cache-Control", "no-cache")
+	if i.lastEventID != "" {
+		req.Header.Set("Last-Event-ID", i.lastEventID)
+	}
+
+	// Cancel the request if the connect context ends before a response.
+	stopWatch := context.AfterFunc(ctx, cancelFn)
+	res, err := i.client.Do(req)
+	stopWatch()
+	if err != nil {
+		cancelFn()
+		return err
+	}
+
+	if res.StatusCode == http.StatusNoContent {
+		res.Body.Close()
+		cancelFn()
+		i.ended = true
+		return service.ErrEndOfInput
+	}
+	if res.StatusCode != http.StatusOK {
+		res.Body.Close()
+		cancelFn()
+		return fmt.Errorf("stream returned unexpected response code: %v", res.StatusCode)
+	}
+	if mediaType, _, _ := mime.ParseMediaType(res.Header.Get("Content-Type")); mediaType != "text/event-stream" {
+		res.Body.Close()
+		cancelFn()
+		return fmt.Errorf("stream returned unexpected content type: %v", res.Header.Get("Content-Type"))
+	}
+
+	i.connected = true
+	i.cancelFn = cancelFn
+	i.done = make(chan struct{})
+	go i.readLoop(streamCtx, res.Body, i.lastEventID, i.done)
+	return nil
+}
+
+func (i *input) readLoop(ctx context.Context, body io.ReadCloser, lastEventID string, done chan struct{}) {
+	defer func() {
+		body.Close()
+		i.mut.Lock()
+		i.connected = false
+		i.mut.Unlock()
+		close(done)
+	}()
+
+	err := parseEvents(body, lastEventID, func(e event) {
+		i.mut.Lock()
+		i.lastEventID = e.id
+		i.mut.Unlock()
+
+		if i.eventTypes != nil {
+			if _, exists := i.eventTypes[e.eventType]; !exists {
+				return
+			}
+		}
+		select {
+		case i.events <- e:
+		case <-ctx.Done():
+		}
+	}, func(retry time.Duration) {
+		i.mut.Lock()
+		i.retryDelay = retry
+		i.mut.Unlock()
+	})
+	if ctx.Err() != nil {
+		return
+	}
+	if err != nil {
+		i.log.Errorf("Event stream failed: %v", err)
+	} else {
+		i.log.Warn("Event stream was closed by the server")
+	}
+}
+
+func (i *input) Read(ctx context.Context) (*service.Message, service.AckFunc, error) {
+	i.mut.Lock()
+	done := i.done
+	i.mut.Unlock()
+
+	if done == nil {
+		return nil, nil, service.ErrNotConnected
+	}
+
+	select {
+	case e := <-i.events:
+		msg := service.NewMessage([]byte(e.data))
+		msg.MetaSetMut("sse_event", e.eventType)
+		msg.MetaSetMut("sse_id", e.id)
+		return msg, func(ctx context.Context, err error) error {
+			if err != nil || i.cache == "" || e.id == "" {
+				return nil
+			}
+			var setErr error
+			if err := i.mgr.AccessCache(ctx, i.cache, func(c service.Cache) {
+				setErr = c.Set(ctx, i.cacheKey, []byte(e.id), nil)
+			}); err != nil {
+				return err
+			}
+			return setErr
+		}, nil
+	case <-done:
+		return nil, nil, service.ErrNotConnected
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+}
+
+func (i *input) Close(ctx context.Context) error {
+	i.mut.Lock()
+	cancelFn, done := i.cancelFn, i.done
+	i.mut.Unlock()
+
+	if cancelFn == nil {
+		return nil
+	}
+	cancelFn()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// scanLines splits on any of the line endings allowed by the event stream
+// format, which are CRLF, LF and a lone CR.
+func scanLines(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		if data[i] == '\n' {
+			return i + 1, data[:i], nil
+		}
+		if i+1 < len(data) {
+			if data[i+1] == '\n' {
+				return i + 2, data[:i], nil
+			}
+			return i + 1, data[:i], nil
+		}
+		if atEOF {
+			return i + 1, data[:i], nil
+		}
+		// Request more data in order to determine whether this CR is followed
+		// by an LF.
+		return 0, nil, nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// parseEvents reads an event stream, calling fn for each event dispatched and
+// retryFn for each reconnection time specified. The identifier of events
+// begins as lastID until the stream specifies one.
+func parseEvents(r io.Reader, lastID string, fn func(e event), retryFn func(time.Duration)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	scanner.Split(scanLines)
+
+	var (
+		eventType string
+		data      strings.Builder
+		hasData   bool
+	)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			if hasData {
+				if eventType == "" {
+					eventType = "message"
+				}
+				fn(event{id: lastID, eventType: eventType, data: data.String()})
+			}
+			eventType, hasData = "", false
+			data.Reset()
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value, found := strings.Cut(line, ":")
+		if found {
+			value = strings.TrimPrefix(value, " ")
+		}
+
+		switch field {
+		case "event":
+			eventType = value
+		case "data":
+			if hasData {
+				data.WriteByte('\n')
+			}
+			data.WriteString(value)
+			hasData = true
+		case "id":
+			if !strings.ContainsRune(value, 0) {
+				lastID = value
+			}
+		case "retry":
+			if ms, err := strconv.ParseUint(value, 10, 63); err == nil {
+				retryFn(time.Duration(ms) * time.Millisecond)
+			}
+		}
+	}
+	return scanner.Err()
+}
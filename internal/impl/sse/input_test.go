@@ -0,0 +1,193 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sse
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func TestParseEvents(t *testing.T) {
+	stream := ": comment\n" +
+		"retry: 1500\n" +
+		"data: first\n\n" +
+		"id: 1\r\n" +
+		"event: update\r\n" +
+		"data: second\r\n" +
+		"data:  line two\r\n\r\n" +
+		"event: ignored\n\n" +
+		"id: 2\rdata\r\r" +
+		"id: bad\x00id\n" +
+		"data:{\"a\":1}\n\n" +
+		"data: unterminated"
+
+	var events []event
+	var retries []time.Duration
+	require.NoError(t, parseEvents(strings.NewReader(stream), "0", func(e event) {
+		events = append(events, e)
+	}, func(d time.Duration) {
+		retries = append(retries, d)
+	}))
+
+	assert.Equal(t, []event{
+		{id: "0", eventType: "message", data: "first"},
+		{id: "1", eventType: "update", data: "second\n line two"},
+		{id: "2", eventType: "message", data: ""},
+		{id: "2", eventType: "message", data: `{"a":1}`},
+	}, events)
+	assert.Equal(t, []time.Duration{1500 * time.Millisecond}, retries)
+}
+
+type testStreamServer struct {
+	mut          sync.Mutex
+	lastEventIDs []string
+}
+
+func newTestStreamServer(t *testing.T) (*testStreamServer, string) {
+	t.Helper()
+
+	s := &testStreamServer{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "text/event-stream", r.Header.Get("Accept"))
+		assert.Equal(t, "bar", r.Header.Get("X-Foo"))
+
+		lastID := r.Header.Get("Last-Event-ID")
+		s.mut.Lock()
+		s.lastEventIDs = append(s.lastEventIDs, lastID)
+		s.mut.Unlock()
+
+		var body string
+		switch lastID {
+		case "":
+			body = "retry: 10\n\nid: 1\ndata: a\n\nevent: ping\ndata: p\n\nid: 2\nevent: update\ndata: b\n\n"
+		case "2":
+			body = "id: 3\ndata: c\n\n"
+		default:
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream; charset=utf-8")
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return s, srv.URL
+}
+
+func testInput(t *testing.T, yamlStr string, mgr *service.Resources) *input {
+	t.Helper()
+
+	conf, err := inputSpec().ParseYAML(yamlStr, nil)
+	require.NoError(t, err)
+
+	i, err := newInputFromParsed(conf, mgr)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = i.Close(context.Background())
+	})
+	return i
+}
+
+// readAll reads messages from the input, reconnecting when disconnected, until
+// the end of input is reached.
+func readAll(t *testing.T, i *input) (msgs []*service.Message) {
+	t.Helper()
+
+	ctx, done := context.WithTimeout(context.Background(), 5*time.Second)
+	defer done()
+
+	for {
+		err := i.Connect(ctx)
+		if errors.Is(err, service.ErrEndOfInput) {
+			return
+		}
+		require.NoError(t, err)
+
+		for {
+			msg, ackFn, err := i.Read(ctx)
+			if errors.Is(err, service.ErrNotConnected) {
+				break
+			}
+			require.NoError(t, err)
+			require.NoError(t, ackFn(ctx, nil))
+			msgs = append(msgs, msg)
+		}
+	}
+}
+
+func TestInputResume(t *testing.T) {
+	s, url := newTestStreamServer(t)
+
+	i := testInput(t, `
+url: `+url+`
+headers:
+  X-Foo: bar
+event_types: [ message, update ]
+`, service.MockResources())
+
+	msgs := readAll(t, i)
+
+	var results []string
+	for _, msg := range msgs {
+		b, err := msg.AsBytes()
+		require.NoError(t, err)
+		eventType, _ := msg.MetaGetMut("sse_event")
+		id, _ := msg.MetaGetMut("sse_id")
+		results = append(results, string(b)+":"+eventType.(string)+":"+id.(string))
+	}
+	assert.Equal(t, []string{"a:message:1", "b:update:2", "c:message:3"}, results)
+	assert.Equal(t, []string{"", "2", "3"}, s.lastEventIDs)
+}
+
+func TestInputCache(t *testing.T) {
+	s, url := newTestStreamServer(t)
+
+	mgr := service.MockResources(service.MockResourcesOptAddCache("ids"))
+	require.NoError(t, mgr.AccessCache(context.Background(), "ids", func(c service.Cache) {
+		require.NoError(t, c.Set(context.Background(), "sse_last_event_id", []byte("2"), nil))
+	}))
+
+	i := testInput(t, `
+url: `+url+`
+headers:
+  X-Foo: bar
+cache: ids
+retry_interval: 10ms
+`, mgr)
+
+	msgs := readAll(t, i)
+	require.Len(t, msgs, 1)
+
+	b, err := msgs[0].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, "c", string(b))
+	assert.Equal(t, []string{"2", "3"}, s.lastEventIDs)
+
+	require.NoError(t, mgr.AccessCache(context.Background(), "ids", func(c service.Cache) {
+		v, err := c.Get(context.Background(), "sse_last_event_id")
+		require.NoError(t, err)
+		assert.Equal(t, "3", string(v))
+	}))
+}
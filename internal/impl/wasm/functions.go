@@ -121,3 +121,41 @@ var _ = registerModuleRunnerFunction("v0_msg_get_meta", func(r *moduleRunner) in
 		return ptrLen(contentPtr, uint64(len(metaValueBytes)))
 	}
 })
+
+var _ = registerModuleRunnerFunction("v0_msg_delete_meta", func(r *moduleRunner) interface{} {
+	return func(ctx context.Context, m api.Module, keyPtr, keySize uint32) {
+		if r.targetMessage == nil {
+			r.funcErr(errors.New("attempted to delete metadata of deleted message"))
+			return
+		}
+
+		keyBytes, err := r.readBytesOutbound(ctx, keyPtr, keySize)
+		if err != nil {
+			r.funcErr(fmt.Errorf("failed to read out-bound meta key memory: %w", err))
+			return
+		}
+
+		r.targetMessage.MetaDelete(string(keyBytes))
+	}
+})
+
+var _ = registerModuleRunnerFunction("v0_log", func(r *moduleRunner) interface{} {
+	return func(ctx context.Context, m api.Module, level, contentPtr, contentSize uint32) {
+		contentBytes, err := r.readBytesOutbound(ctx, contentPtr, contentSize)
+		if err != nil {
+			r.funcErr(fmt.Errorf("failed to read out-bound log memory: %w", err))
+			return
+		}
+
+		switch level {
+		case 0:
+			r.log.Debug(string(contentBytes))
+		case 2:
+			r.log.Warn(string(contentBytes))
+		case 3:
+			r.log.Error(string(contentBytes))
+		default:
+			r.log.Info(string(contentBytes))
+		}
+	}
+})
@@ -15,12 +15,15 @@
 package wasm
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"os"
 	"sync"
+	"time"
 
+	"github.com/Jeffail/shutdown"
 	"github.com/tetratelabs/wazero"
 	"github.com/tetratelabs/wazero/api"
 	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
@@ -34,21 +37,42 @@ func wazeroAllocProcessorConfig() *service.ConfigSpec {
 		Categories("Utility").
 		Summary("Executes a function exported by a WASM module for each message.").
 		Description(`
-This processor uses https://github.com/tetratelabs/wazero[Wazero^] to execute a WASM module (with support for WASI), calling a specific function for each message being processed. From within the WASM module it is possible to query and mutate the message being processed via a suite of functions exported to the module.
+This processor uses https://github.com/tetratelabs/wazero[Wazero^] to execute a WASM module (with support for WASI preview 1), calling a specific function for each message being processed. From within the WASM module it is possible to query and mutate the message being processed via a suite of functions exported to the module.
 
 This ecosystem is delicate as WASM doesn't have a single clearly defined way to pass strings back and forth between the host and the module. In order to remedy this we're gradually working on introducing libraries and examples for multiple languages which can be found in https://github.com/redpanda-data/benthos/tree/main/public/wasm/README.md[the codebase^].
 
 These examples, as well as the processor itself, is a work in progress.
 
+== Host Functions
+
+The following functions are exported to the module under the name ` + "`benthos_wasm`" + `:
+
+- ` + "`v0_msg_as_bytes() u64`" + ` returns the payload of the message as a pointer and length packed into the upper and lower 32 bits.
+- ` + "`v0_msg_set_bytes(ptr, len u32)`" + ` sets the payload of the message.
+- ` + "`v0_msg_get_meta(key_ptr, key_len u32) u64`" + ` returns the value of a metadata key, or an empty string, as a packed pointer and length.
+- ` + "`v0_msg_set_meta(key_ptr, key_len, value_ptr, value_len u32)`" + ` sets a metadata key.
+- ` + "`v0_msg_delete_meta(key_ptr, key_len u32)`" + ` removes a metadata key.
+- ` + "`v0_log(level, ptr, len u32)`" + ` writes a message to the logs of this processor, where the level is one of ` + "`0`" + ` (debug), ` + "`1`" + ` (info), ` + "`2`" + ` (warn) or ` + "`3`" + ` (error).
+
 == Parallelism
 
-It's not currently possible to execute a single WASM runtime across parallel threads with this processor. Therefore, in order to support parallel processing this processor implements pooling of module runtimes. Ideally your WASM module shouldn't depend on any global state, but if it does then you need to ensure the processor xref:configuration:processing_pipelines.adoc[is only run on a single thread].
+It's not currently possible to execute a single WASM runtime across parallel threads with this processor. Therefore, in order to support parallel processing this processor implements pooling of module instances, which share a single compilation of the module. Ideally your WASM module shouldn't depend on any global state, but if it does then you need to ensure the processor xref:configuration:processing_pipelines.adoc[is only run on a single thread].
+
+== Reloading
+
+When a ` + "`reload_interval`" + ` is set the module file is checked for changes at that interval. When it has changed the new module is instantiated, and if successful subsequent batches are processed with it, whilst batches already being processed complete with the previous module. A module that fails to instantiate is logged and the previous module remains in use.
 `).
 		Field(service.NewStringField("module_path").
 			Description("The path of the target WASM module to execute.")).
 		Field(service.NewStringField("function").
 			Default("process").
 			Description("The name of the function exported by the target WASM module to run for each message.")).
+		Field(service.NewDurationField("reload_interval").
+			Description("An optional interval at which to check the module file for changes and reload it.").
+			Example("30s").
+			Optional().
+			Advanced().
+			Version("4.45.0")).
 		Version("4.11.0")
 }
 
@@ -68,8 +92,12 @@ func init() {
 type wazeroAllocProcessor struct {
 	log          *service.Logger
 	functionName string
-	wasmBinary   []byte
-	modulePool   sync.Pool
+	modulePath   string
+
+	modMut sync.RWMutex
+	mod    *wazeroModule
+
+	shutSig *shutdown.Signaller
 }
 
 func newWazeroAllocProcessorFromConfig(conf *service.ParsedConfig, mgr *service.Resources) (*wazeroAllocProcessor, error) {
@@ -88,34 +116,175 @@ func newWazeroAllocProcessorFromConfig(conf *service.ParsedConfig, mgr *service.
 		return nil, err
 	}
 
-	return newWazeroAllocProcessor(function, fileBytes, mgr)
+	proc, err := newWazeroAllocProcessor(function, fileBytes, mgr)
+	if err != nil {
+		return nil, err
+	}
+	proc.modulePath = pathStr
+
+	if conf.Contains("reload_interval") {
+		interval, err := conf.FieldDuration("reload_interval")
+		if err != nil {
+			_ = proc.Close(context.Background())
+			return nil, err
+		}
+		proc.shutSig = shutdown.NewSignaller()
+		go proc.reloadLoop(interval)
+	}
+	return proc, nil
 }
 
 func newWazeroAllocProcessor(functionName string, wasmBinary []byte, mgr *service.Resources) (*wazeroAllocProcessor, error) {
-	proc := &wazeroAllocProcessor{
-		log:        mgr.Logger(),
-		modulePool: sync.Pool{},
+	mod, err := newWazeroModule(mgr.Logger(), functionName, wasmBinary)
+	if err != nil {
+		return nil, err
+	}
+	return &wazeroAllocProcessor{
+		log:          mgr.Logger(),
+		functionName: functionName,
+		mod:          mod,
+	}, nil
+}
+
+func (p *wazeroAllocProcessor) reloadLoop(interval time.Duration) {
+	defer p.shutSig.TriggerHasStopped()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := p.reload(); err != nil {
+				p.log.Errorf("Failed to reload WASM module %v: %v", p.modulePath, err)
+			}
+		case <-p.shutSig.SoftStopChan():
+			return
+		}
+	}
+}
+
+// reload replaces the module with the current contents of the module file if
+// they have changed and can be instantiated.
+func (p *wazeroAllocProcessor) reload() error {
+	fileBytes, err := os.ReadFile(p.modulePath)
+	if err != nil {
+		return err
+	}
+
+	p.modMut.RLock()
+	unchanged := bytes.Equal(fileBytes, p.mod.wasmBinary)
+	p.modMut.RUnlock()
+	if unchanged {
+		return nil
+	}
+
+	mod, err := newWazeroModule(p.log, p.functionName, fileBytes)
+	if err != nil {
+		return err
+	}
 
+	p.modMut.Lock()
+	prev := p.mod
+	p.mod = mod
+	p.modMut.Unlock()
+
+	p.log.Infof("Reloaded WASM module %v", p.modulePath)
+	return prev.retire(context.Background())
+}
+
+func (p *wazeroAllocProcessor) acquire() (*moduleRunner, error) {
+	for {
+		p.modMut.RLock()
+		mod := p.mod
+		p.modMut.RUnlock()
+
+		// The module may have been retired by a reload since it was read, in
+		// which case the replacement is used instead.
+		modRunner, err := mod.acquire()
+		if !errors.Is(err, errModuleRetired) {
+			return modRunner, err
+		}
+	}
+}
+
+func (p *wazeroAllocProcessor) ProcessBatch(ctx context.Context, batch service.MessageBatch) ([]service.MessageBatch, error) {
+	modRunner, err := p.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		modRunner.owner.release(context.Background(), modRunner)
+	}()
+
+	res, err := modRunner.Run(ctx, batch)
+	if err != nil {
+		return nil, err
+	}
+	return []service.MessageBatch{res}, nil
+}
+
+func (p *wazeroAllocProcessor) Close(ctx context.Context) error {
+	if p.shutSig != nil {
+		p.shutSig.TriggerSoftStop()
+		select {
+		case <-p.shutSig.HasStoppedChan():
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	p.modMut.RLock()
+	mod := p.mod
+	p.modMut.RUnlock()
+	return mod.retire(ctx)
+}
+
+//------------------------------------------------------------------------------
+
+var errModuleRetired = errors.New("module has been retired")
+
+// wazeroModule is a compiled WASM module along with a pool of instances of
+// it. A module is retired when it is replaced by a reload, or when the
+// processor is closed, after which instances are closed as they're released.
+type wazeroModule struct {
+	log          *service.Logger
+	functionName string
+	wasmBinary   []byte
+	cache        wazero.CompilationCache
+
+	mut     sync.Mutex
+	idle    []*moduleRunner
+	inUse   int
+	retired bool
+}
+
+func newWazeroModule(log *service.Logger, functionName string, wasmBinary []byte) (*wazeroModule, error) {
+	mod := &wazeroModule{
+		log:          log,
 		functionName: functionName,
 		wasmBinary:   wasmBinary,
+		cache:        wazero.NewCompilationCache(),
 	}
 
 	// Ensure we can create at least one module runner.
-	modRunner, err := proc.newModule()
+	modRunner, err := mod.newRunner()
 	if err != nil {
+		_ = mod.cache.Close(context.Background())
 		return nil, err
 	}
 
-	proc.modulePool.Put(modRunner)
-	return proc, nil
+	mod.idle = append(mod.idle, modRunner)
+	return mod, nil
 }
 
-func (p *wazeroAllocProcessor) newModule() (mod *moduleRunner, err error) {
+func (w *wazeroModule) newRunner() (mod *moduleRunner, err error) {
 	ctx := context.Background()
 
-	r := wazero.NewRuntime(ctx)
+	r := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().WithCompilationCache(w.cache))
 	mod = &moduleRunner{
-		log:     p.log,
+		log:     w.log,
+		owner:   w,
 		runtime: r,
 	}
 	defer func() {
@@ -136,11 +305,14 @@ func (p *wazeroAllocProcessor) newModule() (mod *moduleRunner, err error) {
 		return
 	}
 
-	if mod.mod, err = r.Instantiate(ctx, p.wasmBinary); err != nil {
+	if mod.mod, err = r.Instantiate(ctx, w.wasmBinary); err != nil {
 		return
 	}
 
-	mod.process = mod.mod.ExportedFunction(p.functionName)
+	if mod.process = mod.mod.ExportedFunction(w.functionName); mod.process == nil {
+		err = fmt.Errorf("function %v is not exported by the module", w.functionName)
+		return
+	}
 	mod.goMalloc = mod.mod.ExportedFunction("malloc")
 	mod.goFree = mod.mod.ExportedFunction("free")
 	mod.rustAlloc = mod.mod.ExportedFunction("allocate")
@@ -149,43 +321,84 @@ func (p *wazeroAllocProcessor) newModule() (mod *moduleRunner, err error) {
 	return mod, nil
 }
 
-func (p *wazeroAllocProcessor) ProcessBatch(ctx context.Context, batch service.MessageBatch) ([]service.MessageBatch, error) {
-	var modRunner *moduleRunner
-	var err error
-	if modRunnerPtr := p.modulePool.Get(); modRunnerPtr != nil {
-		modRunner = modRunnerPtr.(*moduleRunner)
-	} else {
-		if modRunner, err = p.newModule(); err != nil {
-			return nil, err
-		}
+func (w *wazeroModule) acquire() (*moduleRunner, error) {
+	w.mut.Lock()
+	if w.retired {
+		w.mut.Unlock()
+		return nil, errModuleRetired
 	}
-	defer func() {
-		p.modulePool.Put(modRunner)
-	}()
+	w.inUse++
+	if n := len(w.idle); n > 0 {
+		modRunner := w.idle[n-1]
+		w.idle = w.idle[:n-1]
+		w.mut.Unlock()
+		return modRunner, nil
+	}
+	w.mut.Unlock()
 
-	res, err := modRunner.Run(ctx, batch)
+	modRunner, err := w.newRunner()
 	if err != nil {
+		w.mut.Lock()
+		w.inUse--
+		closeCache := w.retired && w.inUse == 0
+		w.mut.Unlock()
+		if closeCache {
+			_ = w.cache.Close(context.Background())
+		}
 		return nil, err
 	}
-	return []service.MessageBatch{res}, nil
+	return modRunner, nil
 }
 
-func (p *wazeroAllocProcessor) Close(ctx context.Context) error {
-	for {
-		mr := p.modulePool.Get()
-		if mr == nil {
-			return nil
+func (w *wazeroModule) release(ctx context.Context, modRunner *moduleRunner) {
+	w.mut.Lock()
+	w.inUse--
+	if !w.retired {
+		w.idle = append(w.idle, modRunner)
+		w.mut.Unlock()
+		return
+	}
+	closeCache := w.inUse == 0
+	w.mut.Unlock()
+
+	if err := modRunner.Close(ctx); err != nil {
+		w.log.Errorf("Failed to close retired WASM module instance: %v", err)
+	}
+	if closeCache {
+		if err := w.cache.Close(ctx); err != nil {
+			w.log.Errorf("Failed to close retired WASM module: %v", err)
 		}
-		if err := mr.(*moduleRunner).Close(ctx); err != nil {
+	}
+}
+
+func (w *wazeroModule) retire(ctx context.Context) error {
+	w.mut.Lock()
+	if w.retired {
+		w.mut.Unlock()
+		return nil
+	}
+	w.retired = true
+	idle := w.idle
+	w.idle = nil
+	closeCache := w.inUse == 0
+	w.mut.Unlock()
+
+	for _, modRunner := range idle {
+		if err := modRunner.Close(ctx); err != nil {
 			return err
 		}
 	}
+	if closeCache {
+		return w.cache.Close(ctx)
+	}
+	return nil
 }
 
 //------------------------------------------------------------------------------
 
 type moduleRunner struct {
-	log *service.Logger
+	log   *service.Logger
+	owner *wazeroModule
 
 	runtime wazero.Runtime
 	mod     api.Module
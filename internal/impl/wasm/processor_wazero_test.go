@@ -18,6 +18,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"testing"
@@ -182,3 +183,140 @@ func BenchmarkWazeroWASIRustCalls(b *testing.B) {
 		require.NoError(b, err)
 	}
 }
+
+//------------------------------------------------------------------------------
+
+func wasmULEB(v uint32) []byte {
+	var b []byte
+	for {
+		c := byte(v & 0x7f)
+		v >>= 7
+		if v != 0 {
+			b = append(b, c|0x80)
+			continue
+		}
+		return append(b, c)
+	}
+}
+
+func wasmVec(items ...[]byte) []byte {
+	b := wasmULEB(uint32(len(items)))
+	for _, item := range items {
+		b = append(b, item...)
+	}
+	return b
+}
+
+func wasmName(s string) []byte {
+	return append(wasmULEB(uint32(len(s))), s...)
+}
+
+func wasmSection(id byte, contents []byte) []byte {
+	return append(append([]byte{id}, wasmULEB(uint32(len(contents)))...), contents...)
+}
+
+// testSetBytesModule assembles a WASM module exporting a function process
+// that sets the payload of each message to the provided content, and then
+// writes it to the logs at the error level.
+func testSetBytesModule(content string) []byte {
+	const (
+		i32     = 0x7f
+		i32cnst = 0x41
+		call    = 0x10
+		end     = 0x0b
+	)
+	size := wasmULEB(uint32(len(content)))
+
+	var body []byte
+	body = append(body, i32cnst, 0, i32cnst)
+	body = append(body, size...)
+	body = append(body, call, 0, i32cnst, 3, i32cnst, 0, i32cnst)
+	body = append(body, size...)
+	body = append(body, call, 1, end)
+	body = append(wasmVec(), body...)
+
+	var mod []byte
+	mod = append(mod, 0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00)
+	mod = append(mod, wasmSection(1, wasmVec(
+		[]byte{0x60, 2, i32, i32, 0},
+		[]byte{0x60, 3, i32, i32, i32, 0},
+		[]byte{0x60, 0, 0},
+	))...)
+	mod = append(mod, wasmSection(2, wasmVec(
+		append(append(wasmName("benthos_wasm"), wasmName("v0_msg_set_bytes")...), 0x00, 0),
+		append(append(wasmName("benthos_wasm"), wasmName("v0_log")...), 0x00, 1),
+	))...)
+	mod = append(mod, wasmSection(3, wasmVec([]byte{2}))...)
+	mod = append(mod, wasmSection(5, wasmVec([]byte{0x00, 1}))...)
+	mod = append(mod, wasmSection(7, wasmVec(
+		append(wasmName("process"), 0x00, 2),
+		append(wasmName("memory"), 0x02, 0),
+	))...)
+	mod = append(mod, wasmSection(10, wasmVec(append(wasmULEB(uint32(len(body))), body...)))...)
+	mod = append(mod, wasmSection(11, wasmVec(
+		append([]byte{0x00, i32cnst, 0, end}, wasmName(content)...),
+	))...)
+	return mod
+}
+
+func processWASMProc(t *testing.T, proc *wazeroAllocProcessor) string {
+	t.Helper()
+
+	outBatches, err := proc.ProcessBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte("hello world")),
+	})
+	require.NoError(t, err)
+	require.Len(t, outBatches, 1)
+	require.Len(t, outBatches[0], 1)
+	require.NoError(t, outBatches[0][0].GetError())
+
+	resBytes, err := outBatches[0][0].AsBytes()
+	require.NoError(t, err)
+	return string(resBytes)
+}
+
+func TestWazeroHostFunctions(t *testing.T) {
+	proc, err := newWazeroAllocProcessor("process", testSetBytesModule("from wasm"), service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, proc.Close(context.Background()))
+	})
+
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, "from wasm", processWASMProc(t, proc))
+	}
+}
+
+func TestWazeroMissingFunction(t *testing.T) {
+	_, err := newWazeroAllocProcessor("nope", testSetBytesModule("from wasm"), service.MockResources())
+	require.EqualError(t, err, "function nope is not exported by the module")
+}
+
+func TestWazeroReload(t *testing.T) {
+	modPath := filepath.Join(t.TempDir(), "module.wasm")
+	require.NoError(t, os.WriteFile(modPath, testSetBytesModule("first"), 0o644))
+
+	pConf, err := wazeroAllocProcessorConfig().ParseYAML(`
+module_path: `+modPath+`
+reload_interval: 10ms
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := newWazeroAllocProcessorFromConfig(pConf, service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, proc.Close(context.Background()))
+	})
+
+	assert.Equal(t, "first", processWASMProc(t, proc))
+
+	// An invalid module is ignored.
+	require.NoError(t, os.WriteFile(modPath, []byte("not wasm"), 0o644))
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, "first", processWASMProc(t, proc))
+
+	require.NoError(t, os.WriteFile(modPath, testSetBytesModule("second"), 0o644))
+	assert.Eventually(t, func() bool {
+		return processWASMProc(t, proc) == "second"
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,404 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+
+	"github.com/redpanda-data/connect/v4/internal/httpquota"
+	"github.com/redpanda-data/connect/v4/internal/retries"
+)
+
+const (
+	woFieldURL             = "url"
+	woFieldVerb            = "verb"
+	woFieldHeaders         = "headers"
+	woFieldSigning         = "signing"
+	woFieldSigningSecret   = "secret"
+	woFieldSigningHeader   = "header"
+	woFieldSigningPrefix   = "prefix"
+	woFieldSigningEncoding = "encoding"
+	woFieldSigningTSHeader = "timestamp_header"
+	woFieldTimeout         = "timeout"
+	woFieldQuota           = "quota"
+	woFieldDeadLetter      = "dead_letter"
+	woFieldTLS             = "tls"
+)
+
+func outputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Categories("Network").
+		Version("4.45.0").
+		Summary("Delivers messages to webhook endpoints as HTTP requests signed with HMAC-SHA256.").
+		Description(`
+Each message is sent as the body of a request, and is delivered once the endpoint responds with a 2XX status code. Requests that fail with a connection error, a timeout, or the status codes 408, 429 or 5XX are retried with a backoff, and when a response contains a `+"`Retry-After`"+` header the next attempt is delayed by at least the period it specifies. Any other status code is considered a permanent failure and is not retried.
+
+== Signatures
+
+When a `+"`signing.secret`"+` is set each request includes a header containing the HMAC-SHA256 of the body, allowing receivers to verify that requests originate from this pipeline. The default settings match the scheme used by GitHub, with a header `+"`X-Signature-256: sha256=<hex digest>`"+`. When a `+"`signing.timestamp_header`"+` is set the current unix timestamp is sent within that header, and the signed content becomes `+"`<timestamp>.<body>`"+`, which allows receivers to reject replayed requests.
+
+== Dead letters
+
+Messages that cannot be delivered, either because of a permanent failure or because retries are exhausted, are rejected unless a `+"`dead_letter`"+` output is configured, in which case they are written to it instead, with the metadata fields `+"`webhook_status_code`"+` (when a response was received) and `+"`webhook_error`"+` added.`).
+		Fields(
+			service.NewInterpolatedStringField(woFieldURL).
+				Description("The URL of the webhook endpoint.").
+				Example("https://example.com/hooks/${! @tenant }"),
+			service.NewStringField(woFieldVerb).
+				Description("The HTTP verb to use.").
+				Default("POST").
+				Advanced(),
+			service.NewInterpolatedStringMapField(woFieldHeaders).
+				Description("A map of headers to add to each request.").
+				Default(map[string]any{"Content-Type": "application/json"}),
+			service.NewObjectField(woFieldSigning,
+				service.NewStringField(woFieldSigningSecret).
+					Description("The secret used to sign requests, signing is disabled when empty.").
+					Default("").
+					Secret(),
+				service.NewStringField(woFieldSigningHeader).
+					Description("The header that contains the signature.").
+					Default("X-Signature-256"),
+				service.NewStringField(woFieldSigningPrefix).
+					Description("A prefix added to the signature within the header.").
+					Default("sha256="),
+				service.NewStringEnumField(woFieldSigningEncoding, "hex", "base64").
+					Description("The encoding of the signature.").
+					Default("hex"),
+				service.NewStringField(woFieldSigningTSHeader).
+					Description("An optional header containing the unix timestamp at which the request was signed, which is also included in the signed content.").
+					Default("").
+					Example("X-Webhook-Timestamp"),
+			).Description("Signing of requests with HMAC-SHA256."),
+			service.NewDurationField(woFieldTimeout).
+				Description("The maximum period to wait for each request to complete.").
+				Default("10s"),
+		).
+		Fields(retries.CommonRetryBackOffFields(3, "1s", "30s", "2m")...).
+		Fields(
+			httpquota.Field(woFieldQuota),
+			service.NewOutputField(woFieldDeadLetter).
+				Description("An optional output to write messages to when they cannot be delivered.").
+				Optional(),
+			service.NewTLSToggledField(woFieldTLS),
+			service.NewOutputMaxInFlightField(),
+		).
+		Example("Signed Deliveries", "Deliver events to a customer endpoint with signed requests, writing failed deliveries to a file for later inspection.", `
+output:
+  webhook:
+    url: https://customer.example.com/webhooks
+    signing:
+      secret: ${WEBHOOK_SECRET}
+      timestamp_header: X-Webhook-Timestamp
+    dead_letter:
+      file:
+        path: ./failed_webhooks.jsonl
+        codec: lines
+`)
+}
+
+func init() {
+	err := service.RegisterOutput("webhook", outputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.Output, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldMaxInFlight(); err != nil {
+				return
+			}
+			out, err = newOutputFromParsed(conf, mgr)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+// deliveryError describes a failed delivery attempt.
+type deliveryError struct {
+	statusCode int
+	retryAt    time.Time
+	err        error
+}
+
+func (d *deliveryError) Error() string {
+	if d.statusCode > 0 {
+		return fmt.Sprintf("webhook returned unexpected response code: %v", d.statusCode)
+	}
+	return d.err.Error()
+}
+
+func (d *deliveryError) Unwrap() error {
+	return d.err
+}
+
+func (d *deliveryError) retryable() bool {
+	if d.statusCode == 0 {
+		return true
+	}
+	return d.statusCode == http.StatusRequestTimeout ||
+		d.statusCode == http.StatusTooManyRequests ||
+		d.statusCode >= 500
+}
+
+type output struct {
+	url             *service.InterpolatedString
+	verb            string
+	headers         map[string]*service.InterpolatedString
+	secret          []byte
+	sigHeader       string
+	sigPrefix       string
+	sigEncoding     string
+	timestampHeader string
+	backoffCtor     func() backoff.BackOff
+	quota           *httpquota.Tracker
+	client          *http.Client
+	log             *service.Logger
+
+	nowFn func() time.Time
+
+	deadLetter *service.OwnedOutput
+	dlMut      sync.Mutex
+	dlPrimed   bool
+	inFlight   sync.WaitGroup
+}
+
+func newOutputFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*output, error) {
+	o := &output{log: mgr.Logger(), nowFn: time.Now}
+
+	var err error
+	if o.url, err = conf.FieldInterpolatedString(woFieldURL); err != nil {
+		return nil, err
+	}
+	if o.verb, err = conf.FieldString(woFieldVerb); err != nil {
+		return nil, err
+	}
+	if o.headers, err = conf.FieldInterpolatedStringMap(woFieldHeaders); err != nil {
+		return nil, err
+	}
+
+	sConf := conf.Namespace(woFieldSigning)
+	secret, err := sConf.FieldString(woFieldSigningSecret)
+	if err != nil {
+		return nil, err
+	}
+	o.secret = []byte(secret)
+	if o.sigHeader, err = sConf.FieldString(woFieldSigningHeader); err != nil {
+		return nil, err
+	}
+	if o.sigPrefix, err = sConf.FieldString(woFieldSigningPrefix); err != nil {
+		return nil, err
+	}
+	if o.sigEncoding, err = sConf.FieldString(woFieldSigningEncoding); err != nil {
+		return nil, err
+	}
+	if o.timestampHeader, err = sConf.FieldString(woFieldSigningTSHeader); err != nil {
+		return nil, err
+	}
+
+	timeout, err := conf.FieldDuration(woFieldTimeout)
+	if err != nil {
+		return nil, err
+	}
+	if o.backoffCtor, err = retries.CommonRetryBackOffCtorFromParsed(conf); err != nil {
+		return nil, err
+	}
+	if o.quota, err = httpquota.TrackerFromParsed(conf.Namespace(woFieldQuota), mgr); err != nil {
+		return nil, err
+	}
+	if conf.Contains(woFieldDeadLetter) {
+		if o.deadLetter, err = conf.FieldOutput(woFieldDeadLetter); err != nil {
+			return nil, err
+		}
+	}
+
+	tlsConf, tlsEnabled, err := conf.FieldTLSToggled(woFieldTLS)
+	if err != nil {
+		return nil, err
+	}
+	o.client = &http.Client{Timeout: timeout}
+	if tlsEnabled && tlsConf != nil {
+		if c, ok := http.DefaultTransport.(*http.Transport); ok {
+			cloned := c.Clone()
+			cloned.TLSClientConfig = tlsConf
+			o.client.Transport = cloned
+		} else {
+			o.client.Transport = &http.Transport{
+				TLSClientConfig: tlsConf,
+			}
+		}
+	}
+	return o, nil
+}
+
+func (o *output) Connect(ctx context.Context) error {
+	o.dlMut.Lock()
+	defer o.dlMut.Unlock()
+
+	if o.deadLetter == nil || o.dlPrimed {
+		return nil
+	}
+	if err := o.deadLetter.Prime(); err != nil {
+		return err
+	}
+	o.dlPrimed = true
+	return nil
+}
+
+func (o *output) sign(body []byte, timestamp string) string {
+	mac := hmac.New(sha256.New, o.secret)
+	if timestamp != "" {
+		_, _ = mac.Write([]byte(timestamp + "."))
+	}
+	_, _ = mac.Write(body)
+
+	sum := mac.Sum(nil)
+	if o.sigEncoding == "base64" {
+		return o.sigPrefix + base64.StdEncoding.EncodeToString(sum)
+	}
+	return o.sigPrefix + hex.EncodeToString(sum)
+}
+
+func (o *output) attempt(ctx context.Context, msg *service.Message, url string, body []byte) *deliveryError {
+	if err := o.quota.Wait(ctx); err != nil {
+		return &deliveryError{err: err}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, o.verb, url, bytes.NewReader(body))
+	if err != nil {
+		return &deliveryError{err: err}
+	}
+	for k, v := range o.headers {
+		vStr, err := v.TryString(msg)
+		if err != nil {
+			return &deliveryError{err: fmt.Errorf("header %v interpolation: %w", k, err)}
+		}
+		req.Header.Set(k, vStr)
+	}
+	if len(o.secret) > 0 {
+		var timestamp string
+		if o.timestampHeader != "" {
+			timestamp = strconv.FormatInt(o.nowFn().Unix(), 10)
+			req.Header.Set(o.timestampHeader, timestamp)
+		}
+		req.Header.Set(o.sigHeader, o.sign(body, timestamp))
+	}
+
+	res, err := o.client.Do(req)
+	if err != nil {
+		return &deliveryError{err: err}
+	}
+	_, _ = io.Copy(io.Discard, res.Body)
+	res.Body.Close()
+	o.quota.Observe(res)
+
+	if res.StatusCode >= 200 && res.StatusCode <= 299 {
+		return nil
+	}
+	dErr := &deliveryError{statusCode: res.StatusCode}
+	dErr.retryAt, _ = httpquota.RetryAfter(res.Header, o.nowFn())
+	return dErr
+}
+
+func (o *output) deliver(ctx context.Context, msg *service.Message) *deliveryError {
+	url, err := o.url.TryString(msg)
+	if err != nil {
+		return &deliveryError{err: fmt.Errorf("url interpolation: %w", err)}
+	}
+	body, err := msg.AsBytes()
+	if err != nil {
+		return &deliveryError{err: err}
+	}
+
+	boff := o.backoffCtor()
+	for {
+		dErr := o.attempt(ctx, msg, url, body)
+		if dErr == nil || !dErr.retryable() || ctx.Err() != nil {
+			return dErr
+		}
+
+		wait := boff.NextBackOff()
+		if wait == backoff.Stop {
+			return dErr
+		}
+		if untilRetry := dErr.retryAt.Sub(o.nowFn()); untilRetry > wait {
+			wait = untilRetry
+		}
+		o.log.Debugf("Webhook delivery failed, retrying in %v: %v", wait, dErr)
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return &deliveryError{err: ctx.Err()}
+		}
+	}
+}
+
+func (o *output) Write(ctx context.Context, msg *service.Message) error {
+	dErr := o.deliver(ctx, msg)
+	if dErr == nil {
+		return nil
+	}
+	if o.deadLetter == nil || ctx.Err() != nil {
+		return dErr
+	}
+
+	o.inFlight.Add(1)
+	defer o.inFlight.Done()
+
+	o.log.Warnf("Webhook delivery failed permanently, writing to dead letter output: %v", dErr)
+
+	dlMsg := msg.Copy()
+	if dErr.statusCode > 0 {
+		dlMsg.MetaSetMut("webhook_status_code", dErr.statusCode)
+	}
+	dlMsg.MetaSetMut("webhook_error", dErr.Error())
+	if err := o.deadLetter.Write(ctx, dlMsg); err != nil {
+		return fmt.Errorf("failed to write to dead letter output: %w", err)
+	}
+	return nil
+}
+
+func (o *output) Close(ctx context.Context) error {
+	o.client.CloseIdleConnections()
+	if o.deadLetter == nil {
+		return nil
+	}
+
+	// Wait for pending dead letter writes before closing the output.
+	done := make(chan struct{})
+	go func() {
+		o.inFlight.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return o.deadLetter.Close(ctx)
+}
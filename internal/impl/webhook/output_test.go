@@ -0,0 +1,222 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+type captureOutput struct {
+	mut     sync.Mutex
+	batches []service.MessageBatch
+}
+
+func (c *captureOutput) Connect(context.Context) error { return nil }
+
+func (c *captureOutput) WriteBatch(_ context.Context, b service.MessageBatch) error {
+	c.mut.Lock()
+	c.batches = append(c.batches, b)
+	c.mut.Unlock()
+	return nil
+}
+
+func (c *captureOutput) Close(context.Context) error { return nil }
+
+func testOutput(t *testing.T, yamlStr string, deadLetter *captureOutput) *output {
+	t.Helper()
+
+	env := service.NewEnvironment()
+	require.NoError(t, env.RegisterBatchOutput("webhook_test_dead_letter", service.NewConfigSpec(),
+		func(*service.ParsedConfig, *service.Resources) (service.BatchOutput, service.BatchPolicy, int, error) {
+			return deadLetter, service.BatchPolicy{}, 1, nil
+		}))
+
+	conf, err := outputSpec().ParseYAML(yamlStr, env)
+	require.NoError(t, err)
+
+	o, err := newOutputFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+	o.nowFn = func() time.Time { return time.Unix(1_700_000_000, 0) }
+
+	require.NoError(t, o.Connect(context.Background()))
+	t.Cleanup(func() {
+		require.NoError(t, o.Close(context.Background()))
+	})
+	return o
+}
+
+func TestOutputSigning(t *testing.T) {
+	type received struct {
+		path, signature, timestamp, tenant, body string
+	}
+	var reqs []received
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		reqs = append(reqs, received{
+			path:      r.URL.Path,
+			signature: r.Header.Get("X-Signature-256"),
+			timestamp: r.Header.Get("X-Webhook-Timestamp"),
+			tenant:    r.Header.Get("X-Tenant"),
+			body:      string(body),
+		})
+	}))
+	t.Cleanup(srv.Close)
+
+	o := testOutput(t, `
+url: `+srv.URL+`/hooks/${! @tenant }
+headers:
+  X-Tenant: ${! @tenant }
+signing:
+  secret: shh
+`, nil)
+
+	msg := service.NewMessage([]byte(`{"id":1}`))
+	msg.MetaSetMut("tenant", "acme")
+	require.NoError(t, o.Write(context.Background(), msg))
+
+	mac := hmac.New(sha256.New, []byte("shh"))
+	_, _ = mac.Write([]byte(`{"id":1}`))
+
+	require.Len(t, reqs, 1)
+	assert.Equal(t, received{
+		path:      "/hooks/acme",
+		signature: "sha256=" + hex.EncodeToString(mac.Sum(nil)),
+		tenant:    "acme",
+		body:      `{"id":1}`,
+	}, reqs[0])
+
+	o = testOutput(t, `
+url: `+srv.URL+`/hooks
+signing:
+  secret: shh
+  header: X-Signature-256
+  prefix: ""
+  encoding: base64
+  timestamp_header: X-Webhook-Timestamp
+`, nil)
+	require.NoError(t, o.Write(context.Background(), service.NewMessage([]byte(`{"id":2}`))))
+
+	require.Len(t, reqs, 2)
+	assert.Equal(t, "1700000000", reqs[1].timestamp)
+	assert.Equal(t, o.sign([]byte(`{"id":2}`), "1700000000"), reqs[1].signature)
+	assert.NotEqual(t, o.sign([]byte(`{"id":2}`), ""), reqs[1].signature)
+}
+
+func TestOutputRetries(t *testing.T) {
+	var mut sync.Mutex
+	var attempts []time.Time
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mut.Lock()
+		attempts = append(attempts, time.Now())
+		n := len(attempts)
+		mut.Unlock()
+
+		switch n {
+		case 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	o := testOutput(t, `
+url: `+srv.URL+`
+max_retries: 5
+backoff:
+  initial_interval: 1ms
+  max_interval: 1ms
+`, nil)
+
+	// The fixed clock means the Retry-After period is the whole second.
+	require.NoError(t, o.Write(context.Background(), service.NewMessage([]byte(`{}`))))
+
+	require.Len(t, attempts, 3)
+	assert.GreaterOrEqual(t, attempts[2].Sub(attempts[1]), time.Second)
+}
+
+func TestOutputDeadLetter(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		switch string(body) {
+		case "bad":
+			w.WriteHeader(http.StatusBadRequest)
+		case "down":
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	deadLetter := &captureOutput{}
+	o := testOutput(t, `
+url: `+srv.URL+`
+max_retries: 2
+backoff:
+  initial_interval: 1ms
+  max_interval: 1ms
+dead_letter:
+  webhook_test_dead_letter: {}
+`, deadLetter)
+
+	for _, body := range []string{"good", "bad", "down"} {
+		require.NoError(t, o.Write(context.Background(), service.NewMessage([]byte(body))))
+	}
+
+	deadLetter.mut.Lock()
+	defer deadLetter.mut.Unlock()
+
+	require.Len(t, deadLetter.batches, 2)
+	for i, exp := range []struct {
+		body string
+		code int
+	}{
+		{body: "bad", code: 400},
+		{body: "down", code: 502},
+	} {
+		require.Len(t, deadLetter.batches[i], 1)
+		msg := deadLetter.batches[i][0]
+
+		b, err := msg.AsBytes()
+		require.NoError(t, err)
+		assert.Equal(t, exp.body, string(b))
+
+		code, _ := msg.MetaGetMut("webhook_status_code")
+		assert.Equal(t, exp.code, code)
+		errStr, _ := msg.MetaGetMut("webhook_error")
+		assert.Contains(t, errStr, "unexpected response code")
+	}
+
+	// Without a dead letter output failed deliveries are rejected.
+	o = testOutput(t, `
+url: `+srv.URL+`
+max_retries: 1
+`, nil)
+	require.Error(t, o.Write(context.Background(), service.NewMessage([]byte("bad"))))
+}
@@ -0,0 +1,183 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xml
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	xvpFieldSchemaPath = "schema_path"
+	xvpFieldToJSON     = "to_json"
+)
+
+func xmlValidateProcSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Categories("Parsing").
+		Beta().
+		Version("4.45.0").
+		Summary(`Validates XML documents against an XML schema (XSD), and optionally converts valid documents into JSON typed according to the schema.`).
+		Description(`
+Schemas are loaded once when the processor is created, including any schemas they reference with `+"`xs:include`"+` or `+"`xs:import`"+`, which are resolved relative to the referencing schema.
+
+Messages that do not conform to the schema are flagged as having failed, and the metadata field `+"`xml_validate_errors`"+` is set to a list of the violations found, each an object with the fields `+"`path`"+`, `+"`line`"+` and `+"`message`"+`. Paths identify elements from the document root, with indexes for repeated elements, e.g. `+"`/order/items/item[2]/quantity`"+`. These messages can be handled with xref:configuration:error_handling.adoc[error handling] patterns.
+
+== Schema support
+
+A pragmatic subset of XSD 1.0 is supported, including global and local element declarations, named and anonymous types, `+"`sequence`"+`, `+"`choice`"+` and `+"`all`"+` model groups with occurrence constraints, element and attribute references and groups, complex type extension, simple content, lists and unions, and the restriction facets `+"`enumeration`"+`, `+"`pattern`"+`, `+"`length`"+`, `+"`minLength`"+`, `+"`maxLength`"+`, `+"`minInclusive`"+`, `+"`maxInclusive`"+`, `+"`minExclusive`"+`, `+"`maxExclusive`"+`, `+"`totalDigits`"+` and `+"`fractionDigits`"+`. Elements and attributes are matched by their namespaces according to the `+"`targetNamespace`"+`, `+"`elementFormDefault`"+` and `+"`attributeFormDefault`"+` of schemas, wildcards are matched by their `+"`namespace`"+` and validated according to their `+"`processContents`"+`, and `+"`fixed`"+` values are enforced. Schemas that use constructs which are not supported, such as `+"`xs:redefine`"+`, substitution groups and identity constraints such as `+"`xs:key`"+`, fail to load rather than being partially enforced, and documents that specify `+"`xsi:type`"+` fail validation. Patterns are evaluated with Go regular expressions.
+
+== JSON conversion
+
+When `+"`"+xvpFieldToJSON+"`"+` is enabled valid documents are converted into JSON with the same conventions as the `+"`xml`"+` processor, where attributes are prefixed with a hyphen and text alongside attributes is given the key `+"`#text`"+`. However, the structure is determined by the schema rather than the document: numeric and boolean values are converted to their types, and elements that the schema allows to occur more than once are always arrays, even when a document contains only one of them.
+`).
+		Fields(
+			service.NewStringField(xvpFieldSchemaPath).
+				Description("The path of the XSD schema to validate documents against.").
+				Example("./schemas/order.xsd"),
+			service.NewBoolField(xvpFieldToJSON).
+				Description("Whether to convert valid documents into JSON structured according to the schema.").
+				Default(false),
+		).
+		Example("Validate and convert orders", "Orders that fail validation are routed to a dead letter topic along with the reasons.", `
+pipeline:
+  processors:
+    - xml_validate:
+        schema_path: ./schemas/order.xsd
+        to_json: true
+
+output:
+  switch:
+    cases:
+      - check: errored()
+        output:
+          kafka:
+            addresses: [ localhost:9092 ]
+            topic: orders_dlq
+          processors:
+            - mapping: |
+                root = content()
+                meta validation_errors = @xml_validate_errors.format_json()
+      - output:
+          kafka:
+            addresses: [ localhost:9092 ]
+            topic: orders
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"xml_validate", xmlValidateProcSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return xmlValidateProcFromParsed(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type xmlValidateProc struct {
+	log    *service.Logger
+	schema *xsdSchema
+	toJSON bool
+}
+
+func xmlValidateProcFromParsed(pConf *service.ParsedConfig, mgr *service.Resources) (*xmlValidateProc, error) {
+	schemaPath, err := pConf.FieldString(xvpFieldSchemaPath)
+	if err != nil {
+		return nil, err
+	}
+
+	toJSON, err := pConf.FieldBool(xvpFieldToJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	schema, err := parseXSDSchema(schemaPath, func(p string) ([]byte, error) {
+		return service.ReadFile(mgr.FS(), p)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load schema: %w", err)
+	}
+
+	return &xmlValidateProc{
+		log:    mgr.Logger(),
+		schema: schema,
+		toJSON: toJSON,
+	}, nil
+}
+
+func (p *xmlValidateProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	mBytes, err := msg.AsBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := parseXMLNodes(mBytes, false)
+	if err != nil {
+		p.log.Debugf("Failed to parse part as XML: %v", err)
+		return nil, err
+	}
+
+	v := p.schema.validate(root)
+	if len(v.errs) > 0 {
+		details := make([]any, 0, len(v.errs))
+		for _, e := range v.errs {
+			details = append(details, map[string]any{
+				"path":    e.Path,
+				"line":    e.Line,
+				"message": e.Message,
+			})
+		}
+		msg.MetaSetMut("xml_validate_errors", details)
+		msg.SetError(xsdErrorsSummary(v.errs))
+		return service.MessageBatch{msg}, nil
+	}
+
+	if p.toJSON {
+		msg.SetStructuredMut(v.toJSON(root))
+	}
+	return service.MessageBatch{msg}, nil
+}
+
+func xsdErrorsSummary(errs []xsdValidationError) error {
+	const maxSummarised = 3
+
+	var b strings.Builder
+	if len(errs) == 1 {
+		b.WriteString("document failed schema validation: ")
+	} else {
+		fmt.Fprintf(&b, "document failed schema validation with %v errors: ", len(errs))
+	}
+	for i, e := range errs {
+		if i == maxSummarised {
+			fmt.Fprintf(&b, " and %v more", len(errs)-maxSummarised)
+			break
+		}
+		if i > 0 {
+			b.WriteString("; ")
+		}
+		b.WriteString(e.Error())
+	}
+	return errors.New(b.String())
+}
+
+func (p *xmlValidateProc) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,403 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xml
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const testOrderTypesXSD = `<?xml version="1.0"?>
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+  <xs:simpleType name="sku">
+    <xs:restriction base="xs:string">
+      <xs:pattern value="[A-Z]{3}-[0-9]+"/>
+    </xs:restriction>
+  </xs:simpleType>
+  <xs:complexType name="price">
+    <xs:simpleContent>
+      <xs:extension base="xs:decimal">
+        <xs:attribute name="currency" type="xs:string" use="required"/>
+      </xs:extension>
+    </xs:simpleContent>
+  </xs:complexType>
+</xs:schema>`
+
+const testOrderXSD = `<?xml version="1.0"?>
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+  <xs:include schemaLocation="types.xsd"/>
+  <xs:element name="order">
+    <xs:complexType>
+      <xs:sequence>
+        <xs:element name="customer" type="xs:string"/>
+        <xs:element name="express" type="xs:boolean" minOccurs="0"/>
+        <xs:element name="items">
+          <xs:complexType>
+            <xs:sequence>
+              <xs:element name="item" maxOccurs="unbounded">
+                <xs:complexType>
+                  <xs:sequence>
+                    <xs:element name="sku" type="sku"/>
+                    <xs:element name="quantity" type="xs:positiveInteger"/>
+                    <xs:element name="price" type="price"/>
+                  </xs:sequence>
+                </xs:complexType>
+              </xs:element>
+            </xs:sequence>
+          </xs:complexType>
+        </xs:element>
+      </xs:sequence>
+      <xs:attribute name="id" type="xs:int" use="required"/>
+      <xs:attribute name="status">
+        <xs:simpleType>
+          <xs:restriction base="xs:string">
+            <xs:enumeration value="open"/>
+            <xs:enumeration value="closed"/>
+          </xs:restriction>
+        </xs:simpleType>
+      </xs:attribute>
+    </xs:complexType>
+  </xs:element>
+</xs:schema>`
+
+func testXMLValidateProc(t *testing.T, extraConf string) *xmlValidateProc {
+	t.Helper()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "types.xsd"), []byte(testOrderTypesXSD), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "order.xsd"), []byte(testOrderXSD), 0o644))
+
+	conf, err := xmlValidateProcSpec().ParseYAML(`
+schema_path: `+filepath.Join(dir, "order.xsd")+`
+`+extraConf, nil)
+	require.NoError(t, err)
+
+	proc, err := xmlValidateProcFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+	return proc
+}
+
+func TestXMLValidateToJSON(t *testing.T) {
+	proc := testXMLValidateProc(t, `to_json: true`)
+
+	batch, err := proc.Process(context.Background(), service.NewMessage([]byte(`<order id="7" status="open">
+  <customer>Ada</customer>
+  <express>1</express>
+  <items>
+    <item>
+      <sku>ABC-1</sku>
+      <quantity>3</quantity>
+      <price currency="GBP">4.50</price>
+    </item>
+  </items>
+</order>`)))
+	require.NoError(t, err)
+	require.Len(t, batch, 1)
+	require.NoError(t, batch[0].GetError())
+
+	b, err := batch[0].AsBytes()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"order":{
+  "-id":7,
+  "-status":"open",
+  "customer":"Ada",
+  "express":true,
+  "items":{"item":[{"sku":"ABC-1","quantity":3,"price":{"-currency":"GBP","#text":4.5}}]}
+}}`, string(b))
+}
+
+func TestXMLValidateErrors(t *testing.T) {
+	proc := testXMLValidateProc(t, ``)
+
+	input := `<order status="pending">
+  <customer>Ada</customer>
+  <items>
+    <item>
+      <sku>ABC-1</sku>
+      <quantity>3</quantity>
+      <price currency="GBP">4.50</price>
+    </item>
+    <item>
+      <sku>abc</sku>
+      <quantity>0</quantity>
+      <price>nope</price>
+    </item>
+  </items>
+  <notes>hello</notes>
+</order>`
+
+	batch, err := proc.Process(context.Background(), service.NewMessage([]byte(input)))
+	require.NoError(t, err)
+	require.Len(t, batch, 1)
+	require.Error(t, batch[0].GetError())
+
+	// Invalid documents are left untouched.
+	b, err := batch[0].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, input, string(b))
+
+	v, exists := batch[0].MetaGetMut("xml_validate_errors")
+	require.True(t, exists)
+
+	var paths []string
+	for _, e := range v.([]any) {
+		obj := e.(map[string]any)
+		paths = append(paths, obj["path"].(string)+": "+obj["message"].(string))
+	}
+	assert.Equal(t, []string{
+		`/order: attribute status: value "pending" is not one of the allowed values open, closed`,
+		`/order: missing required attribute id`,
+		`/order/items/item[2]/sku: value "abc" does not match the pattern [A-Z]{3}-[0-9]+`,
+		`/order/items/item[2]/quantity: value "0" is out of range for positiveInteger`,
+		`/order/items/item[2]/price: missing required attribute currency`,
+		`/order/items/item[2]/price: value "nope" is not a valid decimal`,
+		`/order/notes: unexpected element notes`,
+	}, paths)
+
+	lines := map[string]int{}
+	for _, e := range v.([]any) {
+		obj := e.(map[string]any)
+		lines[obj["path"].(string)] = obj["line"].(int)
+	}
+	assert.Equal(t, 10, lines["/order/items/item[2]/sku"])
+	assert.Equal(t, 15, lines["/order/notes"])
+}
+
+func TestXSDModelGroups(t *testing.T) {
+	schema := `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+  <xs:element name="root">
+    <xs:complexType>
+      <xs:sequence>
+        <xs:choice>
+          <xs:element name="a" type="xs:int"/>
+          <xs:element name="b" type="xs:string"/>
+        </xs:choice>
+        <xs:element name="c" type="xs:string" minOccurs="2" maxOccurs="3"/>
+        <xs:element name="tags">
+          <xs:simpleType>
+            <xs:list itemType="xs:int"/>
+          </xs:simpleType>
+        </xs:element>
+      </xs:sequence>
+    </xs:complexType>
+  </xs:element>
+</xs:schema>`
+
+	s, err := parseXSDSchema("schema.xsd", func(string) ([]byte, error) {
+		return []byte(schema), nil
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		input  string
+		errors []string
+	}{
+		{
+			name:  "valid choice a",
+			input: `<root><a>1</a><c/><c/><tags>1 2</tags></root>`,
+		},
+		{
+			name:  "valid choice b",
+			input: `<root><b>x</b><c/><c/><c/><tags/></root>`,
+		},
+		{
+			name:   "missing choice",
+			input:  `<root><c/><c/><tags/></root>`,
+			errors: []string{`/root (line 1): missing required element a or b`},
+		},
+		{
+			name:   "too few",
+			input:  `<root><a>1</a><c/><tags/></root>`,
+			errors: []string{`/root (line 1): missing required element c`},
+		},
+		{
+			name:   "too many",
+			input:  `<root><a>1</a><c/><c/><c/><c/><tags/></root>`,
+			errors: []string{`/root (line 1): missing required element tags`, `/root/c[4] (line 1): unexpected element c`},
+		},
+		{
+			name:   "bad list item",
+			input:  `<root><a>1</a><c/><c/><tags>1 x</tags></root>`,
+			errors: []string{`/root/tags (line 1): value "x" is not a valid int`},
+		},
+		{
+			name:   "undeclared root",
+			input:  `<other/>`,
+			errors: []string{`/other (line 1): element other is not declared by the schema`},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root, err := parseXMLNodes([]byte(test.input), false)
+			require.NoError(t, err)
+
+			var errs []string
+			for _, e := range s.validate(root).errs {
+				errs = append(errs, e.Error())
+			}
+			assert.Equal(t, test.errors, errs)
+		})
+	}
+}
+
+func TestXSDNamespaces(t *testing.T) {
+	files := map[string]string{
+		"order.xsd": `<?xml version="1.0"?>
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema"
+  xmlns:o="urn:order" xmlns:c="urn:common"
+  targetNamespace="urn:order" elementFormDefault="qualified">
+  <xs:import namespace="urn:common" schemaLocation="common.xsd"/>
+  <xs:element name="order">
+    <xs:complexType>
+      <xs:sequence>
+        <xs:element name="id" type="xs:int"/>
+        <xs:element ref="c:note" minOccurs="0"/>
+        <xs:element name="local" type="xs:string" form="unqualified" minOccurs="0"/>
+        <xs:any namespace="##other" processContents="lax" minOccurs="0"/>
+      </xs:sequence>
+      <xs:attribute name="version" type="xs:decimal" fixed="2"/>
+    </xs:complexType>
+  </xs:element>
+</xs:schema>`,
+		"common.xsd": `<?xml version="1.0"?>
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema" targetNamespace="urn:common">
+  <xs:element name="note" type="xs:string"/>
+  <xs:element name="count" type="xs:int"/>
+</xs:schema>`,
+	}
+
+	s, err := parseXSDSchema("order.xsd", func(p string) ([]byte, error) {
+		return []byte(files[p]), nil
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		input  string
+		errors []string
+	}{
+		{
+			name:  "valid",
+			input: `<order xmlns="urn:order" xmlns:c="urn:common" version="2.0"><id>1</id><c:note>hi</c:note><local xmlns="">x</local><c:count>3</c:count></order>`,
+		},
+		{
+			name:   "root without namespace",
+			input:  `<order><id>1</id></order>`,
+			errors: []string{`/order (line 1): element order is not declared by the schema`},
+		},
+		{
+			name:   "child in wrong namespace",
+			input:  `<o:order xmlns:o="urn:order"><id>1</id></o:order>`,
+			errors: []string{`/order (line 1): missing required element {urn:order}id`, `/order/id (line 1): unexpected element id`},
+		},
+		{
+			name:   "unqualified local in namespace",
+			input:  `<order xmlns="urn:order"><id>1</id><local>x</local></order>`,
+			errors: []string{`/order/local (line 1): unexpected element {urn:order}local`},
+		},
+		{
+			name:   "wildcard validates declared elements",
+			input:  `<order xmlns="urn:order" xmlns:c="urn:common"><id>1</id><c:count>x</c:count></order>`,
+			errors: []string{`/order/count (line 1): value "x" is not a valid int`},
+		},
+		{
+			name:   "wildcard excludes target namespace",
+			input:  `<order xmlns="urn:order"><id>1</id><other/></order>`,
+			errors: []string{`/order/other (line 1): unexpected element {urn:order}other`},
+		},
+		{
+			name:   "fixed attribute",
+			input:  `<order xmlns="urn:order" version="3"><id>1</id></order>`,
+			errors: []string{`/order (line 1): attribute version: value "3" does not equal the fixed value "2"`},
+		},
+		{
+			name:   "xsi type",
+			input:  `<order xmlns="urn:order" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:type="other"><id>1</id></order>`,
+			errors: []string{`/order (line 1): xsi:type is not supported`},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root, err := parseXMLNodes([]byte(test.input), false)
+			require.NoError(t, err)
+
+			var errs []string
+			for _, e := range s.validate(root).errs {
+				errs = append(errs, e.Error())
+			}
+			assert.Equal(t, test.errors, errs)
+		})
+	}
+}
+
+func TestXSDUnsupported(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		err    string
+	}{
+		{
+			name:   "redefine",
+			schema: `<xs:redefine schemaLocation="other.xsd"/>`,
+			err:    `xs:redefine is not supported`,
+		},
+		{
+			name: "substitution group",
+			schema: `<xs:element name="a" type="xs:string"/>
+  <xs:element name="b" type="xs:string" substitutionGroup="a"/>`,
+			err: `substitution groups are not supported`,
+		},
+		{
+			name: "identity constraint",
+			schema: `<xs:element name="a">
+    <xs:complexType/>
+    <xs:key name="k"><xs:selector xpath="b"/><xs:field xpath="@id"/></xs:key>
+  </xs:element>`,
+			err: `identity constraint xs:key is not supported`,
+		},
+		{
+			name:   "undeclared type",
+			schema: `<xs:element name="a" type="missing"/>`,
+			err:    `type missing is not declared by the schema`,
+		},
+		{
+			name:   "undeclared group",
+			schema: `<xs:complexType name="a"><xs:group ref="missing"/></xs:complexType>`,
+			err:    `group missing is not declared by the schema`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := parseXSDSchema("schema.xsd", func(string) ([]byte, error) {
+				return []byte(`<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+  ` + test.schema + `
+</xs:schema>`), nil
+			})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.err)
+		})
+	}
+}
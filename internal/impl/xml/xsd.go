@@ -0,0 +1,1777 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xml
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"path"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html/charset"
+)
+
+const (
+	xsdNamespace  = "http://www.w3.org/2001/XMLSchema"
+	xsiNamespace  = "http://www.w3.org/2001/XMLSchema-instance"
+	xmlNamespace  = "http://www.w3.org/XML/1998/namespace"
+	xmlnsPrefix   = "xmlns"
+	xsdMaxErrors  = 100
+	xsdUnbounded  = -1
+	xsdAnyTypeTag = "anyType"
+)
+
+// xmlNode is a generic element of a parsed XML document.
+type xmlNode struct {
+	name     xml.Name
+	attrs    []xml.Attr
+	children []*xmlNode
+	text     string
+	line     int
+
+	// Namespace prefixes in scope, which are only tracked for schemas as they
+	// are needed in order to resolve type references.
+	ns map[string]string
+}
+
+func (n *xmlNode) attr(local string) (string, bool) {
+	for _, a := range n.attrs {
+		if a.Name.Space == "" && a.Name.Local == local {
+			return a.Value, true
+		}
+	}
+	return "", false
+}
+
+func parseXMLNodes(b []byte, trackNS bool) (*xmlNode, error) {
+	dec := xml.NewDecoder(bytes.NewReader(b))
+	dec.CharsetReader = charset.NewReaderLabel
+
+	var root *xmlNode
+	var stack []*xmlNode
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			line, _ := dec.InputPos()
+			n := &xmlNode{name: t.Name, attrs: t.Attr, line: line}
+			if trackNS {
+				n.ns = map[string]string{}
+				if len(stack) > 0 {
+					for k, v := range stack[len(stack)-1].ns {
+						n.ns[k] = v
+					}
+				}
+				for _, a := range t.Attr {
+					if a.Name.Space == xmlnsPrefix {
+						n.ns[a.Name.Local] = a.Value
+					} else if a.Name.Space == "" && a.Name.Local == xmlnsPrefix {
+						n.ns[""] = a.Value
+					}
+				}
+			}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, n)
+			} else if root != nil {
+				return nil, errors.New("document contains multiple root elements")
+			} else {
+				root = n
+			}
+			stack = append(stack, n)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text += string(t)
+			}
+		}
+	}
+	if root == nil {
+		return nil, errors.New("document contains no root element")
+	}
+	return root, nil
+}
+
+//------------------------------------------------------------------------------
+
+type xsdQName struct {
+	space string
+	local string
+}
+
+func (q xsdQName) isBuiltin() bool {
+	return q.space == xsdNamespace
+}
+
+// String returns the name in the form {namespace}local when it has a
+// namespace, which is how names are reported by validation errors.
+func (q xsdQName) String() string {
+	if q.space == "" {
+		return q.local
+	}
+	return "{" + q.space + "}" + q.local
+}
+
+func nodeXSDName(n *xmlNode) xsdQName {
+	return xsdQName{space: n.name.Space, local: n.name.Local}
+}
+
+type xsdFacets struct {
+	enumeration    []string
+	patterns       []*regexp.Regexp
+	length         *int
+	minLength      *int
+	maxLength      *int
+	minInclusive   *string
+	maxInclusive   *string
+	minExclusive   *string
+	maxExclusive   *string
+	totalDigits    *int
+	fractionDigits *int
+}
+
+type xsdSimpleType struct {
+	base       xsdQName
+	baseSimple *xsdSimpleType
+	listItem   *xsdQName
+	listSimple *xsdSimpleType
+	union      []xsdQName
+	unionTypes []*xsdSimpleType
+	facets     xsdFacets
+}
+
+type xsdAttribute struct {
+	name     xsdQName
+	ref      xsdQName
+	typeName xsdQName
+	simple   *xsdSimpleType
+	fixed    *string
+	required bool
+}
+
+type xsdElement struct {
+	name      xsdQName
+	ref       xsdQName
+	typeName  xsdQName
+	complex   *xsdComplexType
+	simple    *xsdSimpleType
+	fixed     *string
+	nillable  bool
+	minOccurs int
+	maxOccurs int
+}
+
+// xsdWildcard is an xs:any or xs:anyAttribute, which matches elements or
+// attributes of the namespaces that it allows.
+type xsdWildcard struct {
+	// Set for ##other, which allows namespaces other than the target namespace
+	// and excludes names without a namespace.
+	other     bool
+	namespace string
+
+	// The namespaces allowed, where names without a namespace are allowed by
+	// the empty string. Nil allows any namespace.
+	allowed map[string]struct{}
+
+	// Either strict, lax or skip.
+	processContents string
+}
+
+func (w *xsdWildcard) allows(space string) bool {
+	if w.other {
+		return space != w.namespace && space != ""
+	}
+	if w.allowed == nil {
+		return true
+	}
+	_, exists := w.allowed[space]
+	return exists
+}
+
+type xsdParticle struct {
+	kind      string
+	element   *xsdElement
+	wildcard  *xsdWildcard
+	children  []*xsdParticle
+	groupRef  xsdQName
+	minOccurs int
+	maxOccurs int
+}
+
+type xsdComplexType struct {
+	mixed        bool
+	abstract     bool
+	particle     *xsdParticle
+	attributes   []*xsdAttribute
+	attrGroups   []xsdQName
+	anyAttribute *xsdWildcard
+
+	// Set for complex types deriving from another complex type.
+	base      xsdQName
+	extension bool
+
+	// Set for complex types with simple content.
+	simpleContent *xsdSimpleType
+}
+
+// xsdRef is a reference from one declaration to another, which are resolved
+// once all schemas are loaded so that declarations can be referenced before
+// they are declared.
+type xsdRef struct {
+	kind string
+	name xsdQName
+	at   string
+}
+
+type xsdSchema struct {
+	elements    map[xsdQName]*xsdElement
+	complexes   map[xsdQName]*xsdComplexType
+	simples     map[xsdQName]*xsdSimpleType
+	attributes  map[xsdQName]*xsdAttribute
+	groups      map[xsdQName]*xsdParticle
+	attrGroups  map[xsdQName]*xsdComplexType
+	effectiveCT map[*xsdComplexType]*xsdComplexType
+
+	refs []xsdRef
+}
+
+// parseXSDSchema parses a schema, where readFile is used to read the schema,
+// along with any schemas it includes or imports relative to it.
+func parseXSDSchema(schemaPath string, readFile func(string) ([]byte, error)) (*xsdSchema, error) {
+	s := &xsdSchema{
+		elements:    map[xsdQName]*xsdElement{},
+		complexes:   map[xsdQName]*xsdComplexType{},
+		simples:     map[xsdQName]*xsdSimpleType{},
+		attributes:  map[xsdQName]*xsdAttribute{},
+		groups:      map[xsdQName]*xsdParticle{},
+		attrGroups:  map[xsdQName]*xsdComplexType{},
+		effectiveCT: map[*xsdComplexType]*xsdComplexType{},
+	}
+	if err := s.load(xsdLocation{path: schemaPath}, readFile, map[xsdLocation]struct{}{}); err != nil {
+		return nil, err
+	}
+
+	// Declarations that are referenced but not declared would otherwise be
+	// validated as if they allowed any content.
+	for _, r := range s.refs {
+		if !s.declares(r.kind, r.name) {
+			return nil, fmt.Errorf("%v: %v %v is not declared by the schema", r.at, r.kind, r.name)
+		}
+	}
+	return s, nil
+}
+
+func (s *xsdSchema) declares(kind string, name xsdQName) bool {
+	var exists bool
+	switch kind {
+	case "element":
+		_, exists = s.elements[name]
+	case "type":
+		if _, exists = s.complexes[name]; !exists {
+			_, exists = s.simples[name]
+		}
+	case "simple type":
+		_, exists = s.simples[name]
+	case "attribute":
+		_, exists = s.attributes[name]
+	case "group":
+		_, exists = s.groups[name]
+	case "attribute group":
+		_, exists = s.attrGroups[name]
+	}
+	return exists
+}
+
+func isXSD(n *xmlNode, local string) bool {
+	return n.name.Space == xsdNamespace && n.name.Local == local
+}
+
+// xsdLocation is a schema to load, along with the namespace that it must
+// target when it is included or imported by another schema.
+type xsdLocation struct {
+	path      string
+	checkNS   bool
+	namespace string
+
+	// Set for includes, where a schema without a target namespace takes the
+	// namespace of the schema including it.
+	chameleon bool
+}
+
+// xsdDoc is the context of the schema document that declarations are parsed
+// from.
+type xsdDoc struct {
+	schema *xsdSchema
+	path   string
+
+	targetNamespace     string
+	chameleon           bool
+	qualifiedElements   bool
+	qualifiedAttributes bool
+}
+
+func (s *xsdSchema) load(loc xsdLocation, readFile func(string) ([]byte, error), seen map[xsdLocation]struct{}) error {
+	if _, exists := seen[loc]; exists {
+		return nil
+	}
+	seen[loc] = struct{}{}
+
+	b, err := readFile(loc.path)
+	if err != nil {
+		return err
+	}
+	root, err := parseXMLNodes(b, true)
+	if err != nil {
+		return fmt.Errorf("failed to parse schema %v: %w", loc.path, err)
+	}
+	if !isXSD(root, "schema") {
+		return fmt.Errorf("schema %v: root element is not an xs:schema", loc.path)
+	}
+
+	d := &xsdDoc{schema: s, path: loc.path}
+	d.targetNamespace, _ = root.attr("targetNamespace")
+	if loc.checkNS && d.targetNamespace != loc.namespace {
+		if !loc.chameleon || d.targetNamespace != "" {
+			return fmt.Errorf("schema %v: target namespace %q does not match the expected namespace %q", loc.path, d.targetNamespace, loc.namespace)
+		}
+		d.targetNamespace, d.chameleon = loc.namespace, true
+	}
+	if v, _ := root.attr("elementFormDefault"); v == "qualified" {
+		d.qualifiedElements = true
+	}
+	if v, _ := root.attr("attributeFormDefault"); v == "qualified" {
+		d.qualifiedAttributes = true
+	}
+
+	for _, c := range root.children {
+		if err := d.loadTopLevel(c, readFile, seen); err != nil {
+			return fmt.Errorf("schema %v (line %v): %w", loc.path, c.line, err)
+		}
+	}
+	return nil
+}
+
+func (d *xsdDoc) loadTopLevel(c *xmlNode, readFile func(string) ([]byte, error), seen map[xsdLocation]struct{}) error {
+	if c.name.Space != xsdNamespace {
+		return nil
+	}
+	name, _ := c.attr("name")
+	qName := xsdQName{space: d.targetNamespace, local: name}
+
+	s := d.schema
+	switch c.name.Local {
+	case "include", "import":
+		schemaLoc, ok := c.attr("schemaLocation")
+		if !ok {
+			// Imports without a location reference namespaces that are not
+			// validated, such as the XML namespace, and declarations of them
+			// fail to resolve.
+			return nil
+		}
+		if !path.IsAbs(schemaLoc) {
+			schemaLoc = path.Join(path.Dir(d.path), schemaLoc)
+		}
+		loc := xsdLocation{path: schemaLoc, checkNS: true, namespace: d.targetNamespace, chameleon: true}
+		if c.name.Local == "import" {
+			loc.namespace, _ = c.attr("namespace")
+			loc.chameleon = false
+		}
+		return s.load(loc, readFile, seen)
+	case "element":
+		el, err := d.parseElement(c, true)
+		if err != nil {
+			return err
+		}
+		s.elements[qName] = el
+	case "complexType":
+		ct, err := d.parseComplexType(c)
+		if err != nil {
+			return err
+		}
+		s.complexes[qName] = ct
+	case "simpleType":
+		st, err := d.parseSimpleType(c)
+		if err != nil {
+			return err
+		}
+		s.simples[qName] = st
+	case "attribute":
+		attr, err := d.parseAttribute(c, true)
+		if err != nil {
+			return err
+		}
+		s.attributes[qName] = attr
+	case "group":
+		for _, gc := range c.children {
+			if isXSD(gc, "sequence") || isXSD(gc, "choice") || isXSD(gc, "all") {
+				p, err := d.parseParticle(gc)
+				if err != nil {
+					return err
+				}
+				s.groups[qName] = p
+			}
+		}
+	case "attributeGroup":
+		ct := &xsdComplexType{}
+		if err := d.parseAttributes(c, ct); err != nil {
+			return err
+		}
+		s.attrGroups[qName] = ct
+	case "annotation", "notation":
+	default:
+		// Such as xs:redefine, which modifies the declarations of another
+		// schema.
+		return fmt.Errorf("xs:%v is not supported", c.name.Local)
+	}
+	return nil
+}
+
+// ref resolves a reference to a declaration of a kind, which is checked once
+// all schemas are loaded.
+func (d *xsdDoc) ref(n *xmlNode, kind, v string) xsdQName {
+	q := d.resolve(n, v)
+	if !q.isBuiltin() {
+		d.schema.refs = append(d.schema.refs, xsdRef{
+			kind: kind,
+			name: q,
+			at:   fmt.Sprintf("schema %v (line %v)", d.path, n.line),
+		})
+	}
+	return q
+}
+
+func (d *xsdDoc) resolve(n *xmlNode, v string) xsdQName {
+	q := resolveXSDQName(n, v)
+	if q.space == "" && d.chameleon {
+		q.space = d.targetNamespace
+	}
+	return q
+}
+
+// localName returns the name of a local element or attribute declaration,
+// which only has a namespace when it is qualified.
+func (d *xsdDoc) localName(n *xmlNode, name string, global, qualifiedDefault bool) xsdQName {
+	qualified := global || qualifiedDefault
+	if form, ok := n.attr("form"); ok {
+		qualified = global || form == "qualified"
+	}
+	if !qualified {
+		return xsdQName{local: name}
+	}
+	return xsdQName{space: d.targetNamespace, local: name}
+}
+
+func resolveXSDQName(n *xmlNode, v string) xsdQName {
+	prefix, local, found := strings.Cut(v, ":")
+	if !found {
+		return xsdQName{space: n.ns[""], local: v}
+	}
+	return xsdQName{space: n.ns[prefix], local: local}
+}
+
+func parseXSDOccurs(n *xmlNode) (minOccurs, maxOccurs int, err error) {
+	minOccurs, maxOccurs = 1, 1
+	if v, ok := n.attr("minOccurs"); ok {
+		if minOccurs, err = strconv.Atoi(v); err != nil {
+			return 0, 0, fmt.Errorf("invalid minOccurs: %w", err)
+		}
+	}
+	if v, ok := n.attr("maxOccurs"); ok {
+		if v == "unbounded" {
+			maxOccurs = xsdUnbounded
+		} else if maxOccurs, err = strconv.Atoi(v); err != nil {
+			return 0, 0, fmt.Errorf("invalid maxOccurs: %w", err)
+		}
+	}
+	return
+}
+
+func (d *xsdDoc) parseElement(n *xmlNode, global bool) (*xsdElement, error) {
+	if _, ok := n.attr("substitutionGroup"); ok {
+		return nil, errors.New("substitution groups are not supported")
+	}
+	if v, _ := n.attr("abstract"); v == "true" {
+		return nil, errors.New("abstract elements are not supported")
+	}
+
+	el := &xsdElement{}
+	if name, ok := n.attr("name"); ok {
+		el.name = d.localName(n, name, global, d.qualifiedElements)
+	}
+	if ref, ok := n.attr("ref"); ok {
+		el.ref = d.ref(n, "element", ref)
+	}
+	if t, ok := n.attr("type"); ok {
+		el.typeName = d.ref(n, "type", t)
+	}
+	if v, ok := n.attr("fixed"); ok {
+		el.fixed = &v
+	}
+	if v, _ := n.attr("nillable"); v == "true" {
+		el.nillable = true
+	}
+
+	var err error
+	if el.minOccurs, el.maxOccurs, err = parseXSDOccurs(n); err != nil {
+		return nil, err
+	}
+	if el.name.local == "" && el.ref.local == "" {
+		return nil, errors.New("element requires a name or ref")
+	}
+
+	for _, c := range n.children {
+		switch {
+		case isXSD(c, "complexType"):
+			if el.complex, err = d.parseComplexType(c); err != nil {
+				return nil, err
+			}
+		case isXSD(c, "simpleType"):
+			if el.simple, err = d.parseSimpleType(c); err != nil {
+				return nil, err
+			}
+		case isXSD(c, "key"), isXSD(c, "keyref"), isXSD(c, "unique"):
+			return nil, fmt.Errorf("identity constraint xs:%v is not supported", c.name.Local)
+		case isXSD(c, "alternative"):
+			return nil, errors.New("xs:alternative is not supported")
+		}
+	}
+	return el, nil
+}
+
+func (d *xsdDoc) parseWildcard(n *xmlNode) *xsdWildcard {
+	w := &xsdWildcard{namespace: d.targetNamespace, processContents: "strict"}
+	if v, ok := n.attr("processContents"); ok {
+		w.processContents = v
+	}
+	switch v, _ := n.attr("namespace"); v {
+	case "", "##any":
+	case "##other":
+		w.other = true
+	default:
+		w.allowed = map[string]struct{}{}
+		for _, space := range strings.Fields(v) {
+			switch space {
+			case "##targetNamespace":
+				space = d.targetNamespace
+			case "##local":
+				space = ""
+			}
+			w.allowed[space] = struct{}{}
+		}
+	}
+	return w
+}
+
+func (d *xsdDoc) parseParticle(n *xmlNode) (*xsdParticle, error) {
+	p := &xsdParticle{kind: n.name.Local}
+
+	var err error
+	if p.minOccurs, p.maxOccurs, err = parseXSDOccurs(n); err != nil {
+		return nil, err
+	}
+
+	switch n.name.Local {
+	case "element":
+		if p.element, err = d.parseElement(n, false); err != nil {
+			return nil, err
+		}
+		return p, nil
+	case "any":
+		p.wildcard = d.parseWildcard(n)
+		return p, nil
+	case "group":
+		ref, ok := n.attr("ref")
+		if !ok {
+			return nil, errors.New("group reference requires a ref")
+		}
+		p.groupRef = d.ref(n, "group", ref)
+		return p, nil
+	case "sequence", "choice", "all":
+	default:
+		return nil, fmt.Errorf("unsupported particle %v", n.name.Local)
+	}
+
+	for _, c := range n.children {
+		if c.name.Space != xsdNamespace || c.name.Local == "annotation" {
+			continue
+		}
+		cp, err := d.parseParticle(c)
+		if err != nil {
+			return nil, err
+		}
+		p.children = append(p.children, cp)
+	}
+	return p, nil
+}
+
+func (d *xsdDoc) parseAttribute(n *xmlNode, global bool) (*xsdAttribute, error) {
+	attr := &xsdAttribute{}
+	if name, ok := n.attr("name"); ok {
+		attr.name = d.localName(n, name, global, d.qualifiedAttributes)
+	}
+	if ref, ok := n.attr("ref"); ok {
+		attr.ref = d.ref(n, "attribute", ref)
+	}
+	if t, ok := n.attr("type"); ok {
+		attr.typeName = d.ref(n, "simple type", t)
+	}
+	if v, ok := n.attr("fixed"); ok {
+		attr.fixed = &v
+	}
+	if use, _ := n.attr("use"); use == "required" {
+		attr.required = true
+	}
+	if attr.name.local == "" && attr.ref.local == "" {
+		return nil, errors.New("attribute requires a name or ref")
+	}
+	for _, c := range n.children {
+		if isXSD(c, "simpleType") {
+			var err error
+			if attr.simple, err = d.parseSimpleType(c); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return attr, nil
+}
+
+// parseAttributes parses the attribute declarations within a node into a
+// complex type.
+func (d *xsdDoc) parseAttributes(n *xmlNode, ct *xsdComplexType) error {
+	for _, c := range n.children {
+		switch {
+		case isXSD(c, "attribute"):
+			attr, err := d.parseAttribute(c, false)
+			if err != nil {
+				return err
+			}
+			ct.attributes = append(ct.attributes, attr)
+		case isXSD(c, "attributeGroup"):
+			if ref, ok := c.attr("ref"); ok {
+				ct.attrGroups = append(ct.attrGroups, d.ref(c, "attribute group", ref))
+			}
+		case isXSD(c, "anyAttribute"):
+			ct.anyAttribute = d.parseWildcard(c)
+		case isXSD(c, "assert"):
+			return errors.New("xs:assert is not supported")
+		}
+	}
+	return nil
+}
+
+func (d *xsdDoc) parseComplexType(n *xmlNode) (*xsdComplexType, error) {
+	ct := &xsdComplexType{}
+	if v, _ := n.attr("mixed"); v == "true" {
+		ct.mixed = true
+	}
+	if v, _ := n.attr("abstract"); v == "true" {
+		ct.abstract = true
+	}
+
+	for _, c := range n.children {
+		switch {
+		case isXSD(c, "sequence"), isXSD(c, "choice"), isXSD(c, "all"), isXSD(c, "group"):
+			p, err := d.parseParticle(c)
+			if err != nil {
+				return nil, err
+			}
+			ct.particle = p
+		case isXSD(c, "complexContent"), isXSD(c, "simpleContent"):
+			if v, _ := c.attr("mixed"); v == "true" {
+				ct.mixed = true
+			}
+			for _, e := range c.children {
+				if !isXSD(e, "extension") && !isXSD(e, "restriction") {
+					continue
+				}
+				base, _ := e.attr("base")
+				ct.base = d.ref(e, "type", base)
+				ct.extension = e.name.Local == "extension"
+				if isXSD(c, "simpleContent") {
+					st, err := d.parseRestriction(e)
+					if err != nil {
+						return nil, err
+					}
+					ct.simpleContent = st
+				}
+				for _, f := range e.children {
+					if isXSD(f, "sequence") || isXSD(f, "choice") || isXSD(f, "all") || isXSD(f, "group") {
+						p, err := d.parseParticle(f)
+						if err != nil {
+							return nil, err
+						}
+						ct.particle = p
+					}
+				}
+				if err := d.parseAttributes(e, ct); err != nil {
+					return nil, err
+				}
+			}
+		case isXSD(c, "openContent"):
+			return nil, errors.New("xs:openContent is not supported")
+		}
+	}
+	if err := d.parseAttributes(n, ct); err != nil {
+		return nil, err
+	}
+	return ct, nil
+}
+
+func (d *xsdDoc) parseSimpleType(n *xmlNode) (*xsdSimpleType, error) {
+	for _, c := range n.children {
+		switch {
+		case isXSD(c, "restriction"):
+			return d.parseRestriction(c)
+		case isXSD(c, "list"):
+			st := &xsdSimpleType{}
+			if item, ok := c.attr("itemType"); ok {
+				q := d.ref(c, "simple type", item)
+				st.listItem = &q
+			}
+			for _, e := range c.children {
+				if isXSD(e, "simpleType") {
+					var err error
+					if st.listSimple, err = d.parseSimpleType(e); err != nil {
+						return nil, err
+					}
+				}
+			}
+			if st.listItem == nil && st.listSimple == nil {
+				return nil, errors.New("list requires an item type")
+			}
+			return st, nil
+		case isXSD(c, "union"):
+			st := &xsdSimpleType{}
+			if members, ok := c.attr("memberTypes"); ok {
+				for _, m := range strings.Fields(members) {
+					st.union = append(st.union, d.ref(c, "simple type", m))
+				}
+			}
+			for _, e := range c.children {
+				if isXSD(e, "simpleType") {
+					ust, err := d.parseSimpleType(e)
+					if err != nil {
+						return nil, err
+					}
+					st.unionTypes = append(st.unionTypes, ust)
+				}
+			}
+			return st, nil
+		}
+	}
+	return nil, errors.New("simple type requires a restriction, list or union")
+}
+
+// parseRestriction parses the base and facets of a restriction, or the base
+// of an extension.
+func (d *xsdDoc) parseRestriction(n *xmlNode) (*xsdSimpleType, error) {
+	st := &xsdSimpleType{}
+	if base, ok := n.attr("base"); ok {
+		st.base = d.ref(n, "type", base)
+	}
+
+	intFacet := func(c *xmlNode) (*int, error) {
+		v, _ := c.attr("value")
+		i, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %v facet: %w", c.name.Local, err)
+		}
+		return &i, nil
+	}
+	strFacet := func(c *xmlNode) *string {
+		v, _ := c.attr("value")
+		return &v
+	}
+
+	var err error
+	for _, c := range n.children {
+		if c.name.Space != xsdNamespace {
+			continue
+		}
+		switch c.name.Local {
+		case "simpleType":
+			if st.baseSimple, err = d.parseSimpleType(c); err != nil {
+				return nil, err
+			}
+		case "enumeration":
+			v, _ := c.attr("value")
+			st.facets.enumeration = append(st.facets.enumeration, v)
+		case "pattern":
+			v, _ := c.attr("value")
+			re, err := regexp.Compile("^(?:" + v + ")$")
+			if err != nil {
+				return nil, fmt.Errorf("unsupported pattern %q: %w", v, err)
+			}
+			st.facets.patterns = append(st.facets.patterns, re)
+		case "length":
+			st.facets.length, err = intFacet(c)
+		case "minLength":
+			st.facets.minLength, err = intFacet(c)
+		case "maxLength":
+			st.facets.maxLength, err = intFacet(c)
+		case "totalDigits":
+			st.facets.totalDigits, err = intFacet(c)
+		case "fractionDigits":
+			st.facets.fractionDigits, err = intFacet(c)
+		case "minInclusive":
+			st.facets.minInclusive = strFacet(c)
+		case "maxInclusive":
+			st.facets.maxInclusive = strFacet(c)
+		case "minExclusive":
+			st.facets.minExclusive = strFacet(c)
+		case "maxExclusive":
+			st.facets.maxExclusive = strFacet(c)
+		case "assertion":
+			return nil, errors.New("xs:assertion is not supported")
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return st, nil
+}
+
+//------------------------------------------------------------------------------
+
+// xsdValidationError describes a single violation of a schema within a
+// document.
+type xsdValidationError struct {
+	Path    string
+	Line    int
+	Message string
+}
+
+func (e xsdValidationError) Error() string {
+	return fmt.Sprintf("%v (line %v): %v", e.Path, e.Line, e.Message)
+}
+
+type xsdDecl struct {
+	repeated bool
+	simple   *xsdSimpleType
+	complex  *xsdComplexType
+}
+
+type xsdValidator struct {
+	schema *xsdSchema
+	errs   []xsdValidationError
+	decls  map[*xmlNode]xsdDecl
+}
+
+func (s *xsdSchema) validate(root *xmlNode) *xsdValidator {
+	v := &xsdValidator{schema: s, decls: map[*xmlNode]xsdDecl{}}
+
+	rootPath := "/" + root.name.Local
+	el, exists := s.elements[nodeXSDName(root)]
+	if !exists {
+		v.fail(root, rootPath, "element %v is not declared by the schema", nodeXSDName(root))
+		return v
+	}
+	v.validateElement(root, el, rootPath, false)
+	return v
+}
+
+func (v *xsdValidator) fail(n *xmlNode, nodePath, format string, args ...any) {
+	if len(v.errs) >= xsdMaxErrors {
+		return
+	}
+	v.errs = append(v.errs, xsdValidationError{
+		Path:    nodePath,
+		Line:    n.line,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func (v *xsdValidator) resolveElement(el *xsdElement) *xsdElement {
+	if el.ref.local == "" {
+		return el
+	}
+	if global, exists := v.schema.elements[el.ref]; exists {
+		return global
+	}
+	return el
+}
+
+func (v *xsdValidator) elementName(el *xsdElement) xsdQName {
+	if el.ref.local != "" {
+		return el.ref
+	}
+	return el.name
+}
+
+func isXSDNamespaceAttr(a xml.Attr) bool {
+	return a.Name.Space == xmlnsPrefix || (a.Name.Space == "" && a.Name.Local == xmlnsPrefix) || a.Name.Space == xsiNamespace || a.Name.Space == xmlNamespace
+}
+
+// checkFixed checks that a value equals the fixed value of a declaration,
+// where values are compared in their structured form such that 1.0 equals 1
+// for decimals.
+func (v *xsdValidator) checkFixed(st *xsdSimpleType, fixed *string, value string) error {
+	if fixed == nil {
+		return nil
+	}
+	if st != nil {
+		want, wantErr := v.checkSimple(st, *fixed)
+		got, gotErr := v.checkSimple(st, value)
+		if wantErr == nil && gotErr == nil && reflect.DeepEqual(want, got) {
+			return nil
+		}
+	} else if value == *fixed {
+		return nil
+	}
+	return fmt.Errorf("value %q does not equal the fixed value %q", value, *fixed)
+}
+
+func (v *xsdValidator) validateElement(n *xmlNode, el *xsdElement, nodePath string, repeated bool) {
+	decl := v.resolveElement(el)
+	if decl != el && decl.ref.local == "" {
+		// Occurrences belong to the reference whereas the type belongs to the
+		// referenced declaration.
+		el = decl
+	}
+
+	var simple *xsdSimpleType
+	var complex *xsdComplexType
+	switch {
+	case el.complex != nil:
+		complex = el.complex
+	case el.simple != nil:
+		simple = el.simple
+	case el.typeName.local == "":
+	case el.typeName.isBuiltin():
+		if el.typeName.local != xsdAnyTypeTag {
+			simple = &xsdSimpleType{base: el.typeName}
+		}
+	default:
+		if ct, exists := v.schema.complexes[el.typeName]; exists {
+			if ct.abstract {
+				v.fail(n, nodePath, "type %v is abstract", el.typeName)
+				return
+			}
+			complex = ct
+		} else if st, exists := v.schema.simples[el.typeName]; exists {
+			simple = st
+		} else {
+			v.fail(n, nodePath, "type %v is not declared by the schema", el.typeName)
+			return
+		}
+	}
+	v.decls[n] = xsdDecl{repeated: repeated, simple: simple, complex: complex}
+
+	for _, a := range n.attrs {
+		if a.Name.Space == xsiNamespace && a.Name.Local == "type" {
+			// The type of an element is not substituted by the type that the
+			// document names, and so it is rejected rather than validated
+			// against the declared type.
+			v.fail(n, nodePath, "xsi:type is not supported")
+			return
+		}
+		if a.Name.Space == xsiNamespace && a.Name.Local == "nil" && (a.Value == "true" || a.Value == "1") {
+			if !el.nillable {
+				v.fail(n, nodePath, "element is not nillable")
+			}
+			if len(n.children) > 0 || strings.TrimSpace(n.text) != "" {
+				v.fail(n, nodePath, "nil element must be empty")
+			}
+			return
+		}
+	}
+
+	switch {
+	case simple != nil:
+		for _, a := range n.attrs {
+			if !isXSDNamespaceAttr(a) {
+				v.fail(n, nodePath, "attribute %v is not allowed", xsdQName{space: a.Name.Space, local: a.Name.Local})
+			}
+		}
+		if len(n.children) > 0 {
+			v.fail(n, nodePath, "element must not contain child elements")
+			return
+		}
+		if _, err := v.checkSimple(simple, n.text); err != nil {
+			v.fail(n, nodePath, "%v", err)
+		} else if n.text != "" {
+			// Empty elements take the fixed value.
+			if err := v.checkFixed(simple, el.fixed, n.text); err != nil {
+				v.fail(n, nodePath, "%v", err)
+			}
+		}
+	case complex != nil:
+		v.validateComplex(n, v.schema.effective(complex), el.fixed, nodePath)
+	}
+}
+
+func (v *xsdValidator) validateComplex(n *xmlNode, ct *xsdComplexType, fixed *string, nodePath string) {
+	attrs := v.schema.attributesOf(ct)
+	present := map[xsdQName]struct{}{}
+	for _, a := range n.attrs {
+		if isXSDNamespaceAttr(a) {
+			continue
+		}
+		name := xsdQName{space: a.Name.Space, local: a.Name.Local}
+		present[name] = struct{}{}
+		decl, exists := attrs[name]
+		if !exists && ct.anyAttribute != nil && ct.anyAttribute.allows(name.space) {
+			if ct.anyAttribute.processContents == "skip" {
+				continue
+			}
+			if decl, exists = v.schema.attributes[name]; !exists {
+				if ct.anyAttribute.processContents == "strict" {
+					v.fail(n, nodePath, "attribute %v is not declared by the schema", name)
+				}
+				continue
+			}
+		}
+		if !exists {
+			v.fail(n, nodePath, "attribute %v is not allowed", name)
+			continue
+		}
+		if st := v.schema.attributeType(decl); st != nil {
+			if _, err := v.checkSimple(st, a.Value); err != nil {
+				v.fail(n, nodePath, "attribute %v: %v", name, err)
+				continue
+			}
+		}
+		if err := v.checkFixed(v.schema.attributeType(decl), decl.fixed, a.Value); err != nil {
+			v.fail(n, nodePath, "attribute %v: %v", name, err)
+		}
+	}
+	for name, decl := range attrs {
+		if _, exists := present[name]; !exists && decl.required {
+			v.fail(n, nodePath, "missing required attribute %v", name)
+		}
+	}
+
+	if ct.simpleContent != nil {
+		if len(n.children) > 0 {
+			v.fail(n, nodePath, "element must not contain child elements")
+			return
+		}
+		if _, err := v.checkSimple(ct.simpleContent, n.text); err != nil {
+			v.fail(n, nodePath, "%v", err)
+		} else if n.text != "" {
+			if err := v.checkFixed(ct.simpleContent, fixed, n.text); err != nil {
+				v.fail(n, nodePath, "%v", err)
+			}
+		}
+		return
+	}
+
+	if !ct.mixed && strings.TrimSpace(n.text) != "" {
+		v.fail(n, nodePath, "text content is not allowed")
+	}
+
+	if ct.particle == nil {
+		if len(n.children) > 0 {
+			v.fail(n.children[0], v.childPath(n, 0, nodePath), "unexpected element %v, element must be empty", nodeXSDName(n.children[0]))
+		}
+		return
+	}
+
+	pos, ok := v.match(ct.particle, n, 0, nodePath, false)
+	if !ok {
+		v.fail(n, nodePath, "missing required element %v", strings.Join(v.firstNames(ct.particle), " or "))
+	}
+	if pos < len(n.children) {
+		c := n.children[pos]
+		v.fail(c, v.childPath(n, pos, nodePath), "unexpected element %v", nodeXSDName(c))
+	}
+}
+
+// childPath returns the path of a child element, which includes the index of
+// the child amongst its siblings of the same name when there are several.
+func (v *xsdValidator) childPath(parent *xmlNode, i int, parentPath string) string {
+	name := parent.children[i].name.Local
+	var index, count int
+	for j, c := range parent.children {
+		if c.name.Local == name {
+			count++
+			if j <= i {
+				index = count
+			}
+		}
+	}
+	if count > 1 {
+		return fmt.Sprintf("%v/%v[%v]", parentPath, name, index)
+	}
+	return parentPath + "/" + name
+}
+
+func (v *xsdValidator) group(p *xsdParticle) *xsdParticle {
+	if p.kind != "group" {
+		return p
+	}
+	if g, exists := v.schema.groups[p.groupRef]; exists {
+		return g
+	}
+	return &xsdParticle{kind: "sequence", minOccurs: 1, maxOccurs: 1}
+}
+
+func (v *xsdValidator) nullable(p *xsdParticle) bool {
+	if p.minOccurs == 0 {
+		return true
+	}
+	switch p.kind {
+	case "element", "any":
+		return false
+	case "group":
+		return v.nullable(v.group(p))
+	case "choice":
+		for _, c := range p.children {
+			if v.nullable(c) {
+				return true
+			}
+		}
+		return len(p.children) == 0
+	}
+	for _, c := range p.children {
+		if !v.nullable(c) {
+			return false
+		}
+	}
+	return true
+}
+
+func (v *xsdValidator) canStart(p *xsdParticle, name xsdQName) bool {
+	switch p.kind {
+	case "element":
+		return v.elementName(p.element) == name
+	case "any":
+		return p.wildcard.allows(name.space)
+	case "group":
+		return v.canStart(v.group(p), name)
+	case "sequence":
+		for _, c := range p.children {
+			if v.canStart(c, name) {
+				return true
+			}
+			if !v.nullable(c) {
+				return false
+			}
+		}
+		return false
+	}
+	for _, c := range p.children {
+		if v.canStart(c, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *xsdValidator) firstNames(p *xsdParticle) []string {
+	switch p.kind {
+	case "element":
+		return []string{v.elementName(p.element).String()}
+	case "any":
+		return []string{"any element"}
+	case "group":
+		return v.firstNames(v.group(p))
+	case "sequence":
+		var names []string
+		for _, c := range p.children {
+			names = append(names, v.firstNames(c)...)
+			if !v.nullable(c) {
+				break
+			}
+		}
+		return names
+	}
+	var names []string
+	for _, c := range p.children {
+		names = append(names, v.firstNames(c)...)
+	}
+	return names
+}
+
+// match matches a particle against the children of an element from a
+// position, returning the position after the matched children and false if
+// the particle did not occur the minimum number of times.
+func (v *xsdValidator) match(p *xsdParticle, parent *xmlNode, pos int, parentPath string, repeated bool) (int, bool) {
+	repeated = repeated || p.maxOccurs != 1
+
+	count := 0
+	for p.maxOccurs == xsdUnbounded || count < p.maxOccurs {
+		next, ok := v.matchOnce(p, parent, pos, parentPath, repeated, count < p.minOccurs)
+		if !ok {
+			break
+		}
+		if next == pos {
+			// The particle matched without consuming any children, and
+			// would continue to do so.
+			count = max(count, p.minOccurs)
+			break
+		}
+		pos = next
+		count++
+	}
+	return pos, count >= p.minOccurs
+}
+
+// matchOnce matches a single occurrence of a particle. When required is true
+// sequences are matched even when the current child cannot start them, so that
+// the elements missing from them are reported.
+func (v *xsdValidator) matchOnce(p *xsdParticle, parent *xmlNode, pos int, parentPath string, repeated, required bool) (int, bool) {
+	children := parent.children
+	var current xsdQName
+	if pos < len(children) {
+		current = nodeXSDName(children[pos])
+	}
+
+	switch p.kind {
+	case "element":
+		if pos >= len(children) || current != v.elementName(p.element) {
+			return pos, false
+		}
+		v.validateElement(children[pos], p.element, v.childPath(parent, pos, parentPath), repeated)
+		return pos + 1, true
+	case "any":
+		if pos >= len(children) || !p.wildcard.allows(current.space) {
+			return pos, false
+		}
+		if p.wildcard.processContents != "skip" {
+			childPath := v.childPath(parent, pos, parentPath)
+			if el, exists := v.schema.elements[current]; exists {
+				v.validateElement(children[pos], el, childPath, repeated)
+			} else if p.wildcard.processContents == "strict" {
+				v.fail(children[pos], childPath, "element %v is not declared by the schema", current)
+			}
+		}
+		return pos + 1, true
+	case "group":
+		return v.match(v.group(p), parent, pos, parentPath, repeated)
+	case "sequence":
+		if !required && (pos >= len(children) || !v.canStart(p, current)) {
+			return pos, v.nullable(p)
+		}
+		for _, c := range p.children {
+			next, ok := v.match(c, parent, pos, parentPath, repeated)
+			if !ok {
+				at := parent
+				if next < len(children) {
+					at = children[next]
+				}
+				v.fail(at, parentPath, "missing required element %v", strings.Join(v.firstNames(c), " or "))
+			}
+			pos = next
+		}
+		return pos, true
+	case "choice":
+		if pos < len(children) {
+			for _, c := range p.children {
+				if v.canStart(c, current) {
+					return v.match(c, parent, pos, parentPath, repeated)
+				}
+			}
+		}
+		return pos, v.nullable(p)
+	case "all":
+		seen := map[*xsdParticle]struct{}{}
+		for pos < len(children) {
+			var matched *xsdParticle
+			for _, c := range p.children {
+				if _, done := seen[c]; !done && c.kind == "element" && v.elementName(c.element) == nodeXSDName(children[pos]) {
+					matched = c
+					break
+				}
+			}
+			if matched == nil {
+				break
+			}
+			seen[matched] = struct{}{}
+			v.validateElement(children[pos], matched.element, v.childPath(parent, pos, parentPath), repeated)
+			pos++
+		}
+		ok := true
+		for _, c := range p.children {
+			if _, done := seen[c]; !done && c.minOccurs > 0 {
+				ok = false
+			}
+		}
+		return pos, ok
+	}
+	return pos, false
+}
+
+// effective returns a complex type merged with the types it derives from.
+func (s *xsdSchema) effective(ct *xsdComplexType) *xsdComplexType {
+	if e, exists := s.effectiveCT[ct]; exists {
+		return e
+	}
+	if ct.base.local == "" || ct.base.isBuiltin() {
+		s.effectiveCT[ct] = ct
+		return ct
+	}
+
+	// Prevent cycles from recursing indefinitely.
+	s.effectiveCT[ct] = ct
+
+	e := *ct
+	if base, exists := s.complexes[ct.base]; exists {
+		baseE := s.effective(base)
+		if ct.extension {
+			e.attributes = append(append([]*xsdAttribute{}, baseE.attributes...), ct.attributes...)
+			e.attrGroups = append(append([]xsdQName{}, baseE.attrGroups...), ct.attrGroups...)
+			if e.anyAttribute == nil {
+				e.anyAttribute = baseE.anyAttribute
+			}
+			switch {
+			case baseE.particle == nil:
+			case ct.particle == nil:
+				e.particle = baseE.particle
+			default:
+				e.particle = &xsdParticle{
+					kind:      "sequence",
+					children:  []*xsdParticle{baseE.particle, ct.particle},
+					minOccurs: 1,
+					maxOccurs: 1,
+				}
+			}
+		}
+		if ct.simpleContent != nil && baseE.simpleContent != nil {
+			sc := *ct.simpleContent
+			sc.base = xsdQName{}
+			sc.baseSimple = baseE.simpleContent
+			e.simpleContent = &sc
+		}
+	}
+	s.effectiveCT[ct] = &e
+	return &e
+}
+
+func (s *xsdSchema) attributesOf(ct *xsdComplexType) map[xsdQName]*xsdAttribute {
+	attrs := map[xsdQName]*xsdAttribute{}
+	var add func(ct *xsdComplexType, seen map[xsdQName]struct{})
+	add = func(ct *xsdComplexType, seen map[xsdQName]struct{}) {
+		for _, a := range ct.attributes {
+			if a.ref.local != "" {
+				if global, exists := s.attributes[a.ref]; exists {
+					ref := *global
+					ref.required = a.required
+					if a.fixed != nil {
+						ref.fixed = a.fixed
+					}
+					attrs[a.ref] = &ref
+					continue
+				}
+			}
+			attrs[a.name] = a
+		}
+		for _, g := range ct.attrGroups {
+			if _, done := seen[g]; done {
+				continue
+			}
+			seen[g] = struct{}{}
+			if group, exists := s.attrGroups[g]; exists {
+				add(group, seen)
+			}
+		}
+	}
+	add(ct, map[xsdQName]struct{}{})
+	return attrs
+}
+
+func (s *xsdSchema) attributeType(a *xsdAttribute) *xsdSimpleType {
+	switch {
+	case a.simple != nil:
+		return a.simple
+	case a.typeName.local == "":
+		return nil
+	case a.typeName.isBuiltin():
+		return &xsdSimpleType{base: a.typeName}
+	}
+	return s.simples[a.typeName]
+}
+
+//------------------------------------------------------------------------------
+
+// checkSimple validates a value against a simple type, returning the value
+// converted into a structured form according to its built-in type.
+func (v *xsdValidator) checkSimple(st *xsdSimpleType, value string) (any, error) {
+	return v.checkSimpleDepth(st, value, 0)
+}
+
+func (v *xsdValidator) checkSimpleDepth(st *xsdSimpleType, value string, depth int) (any, error) {
+	if depth > 32 {
+		return nil, errors.New("simple type derivation is too deep")
+	}
+
+	switch {
+	case st.listItem != nil || st.listSimple != nil:
+		item := st.listSimple
+		if item == nil {
+			item = v.simpleByName(*st.listItem)
+		}
+		var items []any
+		for _, field := range strings.Fields(value) {
+			res, err := v.checkSimpleDepth(item, field, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, res)
+		}
+		if err := checkXSDLengthFacets(&st.facets, len(items)); err != nil {
+			return nil, err
+		}
+		return items, nil
+	case len(st.union) > 0 || len(st.unionTypes) > 0:
+		members := append([]*xsdSimpleType{}, st.unionTypes...)
+		for _, m := range st.union {
+			members = append(members, v.simpleByName(m))
+		}
+		for _, m := range members {
+			if res, err := v.checkSimpleDepth(m, value, depth+1); err == nil {
+				return res, v.checkFacets(&st.facets, value, res)
+			}
+		}
+		return nil, fmt.Errorf("value %q does not match any member of the union", value)
+	}
+
+	var res any
+	var err error
+	switch {
+	case st.baseSimple != nil:
+		res, err = v.checkSimpleDepth(st.baseSimple, value, depth+1)
+	case st.base.isBuiltin():
+		res, err = checkXSDBuiltin(st.base.local, value)
+	case st.base.local != "":
+		res, err = v.checkSimpleDepth(v.simpleByName(st.base), value, depth+1)
+	default:
+		res = value
+	}
+	if err != nil {
+		return nil, err
+	}
+	if s, isStr := res.(string); isStr {
+		// Facets apply to the normalised value for types that aren't
+		// strings, such as dates.
+		value = s
+	}
+	return res, v.checkFacets(&st.facets, value, res)
+}
+
+func (v *xsdValidator) simpleByName(q xsdQName) *xsdSimpleType {
+	if q.isBuiltin() {
+		return &xsdSimpleType{base: q}
+	}
+	if st, exists := v.schema.simples[q]; exists {
+		return st
+	}
+	return &xsdSimpleType{}
+}
+
+func checkXSDLengthFacets(f *xsdFacets, l int) error {
+	if f.length != nil && l != *f.length {
+		return fmt.Errorf("length %v does not equal %v", l, *f.length)
+	}
+	if f.minLength != nil && l < *f.minLength {
+		return fmt.Errorf("length %v is less than the minimum of %v", l, *f.minLength)
+	}
+	if f.maxLength != nil && l > *f.maxLength {
+		return fmt.Errorf("length %v is greater than the maximum of %v", l, *f.maxLength)
+	}
+	return nil
+}
+
+func compareXSDValues(a, b string) int {
+	ra, okA := new(big.Rat).SetString(strings.TrimSpace(a))
+	rb, okB := new(big.Rat).SetString(strings.TrimSpace(b))
+	if okA && okB {
+		return ra.Cmp(rb)
+	}
+	return strings.Compare(a, b)
+}
+
+func (v *xsdValidator) checkFacets(f *xsdFacets, value string, res any) error {
+	if len(f.enumeration) > 0 {
+		found := false
+		for _, e := range f.enumeration {
+			if e == value || (res != nil && compareXSDValues(e, value) == 0 && !isXSDString(res)) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("value %q is not one of the allowed values %v", value, strings.Join(f.enumeration, ", "))
+		}
+	}
+	for _, re := range f.patterns {
+		if !re.MatchString(value) {
+			return fmt.Errorf("value %q does not match the pattern %v", value, strings.TrimSuffix(strings.TrimPrefix(re.String(), "^(?:"), ")$"))
+		}
+	}
+	if _, isList := res.([]any); !isList {
+		if err := checkXSDLengthFacets(f, len([]rune(value))); err != nil {
+			return fmt.Errorf("value %q: %w", value, err)
+		}
+	}
+	if f.minInclusive != nil && compareXSDValues(value, *f.minInclusive) < 0 {
+		return fmt.Errorf("value %v is less than the minimum of %v", value, *f.minInclusive)
+	}
+	if f.maxInclusive != nil && compareXSDValues(value, *f.maxInclusive) > 0 {
+		return fmt.Errorf("value %v is greater than the maximum of %v", value, *f.maxInclusive)
+	}
+	if f.minExclusive != nil && compareXSDValues(value, *f.minExclusive) <= 0 {
+		return fmt.Errorf("value %v must be greater than %v", value, *f.minExclusive)
+	}
+	if f.maxExclusive != nil && compareXSDValues(value, *f.maxExclusive) >= 0 {
+		return fmt.Errorf("value %v must be less than %v", value, *f.maxExclusive)
+	}
+	if f.totalDigits != nil || f.fractionDigits != nil {
+		digits := strings.TrimLeft(strings.TrimSpace(value), "+-")
+		intPart, fracPart, _ := strings.Cut(digits, ".")
+		fracPart = strings.TrimRight(fracPart, "0")
+		intPart = strings.TrimLeft(intPart, "0")
+		if f.totalDigits != nil && len(intPart)+len(fracPart) > *f.totalDigits {
+			return fmt.Errorf("value %v has more than %v digits", value, *f.totalDigits)
+		}
+		if f.fractionDigits != nil && len(fracPart) > *f.fractionDigits {
+			return fmt.Errorf("value %v has more than %v fraction digits", value, *f.fractionDigits)
+		}
+	}
+	return nil
+}
+
+func isXSDString(v any) bool {
+	_, isStr := v.(string)
+	return isStr
+}
+
+var (
+	xsdDecimalRegexp  = regexp.MustCompile(`^[+-]?(\d+(\.\d*)?|\.\d+)$`)
+	xsdIntegerRegexp  = regexp.MustCompile(`^[+-]?\d+$`)
+	xsdTimezoneSuffix = `(Z|[+-]\d{2}:\d{2})?`
+	xsdDateRegexp     = regexp.MustCompile(`^-?\d{4,}-\d{2}-\d{2}` + xsdTimezoneSuffix + `$`)
+	xsdDateTimeRegexp = regexp.MustCompile(`^-?\d{4,}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d+)?` + xsdTimezoneSuffix + `$`)
+	xsdTimeRegexp     = regexp.MustCompile(`^\d{2}:\d{2}:\d{2}(\.\d+)?` + xsdTimezoneSuffix + `$`)
+)
+
+var xsdIntegerRanges = map[string][2]*big.Int{}
+
+func init() {
+	bound := func(s string) *big.Int {
+		i, _ := new(big.Int).SetString(s, 10)
+		return i
+	}
+	for name, r := range map[string][2]string{
+		"long":               {"-9223372036854775808", "9223372036854775807"},
+		"int":                {"-2147483648", "2147483647"},
+		"short":              {"-32768", "32767"},
+		"byte":               {"-128", "127"},
+		"unsignedLong":       {"0", "18446744073709551615"},
+		"unsignedInt":        {"0", "4294967295"},
+		"unsignedShort":      {"0", "65535"},
+		"unsignedByte":       {"0", "255"},
+		"nonNegativeInteger": {"0", ""},
+		"positiveInteger":    {"1", ""},
+		"nonPositiveInteger": {"", "0"},
+		"negativeInteger":    {"", "-1"},
+	} {
+		var b [2]*big.Int
+		if r[0] != "" {
+			b[0] = bound(r[0])
+		}
+		if r[1] != "" {
+			b[1] = bound(r[1])
+		}
+		xsdIntegerRanges[name] = b
+	}
+}
+
+// checkXSDBuiltin validates a value against a built-in type, returning it in
+// a structured form, where numbers and booleans are converted.
+func checkXSDBuiltin(name, value string) (any, error) {
+	collapsed := strings.Join(strings.Fields(value), " ")
+
+	switch name {
+	case "boolean":
+		switch collapsed {
+		case "true", "1":
+			return true, nil
+		case "false", "0":
+			return false, nil
+		}
+		return nil, fmt.Errorf("value %q is not a valid boolean", value)
+	case "decimal":
+		if !xsdDecimalRegexp.MatchString(collapsed) {
+			return nil, fmt.Errorf("value %q is not a valid decimal", value)
+		}
+		return strconv.ParseFloat(collapsed, 64)
+	case "float", "double":
+		switch collapsed {
+		case "INF", "-INF", "NaN":
+			return collapsed, nil
+		}
+		f, err := strconv.ParseFloat(collapsed, 64)
+		if err != nil {
+			return nil, fmt.Errorf("value %q is not a valid %v", value, name)
+		}
+		return f, nil
+	case "integer", "long", "int", "short", "byte",
+		"unsignedLong", "unsignedInt", "unsignedShort", "unsignedByte",
+		"nonNegativeInteger", "positiveInteger", "nonPositiveInteger", "negativeInteger":
+		if !xsdIntegerRegexp.MatchString(collapsed) {
+			return nil, fmt.Errorf("value %q is not a valid %v", value, name)
+		}
+		i, _ := new(big.Int).SetString(strings.TrimPrefix(collapsed, "+"), 10)
+		if r, exists := xsdIntegerRanges[name]; exists {
+			if (r[0] != nil && i.Cmp(r[0]) < 0) || (r[1] != nil && i.Cmp(r[1]) > 0) {
+				return nil, fmt.Errorf("value %q is out of range for %v", value, name)
+			}
+		}
+		if i.IsInt64() {
+			return i.Int64(), nil
+		}
+		f, _ := new(big.Float).SetInt(i).Float64()
+		return f, nil
+	case "date":
+		if !xsdDateRegexp.MatchString(collapsed) || !validXSDDate(collapsed[:10]) {
+			return nil, fmt.Errorf("value %q is not a valid date", value)
+		}
+		return collapsed, nil
+	case "dateTime":
+		if !xsdDateTimeRegexp.MatchString(collapsed) || !validXSDDate(collapsed[:10]) {
+			return nil, fmt.Errorf("value %q is not a valid dateTime", value)
+		}
+		return collapsed, nil
+	case "time":
+		if !xsdTimeRegexp.MatchString(collapsed) {
+			return nil, fmt.Errorf("value %q is not a valid time", value)
+		}
+		return collapsed, nil
+	case "base64Binary":
+		if _, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(value), "")); err != nil {
+			return nil, fmt.Errorf("value %q is not valid base64Binary", value)
+		}
+		return collapsed, nil
+	case "hexBinary":
+		if _, err := hex.DecodeString(collapsed); err != nil {
+			return nil, fmt.Errorf("value %q is not valid hexBinary", value)
+		}
+		return collapsed, nil
+	case "string", "anySimpleType", xsdAnyTypeTag:
+		return value, nil
+	}
+	// Remaining string derived types, such as token and anyURI, are only
+	// whitespace normalised.
+	return collapsed, nil
+}
+
+func validXSDDate(s string) bool {
+	if strings.HasPrefix(s, "-") {
+		return true
+	}
+	_, err := time.Parse("2006-01-02", s)
+	return err == nil
+}
+
+//------------------------------------------------------------------------------
+
+// toJSON converts a validated document into a structure where values are
+// typed, and elements that may occur multiple times are always arrays,
+// according to the schema.
+func (v *xsdValidator) toJSON(root *xmlNode) map[string]any {
+	return map[string]any{root.name.Local: v.nodeJSON(root)}
+}
+
+func (v *xsdValidator) nodeJSON(n *xmlNode) any {
+	decl, declared := v.decls[n]
+
+	if declared && decl.simple != nil {
+		res, err := v.checkSimple(decl.simple, n.text)
+		if err != nil {
+			return n.text
+		}
+		return res
+	}
+
+	var ct *xsdComplexType
+	var attrs map[xsdQName]*xsdAttribute
+	if declared && decl.complex != nil {
+		ct = v.schema.effective(decl.complex)
+		attrs = v.schema.attributesOf(ct)
+	}
+
+	obj := map[string]any{}
+	for _, a := range n.attrs {
+		if isXSDNamespaceAttr(a) {
+			continue
+		}
+		var value any = a.Value
+		if decl, exists := attrs[xsdQName{space: a.Name.Space, local: a.Name.Local}]; exists {
+			if st := v.schema.attributeType(decl); st != nil {
+				if res, err := v.checkSimple(st, a.Value); err == nil {
+					value = res
+				}
+			}
+		}
+		obj["-"+a.Name.Local] = value
+	}
+
+	if ct != nil && ct.simpleContent != nil {
+		var text any = n.text
+		if res, err := v.checkSimple(ct.simpleContent, n.text); err == nil {
+			text = res
+		}
+		if len(attrs) == 0 {
+			return text
+		}
+		obj["#text"] = text
+		return obj
+	}
+
+	if len(n.children) == 0 && len(obj) == 0 && ct == nil {
+		return n.text
+	}
+
+	if text := strings.TrimSpace(n.text); text != "" {
+		obj["#text"] = text
+	}
+
+	counts := map[string]int{}
+	for _, c := range n.children {
+		counts[c.name.Local]++
+	}
+	for _, c := range n.children {
+		name := c.name.Local
+		value := v.nodeJSON(c)
+
+		cDecl, cDeclared := v.decls[c]
+		if (cDeclared && cDecl.repeated) || (!cDeclared && counts[name] > 1) {
+			arr, _ := obj[name].([]any)
+			obj[name] = append(arr, value)
+			continue
+		}
+		obj[name] = value
+	}
+	return obj
+}
@@ -0,0 +1,353 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package loglevels provides log levels for individual component paths, which
+// are applied on top of the level of the main logger.
+package loglevels
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+// FieldName is the name of the top level config field that sets the log levels
+// of component paths.
+const FieldName = "log_levels"
+
+// levelOff is above every level emitted by components.
+const levelOff = slog.Level(100)
+
+// ConfigField returns the top level config field that sets the log levels of
+// component paths.
+func ConfigField() *service.ConfigField {
+	return service.NewStringMapField(FieldName).
+		Description("A map of component paths to log levels, which allows specific components to emit logs more verbosely than the level of the `logger`. A path matches the component at that path along with all of its children, where the longest matching path takes precedence. Paths are dot separated and the leading `root.` is optional, e.g. `output.broker.outputs.0` refers to the first output of a broker output. Levels can also be changed at runtime with the admin endpoint `/admin/log_levels`.").
+		Example(map[string]any{
+			"output.broker.outputs.0": "debug",
+			"input":                   "trace",
+		}).
+		Version("4.45.0").
+		Default(map[string]any{}).
+		Advanced()
+}
+
+// ParseLevel parses a log level name as accepted by the field `logger.level`.
+// Trace logs are emitted by components at debug level, and therefore `trace`
+// and `all` are equivalent to `debug`.
+func ParseLevel(name string) (slog.Level, error) {
+	switch strings.ToLower(name) {
+	case "off", "none":
+		return levelOff, nil
+	case "fatal", "error":
+		return slog.LevelError, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "info":
+		return slog.LevelInfo, nil
+	case "debug", "trace", "all":
+		return slog.LevelDebug, nil
+	}
+	return 0, fmt.Errorf("log level '%v' not recognized", name)
+}
+
+func normalisePath(p string) string {
+	p = strings.Trim(p, ".")
+	if p == "root" {
+		return ""
+	}
+	return strings.TrimPrefix(p, "root.")
+}
+
+type override struct {
+	path  string
+	name  string
+	level slog.Level
+}
+
+// Overrides holds the log levels of component paths. Logs of a component are
+// emitted by the main logger according to its own level, and those that fall
+// below that level but are enabled by the override of the component are
+// written to a separate handler in the same format. As such overrides are only
+// able to make components more verbose.
+type Overrides struct {
+	mut       sync.RWMutex
+	root      slog.Level
+	overrides []override // Sorted by path length, longest first
+	out       slog.Handler
+}
+
+// New creates an empty set of overrides, where the main logger is assumed to
+// be at info level until SetRootLevel is called.
+func New() *Overrides {
+	return &Overrides{
+		root: slog.LevelInfo,
+		out:  slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+	}
+}
+
+// SetRootLevel sets the level of the main logger.
+func (o *Overrides) SetRootLevel(name string) error {
+	level, err := ParseLevel(name)
+	if err != nil {
+		return err
+	}
+	o.mut.Lock()
+	o.root = level
+	o.mut.Unlock()
+	return nil
+}
+
+// SetOutput sets the handler that logs enabled by overrides are written to.
+func (o *Overrides) SetOutput(h slog.Handler) {
+	o.mut.Lock()
+	o.out = h
+	o.mut.Unlock()
+}
+
+// Set replaces all overrides with a map of component paths to level names.
+func (o *Overrides) Set(levels map[string]string) error {
+	overrides := make([]override, 0, len(levels))
+	for p, name := range levels {
+		level, err := ParseLevel(name)
+		if err != nil {
+			return fmt.Errorf("path %v: %w", p, err)
+		}
+		overrides = append(overrides, override{
+			path:  normalisePath(p),
+			name:  strings.ToLower(name),
+			level: level,
+		})
+	}
+	sort.Slice(overrides, func(i, j int) bool {
+		if len(overrides[i].path) == len(overrides[j].path) {
+			return overrides[i].path < overrides[j].path
+		}
+		return len(overrides[i].path) > len(overrides[j].path)
+	})
+
+	o.mut.Lock()
+	o.overrides = overrides
+	o.mut.Unlock()
+	return nil
+}
+
+// Levels returns the current overrides as a map of component paths to level
+// names.
+func (o *Overrides) Levels() map[string]string {
+	o.mut.RLock()
+	defer o.mut.RUnlock()
+
+	levels := make(map[string]string, len(o.overrides))
+	for _, ov := range o.overrides {
+		if ov.path == "" {
+			levels["root"] = ov.name
+		} else {
+			levels[ov.path] = ov.name
+		}
+	}
+	return levels
+}
+
+// InitFromParsed sets the overrides, the level of the main logger and the
+// format of the output from the root of a parsed config.
+func (o *Overrides) InitFromParsed(pConf *service.ParsedConfig) error {
+	if pConf.Contains(FieldName) {
+		levels, err := pConf.FieldStringMap(FieldName)
+		if err != nil {
+			return err
+		}
+		if err := o.Set(levels); err != nil {
+			return fmt.Errorf("%v: %w", FieldName, err)
+		}
+	}
+
+	lConf := pConf.Namespace("logger")
+	if name, err := lConf.FieldString("level"); err == nil {
+		if err := o.SetRootLevel(name); err != nil {
+			return err
+		}
+	}
+
+	// Logs are written to stderr when messages are written to stdout, which
+	// matches the behaviour of the main logger.
+	var w io.Writer = os.Stdout
+	if pConf.Contains("output", "stdout") {
+		w = os.Stderr
+	}
+	if path, _ := lConf.FieldString("file", "path"); path != "" {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o666)
+		if err != nil {
+			return fmt.Errorf("failed to open log file: %w", err)
+		}
+		w = f
+	}
+
+	opts := &slog.HandlerOptions{Level: slog.LevelDebug}
+	if addTS, err := lConf.FieldBool("add_timestamp"); err == nil && !addTS {
+		opts.ReplaceAttr = func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		}
+	}
+
+	var h slog.Handler
+	if format, _ := lConf.FieldString("format"); format == "json" {
+		h = slog.NewJSONHandler(w, opts)
+	} else {
+		h = slog.NewTextHandler(w, opts)
+	}
+	if static, err := lConf.FieldStringMap("static_fields"); err == nil && len(static) > 0 {
+		attrs := make([]slog.Attr, 0, len(static))
+		for k, v := range static {
+			attrs = append(attrs, slog.String(k, v))
+		}
+		h = h.WithAttrs(attrs)
+	}
+	o.SetOutput(h)
+	return nil
+}
+
+// level returns the level of a component path when an override applies to it.
+func (o *Overrides) level(path string) (level slog.Level, root slog.Level, out slog.Handler, ok bool) {
+	path = normalisePath(path)
+
+	o.mut.RLock()
+	defer o.mut.RUnlock()
+
+	for _, ov := range o.overrides {
+		if ov.path == "" || path == ov.path || strings.HasPrefix(path, ov.path+".") {
+			return ov.level, o.root, o.out, true
+		}
+	}
+	return 0, o.root, nil, false
+}
+
+// Handler wraps a handler such that every record is passed to it, and records
+// of components with an override that are below the level of the main logger
+// are also written to the output of the overrides.
+func (o *Overrides) Handler(next slog.Handler) slog.Handler {
+	return &handler{o: o, next: next}
+}
+
+type handler struct {
+	o    *Overrides
+	next slog.Handler
+	path string
+
+	// Attributes within groups do not identify the component path.
+	grouped bool
+
+	// Applied to the output handler when a record is written, since the
+	// output may be changed after loggers are created.
+	wrap []func(slog.Handler) slog.Handler
+}
+
+func (h *handler) overridden(l slog.Level) (slog.Handler, bool) {
+	level, root, out, ok := h.o.level(h.path)
+	if !ok || l < level || l >= root {
+		return nil, false
+	}
+	return out, true
+}
+
+func (h *handler) Enabled(ctx context.Context, l slog.Level) bool {
+	if h.next.Enabled(ctx, l) {
+		return true
+	}
+	_, ok := h.overridden(l)
+	return ok
+}
+
+func (h *handler) Handle(ctx context.Context, r slog.Record) error {
+	var err error
+	if h.next.Enabled(ctx, r.Level) {
+		err = h.next.Handle(ctx, r.Clone())
+	}
+	if out, ok := h.overridden(r.Level); ok {
+		for _, fn := range h.wrap {
+			out = fn(out)
+		}
+		// Messages formatted by components often end with a newline, which the
+		// main logger omits.
+		r.Message = strings.TrimSuffix(r.Message, "\n")
+		if oErr := out.Handle(ctx, r); oErr != nil && err == nil {
+			err = oErr
+		}
+	}
+	return err
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := *h
+	c.next = h.next.WithAttrs(attrs)
+	if !h.grouped {
+		for _, a := range attrs {
+			if a.Key == "path" {
+				c.path = a.Value.String()
+			}
+		}
+	}
+	c.wrap = append(h.wrap[:len(h.wrap):len(h.wrap)], func(out slog.Handler) slog.Handler {
+		return out.WithAttrs(attrs)
+	})
+	return &c
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	c := *h
+	c.next = h.next.WithGroup(name)
+	c.grouped = true
+	c.wrap = append(h.wrap[:len(h.wrap):len(h.wrap)], func(out slog.Handler) slog.Handler {
+		return out.WithGroup(name)
+	})
+	return &c
+}
+
+// ServeHTTP implements http.Handler, where a GET request returns the current
+// overrides as a JSON object of component paths to level names, and a PUT
+// request replaces them with the JSON object of the request body.
+func (o *Overrides) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var levels map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&levels); err != nil {
+			http.Error(w, fmt.Sprintf("failed to parse log levels: %v", err), http.StatusBadRequest)
+			return
+		}
+		if err := o.Set(levels); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(o.Levels())
+}
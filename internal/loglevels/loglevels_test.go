@@ -0,0 +1,83 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loglevels
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOverridesHandler(t *testing.T) {
+	var mainBuf, outBuf bytes.Buffer
+
+	o := New()
+	o.SetOutput(slog.NewTextHandler(&outBuf, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+	require.NoError(t, o.Set(map[string]string{
+		"output.broker.outputs.0": "debug",
+		"root.output":             "warn",
+	}))
+
+	logger := slog.New(o.Handler(slog.NewTextHandler(&mainBuf, &slog.HandlerOptions{Level: slog.LevelInfo})))
+
+	child := logger.With("path", "root.output.broker.outputs.0.http_client")
+	child.Debug("child debug")
+	child.Info("child info")
+
+	sibling := logger.With("path", "root.output.broker.outputs.1")
+	sibling.Debug("sibling debug")
+
+	logger.With("path", "root.output.broker.outputs.01").Debug("similar debug")
+	logger.With("path", "root.input").Debug("input debug")
+
+	assert.Equal(t, "level=DEBUG msg=\"child debug\" path=root.output.broker.outputs.0.http_client\n", outBuf.String())
+	assert.Contains(t, mainBuf.String(), "child info")
+	assert.NotContains(t, mainBuf.String(), "debug")
+}
+
+func TestOverridesHTTP(t *testing.T) {
+	o := New()
+
+	req := httptest.NewRequest(http.MethodPut, "/admin/log_levels", strings.NewReader(`{"root.input":"debug","root":"trace"}`))
+	rec := httptest.NewRecorder()
+	o.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.JSONEq(t, `{"input":"debug","root":"trace"}`, rec.Body.String())
+
+	req = httptest.NewRequest(http.MethodPut, "/admin/log_levels", strings.NewReader(`{"input":"loud"}`))
+	rec = httptest.NewRecorder()
+	o.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/admin/log_levels", http.NoBody)
+	rec = httptest.NewRecorder()
+	o.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"input":"debug","root":"trace"}`, rec.Body.String())
+}
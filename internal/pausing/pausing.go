@@ -0,0 +1,304 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package pausing provides named gates that components use in order to be
+// paused and resumed at runtime.
+package pausing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+// Gate controls whether the operations of a component, such as reads of an
+// input or writes of an output, are allowed to proceed, and tracks those that
+// are in flight so that a pause can wait for them to drain.
+type Gate struct {
+	mut      sync.Mutex
+	paused   bool
+	inFlight int
+	changed  chan struct{}
+
+	// Cancelled when the gate is paused.
+	runCtx  context.Context
+	runDone func()
+}
+
+func newGate() *Gate {
+	g := &Gate{changed: make(chan struct{})}
+	g.runCtx, g.runDone = context.WithCancel(context.Background())
+	return g
+}
+
+// notify wakes all waiters, the mutex must be held.
+func (g *Gate) notify() {
+	close(g.changed)
+	g.changed = make(chan struct{})
+}
+
+// Enter blocks until the gate is open and then marks an operation as in
+// flight, which must be followed by a call to Done.
+func (g *Gate) Enter(ctx context.Context) error {
+	for {
+		g.mut.Lock()
+		if !g.paused {
+			g.inFlight++
+			g.mut.Unlock()
+			return nil
+		}
+		changed := g.changed
+		g.mut.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Done marks an operation that entered the gate as no longer in flight.
+func (g *Gate) Done() {
+	g.mut.Lock()
+	g.inFlight--
+	if g.inFlight == 0 {
+		g.notify()
+	}
+	g.mut.Unlock()
+}
+
+// WithPause returns a derived context that is also cancelled when the gate is
+// paused, which allows operations that block indefinitely, such as reads, to
+// be abandoned.
+func (g *Gate) WithPause(ctx context.Context) (context.Context, context.CancelFunc) {
+	pCtx, cancel := context.WithCancel(ctx)
+
+	g.mut.Lock()
+	defer g.mut.Unlock()
+	if g.paused {
+		cancel()
+		return pCtx, cancel
+	}
+	stop := context.AfterFunc(g.runCtx, cancel)
+	return pCtx, func() {
+		stop()
+		cancel()
+	}
+}
+
+// Pause closes the gate, operations that are already in flight are allowed to
+// complete.
+func (g *Gate) Pause() {
+	g.mut.Lock()
+	defer g.mut.Unlock()
+	if g.paused {
+		return
+	}
+	g.paused = true
+	g.runDone()
+	g.notify()
+}
+
+// Resume opens the gate.
+func (g *Gate) Resume() {
+	g.mut.Lock()
+	defer g.mut.Unlock()
+	if !g.paused {
+		return
+	}
+	g.paused = false
+	g.runCtx, g.runDone = context.WithCancel(context.Background())
+	g.notify()
+}
+
+// WaitDrained blocks until no operations are in flight.
+func (g *Gate) WaitDrained(ctx context.Context) error {
+	for {
+		g.mut.Lock()
+		if g.inFlight == 0 {
+			g.mut.Unlock()
+			return nil
+		}
+		changed := g.changed
+		g.mut.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// State is the reported state of a gate.
+type State struct {
+	Name     string `json:"name"`
+	Paused   bool   `json:"paused"`
+	InFlight int    `json:"in_flight"`
+}
+
+func (g *Gate) state(name string) State {
+	g.mut.Lock()
+	defer g.mut.Unlock()
+	return State{Name: name, Paused: g.paused, InFlight: g.inFlight}
+}
+
+//------------------------------------------------------------------------------
+
+// DefaultDrainTimeout is the maximum period that a pause request waits for
+// in flight operations to drain when a timeout is not specified.
+const DefaultDrainTimeout = 30 * time.Second
+
+// Registry holds the gates of components by name, where components that share
+// a name also share a gate.
+type Registry struct {
+	mut   sync.Mutex
+	gates map[string]*Gate
+	mux   *http.ServeMux
+}
+
+// NewRegistry creates an empty registry of gates.
+func NewRegistry() *Registry {
+	r := &Registry{
+		gates: map[string]*Gate{},
+		mux:   http.NewServeMux(),
+	}
+	r.mux.HandleFunc("GET /admin/pause", r.handleList)
+	r.mux.HandleFunc("POST /admin/pause/{name}", r.handlePause)
+	r.mux.HandleFunc("POST /admin/resume/{name}", r.handleResume)
+	return r
+}
+
+type registryKeyType int
+
+var registryKey registryKeyType
+
+// SetRegistry sets the registry that components created from a resources
+// handle obtain their gates from.
+func SetRegistry(res *service.Resources, r *Registry) {
+	res.SetGeneric(registryKey, r)
+}
+
+// FromResources returns the registry of a resources handle, creating one when
+// it has not been set.
+func FromResources(res *service.Resources) *Registry {
+	r, _ := res.GetOrSetGeneric(registryKey, NewRegistry())
+	return r.(*Registry)
+}
+
+// Gate returns the gate of a name, creating it if it does not yet exist.
+func (r *Registry) Gate(name string) *Gate {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	g, exists := r.gates[name]
+	if !exists {
+		g = newGate()
+		r.gates[name] = g
+	}
+	return g
+}
+
+func (r *Registry) lookup(name string) (*Gate, bool) {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	g, exists := r.gates[name]
+	return g, exists
+}
+
+// States returns the state of each gate sorted by name.
+func (r *Registry) States() []State {
+	r.mut.Lock()
+	names := make([]string, 0, len(r.gates))
+	gates := make(map[string]*Gate, len(r.gates))
+	for name, g := range r.gates {
+		names = append(names, name)
+		gates[name] = g
+	}
+	r.mut.Unlock()
+
+	sort.Strings(names)
+	states := make([]State, 0, len(names))
+	for _, name := range names {
+		states = append(states, gates[name].state(name))
+	}
+	return states
+}
+
+// ServeHTTP implements http.Handler, serving the state of all gates at
+// `GET /admin/pause`, pausing a gate at `POST /admin/pause/{name}` and
+// resuming it at `POST /admin/resume/{name}`. A pause request responds once
+// in flight operations have drained, or fails with a 504 status once the
+// duration of the query parameter `timeout` has elapsed, in which case the
+// gate remains paused.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mux.ServeHTTP(w, req)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func (r *Registry) handleList(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, r.States())
+}
+
+func (r *Registry) handlePause(w http.ResponseWriter, req *http.Request) {
+	name := req.PathValue("name")
+	g, exists := r.lookup(name)
+	if !exists {
+		http.Error(w, fmt.Sprintf("component %v does not exist", name), http.StatusNotFound)
+		return
+	}
+
+	timeout := DefaultDrainTimeout
+	if tStr := req.URL.Query().Get("timeout"); tStr != "" {
+		var err error
+		if timeout, err = time.ParseDuration(tStr); err != nil {
+			http.Error(w, fmt.Sprintf("failed to parse timeout: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	g.Pause()
+
+	ctx, done := context.WithTimeout(req.Context(), timeout)
+	defer done()
+	status := http.StatusOK
+	if err := g.WaitDrained(ctx); err != nil {
+		status = http.StatusGatewayTimeout
+	}
+	writeJSON(w, status, g.state(name))
+}
+
+func (r *Registry) handleResume(w http.ResponseWriter, req *http.Request) {
+	name := req.PathValue("name")
+	g, exists := r.lookup(name)
+	if !exists {
+		http.Error(w, fmt.Sprintf("component %v does not exist", name), http.StatusNotFound)
+		return
+	}
+	g.Resume()
+	writeJSON(w, http.StatusOK, g.state(name))
+}
@@ -0,0 +1,101 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pausing
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGatePauseResume(t *testing.T) {
+	g := newGate()
+
+	require.NoError(t, g.Enter(context.Background()))
+	readCtx, cancel := g.WithPause(context.Background())
+	defer cancel()
+
+	// Pending operations are cancelled by a pause.
+	g.Pause()
+	assert.Eventually(t, func() bool {
+		return readCtx.Err() != nil
+	}, time.Second, time.Millisecond)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer done()
+	assert.ErrorIs(t, g.Enter(ctx), context.DeadlineExceeded)
+	assert.ErrorIs(t, g.WaitDrained(ctx), context.DeadlineExceeded)
+
+	g.Done()
+	require.NoError(t, g.WaitDrained(context.Background()))
+
+	entered := make(chan error)
+	go func() {
+		entered <- g.Enter(context.Background())
+	}()
+	select {
+	case <-entered:
+		t.Fatal("entered a paused gate")
+	case <-time.After(time.Millisecond * 10):
+	}
+
+	g.Resume()
+	require.NoError(t, <-entered)
+	readCtx, cancel = g.WithPause(context.Background())
+	defer cancel()
+	assert.NoError(t, readCtx.Err())
+}
+
+func TestRegistryHTTP(t *testing.T) {
+	r := NewRegistry()
+	g := r.Gate("foo")
+	require.NoError(t, g.Enter(context.Background()))
+
+	do := func(method, path string) (int, string) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(method, path, http.NoBody))
+		return rec.Code, rec.Body.String()
+	}
+
+	code, _ := do(http.MethodPost, "/admin/pause/bar")
+	assert.Equal(t, http.StatusNotFound, code)
+
+	// The pause times out whilst an operation is in flight.
+	code, body := do(http.MethodPost, "/admin/pause/foo?timeout=10ms")
+	assert.Equal(t, http.StatusGatewayTimeout, code)
+	assert.JSONEq(t, `{"name":"foo","paused":true,"in_flight":1}`, body)
+
+	g.Done()
+	code, body = do(http.MethodPost, "/admin/pause/foo")
+	assert.Equal(t, http.StatusOK, code)
+	assert.JSONEq(t, `{"name":"foo","paused":true,"in_flight":0}`, body)
+
+	code, body = do(http.MethodPost, "/admin/resume/foo")
+	assert.Equal(t, http.StatusOK, code)
+	assert.JSONEq(t, `{"name":"foo","paused":false,"in_flight":0}`, body)
+
+	code, body = do(http.MethodGet, "/admin/pause")
+	assert.Equal(t, http.StatusOK, code)
+	var states []State
+	require.NoError(t, json.Unmarshal([]byte(body), &states))
+	assert.Equal(t, []State{{Name: "foo"}}, states)
+}
@@ -0,0 +1,194 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protohealth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+// Probe is an active check of a dependency of a stream, such as a broker or
+// a database.
+type Probe struct {
+	Name     string
+	Interval time.Duration
+	Timeout  time.Duration
+	Check    func(ctx context.Context) error
+	Close    func(ctx context.Context) error
+}
+
+const (
+	// ProbesFieldName is the name of the top level config field that sets the
+	// dependency probes.
+	ProbesFieldName = "health_probes"
+
+	hpFieldName       = "name"
+	hpFieldTCPAddress = "tcp_address"
+	hpFieldProcessors = "processors"
+	hpFieldInterval   = "interval"
+	hpFieldTimeout    = "timeout"
+)
+
+// ProbesField returns the top level config field that sets the dependency
+// probes.
+func ProbesField() *service.ConfigField {
+	return service.NewObjectListField(ProbesFieldName,
+		service.NewStringField(hpFieldName).
+			Description("A unique name of the probe, which it is reported under."),
+		service.NewStringField(hpFieldTCPAddress).
+			Description("An address to open a TCP connection to, where the probe passes when the connection is established.").
+			Example("localhost:9092").
+			Optional(),
+		service.NewProcessorListField(hpFieldProcessors).
+			Description("A list of processors to execute on an empty message, where the probe passes when the processors succeed without flagging the message with an error. This allows for probes such as a `SELECT 1` query with a `sql_raw` processor.").
+			Optional(),
+		service.NewDurationField(hpFieldInterval).
+			Description("The period between checks.").
+			Default("10s"),
+		service.NewDurationField(hpFieldTimeout).
+			Description("The maximum period to wait for a check before it fails.").
+			Default("5s"),
+	).
+		Description("A list of probes that actively check the dependencies of streams. Failing probes cause the instance to be reported as not ready by the gRPC health endpoint and the admin endpoint `/admin/ready`, where the last error of each probe is detailed. Each probe must specify either `" + hpFieldTCPAddress + "` or `" + hpFieldProcessors + "`.").
+		Example([]any{
+			map[string]any{
+				"name":        "kafka",
+				"tcp_address": "localhost:9092",
+			},
+			map[string]any{
+				"name": "postgres",
+				"processors": []any{
+					map[string]any{
+						"sql_raw": map[string]any{
+							"driver":    "postgres",
+							"dsn":       "postgres://localhost:5432/orders",
+							"query":     "SELECT 1",
+							"exec_only": true,
+						},
+					},
+				},
+			},
+		}).
+		Version("4.45.0").
+		Default([]any{}).
+		Advanced()
+}
+
+// ProbesFromParsed creates the dependency probes from the root of a parsed
+// config.
+func ProbesFromParsed(pConf *service.ParsedConfig) ([]Probe, error) {
+	if !pConf.Contains(ProbesFieldName) {
+		return nil, nil
+	}
+	pConfs, err := pConf.FieldObjectList(ProbesFieldName)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]struct{}{}
+	probes := make([]Probe, 0, len(pConfs))
+	for i, c := range pConfs {
+		p, err := probeFromParsed(c)
+		if err != nil {
+			return nil, fmt.Errorf("%v %v: %w", ProbesFieldName, i, err)
+		}
+		if _, exists := seen[p.Name]; exists {
+			return nil, fmt.Errorf("%v %v: duplicate name %v", ProbesFieldName, i, p.Name)
+		}
+		seen[p.Name] = struct{}{}
+		probes = append(probes, p)
+	}
+	return probes, nil
+}
+
+func probeFromParsed(pConf *service.ParsedConfig) (p Probe, err error) {
+	if p.Name, err = pConf.FieldString(hpFieldName); err != nil {
+		return
+	}
+	if p.Name == "" {
+		err = errors.New("a name must be specified")
+		return
+	}
+	if p.Interval, err = pConf.FieldDuration(hpFieldInterval); err != nil {
+		return
+	}
+	if p.Timeout, err = pConf.FieldDuration(hpFieldTimeout); err != nil {
+		return
+	}
+
+	var address string
+	if pConf.Contains(hpFieldTCPAddress) {
+		if address, err = pConf.FieldString(hpFieldTCPAddress); err != nil {
+			return
+		}
+	}
+	var procs []*service.OwnedProcessor
+	if pConf.Contains(hpFieldProcessors) {
+		if procs, err = pConf.FieldProcessorList(hpFieldProcessors); err != nil {
+			return
+		}
+	}
+	if (address == "") == (len(procs) == 0) {
+		err = fmt.Errorf("either %v or %v must be specified", hpFieldTCPAddress, hpFieldProcessors)
+		return
+	}
+
+	if address != "" {
+		p.Check = tcpCheck(address)
+		return
+	}
+	p.Check = processorsCheck(procs)
+	p.Close = func(ctx context.Context) error {
+		var errs []error
+		for _, proc := range procs {
+			errs = append(errs, proc.Close(ctx))
+		}
+		return errors.Join(errs...)
+	}
+	return
+}
+
+func tcpCheck(address string) func(context.Context) error {
+	return func(ctx context.Context) error {
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", address)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+}
+
+func processorsCheck(procs []*service.OwnedProcessor) func(context.Context) error {
+	return func(ctx context.Context) error {
+		batches, err := service.ExecuteProcessors(ctx, procs, service.MessageBatch{service.NewMessage(nil)})
+		if err != nil {
+			return err
+		}
+		for _, b := range batches {
+			for _, m := range b {
+				if err := m.GetError(); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+}
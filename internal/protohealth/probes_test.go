@@ -0,0 +1,87 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protohealth
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+
+	_ "github.com/redpanda-data/benthos/v4/public/components/pure"
+)
+
+func parseProbes(t *testing.T, yamlStr string) ([]Probe, error) {
+	t.Helper()
+
+	spec := service.NewConfigSpec().Field(ProbesField())
+	pConf, err := spec.ParseYAML(yamlStr, nil)
+	require.NoError(t, err)
+	return ProbesFromParsed(pConf)
+}
+
+func TestProbesFromParsed(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = lis.Close()
+	})
+
+	probes, err := parseProbes(t, `
+health_probes:
+  - name: listener
+    tcp_address: `+lis.Addr().String()+`
+  - name: passing
+    processors:
+      - mapping: 'root = "ok"'
+  - name: failing
+    processors:
+      - mapping: 'root = throw("nope")'
+`)
+	require.NoError(t, err)
+	require.Len(t, probes, 3)
+
+	ctx := context.Background()
+	assert.NoError(t, probes[0].Check(ctx))
+	assert.NoError(t, probes[1].Check(ctx))
+	assert.ErrorContains(t, probes[2].Check(ctx), "nope")
+
+	for _, p := range probes {
+		if p.Close != nil {
+			require.NoError(t, p.Close(ctx))
+		}
+	}
+}
+
+func TestProbesFromParsedErrors(t *testing.T) {
+	_, err := parseProbes(t, `
+health_probes:
+  - name: neither
+`)
+	require.ErrorContains(t, err, "either tcp_address or processors must be specified")
+
+	_, err = parseProbes(t, `
+health_probes:
+  - name: foo
+    tcp_address: localhost:9092
+  - name: foo
+    tcp_address: localhost:9093
+`)
+	require.ErrorContains(t, err, "duplicate name foo")
+}
@@ -0,0 +1,383 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protohealth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+// ComponentStatus is the connection state of a single component of a stream.
+type ComponentStatus struct {
+	Name  string
+	Label string
+	Path  string
+	Ready bool
+	Err   error
+}
+
+// StatusFunc returns the current connection state of each component of a
+// stream.
+type StatusFunc func() []ComponentStatus
+
+// SummaryStatusFunc creates a StatusFunc from a running stream, where each
+// component is named after its label, or its path within the config when it
+// has no label.
+func SummaryStatusFunc(s *service.RunningStreamSummary) StatusFunc {
+	return func() []ComponentStatus {
+		conns := s.ConnectionStatuses()
+		statuses := make([]ComponentStatus, 0, len(conns))
+		for _, c := range conns {
+			name := c.Label()
+			if name == "" {
+				name = strings.Join(c.Path(), ".")
+			}
+			statuses = append(statuses, ComponentStatus{
+				Name:  name,
+				Label: c.Label(),
+				Path:  strings.Join(c.Path(), "."),
+				Ready: c.Active(),
+				Err:   c.Err(),
+			})
+		}
+		return statuses
+	}
+}
+
+// Readiness hosts the standard grpc health checking protocol reflecting the
+// readiness of running streams, for consumption by service meshes and
+// orchestrators that do not use the HTTP endpoints.
+//
+// The overall status (an empty service name) is SERVING once streams have
+// been added and all of their components are connected and dependency probes
+// are passing. Each component and probe is also reported individually using
+// its name as the service name.
+type Readiness struct {
+	address string
+	period  time.Duration
+	srv     *grpc.Server
+	health  *health.Server
+
+	mut      sync.Mutex
+	sources  map[string]StatusFunc
+	probes   []*probeState
+	services map[string]struct{}
+	states   map[string]*ComponentState
+	ready    bool
+}
+
+// ComponentState is the reported state of a component of a stream or of a
+// dependency probe.
+type ComponentState struct {
+	Name        string     `json:"name"`
+	Label       string     `json:"-"`
+	Path        string     `json:"path,omitempty"`
+	Ready       bool       `json:"ready"`
+	Error       string     `json:"error,omitempty"`
+	Since       time.Time  `json:"since"`
+	LastError   string     `json:"last_error,omitempty"`
+	LastErrorAt *time.Time `json:"last_error_at,omitempty"`
+	CheckedAt   *time.Time `json:"checked_at,omitempty"`
+}
+
+func (c *ComponentState) update(now time.Time, ready bool, err error) {
+	if c.Since.IsZero() || c.Ready != ready {
+		c.Since = now
+	}
+	c.Ready = ready
+	c.Error = ""
+	if err != nil {
+		c.Error = err.Error()
+		if c.LastError != c.Error || c.LastErrorAt == nil {
+			errAt := now
+			c.LastErrorAt = &errAt
+		}
+		c.LastError = c.Error
+	}
+}
+
+type probeState struct {
+	probe    Probe
+	state    ComponentState
+	running  bool
+	lastDone time.Time
+}
+
+// NewReadiness constructs a Readiness that listens on an address and polls
+// the status of streams at the given period.
+func NewReadiness(address string, period time.Duration) *Readiness {
+	srv := grpc.NewServer()
+	reflection.Register(srv)
+	r := &Readiness{
+		address:  address,
+		period:   period,
+		srv:      srv,
+		health:   health.NewServer(),
+		sources:  map[string]StatusFunc{},
+		services: map[string]struct{}{},
+		states:   map[string]*ComponentState{},
+	}
+	r.health.SetServingStatus("", grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+	grpc_health_v1.RegisterHealthServer(srv, r.health)
+	return r
+}
+
+// AddStream adds a stream to be reflected in the reported statuses, replacing
+// any stream previously added with the same name, such as when a stream is
+// restarted after its config has changed.
+func (r *Readiness) AddStream(name string, fn StatusFunc) {
+	r.mut.Lock()
+	r.sources[name] = fn
+	r.mut.Unlock()
+	r.refresh()
+}
+
+// RemoveStream removes a stream from the reported statuses, where the
+// components of the stream are subsequently reported as unknown.
+func (r *Readiness) RemoveStream(name string) {
+	r.mut.Lock()
+	delete(r.sources, name)
+	r.mut.Unlock()
+	r.refresh()
+}
+
+// AddProbe adds a dependency probe that is checked periodically, where the
+// overall status is not ready whilst a probe is failing. A probe is reported
+// as not ready until its first check has completed.
+func (r *Readiness) AddProbe(p Probe) {
+	r.mut.Lock()
+	r.probes = append(r.probes, &probeState{
+		probe: p,
+		state: ComponentState{Name: p.Name, Since: time.Now()},
+	})
+	r.mut.Unlock()
+	r.refresh()
+}
+
+func (r *Readiness) checkProbe(ps *probeState) {
+	ctx, done := context.WithTimeout(context.Background(), ps.probe.Timeout)
+	err := ps.probe.Check(ctx)
+	done()
+
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	now := time.Now()
+	ps.state.update(now, err == nil, err)
+	ps.state.CheckedAt = &now
+	ps.running = false
+	ps.lastDone = now
+}
+
+func (r *Readiness) refresh() {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	now := time.Now()
+	ready := len(r.sources) > 0
+	seen := map[string]struct{}{}
+	for _, fn := range r.sources {
+		for _, s := range fn() {
+			status := grpc_health_v1.HealthCheckResponse_NOT_SERVING
+			if s.Ready {
+				status = grpc_health_v1.HealthCheckResponse_SERVING
+			} else {
+				ready = false
+			}
+			r.health.SetServingStatus(s.Name, status)
+			seen[s.Name] = struct{}{}
+
+			state, exists := r.states[s.Name]
+			if !exists {
+				state = &ComponentState{Name: s.Name}
+				r.states[s.Name] = state
+			}
+			state.Label = s.Label
+			state.Path = s.Path
+			state.update(now, s.Ready, s.Err)
+		}
+	}
+
+	for _, ps := range r.probes {
+		if !ps.running && (ps.lastDone.IsZero() || now.Sub(ps.lastDone) >= ps.probe.Interval) {
+			ps.running = true
+			go r.checkProbe(ps)
+		}
+
+		status := grpc_health_v1.HealthCheckResponse_NOT_SERVING
+		if ps.state.Ready {
+			status = grpc_health_v1.HealthCheckResponse_SERVING
+		} else {
+			ready = false
+		}
+		r.health.SetServingStatus(ps.probe.Name, status)
+		seen[ps.probe.Name] = struct{}{}
+	}
+
+	// Components that are no longer running are reported as unknown.
+	for name := range r.services {
+		if _, exists := seen[name]; !exists {
+			r.health.SetServingStatus(name, grpc_health_v1.HealthCheckResponse_SERVICE_UNKNOWN)
+		}
+	}
+	for name := range r.states {
+		if _, exists := seen[name]; !exists {
+			delete(r.states, name)
+		}
+	}
+	r.services = seen
+	r.ready = ready
+
+	overall := grpc_health_v1.HealthCheckResponse_NOT_SERVING
+	if ready {
+		overall = grpc_health_v1.HealthCheckResponse_SERVING
+	}
+	r.health.SetServingStatus("", overall)
+}
+
+// Run listens on the configured address for unencrypted connections and
+// polls the status of streams until the context is cancelled.
+func (r *Readiness) Run(ctx context.Context) error {
+	lis, err := net.Listen("tcp", r.address)
+	if err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+	return r.Serve(ctx, lis)
+}
+
+// Serve is the same as Run with an existing listener.
+func (r *Readiness) Serve(ctx context.Context, lis net.Listener) error {
+	errC := make(chan error, 1)
+	go func() {
+		errC <- r.srv.Serve(lis)
+	}()
+	return r.poll(ctx, errC)
+}
+
+// Poll polls the status of streams and checks dependency probes until the
+// context is cancelled without serving the grpc health protocol, which is
+// used when only the HTTP endpoint is served.
+func (r *Readiness) Poll(ctx context.Context) error {
+	return r.poll(ctx, nil)
+}
+
+func (r *Readiness) poll(ctx context.Context, errC <-chan error) error {
+	ticker := time.NewTicker(r.period)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			r.refresh()
+		case <-ctx.Done():
+			r.health.Shutdown()
+			r.srv.Stop()
+			return ctx.Err()
+		case err := <-errC:
+			return err
+		}
+	}
+}
+
+// Shutdown latches all statuses to NOT_SERVING, notifying all watchers, this
+// cannot be reversed. Dependency probes are also closed.
+func (r *Readiness) Shutdown() {
+	r.health.Shutdown()
+
+	r.mut.Lock()
+	probes := r.probes
+	r.mut.Unlock()
+	for _, ps := range probes {
+		if ps.probe.Close != nil {
+			ctx, done := context.WithTimeout(context.Background(), ps.probe.Timeout)
+			_ = ps.probe.Close(ctx)
+			done()
+		}
+	}
+}
+
+// componentDetails is the state of a component as reported by the
+// /admin/ready endpoint, which extends the fields reported by the /ready
+// endpoint of the HTTP server of a config.
+type componentDetails struct {
+	Label       string     `json:"label"`
+	Path        string     `json:"path"`
+	Connected   bool       `json:"connected"`
+	Error       string     `json:"error,omitempty"`
+	Since       time.Time  `json:"since"`
+	LastError   string     `json:"last_error,omitempty"`
+	LastErrorAt *time.Time `json:"last_error_at,omitempty"`
+}
+
+type readinessDetails struct {
+	Statuses []componentDetails `json:"statuses"`
+	Probes   []ComponentState   `json:"probes,omitempty"`
+}
+
+// ServeHTTP implements http.Handler by reporting the connection state of each
+// input and output as JSON along with the last error of each and when it
+// occurred, followed by the results of dependency probes. The response status
+// is 503 when not ready.
+func (r *Readiness) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	r.refresh()
+
+	r.mut.Lock()
+	ready := r.ready
+	details := readinessDetails{
+		Statuses: make([]componentDetails, 0, len(r.states)),
+	}
+	for _, s := range r.states {
+		details.Statuses = append(details.Statuses, componentDetails{
+			Label:       s.Label,
+			Path:        s.Path,
+			Connected:   s.Ready,
+			Error:       s.Error,
+			Since:       s.Since,
+			LastError:   s.LastError,
+			LastErrorAt: s.LastErrorAt,
+		})
+	}
+	for _, ps := range r.probes {
+		details.Probes = append(details.Probes, ps.state)
+	}
+	r.mut.Unlock()
+
+	sort.Slice(details.Statuses, func(i, j int) bool {
+		return details.Statuses[i].Path < details.Statuses[j].Path
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	if !ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_ = json.NewEncoder(w).Encode(details)
+}
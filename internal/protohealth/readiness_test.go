@@ -0,0 +1,180 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protohealth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestReadiness(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	r := NewReadiness("", 10*time.Millisecond)
+
+	ctx, done := context.WithCancel(context.Background())
+	defer done()
+	go func() {
+		_ = r.Serve(ctx, lis)
+	}()
+
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+	client := grpc_health_v1.NewHealthClient(conn)
+
+	check := func(name string) grpc_health_v1.HealthCheckResponse_ServingStatus {
+		t.Helper()
+		res, err := client.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{Service: name})
+		if err != nil {
+			return grpc_health_v1.HealthCheckResponse_SERVICE_UNKNOWN
+		}
+		return res.Status
+	}
+
+	// Not ready until a stream is added.
+	assert.Equal(t, grpc_health_v1.HealthCheckResponse_NOT_SERVING, check(""))
+
+	var outputReady, outputRunning atomic.Bool
+	outputRunning.Store(true)
+	r.AddStream("main", func() []ComponentStatus {
+		statuses := []ComponentStatus{{Name: "in", Ready: true}}
+		if outputRunning.Load() {
+			statuses = append(statuses, ComponentStatus{Name: "out", Ready: outputReady.Load()})
+		}
+		return statuses
+	})
+
+	assert.Equal(t, grpc_health_v1.HealthCheckResponse_NOT_SERVING, check(""))
+	assert.Equal(t, grpc_health_v1.HealthCheckResponse_SERVING, check("in"))
+	assert.Equal(t, grpc_health_v1.HealthCheckResponse_NOT_SERVING, check("out"))
+
+	outputReady.Store(true)
+	assert.Eventually(t, func() bool {
+		return check("") == grpc_health_v1.HealthCheckResponse_SERVING
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, grpc_health_v1.HealthCheckResponse_SERVING, check("out"))
+
+	outputRunning.Store(false)
+	assert.Eventually(t, func() bool {
+		return check("out") == grpc_health_v1.HealthCheckResponse_SERVICE_UNKNOWN
+	}, time.Second, 10*time.Millisecond)
+
+	// Adding a stream of the same name replaces it.
+	r.AddStream("main", func() []ComponentStatus {
+		return []ComponentStatus{{Name: "in2", Ready: true}}
+	})
+	assert.Equal(t, grpc_health_v1.HealthCheckResponse_SERVING, check(""))
+	assert.Equal(t, grpc_health_v1.HealthCheckResponse_SERVING, check("in2"))
+	assert.Equal(t, grpc_health_v1.HealthCheckResponse_SERVICE_UNKNOWN, check("in"))
+
+	r.RemoveStream("main")
+	assert.Equal(t, grpc_health_v1.HealthCheckResponse_NOT_SERVING, check(""))
+	assert.Equal(t, grpc_health_v1.HealthCheckResponse_SERVICE_UNKNOWN, check("in2"))
+
+	r.Shutdown()
+	assert.Equal(t, grpc_health_v1.HealthCheckResponse_NOT_SERVING, check(""))
+	assert.Equal(t, grpc_health_v1.HealthCheckResponse_NOT_SERVING, check("in"))
+}
+
+func TestReadinessDetails(t *testing.T) {
+	r := NewReadiness("", time.Hour)
+
+	r.AddStream("main", func() []ComponentStatus {
+		return []ComponentStatus{
+			{Name: "in", Label: "in", Path: "input", Ready: true},
+			{Name: "output", Path: "output", Err: errors.New("connection refused")},
+		}
+	})
+
+	var probeFailing atomic.Bool
+	probeFailing.Store(true)
+	r.AddProbe(Probe{
+		Name:     "db",
+		Interval: time.Millisecond,
+		Timeout:  time.Second,
+		Check: func(context.Context) error {
+			if probeFailing.Load() {
+				return errors.New("nope")
+			}
+			return nil
+		},
+	})
+
+	type status struct {
+		Label       string     `json:"label"`
+		Path        string     `json:"path"`
+		Connected   bool       `json:"connected"`
+		Error       string     `json:"error"`
+		LastError   string     `json:"last_error"`
+		LastErrorAt *time.Time `json:"last_error_at"`
+	}
+	type details struct {
+		Statuses []status         `json:"statuses"`
+		Probes   []ComponentState `json:"probes"`
+	}
+	get := func() (int, details) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ready", http.NoBody))
+		var d details
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &d))
+		return rec.Code, d
+	}
+
+	assert.Eventually(t, func() bool {
+		_, d := get()
+		return d.Probes[0].CheckedAt != nil
+	}, time.Second, time.Millisecond)
+
+	code, d := get()
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	require.Len(t, d.Statuses, 2)
+	assert.Equal(t, "in", d.Statuses[0].Label)
+	assert.True(t, d.Statuses[0].Connected)
+	assert.Empty(t, d.Statuses[1].Label)
+	assert.Equal(t, "output", d.Statuses[1].Path)
+	assert.False(t, d.Statuses[1].Connected)
+	assert.Equal(t, "connection refused", d.Statuses[1].Error)
+	assert.Equal(t, "connection refused", d.Statuses[1].LastError)
+	require.NotNil(t, d.Statuses[1].LastErrorAt)
+	assert.Equal(t, "nope", d.Probes[0].Error)
+
+	probeFailing.Store(false)
+	assert.Eventually(t, func() bool {
+		_, d := get()
+		return d.Probes[0].Ready
+	}, time.Second, time.Millisecond)
+
+	_, d = get()
+	assert.Empty(t, d.Probes[0].Error)
+	assert.Equal(t, "nope", d.Probes[0].LastError)
+}
@@ -0,0 +1,118 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed as a Redpanda Enterprise file under the Redpanda Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+// https://github.com/redpanda-data/connect/blob/main/licenses/rcl.md
+
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// vaultSecretsManager reads secrets from the KV version 2 secrets engine of
+// HashiCorp Vault, where each secret is returned as a JSON object of its
+// fields so that individual fields can be selected.
+type vaultSecretsManager struct {
+	logger    *slog.Logger
+	client    *http.Client
+	baseURL   string
+	mount     string
+	token     string
+	namespace string
+}
+
+func newVaultSecretsManager(logger *slog.Logger, u *url.URL) (*vaultSecretsManager, string, error) {
+	mount, prefix, _ := strings.Cut(strings.TrimPrefix(u.Path, "/"), "/")
+	if mount == "" {
+		return nil, "", errors.New("vault secrets require a mount path, e.g. vault://localhost:8200/secret")
+	}
+
+	// The token is read from the environment in order to keep it out of the
+	// command line.
+	token := os.Getenv("VAULT_TOKEN")
+	if token == "" {
+		return nil, "", errors.New("vault secrets require a token within the environment variable VAULT_TOKEN")
+	}
+
+	scheme := "https"
+	if u.Query().Get("tls") == "false" {
+		scheme = "http"
+	}
+
+	v := &vaultSecretsManager{
+		logger:    logger,
+		client:    &http.Client{Timeout: 30 * time.Second},
+		baseURL:   scheme + "://" + u.Host,
+		mount:     mount,
+		token:     token,
+		namespace: os.Getenv("VAULT_NAMESPACE"),
+	}
+	return v, prefix, nil
+}
+
+func (v *vaultSecretsManager) read(ctx context.Context, key string) (data json.RawMessage, found bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%v/v1/%v/data/%v", v.baseURL, v.mount, key), http.NoBody)
+	if err != nil {
+		return nil, false, err
+	}
+	req.Header.Set("X-Vault-Token", v.token)
+	if v.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.namespace)
+	}
+
+	res, err := v.client.Do(req)
+	if err != nil {
+		return nil, false, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound {
+		return nil, false, nil
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, false, fmt.Errorf("unexpected status: %v", res.Status)
+	}
+
+	var body struct {
+		Data struct {
+			Data json.RawMessage `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return nil, false, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if len(body.Data.Data) == 0 || string(body.Data.Data) == "null" {
+		// Deleted versions of a secret have no data.
+		return nil, false, nil
+	}
+	return body.Data.Data, true, nil
+}
+
+func (v *vaultSecretsManager) GetSecretValue(ctx context.Context, key string) (string, bool) {
+	data, found, err := v.read(ctx, key)
+	if err != nil {
+		v.logger.With("error", err, "key", key).Error("Failed to look up secret")
+		return "", false
+	}
+	if !found {
+		return "", false
+	}
+	return string(data), true
+}
+
+func (v *vaultSecretsManager) CheckSecretExists(ctx context.Context, key string) bool {
+	_, found, err := v.read(ctx, key)
+	return err == nil && found
+}
@@ -0,0 +1,67 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed as a Redpanda Enterprise file under the Redpanda Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+// https://github.com/redpanda-data/connect/blob/main/licenses/rcl.md
+
+package secrets
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVaultSecrets(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "foo" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/connect/orders":
+			_, _ = w.Write([]byte(`{"data":{"data":{"user":"bob","password":"hunter2"},"metadata":{"version":1}}}`))
+		case "/v1/secret/data/connect/deleted":
+			_, _ = w.Write([]byte(`{"data":{"data":null,"metadata":{"version":2}}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	t.Setenv("VAULT_TOKEN", "foo")
+
+	ctx := context.Background()
+	urn := "vault://" + strings.TrimPrefix(srv.URL, "http://") + "/secret/connect/?tls=false"
+	lookup, err := parseSecretsLookupURN(ctx, slog.Default(), urn)
+	require.NoError(t, err)
+
+	v, exists := lookup(ctx, "orders.password")
+	assert.True(t, exists)
+	assert.Equal(t, "hunter2", v)
+
+	v, exists = lookup(ctx, "orders")
+	assert.True(t, exists)
+	assert.JSONEq(t, `{"user":"bob","password":"hunter2"}`, v)
+
+	_, exists = lookup(ctx, "orders.missing")
+	assert.False(t, exists)
+
+	_, exists = lookup(ctx, "deleted")
+	assert.False(t, exists)
+
+	_, exists = lookup(ctx, "nope")
+	assert.False(t, exists)
+
+	t.Setenv("VAULT_TOKEN", "")
+	_, err = parseSecretsLookupURN(ctx, slog.Default(), urn)
+	require.ErrorContains(t, err, "VAULT_TOKEN")
+}
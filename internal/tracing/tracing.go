@@ -0,0 +1,230 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tracing provides helpers for propagating distributed tracing
+// contexts through the messages consumed by inputs, such as from the headers
+// of Kafka records.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/redpanda-data/benthos/v4/public/bloblang"
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	fieldExtractMap  = "extract_tracing_map"
+	fieldExtractMode = "extract_tracing_mode"
+
+	// ModeParent creates the span of a consumed message as a child of the
+	// extracted tracing context.
+	ModeParent = "parent"
+
+	// ModeLink creates the span of a consumed message as the root of a new
+	// trace with a link to the extracted tracing context.
+	ModeLink = "link"
+)
+
+// ExtractFields returns the config fields for extracting tracing contexts from
+// consumed messages, which are used with WrapBatchInput or WrapInput.
+func ExtractFields() []*service.ConfigField {
+	return []*service.ConfigField{
+		service.NewExtractTracingSpanMappingField(),
+		ExtractModeField(),
+	}
+}
+
+// ExtractModeField returns a config field for choosing how the span of a
+// consumed message relates to the extracted tracing context, for components
+// that declare the mapping field with service.NewExtractTracingSpanMappingField
+// themselves.
+func ExtractModeField() *service.ConfigField {
+	return service.NewStringAnnotatedEnumField(fieldExtractMode, map[string]string{
+		ModeParent: "The span of each message is a child of the extracted context, which continues the trace of the producer.",
+		ModeLink:   "The span of each message starts a new trace with a link to the extracted context, which is useful when the producer and consumer traces should be kept separate.",
+	}).
+		Description("How the span of each consumed message relates to the tracing context extracted with `" + fieldExtractMap + "`.").
+		Default(ModeParent).
+		Version("4.45.0").
+		Advanced()
+}
+
+// WrapBatchInput wraps a BatchInput with a mechanism for extracting a tracing
+// context from each consumed message, as configured by ExtractFields. Unlike
+// the generic extraction of the service package the mapping is executed for
+// every message of a batch, as inputs such as Kafka consume batches of records
+// that each carry their own tracing context.
+func WrapBatchInput(conf *service.ParsedConfig, mgr *service.Resources, inputName string, i service.BatchInput) (service.BatchInput, error) {
+	e, err := newExtractor(conf, mgr, inputName)
+	if err != nil || e == nil {
+		return i, err
+	}
+	return &extractBatchInput{e: e, rdr: i}, nil
+}
+
+// WrapInput wraps an Input with a mechanism for extracting a tracing context
+// from each consumed message, as configured by ExtractFields.
+func WrapInput(conf *service.ParsedConfig, mgr *service.Resources, inputName string, i service.Input) (service.Input, error) {
+	e, err := newExtractor(conf, mgr, inputName)
+	if err != nil || e == nil {
+		return i, err
+	}
+	return &extractInput{e: e, rdr: i}, nil
+}
+
+//------------------------------------------------------------------------------
+
+type extractor struct {
+	operationName string
+	mapping       *bloblang.Executor
+	link          bool
+	prov          trace.TracerProvider
+	log           *service.Logger
+}
+
+func newExtractor(conf *service.ParsedConfig, mgr *service.Resources, inputName string) (*extractor, error) {
+	if str, _ := conf.FieldString(fieldExtractMap); str == "" {
+		return nil, nil
+	}
+
+	e := &extractor{
+		operationName: "input_" + inputName,
+		prov:          mgr.OtelTracer(),
+		log:           mgr.Logger(),
+	}
+
+	var err error
+	if e.mapping, err = conf.FieldBloblang(fieldExtractMap); err != nil {
+		return nil, err
+	}
+	mode, err := conf.FieldString(fieldExtractMode)
+	if err != nil {
+		return nil, err
+	}
+	e.link = mode == ModeLink
+	return e, nil
+}
+
+func carrierFrom(spanPart *service.Message) (propagation.MapCarrier, error) {
+	structured, err := spanPart.AsStructured()
+	if err != nil {
+		return nil, err
+	}
+
+	spanMap, ok := structured.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("expected an object, got: %T", structured)
+	}
+
+	c := propagation.MapCarrier{}
+	for k, v := range spanMap {
+		switch t := v.(type) {
+		case string:
+			c[strings.ToLower(k)] = t
+		case []byte:
+			c[strings.ToLower(k)] = string(t)
+		}
+	}
+	return c, nil
+}
+
+// start begins the span of a message from the tracing context extracted from
+// the result of the mapping.
+func (e *extractor) start(msg *service.Message, spanPart *service.Message, err error) *service.Message {
+	if err != nil {
+		e.log.Errorf("Mapping failed for tracing span: %v", err)
+		return msg
+	}
+	if spanPart == nil {
+		return msg
+	}
+
+	c, err := carrierFrom(spanPart)
+	if err != nil {
+		e.log.Errorf("Mapping failed for tracing span: %v", err)
+		return msg
+	}
+
+	ctx := msg.Context()
+	extracted := otel.GetTextMapPropagator().Extract(ctx, c)
+
+	var opts []trace.SpanStartOption
+	if e.link {
+		if sc := trace.SpanContextFromContext(extracted); sc.IsValid() {
+			opts = append(opts, trace.WithNewRoot(), trace.WithLinks(trace.Link{SpanContext: sc}))
+		}
+	} else {
+		ctx = extracted
+	}
+
+	ctx, _ = e.prov.Tracer("benthos").Start(ctx, e.operationName, opts...)
+	return msg.WithContext(ctx)
+}
+
+type extractBatchInput struct {
+	e   *extractor
+	rdr service.BatchInput
+}
+
+func (s *extractBatchInput) Connect(ctx context.Context) error {
+	return s.rdr.Connect(ctx)
+}
+
+func (s *extractBatchInput) ReadBatch(ctx context.Context) (service.MessageBatch, service.AckFunc, error) {
+	b, afn, err := s.rdr.ReadBatch(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	exec := b.BloblangExecutor(s.e.mapping)
+	for i, m := range b {
+		spanPart, err := exec.Query(i)
+		b[i] = s.e.start(m, spanPart, err)
+	}
+	return b, afn, nil
+}
+
+func (s *extractBatchInput) Close(ctx context.Context) error {
+	return s.rdr.Close(ctx)
+}
+
+type extractInput struct {
+	e   *extractor
+	rdr service.Input
+}
+
+func (s *extractInput) Connect(ctx context.Context) error {
+	return s.rdr.Connect(ctx)
+}
+
+func (s *extractInput) Read(ctx context.Context) (*service.Message, service.AckFunc, error) {
+	m, afn, err := s.rdr.Read(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	spanPart, err := m.BloblangQuery(s.e.mapping)
+	return s.e.start(m, spanPart, err), afn, nil
+}
+
+func (s *extractInput) Close(ctx context.Context) error {
+	return s.rdr.Close(ctx)
+}
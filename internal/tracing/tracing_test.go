@@ -0,0 +1,141 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	tracesdk "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	testTraceA = "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"
+	testTraceB = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+)
+
+type batchInput struct {
+	batch service.MessageBatch
+}
+
+func (b *batchInput) Connect(context.Context) error {
+	return nil
+}
+
+func (b *batchInput) ReadBatch(context.Context) (service.MessageBatch, service.AckFunc, error) {
+	return b.batch, func(context.Context, error) error { return nil }, nil
+}
+
+func (b *batchInput) Close(context.Context) error {
+	return nil
+}
+
+func testMessage(traceParent string) *service.Message {
+	m := service.NewMessage([]byte("hello"))
+	m.MetaSetMut("traceparent", traceParent)
+	return m
+}
+
+func TestWrapBatchInputNoMapping(t *testing.T) {
+	in := &batchInput{}
+	pConf, err := service.NewConfigSpec().Fields(ExtractFields()...).ParseYAML(`{}`, nil)
+	require.NoError(t, err)
+
+	i, err := WrapBatchInput(pConf, service.MockResources(), "test", in)
+	require.NoError(t, err)
+	assert.Same(t, in, i)
+}
+
+func TestWrapBatchInputParent(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	pConf, err := service.NewConfigSpec().Fields(ExtractFields()...).ParseYAML(`
+extract_tracing_map: 'root = @'
+`, nil)
+	require.NoError(t, err)
+
+	i, err := WrapBatchInput(pConf, service.MockResources(), "test", &batchInput{batch: service.MessageBatch{testMessage(testTraceA), testMessage(testTraceB)}})
+	require.NoError(t, err)
+
+	batch, _, err := i.ReadBatch(context.Background())
+	require.NoError(t, err)
+	require.Len(t, batch, 2)
+
+	// Each message continues the trace extracted from its own headers.
+	scA := trace.SpanContextFromContext(batch[0].Context())
+	assert.Equal(t, "0af7651916cd43dd8448eb211c80319c", scA.TraceID().String())
+	scB := trace.SpanContextFromContext(batch[1].Context())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", scB.TraceID().String())
+}
+
+func TestWrapBatchInputLink(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	pConf, err := service.NewConfigSpec().Fields(ExtractFields()...).ParseYAML(`
+extract_tracing_map: 'root = @'
+extract_tracing_mode: link
+`, nil)
+	require.NoError(t, err)
+
+	i, err := WrapBatchInput(pConf, service.MockResources(), "test", &batchInput{batch: service.MessageBatch{testMessage(testTraceA)}})
+	require.NoError(t, err)
+
+	rec := tracetest.NewSpanRecorder()
+	i.(*extractBatchInput).e.prov = tracesdk.NewTracerProvider(tracesdk.WithSpanProcessor(rec))
+
+	batch, _, err := i.ReadBatch(context.Background())
+	require.NoError(t, err)
+	require.Len(t, batch, 1)
+
+	sc := trace.SpanContextFromContext(batch[0].Context())
+	require.True(t, sc.IsValid())
+	assert.NotEqual(t, "0af7651916cd43dd8448eb211c80319c", sc.TraceID().String())
+
+	trace.SpanFromContext(batch[0].Context()).End()
+	spans := rec.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "input_test", spans[0].Name())
+	assert.False(t, spans[0].Parent().IsValid())
+	require.Len(t, spans[0].Links(), 1)
+	assert.Equal(t, "0af7651916cd43dd8448eb211c80319c", spans[0].Links()[0].SpanContext.TraceID().String())
+	assert.Equal(t, "b7ad6b7169203331", spans[0].Links()[0].SpanContext.SpanID().String())
+}
+
+func TestWrapBatchInputMappingFailure(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	pConf, err := service.NewConfigSpec().Fields(ExtractFields()...).ParseYAML(`
+extract_tracing_map: 'root = this.nope.number()'
+`, nil)
+	require.NoError(t, err)
+
+	i, err := WrapBatchInput(pConf, service.MockResources(), "test", &batchInput{batch: service.MessageBatch{testMessage(testTraceA)}})
+	require.NoError(t, err)
+
+	batch, _, err := i.ReadBatch(context.Background())
+	require.NoError(t, err)
+	require.Len(t, batch, 1)
+
+	// Failed mappings leave the message untouched rather than dropping it.
+	assert.False(t, trace.SpanContextFromContext(batch[0].Context()).IsValid())
+}
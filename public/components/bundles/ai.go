@@ -0,0 +1,20 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed as a Redpanda Enterprise file under the Redpanda Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+// https://github.com/redpanda-data/connect/blob/main/licenses/rcl.md
+
+//go:build bundle_all || bundle_ai
+
+package bundles
+
+import (
+	// Import AI model and vector database components.
+	_ "github.com/redpanda-data/connect/v4/public/components/cohere"
+	_ "github.com/redpanda-data/connect/v4/public/components/ollama"
+	_ "github.com/redpanda-data/connect/v4/public/components/openai"
+	_ "github.com/redpanda-data/connect/v4/public/components/pinecone"
+	_ "github.com/redpanda-data/connect/v4/public/components/qdrant"
+)
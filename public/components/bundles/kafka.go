@@ -0,0 +1,19 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed as a Redpanda Enterprise file under the Redpanda Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+// https://github.com/redpanda-data/connect/blob/main/licenses/rcl.md
+
+//go:build bundle_all || bundle_kafka
+
+package bundles
+
+import (
+	// Import Kafka and Redpanda components.
+	_ "github.com/redpanda-data/connect/v4/public/components/confluent"
+	_ "github.com/redpanda-data/connect/v4/public/components/kafka"
+	_ "github.com/redpanda-data/connect/v4/public/components/kafka/enterprise"
+	_ "github.com/redpanda-data/connect/v4/public/components/redpanda"
+)
@@ -0,0 +1,26 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed as a Redpanda Enterprise file under the Redpanda Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+// https://github.com/redpanda-data/connect/blob/main/licenses/rcl.md
+
+//go:build bundle_all || bundle_messaging
+
+package bundles
+
+import (
+	// Import message broker and queue components.
+	_ "github.com/redpanda-data/connect/v4/public/components/amqp09"
+	_ "github.com/redpanda-data/connect/v4/public/components/amqp1"
+	_ "github.com/redpanda-data/connect/v4/public/components/beanstalkd"
+	_ "github.com/redpanda-data/connect/v4/public/components/mqtt"
+	_ "github.com/redpanda-data/connect/v4/public/components/nanomsg"
+	_ "github.com/redpanda-data/connect/v4/public/components/nats"
+	_ "github.com/redpanda-data/connect/v4/public/components/nsq"
+	_ "github.com/redpanda-data/connect/v4/public/components/ockam"
+	_ "github.com/redpanda-data/connect/v4/public/components/pulsar"
+	_ "github.com/redpanda-data/connect/v4/public/components/pusher"
+	_ "github.com/redpanda-data/connect/v4/public/components/zeromq"
+)
@@ -0,0 +1,29 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed as a Redpanda Enterprise file under the Redpanda Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+// https://github.com/redpanda-data/connect/blob/main/licenses/rcl.md
+
+//go:build bundle_all || bundle_nosql
+
+package bundles
+
+import (
+	// Import NoSQL database and search engine components.
+	_ "github.com/redpanda-data/connect/v4/public/components/cassandra"
+	_ "github.com/redpanda-data/connect/v4/public/components/consul"
+	_ "github.com/redpanda-data/connect/v4/public/components/couchbase"
+	_ "github.com/redpanda-data/connect/v4/public/components/cypher"
+	_ "github.com/redpanda-data/connect/v4/public/components/dgraph"
+	_ "github.com/redpanda-data/connect/v4/public/components/elasticsearch"
+	_ "github.com/redpanda-data/connect/v4/public/components/etcd"
+	_ "github.com/redpanda-data/connect/v4/public/components/hdfs"
+	_ "github.com/redpanda-data/connect/v4/public/components/influxdb"
+	_ "github.com/redpanda-data/connect/v4/public/components/memcached"
+	_ "github.com/redpanda-data/connect/v4/public/components/mongodb"
+	_ "github.com/redpanda-data/connect/v4/public/components/opensearch"
+	_ "github.com/redpanda-data/connect/v4/public/components/redis"
+	_ "github.com/redpanda-data/connect/v4/public/components/spicedb"
+)
@@ -0,0 +1,33 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed as a Redpanda Enterprise file under the Redpanda Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+// https://github.com/redpanda-data/connect/blob/main/licenses/rcl.md
+
+// Package bundles imports the components of Redpanda Connect that are selected
+// with build tags, which is a way of building slim binaries that only contain
+// the connectors required by a deployment. Components that do not interact
+// with external systems, along with the prometheus metrics exporter that is
+// used by default, are always imported.
+//
+// A bundle is selected with the tag bundle_<name>, where the bundles are ai,
+// aws, azure, gcp, kafka, messaging, network, nosql, observability, scripting,
+// sql and utility. The tag bundle_all selects every bundle, which is
+// equivalent to importing github.com/redpanda-data/connect/v4/public/components/all.
+//
+// EXPERIMENTAL: The components within each bundle may change outside of major
+// version releases.
+package bundles
+
+import (
+	// Import components that are included with every bundle.
+	_ "github.com/redpanda-data/connect/v4/public/components/avro"
+	_ "github.com/redpanda-data/connect/v4/public/components/crypto"
+	_ "github.com/redpanda-data/connect/v4/public/components/io"
+	_ "github.com/redpanda-data/connect/v4/public/components/msgpack"
+	_ "github.com/redpanda-data/connect/v4/public/components/prometheus"
+	_ "github.com/redpanda-data/connect/v4/public/components/pure"
+	_ "github.com/redpanda-data/connect/v4/public/components/pure/extended"
+)
@@ -0,0 +1,18 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed as a Redpanda Enterprise file under the Redpanda Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+// https://github.com/redpanda-data/connect/blob/main/licenses/rcl.md
+
+//go:build bundle_all || bundle_scripting
+
+package bundles
+
+import (
+	// Import scripting language components.
+	_ "github.com/redpanda-data/connect/v4/public/components/javascript"
+	_ "github.com/redpanda-data/connect/v4/public/components/python"
+	_ "github.com/redpanda-data/connect/v4/public/components/wasm"
+)
@@ -0,0 +1,21 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed as a Redpanda Enterprise file under the Redpanda Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+// https://github.com/redpanda-data/connect/blob/main/licenses/rcl.md
+
+//go:build bundle_all || bundle_sql
+
+package bundles
+
+import (
+	// Import SQL database components.
+	_ "github.com/redpanda-data/connect/v4/public/components/cockroachdb"
+	_ "github.com/redpanda-data/connect/v4/public/components/postgresql"
+	_ "github.com/redpanda-data/connect/v4/public/components/questdb"
+	_ "github.com/redpanda-data/connect/v4/public/components/snowflake"
+	_ "github.com/redpanda-data/connect/v4/public/components/sql"
+	_ "github.com/redpanda-data/connect/v4/public/components/timeplus"
+)
@@ -25,4 +25,6 @@ package pure
 import (
 	// Import only pure packages.
 	_ "github.com/redpanda-data/benthos/v4/public/components/pure"
+
+	_ "github.com/redpanda-data/connect/v4/internal/impl/pure"
 )
@@ -19,7 +19,9 @@ import (
 	"github.com/redpanda-data/benthos/v4/public/service"
 
 	"github.com/redpanda-data/connect/v4/internal/impl/kafka/enterprise"
+	"github.com/redpanda-data/connect/v4/internal/loglevels"
 	"github.com/redpanda-data/connect/v4/internal/plugins"
+	"github.com/redpanda-data/connect/v4/internal/protohealth"
 )
 
 func redpandaTopLevelConfigField() *service.ConfigField {
@@ -35,6 +37,8 @@ func Standard(version, dateBuilt string) *service.ConfigSchema {
 		"@service": "redpanda-connect",
 	}, "logger", "static_fields")
 	s = s.Field(redpandaTopLevelConfigField())
+	s = s.Field(loglevels.ConfigField())
+	s = s.Field(protohealth.ProbesField())
 	return s
 }
 
@@ -64,6 +68,8 @@ func Cloud(version, dateBuilt string) *service.ConfigSchema {
 		"@service": "redpanda-connect",
 	}, "logger", "static_fields")
 	s = s.Field(redpandaTopLevelConfigField())
+	s = s.Field(loglevels.ConfigField())
+	s = s.Field(protohealth.ProbesField())
 	return s
 }
 
@@ -93,5 +99,7 @@ func CloudAI(version, dateBuilt string) *service.ConfigSchema {
 		"@service": "redpanda-connect",
 	}, "logger", "static_fields")
 	s = s.Field(redpandaTopLevelConfigField())
+	s = s.Field(loglevels.ConfigField())
+	s = s.Field(protohealth.ProbesField())
 	return s
 }